@@ -22,30 +22,40 @@ import (
 
 	"github.com/GoogleContainerTools/container-diff/cmd/util/output"
 	"github.com/GoogleContainerTools/container-diff/differs"
-	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-var analyzeCmd = &cobra.Command{
-	Use:   "analyze image",
-	Short: "Analyzes an image: container-diff image",
-	Long: `Analyzes an image using the specifed analyzers as indicated via --type flag(s).
+// AnalyzeOptions are the options of the analyze command.
+type AnalyzeOptions struct {
+	SharedOptions
+}
+
+func newAnalyzeCmd() *cobra.Command {
+	opts := &AnalyzeOptions{}
+	cmd := &cobra.Command{
+		Use:   "analyze image",
+		Short: "Analyzes an image: container-diff image",
+		Long: `Analyzes an image using the specifed analyzers as indicated via --type flag(s).
 
 For details on how to specify images, run: container-diff help`,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if err := validateArgs(args, checkAnalyzeArgNum, checkIfValidAnalyzer); err != nil {
-			return err
-		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := analyzeImage(args[0], types); err != nil {
-			logrus.Error(err)
-			os.Exit(1)
-		}
-	},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := checkAnalyzeArgNum(args); err != nil {
+				return err
+			}
+			return opts.Validate()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.Run(args[0]); err != nil {
+				logrus.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+	addSharedFlags(cmd, &opts.SharedOptions)
+	output.AddFlags(cmd)
+	return cmd
 }
 
 func checkAnalyzeArgNum(args []string) error {
@@ -55,36 +65,47 @@ func checkAnalyzeArgNum(args []string) error {
 	return nil
 }
 
-func analyzeImage(imageName string, analyzerArgs []string) error {
-	analyzeTypes, err := differs.GetAnalyzers(analyzerArgs)
+// Validate checks the options, defaulting to the size analyzer.
+func (o *AnalyzeOptions) Validate() error {
+	return validateArgs(nil, o.checkIfValidAnalyzer, o.checkPackageFlags, o.checkFormatFlag, o.checkResultsFlags)
+}
+
+// Run analyzes an image and writes the results.
+func (o *AnalyzeOptions) Run(imageName string) error {
+	analyzeTypes, err := differs.GetAnalyzers(o.Types)
 	if err != nil {
 		return errors.Wrap(err, "getting analyzers")
 	}
+	analyzeTypes = differs.WithOptions(analyzeTypes, o.Analyzers)
+	defer o.start()()
 
-	image, err := getImage(imageName)
+	image, err := o.getImage(imageName, nil, false)
 	if err != nil {
 		return errors.Wrapf(err, "error retrieving image %s", imageName)
 	}
+	defer o.cleanupImage(image)
 
-	if noCache && !save {
-		defer pkgutil.CleanupImage(image)
-	}
-	if err != nil {
-		return fmt.Errorf("error processing image: %s", err)
+	if err := o.checkExpectedBase(image); err != nil {
+		return err
 	}
 
 	req := differs.SingleRequest{
 		Image:        image,
-		AnalyzeTypes: analyzeTypes}
+		AnalyzeTypes: analyzeTypes,
+		Timings:      o.timings,
+		Concurrency:  o.AnalyzerConcurrency}
 	analyses, err := req.GetAnalysis()
 	if err != nil {
 		return fmt.Errorf("error performing image analysis: %s", err)
 	}
 
 	logrus.Info("retrieving analyses")
-	outputResults(analyses)
+	o.outputResults(analyses)
+	if err := o.pushResults(analyses, image); err != nil {
+		return err
+	}
 
-	if noCache && save {
+	if o.NoCache && o.Save {
 		logrus.Infof("image was saved at %s", image.FSPath)
 	}
 
@@ -92,7 +113,5 @@ func analyzeImage(imageName string, analyzerArgs []string) error {
 }
 
 func init() {
-	RootCmd.AddCommand(analyzeCmd)
-	addSharedFlags(analyzeCmd)
-	output.AddFlags(analyzeCmd)
+	RootCmd.AddCommand(newAnalyzeCmd())
 }
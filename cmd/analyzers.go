@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// AnalyzersOptions are the options of the analyzers command.
+type AnalyzersOptions struct {
+	JSON bool
+}
+
+func newAnalyzersCmd() *cobra.Command {
+	opts := &AnalyzersOptions{}
+	cmd := &cobra.Command{
+		Use:   "analyzers",
+		Short: "List the analyzers available with --type",
+		Long: `Lists the analyzers available with --type, with what they report, the image
+paths they read, whether they need the image filesystem extracted, and,
+with --json, the fields of their JSON results.`,
+		Args: cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.Run(os.Stdout); err != nil {
+				logrus.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&opts.JSON, "json", "j", false, "List the analyzers as JSON, including the fields of their results.")
+	return cmd
+}
+
+// Run writes the analyzers to w.
+func (o *AnalyzersOptions) Run(w io.Writer) error {
+	infos := differs.GetAnalyzerInfo()
+	if o.JSON {
+		out, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 8, 8, 8, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tCOMMANDS\tEXTRACTION\tDESCRIPTION")
+	for _, info := range infos {
+		commands := []string{}
+		if info.Analyze {
+			commands = append(commands, "analyze")
+		}
+		if info.Diff {
+			commands = append(commands, "diff")
+		}
+		extraction := "streamed"
+		if info.Extraction {
+			extraction = "extracted"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", info.Type, strings.Join(commands, ","), extraction, info.Description)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	RootCmd.AddCommand(newAnalyzersCmd())
+}
@@ -19,9 +19,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 
+	"code.cloudfoundry.org/bytefmt"
 	"github.com/GoogleContainerTools/container-diff/cmd/util/output"
 	"github.com/GoogleContainerTools/container-diff/differs"
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
@@ -31,26 +33,92 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var filename string
+// DiffOptions are the options of the diff command.
+type DiffOptions struct {
+	SharedOptions
+	Filename          string
+	FailFastIdentical bool
+	PatchDir          string
+	PatchGlobs        []string
+	AllPlatforms      bool
+	MaxSizeIncrease   string
+	sizeBudget        int64
+	FailOnDowngrade   bool
+	FailOnMajor       bool
+	ContentMaxSize    string
+	InterestingFiles  []string
+	NoInteresting     bool
+	AllowPartial      bool
+	TagOffset         int
+	SinceTag          string
+	TagOrder          string
 
-var diffCmd = &cobra.Command{
-	Use:   "diff image1 image2",
-	Short: "Compare two images: container-diff image1 image2",
-	Long: `Compares two images using the specifed analyzers as indicated via --type flag(s).
+	// FileDiff configures the file differ. Its InterestingFiles and
+	// ContentDiffMaxSize are derived from the fields above by fileDiffOptions.
+	FileDiff differs.FileDiffOptions
+}
+
+func newDiffCmd() *cobra.Command {
+	opts := &DiffOptions{}
+	cmd := &cobra.Command{
+		Use:   "diff image1 image2 | image --tag-offset=N | image --since-tag=TAG",
+		Short: "Compare two images: container-diff image1 image2",
+		Long: `Compares two images using the specifed analyzers as indicated via --type flag(s).
+
+With --tag-offset or --since-tag, a single registry image is compared
+against one of the earlier tags of its repository, e.g. the version in
+production.
 
 For details on how to specify images, run: container-diff help`,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if err := validateArgs(args, checkDiffArgNum, checkIfValidAnalyzer, checkFilenameFlag); err != nil {
-			return err
-		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := diffImages(args[0], args[1], types); err != nil {
-			logrus.Error(err)
-			os.Exit(1)
-		}
-	},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.historical() {
+				if len(args) != 1 {
+					return errors.New("'diff' requires one image as argument with --tag-offset or --since-tag: container-diff diff [image] --tag-offset=1")
+				}
+			} else if err := checkDiffArgNum(args); err != nil {
+				return err
+			}
+			return opts.Validate()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if opts.historical() {
+				image1, err := opts.resolveHistoricalImage(args[0])
+				if err != nil {
+					logrus.Error(err)
+					os.Exit(1)
+				}
+				args = []string{image1, args[0]}
+			}
+			if err := opts.Run(args[0], args[1]); err != nil {
+				logrus.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Filename, "filename", "f", "", "Set this flag to the path of a file in both containers to view the diff of the file. Must be used with --types=file flag.")
+	cmd.Flags().BoolVar(&opts.AllPlatforms, "all-platforms", false, "Diff each platform of two multi-platform manifest lists, such as linux/amd64 or windows/amd64:10.0.17763, and summarize which platforms changed.")
+	cmd.Flags().BoolVar(&opts.FailFastIdentical, "fail-fast-identical", false, "Also report registry images as identical without pulling them when their manifests differ but their config and layer digests match. remote:// images resolving to the same manifest are always reported as identical.")
+	cmd.Flags().StringVar(&opts.PatchDir, "patch-dir", "", "Write a unified patch for each changed configuration file to this directory, laid out like the image, e.g. etc/nginx/nginx.conf.patch.")
+	cmd.Flags().StringSliceVar(&opts.PatchGlobs, "content-diff-glob", []string{}, fmt.Sprintf("Globs selecting the files written with --patch-dir. Globs without a slash match file names, and dir/** matches everything below dir. (default %s)", strings.Join(util.DefaultPatchGlobs, ",")))
+	cmd.Flags().BoolVar(&opts.FileDiff.ExpandApplets, "expand-applets", false, "Set this flag to list busybox-style applet symlinks individually in file diffs instead of grouping them by target.")
+	cmd.Flags().IntVar(&opts.FileDiff.ArchiveDepth, "archive-depth", 0, "Number of nested archive levels to open when diffing modified archives such as jars, wheels and tarballs. Set to 0 to compare archives as plain files.")
+	cmd.Flags().StringVar(&opts.MaxSizeIncrease, "max-size-increase", "", "Fail when the second image is larger than the first by more than this size, e.g. 50M or 1048576, and rank the files, packages and layers that grew it.")
+	cmd.Flags().BoolVar(&opts.FailOnDowngrade, "fail-on-downgrade", false, "Fail when a package found by the package analyzers among the --types, such as apt or pip, is downgraded in the second image.")
+	cmd.Flags().BoolVar(&opts.FailOnMajor, "fail-on-major-upgrade", false, "Fail when a package found by the package analyzers among the --types is upgraded to a new major version, or a new epoch for apt and rpm packages, in the second image.")
+	cmd.Flags().BoolVar(&opts.FileDiff.DiffContent, "diff-content", false, "Show a unified diff of each modified text file in file diffs. Must be used with --types=file flag.")
+	cmd.Flags().IntVar(&opts.FileDiff.HotspotDepth, "hotspot-depth", 0, "Rank the directories that grew or shrank the most in file diffs, aggregating size changes at this many path components, e.g. 2 for /usr/lib. Set to 0 to skip the ranking. Must be used with --types=file flag.")
+	cmd.Flags().IntVar(&opts.FileDiff.HotspotTop, "hotspot-top", 10, "Number of directories ranked with --hotspot-depth. Set to 0 to rank all of them.")
+	cmd.Flags().StringVar(&opts.ContentMaxSize, "diff-content-max-size", "64K", "Largest file shown with --diff-content or --interesting-files, e.g. 64K or 65536.")
+	cmd.Flags().StringSliceVar(&opts.InterestingFiles, "interesting-files", []string{}, fmt.Sprintf("Globs of files whose content diffs file diffs always show when they are added, deleted or modified, in addition to the defaults. Globs are matched as with --content-diff-glob. (default %s)", strings.Join(util.DefaultInterestingFiles, ",")))
+	cmd.Flags().BoolVar(&opts.NoInteresting, "no-interesting-files", false, "Don't show the content diffs of the default --interesting-files, only those of the globs given.")
+	cmd.Flags().BoolVar(&opts.AllowPartial, "allow-partial", false, "When only one of the images can be retrieved, output its analysis along with the error for the other instead of failing outright. The exit status is still non-zero.")
+	cmd.Flags().IntVar(&opts.TagOffset, "tag-offset", 0, "Compare the image against the tag this many tags earlier in its repository, listed from the registry, e.g. 1 for the previous version.")
+	cmd.Flags().StringVar(&opts.SinceTag, "since-tag", "", "Compare the image against this tag of its repository, e.g. the version in production.")
+	cmd.Flags().StringVar(&opts.TagOrder, "tag-order", util.TagOrderSemver, fmt.Sprintf("How --tag-offset orders tags: %s, by version, or %s, by the upload time GCR and Artifact Registry report, and by image creation time on other registries.", util.TagOrderSemver, util.TagOrderTime))
+	cmd.Flags().StringSliceVar(&opts.FileDiff.ArchiveExtensions, "archive-extensions", differs.DefaultArchiveExtensions, "File extensions of the archives to open with --archive-depth.")
+	addSharedFlags(cmd, &opts.SharedOptions)
+	output.AddFlags(cmd)
+	return cmd
 }
 
 func checkDiffArgNum(args []string) error {
@@ -60,11 +128,73 @@ func checkDiffArgNum(args []string) error {
 	return nil
 }
 
-func checkFilenameFlag(_ []string) error {
-	if filename == "" {
+// Validate checks the options, defaulting to the size analyzer.
+func (o *DiffOptions) Validate() error {
+	return validateArgs(nil, o.checkIfValidAnalyzer, o.checkFilenameFlag, o.checkFailFastFlag, o.checkAllPlatformsFlag, o.checkSizeBudgetFlag, o.checkVersionGateFlags, o.checkDiffContentFlag, o.checkHotspotFlags, o.checkPackageFlags, o.checkFormatFlag, o.checkResultsFlags, o.checkTagFlags)
+}
+
+func (o *DiffOptions) checkTagFlags(_ []string) error {
+	if o.TagOffset < 0 {
+		return errors.New("--tag-offset must be positive")
+	}
+	if o.TagOffset > 0 && o.SinceTag != "" {
+		return errors.New("--tag-offset can't be combined with --since-tag")
+	}
+	if o.TagOrder != util.TagOrderSemver && o.TagOrder != util.TagOrderTime {
+		return fmt.Errorf("--tag-order must be %s or %s", util.TagOrderSemver, util.TagOrderTime)
+	}
+	return nil
+}
+
+// historical tells whether the image is compared against an earlier tag of
+// its repository.
+func (o *DiffOptions) historical() bool {
+	return o.TagOffset > 0 || o.SinceTag != ""
+}
+
+// resolveHistoricalImage returns the earlier tag of the repository of
+// imageArg which --tag-offset or --since-tag selects.
+func (o *DiffOptions) resolveHistoricalImage(imageArg string) (string, error) {
+	tags, err := pkgutil.ListTags(imageArg)
+	if err != nil {
+		return "", err
+	}
+	tag := o.SinceTag
+	if tag != "" {
+		found := false
+		for _, t := range tags {
+			found = found || t.Tag == tag
+		}
+		if !found {
+			return "", fmt.Errorf("tag %s not found in the repository of %s", tag, imageArg)
+		}
+	} else {
+		current, err := pkgutil.ImageTag(imageArg)
+		if err != nil {
+			return "", err
+		}
+		if o.TagOrder == util.TagOrderTime {
+			if err := pkgutil.ResolveTagTimes(imageArg, tags); err != nil {
+				return "", err
+			}
+		}
+		if tag, err = util.HistoricalTag(tags, current, o.TagOffset, o.TagOrder); err != nil {
+			return "", errors.Wrapf(err, "resolving the tag %d before %s", o.TagOffset, imageArg)
+		}
+	}
+	image, err := pkgutil.WithTag(imageArg, tag)
+	if err != nil {
+		return "", err
+	}
+	logrus.Infof("comparing %s against %s", imageArg, image)
+	return image, nil
+}
+
+func (o *DiffOptions) checkFilenameFlag(_ []string) error {
+	if o.Filename == "" {
 		return nil
 	}
-	for _, t := range types {
+	for _, t := range o.Types {
 		if t == "file" {
 			return nil
 		}
@@ -72,9 +202,157 @@ func checkFilenameFlag(_ []string) error {
 	return errors.New("please include --types=file with the --filename flag")
 }
 
-// processImage is a concurrency-friendly wrapper around getImageForName
-func processImage(imageName string, errChan chan<- error) *pkgutil.Image {
-	image, err := getImage(imageName)
+func (o *DiffOptions) checkFailFastFlag(_ []string) error {
+	if o.FailFastIdentical && o.ExpectedBase != "" {
+		return errors.New("--fail-fast-identical can't be combined with --expected-base, which needs the image layers")
+	}
+	return nil
+}
+
+func (o *DiffOptions) checkSizeBudgetFlag(_ []string) error {
+	if o.MaxSizeIncrease == "" {
+		return nil
+	}
+	if o.FailFastIdentical {
+		return errors.New("--fail-fast-identical can't be combined with --max-size-increase, which needs the image filesystems")
+	}
+	budget, err := parseSize(o.MaxSizeIncrease)
+	if err != nil {
+		return errors.Wrap(err, "--max-size-increase")
+	}
+	o.sizeBudget = budget
+	return nil
+}
+
+func (o *DiffOptions) checkVersionGateFlags(_ []string) error {
+	if !o.FailOnDowngrade && !o.FailOnMajor {
+		return nil
+	}
+	if o.FailFastIdentical {
+		return errors.New("--fail-fast-identical can't be combined with --fail-on-downgrade or --fail-on-major-upgrade, which need the image filesystems")
+	}
+	for _, t := range o.Types {
+		if differs.IsPackageAnalyzer(t) {
+			return nil
+		}
+	}
+	return errors.New("please include a package analyzer, such as --types=apt, with --fail-on-downgrade or --fail-on-major-upgrade")
+}
+
+// fileDiffOptions returns the file differ options of the flags, without
+// changing o, so one DiffOptions can serve several runs.
+func (o *DiffOptions) fileDiffOptions() (differs.FileDiffOptions, error) {
+	opts := o.FileDiff
+	if opts.ArchiveExtensions == nil {
+		opts.ArchiveExtensions = differs.DefaultArchiveExtensions
+	}
+	opts.InterestingFiles = o.InterestingFiles
+	if !o.NoInteresting {
+		opts.InterestingFiles = append(append([]string{}, util.DefaultInterestingFiles...), o.InterestingFiles...)
+	}
+	opts.ContentDiffMaxSize = differs.DefaultFileDiffOptions().ContentDiffMaxSize
+	if o.ContentMaxSize != "" {
+		size, err := parseSize(o.ContentMaxSize)
+		if err != nil {
+			return opts, errors.Wrap(err, "--diff-content-max-size")
+		}
+		opts.ContentDiffMaxSize = size
+	}
+	return opts, nil
+}
+
+func (o *DiffOptions) checkDiffContentFlag(_ []string) error {
+	if _, err := o.fileDiffOptions(); err != nil {
+		return err
+	}
+	if !o.FileDiff.DiffContent {
+		return nil
+	}
+	for _, t := range o.Types {
+		if t == "file" {
+			return nil
+		}
+	}
+	return errors.New("please include --types=file with the --diff-content flag")
+}
+
+func (o *DiffOptions) checkHotspotFlags(_ []string) error {
+	if o.FileDiff.HotspotDepth < 0 || o.FileDiff.HotspotTop < 0 {
+		return errors.New("--hotspot-depth and --hotspot-top can't be negative")
+	}
+	if o.FileDiff.HotspotDepth == 0 {
+		return nil
+	}
+	for _, t := range o.Types {
+		if t == "file" {
+			return nil
+		}
+	}
+	return errors.New("please include --types=file with the --hotspot-depth flag")
+}
+
+// parseSize reads a size in bytes, or with a unit such as 50M.
+func parseSize(s string) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && n >= 0 {
+		return n, nil
+	}
+	n, err := bytefmt.ToBytes(s)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// checkSizeBudget fails when the second image grew past --max-size-increase,
+// printing what grew it.
+func (o *DiffOptions) checkSizeBudget(image1, image2 pkgutil.Image, diffs map[string]util.Result) error {
+	if o.MaxSizeIncrease == "" {
+		return nil
+	}
+	err := differs.CheckSizeBudget(image1, image2, diffs, o.sizeBudget)
+	if budgetErr, ok := err.(*differs.SizeBudgetError); ok {
+		output.PrintToStdErr("%s", budgetErr.Explanation)
+	}
+	return err
+}
+
+// checkVersionChanges fails when a package changed version in a way
+// --fail-on-downgrade or --fail-on-major-upgrade disallows, listing the
+// changes in the error.
+func (o *DiffOptions) checkVersionChanges(diffs map[string]util.Result) error {
+	kinds := []string{}
+	if o.FailOnDowngrade {
+		kinds = append(kinds, util.ChangeDowngrade)
+	}
+	if o.FailOnMajor {
+		kinds = append(kinds, util.ChangeMajor)
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	return differs.CheckVersionChanges(diffs, kinds)
+}
+
+// processImage is a concurrency-friendly wrapper around getImage
+func (o *DiffOptions) processImage(imageName string, errChan chan<- error) *pkgutil.Image {
+	var materialize []string
+	if o.Filename != "" {
+		materialize = append(materialize, o.Filename)
+	}
+	fileOpts, err := o.fileDiffOptions()
+	if err != nil {
+		errChan <- err
+		return &pkgutil.Image{}
+	}
+	for _, t := range o.Types {
+		if t == "file" {
+			materialize = append(materialize, fileOpts.InterestingFiles...)
+		}
+	}
+	// patched files, archives and diffed contents are read from the
+	// extracted filesystem
+	extract := o.PatchDir != "" || fileOpts.ArchiveDepth > 0 || fileOpts.DiffContent
+	image, err := o.getImage(imageName, materialize, extract)
 	if err != nil {
 		errChan <- fmt.Errorf("error retrieving image %s: %s", imageName, err)
 	}
@@ -95,77 +373,236 @@ func readErrorsFromChannel(c chan error) error {
 	return nil
 }
 
-func diffImages(image1Arg, image2Arg string, diffArgs []string) error {
-	diffTypes, err := differs.GetAnalyzers(diffArgs)
+// checkIdentical reports whether two registry images resolve to the same
+// manifest, or with --fail-fast-identical to the same config and layers.
+// Other images would have to be exported to be compared, so they are left for
+// the diff, as are, unless --fail-fast-identical is set, registry images
+// which can't be resolved here.
+func (o *DiffOptions) checkIdentical(image1Arg, image2Arg string) (bool, string, error) {
+	if o.ExpectedBase != "" {
+		// the base is verified on the pulled image
+		return false, "", nil
+	}
+	if !pkgutil.IsRegistryImage(image1Arg) || !pkgutil.IsRegistryImage(image2Arg) {
+		logrus.Infof("not checking whether the images are identical: only images prefixed with remote:// are compared before they are retrieved")
+		return false, "", nil
+	}
+	identical, reason, err := pkgutil.CheckIdentical(image1Arg, image2Arg, o.FailFastIdentical)
 	if err != nil {
-		return errors.Wrap(err, "getting analyzers")
+		if !o.FailFastIdentical {
+			logrus.Infof("not checking whether the images are identical: %s", err)
+			return false, "", nil
+		}
+		return false, "", errors.Wrap(err, "checking whether images are identical")
 	}
+	return identical, reason, nil
+}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+// getDiffers returns the differs of the --types, configured by the options
+// of this diff.
+func (o *DiffOptions) getDiffers() ([]differs.Analyzer, error) {
+	diffTypes, err := differs.GetAnalyzers(o.Types)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting analyzers")
+	}
+	diffTypes = differs.WithOptions(diffTypes, o.Analyzers)
+	fileOpts, err := o.fileDiffOptions()
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range diffTypes {
+		if _, ok := d.(differs.FileAnalyzer); ok {
+			diffTypes[i] = differs.FileAnalyzer{Options: &fileOpts}
+		}
+	}
+	return diffTypes, nil
+}
 
-	logrus.Infof("starting diff on images %s and %s, using differs: %s\n", image1Arg, image2Arg, diffArgs)
+// Run diffs two images and writes the results.
+func (o *DiffOptions) Run(image1Arg, image2Arg string) error {
+	diffTypes, err := o.getDiffers()
+	if err != nil {
+		return err
+	}
+	defer o.start()()
 
-	var image1, image2 *pkgutil.Image
-	errChan := make(chan error, 2)
+	if o.AllPlatforms {
+		return o.diffPlatforms(image1Arg, image2Arg, diffTypes)
+	}
 
-	go func() {
-		defer wg.Done()
-		image1 = processImage(image1Arg, errChan)
-	}()
-	go func() {
-		defer wg.Done()
-		image2 = processImage(image2Arg, errChan)
-	}()
+	// compare digests first, so identical images are never pulled
+	identical, reason, err := o.checkIdentical(image1Arg, image2Arg)
+	if err != nil {
+		return err
+	}
+	if identical {
+		logrus.Infof("skipping analysis, %s and %s are identical: %s", image1Arg, image2Arg, reason)
+		results := map[string]util.Result{"identical": &util.IdenticalDiffResult{
+			Image1:   image1Arg,
+			Image2:   image2Arg,
+			DiffType: "Identical",
+			Diff:     util.IdenticalDiff{Identical: true, Reason: reason},
+		}}
+		o.outputResults(results)
+		// the images weren't pulled, so the results refer to no manifest
+		return o.pushResults(results, pkgutil.Image{Source: image2Arg})
+	}
 
-	wg.Wait()
-	close(errChan)
+	logrus.Infof("starting diff on images %s and %s, using differs: %s\n", image1Arg, image2Arg, o.Types)
 
-	if noCache && !save {
-		defer pkgutil.CleanupImage(*image1)
-		defer pkgutil.CleanupImage(*image2)
+	image1, image2, cleanup, err := o.retrieveImages(image1Arg, image2Arg)
+	defer cleanup()
+	if partial, ok := err.(*partialRetrievalError); ok && o.AllowPartial {
+		return o.analyzePartial(image1Arg, image2Arg, image1, image2, partial, diffTypes)
 	}
-
-	if err := readErrorsFromChannel(errChan); err != nil {
+	if err != nil {
+		return err
+	}
+	if err := o.checkExpectedBase(*image2); err != nil {
 		return err
 	}
 
 	logrus.Info("computing diffs")
 	req := differs.DiffRequest{
-		Image1:    *image1,
-		Image2:    *image2,
-		DiffTypes: diffTypes}
+		Image1:      *image1,
+		Image2:      *image2,
+		DiffTypes:   diffTypes,
+		Timings:     o.timings,
+		Concurrency: o.AnalyzerConcurrency}
 	diffs, err := req.GetDiff()
 	if err != nil {
 		return fmt.Errorf("could not retrieve diff: %s", err)
 	}
-	outputResults(diffs)
+	o.outputResults(diffs)
+	if err := o.pushResults(diffs, *image2); err != nil {
+		return err
+	}
 
-	if filename != "" {
+	if o.Filename != "" {
 		logrus.Info("computing filename diffs")
-		err := diffFile(image1, image2)
+		err := o.diffFile(image1, image2)
 		if err != nil {
 			return err
 		}
 	}
 
-	if noCache && save {
+	if o.PatchDir != "" {
+		logrus.Info("writing patches for changed files")
+		patches, err := util.WritePatches(image1, image2, o.PatchDir, o.PatchGlobs)
+		if err != nil {
+			return errors.Wrap(err, "writing patches")
+		}
+		output.PrintToStdErr("Wrote %d patch(es) to %s\n", len(patches), o.PatchDir)
+	}
+
+	if err := o.checkSizeBudget(*image1, *image2, diffs); err != nil {
+		return err
+	}
+	if err := o.checkVersionChanges(diffs); err != nil {
+		return err
+	}
+
+	if o.NoCache && o.Save {
 		logrus.Infof("images were saved at %s and %s", image1.FSPath,
 			image2.FSPath)
 	}
 	return nil
 }
 
-func diffFile(image1, image2 *pkgutil.Image) error {
-	diff, err := util.DiffFile(image1, image2, filename)
+// partialRetrievalError is returned by retrieveImages when only one of the
+// images could be retrieved, so that --allow-partial can analyze the other.
+type partialRetrievalError struct {
+	// failed is the index, 1 or 2, of the image which couldn't be retrieved.
+	failed int
+	err    error
+}
+
+func (e *partialRetrievalError) Error() string {
+	return e.err.Error()
+}
+
+// retrieveImages retrieves both images concurrently. The returned function
+// removes the image filesystems which are not to be kept, and must be called
+// even if retrieval fails.
+func (o *DiffOptions) retrieveImages(image1Arg, image2Arg string) (*pkgutil.Image, *pkgutil.Image, func(), error) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	var image1, image2 *pkgutil.Image
+	errChan1 := make(chan error, 1)
+	errChan2 := make(chan error, 1)
+
+	go func() {
+		defer wg.Done()
+		image1 = o.processImage(image1Arg, errChan1)
+	}()
+	go func() {
+		defer wg.Done()
+		image2 = o.processImage(image2Arg, errChan2)
+	}()
+
+	wg.Wait()
+	close(errChan1)
+	close(errChan2)
+
+	cleanup := func() {
+		o.cleanupImage(*image1)
+		o.cleanupImage(*image2)
+	}
+	err1, err2 := readErrorsFromChannel(errChan1), readErrorsFromChannel(errChan2)
+	switch {
+	case err1 != nil && err2 != nil:
+		return image1, image2, cleanup, errors.New(err1.Error() + "\n" + err2.Error())
+	case err1 != nil:
+		return image1, image2, cleanup, &partialRetrievalError{failed: 1, err: err1}
+	case err2 != nil:
+		return image1, image2, cleanup, &partialRetrievalError{failed: 2, err: err2}
+	}
+	return image1, image2, cleanup, nil
+}
+
+// analyzePartial outputs the analysis of the image which could be retrieved
+// along with the error for the other, and returns that error.
+func (o *DiffOptions) analyzePartial(image1Arg, image2Arg string, image1, image2 *pkgutil.Image, partial *partialRetrievalError, diffTypes []differs.Analyzer) error {
+	image, failed := image1, image2Arg
+	if partial.failed == 1 {
+		image, failed = image2, image1Arg
+	}
+	logrus.Warnf("only analyzing %s: %s", image.Source, partial.err)
+	req := differs.SingleRequest{
+		Image:        *image,
+		AnalyzeTypes: diffTypes,
+		Timings:      o.timings,
+		Concurrency:  o.AnalyzerConcurrency}
+	results, err := req.GetAnalysis()
+	if err != nil {
+		logrus.Error(err)
+		results = map[string]util.Result{}
+	}
+	results["partial"] = &util.PartialDiffResult{
+		Image1:   image1Arg,
+		Image2:   image2Arg,
+		DiffType: "Partial",
+		Diff: util.PartialDiff{
+			AnalyzedImage: image.Source,
+			FailedImage:   failed,
+			Error:         partial.err.Error(),
+		},
+	}
+	o.outputResults(results)
+	return partial.err
+}
+
+func (o *DiffOptions) diffFile(image1, image2 *pkgutil.Image) error {
+	diff, err := util.DiffFile(image1, image2, o.Filename)
 	if err != nil {
 		return err
 	}
-	writer, err := getWriter(outputFile)
+	writer, err := o.getWriter()
 	if err != nil {
 		return err
 	}
-	util.TemplateOutput(writer, diff, "FilenameDiff")
+	util.TemplateOutput(writer, diff, "FilenameDiff", o.Output)
 	if err != nil {
 		logrus.Error(err)
 		return err
@@ -174,8 +611,5 @@ func diffFile(image1, image2 *pkgutil.Image) error {
 }
 
 func init() {
-	diffCmd.Flags().StringVarP(&filename, "filename", "f", "", "Set this flag to the path of a file in both containers to view the diff of the file. Must be used with --types=file flag.")
-	RootCmd.AddCommand(diffCmd)
-	addSharedFlags(diffCmd)
-	output.AddFlags(diffCmd)
+	RootCmd.AddCommand(newDiffCmd())
 }
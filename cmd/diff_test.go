@@ -17,7 +17,25 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/spf13/cobra"
 )
 
 var diffArgNumTests = []testpair{
@@ -34,6 +52,118 @@ func TestDiffArgNum(t *testing.T) {
 	}
 }
 
+func TestDiffFileOptions(t *testing.T) {
+	content := &DiffOptions{
+		SharedOptions:  SharedOptions{Types: []string{"file"}},
+		ContentMaxSize: "1K",
+		NoInteresting:  true,
+		TagOrder:       util.TagOrderSemver,
+		FileDiff:       differs.FileDiffOptions{DiffContent: true, HotspotDepth: 2, HotspotTop: 5},
+	}
+	plain := &DiffOptions{
+		SharedOptions:    SharedOptions{Types: []string{"apt", "file"}},
+		InterestingFiles: []string{"*.service"},
+		TagOrder:         util.TagOrderSemver,
+		FileDiff:         differs.FileDiffOptions{ExpandApplets: true, ArchiveDepth: 1},
+	}
+	for _, opts := range []*DiffOptions{content, plain} {
+		if err := opts.Validate(); err != nil {
+			t.Fatalf("Error validating %v: %s", opts.Types, err)
+		}
+	}
+	if content.FileDiff.InterestingFiles != nil || content.FileDiff.ContentDiffMaxSize != 0 {
+		t.Errorf("Validate changed the file diff options: %+v", content.FileDiff)
+	}
+
+	tests := []struct {
+		opts     *DiffOptions
+		expected differs.FileDiffOptions
+	}{
+		{
+			opts: content,
+			expected: differs.FileDiffOptions{
+				ArchiveExtensions:  differs.DefaultArchiveExtensions,
+				DiffContent:        true,
+				ContentDiffMaxSize: 1024,
+				HotspotDepth:       2,
+				HotspotTop:         5,
+			},
+		},
+		{
+			opts: plain,
+			expected: differs.FileDiffOptions{
+				ExpandApplets:      true,
+				ArchiveDepth:       1,
+				ArchiveExtensions:  differs.DefaultArchiveExtensions,
+				ContentDiffMaxSize: 64 * 1024,
+				InterestingFiles:   append(append([]string{}, util.DefaultInterestingFiles...), "*.service"),
+			},
+		},
+	}
+	for _, test := range tests {
+		diffTypes, err := test.opts.getDiffers()
+		if err != nil {
+			t.Fatalf("Error getting differs of %v: %s", test.opts.Types, err)
+		}
+		file, ok := diffTypes[len(diffTypes)-1].(differs.FileAnalyzer)
+		if !ok || file.Options == nil {
+			t.Fatalf("Expected a configured file differ for %v, got %#v", test.opts.Types, diffTypes)
+		}
+		if !reflect.DeepEqual(*file.Options, test.expected) {
+			t.Errorf("Expected file diff options for %v\n%+v\nbut got\n%+v", test.opts.Types, test.expected, *file.Options)
+		}
+	}
+	if _, ok := differs.Analyzers["file"].(differs.FileAnalyzer); !ok || differs.Analyzers["file"].(differs.FileAnalyzer).Options != nil {
+		t.Errorf("Registered file differ was configured: %#v", differs.Analyzers["file"])
+	}
+}
+
+func TestDiffAnalyzerOptions(t *testing.T) {
+	filtered := &DiffOptions{
+		SharedOptions: SharedOptions{
+			Types:     []string{"apt", "vuln", "metadata"},
+			Analyzers: differs.AnalyzerOptions{IgnorePackages: []string{"tzdata"}, AdvisoryDBPath: "advisories.json"},
+		},
+	}
+	plain := &DiffOptions{SharedOptions: SharedOptions{Types: []string{"apt", "vuln", "metadata"}}}
+	for _, opts := range []*DiffOptions{filtered, plain} {
+		diffTypes, err := opts.getDiffers()
+		if err != nil {
+			t.Fatalf("Error getting differs of %v: %s", opts.Types, err)
+		}
+		apt, ok := diffTypes[0].(differs.AptAnalyzer)
+		if !ok || apt.Options == nil || !reflect.DeepEqual(*apt.Options, opts.Analyzers) {
+			t.Errorf("Expected the apt differ configured with %+v, got %#v", opts.Analyzers, diffTypes[0])
+		}
+		vuln, ok := diffTypes[1].(differs.VulnAnalyzer)
+		if !ok || vuln.Options == nil || !reflect.DeepEqual(*vuln.Options, opts.Analyzers) {
+			t.Errorf("Expected the vuln differ configured with %+v, got %#v", opts.Analyzers, diffTypes[1])
+		}
+	}
+	if differs.Analyzers["apt"].(differs.AptAnalyzer).Options != nil {
+		t.Errorf("Registered apt differ was configured: %#v", differs.Analyzers["apt"])
+	}
+
+	for _, test := range []struct {
+		args []string
+		skip bool
+	}{
+		{args: []string{}, skip: false},
+		{args: []string{"--follow-package-symlinks"}, skip: false},
+		{args: []string{"--follow-package-symlinks=false"}, skip: true},
+	} {
+		opts := SharedOptions{}
+		cmd := &cobra.Command{}
+		addSharedFlags(cmd, &opts)
+		if err := cmd.ParseFlags(test.args); err != nil {
+			t.Fatalf("Error parsing %v: %s", test.args, err)
+		}
+		if opts.Analyzers.SkipPackageSymlinks != test.skip {
+			t.Errorf("%v: Expected SkipPackageSymlinks %t", test.args, test.skip)
+		}
+	}
+}
+
 type imageDiff struct {
 	image1      string
 	image2      string
@@ -49,13 +179,126 @@ var imageDiffs = []imageDiff{
 
 func TestDiffImages(t *testing.T) {
 	for _, test := range imageDiffs {
-		err := diffImages(test.image1, test.image2, []string{"apt"})
+		opts := DiffOptions{SharedOptions: SharedOptions{Types: []string{"apt"}}}
+		err := opts.Run(test.image1, test.image2)
 		checkError(t, err, test.shouldError)
-		err = diffImages(test.image1, test.image2, []string{"metadata"})
+		opts = DiffOptions{SharedOptions: SharedOptions{Types: []string{"metadata"}}}
+		err = opts.Run(test.image1, test.image2)
 		checkError(t, err, test.shouldError)
 	}
 }
 
+func TestDiffAllowPartial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "partial")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(16, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+	present := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(present, tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+	missing := filepath.Join(dir, "missing.tar")
+
+	var output bytes.Buffer
+	opts := DiffOptions{SharedOptions: SharedOptions{Types: []string{"size"}, NoCache: true, JSON: true, Writer: &output}, AllowPartial: true}
+	err = opts.Run(present, missing)
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected error retrieving %s but got: %v", missing, err)
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
+		t.Fatalf("Error parsing JSON output: %s\n%s", err, output.String())
+	}
+	if len(results) != 2 || results[0]["AnalyzeType"] != "Size" || results[1]["DiffType"] != "Partial" {
+		t.Fatalf("Expected the size analysis of %s and a partial result but got:\n%s", present, output.String())
+	}
+	partial := results[1]["Diff"].(map[string]interface{})
+	if partial["AnalyzedImage"] != present || partial["FailedImage"] != missing {
+		t.Errorf("Expected %s analyzed and %s failed but got: %v", present, missing, partial)
+	}
+
+	output.Reset()
+	opts.AllowPartial = false
+	if err := opts.Run(present, missing); err == nil {
+		t.Errorf("Expected error without --allow-partial but got none")
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no output without --allow-partial but got:\n%s", output.String())
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	img, err := random.Image(16, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	manifest, _ := img.RawManifest()
+	digest, _ := img.Digest()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/v2/app/manifests/"):
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			w.Write(manifest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	repo := "remote://" + strings.TrimPrefix(server.URL, "http://") + "/app:"
+
+	var output bytes.Buffer
+	opts := DiffOptions{SharedOptions: SharedOptions{Types: []string{"file"}, NoCache: true, JSON: true, Writer: &output}}
+	if err := opts.Run(repo+"v1", repo+"v2"); err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	var results []struct {
+		DiffType string
+		Diff     struct{ Identical bool }
+	}
+	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
+		t.Fatalf("Error reading results: %s\n%s", err, output.String())
+	}
+	if len(results) != 1 || results[0].DiffType != "Identical" || !results[0].Diff.Identical {
+		t.Errorf("Expected a single identical result but got:\n%s", output.String())
+	}
+	for _, request := range requests {
+		if strings.HasPrefix(request, "GET /v2/app/") {
+			t.Errorf("Expected only HEAD requests for the manifests, got %v", requests)
+			break
+		}
+	}
+
+	// local images are left for the diff rather than exported to be compared
+	dir, err := ioutil.TempDir("", "identical")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+	os.Setenv("DOCKER_HOST", "unix://"+filepath.Join(dir, "docker.sock"))
+	for _, images := range [][2]string{{"daemon://app:v1", "daemon://app:v1"}, {repo + "v1", "app:v1"}} {
+		identical, _, err := opts.checkIdentical(images[0], images[1])
+		if identical || err != nil {
+			t.Errorf("Expected %s and %s left for the diff, got %t, %v", images[0], images[1], identical, err)
+		}
+	}
+	opts.FailFastIdentical = true
+	if identical, _, err := opts.checkIdentical("daemon://app:v1", "daemon://app:v1"); identical || err != nil {
+		t.Errorf("Expected daemon images left for the diff with --fail-fast-identical, got %t, %v", identical, err)
+	}
+}
+
 func checkError(t *testing.T, err error, shouldError bool) {
 	if (err == nil) == shouldError {
 		if shouldError {
@@ -65,3 +308,169 @@ func checkError(t *testing.T, err error, shouldError bool) {
 		}
 	}
 }
+
+func TestResolveHistoricalImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/app/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/app/tags/list?last=v1.1.0&n=3>; rel="next"`)
+			w.Write([]byte(`{"name": "app", "tags": ["latest", "v1.0.0", "v1.1.0"]}`))
+		case r.URL.Path == "/v2/app/tags/list":
+			w.Write([]byte(`{"name": "app", "tags": ["v1.2.0", "v2.0.0"], "manifest": {
+				"sha256:aa": {"tag": ["v1.0.0"], "timeUploadedMs": "1000"},
+				"sha256:bb": {"tag": ["v1.2.0"], "timeUploadedMs": "2000"},
+				"sha256:cc": {"tag": ["v1.1.0"], "timeUploadedMs": "3000"},
+				"sha256:dd": {"tag": ["latest", "v2.0.0"], "timeUploadedMs": "4000"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	image := "remote://" + strings.TrimPrefix(server.URL, "http://") + "/app"
+
+	testCases := []struct {
+		descrip     string
+		opts        DiffOptions
+		image       string
+		expected    string
+		shouldError bool
+	}{
+		{descrip: "previous version", opts: DiffOptions{TagOffset: 1, TagOrder: "semver"}, image: image + ":v2.0.0", expected: image + ":v1.2.0"},
+		{descrip: "previous push", opts: DiffOptions{TagOffset: 1, TagOrder: "time"}, image: image, expected: image + ":v1.1.0"},
+		{descrip: "since tag", opts: DiffOptions{SinceTag: "v1.0.0", TagOrder: "semver"}, image: image + ":v2.0.0", expected: image + ":v1.0.0"},
+		{descrip: "unknown since tag", opts: DiffOptions{SinceTag: "v0.9.0", TagOrder: "semver"}, image: image + ":v2.0.0", shouldError: true},
+		{descrip: "too far back", opts: DiffOptions{TagOffset: 4, TagOrder: "semver"}, image: image + ":v2.0.0", shouldError: true},
+	}
+	for _, test := range testCases {
+		resolved, err := test.opts.resolveHistoricalImage(test.image)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", test.descrip, resolved)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.descrip, err)
+		} else if resolved != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.descrip, test.expected, resolved)
+		}
+	}
+}
+
+func TestDiffAllPlatformsOCILayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "platforms")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	// each image is written to the layout, keeping the descriptor of its
+	// manifest for the indexes
+	manifest := func(platform v1.Platform) v1.Descriptor {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("Error creating image: %s", err)
+		}
+		writeOCILayout(t, dir, img, v1.Descriptor{Platform: &platform})
+		var index v1.IndexManifest
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+		json.Unmarshal(data, &index)
+		return index.Manifests[0]
+	}
+	index := func(tag string, manifests ...v1.Descriptor) v1.Descriptor {
+		data, _ := json.Marshal(v1.IndexManifest{SchemaVersion: 2, MediaType: types.OCIImageIndex, Manifests: manifests})
+		h, size, _ := v1.SHA256(bytes.NewReader(data))
+		if err := ioutil.WriteFile(filepath.Join(dir, "blobs", h.Algorithm, h.Hex), data, 0644); err != nil {
+			t.Fatalf("Error writing index: %s", err)
+		}
+		return v1.Descriptor{MediaType: types.OCIImageIndex, Size: size, Digest: h, Annotations: map[string]string{"org.opencontainers.image.ref.name": tag}}
+	}
+	amd64 := manifest(v1.Platform{OS: "linux", Architecture: "amd64"})
+	arm64 := manifest(v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	arm64Updated := manifest(v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	riscv64 := manifest(v1.Platform{OS: "linux", Architecture: "riscv64"})
+	data, _ := json.Marshal(v1.IndexManifest{SchemaVersion: 2, Manifests: []v1.Descriptor{
+		index("v1", amd64, arm64),
+		index("v2", amd64, arm64Updated, riscv64),
+	}})
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), data, 0644)
+
+	var output bytes.Buffer
+	opts := DiffOptions{SharedOptions: SharedOptions{Types: []string{"size"}, NoCache: true, JSON: true, Writer: &output}, AllPlatforms: true}
+	if err := opts.Run("oci://"+dir+":v1", "oci://"+dir+":v2"); err != nil {
+		t.Fatalf("Error diffing platforms: %s", err)
+	}
+	var results struct {
+		Summary struct {
+			Diff []struct {
+				Platform string
+				Status   string
+			}
+		}
+		Platforms []struct {
+			Platform string
+			Image1   string
+			Image2   string
+		}
+	}
+	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
+		t.Fatalf("Error parsing JSON output: %s\n%s", err, output.String())
+	}
+	statuses := map[string]string{}
+	for _, p := range results.Summary.Diff {
+		statuses[p.Platform] = p.Status
+	}
+	expected := map[string]string{"linux/amd64": "identical", "linux/arm64/v8": "changed", "linux/riscv64": "added"}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected platforms %v but got %v", expected, statuses)
+	}
+	if len(results.Platforms) != 1 || results.Platforms[0].Platform != "linux/arm64/v8" {
+		t.Fatalf("Expected only linux/arm64/v8 diffed but got:\n%s", output.String())
+	}
+	if image := "oci://" + dir + "@" + arm64.Digest.String(); results.Platforms[0].Image1 != image {
+		t.Errorf("Expected linux/arm64/v8 of v1 read as %s but got %s", image, results.Platforms[0].Image1)
+	}
+
+	opts.AllPlatforms = false
+	if err := opts.Run("oci://"+dir+"@"+amd64.Digest.String(), "oci://"+dir+"@"+riscv64.Digest.String()); err != nil {
+		t.Errorf("Error diffing images of the layout by digest: %s", err)
+	}
+	opts.AllPlatforms = true
+	if err := opts.Run("oci://"+dir+"@"+amd64.Digest.String(), "oci://"+dir+":v2"); err == nil || !strings.Contains(err.Error(), "single image") {
+		t.Errorf("Expected an error diffing the platforms of a single image but got %v", err)
+	}
+}
+
+// writeOCILayout writes img to an OCI image layout in dir, listed in its
+// index once for each of descs, completed with the manifest of img.
+func writeOCILayout(t *testing.T, dir string, img v1.Image, descs ...v1.Descriptor) {
+	writeBlob := func(data []byte) v1.Hash {
+		h, _, _ := v1.SHA256(bytes.NewReader(data))
+		if err := os.MkdirAll(filepath.Join(dir, "blobs", h.Algorithm), 0755); err != nil {
+			t.Fatalf("Error creating blobs dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "blobs", h.Algorithm, h.Hex), data, 0644); err != nil {
+			t.Fatalf("Error writing blob: %s", err)
+		}
+		return h
+	}
+	layers, _ := img.Layers()
+	for _, layer := range layers {
+		r, _ := layer.Compressed()
+		data, _ := ioutil.ReadAll(r)
+		writeBlob(data)
+	}
+	config, _ := img.RawConfigFile()
+	writeBlob(config)
+	manifest, _ := img.RawManifest()
+	mediaType, _ := img.MediaType()
+	index := v1.IndexManifest{SchemaVersion: 2}
+	for _, desc := range descs {
+		desc.MediaType, desc.Size, desc.Digest = mediaType, int64(len(manifest)), writeBlob(manifest)
+		index.Manifests = append(index.Manifests, desc)
+	}
+	data, _ := json.Marshal(index)
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), data, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0644)
+}
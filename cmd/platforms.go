@@ -0,0 +1,115 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// platformResults are the diffs of one platform in JSON output
+type platformResults struct {
+	Platform string
+	Image1   string
+	Image2   string
+	Results  []interface{}
+}
+
+func (o *DiffOptions) checkAllPlatformsFlag(_ []string) error {
+	if o.AllPlatforms && (o.Filename != "" || o.PatchDir != "" || o.ExpectedBase != "" || o.PushResult != "" || o.MaxSizeIncrease != "") {
+		return errors.New("--all-platforms can't be combined with --filename, --patch-dir, --expected-base, --push-result or --max-size-increase")
+	}
+	return nil
+}
+
+// diffPlatforms diffs each platform two manifest lists have in common, and
+// summarizes which platforms were added, removed, changed or left identical.
+func (o *DiffOptions) diffPlatforms(image1Arg, image2Arg string, diffTypes []differs.Analyzer) error {
+	images1, err := pkgutil.GetPlatformImages(image1Arg)
+	if err != nil {
+		return err
+	}
+	images2, err := pkgutil.GetPlatformImages(image2Arg)
+	if err != nil {
+		return err
+	}
+	summary := util.MatchPlatforms(images1, images2)
+	summaryResult := &util.PlatformDiffResult{
+		Image1:   image1Arg,
+		Image2:   image2Arg,
+		DiffType: "Platforms",
+		Diff:     summary,
+	}
+
+	writer, err := o.getWriter()
+	if err != nil {
+		return err
+	}
+	if !o.JSON && o.Output.Format != util.DotFormat {
+		if err := summaryResult.OutputText(writer, "platforms", o.Output); err != nil {
+			return err
+		}
+	}
+	platforms := []platformResults{}
+	for _, p := range summary {
+		if p.Status != util.PlatformChanged {
+			continue
+		}
+		logrus.Infof("diffing %s images %s and %s", p.Platform, p.Reference1, p.Reference2)
+		diffs, err := o.diffPlatform(p.Reference1, p.Reference2, diffTypes)
+		if err != nil {
+			return fmt.Errorf("could not diff %s images: %s", p.Platform, err)
+		}
+		if !o.JSON {
+			fmt.Fprintf(writer, "\n===== %s =====\n", p.Platform)
+			o.outputResults(diffs)
+			continue
+		}
+		results := platformResults{Platform: p.Platform, Image1: p.Reference1, Image2: p.Reference2, Results: []interface{}{}}
+		for _, analyzerType := range sortedResultTypes(diffs) {
+			results.Results = append(results.Results, diffs[analyzerType].OutputStruct(o.Output))
+		}
+		platforms = append(platforms, results)
+	}
+	if o.JSON {
+		return util.JSONify(writer, struct {
+			Summary   interface{}
+			Platforms []platformResults
+		}{summaryResult.OutputStruct(o.Output), platforms})
+	}
+	return nil
+}
+
+func (o *DiffOptions) diffPlatform(image1Arg, image2Arg string, diffTypes []differs.Analyzer) (map[string]util.Result, error) {
+	image1, image2, cleanup, err := o.retrieveImages(image1Arg, image2Arg)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	req := differs.DiffRequest{
+		Image1:      *image1,
+		Image2:      *image2,
+		DiffTypes:   diffTypes,
+		Timings:     o.timings,
+		Concurrency: o.AnalyzerConcurrency}
+	return req.GetDiff()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/GoogleContainerTools/container-diff/cmd/util/output"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// PrefetchOptions are the options of the prefetch command.
+type PrefetchOptions struct {
+	SharedOptions
+	Concurrency int
+}
+
+func newPrefetchCmd() *cobra.Command {
+	opts := &PrefetchOptions{}
+	cmd := &cobra.Command{
+		Use:   "prefetch image...",
+		Short: "Pull images into the cache without analyzing them: container-diff prefetch image1 image2 ...",
+		Long: `Pulls images and extracts their filesystems into the cache, so that later
+analyze and diff runs using the same cache directory start right away.
+Images are pulled concurrently, and --platform picks the platform of
+multi-platform images.
+
+For details on how to specify images, run: container-diff help`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := checkPrefetchArgNum(args); err != nil {
+				return err
+			}
+			return opts.Validate()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.Run(args); err != nil {
+				logrus.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 4, "Maximum number of images to pull at the same time.")
+	cmd.Flags().StringVarP(&opts.CacheDir, "cache-dir", "c", "", "cache directory base to create .container-diff (default is $HOME).")
+	output.AddFlags(cmd)
+	return cmd
+}
+
+func checkPrefetchArgNum(args []string) error {
+	if len(args) == 0 {
+		return errors.New("'prefetch' requires at least one image as an argument: container-diff prefetch [image...]")
+	}
+	return nil
+}
+
+// Validate checks the options.
+func (o *PrefetchOptions) Validate() error {
+	if o.Concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	return nil
+}
+
+// Run pulls the images into the cache, up to Concurrency at a time. Every
+// image is attempted even if some fail, and the errors are combined.
+func (o *PrefetchOptions) Run(imageNames []string) error {
+	// an image named twice would be extracted twice into the same directory
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range imageNames {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(unique))
+	slots := make(chan struct{}, o.Concurrency)
+	for _, name := range unique {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			image, err := o.getImage(name, nil, true)
+			if err != nil {
+				errChan <- fmt.Errorf("error retrieving image %s: %s", name, err)
+				return
+			}
+			output.PrintToStdErr("Cached %s in %s\n", name, image.FSPath)
+		}(name)
+	}
+	wg.Wait()
+	close(errChan)
+	return readErrorsFromChannel(errChan)
+}
+
+func init() {
+	RootCmd.AddCommand(newPrefetchCmd())
+}
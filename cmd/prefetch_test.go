@@ -0,0 +1,62 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrefetchArgNum(t *testing.T) {
+	if err := checkPrefetchArgNum([]string{}); err == nil {
+		t.Errorf("Expected error for no images but got none")
+	}
+	for _, args := range [][]string{{"one"}, {"one", "two", "three"}} {
+		if err := checkPrefetchArgNum(args); err != nil {
+			t.Errorf("Got unexpected error for %v: %s", args, err)
+		}
+	}
+}
+
+func TestPrefetchValidate(t *testing.T) {
+	if err := (&PrefetchOptions{Concurrency: 0}).Validate(); err == nil {
+		t.Errorf("Expected error for a concurrency of 0 but got none")
+	}
+	if err := (&PrefetchOptions{Concurrency: 2}).Validate(); err != nil {
+		t.Errorf("Got unexpected error: %s", err)
+	}
+}
+
+func TestPrefetchReportsEveryFailure(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "prefetch")
+	if err != nil {
+		t.Fatalf("Error creating cache dir: %s", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	opts := PrefetchOptions{SharedOptions: SharedOptions{CacheDir: cacheDir}, Concurrency: 1}
+	err = opts.Run([]string{"missing1.tar", "missing2.tar", "missing1.tar"})
+	if err == nil {
+		t.Fatalf("Expected error for missing images but got none")
+	}
+	for _, name := range []string{"missing1.tar", "missing2.tar"} {
+		if strings.Count(err.Error(), "error retrieving image "+name) != 1 {
+			t.Errorf("Expected one error for %s but got: %s", name, err)
+		}
+	}
+}
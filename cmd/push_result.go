@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/cmd/util/output"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/pkg/errors"
+)
+
+// pushResults pushes the results to the --push-result repository, referring
+// to subject, if the flag is set.
+func (o *SharedOptions) pushResults(resultMap map[string]util.Result, subject pkgutil.Image) error {
+	if o.PushResult == "" {
+		return nil
+	}
+	results, err := json.MarshalIndent(o.jsonResults(resultMap), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling results")
+	}
+	files := []pkgutil.ArtifactFile{
+		{Name: "results.json", MediaType: "application/json", Data: results},
+		{Name: "report.html", MediaType: "text/html", Data: o.htmlReport(resultMap, subject.Source)},
+	}
+	digest, err := pkgutil.PushResultArtifact(o.PushResult, subject, files, o.Annotations)
+	if err != nil {
+		return errors.Wrapf(err, "pushing results to %s", o.PushResult)
+	}
+	output.PrintToStdErr("Pushed results to %s as %s\n", o.PushResult, digest)
+	return nil
+}
+
+// htmlReport renders the text output of the results as a standalone page.
+func (o *SharedOptions) htmlReport(resultMap map[string]util.Result, title string) []byte {
+	var text bytes.Buffer
+	if len(o.Annotations) > 0 {
+		writeAnnotations(&text, o.Annotations)
+	}
+	for _, analyzerType := range sortedResultTypes(resultMap) {
+		if err := resultMap[analyzerType].OutputText(&text, analyzerType, o.Output); err != nil {
+			fmt.Fprintf(&text, "error printing %s results: %s\n", analyzerType, err)
+		}
+	}
+	var page strings.Builder
+	fmt.Fprintf(&page, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>container-diff: %s</title>\n</head>\n", html.EscapeString(title))
+	fmt.Fprintf(&page, "<body>\n<h1>%s</h1>\n<pre>%s</pre>\n</body>\n</html>\n", html.EscapeString(title), html.EscapeString(text.String()))
+	return []byte(page.String())
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+// fakeRegistry stores what is pushed to it, and knows the manifest of one
+// image. Like registries without the referrers API, it returns no
+// OCI-Subject header.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	image     string
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	p := r.URL.Path
+	switch {
+	case p == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead && strings.HasPrefix(p, "/v2/results/manifests/"+f.image):
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+		w.Header().Set("Content-Length", "1234")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead && strings.HasPrefix(p, "/v2/results/blobs/"):
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPost && p == "/v2/results/blobs/uploads/":
+		w.Header().Set("Location", "/v2/results/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && p == "/v2/results/blobs/uploads/1":
+		f.blobs[r.URL.Query().Get("digest")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(p, "/v2/results/manifests/"):
+		f.manifests[strings.TrimPrefix(p, "/v2/results/manifests/")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && strings.HasPrefix(p, "/v2/results/manifests/"):
+		if m, ok := f.manifests[strings.TrimPrefix(p, "/v2/results/manifests/")]; ok {
+			w.Write(m)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushResults(t *testing.T) {
+	img, err := random.Image(16, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := &fakeRegistry{image: digest.String(), blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	o := &SharedOptions{PushResult: "oci://" + host + "/results:build-1", Annotations: map[string]string{"build": "1"}}
+	results := map[string]util.Result{"history": &util.HistDiffResult{
+		Image1:   "image1",
+		Image2:   host + "/results:latest",
+		DiffType: "History",
+		Diff:     differs.HistDiff{Adds: []string{"RUN <make>"}, Dels: []string{"RUN make"}},
+	}}
+	subject := pkgutil.Image{Source: host + "/results:latest", Image: img}
+	if err := o.pushResults(results, subject); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	var manifest struct {
+		ArtifactType string
+		Layers       []struct {
+			MediaType string
+			Digest    string
+		}
+		Subject struct {
+			Digest string
+			Size   int64
+		}
+		Annotations map[string]string
+	}
+	if err := json.Unmarshal(registry.manifests["build-1"], &manifest); err != nil {
+		t.Fatalf("Could not read pushed manifest: %s", err)
+	}
+	if manifest.ArtifactType != pkgutil.ResultArtifactType {
+		t.Errorf("Expected artifact type %s but got %s", pkgutil.ResultArtifactType, manifest.ArtifactType)
+	}
+	if manifest.Subject.Digest != digest.String() || manifest.Subject.Size != 1234 {
+		t.Errorf("Expected subject %s of size 1234 but got %+v", digest, manifest.Subject)
+	}
+	if manifest.Annotations["build"] != "1" {
+		t.Errorf("Expected the build annotation but got %v", manifest.Annotations)
+	}
+	if len(manifest.Layers) != 2 {
+		t.Fatalf("Expected results and report layers but got %+v", manifest.Layers)
+	}
+	var pushed util.ResultsWithMetadata
+	if err := json.Unmarshal(registry.blobs[manifest.Layers[0].Digest], &pushed); err != nil {
+		t.Fatalf("Could not read pushed results: %s", err)
+	}
+	if len(pushed.Results) != 1 || pushed.Annotations["build"] != "1" {
+		t.Errorf("Expected one result with annotations but got %+v", pushed)
+	}
+	if report := string(registry.blobs[manifest.Layers[1].Digest]); !strings.Contains(report, "RUN &lt;make&gt;") {
+		t.Errorf("Expected the escaped history in the report but got %s", report)
+	}
+
+	// registries without the referrers API list referrers in a tagged index
+	if index := string(registry.manifests[strings.Replace(digest.String(), ":", "-", 1)]); !strings.Contains(index, pkgutil.ResultArtifactType) {
+		t.Errorf("Expected the artifact in the referrers tag but got %s", index)
+	}
+}
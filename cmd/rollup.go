@@ -0,0 +1,169 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// RollupOptions are the options of the rollup command.
+type RollupOptions struct {
+	JSON bool
+	CSV  bool
+	// Package, as name or name@version, lists the images having it instead
+	// of the fleet statistics
+	Package string
+	// Top is how many of the most common packages text output shows
+	Top int
+}
+
+func newRollupCmd() *cobra.Command {
+	opts := &RollupOptions{}
+	cmd := &cobra.Command{
+		Use:   "rollup results...",
+		Short: "Aggregate the JSON results of many runs: container-diff rollup results-dir/",
+		Long: `Reads the JSON results of analyze and diff runs, given as files or
+directories searched for .json files, and reports fleet-level statistics:
+the most common packages and their versions, from the results of package
+analyzers, and the image sizes and size changes, from the results of the
+size analyzer. With --package, the images having a package, at any
+version or at name@version, are listed instead.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("'rollup' requires at least one results file or directory: container-diff rollup [results...]")
+			}
+			return opts.Validate()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.Run(os.Stdout, args); err != nil {
+				logrus.Error(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&opts.JSON, "json", "j", false, "Write the statistics, or the images having --package, as JSON.")
+	cmd.Flags().BoolVar(&opts.CSV, "csv", false, "Write a type,package,version,images row for every package version, or a type,package,version,image row for every image having --package, as CSV.")
+	cmd.Flags().StringVar(&opts.Package, "package", "", "List the images having this package, e.g. openssl, or this version of it, e.g. openssl@3.0.2-0ubuntu1.10.")
+	cmd.Flags().IntVar(&opts.Top, "top", 20, "Number of the most common packages to show in text output. Set to 0 to show all of them. JSON and CSV output list all of them.")
+	return cmd
+}
+
+// Validate checks the options.
+func (o *RollupOptions) Validate() error {
+	if o.JSON && o.CSV {
+		return errors.New("--json and --csv can't be used together")
+	}
+	if o.Top < 0 {
+		return errors.New("--top can't be negative")
+	}
+	return nil
+}
+
+// Run aggregates the results found in paths and writes them to w. Files
+// which aren't results are skipped with a warning, as result directories
+// often hold other files too.
+func (o *RollupOptions) Run(w io.Writer, paths []string) error {
+	files, err := findResultFiles(paths)
+	if err != nil {
+		return err
+	}
+	builder := util.NewRollupBuilder(differs.PackageAnalyzeTypes())
+	read := 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := builder.AddDocument(data); err != nil {
+			logrus.Warnf("skipping %s, which holds no container-diff results: %s", file, err)
+			continue
+		}
+		read++
+	}
+	if read == 0 {
+		return fmt.Errorf("no container-diff results found in %s", strings.Join(paths, ", "))
+	}
+	rollup := builder.Rollup()
+
+	if o.Package != "" {
+		name, version := o.Package, ""
+		if i := strings.LastIndex(o.Package, "@"); i > 0 {
+			name, version = o.Package[:i], o.Package[i+1:]
+		}
+		images := rollup.ImagesWithPackage(name, version)
+		switch {
+		case o.JSON:
+			return util.JSONify(w, images)
+		case o.CSV:
+			return util.WriteImagesCSV(w, images)
+		}
+		return util.OutputImagesText(w, images, util.OutputOptions{})
+	}
+	switch {
+	case o.JSON:
+		return util.JSONify(w, rollup)
+	case o.CSV:
+		return rollup.WritePackagesCSV(w)
+	}
+	return rollup.OutputText(w, o.Top, util.OutputOptions{})
+}
+
+// findResultFiles lists the files among paths, and the .json files below
+// the directories among them, sorted.
+func findResultFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.Walk(p, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() && strings.EqualFold(filepath.Ext(file), ".json") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "searching %s for results", p)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func init() {
+	RootCmd.AddCommand(newRollupCmd())
+}
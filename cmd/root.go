@@ -23,8 +23,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/GoogleContainerTools/container-diff/cmd/util/output"
 	"github.com/GoogleContainerTools/container-diff/differs"
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
@@ -35,22 +39,90 @@ import (
 	"github.com/spf13/pflag"
 )
 
-var json bool
-
-var save bool
-var types multiValueFlag
-var noCache bool
-
-var outputFile string
-var forceWrite bool
-var cacheDir string
+// LogLevel and the registry, request, platform, decryption, runtime and
+// workdir flags configure the whole process, so they stay global.
+// Everything else is set per command through SharedOptions.
 var LogLevel string
-var format string
 var skipTsVerifyRegistries multiValueFlag
+var insecureRegistries multiValueFlag
 var registriesCertificates keyValueFlag
+var registryCACertificates multiValueFlag
+var decryptionKeys multiValueFlag
+var registryUsername string
+var registryPassword string
+var registryToken string
+var authRegistries multiValueFlag
+var preferredRuntimes []string
+var containerdNamespace string
+var platform string
+var userAgent string
+var registryHeaders keyValueFlag
+var auditLogPath string
+var recordDir string
+var replayDir string
+var workdirBackend string
+var pullPolicy string
+
+// SharedOptions are the options common to the analyze and diff commands.
+// Library callers can fill them in directly instead of parsing flags.
+type SharedOptions struct {
+	JSON           bool
+	Save           bool
+	Types          []string
+	NoCache        bool
+	OutputFile     string
+	ForceWrite     bool
+	CacheDir       string
+	IncludeTimings bool
+	ExpectedBase   string
+	ResultsFD      int
+	ResultsFile    string
+	PushResult     string
+
+	// AnalyzerConcurrency is how many analyzers run at once on each image
+	AnalyzerConcurrency int
+
+	// JSONStyle adjusts the JSON output to the schema of its consumers
+	JSONStyle util.JSONStyle
+
+	// Output is how results are written: the format of text output, how
+	// lists are sorted and how text tables are printed.
+	Output util.OutputOptions
+
+	// Analyzers configure the package, advisory and provenance analyzers:
+	// the packages reported, where packages are searched for and the
+	// advisories and attestations looked up.
+	Analyzers differs.AnalyzerOptions
+
+	// Annotations are copied verbatim into the output, e.g. the build or
+	// pull request the results belong to.
+	Annotations map[string]string
+
+	// Writer receives the results when set, instead of the file or stream
+	// selected by OutputFile, ResultsFD or ResultsFile.
+	Writer io.Writer
+
+	// out is opened once per run, so every result of a run goes to the
+	// same stream rather than replacing the earlier ones
+	out  io.Writer
+	file *os.File
+
+	// timings is set for the current run when IncludeTimings is set
+	timings *util.Timings
+
+	// origins records where the daemon images of the current run came from
+	origins *imageOrigins
+}
 
 const containerDiffEnvCacheDir = "CONTAINER_DIFF_CACHEDIR"
 
+// the registry password and token are read from the environment when not
+// given as flags, which other users of the host could see in its process list
+const (
+	containerDiffEnvRegistryPassword = "CONTAINER_DIFF_REGISTRY_PASSWORD"
+	containerDiffEnvRegistryToken    = "CONTAINER_DIFF_REGISTRY_TOKEN"
+)
+
 type validatefxn func(args []string) error
 
 var RootCmd = &cobra.Command{
@@ -61,9 +133,13 @@ var RootCmd = &cobra.Command{
 Images can be specified from either a local Docker daemon, or from a remote registry.
 To specify a local image, prefix the image ID with 'daemon://', e.g. 'daemon://gcr.io/foo/bar'.
 To specify a remote image, prefix the image ID with 'remote://', e.g. 'remote://gcr.io/foo/bar'.
-If no prefix is specified, the local daemon will be checked first.
+To read an image from containerd without a Docker daemon, prefix it with 'containerd://', e.g. 'containerd://docker.io/library/nginx:latest'.
+To read an image from the local podman or buildah storage, prefix it with 'podman://' or 'containers-storage:', e.g. 'podman://myapp:latest'.
+If no prefix is specified, the local Docker, Podman and containerd runtimes are checked first, in the order set by --prefer-runtime.
 
-Tarballs can also be specified by simply providing the path to the .tar, .tar.gz, or .tgz file.`,
+Tarballs can also be specified by simply providing the path to the .tar, .tar.gz, or .tgz file.
+Images exported as an OCI image layout are read with 'oci://path/to/layout[:tag]'.
+Remote filesystems and tarballs can be read over SSH with 'ssh://[user@]host[:port]/path'.`,
 	PersistentPreRun: func(c *cobra.Command, s []string) {
 		ll, err := logrus.ParseLevel(LogLevel)
 		if err != nil {
@@ -72,43 +148,207 @@ Tarballs can also be specified by simply providing the path to the .tar, .tar.gz
 		}
 		logrus.SetLevel(ll)
 		pkgutil.ConfigureTLS(skipTsVerifyRegistries, registriesCertificates)
+		pkgutil.ConfigureInsecureRegistries(insecureRegistries)
+		if err := pkgutil.ConfigureRegistryCAs(registryCACertificates); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		pkgutil.ConfigureDecryption(decryptionKeys)
+		if registryPassword == "" {
+			registryPassword = os.Getenv(containerDiffEnvRegistryPassword)
+		}
+		if registryToken == "" {
+			registryToken = os.Getenv(containerDiffEnvRegistryToken)
+		}
+		if err := pkgutil.ConfigureRegistryAuth(registryUsername, registryPassword, registryToken, authRegistries); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := configureRequests(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := pkgutil.ConfigurePlatform(platform); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := pkgutil.ConfigureRuntimes(preferredRuntimes); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		pkgutil.ConfigureContainerdNamespace(containerdNamespace)
+		if err := pkgutil.ConfigureWorkdir(workdirBackend); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := pkgutil.ConfigurePullPolicy(pullPolicy); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
-func outputResults(resultMap map[string]util.Result) {
-	// Outputs diff/analysis results in alphabetical order by analyzer name
+// configureRequests sets the headers of registry requests, and opens the
+// audit log they are recorded to. The log is appended to, so it keeps the
+// requests of earlier runs. Registry responses are recorded to, or
+// replayed from, a fixture directory.
+func configureRequests() error {
+	if err := pkgutil.ConfigureFixtures(recordDir, replayDir); err != nil {
+		return err
+	}
+	var audit io.Writer
+	if auditLogPath != "" {
+		file, err := os.OpenFile(auditLogPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return errors.Wrap(err, "opening audit log")
+		}
+		audit = file
+	}
+	pkgutil.ConfigureRequests(userAgent, registryHeaders, audit)
+	return nil
+}
+
+// sortedResultTypes orders results alphabetically by analyzer name
+func sortedResultTypes(resultMap map[string]util.Result) []string {
 	sortedTypes := []string{}
 	for analyzerType := range resultMap {
 		sortedTypes = append(sortedTypes, analyzerType)
 	}
 	sort.Strings(sortedTypes)
+	return sortedTypes
+}
+
+// outputResults writes diff/analysis results in alphabetical order by
+// analyzer name
+func (o *SharedOptions) outputResults(resultMap map[string]util.Result) {
+	sortedTypes := sortedResultTypes(resultMap)
 
 	// Get the writer
-	writer, err := getWriter(outputFile)
+	writer, err := o.getWriter()
 	if err != nil {
-		errors.Wrap(err, "getting writer for output file")
+		logrus.Error(errors.Wrap(err, "getting writer for output file"))
+		return
 	}
 
-	results := make([]interface{}, len(resultMap))
-	for i, analyzerType := range sortedTypes {
-		result := resultMap[analyzerType]
-		if json {
-			results[i] = result.OutputStruct()
-		} else {
-			err := result.OutputText(writer, analyzerType, format)
-			if err != nil {
-				logrus.Error(err)
-			}
+	if o.JSON {
+		if err := util.JSONify(writer, o.jsonResults(resultMap)); err != nil {
+			logrus.Error(err)
 		}
+		return
+	}
+	if o.Output.Format == util.DotFormat {
+		o.outputGraphs(writer, resultMap)
+		return
 	}
-	if json {
-		err := util.JSONify(writer, results)
+	if len(o.Annotations) > 0 {
+		writeAnnotations(writer, o.Annotations)
+	}
+	if origins := o.origins.list(); len(origins) > 0 {
+		writeOrigins(writer, origins)
+	}
+	for _, analyzerType := range sortedTypes {
+		err := resultMap[analyzerType].OutputText(writer, analyzerType, o.Output)
 		if err != nil {
 			logrus.Error(err)
 		}
 	}
 }
 
+// outputGraphs writes the results as Graphviz graphs, skipping those which
+// can't be drawn.
+func (o *SharedOptions) outputGraphs(writer io.Writer, resultMap map[string]util.Result) {
+	for _, analyzerType := range sortedResultTypes(resultMap) {
+		graph, ok := resultMap[analyzerType].(util.DotResult)
+		if !ok {
+			logrus.Warnf("%s results can't be drawn with --format=dot, leaving them out", analyzerType)
+			continue
+		}
+		if err := graph.OutputDot(writer); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+// jsonResults returns what JSON output holds: the results in the order of
+// outputResults, wrapped with the annotations and timings if there are any,
+// in the configured JSON style.
+func (o *SharedOptions) jsonResults(resultMap map[string]util.Result) interface{} {
+	sortedTypes := sortedResultTypes(resultMap)
+	results := make([]interface{}, len(resultMap))
+	for i, analyzerType := range sortedTypes {
+		results[i] = resultMap[analyzerType].OutputStruct(o.Output)
+	}
+	results = o.JSONStyle.OmitEmptyResults(results)
+	origins := o.origins.list()
+	if o.timings == nil && len(o.Annotations) == 0 && len(origins) == 0 {
+		return o.JSONStyle.Apply(results)
+	}
+	o.timings.Finish()
+	return o.JSONStyle.Apply(util.ResultsWithMetadata{Annotations: o.Annotations, Timings: o.timings, Origins: origins, Results: results})
+}
+
+// writeAnnotations prints the annotations ahead of text results, ordered by
+// key.
+func writeAnnotations(writer io.Writer, annotations map[string]string) {
+	keys := []string{}
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(writer, "\n-----Annotations-----")
+	for _, key := range keys {
+		fmt.Fprintf(writer, "%s: %s\n", key, annotations[key])
+	}
+}
+
+// writeOrigins prints whether each daemon image was already in the daemon
+// or pulled for the run, and which image that was, ahead of text results.
+func writeOrigins(writer io.Writer, origins []util.ImageOrigin) {
+	fmt.Fprintln(writer, "\n-----Origins-----")
+	for _, origin := range origins {
+		fmt.Fprintf(writer, "%s: %s %s\n", origin.Image, origin.Origin, origin.ID)
+	}
+}
+
+// imageOrigins records where the daemon images of a run came from. Its
+// methods are no-ops on a nil imageOrigins, as outside of runs.
+type imageOrigins struct {
+	mu      sync.Mutex
+	origins []util.ImageOrigin
+}
+
+// add records where image came from, if it was read from the daemon.
+// Images may be retrieved concurrently.
+func (r *imageOrigins) add(imageName string, image pkgutil.Image) {
+	if r == nil || image.Origin == "" {
+		return
+	}
+	origin := util.ImageOrigin{Image: imageName, Origin: image.Origin}
+	if id, err := image.Image.ConfigName(); err == nil {
+		origin.ID = id.String()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, recorded := range r.origins {
+		if recorded.Image == imageName {
+			return
+		}
+	}
+	r.origins = append(r.origins, origin)
+}
+
+// list returns the recorded origins, ordered by image.
+func (r *imageOrigins) list() []util.ImageOrigin {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	origins := append([]util.ImageOrigin{}, r.origins...)
+	sort.Slice(origins, func(i, j int) bool { return origins[i].Image < origins[j].Image })
+	return origins
+}
+
 func validateArgs(args []string, validatefxns ...validatefxn) error {
 	for _, validatefxn := range validatefxns {
 		if err := validatefxn(args); err != nil {
@@ -118,11 +358,47 @@ func validateArgs(args []string, validatefxns ...validatefxn) error {
 	return nil
 }
 
-func checkIfValidAnalyzer(_ []string) error {
-	if len(types) == 0 {
-		types = []string{"size"}
+func (o *SharedOptions) checkResultsFlags(_ []string) error {
+	set := 0
+	for _, isSet := range []bool{o.OutputFile != "", o.ResultsFD != 0, o.ResultsFile != ""} {
+		if isSet {
+			set++
+		}
 	}
-	for _, name := range types {
+	if set > 1 {
+		return errors.New("only one of --output, --results-fd and --results-file can be set")
+	}
+	if o.ResultsFD < 0 || o.ResultsFD == 1 || o.ResultsFD == 2 {
+		return errors.New("--results-fd must be a file descriptor other than stdout and stderr, e.g. 3")
+	}
+	if o.PushResult != "" && !strings.HasPrefix(o.PushResult, "oci://") {
+		return errors.New("--push-result must be a repository given as oci://registry/repository[:tag]")
+	}
+	if err := o.JSONStyle.Validate(); err != nil {
+		return errors.Wrap(err, "--json-keys")
+	}
+	return nil
+}
+
+func (o *SharedOptions) checkFormatFlag(_ []string) error {
+	if o.Output.Format == util.DotFormat && o.JSON {
+		return errors.New("--format=dot can't be combined with --json")
+	}
+	return nil
+}
+
+func (o *SharedOptions) checkPackageFlags(_ []string) error {
+	if err := o.Analyzers.ValidatePackageGlobs(); err != nil {
+		return errors.Wrap(err, "--ignore-package or --only-package")
+	}
+	return nil
+}
+
+func (o *SharedOptions) checkIfValidAnalyzer(_ []string) error {
+	if len(o.Types) == 0 {
+		o.Types = []string{"size"}
+	}
+	for _, name := range o.Types {
 		if _, exists := differs.Analyzers[name]; !exists {
 			return fmt.Errorf("Argument %s is not a valid analyzer", name)
 		}
@@ -130,8 +406,24 @@ func checkIfValidAnalyzer(_ []string) error {
 	return nil
 }
 
-func includeLayers() bool {
-	for _, t := range types {
+// start prepares a run, and returns a function which closes what the run
+// opened.
+func (o *SharedOptions) start() func() {
+	o.timings = nil
+	o.origins = &imageOrigins{}
+	if o.IncludeTimings {
+		o.timings = util.NewTimings()
+	}
+	return func() {
+		if o.file != nil {
+			o.file.Close()
+		}
+		o.out, o.file = nil, nil
+	}
+}
+
+func (o *SharedOptions) includeLayers() bool {
+	for _, t := range o.Types {
 		for _, a := range differs.LayerAnalyzers {
 			if t == a {
 				return true
@@ -141,21 +433,114 @@ func includeLayers() bool {
 	return false
 }
 
-func getImage(imageName string) (pkgutil.Image, error) {
+// streamImages reports whether every requested analyzer can work from a
+// streamed file inventory, so the image filesystems need not be extracted.
+// extract is set when the caller reads the extracted filesystem itself.
+func (o *SharedOptions) streamImages(extract bool) bool {
+	if o.Save || extract {
+		return false
+	}
+	for _, t := range o.Types {
+		streamable := false
+		for _, a := range differs.StreamingAnalyzers {
+			if t == a {
+				streamable = true
+			}
+		}
+		if !streamable {
+			return false
+		}
+	}
+	return true
+}
+
+// getImage retrieves an image, streaming it when possible. The materialize
+// paths are extracted even from streamed images.
+func (o *SharedOptions) getImage(imageName string, materialize []string, extract bool) (pkgutil.Image, error) {
 	var cachePath string
 	var err error
-	if !noCache {
-		cachePath, err = getCacheDir(imageName)
+	if !o.NoCache {
+		cachePath, err = o.getCacheDir(imageName)
 		if err != nil {
 			return pkgutil.Image{}, err
 		}
 	}
 
-	return pkgutil.GetImage(imageName, includeLayers(), cachePath)
+	start := time.Now()
+	defer func() { o.timings.AddImage(imageName, start, time.Now()) }()
+
+	var image pkgutil.Image
+	// reading back an image an earlier run fully extracted beats streaming
+	if o.streamImages(extract) && !pkgutil.HasCachedFilesystem(cachePath) {
+		image, err = pkgutil.GetImageInventory(imageName, materialize)
+	} else {
+		image, err = pkgutil.GetImage(imageName, o.includeLayers(), cachePath)
+	}
+	if err != nil {
+		return image, err
+	}
+	reportSkippedLayers(image)
+	reportCacheReuse(image)
+	o.origins.add(imageName, image)
+	return image, nil
 }
 
-func getCacheDir(imageName string) (string, error) {
+// cleanupImage removes an image filesystem unless it is to be kept
+func (o *SharedOptions) cleanupImage(image pkgutil.Image) {
+	// streamed images only hold materialized files in a temp dir
+	if (o.NoCache || image.Inventory != nil) && !o.Save {
+		pkgutil.CleanupImage(image)
+	}
+}
+
+// checkExpectedBase fails unless image was built on top of --expected-base,
+// guarding against builds which pulled a stale or tampered base.
+func (o *SharedOptions) checkExpectedBase(image pkgutil.Image) error {
+	if o.ExpectedBase == "" {
+		return nil
+	}
+	if err := pkgutil.VerifyBase(image.Image, o.ExpectedBase); err != nil {
+		return errors.Wrapf(err, "%s is not built on expected base %s", image.Source, o.ExpectedBase)
+	}
+	logrus.Infof("%s is built on expected base %s", image.Source, o.ExpectedBase)
+	return nil
+}
+
+// reportSkippedLayers lists the layers which were not extracted, so results
+// for images with foreign or encrypted layers aren't mistaken for complete.
+func reportSkippedLayers(image pkgutil.Image) {
+	if len(image.SkippedLayers) == 0 {
+		return
+	}
+	output.PrintToStdErr("Skipped %d layer(s) of %s:\n", len(image.SkippedLayers), image.Source)
+	for _, layer := range image.SkippedLayers {
+		output.PrintToStdErr("  [%d] %s %s (%d bytes): %s\n", layer.Index, layer.Digest, layer.MediaType, layer.Size, layer.Reason)
+		for _, url := range layer.URLs {
+			output.PrintToStdErr("      %s\n", url)
+		}
+	}
+}
+
+// reportCacheReuse lists the work an earlier run left in the cache which
+// was reused, such as an image extracted before the run was interrupted.
+func reportCacheReuse(image pkgutil.Image) {
+	if image.Cached {
+		output.PrintToStdErr("Reused cached filesystem of %s (%s) in %s\n", image.Source, image.Digest, image.FSPath)
+	}
+	cached := 0
+	for _, layer := range image.Layers {
+		if layer.Cached {
+			cached++
+		}
+	}
+	if cached > 0 {
+		output.PrintToStdErr("Reused %d of %d cached layers of %s\n", cached, len(image.Layers), image.Source)
+	}
+}
+
+func (o *SharedOptions) getCacheDir(imageName string) (string, error) {
 	// First preference for cache is set at command line
+	cacheDir := o.CacheDir
 	if cacheDir == "" {
 		// second preference is environment
 		cacheDir = os.Getenv(containerDiffEnvCacheDir)
@@ -175,31 +560,73 @@ func getCacheDir(imageName string) (string, error) {
 	return filepath.Join(rootDir, pkgutil.CleanFilePath(imageName)), nil
 }
 
-func getWriter(outputFile string) (io.Writer, error) {
-	var err error
-	var outWriter io.Writer
+func (o *SharedOptions) getWriter() (io.Writer, error) {
+	if o.Writer != nil {
+		return o.Writer, nil
+	}
+	if o.out != nil {
+		return o.out, nil
+	}
+	// Results sent apart from stdout can't be corrupted by stray prints
+	if o.ResultsFD > 0 {
+		file := os.NewFile(uintptr(o.ResultsFD), "results")
+		if _, err := file.Stat(); err != nil {
+			return nil, errors.Wrapf(err, "file descriptor %d is not open for results", o.ResultsFD)
+		}
+		o.out, o.file = file, file
+		return o.out, nil
+	}
+	if o.ResultsFile != "" {
+		// Unlike --output, no --force is needed, as callers usually create
+		// the named pipe or file beforehand
+		file, err := os.OpenFile(o.ResultsFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening results file")
+		}
+		o.out, o.file = file, file
+		return o.out, nil
+	}
 	// If the user specifies an output file, ensure exists
-	if outputFile != "" {
+	if o.OutputFile != "" {
 		// Don't overwrite a file that exists, unless given --force
-		if _, err := os.Stat(outputFile); !os.IsNotExist(err) && !forceWrite {
+		if _, err := os.Stat(o.OutputFile); !os.IsNotExist(err) && !o.ForceWrite {
 			errors.Wrap(err, "file exist, will not overwrite.")
 		}
-		// Otherwise, output file is an io.writer
-		outWriter, err = os.Create(outputFile)
+		// Otherwise, output file is an io.out
+		file, err := os.Create(o.OutputFile)
+		if err != nil {
+			return nil, err
+		}
+		o.out, o.file = file, file
+		return o.out, nil
 	}
 	// If still doesn't exist, return stdout as the io.Writer
-	if outputFile == "" {
-		outWriter = os.Stdout
-	}
-	return outWriter, err
+	return os.Stdout, nil
 }
 
 func init() {
 	RootCmd.PersistentFlags().StringVarP(&LogLevel, "verbosity", "v", "warning", "This flag controls the verbosity of container-diff.")
-	RootCmd.PersistentFlags().StringVarP(&format, "format", "", "", "Format to output diff in.")
 	RootCmd.PersistentFlags().VarP(&skipTsVerifyRegistries, "skip-tls-verify-registry", "", "Insecure registry ignoring TLS verify to push and pull. Set it repeatedly for multiple registries.")
+	RootCmd.PersistentFlags().VarP(&insecureRegistries, "insecure-registry", "", "Registry, as host[:port], to pull from without TLS: its certificate isn't verified, and plain HTTP is used if HTTPS fails. Set it repeatedly for multiple registries.")
 	registriesCertificates = make(keyValueFlag)
 	RootCmd.PersistentFlags().VarP(&registriesCertificates, "registry-certificate", "", "Use the provided certificate for TLS communication with the given registry. Expected format is 'my.registry=/path/to/the/server/certificate'.")
+	RootCmd.PersistentFlags().VarP(&registryCACertificates, "registry-ca-cert", "", "PEM file of a CA to trust for every registry, along with the system CAs, e.g. the CA of internal registries. Set it repeatedly for multiple files.")
+	RootCmd.PersistentFlags().StringVar(&registryUsername, "username", "", "Username to authenticate to registries with, instead of the credentials of the Docker config.")
+	RootCmd.PersistentFlags().StringVar(&registryPassword, "password", "", "Password for --username. Defaults to "+containerDiffEnvRegistryPassword+", which unlike the flag other users can't see.")
+	RootCmd.PersistentFlags().StringVar(&registryToken, "token", "", "Bearer token to authenticate to registries with, e.g. $(gcloud auth print-access-token). Defaults to "+containerDiffEnvRegistryToken+".")
+	RootCmd.PersistentFlags().VarP(&authRegistries, "auth-registry", "", "Registry, as host[:port], to send the --username or --token credentials to. Set it repeatedly for multiple registries. Defaults to every registry.")
+	RootCmd.PersistentFlags().VarP(&decryptionKeys, "decryption-key", "", "PEM encoded RSA private key used to decrypt encrypted layers. Set it repeatedly for multiple keys.")
+	RootCmd.PersistentFlags().StringVar(&platform, "platform", "", "Platform to pick from multi-platform registry images, as os/architecture[/variant], e.g. linux/arm64. Defaults to the platform of the local Docker daemon, or linux on the host architecture.")
+	RootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", "", "User-Agent header to send with registry requests.")
+	registryHeaders = make(keyValueFlag)
+	RootCmd.PersistentFlags().VarP(&registryHeaders, "registry-header", "", "Extra header to send with registry requests, e.g. 'X-Request-Source=ci'. Set it repeatedly for multiple headers.")
+	RootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "Append a JSON line for every registry request made, with its time, method, URL, status and bytes read, to this file. Credentials in URLs are redacted.")
+	RootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record the registry responses of this run to this directory, with tokens redacted, for --replay.")
+	RootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Answer registry requests from a directory recorded with --record instead of the network. Requests it has no response to fail.")
+	RootCmd.PersistentFlags().StringSliceVar(&preferredRuntimes, "prefer-runtime", nil, "Local container runtimes (docker, podman, containerd) to look for unprefixed images in first, in order. The others are tried afterwards, then the registry.")
+	RootCmd.PersistentFlags().StringVar(&containerdNamespace, "containerd-namespace", "", "Containerd namespace to read containerd:// and unprefixed images from, e.g. k8s.io for the images of Kubernetes nodes. Defaults to CONTAINERD_NAMESPACE, then containerd's default namespace.")
+	RootCmd.PersistentFlags().StringVar(&pullPolicy, "pull-policy", pkgutil.PullNever, "Whether to have the daemon pull daemon:// images from their registry before reading them: 'always', 'if-not-present' or 'never'. Whether each image was local or pulled is recorded in the output.")
+	RootCmd.PersistentFlags().StringVar(&workdirBackend, "workdir-backend", pkgutil.WorkdirDisk, "Where to extract image filesystems which aren't cached: 'disk', the temp dir; 'memory', a tmpfs such as /dev/shm; or 'auto', memory when the image fits in half the free space of the tmpfs, else disk.")
 	pflag.CommandLine.AddGoFlagSet(goflag.CommandLine)
 }
 
@@ -229,6 +656,27 @@ func (f *multiValueFlag) Type() string {
 	return "multiValueFlag"
 }
 
+// negatedBoolFlag sets a bool to the opposite of the flag, for options whose
+// zero value is the default of a flag that is true by default.
+type negatedBoolFlag bool
+
+func (f *negatedBoolFlag) String() string {
+	return strconv.FormatBool(!bool(*f))
+}
+
+func (f *negatedBoolFlag) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*f = negatedBoolFlag(!v)
+	return nil
+}
+
+func (f *negatedBoolFlag) Type() string {
+	return "bool"
+}
+
 type keyValueFlag map[string]string
 
 func (f *keyValueFlag) String() string {
@@ -252,7 +700,7 @@ func (f *keyValueFlag) Type() string {
 	return "keyValueFlag"
 }
 
-func addSharedFlags(cmd *cobra.Command) {
+func addSharedFlags(cmd *cobra.Command, o *SharedOptions) {
 	sortedTypes := []string{}
 	for analyzerType := range differs.Analyzers {
 		sortedTypes = append(sortedTypes, analyzerType)
@@ -260,16 +708,43 @@ func addSharedFlags(cmd *cobra.Command) {
 	sort.Strings(sortedTypes)
 	supportedTypes := strings.Join(sortedTypes, ", ")
 
-	cmd.Flags().BoolVarP(&json, "json", "j", false, "JSON Output defines if the diff should be returned in a human readable format (false) or a JSON (true).")
-	cmd.Flags().VarP(&types, "type", "t",
+	cmd.Flags().BoolVarP(&o.JSON, "json", "j", false, "JSON Output defines if the diff should be returned in a human readable format (false) or a JSON (true).")
+	cmd.Flags().StringVarP(&o.Output.Format, "format", "", "", "Format to output diff in, as a Go template, or dot to draw the results of the sizelayer, sharedlayers and aptdeps analyzers as Graphviz graphs.")
+	cmd.Flags().VarP((*multiValueFlag)(&o.Types), "type", "t",
 		fmt.Sprintf("This flag sets the list of analyzer types to use.\n"+
 			"Set it repeatedly to use multiple analyzers.\n"+
 			"Supported types: %s.",
 			supportedTypes))
-	cmd.Flags().BoolVarP(&save, "save", "s", false, "Set this flag to save rather than remove the final image filesystems on exit.")
-	cmd.Flags().BoolVarP(&util.SortSize, "order", "o", false, "Set this flag to sort any file/package results by descending size. Otherwise, they will be sorted by name.")
-	cmd.Flags().BoolVarP(&noCache, "no-cache", "n", false, "Set this to force retrieval of image filesystem on each run.")
-	cmd.Flags().StringVarP(&cacheDir, "cache-dir", "c", "", "cache directory base to create .container-diff (default is $HOME).")
-	cmd.Flags().StringVarP(&outputFile, "output", "w", "", "output file to write to (default writes to the screen).")
-	cmd.Flags().BoolVar(&forceWrite, "force", false, "force overwrite output file, if exists already.")
+	cmd.Flags().BoolVarP(&o.Save, "save", "s", false, "Set this flag to save rather than remove the final image filesystems on exit.")
+	cmd.Flags().BoolVarP(&o.Output.SortSize, "order", "o", false, "Set this flag to sort any file/package results by descending size. Otherwise, they will be sorted by name.")
+	cmd.Flags().IntVar(&o.Output.MaxResults, "max-results-per-analyzer", 0, "Maximum number of entries to print for each list in text output, summarizing the rest with a count. JSON output is always complete. Set to 0 for no limit.")
+	cmd.Flags().StringSliceVar(&o.Output.Columns, "columns", nil, "Columns to print in text tables, in order, e.g. name,version,size. Tables without any of them are printed in full.")
+	if o.Output.ColumnNames == nil {
+		o.Output.ColumnNames = map[string]string{}
+	}
+	cmd.Flags().Var((*keyValueFlag)(&o.Output.ColumnNames), "column-name", "Header to print for a text table column, e.g. 'SIZE=Bytes'. Set it repeatedly to rename multiple columns.")
+	cmd.Flags().StringVar(&o.ExpectedBase, "expected-base", "", "Fail unless the lower layers of the analyzed image, or of the second image when diffing, are exactly the layers of this base image, e.g. gcr.io/org/base@sha256:<digest>.")
+	cmd.Flags().StringVar(&o.JSONStyle.Keys, "json-keys", "", "Casing of the keys of JSON output: 'camel' (e.g. analyzeType) or 'snake' (e.g. analyze_type). Defaults to the Go field names, e.g. AnalyzeType. Keys which are data, such as package names, are kept.")
+	cmd.Flags().BoolVar(&o.JSONStyle.OmitEmpty, "json-omit-empty", false, "Leave analyzers with nothing found or no differences out of JSON output.")
+	cmd.Flags().BoolVar(&o.JSONStyle.OmitSizes, "json-omit-sizes", false, "Leave the size fields of listed entries, such as packages, files and layers, out of JSON output.")
+	cmd.Flags().BoolVar(&o.IncludeTimings, "include-timings", false, "Include analysis start and end times, image resolution times and per-analyzer durations in JSON output.")
+	if o.Annotations == nil {
+		o.Annotations = map[string]string{}
+	}
+	cmd.Flags().Var((*keyValueFlag)(&o.Annotations), "annotation", "Annotation to include verbatim in the output, e.g. 'build=1234' or 'pr=567', so that stored results record where they came from. Set it repeatedly for multiple annotations.")
+	cmd.Flags().StringVar(&o.Analyzers.AdvisoryDBPath, "advisory-db", "", "Path to an offline OSV advisory database (a JSON file or directory of files) used by the nodeadvisory and vuln analyzers. Defaults to querying the OSV API.")
+	cmd.Flags().StringSliceVar(&o.Analyzers.IgnorePackages, "ignore-package", []string{}, "Glob of package names for package analyzers to leave out, e.g. tzdata or 'lib*'. Prefix it with an analyzer type, e.g. apt:tzdata, to apply it to that analyzer only. Set it repeatedly for multiple globs.")
+	cmd.Flags().StringSliceVar(&o.Analyzers.OnlyPackages, "only-package", []string{}, "Glob of package names for package analyzers to report, leaving out all others, e.g. 'openssl*'. Prefix it with an analyzer type, e.g. pip:django, to apply it to that analyzer only. Set it repeatedly for multiple globs.")
+	cmd.Flags().VarPF((*negatedBoolFlag)(&o.Analyzers.SkipPackageSymlinks), "follow-package-symlinks", "", "Follow symlinks, resolved within the image, when looking for package directories such as site-packages and node_modules. Set it to false to skip directories reached through a symlink.").NoOptDefVal = "true"
+	cmd.Flags().StringSliceVar(&o.Analyzers.PipRoots, "pip-root", []string{}, "Image path of a directory to search for Python packages besides the standard site-packages directories, e.g. /opt/venv/lib/python3.11/site-packages. Set it repeatedly for multiple directories.")
+	cmd.Flags().StringSliceVar(&o.Analyzers.NodeRoots, "node-root", []string{}, "Image path of a node_modules directory to search for Node packages besides /node_modules and /usr/local/lib/node_modules, e.g. /app/node_modules. Set it repeatedly for multiple directories.")
+	cmd.Flags().StringSliceVar(&o.Analyzers.ProvenancePaths, "provenance", []string{}, "Attestation files, such as SLSA provenance, to check image and layer digests against with the provenance analyzer, and to read build args from with the buildinfo analyzer. Set it repeatedly for multiple files. Defaults to fetching attestations from the registry with the OCI referrers API for the provenance analyzer.")
+	cmd.Flags().BoolVarP(&o.NoCache, "no-cache", "n", false, "Set this to force retrieval of image filesystem on each run.")
+	cmd.Flags().StringVarP(&o.CacheDir, "cache-dir", "c", "", "cache directory base to create .container-diff (default is $HOME).")
+	cmd.Flags().StringVarP(&o.OutputFile, "output", "w", "", "output file to write to (default writes to the screen).")
+	cmd.Flags().BoolVar(&o.ForceWrite, "force", false, "force overwrite output file, if exists already.")
+	cmd.Flags().IntVar(&o.ResultsFD, "results-fd", 0, "Write results to this inherited file descriptor, e.g. 3, instead of stdout, so that nothing else printed can mix with them.")
+	cmd.Flags().StringVar(&o.ResultsFile, "results-file", "", "Write results to this file or named pipe instead of stdout, so that nothing else printed can mix with them.")
+	cmd.Flags().IntVar(&o.AnalyzerConcurrency, "analyzer-concurrency", 1, "Number of analyzers to run at the same time. Analyzers only read the extracted image filesystems. Clearing their write bits guards against accidental changes, but only as a best effort, which doesn't apply when running as root.")
+	cmd.Flags().StringVar(&o.PushResult, "push-result", "", "Also push the JSON results and an HTML report as an OCI artifact to this repository, e.g. 'oci://gcr.io/my-project/results:build-1234'. Registries supporting referrers list it with the analyzed image, or the second image of a diff.")
 }
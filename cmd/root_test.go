@@ -17,12 +17,23 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 
+	"github.com/GoogleContainerTools/container-diff/differs"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	homedir "github.com/mitchellh/go-homedir"
 )
 
@@ -79,11 +90,11 @@ func TestCacheDir(t *testing.T) {
 			if tt.envVar != "" {
 				os.Setenv("CONTAINER_DIFF_CACHEDIR", tt.envVar)
 			}
-			// Set global flag for cache based on --cache-dir
-			cacheDir = tt.cliFlag
+			// Set option for cache based on --cache-dir
+			opts := SharedOptions{CacheDir: tt.cliFlag}
 
 			// call getCacheDir and make sure return is equal to expected
-			actualDir, err := getCacheDir(tt.imageName)
+			actualDir, err := opts.getCacheDir(tt.imageName)
 			if err != nil {
 				t.Errorf("Error getting cache dir %s: %s", tt.name, err.Error())
 			}
@@ -96,6 +107,69 @@ func TestCacheDir(t *testing.T) {
 	}
 }
 
+func TestResultsDestination(t *testing.T) {
+	dir, err := ioutil.TempDir("", "results")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	source := filepath.Join(dir, "image.tar")
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+	if err := tarball.WriteToFile(source, tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatalf("Error creating stdout: %s", err)
+	}
+	defer stdout.Close()
+	defer func(orig *os.File) { os.Stdout = orig }(os.Stdout)
+	os.Stdout = stdout
+
+	for _, fd := range []bool{false, true} {
+		results := filepath.Join(dir, "results.json")
+		os.Remove(results)
+		opts := AnalyzeOptions{SharedOptions{NoCache: true, JSON: true, Types: []string{"size"}}}
+		if fd {
+			file, err := os.Create(results)
+			if err != nil {
+				t.Fatalf("Error creating results file: %s", err)
+			}
+			// the run closes the descriptor it's given, as it would an inherited one
+			opts.ResultsFD, err = syscall.Dup(int(file.Fd()))
+			file.Close()
+			if err != nil {
+				t.Fatalf("Error duplicating results descriptor: %s", err)
+			}
+		} else {
+			opts.ResultsFile = results
+		}
+		if err := opts.Validate(); err != nil {
+			t.Fatalf("Error validating options: %s", err)
+		}
+		if err := opts.Run(source); err != nil {
+			t.Fatalf("Error analyzing %s: %s", source, err)
+		}
+
+		output, err := ioutil.ReadFile(results)
+		if err != nil {
+			t.Fatalf("Error reading results: %s", err)
+		}
+		var decoded []util.SizeAnalyzeResult
+		if err := json.Unmarshal(output, &decoded); err != nil || len(decoded) != 1 || decoded[0].AnalyzeType != "Size" {
+			t.Errorf("Expected the size analysis in the results (fd: %t) but got:\n%s", fd, output)
+		}
+		if printed, _ := ioutil.ReadFile(stdout.Name()); len(printed) != 0 {
+			t.Errorf("Expected nothing on stdout (fd: %t) but got:\n%s", fd, printed)
+		}
+	}
+}
+
 func TestMultiValueFlag_Set_shouldDedupeRepeatedArguments(t *testing.T) {
 	var arg multiValueFlag
 	arg.Set("value1")
@@ -123,3 +197,108 @@ func Test_KeyValueArg_Set_shouldAcceptEqualAsValue(t *testing.T) {
 		t.Error("Invalid split. key=value=something should be split to key=>value=something")
 	}
 }
+
+func TestOutputResultsAnnotations(t *testing.T) {
+	annotations := map[string]string{"build": "1234", "commit": "0a1b2c3", "pr": "https://example.com/pulls/567"}
+	results := map[string]util.Result{"history": &util.HistDiffResult{Image1: "image1", Image2: "image2", DiffType: "History", Diff: differs.HistDiff{Adds: []string{}, Dels: []string{}}}}
+
+	var text bytes.Buffer
+	opts := SharedOptions{Annotations: annotations, Writer: &text}
+	opts.outputResults(results)
+	expected := "\n-----Annotations-----\nbuild: 1234\ncommit: 0a1b2c3\npr: https://example.com/pulls/567\n"
+	if !bytes.HasPrefix(text.Bytes(), []byte(expected)) {
+		t.Errorf("Expected text output to start with annotations:\n%s\nbut got:\n%s", expected, text.String())
+	}
+
+	var output bytes.Buffer
+	opts = SharedOptions{JSON: true, Annotations: annotations, Writer: &output}
+	opts.outputResults(results)
+	var decoded struct {
+		Annotations map[string]string
+		Timings     *util.Timings
+		Results     []interface{}
+	}
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("Error parsing JSON output: %s\n%s", err, output.String())
+	}
+	if !reflect.DeepEqual(decoded.Annotations, annotations) {
+		t.Errorf("Expected annotations %v but got %v", annotations, decoded.Annotations)
+	}
+	if decoded.Timings != nil || len(decoded.Results) != 1 {
+		t.Errorf("Expected one result and no timings but got:\n%s", output.String())
+	}
+}
+
+func TestOutputResultsOrigins(t *testing.T) {
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	id, _ := img.ConfigName()
+	results := map[string]util.Result{"history": &util.HistDiffResult{Image1: "image1", Image2: "image2", DiffType: "History", Diff: differs.HistDiff{Adds: []string{}, Dels: []string{}}}}
+
+	var output bytes.Buffer
+	opts := SharedOptions{JSON: true, Writer: &output}
+	defer opts.start()()
+	opts.origins.add("daemon://app:latest", pkgutil.Image{Image: img, Origin: pkgutil.OriginPulled})
+	opts.origins.add("daemon://app:1.0", pkgutil.Image{Image: img, Origin: pkgutil.OriginLocal})
+	// images which aren't read from the daemon have no origin
+	opts.origins.add("gcr.io/app:1.0", pkgutil.Image{Image: img})
+	opts.outputResults(results)
+	var decoded struct {
+		Origins []util.ImageOrigin
+		Results []interface{}
+	}
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("Error parsing JSON output: %s\n%s", err, output.String())
+	}
+	expected := []util.ImageOrigin{
+		{Image: "daemon://app:1.0", Origin: pkgutil.OriginLocal, ID: id.String()},
+		{Image: "daemon://app:latest", Origin: pkgutil.OriginPulled, ID: id.String()},
+	}
+	if !reflect.DeepEqual(decoded.Origins, expected) || len(decoded.Results) != 1 {
+		t.Errorf("Expected origins %v and one result but got:\n%s", expected, output.String())
+	}
+
+	var text bytes.Buffer
+	opts.JSON, opts.Writer = false, &text
+	opts.outputResults(results)
+	expectedText := "\n-----Origins-----\ndaemon://app:1.0: local " + id.String() + "\ndaemon://app:latest: pulled " + id.String() + "\n"
+	if !bytes.HasPrefix(text.Bytes(), []byte(expectedText)) {
+		t.Errorf("Expected text output to start with origins:\n%s\nbut got:\n%s", expectedText, text.String())
+	}
+}
+
+func TestCheckExpectedBase(t *testing.T) {
+	base, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	other, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	dir, err := ioutil.TempDir("", "base")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	baseTar := filepath.Join(dir, "base.tar")
+	tag, _ := name.NewTag("example.com/base:latest", name.WeakValidation)
+	if err := tarball.WriteToFile(baseTar, tag, base); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+
+	// without --expected-base any image passes
+	opts := SharedOptions{}
+	if err := opts.checkExpectedBase(pkgutil.Image{Image: other, Source: "app:v1"}); err != nil {
+		t.Errorf("Expected no check without an expected base, got %s", err)
+	}
+	opts.ExpectedBase = baseTar
+	if err := opts.checkExpectedBase(pkgutil.Image{Image: base, Source: "app:v1"}); err != nil {
+		t.Errorf("Expected the base to match itself, got %s", err)
+	}
+	if err := opts.checkExpectedBase(pkgutil.Image{Image: other, Source: "app:v1"}); err == nil || !strings.Contains(err.Error(), "app:v1 is not built on expected base "+baseTar) {
+		t.Errorf("Expected an error naming the image and its expected base, got %v", err)
+	}
+}
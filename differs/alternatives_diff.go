@@ -0,0 +1,159 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// update-alternatives administrative directory and link directory locations
+const (
+	alternativesAdminDir = "var/lib/dpkg/alternatives"
+	alternativesLinkDir  = "etc/alternatives"
+)
+
+type AlternativesAnalyzer struct {
+}
+
+func (a AlternativesAnalyzer) Name() string {
+	return "AlternativesAnalyzer"
+}
+
+// Diff compares the providers selected for each alternative in two images.
+func (a AlternativesAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	alts1, err := getAlternatives(image1.FSPath)
+	if err != nil {
+		return &util.AlternativesDiffResult{}, err
+	}
+	alts2, err := getAlternatives(image2.FSPath)
+	if err != nil {
+		return &util.AlternativesDiffResult{}, err
+	}
+	return &util.AlternativesDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Alternatives",
+		Diff:     util.GetAlternativesDiff(alts1, alts2),
+	}, nil
+}
+
+func (a AlternativesAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	alts, err := getAlternatives(image.FSPath)
+	if err != nil {
+		return &util.AlternativesAnalyzeResult{}, err
+	}
+	return &util.AlternativesAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Alternatives",
+		Analysis:    util.SortedAlternatives(alts),
+	}, nil
+}
+
+// getAlternatives reads the update-alternatives database and the selected links
+// under /etc/alternatives. Links without an administrative file are reported
+// with only their selected provider.
+func getAlternatives(root string) (map[string]util.Alternative, error) {
+	alts := make(map[string]util.Alternative)
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return alts, err
+	}
+
+	adminDir := filepath.Join(root, alternativesAdminDir)
+	if contents, err := ioutil.ReadDir(adminDir); err == nil {
+		for _, c := range contents {
+			if c.IsDir() {
+				continue
+			}
+			alt, err := readAlternativeFile(filepath.Join(adminDir, c.Name()))
+			if err != nil {
+				logrus.Warningf("Could not parse alternative %s: %s", c.Name(), err)
+				continue
+			}
+			alt.Name = c.Name()
+			alts[alt.Name] = alt
+		}
+	}
+
+	linkDir := filepath.Join(root, alternativesLinkDir)
+	if contents, err := ioutil.ReadDir(linkDir); err == nil {
+		for _, c := range contents {
+			if c.Mode()&os.ModeSymlink == 0 {
+				continue
+			}
+			target, err := os.Readlink(filepath.Join(linkDir, c.Name()))
+			if err != nil {
+				continue
+			}
+			alt, ok := alts[c.Name()]
+			if !ok {
+				alt = util.Alternative{Name: c.Name(), Providers: []string{}}
+			}
+			alt.Selected = target
+			alts[c.Name()] = alt
+		}
+	}
+	return alts, nil
+}
+
+// readAlternativeFile parses an update-alternatives administrative file:
+// the mode, the master link, pairs of slave name/link lines terminated by a
+// blank line, then for each provider its path, priority and one line per slave.
+func readAlternativeFile(path string) (util.Alternative, error) {
+	alt := util.Alternative{Providers: []string{}}
+	file, err := os.Open(path)
+	if err != nil {
+		return alt, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return alt, err
+	}
+	if len(lines) < 2 {
+		return alt, nil
+	}
+	alt.Mode = lines[0]
+	alt.Link = lines[1]
+
+	i := 2
+	slaves := 0
+	for ; i+1 < len(lines) && lines[i] != ""; i += 2 {
+		slaves++
+	}
+	// skip the blank line terminating the slave list
+	i++
+	for i < len(lines) && lines[i] != "" {
+		alt.Providers = append(alt.Providers, lines[i])
+		// provider path, priority and slave paths
+		i += 2 + slaves
+	}
+	return alt, nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetAlternatives(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		path     string
+		expected map[string]util.Alternative
+		err      bool
+	}{
+		{
+			descrip:  "no directory",
+			path:     "testDirs/notThere",
+			expected: map[string]util.Alternative{},
+			err:      true,
+		},
+		{
+			descrip:  "no alternatives",
+			path:     "testDirs/noPackages",
+			expected: map[string]util.Alternative{},
+		},
+		{
+			descrip: "alternatives with and without admin files",
+			path:    "testDirs/alternatives",
+			expected: map[string]util.Alternative{
+				"editor": {
+					Name:      "editor",
+					Link:      "/usr/bin/editor",
+					Mode:      "auto",
+					Selected:  "/usr/bin/vim.basic",
+					Providers: []string{"/bin/nano", "/usr/bin/vim.basic"},
+				},
+				"java": {
+					Name:      "java",
+					Link:      "/usr/bin/java",
+					Mode:      "manual",
+					Selected:  "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+					Providers: []string{"/usr/lib/jvm/java-11-openjdk-amd64/bin/java", "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+				},
+				"python": {
+					Name:      "python",
+					Selected:  "/usr/bin/python3.9",
+					Providers: []string{},
+				},
+			},
+		},
+	}
+	for _, test := range testCases {
+		alts, err := getAlternatives(test.path)
+		if err != nil && !test.err {
+			t.Errorf("Got unexpected error: %s", err)
+		}
+		if err == nil && test.err {
+			t.Errorf("Expected error but got none.")
+		}
+		if !reflect.DeepEqual(alts, test.expected) {
+			t.Errorf("Expected: %v but got: %v", test.expected, alts)
+		}
+	}
+}
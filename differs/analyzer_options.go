@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+// AnalyzerOptions configure the package, advisory and provenance analyzers
+// of one run, as FileDiffOptions do the file differ. The zero value is the
+// default of each option.
+type AnalyzerOptions struct {
+	// IgnorePackages and OnlyPackages are globs of the package names
+	// package analyzers leave out, and, when set, the only ones they report,
+	// e.g. tzdata or lib*. A glob prefixed by an analyzer type, such as
+	// apt:tzdata, applies to that analyzer only.
+	IgnorePackages []string
+	OnlyPackages   []string
+	// PipRoots and NodeRoots are image paths searched for Python packages,
+	// like site-packages directories, and for node_modules directories,
+	// besides the standard locations.
+	PipRoots  []string
+	NodeRoots []string
+	// SkipPackageSymlinks skips the package roots, such as site-packages
+	// and node_modules directories, reached through a symlink. Otherwise
+	// symlinks are resolved as the image would.
+	SkipPackageSymlinks bool
+	// AdvisoryDBPath points at an offline OSV advisory database. When
+	// empty, advisories are looked up with the OSV API.
+	AdvisoryDBPath string
+	// ProvenancePaths lists attestation files, such as SLSA provenance, to
+	// check images against. When empty, attestations are fetched from the
+	// registry with the OCI referrers API.
+	ProvenancePaths []string
+}
+
+func analyzerOptions(opts *AnalyzerOptions) AnalyzerOptions {
+	if opts == nil {
+		return AnalyzerOptions{}
+	}
+	return *opts
+}
+
+// configurable is implemented by the analyzers taking AnalyzerOptions.
+type configurable interface {
+	withOptions(opts *AnalyzerOptions) Analyzer
+}
+
+// WithOptions returns analyzers with those taking AnalyzerOptions
+// configured by opts.
+func WithOptions(analyzers []Analyzer, opts AnalyzerOptions) []Analyzer {
+	configured := make([]Analyzer, len(analyzers))
+	for i, a := range analyzers {
+		if c, ok := a.(configurable); ok {
+			a = c.withOptions(&opts)
+		}
+		configured[i] = a
+	}
+	return configured
+}
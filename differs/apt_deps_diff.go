@@ -0,0 +1,258 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// apt records which packages were installed automatically here
+const aptExtendedStatesFile string = "var/lib/apt/extended_states"
+
+// AptDepsAnalyzer builds the dependency graph of the packages installed by
+// apt-get and reports its structure rather than a flat package list.
+type AptDepsAnalyzer struct {
+}
+
+func (a AptDepsAnalyzer) Name() string {
+	return "AptDepsAnalyzer"
+}
+
+func (a AptDepsAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	graph1, err := readDependencyGraph(image1.FSPath)
+	if err != nil {
+		return &util.AptDepsDiffResult{}, err
+	}
+	graph2, err := readDependencyGraph(image2.FSPath)
+	if err != nil {
+		return &util.AptDepsDiffResult{}, err
+	}
+	return &util.AptDepsDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "AptDeps",
+		Diff:     util.GetDependencyDiff(graph1, graph2),
+	}, nil
+}
+
+func (a AptDepsAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	graph, err := readDependencyGraph(image.FSPath)
+	if err != nil {
+		return &util.AptDepsAnalyzeResult{}, err
+	}
+	return &util.AptDepsAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "AptDeps",
+		Analysis:    util.GetDependencyAnalysis(graph),
+	}, nil
+}
+
+// dpkgStanza holds the fields of a package entry in the dpkg status file
+// which the dependency graph needs.
+type dpkgStanza struct {
+	name      string
+	version   string
+	status    string
+	essential bool
+	priority  string
+	depends   []string
+	provides  []string
+	conffiles []dpkgConffile
+}
+
+// dpkgConffile is a configuration file recorded for a package, with the
+// md5sum of the content the package ships. Obsolete conffiles are no longer
+// shipped by the installed version of the package.
+type dpkgConffile struct {
+	path     string
+	md5sum   string
+	obsolete bool
+}
+
+func readDependencyGraph(root string) (util.DependencyGraph, error) {
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return nil, err
+	}
+	graph := util.DependencyGraph{}
+	stanzas, err := readDpkgStanzas(filepath.Join(root, dpkgStatusFile))
+	if err != nil {
+		return nil, err
+	}
+	auto, err := readAutoInstalled(filepath.Join(root, aptExtendedStatesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	installed := []dpkgStanza{}
+	providers := map[string][]string{}
+	for _, s := range stanzas {
+		if !strings.HasSuffix(s.status, " installed") {
+			continue
+		}
+		installed = append(installed, s)
+		for _, virtual := range s.provides {
+			providers[virtual] = append(providers[virtual], s.name)
+		}
+	}
+	for _, s := range installed {
+		// a real package satisfies a dependency on its name before a provider
+		providers[s.name] = []string{s.name}
+	}
+	for _, s := range installed {
+		node := util.DependencyNode{
+			Auto:      auto[s.name],
+			Protected: s.essential || s.priority == "required",
+		}
+		seen := map[string]bool{}
+		for _, dep := range s.depends {
+			// the first installed alternative satisfies the dependency
+			for _, alternative := range strings.Split(dep, "|") {
+				name := dependencyName(alternative)
+				if p, ok := providers[name]; ok {
+					if !seen[p[0]] && p[0] != s.name {
+						seen[p[0]] = true
+						node.Depends = append(node.Depends, p[0])
+					}
+					break
+				}
+			}
+		}
+		graph[s.name] = node
+	}
+	return graph, nil
+}
+
+// readDpkgStanzas parses the package entries of a dpkg status file. A missing
+// file means no packages.
+func readDpkgStanzas(path string) ([]dpkgStanza, error) {
+	stanzas := []dpkgStanza{}
+	err := readControlFile(path, func(fields map[string]string) {
+		s := dpkgStanza{
+			name:      fields["Package"],
+			version:   fields["Version"],
+			status:    fields["Status"],
+			essential: fields["Essential"] == "yes",
+			priority:  fields["Priority"],
+			depends:   splitRelations(fields["Pre-Depends"]),
+			provides:  []string{},
+		}
+		s.depends = append(s.depends, splitRelations(fields["Depends"])...)
+		for _, line := range strings.Split(fields["Conffiles"], "\n") {
+			// each line holds the path, its md5sum and optional flags
+			if f := strings.Fields(line); len(f) >= 2 {
+				s.conffiles = append(s.conffiles, dpkgConffile{path: f[0], md5sum: f[1], obsolete: containsString(f[2:], "obsolete")})
+			}
+		}
+		for _, p := range splitRelations(fields["Provides"]) {
+			s.provides = append(s.provides, dependencyName(p))
+		}
+		if s.name != "" {
+			stanzas = append(stanzas, s)
+		}
+	})
+	return stanzas, err
+}
+
+// readAutoInstalled lists the packages marked as automatically installed in
+// apt's extended_states file.
+func readAutoInstalled(path string) (map[string]bool, error) {
+	auto := map[string]bool{}
+	err := readControlFile(path, func(fields map[string]string) {
+		if fields["Auto-Installed"] == "1" {
+			auto[fields["Package"]] = true
+		}
+	})
+	return auto, err
+}
+
+// readControlFile calls handle with the fields of each paragraph of a Debian
+// control file. Continuation lines are appended to their field, each on a
+// line of its own.
+func readControlFile(path string, handle func(map[string]string)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	fields := map[string]string{}
+	var key string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		text := strings.TrimRight(line, "\r\n")
+		if text == "" {
+			if len(fields) != 0 {
+				handle(fields)
+				fields = map[string]string{}
+			}
+		} else if text[0] != ' ' && text[0] != '\t' {
+			if i := strings.Index(text, ":"); i > 0 {
+				key = text[:i]
+				fields[key] = strings.TrimSpace(text[i+1:])
+			}
+		} else if key != "" {
+			fields[key] += "\n" + strings.TrimSpace(text)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(fields) != 0 {
+		handle(fields)
+	}
+	return nil
+}
+
+// splitRelations splits a comma separated relationship field.
+func splitRelations(field string) []string {
+	relations := []string{}
+	for _, r := range strings.Split(field, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
+
+// dependencyName strips the version constraint, architecture qualifier and
+// architecture restrictions from a single relation, e.g.
+// "libc6:any (>= 2.14) [amd64]" to "libc6".
+func dependencyName(relation string) string {
+	name := strings.TrimSpace(relation)
+	if i := strings.IndexAny(name, " ([<"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestAptDepsAnalyze(t *testing.T) {
+	image := pkgutil.Image{Source: "image1", FSPath: "testDirs/aptDeps/image1"}
+	result, err := AptDepsAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Error analyzing image: %s", err)
+	}
+	analysis := result.(*util.AptDepsAnalyzeResult).Analysis.(util.DependencyAnalysis)
+	if expected := []string{"curl"}; !reflect.DeepEqual(analysis.Roots, expected) {
+		t.Errorf("Expected roots: %v but got: %v", expected, analysis.Roots)
+	}
+	if len(analysis.Removable) != 0 {
+		t.Errorf("Expected nothing removable but got: %v", analysis.Removable)
+	}
+	expected := []util.PackageDependencies{
+		{Name: "curl", Depends: []string{"libc6", "libcurl4"}, RequiredBy: []string{}},
+		{Name: "libc6", Depends: []string{}, RequiredBy: []string{"curl", "libcurl4", "libssl1.1"}},
+		{Name: "libcurl4", Depends: []string{"libc6", "libssl1.1"}, RequiredBy: []string{"curl"}},
+		{Name: "libssl1.1", Depends: []string{"libc6"}, RequiredBy: []string{"libcurl4"}},
+	}
+	if !reflect.DeepEqual(analysis.Packages, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, analysis.Packages)
+	}
+}
+
+func TestAptDepsDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/aptDeps/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/aptDeps/image2"}
+	result, err := AptDepsAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.AptDepsDiffResult).Diff.(util.DependencyDiff)
+	expected := util.DependencyDiff{
+		NewRoots: []string{"git", "libssl1.1"},
+		Added: []util.PackageReason{
+			{Name: "ca-certificates", RequiredBy: []string{"curl"}},
+			{Name: "git", RequiredBy: []string{}},
+			{Name: "libssl3", RequiredBy: []string{"libcurl4", "openssl"}},
+			{Name: "openssl", RequiredBy: []string{"ca-certificates"}},
+			{Name: "perl-base", RequiredBy: []string{"git"}},
+		},
+		ReverseDepsChanged: []util.ReverseDependencyChange{
+			{Name: "libc6", Added: []string{"git", "libssl3"}, Removed: []string{}},
+			{Name: "libssl1.1", Added: []string{}, Removed: []string{"libcurl4"}},
+		},
+		Removable: []string{"libssl1.1"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
+
+func TestDependencyName(t *testing.T) {
+	for relation, expected := range map[string]string{
+		"libc6":                     "libc6",
+		" libc6:any (>= 2.14)":      "libc6",
+		"perl-base [amd64]":         "perl-base",
+		"libssl1.1(>=1.1.1)":        "libssl1.1",
+		"python3:native <!nocheck>": "python3",
+	} {
+		if actual := dependencyName(relation); actual != expected {
+			t.Errorf("dependencyName(%q): expected %s but got %s", relation, expected, actual)
+		}
+	}
+}
@@ -32,6 +32,17 @@ import (
 const dpkgStatusFile string = "var/lib/dpkg/status"
 
 type AptAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a AptAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a AptAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
 }
 
 func (a AptAnalyzer) Name() string {
@@ -128,6 +139,17 @@ func parseLine(text string, currPackage string, packages map[string]util.Package
 }
 
 type AptLayerAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a AptLayerAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a AptLayerAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
 }
 
 func (a AptLayerAnalyzer) Name() string {
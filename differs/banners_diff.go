@@ -0,0 +1,211 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// bannerFiles are the image paths, as globs, of the banner files and the
+// kind of each.
+var bannerFiles = []struct {
+	pattern string
+	kind    string
+}{
+	{"/etc/motd", util.BannerMotd},
+	{"/etc/motd.d/*", util.BannerMotd},
+	{"/etc/issue", util.BannerIssue},
+	{"/etc/issue.d/*", util.BannerIssue},
+	{"/etc/issue.net", util.BannerIssueNet},
+	{"/etc/update-motd.d/*", util.BannerMotdScript},
+}
+
+// sshdConfig is read for the Banner sshd sends, along with the files it
+// includes.
+const sshdConfig = "/etc/ssh/sshd_config"
+
+// maxBannerSize skips files too large to be banners
+const maxBannerSize = 64 << 10
+
+type BannersAnalyzer struct {
+}
+
+func (a BannersAnalyzer) Name() string {
+	return "BannersAnalyzer"
+}
+
+// Diff compares the banners of two images, with the changes to their content.
+func (a BannersAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	banners1, err := getBanners(image1.FSPath)
+	if err != nil {
+		return &util.BannerDiffResult{}, err
+	}
+	banners2, err := getBanners(image2.FSPath)
+	if err != nil {
+		return &util.BannerDiffResult{}, err
+	}
+	diff, err := util.GetBannerDiff(banners1, banners2)
+	if err != nil {
+		return &util.BannerDiffResult{}, err
+	}
+	return &util.BannerDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Banners",
+		Diff:     diff,
+	}, nil
+}
+
+func (a BannersAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	banners, err := getBanners(image.FSPath)
+	if err != nil {
+		return &util.BannerAnalyzeResult{}, err
+	}
+	return &util.BannerAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Banners",
+		Analysis:    banners,
+	}, nil
+}
+
+// getBanners lists the banner files of an image and the file sshd sends as
+// its banner, with symlinks resolved inside the image. Files which aren't
+// text, such as dangling links to the motd generated at login, are left out.
+func getBanners(root string) ([]util.Banner, error) {
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return []util.Banner{}, err
+	}
+	stanzas, err := readDpkgStanzas(filepath.Join(root, dpkgStatusFile))
+	if err != nil {
+		return []util.Banner{}, err
+	}
+	conffiles := map[string]dpkgConffile{}
+	owners := map[string]string{}
+	for _, s := range stanzas {
+		if !strings.HasSuffix(s.status, " installed") {
+			continue
+		}
+		for _, conffile := range s.conffiles {
+			if !conffile.obsolete {
+				conffiles[conffile.path] = conffile
+				owners[conffile.path] = s.name
+			}
+		}
+	}
+
+	banners := []util.Banner{}
+	byPath := map[string]int{}
+	add := func(name, kind string) {
+		if i, ok := byPath[name]; ok {
+			banners[i].Kinds = dedupe(append(banners[i].Kinds, kind))
+			return
+		}
+		content, size, ok := readBanner(root, name)
+		if !ok {
+			return
+		}
+		banner := util.Banner{Path: name, Kinds: []string{kind}, Size: size, Content: content}
+		if conffile, ok := conffiles[name]; ok {
+			sum := md5.Sum([]byte(content))
+			banner.Package = owners[name]
+			banner.Default = hex.EncodeToString(sum[:]) == conffile.md5sum
+		}
+		byPath[name] = len(banners)
+		banners = append(banners, banner)
+	}
+	for _, file := range bannerFiles {
+		matches, _ := filepath.Glob(filepath.Join(root, file.pattern))
+		for _, match := range matches {
+			add(imagePath(root, match), file.kind)
+		}
+	}
+	if banner := sshdBanner(root, sshdConfig, 0); banner != "" && banner != "none" {
+		add(path.Clean(banner), util.BannerSSH)
+	}
+	util.SortBanners(banners)
+	return banners, nil
+}
+
+// readBanner returns the content of the text file at the image path name,
+// and its size.
+func readBanner(root, name string) (string, int64, bool) {
+	resolved, ok := resolveInImage(root, name)
+	if !ok {
+		return "", 0, false
+	}
+	info, err := os.Stat(filepath.Join(root, resolved))
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxBannerSize {
+		return "", 0, false
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(root, resolved))
+	if err != nil || bytes.IndexByte(contents, 0) >= 0 {
+		return "", 0, false
+	}
+	return string(contents), info.Size(), true
+}
+
+// sshdBanner returns the Banner of an sshd configuration file, following
+// its Include directives. As sshd does, the first value wins. Settings of
+// Match blocks only apply to some connections, and are ignored.
+func sshdBanner(root, config string, depth int) string {
+	if depth > 8 {
+		return ""
+	}
+	file, err := os.Open(filepath.Join(root, config))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(strings.Replace(scanner.Text(), "=", " ", 1))
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "match":
+			return ""
+		case "banner":
+			return strings.Trim(fields[1], `"`)
+		case "include":
+			for _, pattern := range fields[1:] {
+				if !path.IsAbs(pattern) {
+					pattern = path.Join("/etc/ssh", pattern)
+				}
+				matches, _ := filepath.Glob(filepath.Join(root, pattern))
+				for _, match := range matches {
+					if banner := sshdBanner(root, imagePath(root, match), depth+1); banner != "" {
+						return banner
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const (
+	debianIssue      = "Debian GNU/Linux 12 \\n \\l\n\n"
+	bannerDpkgStatus = "Package: base-files\nStatus: install ok installed\nVersion: 12.4\nConffiles:\n /etc/issue 349d61a0e072d678e3e94923f0c3ce0e\n /etc/issue.net 0123456789abcdef0123456789abcdef\n"
+	complianceBanner = "Authorized use only.\nActivity may be monitored.\n"
+)
+
+func TestGetBanners(t *testing.T) {
+	root := difftest.NewFS(t).
+		File("var/lib/dpkg/status", bannerDpkgStatus).
+		File("etc/issue", debianIssue).
+		File("etc/issue.net", complianceBanner).
+		Symlink("etc/motd", "/run/motd.dynamic").
+		File("etc/motd.d/10-site", "Welcome\n").
+		Executable("etc/update-motd.d/10-uname", "#!/bin/sh\nuname -snrvm\n").
+		File("etc/ssh/sshd_config", "Include /etc/ssh/sshd_config.d/*.conf\nBanner none\nMatch User admin\n\tBanner /etc/admin-banner\n").
+		File("etc/ssh/sshd_config.d/banner.conf", "# site policy\nBanner /etc/issue.net\n").
+		File("etc/admin-banner", "Admins only\n").
+		Root()
+
+	banners, err := getBanners(root)
+	if err != nil {
+		t.Fatalf("Error reading banners: %s", err)
+	}
+	expected := []util.Banner{
+		{Path: "/etc/issue", Kinds: []string{"issue"}, Size: int64(len(debianIssue)), Package: "base-files", Default: true, Content: debianIssue},
+		{Path: "/etc/issue.net", Kinds: []string{"issue.net", "sshd"}, Size: int64(len(complianceBanner)), Package: "base-files", Content: complianceBanner},
+		{Path: "/etc/motd.d/10-site", Kinds: []string{"motd"}, Size: 8, Content: "Welcome\n"},
+		{Path: "/etc/update-motd.d/10-uname", Kinds: []string{"update-motd"}, Size: 23, Content: "#!/bin/sh\nuname -snrvm\n"},
+	}
+	if !reflect.DeepEqual(banners, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, banners)
+	}
+
+	empty, err := getBanners(difftest.NewFS(t).Root())
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no banners in an empty image, got: %+v, %v", empty, err)
+	}
+	if _, err := getBanners("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestGetBannerDiff(t *testing.T) {
+	issue1 := util.Banner{Path: "/etc/issue", Kinds: []string{"issue"}, Package: "base-files", Content: complianceBanner}
+	issue2 := util.Banner{Path: "/etc/issue", Kinds: []string{"issue"}, Package: "base-files", Default: true, Content: debianIssue}
+	sshd := util.Banner{Path: "/etc/issue.net", Kinds: []string{"issue.net", "sshd"}, Content: complianceBanner}
+	motd1 := util.Banner{Path: "/etc/motd", Kinds: []string{"motd"}, Content: "Welcome\n"}
+	motd2 := util.Banner{Path: "/etc/motd", Kinds: []string{"motd"}, Content: ""}
+	script := util.Banner{Path: "/etc/update-motd.d/10-uname", Kinds: []string{"update-motd"}, Content: "#!/bin/sh\n"}
+	help := util.Banner{Path: "/etc/update-motd.d/10-help-text", Kinds: []string{"update-motd"}, Content: "#!/bin/sh\n"}
+
+	diff, err := util.GetBannerDiff([]util.Banner{issue1, sshd, motd1, script}, []util.Banner{issue2, motd2, help})
+	if err != nil {
+		t.Fatalf("Error diffing banners: %s", err)
+	}
+	expected := util.BannerDiff{
+		Warnings: []string{
+			"issue.net, sshd banner /etc/issue.net removed",
+			"issue banner /etc/issue reset to the default of package base-files",
+			"motd banner /etc/motd emptied",
+			"sshd no longer sends a banner",
+		},
+		Added:   []util.Banner{help},
+		Removed: []util.Banner{sshd, script},
+		Changed: []util.ContentDiff{
+			{Name: "/etc/issue", Diff: "--- a/etc/issue\n+++ b/etc/issue\n@@ -1,2 +1,2 @@\n-Authorized use only.\n-Activity may be monitored.\n+Debian GNU/Linux 12 \\n \\l\n+\n"},
+			{Name: "/etc/motd", Diff: "--- a/etc/motd\n+++ b/etc/motd\n@@ -1 +0,0 @@\n-Welcome\n"},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bytes"
+	"debug/elf"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// BinaryAnalyzer inspects the ELF executables and shared libraries of an
+// image for the libraries they are linked against and the SONAMEs they
+// provide.
+type BinaryAnalyzer struct {
+}
+
+func (a BinaryAnalyzer) Name() string {
+	return "BinaryAnalyzer"
+}
+
+// Diff reports the SONAME bumps between two images, which break the ABI of
+// the binaries linked against the old SONAMEs, and the ELF files added,
+// removed or linked differently.
+func (a BinaryAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	objects1, err := getELFObjects(image1.FSPath)
+	if err != nil {
+		return &util.BinaryDiffResult{}, err
+	}
+	objects2, err := getELFObjects(image2.FSPath)
+	if err != nil {
+		return &util.BinaryDiffResult{}, err
+	}
+	return &util.BinaryDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Binary",
+		Diff:     util.GetBinaryDiff(objects1, objects2),
+	}, nil
+}
+
+func (a BinaryAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	objects, err := getELFObjects(image.FSPath)
+	if err != nil {
+		return &util.BinaryAnalyzeResult{}, err
+	}
+	return &util.BinaryAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Binary",
+		Analysis:    objects,
+	}, nil
+}
+
+// getELFObjects lists the dynamically linked ELF files of an image
+// filesystem. Symlinks, such as those from a library's development name to
+// its SONAME, are left out for the files they point to.
+func getELFObjects(root string) ([]util.ELFObject, error) {
+	objects := []util.ELFObject{}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return objects, err
+	}
+	err := pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if !info.Mode().IsRegular() || info.Size() < int64(len(elfMagic)) {
+			return nil
+		}
+		name := path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(file, root)))
+		if object, ok := readELFObject(file, name); ok {
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	util.SortELFObjects(objects)
+	return objects, err
+}
+
+// readELFObject reads the dynamic section of the ELF executable or shared
+// library file. Static binaries, object files and other files aren't ELF
+// objects worth reporting.
+func readELFObject(file, name string) (util.ELFObject, bool) {
+	object := util.ELFObject{Path: name, Needed: []string{}}
+	f, err := os.Open(file)
+	if err != nil {
+		logrus.Warnf("Could not read %s: %s", name, err)
+		return object, false
+	}
+	defer f.Close()
+	magic := make([]byte, len(elfMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, elfMagic) {
+		return object, false
+	}
+	binary, err := elf.NewFile(f)
+	if err != nil {
+		logrus.Warnf("Could not parse ELF file %s: %s", name, err)
+		return object, false
+	}
+	if binary.Type != elf.ET_EXEC && binary.Type != elf.ET_DYN {
+		return object, false
+	}
+	needed, err := binary.DynString(elf.DT_NEEDED)
+	if err != nil {
+		logrus.Warnf("Could not read the libraries of %s: %s", name, err)
+		return object, false
+	}
+	sonames, err := binary.DynString(elf.DT_SONAME)
+	if err != nil {
+		logrus.Warnf("Could not read the SONAME of %s: %s", name, err)
+		return object, false
+	}
+	if len(sonames) != 0 {
+		object.Soname = sonames[0]
+	}
+	if object.Soname == "" && len(needed) == 0 {
+		return object, false
+	}
+	object.Needed = append(object.Needed, needed...)
+	return object, true
+}
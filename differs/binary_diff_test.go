@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// elfWithDynamic builds a little-endian ELF64 file of the given type whose
+// dynamic section provides soname, if any, and needs the needed libraries.
+func elfWithDynamic(fileType elf.Type, soname string, needed ...string) string {
+	dynstr := bytes.NewBufferString("\x00")
+	var dynamic []elf.Dyn64
+	addString := func(tag elf.DynTag, s string) {
+		dynamic = append(dynamic, elf.Dyn64{Tag: int64(tag), Val: uint64(dynstr.Len())})
+		dynstr.WriteString(s + "\x00")
+	}
+	for _, library := range needed {
+		addString(elf.DT_NEEDED, library)
+	}
+	if soname != "" {
+		addString(elf.DT_SONAME, soname)
+	}
+	dynamic = append(dynamic, elf.Dyn64{Tag: int64(elf.DT_NULL)})
+	shstrtab := "\x00.dynstr\x00.dynamic\x00.shstrtab\x00"
+
+	const headerSize = 64
+	dynstrOffset := uint64(headerSize)
+	dynamicOffset := dynstrOffset + uint64(dynstr.Len())
+	dynamicSize := uint64(len(dynamic) * 16)
+	strtabOffset := dynamicOffset + dynamicSize
+	sectionsOffset := strtabOffset + uint64(len(shstrtab))
+	var out bytes.Buffer
+	header := elf.Header64{
+		Type: uint16(fileType), Machine: uint16(elf.EM_X86_64), Version: 1,
+		Shoff: sectionsOffset, Ehsize: headerSize, Phentsize: 56, Shentsize: 64, Shnum: 4, Shstrndx: 3,
+	}
+	copy(header.Ident[:], []byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), 1})
+	binary.Write(&out, binary.LittleEndian, header)
+	out.Write(dynstr.Bytes())
+	binary.Write(&out, binary.LittleEndian, dynamic)
+	out.WriteString(shstrtab)
+	binary.Write(&out, binary.LittleEndian, []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_STRTAB), Off: dynstrOffset, Size: uint64(dynstr.Len()), Addralign: 1},
+		{Name: 9, Type: uint32(elf.SHT_DYNAMIC), Off: dynamicOffset, Size: dynamicSize, Link: 1, Addralign: 8, Entsize: 16},
+		{Name: 18, Type: uint32(elf.SHT_STRTAB), Off: strtabOffset, Size: uint64(len(shstrtab)), Addralign: 1},
+	})
+	return out.String()
+}
+
+func TestBinaryAnalyze(t *testing.T) {
+	image := difftest.NewFS(t).
+		File("usr/bin/curl", elfWithDynamic(elf.ET_DYN, "", "libcurl.so.4", "libc.so.6")).
+		File("usr/lib/libcurl.so.4.8.0", elfWithDynamic(elf.ET_DYN, "libcurl.so.4", "libssl.so.3", "libc.so.6")).
+		File("usr/lib/libcurl.a", elfWithDynamic(elf.ET_REL, "")).
+		File("usr/local/bin/static", elfWithDynamic(elf.ET_EXEC, "")).
+		File("usr/local/bin/script", "#!/bin/sh\necho \x7fELF\n").
+		Image("image")
+
+	result, err := BinaryAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := []util.ELFObject{
+		{Path: "/usr/bin/curl", Needed: []string{"libcurl.so.4", "libc.so.6"}},
+		{Path: "/usr/lib/libcurl.so.4.8.0", Soname: "libcurl.so.4", Needed: []string{"libssl.so.3", "libc.so.6"}},
+	}
+	if objects := result.(*util.BinaryAnalyzeResult).Analysis.([]util.ELFObject); !reflect.DeepEqual(objects, expected) {
+		t.Errorf("Expected ELF files %v but got %v", expected, objects)
+	}
+}
+
+func TestBinaryDiff(t *testing.T) {
+	image1 := difftest.NewFS(t).
+		File("usr/bin/curl", elfWithDynamic(elf.ET_DYN, "", "libcurl.so.4", "libssl.so.1.1")).
+		File("usr/bin/legacy", elfWithDynamic(elf.ET_DYN, "", "libssl.so.1.1")).
+		File("usr/lib/libcurl.so.4.7.0", elfWithDynamic(elf.ET_DYN, "libcurl.so.4", "libssl.so.1.1")).
+		File("usr/lib/libssl.so.1.1", elfWithDynamic(elf.ET_DYN, "libssl.so.1.1", "libc.so.6")).
+		Image("image1")
+	image2 := difftest.NewFS(t).
+		File("usr/bin/curl", elfWithDynamic(elf.ET_DYN, "", "libcurl.so.4", "libssl.so.3")).
+		File("usr/bin/legacy", elfWithDynamic(elf.ET_DYN, "", "libssl.so.1.1")).
+		File("usr/lib/libcurl.so.4.8.0", elfWithDynamic(elf.ET_DYN, "libcurl.so.4", "libssl.so.1.1")).
+		File("usr/lib/libssl.so.3", elfWithDynamic(elf.ET_DYN, "libssl.so.3", "libc.so.6")).
+		Image("image2")
+
+	result, err := BinaryAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.BinaryDiffResult).Diff.(util.BinaryDiff)
+	expectedBumps := []util.SonameBump{{
+		Library:    "libssl",
+		Sonames1:   []string{"libssl.so.1.1"},
+		Sonames2:   []string{"libssl.so.3"},
+		Dependents: []string{"/usr/bin/legacy", "/usr/lib/libcurl.so.4.8.0"},
+	}}
+	if !reflect.DeepEqual(diff.Bumps, expectedBumps) {
+		t.Errorf("Expected SONAME bumps %v but got %v", expectedBumps, diff.Bumps)
+	}
+	// the libcurl patch release is the same libcurl.so.4
+	if len(diff.Added) != 1 || diff.Added[0].Path != "/usr/lib/libssl.so.3" {
+		t.Errorf("Expected only libssl.so.3 added, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "/usr/lib/libssl.so.1.1" {
+		t.Errorf("Expected only libssl.so.1.1 removed, got %v", diff.Removed)
+	}
+	expectedChanged := []util.ELFChange{{Path: "/usr/bin/curl", AddedNeeded: []string{"libssl.so.3"}, RemovedNeeded: []string{"libssl.so.1.1"}}}
+	if !reflect.DeepEqual(diff.Changed, expectedChanged) {
+		t.Errorf("Expected relinked files %v but got %v", expectedChanged, diff.Changed)
+	}
+}
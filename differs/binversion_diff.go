@@ -0,0 +1,292 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBinarySize bounds the binaries read into memory to look for versions
+const maxBinarySize = 256 << 20
+
+// systemBinaryDirs hold the binaries of the distribution's packages. They
+// are skipped when the image has no dpkg or apk database to tell which
+// files those packages own.
+var systemBinaryDirs = []string{"/bin", "/sbin", "/usr/bin", "/usr/sbin", "/lib", "/usr/lib", "/usr/libexec"}
+
+var (
+	elfMagic    = []byte("\x7fELF")
+	goBuildInfo = []byte("\xff Go buildinf:")
+	// a version must end the string or a word, so that e.g. the "1.13.x"
+	// of "tar 1.13.x format" is no version
+	versionNumber = `v?(\d+\.\d+(?:\.\d+)*(?:[-+~][0-9A-Za-z.]*[0-9A-Za-z])?)(?:[^\w.]|$)`
+	// versionString matches what --version style output is printed from
+	// when the binary's name isn't next to it, e.g. "version: 1.2.3".
+	versionString = regexp.MustCompile(`(?i)\bversion[\s:=]+"?v?(\d+\.\d+\.\d+(?:[-+][0-9A-Za-z.]*[0-9A-Za-z])?)(?:[^\w.]|$)`)
+)
+
+// fdoPackageNote is the type of the ELF note in which systemd's package
+// metadata convention records the package a binary was built for.
+const fdoPackageNote = 0xcafe1a7e
+
+// BinVersionAnalyzer reports the versions of standalone binaries which no
+// package manager tracks, such as tools installed with curl | sh.
+type BinVersionAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a BinVersionAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a BinVersionAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a BinVersionAnalyzer) Name() string {
+	return "BinVersionAnalyzer"
+}
+
+// Diff compares the versions of the untracked binaries in two images.
+func (a BinVersionAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := multiVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a BinVersionAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := multiVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// getPackages maps the name of each untracked binary on the PATH or in
+// /opt/*/bin to its version. Binaries whose version can't be told are left
+// out.
+func (a BinVersionAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
+	root := image.FSPath
+	packages := make(map[string]map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// path provided invalid
+		return packages, err
+	}
+	config, err := configFile(image)
+	if err != nil {
+		return packages, err
+	}
+	searchPath := defaultPath
+	for _, env := range config.Config.Env {
+		if strings.HasPrefix(env, "PATH=") {
+			searchPath = strings.TrimPrefix(env, "PATH=")
+		}
+	}
+	owned, tracked := readOwnedFiles(root)
+
+	candidates := []util.Resolution{}
+	candidates = append(candidates, getPathCommands(root, searchPath)...)
+	for _, p := range glob(root, "/opt/*/bin/*") {
+		candidates = append(candidates, util.Resolution{Name: path.Base(p), Path: p, Target: p})
+	}
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if seen[c.Target] || owned[c.Path] || owned[c.Target] {
+			continue
+		}
+		seen[c.Target] = true
+		if !tracked && inSystemBinaryDir(c.Target) {
+			continue
+		}
+		version, size := readBinaryVersion(filepath.Join(root, c.Target), path.Base(c.Target))
+		if version == "" {
+			continue
+		}
+		name := path.Base(c.Target)
+		if _, ok := packages[name]; !ok {
+			packages[name] = make(map[string]util.PackageInfo)
+		}
+		packages[name][c.Target] = util.PackageInfo{Version: version, Size: size}
+	}
+	return packages, nil
+}
+
+// readOwnedFiles lists the files installed by dpkg and apk packages, and
+// reports whether either database was found.
+func readOwnedFiles(root string) (map[string]bool, bool) {
+	owned := map[string]bool{}
+	tracked := false
+	for _, list := range glob(root, "/var/lib/dpkg/info/*.list") {
+		tracked = true
+		readLines(filepath.Join(root, list), func(line string) {
+			owned[line] = true
+		})
+	}
+	// apk lists each directory as F: followed by its files as R:
+	dir := ""
+	if exists(root, "/lib/apk/db/installed") {
+		tracked = true
+	}
+	readLines(filepath.Join(root, "/lib/apk/db/installed"), func(line string) {
+		switch {
+		case strings.HasPrefix(line, "F:"):
+			dir = "/" + strings.TrimPrefix(line, "F:")
+		case strings.HasPrefix(line, "R:"):
+			owned[path.Join(dir, strings.TrimPrefix(line, "R:"))] = true
+		}
+	})
+	return owned, tracked
+}
+
+func readLines(file string, handle func(string)) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		handle(strings.TrimSpace(scanner.Text()))
+	}
+}
+
+func inSystemBinaryDir(p string) bool {
+	for _, dir := range systemBinaryDirs {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readBinaryVersion returns the version of an ELF binary, and its size. It
+// tries, in order, the package note of the binary, the main module of a Go
+// binary, a version printed after the binary's name, and any other version
+// string.
+func readBinaryVersion(file, name string) (string, int64) {
+	info, err := os.Stat(file)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxBinarySize {
+		return "", -1
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		logrus.Warnf("Could not read %s: %s", file, err)
+		return "", -1
+	}
+	if !bytes.HasPrefix(data, elfMagic) {
+		return "", -1
+	}
+	if version := elfPackageVersion(data); version != "" {
+		return version, info.Size()
+	}
+	if version := goModuleVersion(data); version != "" {
+		return version, info.Size()
+	}
+	named := regexp.MustCompile(`(?i)(?:^|[^\w/.-])` + regexp.QuoteMeta(name) + `(?:\s+version)?[\s:/-]+` + versionNumber)
+	if m := named.FindSubmatch(data); m != nil {
+		return string(m[1]), info.Size()
+	}
+	if m := versionString.FindSubmatch(data); m != nil {
+		return string(m[1]), info.Size()
+	}
+	return "", info.Size()
+}
+
+// elfPackageVersion reads the version from the FDO package note, which
+// holds JSON such as {"type":"rpm","name":"curl","version":"8.2.1-1"}.
+func elfPackageVersion(data []byte) string {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	for _, section := range f.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		notes, err := section.Data()
+		if err != nil {
+			continue
+		}
+		for len(notes) >= 12 {
+			nameSize := uint64(f.ByteOrder.Uint32(notes[0:4]))
+			descSize := uint64(f.ByteOrder.Uint32(notes[4:8]))
+			noteType := f.ByteOrder.Uint32(notes[8:12])
+			nameEnd := 12 + align4(nameSize)
+			descEnd := nameEnd + align4(descSize)
+			if descEnd > uint64(len(notes)) {
+				break
+			}
+			if noteType == fdoPackageNote && string(bytes.TrimRight(notes[12:12+nameSize], "\x00")) == "FDO" {
+				var pkg struct{ Version string }
+				desc := bytes.TrimRight(notes[nameEnd:nameEnd+descSize], "\x00")
+				if json.Unmarshal(desc, &pkg) == nil && pkg.Version != "" {
+					return pkg.Version
+				}
+			}
+			notes = notes[descEnd:]
+		}
+	}
+	return ""
+}
+
+func align4(n uint64) uint64 {
+	return (n + 3) &^ 3
+}
+
+// goModuleVersion reads the main module version from the build information
+// which Go 1.18 and later embed inline in binaries. Binaries built from a
+// checkout record "(devel)", which is no version.
+func goModuleVersion(data []byte) string {
+	i := bytes.Index(data, goBuildInfo)
+	if i < 0 || len(data) < i+32 || data[i+15]&2 == 0 {
+		return ""
+	}
+	rest := data[i+32:]
+	strs := []string{}
+	for len(strs) < 2 {
+		n, size := binary.Uvarint(rest)
+		if size <= 0 || uint64(len(rest)-size) < n {
+			return ""
+		}
+		strs = append(strs, string(rest[size:size+int(n)]))
+		rest = rest[size+int(n):]
+	}
+	modinfo := strs[1]
+	// the module information is framed by 16 byte sentinels
+	if len(modinfo) >= 33 && modinfo[len(modinfo)-17] == '\n' {
+		modinfo = modinfo[16 : len(modinfo)-16]
+	}
+	for _, line := range strings.Split(modinfo, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 3 && fields[0] == "mod" && fields[2] != "(devel)" {
+			return strings.TrimPrefix(fields[2], "v")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// elfWithNote builds a little-endian ELF64 file with a single FDO package
+// note holding desc.
+func elfWithNote(desc string) string {
+	var note bytes.Buffer
+	name := "FDO\x00"
+	descData := []byte(desc)
+	for len(descData)%4 != 0 {
+		descData = append(descData, 0)
+	}
+	binary.Write(&note, binary.LittleEndian, []uint32{uint32(len(name)), uint32(len(desc)), fdoPackageNote})
+	note.WriteString(name)
+	note.Write(descData)
+	shstrtab := "\x00.note.package\x00.shstrtab\x00"
+
+	const headerSize = 64
+	noteOffset := uint64(headerSize)
+	strtabOffset := noteOffset + uint64(note.Len())
+	sectionsOffset := strtabOffset + uint64(len(shstrtab))
+	var out bytes.Buffer
+	header := elf.Header64{
+		Type: uint16(elf.ET_EXEC), Machine: uint16(elf.EM_X86_64), Version: 1,
+		Shoff: sectionsOffset, Ehsize: headerSize, Phentsize: 56, Shentsize: 64, Shnum: 3, Shstrndx: 2,
+	}
+	copy(header.Ident[:], []byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), 1})
+	binary.Write(&out, binary.LittleEndian, header)
+	out.Write(note.Bytes())
+	out.WriteString(shstrtab)
+	binary.Write(&out, binary.LittleEndian, []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_NOTE), Off: noteOffset, Size: uint64(note.Len()), Addralign: 4},
+		{Name: 15, Type: uint32(elf.SHT_STRTAB), Off: strtabOffset, Size: uint64(len(shstrtab)), Addralign: 1},
+	})
+	return out.String()
+}
+
+// goBinary fakes a Go binary whose inline build information names the main
+// module and its version.
+func goBinary(module, version string) string {
+	modinfo := strings.Repeat("0", 16) + "path\t" + module + "\nmod\t" + module + "\t" + version + "\th1:abc=\n" + strings.Repeat("0", 16)
+	var b bytes.Buffer
+	b.WriteString("\x7fELF junk ")
+	b.WriteString("\xff Go buildinf:")
+	b.Write([]byte{8, 2})
+	b.Write(make([]byte, 16))
+	for _, s := range []string{"go1.21.0", modinfo} {
+		var n [binary.MaxVarintLen64]byte
+		b.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+		b.WriteString(s)
+	}
+	// strings of dependencies are no version of the binary
+	b.WriteString("\x00golang.org/x/net version 0.17.0\x00")
+	return b.String()
+}
+
+func TestGetBinaryVersions(t *testing.T) {
+	kubectl := "\x7fELF\x02\x01\x00\x00kubectl 1.13.x format\x00kubectl version v1.28.2\x00version 0.0.1\x00"
+	tool := "\x7fELF\x00\x00Tool for things\x00Version: 2.4.1-rc1\x00"
+	fs := difftest.NewFS(t).
+		Executable("/usr/local/bin/kubectl", kubectl).
+		Executable("/usr/local/bin/kustomize", goBinary("sigs.k8s.io/kustomize/kustomize/v5", "v5.1.1")).
+		Executable("/usr/local/bin/curl", elfWithNote(`{"type":"rpm","name":"curl","version":"8.2.1-1.fc39"}`)).
+		Executable("/opt/tool/bin/tool", tool).
+		// scripts, binaries of packages and binaries without versions are left out
+		Executable("/usr/local/bin/install.sh", "#!/bin/sh\necho install.sh version 1.0.0\n").
+		Executable("/usr/local/bin/nothing", "\x7fELF\x00nothing to see\x00").
+		Executable("/usr/bin/tar", "\x7fELF\x00tar version 1.34\x00").
+		Executable("/usr/bin/untracked", "\x7fELF\x00untracked 3.2.1\x00").
+		File("/var/lib/dpkg/info/tar.list", "/.\n/usr\n/usr/bin\n/usr/bin/tar\n")
+	packages, err := BinVersionAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]util.PackageInfo{
+		"curl":      {"/usr/local/bin/curl": {Version: "8.2.1-1.fc39", Size: int64(len(elfWithNote(`{"type":"rpm","name":"curl","version":"8.2.1-1.fc39"}`)))}},
+		"kubectl":   {"/usr/local/bin/kubectl": {Version: "1.28.2", Size: int64(len(kubectl))}},
+		"kustomize": {"/usr/local/bin/kustomize": {Version: "5.1.1", Size: int64(len(goBinary("sigs.k8s.io/kustomize/kustomize/v5", "v5.1.1")))}},
+		"tool":      {"/opt/tool/bin/tool": {Version: "2.4.1-rc1", Size: int64(len(tool))}},
+		"untracked": {"/usr/bin/untracked": {Version: "3.2.1", Size: 21}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, packages)
+	}
+
+	// without a package database, the system directories are not searched
+	fs = difftest.NewFS(t).
+		Executable("/usr/bin/untracked", "\x7fELF\x00untracked 3.2.1\x00").
+		Executable("/usr/local/bin/kubectl", kubectl)
+	packages, err = BinVersionAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected = map[string]map[string]util.PackageInfo{
+		"kubectl": {"/usr/local/bin/kubectl": {Version: "1.28.2", Size: int64(len(kubectl))}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, packages)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// annotationLabelPrefixes select the labels read as annotations: the OCI
+// image annotations, which builders also set as labels, and the older
+// label-schema ones.
+var annotationLabelPrefixes = []string{"org.opencontainers.image.", "org.label-schema."}
+
+// The annotations the revision, source URL and version are read from, in
+// order.
+var (
+	revisionAnnotations  = []string{"org.opencontainers.image.revision", "org.label-schema.vcs-ref"}
+	sourceURLAnnotations = []string{"org.opencontainers.image.source", "org.label-schema.vcs-url"}
+	versionAnnotations   = []string{"org.opencontainers.image.version", "org.label-schema.version"}
+)
+
+// buildInfoField is the config field in which BuildKit records the
+// frontend args of a build, as base64 encoded JSON.
+const buildInfoField = "moby.buildkit.buildinfo.v1"
+
+// historyArgs matches the build args the builders record ahead of RUN
+// commands, e.g. "|2 A=1 B=2 /bin/sh -c make", prefixed by "RUN " for
+// BuildKit.
+var historyArgs = regexp.MustCompile(`^(?:RUN )?\|(\d+) (.*)$`)
+
+// BuildInfoAnalyzer compares the OCI annotations and build args of images,
+// from their manifests, configs and, with --provenance, their attestations.
+type BuildInfoAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a BuildInfoAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a BuildInfoAnalyzer) Name() string {
+	return "BuildInfoAnalyzer"
+}
+
+// Diff compares the build information of two images.
+func (a BuildInfoAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	info1, err := getBuildInfo(image1, analyzerOptions(a.Options).ProvenancePaths)
+	if err != nil {
+		return &util.BuildInfoDiffResult{}, err
+	}
+	info2, err := getBuildInfo(image2, analyzerOptions(a.Options).ProvenancePaths)
+	if err != nil {
+		return &util.BuildInfoDiffResult{}, err
+	}
+	return &util.BuildInfoDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "BuildInfo",
+		Diff:     util.GetBuildInfoDiff(info1, info2),
+	}, nil
+}
+
+func (a BuildInfoAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	info, err := getBuildInfo(image, analyzerOptions(a.Options).ProvenancePaths)
+	if err != nil {
+		return &util.BuildInfoAnalyzeResult{}, err
+	}
+	return &util.BuildInfoAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "BuildInfo",
+		Analysis:    info,
+	}, nil
+}
+
+// getBuildInfo gathers the annotations and build args of an image. Manifest
+// annotations take precedence over labels, and build args recorded by
+// provenance over those of BuildKit's build information, which in turn
+// take precedence over those read from the history. Provenance is only
+// read from the attestation files of provenancePaths.
+func getBuildInfo(image pkgutil.Image, provenancePaths []string) (util.BuildInfo, error) {
+	info := util.BuildInfo{Annotations: []util.BuildValue{}, BuildArgs: []util.BuildValue{}}
+	config, err := configFile(image)
+	if err != nil {
+		return info, err
+	}
+	annotations := map[string]util.BuildValue{}
+	for key, value := range config.Config.Labels {
+		for _, prefix := range annotationLabelPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				annotations[key] = util.BuildValue{Name: key, Value: value, Source: util.SourceLabel}
+			}
+		}
+	}
+	for key, value := range manifestAnnotations(image) {
+		annotations[key] = util.BuildValue{Name: key, Value: value, Source: util.SourceManifest}
+	}
+
+	args := map[string]util.BuildValue{}
+	for _, h := range config.History {
+		readHistoryArgs(h.CreatedBy, args)
+	}
+	if err := readBuildKitArgs(image, args); err != nil {
+		logrus.Warnf("Could not read the BuildKit build information of %s: %s", image.Source, err)
+	}
+	invocations := []string{}
+	if len(provenancePaths) != 0 {
+		attestation, err := getAttestation(image, provenancePaths)
+		if err != nil {
+			return info, errors.Wrap(err, "reading build args from provenance")
+		}
+		for name, value := range attestation.BuildArgs {
+			args[name] = util.BuildValue{Name: name, Value: value, Source: util.SourceProvenance}
+		}
+		invocations = attestation.Invocations
+	}
+
+	for _, v := range annotations {
+		info.Annotations = append(info.Annotations, v)
+	}
+	for _, v := range args {
+		info.BuildArgs = append(info.BuildArgs, v)
+	}
+	util.SortBuildValues(info.Annotations)
+	util.SortBuildValues(info.BuildArgs)
+	info.Revision = annotationValue(annotations, revisionAnnotations)
+	info.SourceURL = annotationValue(annotations, sourceURLAnnotations)
+	info.Version = annotationValue(annotations, versionAnnotations)
+	info.BuildURL = buildURL(invocations, config.Config.Labels)
+	return info, nil
+}
+
+// manifestAnnotations returns the annotations of an OCI image manifest.
+// Other manifests have none, and are not computed for them, which for
+// local images would mean compressing every layer.
+func manifestAnnotations(image pkgutil.Image) map[string]string {
+	if image.Image == nil {
+		return nil
+	}
+	if mediaType, err := image.Image.MediaType(); err != nil || mediaType != types.OCIManifestSchema1 {
+		return nil
+	}
+	manifest, err := image.Image.Manifest()
+	if err != nil || manifest == nil {
+		return nil
+	}
+	return manifest.Annotations
+}
+
+// readHistoryArgs records the build args of a history entry: the defaults
+// of an ARG instruction, and the values a RUN command was given, which
+// replace them.
+func readHistoryArgs(createdBy string, args map[string]util.BuildValue) {
+	command := strings.TrimSpace(createdBy)
+	// the classic builder records instructions after a no-op shell
+	if i := strings.Index(command, "#(nop)"); i >= 0 {
+		command = strings.TrimSpace(command[i+len("#(nop)"):])
+	}
+	if strings.HasPrefix(command, "ARG ") {
+		for _, arg := range strings.Fields(strings.TrimPrefix(command, "ARG ")) {
+			name, value := arg, ""
+			if i := strings.Index(arg, "="); i >= 0 {
+				name, value = arg[:i], arg[i+1:]
+			}
+			if _, ok := args[name]; !ok || value != "" {
+				args[name] = util.BuildValue{Name: name, Value: value, Source: util.SourceHistory}
+			}
+		}
+		return
+	}
+	m := historyArgs.FindStringSubmatch(command)
+	if m == nil {
+		return
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(m[2])
+	for i := 0; i < n && i < len(fields); i++ {
+		if j := strings.Index(fields[i], "="); j > 0 {
+			args[fields[i][:j]] = util.BuildValue{Name: fields[i][:j], Value: fields[i][j+1:], Source: util.SourceHistory}
+		}
+	}
+}
+
+// readBuildKitArgs records the build args of BuildKit's build information,
+// which older BuildKit versions add to the image config.
+func readBuildKitArgs(image pkgutil.Image, args map[string]util.BuildValue) error {
+	if image.Image == nil {
+		return nil
+	}
+	raw, err := image.Image.RawConfigFile()
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	encoded, ok := fields[buildInfoField]
+	if !ok {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(encoded, &s); err != nil {
+		return err
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	var buildInfo struct {
+		Attrs map[string]*string `json:"attrs"`
+	}
+	if err := json.Unmarshal(data, &buildInfo); err != nil {
+		return err
+	}
+	for key, value := range buildInfo.Attrs {
+		if !strings.HasPrefix(key, "build-arg:") || value == nil {
+			continue
+		}
+		name := strings.TrimPrefix(key, "build-arg:")
+		args[name] = util.BuildValue{Name: name, Value: *value, Source: util.SourceBuildInfo}
+	}
+	return nil
+}
+
+func annotationValue(annotations map[string]util.BuildValue, keys []string) string {
+	for _, key := range keys {
+		if v, ok := annotations[key]; ok && v.Value != "" {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// buildURL returns the build run provenance records, which CI systems set to
+// the URL of the job, or else a build-url label.
+func buildURL(invocations []string, labels map[string]string) string {
+	if len(invocations) != 0 {
+		return invocations[0]
+	}
+	keys := []string{}
+	for key := range labels {
+		lower := strings.ToLower(key)
+		if strings.HasSuffix(lower, "build-url") || strings.HasSuffix(lower, "build_url") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return labels[keys[0]]
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// buildKitImage is a test image whose raw config holds BuildKit's build
+// information.
+type buildKitImage struct {
+	*pkgutil.TestImage
+	raw string
+}
+
+func (i buildKitImage) RawConfigFile() ([]byte, error) {
+	return []byte(i.raw), nil
+}
+
+func buildInfoImage(labels map[string]string, history ...string) pkgutil.Image {
+	config := &v1.ConfigFile{Config: v1.Config{Labels: labels}}
+	for _, createdBy := range history {
+		config.History = append(config.History, v1.History{CreatedBy: createdBy})
+	}
+	return pkgutil.Image{Source: "image", Image: &pkgutil.TestImage{Config: config}}
+}
+
+func TestGetBuildInfo(t *testing.T) {
+	image := buildInfoImage(
+		map[string]string{
+			"org.opencontainers.image.revision": "abc123",
+			"org.opencontainers.image.source":   "https://github.com/example/app",
+			"org.label-schema.version":          "1.2.0",
+			"com.example.build-url":             "https://ci.example.com/job/42",
+			"maintainer":                        "dev@example.com",
+		},
+		"/bin/sh -c #(nop)  ARG VERSION=1.0",
+		"/bin/sh -c #(nop)  ARG DEBUG",
+		"|1 VERSION=1.2.0 /bin/sh -c make",
+		"RUN |2 TARGET=prod HTTP_PROXY=http://proxy /bin/sh -c make install # buildkit",
+		"/bin/sh -c A=1 make",
+	)
+	info, err := getBuildInfo(image, nil)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := util.BuildInfo{
+		Revision:  "abc123",
+		SourceURL: "https://github.com/example/app",
+		Version:   "1.2.0",
+		BuildURL:  "https://ci.example.com/job/42",
+		Annotations: []util.BuildValue{
+			{Name: "org.label-schema.version", Value: "1.2.0", Source: util.SourceLabel},
+			{Name: "org.opencontainers.image.revision", Value: "abc123", Source: util.SourceLabel},
+			{Name: "org.opencontainers.image.source", Value: "https://github.com/example/app", Source: util.SourceLabel},
+		},
+		BuildArgs: []util.BuildValue{
+			{Name: "DEBUG", Source: util.SourceHistory},
+			{Name: "HTTP_PROXY", Value: "http://proxy", Source: util.SourceHistory},
+			{Name: "TARGET", Value: "prod", Source: util.SourceHistory},
+			{Name: "VERSION", Value: "1.2.0", Source: util.SourceHistory},
+		},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, info)
+	}
+
+	buildInfo := base64.StdEncoding.EncodeToString([]byte(`{"frontend":"dockerfile.v0","attrs":{"build-arg:VERSION":"2.0.0","filename":"Dockerfile"}}`))
+	image.Image = buildKitImage{
+		TestImage: image.Image.(*pkgutil.TestImage),
+		raw:       `{"config":{},"moby.buildkit.buildinfo.v1":"` + buildInfo + `"}`,
+	}
+	info, err = getBuildInfo(image, nil)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	version := util.BuildValue{Name: "VERSION", Value: "2.0.0", Source: util.SourceBuildInfo}
+	if arg := info.BuildArgs[len(info.BuildArgs)-1]; arg != version {
+		t.Errorf("Expected BuildKit build info to take precedence with %+v but got %+v", version, arg)
+	}
+}
+
+func TestGetBuildInfoDiff(t *testing.T) {
+	image1 := buildInfoImage(
+		map[string]string{
+			"org.opencontainers.image.revision": "abc123",
+			"org.opencontainers.image.vendor":   "Example",
+		},
+		"/bin/sh -c #(nop)  ARG DEBUG",
+		"|1 VERSION=1.0 /bin/sh -c make",
+	)
+	image2 := buildInfoImage(
+		map[string]string{
+			"org.opencontainers.image.revision": "def456",
+			"org.opencontainers.image.created":  "2024-01-02T03:04:05Z",
+		},
+		"|1 VERSION=1.1 /bin/sh -c make",
+	)
+	result, err := BuildInfoAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := util.BuildInfoDiff{
+		KeyChanges: []util.BuildChange{{Name: "Revision", Value1: "abc123", Value2: "def456"}},
+		Annotations: []util.BuildChange{
+			{Name: "org.opencontainers.image.created", Value2: "2024-01-02T03:04:05Z"},
+			{Name: "org.opencontainers.image.revision", Value1: "abc123", Value2: "def456"},
+			{Name: "org.opencontainers.image.vendor", Value1: "Example"},
+		},
+		BuildArgs: []util.BuildChange{{Name: "VERSION", Value1: "1.0", Value2: "1.1"}},
+	}
+	if diff := result.(*util.BuildInfoDiffResult).Diff; !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
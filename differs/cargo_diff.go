@@ -0,0 +1,274 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultCargoHomes are where rustup and the official Rust images keep
+// CARGO_HOME, in addition to the one set in the image environment.
+var defaultCargoHomes = []string{"/usr/local/cargo", "/root/.cargo", "/home/*/.cargo"}
+
+// crateVersion matches the version suffix of a crate directory or file
+// name, such as the 1.0.0-beta.1 of foo-bar-1.0.0-beta.1.
+var crateVersion = regexp.MustCompile(`-(\d+\.\d+\.\d+\S*)$`)
+
+// installedCrate matches a key of the .crates.toml file written by cargo
+// install, e.g. "ripgrep 13.0.0 (registry+https://github.com/...)".
+var installedCrate = regexp.MustCompile(`^"(\S+) (\S+) \(.*\)"\s*=`)
+
+// CargoAnalyzer compares the Rust crates locked by Cargo.lock files, kept in
+// the cargo registry, or installed with cargo install.
+type CargoAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a CargoAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a CargoAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a CargoAnalyzer) Name() string {
+	return "CargoAnalyzer"
+}
+
+// Diff compares the Rust crates in two images.
+func (a CargoAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := multiVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a CargoAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := multiVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// getPackages maps each crate to the files recording it. A Cargo.lock may
+// lock several versions of a crate, which are then listed together.
+func (a CargoAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
+	root := image.FSPath
+	packages := make(map[string]map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// path provided invalid
+		return packages, err
+	}
+	config, err := configFile(image)
+	if err != nil {
+		return packages, err
+	}
+	patterns := defaultCargoHomes
+	for _, env := range config.Config.Env {
+		if strings.HasPrefix(env, "CARGO_HOME=") {
+			patterns = append([]string{strings.TrimPrefix(env, "CARGO_HOME=")}, patterns...)
+		}
+	}
+	homes := []string{}
+	for _, pattern := range patterns {
+		for _, home := range glob(root, pattern) {
+			home = path.Clean(home)
+			if !containsString(homes, home) {
+				homes = append(homes, home)
+			}
+		}
+	}
+
+	// sources and lock files of published crates are not the image's own
+	skip := map[string]bool{}
+	for _, home := range homes {
+		readCargoRegistry(root, home, packages)
+		readInstalledCrates(root, path.Join(home, ".crates.toml"), packages)
+		skip[filepath.Join(root, home, "registry")] = true
+		skip[filepath.Join(root, home, "git")] = true
+	}
+	err = pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if info.IsDir() && skip[file] {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() && info.Name() == "Cargo.lock" {
+			readCargoLock(root, file, packages)
+		}
+		return nil
+	})
+	return packages, err
+}
+
+// readCargoRegistry records the crates unpacked below registry/src, and the
+// downloaded .crate files below registry/cache which aren't unpacked.
+func readCargoRegistry(root, home string, packages map[string]map[string]util.PackageInfo) {
+	unpacked := map[string]bool{}
+	for _, dir := range glob(root, path.Join(home, "registry/src/*/*")) {
+		name, version := readCargoManifest(filepath.Join(root, dir, "Cargo.toml"))
+		if name == "" {
+			name, version = splitCrateName(path.Base(dir))
+		}
+		if name == "" {
+			continue
+		}
+		index := path.Base(path.Dir(dir))
+		unpacked[index+"/"+path.Base(dir)] = true
+		addCrate(packages, name, dir, version, pkgutil.GetSize(filepath.Join(root, dir)))
+	}
+	for _, file := range glob(root, path.Join(home, "registry/cache/*/*.crate")) {
+		base := strings.TrimSuffix(path.Base(file), ".crate")
+		if unpacked[path.Base(path.Dir(file))+"/"+base] {
+			continue
+		}
+		name, version := splitCrateName(base)
+		if name == "" {
+			continue
+		}
+		var size int64 = -1
+		if info, err := os.Stat(filepath.Join(root, file)); err == nil {
+			size = info.Size()
+		}
+		addCrate(packages, name, file, version, size)
+	}
+}
+
+// readInstalledCrates records the crates whose binaries cargo install put in
+// CARGO_HOME/bin.
+func readInstalledCrates(root, file string, packages map[string]map[string]util.PackageInfo) {
+	f, err := os.Open(filepath.Join(root, file))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match := installedCrate.FindStringSubmatch(strings.TrimSpace(scanner.Text())); match != nil {
+			addCrate(packages, match[1], file, match[2], -1)
+		}
+	}
+}
+
+// readCargoLock records the packages of a Cargo.lock, including the
+// workspace's own crates.
+func readCargoLock(root, file string, packages map[string]map[string]util.PackageInfo) {
+	f, err := os.Open(file)
+	if err != nil {
+		logrus.Warnf("Could not read %s: %s", file, err)
+		return
+	}
+	defer f.Close()
+	lockPath := "/" + filepath.ToSlash(strings.TrimPrefix(file, root+string(os.PathSeparator)))
+	locked := map[string][]string{}
+	var name, version string
+	flush := func() {
+		if name != "" && version != "" && !containsString(locked[name], version) {
+			locked[name] = append(locked[name], version)
+		}
+		name, version = "", ""
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			flush()
+			continue
+		}
+		if key, value, ok := tomlString(line); ok {
+			switch key {
+			case "name":
+				name = value
+			case "version":
+				version = value
+			}
+		}
+	}
+	flush()
+	for crate, versions := range locked {
+		sort.Strings(versions)
+		addCrate(packages, crate, lockPath, strings.Join(versions, ", "), -1)
+	}
+}
+
+// readCargoManifest returns the name and version of the [package] section
+// of a Cargo.toml.
+func readCargoManifest(file string) (string, string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	var name, version string
+	inPackage := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inPackage = line == "[package]"
+			continue
+		}
+		if key, value, ok := tomlString(line); ok && inPackage {
+			switch key {
+			case "name":
+				name = value
+			case "version":
+				version = value
+			}
+		}
+	}
+	return name, version
+}
+
+// tomlString parses a key = "value" line of a TOML file. Other values, such
+// as tables and arrays, are not needed here.
+func tomlString(line string) (string, string, bool) {
+	i := strings.Index(line, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(line[i+1:]))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), value, true
+}
+
+// splitCrateName splits a directory or file name such as serde-1.0.188 into
+// the crate name and version.
+func splitCrateName(base string) (string, string) {
+	loc := crateVersion.FindStringSubmatchIndex(base)
+	if loc == nil || loc[0] == 0 {
+		return "", ""
+	}
+	return base[:loc[0]], base[loc[2]:loc[3]]
+}
+
+func addCrate(packages map[string]map[string]util.PackageInfo, name, location, version string, size int64) {
+	if _, ok := packages[name]; !ok {
+		packages[name] = make(map[string]util.PackageInfo)
+	}
+	packages[name][location] = util.PackageInfo{Version: version, Size: size}
+}
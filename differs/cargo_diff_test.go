@@ -0,0 +1,108 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const appCargoLock = `# This file is automatically @generated by Cargo.
+# It is not intended for manual editing.
+version = 3
+
+[[package]]
+name = "app"
+version = "0.1.0"
+dependencies = [
+ "serde",
+ "syn 1.0.109",
+]
+
+[[package]]
+name = "serde"
+version = "1.0.188"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+checksum = "cf9e0fcba69a370eed61bcf2b728575f726b50b55ba57deb4f0b5a112085d836"
+
+[[package]]
+name = "syn"
+version = "1.0.109"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "syn"
+version = "2.0.38"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+`
+
+func TestGetCargoPackages(t *testing.T) {
+	const index = "/usr/local/cargo/registry/src/index.crates.io-6f17d22bba15001f"
+	fs := difftest.NewFS(t).
+		File("/app/Cargo.lock", appCargoLock).
+		File(index+"/serde-1.0.188/Cargo.toml", "[package]\nedition = \"2018\"\nname = \"serde\"\nversion = \"1.0.188\"\n\n[dependencies.serde_derive]\nversion = \"=1.0.188\"\n").
+		// published crates may ship a lock file of their own, which is skipped
+		File(index+"/ripgrep-13.0.0/Cargo.lock", "[[package]]\nname = \"regex\"\nversion = \"1.5.4\"\n").
+		File(index+"/ripgrep-13.0.0/src/main.rs", "fn main() {}\n").
+		File("/usr/local/cargo/registry/cache/index.crates.io-6f17d22bba15001f/serde-1.0.188.crate", "crate").
+		File("/usr/local/cargo/registry/cache/index.crates.io-6f17d22bba15001f/foo-bar-1.0.0-beta.1.crate", "crate").
+		File("/usr/local/cargo/.crates.toml", "[v1]\n\"ripgrep 13.0.0 (registry+https://github.com/rust-lang/crates.io-index)\" = [\"rg\"]\n")
+	packages, err := CargoAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]util.PackageInfo{
+		"app":   {"/app/Cargo.lock": {Version: "0.1.0", Size: -1}},
+		"serde": {"/app/Cargo.lock": {Version: "1.0.188", Size: -1}, index + "/serde-1.0.188": {Version: "1.0.188", Size: 112}},
+		"syn":   {"/app/Cargo.lock": {Version: "1.0.109, 2.0.38", Size: -1}},
+		"ripgrep": {
+			index + "/ripgrep-13.0.0":       {Version: "13.0.0", Size: 58},
+			"/usr/local/cargo/.crates.toml": {Version: "13.0.0", Size: -1},
+		},
+		"foo-bar": {"/usr/local/cargo/registry/cache/index.crates.io-6f17d22bba15001f/foo-bar-1.0.0-beta.1.crate": {Version: "1.0.0-beta.1", Size: 5}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, packages)
+	}
+
+	if _, err := (CargoAnalyzer{}).getPackages(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
+
+func TestSplitCrateName(t *testing.T) {
+	testCases := []struct {
+		base    string
+		name    string
+		version string
+	}{
+		{base: "serde-1.0.188", name: "serde", version: "1.0.188"},
+		{base: "foo-bar-1.0.0-beta.1", name: "foo-bar", version: "1.0.0-beta.1"},
+		{base: "tokio-1.32.0+extra", name: "tokio", version: "1.32.0+extra"},
+		{base: "not-a-crate", name: "", version: ""},
+	}
+	for _, test := range testCases {
+		name, version := splitCrateName(test.base)
+		if name != test.name || version != test.version {
+			t.Errorf("%s: expected %q %q but got %q %q", test.base, test.name, test.version, name, version)
+		}
+	}
+}
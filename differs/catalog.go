@@ -0,0 +1,220 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// AnalyzerInfo describes an analyzer, so that tools and UIs can discover
+// what container-diff supports instead of hard-coding it.
+type AnalyzerInfo struct {
+	Type        string
+	Description string
+	// Paths are the image paths the analyzer reads. Analyzers of the whole
+	// filesystem list /, and those of the image config none.
+	Paths []string
+	// Extraction tells whether the analyzer needs the extracted filesystem,
+	// or can work from the layers streamed once.
+	Extraction bool
+	// Layers tells whether the analyzer compares the image layers one by
+	// one.
+	Layers       bool
+	Analyze      bool
+	Diff         bool
+	Dependencies []string
+	// AnalysisFields and DiffFields are the JSON fields of the Analysis and
+	// Diff of its results, as dotted paths through lists and maps.
+	AnalysisFields []string
+	DiffFields     []string
+}
+
+// analyzerDoc documents an analyzer. analysis and diff are zero values of
+// the Analysis and Diff of its results, nil when the analyzer has no
+// analysis or diff.
+type analyzerDoc struct {
+	description string
+	paths       []string
+	analysis    interface{}
+	diff        interface{}
+}
+
+var (
+	singleVersionDoc = analyzerDoc{analysis: map[string]util.PackageInfo{}, diff: util.PackageDiff{}}
+	multiVersionDoc  = analyzerDoc{analysis: map[string]map[string]util.PackageInfo{}, diff: util.MultiVersionPackageDiff{}}
+	layerPackageDoc  = analyzerDoc{analysis: util.PackageLayerDiff{}}
+)
+
+func withDoc(doc analyzerDoc, description string, paths ...string) analyzerDoc {
+	doc.description = description
+	doc.paths = paths
+	return doc
+}
+
+var analyzerDocs = map[string]analyzerDoc{
+	historyAnalyzer:      {description: "History", analysis: []string{}, diff: HistDiff{}},
+	metadataAnalyzer:     {description: "Image config: env, labels, entrypoint, ports", analysis: []string{}, diff: MetadataDiff{}},
+	fileAnalyzer:         {description: "File System", paths: []string{"/"}, analysis: []pkgutil.DirectoryEntry{}, diff: util.DirDiff{}},
+	layerAnalyzer:        {description: "File System of each layer", paths: []string{"/"}, analysis: [][]pkgutil.DirectoryEntry{}, diff: util.MultipleDirDiff{}},
+	sizeAnalyzer:         {description: "Size", analysis: []util.SizeEntry{}, diff: []util.SizeDiff{}},
+	sizeLayerAnalyzer:    {description: "Size of each layer", analysis: []util.SizeEntry{}, diff: []util.SizeDiff{}},
+	aptAnalyzer:          withDoc(singleVersionDoc, "Apt", "/var/lib/dpkg/status"),
+	aptLayerAnalyzer:     withDoc(layerPackageDoc, "Apt packages of each layer", "/var/lib/dpkg/status"),
+	aptDepsAnalyzer:      {description: "Apt dependency graph", paths: []string{"/var/lib/dpkg/status", "/var/lib/apt/extended_states"}, analysis: util.DependencyAnalysis{}, diff: util.DependencyDiff{}},
+	rpmAnalyzer:          withDoc(singleVersionDoc, "RPM", "/var/lib/rpm", "/usr/lib/rpm/macros"),
+	rpmLayerAnalyzer:     withDoc(layerPackageDoc, "RPM packages of each layer", "/var/lib/rpm", "/usr/lib/rpm/macros"),
+	pipAnalyzer:          withDoc(multiVersionDoc, "Pip", "/usr/lib/python*", "/usr/local/lib/python*", "/"),
+	nodeAnalyzer:         withDoc(multiVersionDoc, "Node", "/usr/local/lib/node_modules", "/"),
+	nodeAdvisoryAnalyzer: {description: "Node advisories", paths: []string{"/usr/local/lib/node_modules", "/"}, analysis: []util.Advisory{}, diff: util.AdvisoryDiff{}},
+	emergeAnalyzer:       withDoc(singleVersionDoc, "Emerge", "/var/db/pkg"),
+	alternativesAnalyzer: {description: "Dpkg alternatives", paths: []string{"/var/lib/dpkg/alternatives", "/etc/alternatives"}, analysis: []util.Alternative{}, diff: util.AlternativeDiff{}},
+	layerSuggestAnalyzer: {description: "Layer reordering suggestions", diff: util.LayerSuggestDiff{}},
+	reproAnalyzer:        {description: "Reproducibility", paths: []string{"/"}, analysis: []util.ReproFinding{}, diff: util.ReproDiff{}},
+	initAnalyzer:         {description: "Entrypoint, stop signal and healthcheck", analysis: util.InitAnalysis{}, diff: util.InitDiff{}},
+	scoreAnalyzer:        {description: "Image health scorecard", paths: []string{"/"}, analysis: util.Scorecard{}, diff: util.ScorecardDiff{}},
+	systemdAnalyzer:      {description: "Systemd unit enablement", paths: []string{"/etc/systemd/system", "/run/systemd/system", "/lib/systemd/system", "/usr/lib/systemd/system"}, analysis: []util.SystemdUnit{}, diff: util.SystemdDiff{}},
+	servicesAnalyzer:     {description: "Services started by systemd, init scripts and supervisord", paths: []string{"/etc/systemd/system", "/lib/systemd/system", "/usr/lib/systemd/system", "/etc/init.d", "/etc/rc.d", "/etc/runlevels", "/etc/supervisord.conf", "/etc/supervisor", "/etc/supervisord.d"}, analysis: []util.Service{}, diff: util.ServiceDiff{}},
+	cronAnalyzer:         {description: "Cron jobs", paths: []string{"/etc/crontab", "/etc/cron.d", "/etc/cron.hourly", "/etc/cron.daily", "/etc/cron.weekly", "/etc/cron.monthly", "/var/spool/cron", "/etc/crontabs"}, analysis: []util.CronJob{}, diff: util.CronDiff{}},
+	usersAnalyzer:        {description: "User and group accounts", paths: []string{"/etc/passwd", "/etc/group", "/etc/shadow"}, analysis: util.UserAccounts{}, diff: util.AccountDiff{}},
+	certsAnalyzer:        {description: "Trusted CA certificates", paths: []string{"/etc/ssl/certs", "/etc/ssl/cert.pem", "/usr/share/ca-certificates", "/etc/pki/tls/certs", "/etc/pki/ca-trust/extracted", "/etc/pki/java/cacerts", "/usr/lib/jvm", "/opt/java/openjdk", "/usr/local"}, analysis: []util.Certificate{}, diff: util.CertificateDiff{}},
+	bannersAnalyzer:      {description: "Message of the day, issue and sshd banner files", paths: []string{"/etc/motd", "/etc/motd.d", "/etc/issue", "/etc/issue.d", "/etc/issue.net", "/etc/update-motd.d", "/etc/ssh/sshd_config", "/etc/ssh/sshd_config.d", "/var/lib/dpkg/status"}, analysis: []util.Banner{}, diff: util.BannerDiff{}},
+	runtimesAnalyzer:     {description: "Language runtime versions", paths: []string{"/usr", "/usr/local", "/opt"}, analysis: []util.LanguageRuntime{}, diff: []util.RuntimeVersions{}},
+	provenanceAnalyzer:   {description: "Layer provenance against build attestations", analysis: util.ProvenanceAnalysis{}, diff: util.ProvenanceDiff{}},
+	rpmRepoAnalyzer:      {description: "Yum/dnf repositories and module streams", paths: []string{"/etc/yum.repos.d", "/etc/yum.conf", "/etc/dnf/dnf.conf", "/etc/dnf/modules.d", "/etc/distro.repos.d"}, analysis: util.RPMRepoAnalysis{}, diff: util.RPMRepoDiff{}},
+	keyringAnalyzer:      {description: "Apt and rpm signing keys", paths: []string{"/etc/apt/trusted.gpg", "/etc/apt/trusted.gpg.d", "/etc/apt/keyrings", "/usr/share/keyrings", "/etc/pki/rpm-gpg"}, analysis: []util.SigningKey{}, diff: util.KeyringDiff{}},
+	entropyAnalyzer:      {description: "Compressibility and duplicate content", paths: []string{"/"}, analysis: util.EntropyAnalysis{}, diff: util.EntropyDiff{}},
+	snapAnalyzer:         withDoc(singleVersionDoc, "Snaps installed by snapd", "/var/lib/snapd/state.json", "/var/lib/snapd/snaps"),
+	flatpakAnalyzer:      withDoc(singleVersionDoc, "Flatpak applications and runtimes", "/var/lib/flatpak"),
+	linkerAnalyzer:       {description: "Library and PATH resolution", paths: []string{"/etc/ld.so.conf", "/etc/ld.so.cache"}, analysis: util.LinkerAnalysis{}, diff: util.LinkerDiff{}},
+	jarAnalyzer:          withDoc(multiVersionDoc, "Java artifacts in jar, war and ear files", "/"),
+	conffilesAnalyzer:    {description: "Leftover configuration of removed packages", paths: []string{"/var/lib/dpkg/status", "/var/lib/dpkg/info"}, analysis: util.ConffileAnalysis{}, diff: util.ConffileDiff{}},
+	cargoAnalyzer:        withDoc(multiVersionDoc, "Rust crates", "/usr/local/cargo", "/root/.cargo", "/home/*/.cargo", "/"),
+	binVersionAnalyzer:   withDoc(multiVersionDoc, "Versions of untracked binaries", "/bin", "/sbin", "/usr/bin", "/usr/sbin", "/usr/lib", "/usr/libexec", "/lib", "/opt/*/bin"),
+	nugetAnalyzer:        withDoc(multiVersionDoc, "NuGet packages of .NET applications", "/root/.nuget/packages", "/home/*/.nuget/packages", "/usr/share/dotnet/sdk/NuGetFallbackFolder", "/"),
+	yarnAnalyzer:         withDoc(multiVersionDoc, "Yarn projects: lock files, workspaces and node_modules", "/"),
+	osAnalyzer:           {description: "Distribution and base image lineage", paths: []string{"/etc/os-release", "/usr/lib/os-release", "/etc/lsb-release", "/etc/alpine-release", "/etc/debian_version", "/etc/redhat-release"}, analysis: util.OSRelease{}, diff: util.OSDiff{}},
+	buildInfoAnalyzer:    {description: "OCI annotations and build args", analysis: util.BuildInfo{}, diff: util.BuildInfoDiff{}},
+	permissionsAnalyzer:  {description: "File modes and owners, setuid and world-writable files", paths: []string{"/"}, analysis: []util.FilePermission{}, diff: util.PermissionDiff{}},
+	volumesAnalyzer:      {description: "Content baked into VOLUME paths", paths: []string{"/"}, analysis: []util.VolumeContent{}, diff: util.VolumeDiff{}},
+	sharedLayersAnalyzer: {description: "Layers shared by two images and unique to each", diff: util.SharedLayerDiff{}},
+	secretsAnalyzer:      {description: "Likely credentials: private keys, AWS keys, registry and npm auth", paths: []string{"/"}, analysis: []util.Secret{}, diff: util.SecretDiff{}},
+	vulnAnalyzer:         {description: "Known vulnerabilities of OS and language packages", paths: []string{"/"}, analysis: []util.Advisory{}, diff: util.AdvisoryDiff{}},
+	binaryAnalyzer:       {description: "Linked libraries and SONAMEs of ELF binaries", paths: []string{"/"}, analysis: []util.ELFObject{}, diff: util.BinaryDiff{}},
+}
+
+// GetAnalyzerInfo describes every analyzer, in type order.
+func GetAnalyzerInfo() []AnalyzerInfo {
+	types := []string{}
+	for t := range Analyzers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	infos := []AnalyzerInfo{}
+	for _, t := range types {
+		doc := analyzerDocs[t]
+		info := AnalyzerInfo{
+			Type:           t,
+			Description:    doc.description,
+			Paths:          doc.paths,
+			Extraction:     !isStreamingAnalyzer(t),
+			Layers:         isLayerAnalyzer(t),
+			Analyze:        doc.analysis != nil,
+			Diff:           doc.diff != nil,
+			Dependencies:   []string{},
+			AnalysisFields: jsonFields(doc.analysis),
+			DiffFields:     jsonFields(doc.diff),
+		}
+		if info.Paths == nil {
+			info.Paths = []string{}
+		}
+		if dependent, ok := Analyzers[t].(Dependent); ok {
+			info.Dependencies = dependent.Dependencies()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func isStreamingAnalyzer(t string) bool {
+	for _, a := range StreamingAnalyzers {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isLayerAnalyzer(t string) bool {
+	for _, a := range LayerAnalyzers {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonFields lists the JSON fields of the structs in v, its lists and its
+// maps, e.g. Packages1.Version for a PackageDiff.
+func jsonFields(v interface{}) []string {
+	fields := []string{}
+	if v != nil {
+		fields = appendJSONFields(fields, reflect.TypeOf(v), "", map[reflect.Type]bool{})
+	}
+	return fields
+}
+
+func appendJSONFields(fields []string, t reflect.Type, prefix string, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	// values marshalling themselves, such as digests and times, are leaves
+	if t.Kind() != reflect.Struct || seen[t] || t.Implements(jsonMarshaler) || t.Implements(textMarshaler) || reflect.PtrTo(t).Implements(textMarshaler) {
+		return fields
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			fields = appendJSONFields(fields, field.Type, prefix, seen)
+			continue
+		}
+		fields = append(fields, prefix+name)
+		fields = appendJSONFields(fields, field.Type, prefix+name+".", seen)
+	}
+	return fields
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestAnalyzerDocs(t *testing.T) {
+	for name := range Analyzers {
+		doc, ok := analyzerDocs[name]
+		if !ok || doc.description == "" {
+			t.Errorf("Expected analyzer %s to be documented", name)
+		}
+		if doc.analysis == nil && doc.diff == nil {
+			t.Errorf("Expected analyzer %s to support analyze or diff", name)
+		}
+	}
+	for name := range analyzerDocs {
+		if _, ok := Analyzers[name]; !ok {
+			t.Errorf("Expected documented analyzer %s to exist", name)
+		}
+	}
+}
+
+func TestGetAnalyzerInfo(t *testing.T) {
+	infos := map[string]AnalyzerInfo{}
+	for _, info := range GetAnalyzerInfo() {
+		infos[info.Type] = info
+	}
+	if len(infos) != len(Analyzers) {
+		t.Errorf("Expected %d analyzers but got %d", len(Analyzers), len(infos))
+	}
+	apt := infos[aptAnalyzer]
+	if !apt.Extraction || apt.Layers || !apt.Analyze || !apt.Diff {
+		t.Errorf("Expected apt to need extraction and support analyze and diff but got %+v", apt)
+	}
+	if history := infos[historyAnalyzer]; history.Extraction {
+		t.Errorf("Expected history to be streamed")
+	}
+	if suggest := infos[layerSuggestAnalyzer]; suggest.Analyze || !suggest.Diff {
+		t.Errorf("Expected suggest to only support diff but got %+v", suggest)
+	}
+	if advisory := infos[nodeAdvisoryAnalyzer]; !reflect.DeepEqual(advisory.Dependencies, []string{nodeAnalyzer}) {
+		t.Errorf("Expected nodeadvisory to depend on node but got %v", advisory.Dependencies)
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	type entry struct {
+		Name     string
+		Linkname string `json:",omitempty"`
+		Internal string `json:"-"`
+		hidden   string
+	}
+	type diff struct {
+		Entries map[string][]entry
+		Total   int64 `json:"total"`
+	}
+	expected := []string{"Entries", "Entries.Name", "Entries.Linkname", "total"}
+	if actual := jsonFields(diff{}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected fields %v but got %v", expected, actual)
+	}
+	expected = []string{"Version", "Size"}
+	if actual := jsonFields(map[string]util.PackageInfo{}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected fields %v but got %v", expected, actual)
+	}
+	if actual := jsonFields([]string{}); len(actual) != 0 {
+		t.Errorf("Expected no fields for a list of strings but got %v", actual)
+	}
+}
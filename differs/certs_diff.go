@@ -0,0 +1,423 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// certificatePaths are the image paths of the PEM files and bundles of the
+// system trust stores of Debian, Alpine and Red Hat based images.
+var certificatePaths = []string{
+	"/etc/ssl/certs/*",
+	"/etc/ssl/cert.pem",
+	"/etc/pki/tls/certs/*",
+	"/etc/pki/ca-trust/extracted/pem/*",
+}
+
+// keystorePaths are the image paths of the Java trust stores, shared by
+// the system or shipped by JDKs.
+var keystorePaths = []string{
+	"/etc/ssl/certs/java/cacerts",
+	"/etc/pki/ca-trust/extracted/java/cacerts",
+	"/etc/pki/java/cacerts",
+	"/usr/lib/jvm/*/lib/security/cacerts",
+	"/usr/lib/jvm/*/jre/lib/security/cacerts",
+	"/opt/java/openjdk/lib/security/cacerts",
+	"/usr/local/openjdk-*/lib/security/cacerts",
+}
+
+// opensslHashLink matches the links c_rehash adds to certificate
+// directories, which only index the certificates of other files.
+var opensslHashLink = regexp.MustCompile(`^[0-9a-f]{8}\.r?[0-9]+$`)
+
+// Magic numbers of the JKS and JCEKS keystore formats, and the tags of
+// their entries.
+const (
+	jksMagic   = 0xfeedfeed
+	jceksMagic = 0xcececece
+
+	jksPrivateKey  = 1
+	jksTrustedCert = 2
+)
+
+// Object identifiers of the PKCS #12 structures holding certificates.
+var (
+	oidPKCS7Data   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCertBag     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509CertBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+)
+
+type pkcs12PFX struct {
+	Version  int
+	AuthSafe pkcs12ContentInfo
+	MacData  asn1.RawValue `asn1:"optional"`
+}
+
+type pkcs12ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,optional"`
+}
+
+type pkcs12SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0"`
+	Attributes asn1.RawValue `asn1:"optional"`
+}
+
+type pkcs12CertBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type CertsAnalyzer struct {
+}
+
+func (a CertsAnalyzer) Name() string {
+	return "CertsAnalyzer"
+}
+
+// Diff compares the CA certificates two images trust.
+func (a CertsAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	certs1, err := getCertificates(image1.FSPath)
+	if err != nil {
+		return &util.CertificateDiffResult{}, err
+	}
+	certs2, err := getCertificates(image2.FSPath)
+	if err != nil {
+		return &util.CertificateDiffResult{}, err
+	}
+	return &util.CertificateDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Certificates",
+		Diff:     util.GetCertificateDiff(certs1, certs2, time.Now()),
+	}, nil
+}
+
+func (a CertsAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	certs, err := getCertificates(image.FSPath)
+	if err != nil {
+		return &util.CertificateAnalyzeResult{}, err
+	}
+	return &util.CertificateAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Certificates",
+		Analysis:    certs,
+	}, nil
+}
+
+// getCertificates reads the certificates of the trust stores of an image:
+// PEM files and bundles, and JKS, JCEKS or PKCS #12 Java keystores. A
+// certificate found in several of them is listed once, with all of them.
+// Files linking to ones already read, such as the links of /etc/ssl/certs
+// into /usr/share/ca-certificates, are only read once.
+func getCertificates(root string) ([]util.Certificate, error) {
+	certs := []util.Certificate{}
+	if _, err := os.Stat(root); err != nil {
+		return certs, err
+	}
+	keystores := map[string]bool{}
+	for _, file := range globImage(root, keystorePaths) {
+		keystores[file] = true
+	}
+	files := append(globImage(root, keystorePaths), globImage(root, certificatePaths)...)
+	sort.Strings(files)
+
+	read := map[string]bool{}
+	byFingerprint := map[string]*util.Certificate{}
+	order := []string{}
+	for _, file := range files {
+		resolved, ok := resolveInImage(root, file)
+		if !ok || read[resolved] {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, resolved))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		read[resolved] = true
+		data, err := ioutil.ReadFile(filepath.Join(root, resolved))
+		if err != nil {
+			logrus.Warningf("unable to read certificates of %s: %s", file, err)
+			continue
+		}
+		var ders [][]byte
+		if keystores[file] {
+			ders, err = readKeystore(data)
+		} else {
+			ders = readPEMCertificates(data)
+		}
+		if err != nil {
+			logrus.Warningf("unable to read keystore %s: %s", file, err)
+		}
+		for _, der := range ders {
+			cert, err := parseCertificate(der)
+			if err != nil {
+				logrus.Debugf("skipping certificate of %s: %s", file, err)
+				continue
+			}
+			if existing, ok := byFingerprint[cert.Fingerprint]; ok {
+				if existing.Sources[len(existing.Sources)-1] != file {
+					existing.Sources = append(existing.Sources, file)
+				}
+				continue
+			}
+			cert.Sources = []string{file}
+			byFingerprint[cert.Fingerprint] = &cert
+			order = append(order, cert.Fingerprint)
+		}
+	}
+	for _, fingerprint := range order {
+		certs = append(certs, *byFingerprint[fingerprint])
+	}
+	util.SortCertificates(certs)
+	return certs, nil
+}
+
+// globImage returns the image paths matching patterns, other than the
+// hash links of certificate directories.
+func globImage(root string, patterns []string) []string {
+	files := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if file := imagePath(root, match); !opensslHashLink.MatchString(path.Base(file)) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+func parseCertificate(der []byte) (util.Certificate, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return util.Certificate{}, err
+	}
+	sum := sha256.Sum256(der)
+	return util.Certificate{
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Subject:     certificateName(cert.Subject.CommonName, cert.Subject.String()),
+		Issuer:      certificateName(cert.Issuer.CommonName, cert.Issuer.String()),
+		NotBefore:   cert.NotBefore.UTC().Format(util.CertificateDateFormat),
+		NotAfter:    cert.NotAfter.UTC().Format(util.CertificateDateFormat),
+	}, nil
+}
+
+func certificateName(commonName, distinguishedName string) string {
+	if commonName != "" {
+		return commonName
+	}
+	return distinguishedName
+}
+
+// readPEMCertificates returns the DER encodings of the certificates of a
+// PEM file, skipping keys and the other blocks it may hold.
+func readPEMCertificates(data []byte) [][]byte {
+	ders := [][]byte{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return ders
+		}
+		if block.Type == "CERTIFICATE" || block.Type == "TRUSTED CERTIFICATE" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+}
+
+// readKeystore returns the DER encodings of the trusted certificates of a
+// Java keystore, in the JKS or JCEKS format or in PKCS #12. Certificates
+// of PKCS #12 keystores are only read when not encrypted, as the cacerts
+// of recent JDKs are.
+func readKeystore(data []byte) ([][]byte, error) {
+	if len(data) >= 4 {
+		magic := binary.BigEndian.Uint32(data)
+		if magic == jksMagic || magic == jceksMagic {
+			return readJKS(data)
+		}
+	}
+	return readPKCS12(data)
+}
+
+// readJKS reads the trusted certificate entries of a JKS or JCEKS
+// keystore. The certificate chains of private keys aren't trust anchors,
+// and are skipped. Reading stops at the first secret key entry of a JCEKS
+// keystore, whose length isn't recorded.
+func readJKS(data []byte) ([][]byte, error) {
+	r := bytes.NewReader(data)
+	var header struct {
+		Magic   uint32
+		Version uint32
+		Count   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Version != 1 && header.Version != 2 {
+		return nil, fmt.Errorf("unsupported keystore version %d", header.Version)
+	}
+	readUTF := func() error {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return err
+		}
+		_, err := r.Seek(int64(n), io.SeekCurrent)
+		return err
+	}
+	readCert := func() ([]byte, error) {
+		if header.Version == 2 {
+			if err := readUTF(); err != nil {
+				return nil, err
+			}
+		}
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		if int64(n) > int64(r.Len()) {
+			return nil, errors.New("truncated certificate")
+		}
+		der := make([]byte, n)
+		_, err := io.ReadFull(r, der)
+		return der, err
+	}
+
+	ders := [][]byte{}
+	for i := uint32(0); i < header.Count; i++ {
+		var tag uint32
+		if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
+			return ders, err
+		}
+		// alias and creation date
+		if err := readUTF(); err != nil {
+			return ders, err
+		}
+		if _, err := r.Seek(8, io.SeekCurrent); err != nil {
+			return ders, err
+		}
+		switch tag {
+		case jksTrustedCert:
+			der, err := readCert()
+			if err != nil {
+				return ders, err
+			}
+			ders = append(ders, der)
+		case jksPrivateKey:
+			var n uint32
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+				return ders, err
+			}
+			if _, err := r.Seek(int64(n), io.SeekCurrent); err != nil {
+				return ders, err
+			}
+			var chain uint32
+			if err := binary.Read(r, binary.BigEndian, &chain); err != nil {
+				return ders, err
+			}
+			for j := uint32(0); j < chain; j++ {
+				if _, err := readCert(); err != nil {
+					return ders, err
+				}
+			}
+		default:
+			return ders, fmt.Errorf("unsupported keystore entry type %d", tag)
+		}
+	}
+	return ders, nil
+}
+
+// readPKCS12 reads the certificate bags of the unencrypted safe contents
+// of a PKCS #12 keystore.
+func readPKCS12(data []byte) ([][]byte, error) {
+	var pfx pkcs12PFX
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, err
+	}
+	authSafe, err := pkcs12Data(pfx.AuthSafe)
+	if err != nil {
+		return nil, err
+	}
+	var contents []pkcs12ContentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, err
+	}
+	ders := [][]byte{}
+	encrypted := 0
+	for _, content := range contents {
+		safe, err := pkcs12Data(content)
+		if err != nil {
+			encrypted++
+			continue
+		}
+		var bags []pkcs12SafeBag
+		if _, err := asn1.Unmarshal(safe, &bags); err != nil {
+			return ders, err
+		}
+		for _, bag := range bags {
+			if !bag.ID.Equal(oidCertBag) {
+				continue
+			}
+			var cert pkcs12CertBag
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &cert); err != nil || !cert.ID.Equal(oidX509CertBag) {
+				continue
+			}
+			ders = append(ders, cert.Data)
+		}
+	}
+	if len(ders) == 0 && encrypted > 0 {
+		return ders, errors.New("certificates are encrypted")
+	}
+	return ders, nil
+}
+
+// pkcs12Data returns the octets of a content info of the data type.
+func pkcs12Data(content pkcs12ContentInfo) ([]byte, error) {
+	if !content.ContentType.Equal(oidPKCS7Data) {
+		return nil, fmt.Errorf("unsupported content type %s", content.ContentType)
+	}
+	var octets []byte
+	if _, err := asn1.Unmarshal(content.Content.Bytes, &octets); err != nil {
+		return nil, err
+	}
+	return octets, nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// configBackupSuffixes are appended by dpkg, ucf and rpm to the copies of
+// configuration files they save when a package is upgraded or removed.
+var configBackupSuffixes = []string{
+	".dpkg-old", ".dpkg-dist", ".dpkg-new", ".dpkg-bak", ".dpkg-tmp",
+	".ucf-old", ".ucf-dist", ".ucf-new",
+	".rpmsave", ".rpmnew", ".rpmorig",
+}
+
+// ConffilesAnalyzer reports the configuration files which no installed
+// package uses, and the removed packages which left them behind.
+type ConffilesAnalyzer struct {
+}
+
+func (a ConffilesAnalyzer) Name() string {
+	return "ConffilesAnalyzer"
+}
+
+// Diff compares the leftover configuration of two images.
+func (a ConffilesAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	analysis1, err := getConffileAnalysis(image1.FSPath)
+	if err != nil {
+		return &util.ConffileDiffResult{}, err
+	}
+	analysis2, err := getConffileAnalysis(image2.FSPath)
+	if err != nil {
+		return &util.ConffileDiffResult{}, err
+	}
+	return &util.ConffileDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Conffiles",
+		Diff:     util.GetConffileDiff(analysis1, analysis2),
+	}, nil
+}
+
+func (a ConffilesAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := getConffileAnalysis(image.FSPath)
+	if err != nil {
+		return &util.ConffileAnalyzeResult{}, err
+	}
+	return &util.ConffileAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Conffiles",
+		Analysis:    analysis,
+	}, nil
+}
+
+// getConffileAnalysis lists the packages dpkg keeps in the "rc" state, their
+// conffiles and the obsolete conffiles of installed packages still on disk,
+// and the backup copies of configuration files below /etc.
+func getConffileAnalysis(root string) (util.ConffileAnalysis, error) {
+	analysis := util.ConffileAnalysis{Packages: []util.ConfigPackage{}, Files: []util.LeftoverFile{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return analysis, err
+	}
+	stanzas, err := readDpkgStanzas(filepath.Join(root, dpkgStatusFile))
+	if err != nil {
+		return analysis, err
+	}
+
+	owners := map[string]string{}
+	for _, s := range stanzas {
+		removed := dpkgState(s.status) == "config-files"
+		pkg := util.ConfigPackage{Name: s.name, Version: s.version, Conffiles: []string{}}
+		for _, conffile := range s.conffiles {
+			owners[conffile.path] = s.name
+			if !removed && !conffile.obsolete {
+				continue
+			}
+			info, err := os.Lstat(filepath.Join(root, conffile.path))
+			if err != nil {
+				continue
+			}
+			reason := util.LeftoverObsolete
+			if removed {
+				reason = util.LeftoverRemoved
+				pkg.Conffiles = append(pkg.Conffiles, conffile.path)
+			}
+			analysis.Files = append(analysis.Files, util.LeftoverFile{Path: conffile.path, Package: s.name, Reason: reason, Size: info.Size()})
+		}
+		if removed {
+			analysis.Packages = append(analysis.Packages, pkg)
+		}
+	}
+
+	etc := filepath.Join(root, "etc")
+	filepath.Walk(etc, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		for _, suffix := range configBackupSuffixes {
+			if !strings.HasSuffix(file, suffix) {
+				continue
+			}
+			name := "/" + filepath.ToSlash(strings.TrimPrefix(file, root+string(os.PathSeparator)))
+			analysis.Files = append(analysis.Files, util.LeftoverFile{
+				Path:    name,
+				Package: owners[strings.TrimSuffix(name, suffix)],
+				Reason:  util.LeftoverBackup,
+				Size:    info.Size(),
+			})
+			break
+		}
+		return nil
+	})
+	util.SortConffileAnalysis(&analysis)
+	return analysis, nil
+}
+
+// dpkgState returns the package state of a dpkg Status field, the last of
+// its three words, e.g. "config-files" in "deinstall ok config-files".
+func dpkgState(status string) string {
+	fields := strings.Fields(status)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
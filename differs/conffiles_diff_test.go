@@ -0,0 +1,100 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const conffilesStatus = `Package: nginx-common
+Status: deinstall ok config-files
+Version: 1.18.0-6
+Conffiles:
+ /etc/nginx/nginx.conf 0123456789abcdef0123456789abcdef
+ /etc/nginx/mime.types 0123456789abcdef0123456789abcdef
+
+Package: openssh-server
+Status: install ok installed
+Version: 1:8.4p1-5
+Conffiles:
+ /etc/ssh/moduli 0123456789abcdef0123456789abcdef
+ /etc/init/ssh.conf 0123456789abcdef0123456789abcdef obsolete
+ /etc/default/ssh 0123456789abcdef0123456789abcdef obsolete
+Description: secure shell (SSH) server
+ A long description.
+
+Package: purged
+Status: purge ok not-installed
+`
+
+func TestGetConffileAnalysis(t *testing.T) {
+	fs := difftest.NewFS(t).
+		File("/var/lib/dpkg/status", conffilesStatus).
+		File("/etc/nginx/nginx.conf", "user www-data;\n").
+		File("/etc/ssh/moduli", "moduli").
+		File("/etc/ssh/moduli.dpkg-old", "old moduli").
+		File("/etc/init/ssh.conf", "start on filesystem\n").
+		File("/etc/yum.repos.d/base.repo.rpmnew", "[base]\n").
+		File("/etc/hosts", "127.0.0.1 localhost\n")
+	analysis, err := getConffileAnalysis(fs.Root())
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := util.ConffileAnalysis{
+		Packages: []util.ConfigPackage{
+			{Name: "nginx-common", Version: "1.18.0-6", Conffiles: []string{"/etc/nginx/nginx.conf"}},
+		},
+		Files: []util.LeftoverFile{
+			{Path: "/etc/init/ssh.conf", Package: "openssh-server", Reason: util.LeftoverObsolete, Size: 20},
+			{Path: "/etc/nginx/nginx.conf", Package: "nginx-common", Reason: util.LeftoverRemoved, Size: 15},
+			{Path: "/etc/ssh/moduli.dpkg-old", Package: "openssh-server", Reason: util.LeftoverBackup, Size: 10},
+			{Path: "/etc/yum.repos.d/base.repo.rpmnew", Reason: util.LeftoverBackup, Size: 7},
+		},
+		Size: 52,
+	}
+	if !reflect.DeepEqual(analysis, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, analysis)
+	}
+
+	if _, err := getConffileAnalysis("testDirs/notThere"); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
+
+func TestGetConffileDiff(t *testing.T) {
+	nginx := util.ConfigPackage{Name: "nginx-common", Version: "1.18.0-6", Conffiles: []string{}}
+	apache := util.ConfigPackage{Name: "apache2", Version: "2.4.56-1", Conffiles: []string{}}
+	backup := util.LeftoverFile{Path: "/etc/ssh/moduli.dpkg-old", Reason: util.LeftoverBackup}
+	rpmnew := util.LeftoverFile{Path: "/etc/yum.conf.rpmnew", Reason: util.LeftoverBackup}
+	analysis1 := util.ConffileAnalysis{Packages: []util.ConfigPackage{nginx}, Files: []util.LeftoverFile{backup}}
+	analysis2 := util.ConffileAnalysis{Packages: []util.ConfigPackage{apache, nginx}, Files: []util.LeftoverFile{rpmnew}}
+
+	diff := util.GetConffileDiff(analysis1, analysis2)
+	expected := util.ConffileDiff{
+		Packages1: []util.ConfigPackage{},
+		Packages2: []util.ConfigPackage{apache},
+		Files1:    []util.LeftoverFile{backup},
+		Files2:    []util.LeftoverFile{rpmnew},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
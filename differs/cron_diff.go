@@ -0,0 +1,188 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// System crontabs name the user of each job, user crontabs are those of
+// the user they are named after: in /var/spool/cron/crontabs on Debian,
+// /var/spool/cron on Red Hat and /etc/crontabs with busybox crond.
+var (
+	systemCrontab     = "etc/crontab"
+	systemCrontabDirs = []string{"etc/cron.d"}
+	userCrontabDirs   = []string{"var/spool/cron/crontabs", "var/spool/cron", "etc/crontabs"}
+	// run-parts runs the scripts of these directories at their schedule
+	periodicCronDirs = map[string]string{
+		"etc/cron.hourly":  "@hourly",
+		"etc/cron.daily":   "@daily",
+		"etc/cron.weekly":  "@weekly",
+		"etc/cron.monthly": "@monthly",
+	}
+)
+
+// cronFileName matches the files cron and run-parts read from their
+// directories, leaving out those such as backups and package leftovers.
+var cronFileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// cronEnvironment matches the variable assignments of crontabs, such as
+// MAILTO=root.
+var cronEnvironment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+
+type CronAnalyzer struct {
+}
+
+func (a CronAnalyzer) Name() string {
+	return "CronAnalyzer"
+}
+
+// Diff compares the jobs scheduled by the crontabs of two images.
+func (a CronAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	jobs1, err := getCronJobs(image1.FSPath)
+	if err != nil {
+		return &util.CronDiffResult{}, err
+	}
+	jobs2, err := getCronJobs(image2.FSPath)
+	if err != nil {
+		return &util.CronDiffResult{}, err
+	}
+	return &util.CronDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Cron",
+		Diff:     util.GetCronDiff(jobs1, jobs2),
+	}, nil
+}
+
+func (a CronAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	jobs, err := getCronJobs(image.FSPath)
+	if err != nil {
+		return &util.CronAnalyzeResult{}, err
+	}
+	return &util.CronAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Cron",
+		Analysis:    jobs,
+	}, nil
+}
+
+// getCronJobs reads the jobs of the system crontab, of /etc/cron.d, of the
+// users' crontabs and the scripts of the periodic cron directories.
+func getCronJobs(root string) ([]util.CronJob, error) {
+	jobs := []util.CronJob{}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return jobs, err
+	}
+	jobs = append(jobs, readCrontab(root, systemCrontab, "")...)
+	for _, dir := range systemCrontabDirs {
+		for _, name := range cronFiles(filepath.Join(root, dir)) {
+			jobs = append(jobs, readCrontab(root, filepath.Join(dir, name), "")...)
+		}
+	}
+	for _, dir := range userCrontabDirs {
+		for _, name := range cronFiles(filepath.Join(root, dir)) {
+			jobs = append(jobs, readCrontab(root, filepath.Join(dir, name), name)...)
+		}
+	}
+	for dir, schedule := range periodicCronDirs {
+		for _, name := range cronFiles(filepath.Join(root, dir)) {
+			script := "/" + filepath.ToSlash(filepath.Join(dir, name))
+			jobs = append(jobs, util.CronJob{Schedule: schedule, User: "root", Command: script, Source: script})
+		}
+	}
+	util.SortCronJobs(jobs)
+	return jobs, nil
+}
+
+// cronFiles lists the regular files of a cron directory that cron reads.
+func cronFiles(dir string) []string {
+	names := []string{}
+	contents, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	for _, c := range contents {
+		if c.Mode().IsRegular() && cronFileName.MatchString(c.Name()) {
+			names = append(names, c.Name())
+		}
+	}
+	return names
+}
+
+// readCrontab reads the jobs of a crontab. Jobs of system crontabs name
+// their user after the schedule, while those of user crontabs run as user.
+func readCrontab(root, file, user string) []util.CronJob {
+	jobs := []util.CronJob{}
+	f, err := os.Open(filepath.Join(root, file))
+	if err != nil {
+		return jobs
+	}
+	defer f.Close()
+	source := "/" + filepath.ToSlash(file)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || cronEnvironment.MatchString(line) {
+			continue
+		}
+		fields := strings.Fields(line)
+		scheduleFields := 5
+		if strings.HasPrefix(fields[0], "@") {
+			scheduleFields = 1
+		}
+		userFields := 0
+		if user == "" {
+			userFields = 1
+		}
+		if len(fields) <= scheduleFields+userFields {
+			logrus.Debugf("skipping malformed crontab line in %s: %s", source, line)
+			continue
+		}
+		job := util.CronJob{Schedule: strings.Join(fields[:scheduleFields], " "), User: user, Source: source}
+		if user == "" {
+			job.User = fields[scheduleFields]
+		}
+		job.Command = skipFields(line, scheduleFields+userFields)
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// skipFields returns the rest of line after its first n whitespace
+// separated fields, keeping the spacing of the command it holds.
+func skipFields(line string, n int) string {
+	for i := 0; i < n; i++ {
+		line = strings.TrimLeft(line, " \t")
+		if end := strings.IndexAny(line, " \t"); end >= 0 {
+			line = line[end:]
+		} else {
+			line = ""
+		}
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetCronJobs(t *testing.T) {
+	root := difftest.NewFS(t).
+		File("etc/crontab", "SHELL=/bin/sh\n# m h dom mon dow user command\n17 *\t* * *\troot    cd / && run-parts --report /etc/cron.hourly\n").
+		File("etc/cron.d/certbot", "MAILTO=\"\"\n0 */12 * * * root test -x /usr/bin/certbot && certbot -q renew\n@reboot root /usr/local/bin/warmup\n").
+		File("etc/cron.d/certbot.dpkg-dist", "0 0 * * * root certbot renew\n").
+		File("var/spool/cron/crontabs/app", "*/5 * * * * /app/bin/sync --quiet\nbroken line\n").
+		File("etc/cron.daily/logrotate", "#!/bin/sh\n").
+		File("etc/cron.daily/.placeholder", "").
+		Root()
+
+	jobs, err := getCronJobs(root)
+	if err != nil {
+		t.Fatalf("Error reading cron jobs: %s", err)
+	}
+	expected := []util.CronJob{
+		{Schedule: "0 */12 * * *", User: "root", Command: "test -x /usr/bin/certbot && certbot -q renew", Source: "/etc/cron.d/certbot"},
+		{Schedule: "@reboot", User: "root", Command: "/usr/local/bin/warmup", Source: "/etc/cron.d/certbot"},
+		{Schedule: "@daily", User: "root", Command: "/etc/cron.daily/logrotate", Source: "/etc/cron.daily/logrotate"},
+		{Schedule: "17 * * * *", User: "root", Command: "cd / && run-parts --report /etc/cron.hourly", Source: "/etc/crontab"},
+		{Schedule: "*/5 * * * *", User: "app", Command: "/app/bin/sync --quiet", Source: "/var/spool/cron/crontabs/app"},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, jobs)
+	}
+
+	if _, err := getCronJobs("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestGetCronDiff(t *testing.T) {
+	backup := util.CronJob{Schedule: "0 3 * * *", User: "root", Command: "/usr/local/bin/backup", Source: "/etc/cron.d/backup"}
+	sync1 := util.CronJob{Schedule: "*/5 * * * *", User: "app", Command: "/app/bin/sync", Source: "/var/spool/cron/crontabs/app"}
+	sync2 := sync1
+	sync2.Schedule = "*/15 * * * *"
+	renew := util.CronJob{Schedule: "@daily", User: "root", Command: "certbot renew", Source: "/etc/cron.d/certbot"}
+
+	diff := util.GetCronDiff([]util.CronJob{backup, sync1}, []util.CronJob{sync2, renew})
+	expected := util.CronDiff{
+		Added:   []util.CronJob{renew},
+		Removed: []util.CronJob{backup},
+		Changed: []util.CronJobChange{{User: "app", Command: "/app/bin/sync", Source: "/var/spool/cron/crontabs/app", Schedule1: "*/5 * * * *", Schedule2: "*/15 * * * *"}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -18,6 +18,8 @@ package differs
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
@@ -32,21 +34,66 @@ const sizeAnalyzer = "size"
 const sizeLayerAnalyzer = "sizelayer"
 const aptAnalyzer = "apt"
 const aptLayerAnalyzer = "aptlayer"
+const aptDepsAnalyzer = "aptdeps"
 const rpmAnalyzer = "rpm"
 const rpmLayerAnalyzer = "rpmlayer"
 const pipAnalyzer = "pip"
 const nodeAnalyzer = "node"
+const nodeAdvisoryAnalyzer = "nodeadvisory"
 const emergeAnalyzer = "emerge"
+const alternativesAnalyzer = "alternatives"
+const layerSuggestAnalyzer = "suggest"
+const reproAnalyzer = "repro"
+const initAnalyzer = "init"
+const scoreAnalyzer = "score"
+const systemdAnalyzer = "systemd"
+const servicesAnalyzer = "services"
+const cronAnalyzer = "cron"
+const usersAnalyzer = "users"
+const certsAnalyzer = "certs"
+const bannersAnalyzer = "banners"
+const runtimesAnalyzer = "runtimes"
+const provenanceAnalyzer = "provenance"
+const rpmRepoAnalyzer = "rpmrepo"
+const keyringAnalyzer = "keyring"
+const entropyAnalyzer = "entropy"
+const snapAnalyzer = "snap"
+const flatpakAnalyzer = "flatpak"
+const linkerAnalyzer = "linker"
+const jarAnalyzer = "jar"
+const conffilesAnalyzer = "conffiles"
+const cargoAnalyzer = "cargo"
+const binVersionAnalyzer = "binversion"
+const nugetAnalyzer = "nuget"
+const yarnAnalyzer = "yarn"
+const osAnalyzer = "os"
+const buildInfoAnalyzer = "buildinfo"
+const permissionsAnalyzer = "permissions"
+const volumesAnalyzer = "volumes"
+const sharedLayersAnalyzer = "sharedlayers"
+const secretsAnalyzer = "secrets"
+const vulnAnalyzer = "vuln"
+const binaryAnalyzer = "binary"
 
 type DiffRequest struct {
 	Image1    pkgutil.Image
 	Image2    pkgutil.Image
 	DiffTypes []Analyzer
+	// Timings, when set, records how long each differ takes
+	Timings *util.Timings
+	// Concurrency is how many differs run at once. Below 2, they run one
+	// after another.
+	Concurrency int
 }
 
 type SingleRequest struct {
 	Image        pkgutil.Image
 	AnalyzeTypes []Analyzer
+	// Timings, when set, records how long each analyzer takes
+	Timings *util.Timings
+	// Concurrency is how many analyzers run at once. Below 2, they run one
+	// after another.
+	Concurrency int
 }
 
 type Analyzer interface {
@@ -56,38 +103,79 @@ type Analyzer interface {
 }
 
 var Analyzers = map[string]Analyzer{
-	historyAnalyzer:   HistoryAnalyzer{},
-	metadataAnalyzer:  MetadataAnalyzer{},
-	fileAnalyzer:      FileAnalyzer{},
-	layerAnalyzer:     FileLayerAnalyzer{},
-	sizeAnalyzer:      SizeAnalyzer{},
-	sizeLayerAnalyzer: SizeLayerAnalyzer{},
-	aptAnalyzer:       AptAnalyzer{},
-	aptLayerAnalyzer:  AptLayerAnalyzer{},
-	rpmAnalyzer:       RPMAnalyzer{},
-	rpmLayerAnalyzer:  RPMLayerAnalyzer{},
-	pipAnalyzer:       PipAnalyzer{},
-	nodeAnalyzer:      NodeAnalyzer{},
-	emergeAnalyzer:    EmergeAnalyzer{},
+	historyAnalyzer:      HistoryAnalyzer{},
+	metadataAnalyzer:     MetadataAnalyzer{},
+	fileAnalyzer:         FileAnalyzer{},
+	layerAnalyzer:        FileLayerAnalyzer{},
+	sizeAnalyzer:         SizeAnalyzer{},
+	sizeLayerAnalyzer:    SizeLayerAnalyzer{},
+	aptAnalyzer:          AptAnalyzer{},
+	aptLayerAnalyzer:     AptLayerAnalyzer{},
+	aptDepsAnalyzer:      AptDepsAnalyzer{},
+	rpmAnalyzer:          RPMAnalyzer{},
+	rpmLayerAnalyzer:     RPMLayerAnalyzer{},
+	pipAnalyzer:          PipAnalyzer{},
+	nodeAnalyzer:         NodeAnalyzer{},
+	nodeAdvisoryAnalyzer: NodeAdvisoryAnalyzer{},
+	emergeAnalyzer:       EmergeAnalyzer{},
+	alternativesAnalyzer: AlternativesAnalyzer{},
+	layerSuggestAnalyzer: LayerSuggestAnalyzer{},
+	reproAnalyzer:        ReproAnalyzer{},
+	initAnalyzer:         InitAnalyzer{},
+	scoreAnalyzer:        ScoreAnalyzer{},
+	systemdAnalyzer:      SystemdAnalyzer{},
+	servicesAnalyzer:     ServicesAnalyzer{},
+	cronAnalyzer:         CronAnalyzer{},
+	usersAnalyzer:        UsersAnalyzer{},
+	certsAnalyzer:        CertsAnalyzer{},
+	bannersAnalyzer:      BannersAnalyzer{},
+	runtimesAnalyzer:     RuntimesAnalyzer{},
+	provenanceAnalyzer:   ProvenanceAnalyzer{},
+	rpmRepoAnalyzer:      RPMRepoAnalyzer{},
+	keyringAnalyzer:      KeyringAnalyzer{},
+	entropyAnalyzer:      EntropyAnalyzer{},
+	snapAnalyzer:         SnapAnalyzer{},
+	flatpakAnalyzer:      FlatpakAnalyzer{},
+	linkerAnalyzer:       LinkerAnalyzer{},
+	jarAnalyzer:          JarAnalyzer{},
+	conffilesAnalyzer:    ConffilesAnalyzer{},
+	cargoAnalyzer:        CargoAnalyzer{},
+	binVersionAnalyzer:   BinVersionAnalyzer{},
+	nugetAnalyzer:        NugetAnalyzer{},
+	yarnAnalyzer:         YarnAnalyzer{},
+	osAnalyzer:           OSAnalyzer{},
+	buildInfoAnalyzer:    BuildInfoAnalyzer{},
+	permissionsAnalyzer:  PermissionsAnalyzer{},
+	volumesAnalyzer:      VolumesAnalyzer{},
+	sharedLayersAnalyzer: SharedLayersAnalyzer{},
+	secretsAnalyzer:      SecretsAnalyzer{},
+	vulnAnalyzer:         VulnAnalyzer{},
+	binaryAnalyzer:       BinaryAnalyzer{},
 }
 
-var LayerAnalyzers = [...]string{layerAnalyzer, sizeLayerAnalyzer, aptLayerAnalyzer, rpmLayerAnalyzer}
+// StreamingAnalyzers can run against a streamed file inventory instead of an
+// extracted image filesystem.
+var StreamingAnalyzers = [...]string{historyAnalyzer, metadataAnalyzer, fileAnalyzer, sizeAnalyzer, initAnalyzer, buildInfoAnalyzer, permissionsAnalyzer, volumesAnalyzer, sharedLayersAnalyzer}
+
+var LayerAnalyzers = [...]string{layerAnalyzer, sizeLayerAnalyzer, aptLayerAnalyzer, rpmLayerAnalyzer, layerSuggestAnalyzer, scoreAnalyzer}
 
 func (req DiffRequest) GetDiff() (map[string]util.Result, error) {
 	img1 := req.Image1
 	img2 := req.Image2
-	diffs := req.DiffTypes
-
-	results := map[string]util.Result{}
-	for _, differ := range diffs {
-		if diff, err := differ.Diff(img1, img2); err == nil {
-			results[differ.Name()] = diff
-		} else {
-			logrus.Errorf("error getting diff with %s: %s", differ.Name(), err)
-		}
+	diffs, err := resolveDependencies(req.DiffTypes)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
+	inventories.acquire(img1)
+	defer inventories.release(img1)
+	inventories.acquire(img2)
+	defer inventories.release(img2)
+
+	results := runAnalyzers(diffs, req.Concurrency, req.Timings, "diff", func(differ Analyzer) (util.Result, error) {
+		return differ.Diff(img1, img2)
+	})
+
 	if len(results) == 0 {
 		err = fmt.Errorf("could not perform diff on %v and %v", img1, img2)
 	} else {
@@ -99,19 +187,18 @@ func (req DiffRequest) GetDiff() (map[string]util.Result, error) {
 
 func (req SingleRequest) GetAnalysis() (map[string]util.Result, error) {
 	img := req.Image
-	analyses := req.AnalyzeTypes
-
-	results := map[string]util.Result{}
-	for _, analyzer := range analyses {
-		analyzeName := analyzer.Name()
-		if analysis, err := analyzer.Analyze(img); err == nil {
-			results[analyzeName] = analysis
-		} else {
-			logrus.Errorf("error getting analysis with %s: %s", analyzeName, err)
-		}
+	analyses, err := resolveDependencies(req.AnalyzeTypes)
+	if err != nil {
+		return nil, err
 	}
 
-	var err error
+	inventories.acquire(img)
+	defer inventories.release(img)
+
+	results := runAnalyzers(analyses, req.Concurrency, req.Timings, "analysis", func(analyzer Analyzer) (util.Result, error) {
+		return analyzer.Analyze(img)
+	})
+
 	if len(results) == 0 {
 		err = fmt.Errorf("could not perform analysis on %v", img)
 	} else {
@@ -121,6 +208,42 @@ func (req SingleRequest) GetAnalysis() (map[string]util.Result, error) {
 	return results, err
 }
 
+// runAnalyzers runs each analyzer, up to concurrency at a time, and returns
+// the results of those which succeeded by analyzer name. Extracted image
+// filesystems are read-only and the inventory cache is shared safely, so
+// analyzers can run side by side; a dependent one waits on the inventory it
+// needs rather than on the analyzer computing it.
+func runAnalyzers(analyzers []Analyzer, concurrency int, timings *util.Timings, kind string, run func(Analyzer) (util.Result, error)) map[string]util.Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	results := map[string]util.Result{}
+	for _, analyzer := range analyzers {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(analyzer Analyzer) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			name := analyzer.Name()
+			start := time.Now()
+			result, err := run(analyzer)
+			timings.AddAnalyzer(name, start, time.Now())
+			if err != nil {
+				logrus.Errorf("error getting %s with %s: %s", kind, name, err)
+				return
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(analyzer)
+	}
+	wg.Wait()
+	return results
+}
+
 func GetAnalyzers(analyzeNames []string) ([]Analyzer, error) {
 	var analyzeFuncs []Analyzer
 	for _, name := range analyzeNames {
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package difftest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs"
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const dpkgStatus = `Package: curl
+Status: install ok installed
+Installed-Size: 500
+Version: 7.88.1-10
+
+Package: zlib1g
+Status: install ok installed
+Installed-Size: 160
+Version: 1:1.2.13.dfsg-1
+`
+
+func TestFS(t *testing.T) {
+	fs := difftest.NewFS(t).
+		File("/etc/hostname", "box\n").
+		Executable("usr/bin/tool", "#!/bin/sh\n").
+		Symlink("/bin", "usr/bin").
+		Dir("/tmp")
+	info, err := os.Stat(filepath.Join(fs.Root(), "bin", "tool"))
+	if err != nil {
+		t.Fatalf("Error reading file through symlink: %s", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Expected executable mode, got %s", info.Mode())
+	}
+	if image := fs.Image("synthetic"); image.Source != "synthetic" || image.FSPath != fs.Root() {
+		t.Errorf("Unexpected image: %+v", image)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	image := difftest.NewFS(t).File("var/lib/dpkg/status", dpkgStatus).Image("synthetic")
+	result, err := differs.AptAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Error analyzing image: %s", err)
+	}
+	difftest.AssertGolden(t, "testdata/apt_analysis.golden", result.OutputStruct(util.OutputOptions{}))
+	// the same document, indented differently
+	difftest.AssertGolden(t, "testdata/apt_analysis.golden", `{"AnalyzeType": "Apt", "Image": "synthetic",
+		"Analysis": [{"Name": "curl", "Version": "7.88.1-10", "Size": 512000},
+		{"Name": "zlib1g", "Version": "1:1.2.13.dfsg-1", "Size": 163840}]}`)
+}
+
+// recorder captures the errors reported by an assertion.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertJSONMismatch(t *testing.T) {
+	r := &recorder{TB: t}
+	difftest.AssertJSON(r, []byte(`{"Name": "curl", "Version": "7.88"}`), []byte(`{"Version": "7.88", "Name": "curl"}`))
+	if len(r.errors) != 0 {
+		t.Errorf("Expected documents differing only in key order to match, got: %v", r.errors)
+	}
+	difftest.AssertJSON(r, []byte(`{"Name": "curl", "Version": "7.88"}`), []byte(`{"Name": "curl", "Version": "8.0"}`))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], `-  "Version": "7.88"`) || !strings.Contains(r.errors[0], `+  "Version": "8.0"`) {
+		t.Errorf("Expected a diff of the versions, got: %v", r.errors)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package difftest helps test analyzers without pulling real images. FS
+// builds a synthetic image filesystem to run an analyzer against, and
+// AssertGolden compares its result with a JSON golden file.
+package difftest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+// FS is an image filesystem built in a temporary directory, which is removed
+// when the test finishes. Paths are relative to the image root, with or
+// without a leading slash, and parent directories are created as needed.
+type FS struct {
+	t    testing.TB
+	root string
+}
+
+// NewFS creates an empty image filesystem.
+func NewFS(t testing.TB) *FS {
+	t.Helper()
+	root, err := ioutil.TempDir("", "difftest")
+	if err != nil {
+		t.Fatalf("Error creating image filesystem: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	return &FS{t: t, root: root}
+}
+
+// Root returns the directory holding the image filesystem.
+func (fs *FS) Root() string {
+	return fs.root
+}
+
+// Image returns an image with this filesystem, as analyzers receive it.
+func (fs *FS) Image(source string) pkgutil.Image {
+	return pkgutil.Image{Source: source, FSPath: fs.root}
+}
+
+// File writes a regular file.
+func (fs *FS) File(name, contents string) *FS {
+	fs.t.Helper()
+	return fs.write(name, contents, 0644)
+}
+
+// Executable writes a regular file with the executable bits set.
+func (fs *FS) Executable(name, contents string) *FS {
+	fs.t.Helper()
+	return fs.write(name, contents, 0755)
+}
+
+// Dir creates a directory.
+func (fs *FS) Dir(name string) *FS {
+	fs.t.Helper()
+	if err := os.MkdirAll(fs.path(name), 0755); err != nil {
+		fs.t.Fatalf("Error creating directory %s: %s", name, err)
+	}
+	return fs
+}
+
+// Symlink creates a symlink to target, which is stored as given, so that
+// absolute targets are resolved inside the image by analyzers.
+func (fs *FS) Symlink(name, target string) *FS {
+	fs.t.Helper()
+	fs.Dir(filepath.Dir(name))
+	if err := os.Symlink(target, fs.path(name)); err != nil {
+		fs.t.Fatalf("Error creating symlink %s: %s", name, err)
+	}
+	return fs
+}
+
+func (fs *FS) write(name, contents string, mode os.FileMode) *FS {
+	fs.t.Helper()
+	fs.Dir(filepath.Dir(name))
+	if err := ioutil.WriteFile(fs.path(name), []byte(contents), mode); err != nil {
+		fs.t.Fatalf("Error writing %s: %s", name, err)
+	}
+	// the umask may have cleared bits of mode
+	if err := os.Chmod(fs.path(name), mode); err != nil {
+		fs.t.Fatalf("Error setting the mode of %s: %s", name, err)
+	}
+	return fs
+}
+
+func (fs *FS) path(name string) string {
+	return filepath.Join(fs.root, filepath.FromSlash(name))
+}
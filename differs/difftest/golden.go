@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package difftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files with the actual results instead of comparing them.")
+
+// AssertGolden compares actual with the JSON stored in the golden file. A
+// []byte or string is taken to already be JSON, such as the output of
+// `container-diff --json`; anything else, such as an analyzer result, is
+// marshaled first. Values are compared rather than text, so indentation and
+// key order don't matter. Run the tests with -update to write the golden
+// files from the actual results.
+func AssertGolden(t testing.TB, golden string, actual interface{}) {
+	t.Helper()
+	actualJSON, err := toJSON(actual)
+	if err != nil {
+		t.Fatalf("Error marshaling result: %s", err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatalf("Error creating golden file directory: %s", err)
+		}
+		if err := ioutil.WriteFile(golden, append(actualJSON, '\n'), 0644); err != nil {
+			t.Fatalf("Error writing golden file: %s", err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Error reading golden file, run with -update to create it: %s", err)
+	}
+	AssertJSON(t, expected, actualJSON)
+}
+
+// AssertJSON fails the test unless the two JSON documents hold the same
+// values, showing a diff of the two otherwise.
+func AssertJSON(t testing.TB, expected, actual []byte) {
+	t.Helper()
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal(expected, &expectedValue); err != nil {
+		t.Fatalf("Error parsing expected JSON: %s", err)
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		t.Fatalf("Error parsing actual JSON: %s\n%s", err, actual)
+	}
+	if reflect.DeepEqual(expectedValue, actualValue) {
+		return
+	}
+	// indent both the same way so that only the differences show
+	expectedText, _ := json.MarshalIndent(expectedValue, "", "  ")
+	actualText, _ := json.MarshalIndent(actualValue, "", "  ")
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(expectedText)),
+		B:        difflib.SplitLines(string(actualText)),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  3,
+	})
+	t.Errorf("Result does not match expected:\n%s", diff)
+}
+
+func toJSON(value interface{}) ([]byte, error) {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return json.MarshalIndent(value, "", "  ")
+	}
+	var indented bytes.Buffer
+	err := json.Indent(&indented, bytes.TrimSpace(raw), "", "  ")
+	return indented.Bytes(), err
+}
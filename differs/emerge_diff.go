@@ -31,7 +31,19 @@ import (
 //Emerge package database location
 const emergePkgFile string = "/var/db/pkg"
 
-type EmergeAnalyzer struct{}
+type EmergeAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (em EmergeAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(em.Options)
+}
+
+func (em EmergeAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	em.Options = opts
+	return em
+}
 
 func (em EmergeAnalyzer) Name() string {
 	return "EmergeAnalyzer"
@@ -0,0 +1,176 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// entropyDirDepth is the depth at which files are grouped into directories,
+// e.g. /usr/lib for /usr/lib/x86_64-linux-gnu/libc.so.6.
+const entropyDirDepth = 2
+
+type EntropyAnalyzer struct {
+}
+
+func (a EntropyAnalyzer) Name() string {
+	return "EntropyAnalyzer"
+}
+
+// Diff compares the compressibility and duplicate content of two images.
+func (a EntropyAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	analysis1, err := getEntropyAnalysis(image1.FSPath)
+	if err != nil {
+		return &util.EntropyDiffResult{}, err
+	}
+	analysis2, err := getEntropyAnalysis(image2.FSPath)
+	if err != nil {
+		return &util.EntropyDiffResult{}, err
+	}
+	return &util.EntropyDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Entropy",
+		Diff:     util.GetEntropyDiff(analysis1, analysis2),
+	}, nil
+}
+
+func (a EntropyAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := getEntropyAnalysis(image.FSPath)
+	if err != nil {
+		return &util.EntropyAnalyzeResult{}, err
+	}
+	return &util.EntropyAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Entropy",
+		Analysis:    analysis,
+	}, nil
+}
+
+// countingWriter counts the bytes written to it and discards them.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// getEntropyAnalysis gzips each regular file of an image filesystem to
+// measure how well it compresses, and hashes it to find contents stored
+// more than once. Compressing files one by one overestimates the size of a
+// layer, which compresses across files, but ranks directories reliably.
+func getEntropyAnalysis(root string) (util.EntropyAnalysis, error) {
+	analysis := util.EntropyAnalysis{Dirs: []util.DirEntropy{}, Duplicates: []util.DuplicateContent{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return analysis, err
+	}
+
+	dirs := map[string]*util.DirEntropy{}
+	contents := map[string]*util.DuplicateContent{}
+	counter := &countingWriter{}
+	compressor := gzip.NewWriter(counter)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		name := "/" + strings.TrimLeft(filepath.ToSlash(strings.TrimPrefix(path, root)), "/")
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		counter.n = 0
+		compressor.Reset(counter)
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(compressor, hash), file)
+		if err != nil {
+			return err
+		}
+		if err := compressor.Close(); err != nil {
+			return err
+		}
+		if size == 0 {
+			// empty files cost nothing but the gzip header
+			counter.n = 0
+		}
+
+		dirPath := entropyDir(name)
+		dir, ok := dirs[dirPath]
+		if !ok {
+			dir = &util.DirEntropy{Path: dirPath}
+			dirs[dirPath] = dir
+		}
+		dir.Files++
+		dir.Size += size
+		dir.CompressedSize += counter.n
+		analysis.Size += size
+		analysis.CompressedSize += counter.n
+
+		if size == 0 {
+			return nil
+		}
+		digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+		if content, ok := contents[digest]; ok {
+			content.Paths = append(content.Paths, name)
+			dir.DuplicateSize += size
+			analysis.DuplicateSize += size
+		} else {
+			contents[digest] = &util.DuplicateContent{Digest: digest, Size: size, Paths: []string{name}}
+		}
+		return nil
+	})
+	if err != nil {
+		return analysis, err
+	}
+	for _, dir := range dirs {
+		analysis.Dirs = append(analysis.Dirs, *dir)
+	}
+	for _, content := range contents {
+		if len(content.Paths) > 1 {
+			analysis.Duplicates = append(analysis.Duplicates, *content)
+		}
+	}
+	return util.SortEntropyAnalysis(analysis), nil
+}
+
+// entropyDir returns the directory a file is grouped under.
+func entropyDir(name string) string {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(filepath.Dir(name)), "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		return "/"
+	}
+	if len(parts) > entropyDirDepth {
+		parts = parts[:entropyDirDepth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
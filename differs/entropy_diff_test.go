@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestEntropyDir(t *testing.T) {
+	for name, expected := range map[string]string{
+		"/.keep":                  "/",
+		"/etc/hostname":           "/etc",
+		"/usr/share/doc/a/README": "/usr/share",
+	} {
+		if dir := entropyDir(name); dir != expected {
+			t.Errorf("Expected %s to be grouped under %s, got %s", name, expected, dir)
+		}
+	}
+}
+
+func TestGetEntropyAnalysis(t *testing.T) {
+	analysis, err := getEntropyAnalysis("testDirs/entropy/image2")
+	if err != nil {
+		t.Fatalf("Error analyzing entropy: %s", err)
+	}
+	dirs := map[string]util.DirEntropy{}
+	for _, d := range analysis.Dirs {
+		dirs[d.Path] = d
+	}
+	if len(dirs) != 4 {
+		t.Errorf("Expected 4 directories, got: %+v", analysis.Dirs)
+	}
+	// repeated text compresses well, random bytes don't
+	docs, blob := dirs["/usr/share"], dirs["/opt"]
+	if docs.Files != 2 || docs.Size != 22800 || docs.CompressedSize*10 > docs.Size {
+		t.Errorf("Expected the docs to compress to under 10%%, got: %+v", docs)
+	}
+	if blob.Size != 8192 || blob.CompressedSize < blob.Size {
+		t.Errorf("Expected the random blob not to compress, got: %+v", blob)
+	}
+	if docs.DuplicateSize != 11400 || analysis.DuplicateSize != 11400 {
+		t.Errorf("Expected one README copy to be a duplicate, got %d in dir and %d overall", docs.DuplicateSize, analysis.DuplicateSize)
+	}
+	expectedPaths := []string{"/usr/share/doc/a/README", "/usr/share/doc/b/README"}
+	if len(analysis.Duplicates) != 1 || !reflect.DeepEqual(analysis.Duplicates[0].Paths, expectedPaths) {
+		t.Errorf("Expected duplicate paths %v, got: %+v", expectedPaths, analysis.Duplicates)
+	}
+}
+
+func TestEntropyDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/entropy/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/entropy/image2"}
+	result, err := EntropyAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.EntropyDiffResult).Diff.(util.EntropyDiff)
+	paths := []string{}
+	for _, d := range diff.Dirs {
+		paths = append(paths, d.Path)
+	}
+	// the incompressible blob grows the compressed size the most
+	expected := []string{"/opt", "/usr/share"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected changed directories %v, got: %v", expected, paths)
+	}
+	if diff.DuplicateSize1 != 0 || diff.DuplicateSize2 != 11400 {
+		t.Errorf("Expected duplicate sizes 0 and 11400, got %d and %d", diff.DuplicateSize1, diff.DuplicateSize2)
+	}
+}
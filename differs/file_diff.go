@@ -17,12 +17,64 @@ limitations under the License.
 package differs
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultArchiveExtensions select the archives the file differ descends into
+// unless FileDiffOptions name others.
+var DefaultArchiveExtensions = []string{".jar", ".war", ".ear", ".aar", ".zip", ".whl", ".egg", ".tgz", ".tar.gz"}
+
+// FileDiffOptions configure the file differ of one diff.
+type FileDiffOptions struct {
+	// ExpandApplets disables grouping busybox-style applet symlinks.
+	ExpandApplets bool
+	// ArchiveDepth is the number of nested archive levels opened to diff the
+	// entries of modified archives. Zero disables archive inspection.
+	ArchiveDepth int
+	// ArchiveExtensions select the archives ArchiveDepth descends into.
+	ArchiveExtensions []string
+	// DiffContent adds a unified diff of each modified text file.
+	DiffContent bool
+	// ContentDiffMaxSize is the size in bytes above which DiffContent and
+	// InterestingFiles leave a modified file out.
+	ContentDiffMaxSize int64
+	// InterestingFiles select the files whose content diffs are added even
+	// without DiffContent, whether they were added, deleted or modified.
+	InterestingFiles []string
+	// HotspotDepth is the number of path components size changes are
+	// aggregated by, to rank the directories that grew or shrank the most.
+	// Zero disables the ranking.
+	HotspotDepth int
+	// HotspotTop is how many directories HotspotDepth ranks, all of them if 0.
+	HotspotTop int
+}
+
+// DefaultFileDiffOptions returns the options of a file differ given none.
+func DefaultFileDiffOptions() FileDiffOptions {
+	return FileDiffOptions{
+		ArchiveExtensions:  DefaultArchiveExtensions,
+		ContentDiffMaxSize: 64 * 1024,
+		InterestingFiles:   util.DefaultInterestingFiles,
+		HotspotTop:         10,
+	}
+}
+
 type FileAnalyzer struct {
+	// Options configure the diffs, DefaultFileDiffOptions if nil.
+	Options *FileDiffOptions
+}
+
+func (a FileAnalyzer) options() FileDiffOptions {
+	if a.Options == nil {
+		return DefaultFileDiffOptions()
+	}
+	return *a.Options
 }
 
 func (a FileAnalyzer) Name() string {
@@ -31,7 +83,44 @@ func (a FileAnalyzer) Name() string {
 
 // FileDiff diffs two packages and compares their contents
 func (a FileAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	opts := a.options()
+	if image1.Inventory != nil && image2.Inventory != nil {
+		diff, _ := util.DiffInventories(image1.Inventory, image2.Inventory)
+		if !opts.ExpandApplets {
+			diff = util.GroupAppletLinks(diff, linkTarget(image1), linkTarget(image2))
+		}
+		if opts.HotspotDepth > 0 {
+			diff.Hotspots = util.GetHotspots(diff, opts.HotspotDepth, opts.HotspotTop)
+		}
+		// streamed images materialize the InterestingFiles
+		var err error
+		if len(opts.InterestingFiles) > 0 {
+			err = addInterestingContents(&diff, image1.FSPath, image2.FSPath, opts)
+		}
+		return &util.DirDiffResult{
+			Image1:   image1.Source,
+			Image2:   image2.Source,
+			DiffType: "File",
+			Diff:     diff,
+		}, err
+	}
 	diff, err := diffImageFiles(image1.FSPath, image2.FSPath)
+	if err == nil && !opts.ExpandApplets {
+		diff = util.GroupAppletLinks(diff, linkTarget(image1), linkTarget(image2))
+	}
+	if err == nil && opts.ArchiveDepth > 0 {
+		archiveOpts := util.ArchiveOptions{Extensions: opts.ArchiveExtensions, Depth: opts.ArchiveDepth}
+		diff.Archives = util.DiffModifiedArchives(diff, image1.FSPath, image2.FSPath, archiveOpts)
+	}
+	if err == nil && opts.HotspotDepth > 0 {
+		diff.Hotspots = util.GetHotspots(diff, opts.HotspotDepth, opts.HotspotTop)
+	}
+	if err == nil && opts.DiffContent {
+		diff.Contents, err = util.DiffModifiedContents(diff, image1.FSPath, image2.FSPath, opts.ContentDiffMaxSize)
+	}
+	if err == nil && len(opts.InterestingFiles) > 0 {
+		err = addInterestingContents(&diff, image1.FSPath, image2.FSPath, opts)
+	}
 	return &util.DirDiffResult{
 		Image1:   image1.Source,
 		Image2:   image2.Source,
@@ -40,9 +129,40 @@ func (a FileAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
 	}, err
 }
 
+// addInterestingContents adds the content diffs of the InterestingFiles
+// which changed to a file diff, ordered by name with those already there.
+func addInterestingContents(diff *util.DirDiff, root1, root2 string, opts FileDiffOptions) error {
+	if root1 == "" || root2 == "" {
+		// joined to an empty root, the paths would be those of the host
+		return nil
+	}
+	interesting, err := util.DiffInterestingContents(*diff, root1, root2, opts.ContentDiffMaxSize, opts.InterestingFiles)
+	if err != nil {
+		return err
+	}
+	diffed := map[string]bool{}
+	for _, content := range diff.Contents {
+		diffed[content.Name] = true
+	}
+	for _, content := range interesting {
+		if !diffed[content.Name] {
+			diff.Contents = append(diff.Contents, content)
+		}
+	}
+	sort.Slice(diff.Contents, func(i, j int) bool { return diff.Contents[i].Name < diff.Contents[j].Name })
+	return nil
+}
+
 func (a FileAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
 	var result util.FileAnalyzeResult
 
+	if image.Inventory != nil {
+		result.Image = image.Source
+		result.AnalyzeType = "File"
+		result.Analysis = util.InventoryEntries(image.Inventory)
+		return &result, nil
+	}
+
 	imgDir, err := pkgutil.GetDirectory(image.FSPath, true)
 	if err != nil {
 		return result, err
@@ -54,6 +174,25 @@ func (a FileAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
 	return &result, err
 }
 
+// linkTarget resolves symlinks from the image's streamed inventory, or from
+// its extracted filesystem.
+func linkTarget(image pkgutil.Image) util.LinkTarget {
+	return func(name string) (string, bool) {
+		if image.Inventory != nil {
+			entry, ok := image.Inventory[name]
+			if !ok || entry.Linkname == "" {
+				return "", false
+			}
+			return util.ResolveLinkTarget(name, entry.Linkname), true
+		}
+		target, err := os.Readlink(filepath.Join(image.FSPath, name))
+		if err != nil {
+			return "", false
+		}
+		return util.ResolveLinkTarget(name, target), true
+	}
+}
+
 func diffImageFiles(img1, img2 string) (util.DirDiff, error) {
 	var diff util.DirDiff
 
@@ -88,6 +227,10 @@ func (a FileLayerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, erro
 		}
 		// ...else, diff as usual
 		layer2 := image2.Layers[index]
+		if sameLayer(layer, layer2) {
+			dirDiffs = append(dirDiffs, util.DirDiff{})
+			continue
+		}
 		diff, err := diffImageFiles(layer.FSPath, layer2.FSPath)
 		if err != nil {
 			return &util.MultipleDirDiffResult{}, err
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// System-wide flatpak installation location
+const flatpakInstallation = "var/lib/flatpak"
+
+// flatpakCommitLength is the number of commit characters shown, as in
+// `flatpak list`.
+const flatpakCommitLength = 12
+
+var flatpakReleaseVersion = regexp.MustCompile(`<release\s[^>]*\bversion="([^"]+)"`)
+
+type FlatpakAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a FlatpakAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a FlatpakAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a FlatpakAnalyzer) Name() string {
+	return "FlatpakAnalyzer"
+}
+
+// Diff compares the applications and runtimes installed by flatpak.
+func (a FlatpakAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := singleVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a FlatpakAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := singleVersionAnalysis(image, a)
+	return analysis, err
+}
+
+func (a FlatpakAnalyzer) getPackages(image pkgutil.Image) (map[string]util.PackageInfo, error) {
+	return getFlatpakPackages(image.FSPath)
+}
+
+// getFlatpakPackages reads the active deployment of each application and
+// runtime in the system installation, keyed by ref, e.g.
+// app/org.gnome.Calculator/x86_64/stable.
+func getFlatpakPackages(root string) (map[string]util.PackageInfo, error) {
+	packages := make(map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return packages, err
+	}
+	installation := filepath.Join(root, flatpakInstallation)
+	for _, kind := range []string{"app", "runtime"} {
+		// deployments are stored as <kind>/<id>/<arch>/<branch>/<commit>
+		branches, err := filepath.Glob(filepath.Join(installation, kind, "*", "*", "*"))
+		if err != nil {
+			return packages, err
+		}
+		for _, branch := range branches {
+			active, err := os.Readlink(filepath.Join(branch, "active"))
+			if err != nil {
+				// not deployed
+				continue
+			}
+			commit := filepath.Base(active)
+			deploy := filepath.Join(branch, commit)
+			ref, err := filepath.Rel(installation, branch)
+			if err != nil {
+				return packages, err
+			}
+			ref = filepath.ToSlash(ref)
+			id := filepath.Base(filepath.Dir(filepath.Dir(branch)))
+
+			shortCommit := commit
+			if len(shortCommit) > flatpakCommitLength {
+				shortCommit = shortCommit[:flatpakCommitLength]
+			}
+			version := "(" + shortCommit + ")"
+			if release := flatpakVersion(deploy, id); release != "" {
+				version = release + " " + version
+			}
+			packages[ref] = util.PackageInfo{Version: version, Size: pkgutil.GetSize(filepath.Join(deploy, "files"))}
+		}
+	}
+	return packages, nil
+}
+
+// flatpakVersion returns the latest release listed in the AppStream
+// metadata of a deployment, if it ships any. Releases are listed newest
+// first.
+func flatpakVersion(deploy, id string) string {
+	for _, name := range []string{
+		filepath.Join("files", "share", "metainfo", id+".metainfo.xml"),
+		filepath.Join("files", "share", "metainfo", id+".appdata.xml"),
+		filepath.Join("files", "share", "appdata", id+".appdata.xml"),
+	} {
+		contents, err := ioutil.ReadFile(filepath.Join(deploy, name))
+		if err != nil {
+			continue
+		}
+		if match := flatpakReleaseVersion.FindSubmatch(contents); match != nil {
+			return string(match[1])
+		}
+	}
+	return ""
+}
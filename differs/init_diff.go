@@ -0,0 +1,68 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// InitAnalyzer compares how containers of an image start, stop and report
+// their health: ENTRYPOINT, CMD, STOPSIGNAL and HEALTHCHECK.
+type InitAnalyzer struct {
+}
+
+func (a InitAnalyzer) Name() string {
+	return "InitAnalyzer"
+}
+
+func (a InitAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	c1, err := getInitConfig(image1)
+	if err != nil {
+		return &util.InitDiffResult{}, err
+	}
+	c2, err := getInitConfig(image2)
+	if err != nil {
+		return &util.InitDiffResult{}, err
+	}
+	return &util.InitDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Init",
+		Diff:     util.GetInitDiff(c1, c2),
+	}, nil
+}
+
+func (a InitAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	c, err := getInitConfig(image)
+	if err != nil {
+		return &util.InitAnalyzeResult{}, err
+	}
+	return &util.InitAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Init",
+		Analysis:    util.GetInitAnalysis(c),
+	}, nil
+}
+
+func getInitConfig(image pkgutil.Image) (util.InitConfig, error) {
+	configFile, err := image.Image.ConfigFile()
+	if err != nil {
+		return util.InitConfig{}, err
+	}
+	return util.NewInitConfig(configFile.Config), nil
+}
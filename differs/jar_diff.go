@@ -0,0 +1,241 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// jarExtensions are the Java archives scanned, and maxJarDepth how deep
+// archives nested in them, such as the libraries of a WAR or a Spring Boot
+// jar, are read.
+var jarExtensions = []string{".jar", ".war", ".ear"}
+
+const maxJarDepth = 3
+
+// jarFileName splits the file name of an archive without Maven metadata,
+// such as commons-io-2.11.0.jar, into a name and version.
+var jarFileName = regexp.MustCompile(`^(.+?)-(\d[\w.+-]*)$`)
+
+// JarAnalyzer compares the Java artifacts found in jar, war and ear files.
+type JarAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a JarAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a JarAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a JarAnalyzer) Name() string {
+	return "JarAnalyzer"
+}
+
+// Diff compares the Java artifacts in two images.
+func (a JarAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := multiVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a JarAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := multiVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// getPackages maps each artifact, as groupId:artifactId, to the archives
+// holding it. Archives nested in another one are named like
+// /app/app.war!/WEB-INF/lib/util.jar.
+func (a JarAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
+	root := image.FSPath
+	packages := make(map[string]map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// path provided invalid
+		return packages, err
+	}
+	err := pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if !info.Mode().IsRegular() || !isJar(file) {
+			return nil
+		}
+		mapPath := "/" + filepath.ToSlash(strings.TrimPrefix(file, root))
+		mapPath = path.Clean(mapPath)
+		reader, err := zip.OpenReader(file)
+		if err != nil {
+			logrus.Warnf("Could not read Java archive %s: %s", mapPath, err)
+			return nil
+		}
+		defer reader.Close()
+		readJar(&reader.Reader, mapPath, info.Size(), 1, packages)
+		return nil
+	})
+	return packages, err
+}
+
+// readJar records the artifacts of an archive, and of the archives nested in
+// it, in packages.
+func readJar(reader *zip.Reader, name string, size int64, depth int, packages map[string]map[string]util.PackageInfo) {
+	artifacts := map[string]string{}
+	var manifest map[string]string
+	for _, file := range reader.File {
+		switch {
+		case isPomProperties(file.Name):
+			properties, err := readZipEntry(file)
+			if err != nil {
+				logrus.Warnf("Could not read %s in %s: %s", file.Name, name, err)
+				continue
+			}
+			pom := parseProperties(properties)
+			if pom["groupId"] != "" && pom["artifactId"] != "" {
+				artifacts[pom["groupId"]+":"+pom["artifactId"]] = pom["version"]
+			}
+		case file.Name == "META-INF/MANIFEST.MF":
+			data, err := readZipEntry(file)
+			if err != nil {
+				logrus.Warnf("Could not read %s in %s: %s", file.Name, name, err)
+				continue
+			}
+			manifest = parseManifest(data)
+		case depth < maxJarDepth && isJar(file.Name):
+			data, err := readZipEntry(file)
+			if err != nil {
+				logrus.Warnf("Could not read %s in %s: %s", file.Name, name, err)
+				continue
+			}
+			nested, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				logrus.Warnf("Could not read Java archive %s%s%s: %s", name, util.ArchiveSeparator, file.Name, err)
+				continue
+			}
+			readJar(nested, name+util.ArchiveSeparator+file.Name, int64(file.UncompressedSize64), depth+1, packages)
+		}
+	}
+	// shaded jars carry the Maven metadata of every artifact they bundle
+	if len(artifacts) == 0 {
+		artifact, version := manifestArtifact(manifest, name)
+		artifacts[artifact] = version
+	}
+	for artifact, version := range artifacts {
+		if _, ok := packages[artifact]; !ok {
+			packages[artifact] = make(map[string]util.PackageInfo)
+		}
+		packages[artifact][name] = util.PackageInfo{Version: version, Size: size}
+	}
+}
+
+// manifestArtifact names an archive without Maven metadata after its
+// manifest, or after its file name. The version is taken from the file name
+// when the manifest has none.
+func manifestArtifact(manifest map[string]string, name string) (string, string) {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+	fileArtifact, fileVersion := base, ""
+	if match := jarFileName.FindStringSubmatch(base); match != nil {
+		fileArtifact, fileVersion = match[1], match[2]
+	}
+	version := firstValue(manifest["Implementation-Version"], manifest["Bundle-Version"], manifest["Specification-Version"], fileVersion)
+	if vendor, title := manifest["Implementation-Vendor-Id"], manifest["Implementation-Title"]; vendor != "" && title != "" {
+		return vendor + ":" + title, version
+	}
+	// OSGi names may be followed by directives, e.g. ;singleton:=true
+	symbolicName := strings.TrimSpace(strings.SplitN(manifest["Bundle-SymbolicName"], ";", 2)[0])
+	return firstValue(symbolicName, manifest["Implementation-Title"], manifest["Automatic-Module-Name"], fileArtifact), version
+}
+
+func isJar(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, jarExt := range jarExtensions {
+		if ext == jarExt {
+			return true
+		}
+	}
+	return false
+}
+
+// isPomProperties matches the Maven metadata written by the jar plugin, at
+// META-INF/maven/<groupId>/<artifactId>/pom.properties.
+func isPomProperties(name string) bool {
+	parts := strings.Split(name, "/")
+	return len(parts) == 5 && parts[0] == "META-INF" && parts[1] == "maven" && parts[4] == "pom.properties"
+}
+
+func readZipEntry(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// parseProperties reads the key=value (or key: value) lines of a Java
+// properties file, skipping comments.
+func parseProperties(data []byte) map[string]string {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		if i := strings.IndexAny(line, "=:"); i > 0 {
+			properties[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return properties
+}
+
+// parseManifest reads the main section of a jar manifest, joining the
+// continuation lines of long values.
+func parseManifest(data []byte) map[string]string {
+	manifest := map[string]string{}
+	reader := bufio.NewReader(bytes.NewReader(data))
+	var key string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" && (err != nil || key != "") {
+			// a blank line ends the main section
+			break
+		}
+		if strings.HasPrefix(line, " ") && key != "" {
+			manifest[key] += line[1:]
+		} else if i := strings.Index(line, ":"); i > 0 {
+			key = line[:i]
+			manifest[key] = strings.TrimPrefix(line[i+1:], " ")
+		}
+		if err != nil {
+			break
+		}
+	}
+	return manifest
+}
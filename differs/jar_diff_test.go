@@ -0,0 +1,151 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// makeJar builds a zip archive with the given entries, added in name order.
+func makeJar(t *testing.T, entries map[string]string) string {
+	t.Helper()
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Error creating %s: %s", name, err)
+		}
+		f.Write([]byte(entries[name]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Error writing jar: %s", err)
+	}
+	return buf.String()
+}
+
+func pomProperties(group, artifact, version string) string {
+	return "#Generated by Maven\ngroupId=" + group + "\nartifactId=" + artifact + "\nversion=" + version + "\n"
+}
+
+func TestGetJarPackages(t *testing.T) {
+	guava := makeJar(t, map[string]string{
+		"META-INF/MANIFEST.MF":                                 "Manifest-Version: 1.0\r\n",
+		"META-INF/maven/com.google.guava/guava/pom.properties": pomProperties("com.google.guava", "guava", "31.1-jre"),
+		"com/google/common/base/Strings.class":                 "class",
+	})
+	bundle := makeJar(t, map[string]string{
+		"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\r\nBundle-SymbolicName: org.example.bundle;singleton:=t\r\n rue\r\nBundle-Version: 2.0.1\r\n\r\nName: org/example/\r\nImplementation-Version: 9\r\n",
+	})
+	plain := makeJar(t, map[string]string{"org/Plain.class": "class"})
+	war := makeJar(t, map[string]string{
+		"META-INF/maven/org.example/webapp/pom.properties": pomProperties("org.example", "webapp", "1.0.0"),
+		"WEB-INF/lib/guava-31.1-jre.jar":                   guava,
+		"WEB-INF/lib/bundle.jar":                           bundle,
+	})
+
+	fs := difftest.NewFS(t).
+		File("/opt/app/lib/guava.jar", guava).
+		File("/opt/app/lib/commons-io-2.11.0.jar", plain).
+		File("/opt/app/README.txt", "not a jar").
+		File("/opt/app/broken.jar", "not a zip").
+		File("/usr/local/tomcat/webapps/ROOT.war", war)
+	packages, err := JarAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	guavaSize := int64(len(guava))
+	expected := map[string]map[string]util.PackageInfo{
+		"com.google.guava:guava": {
+			"/opt/app/lib/guava.jar": {Version: "31.1-jre", Size: guavaSize},
+			"/usr/local/tomcat/webapps/ROOT.war!/WEB-INF/lib/guava-31.1-jre.jar": {Version: "31.1-jre", Size: guavaSize},
+		},
+		"commons-io": {
+			"/opt/app/lib/commons-io-2.11.0.jar": {Version: "2.11.0", Size: int64(len(plain))},
+		},
+		"org.example.bundle": {
+			"/usr/local/tomcat/webapps/ROOT.war!/WEB-INF/lib/bundle.jar": {Version: "2.0.1", Size: int64(len(bundle))},
+		},
+		"org.example:webapp": {
+			"/usr/local/tomcat/webapps/ROOT.war": {Version: "1.0.0", Size: int64(len(war))},
+		},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, packages)
+	}
+
+	if _, err := (JarAnalyzer{}).getPackages(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
+
+func TestManifestArtifact(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		manifest map[string]string
+		name     string
+		artifact string
+		version  string
+	}{
+		{
+			descrip:  "implementation vendor and title",
+			manifest: map[string]string{"Implementation-Vendor-Id": "org.example", "Implementation-Title": "tool", "Implementation-Version": "3.2"},
+			name:     "/app/tool.jar",
+			artifact: "org.example:tool",
+			version:  "3.2",
+		},
+		{
+			descrip:  "module name and versioned file name",
+			manifest: map[string]string{"Automatic-Module-Name": "org.example.lib"},
+			name:     "/app/lib-1.4.0.jar",
+			artifact: "org.example.lib",
+			version:  "1.4.0",
+		},
+		{
+			descrip:  "file name only",
+			manifest: nil,
+			name:     "/app/app.war!/WEB-INF/lib/jackson-core-2.13.4.Final.jar",
+			artifact: "jackson-core",
+			version:  "2.13.4.Final",
+		},
+		{
+			descrip:  "unversioned file name",
+			manifest: map[string]string{},
+			name:     "/app/launcher.jar",
+			artifact: "launcher",
+		},
+	}
+	for _, test := range testCases {
+		artifact, version := manifestArtifact(test.manifest, test.name)
+		if artifact != test.artifact || version != test.version {
+			t.Errorf("%s: expected %s %s but got %s %s", test.descrip, test.artifact, test.version, artifact, version)
+		}
+	}
+}
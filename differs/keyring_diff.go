@@ -0,0 +1,424 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// keyringPaths are the image paths of the OpenPGP keyrings of apt and rpm:
+// the keyrings apt trusts for every repository, those repositories name
+// with signed-by, and the keys rpm repositories reference with gpgkey.
+var keyringPaths = []string{
+	"/etc/apt/trusted.gpg",
+	"/etc/apt/trusted.gpg.d/*",
+	"/etc/apt/keyrings/*",
+	"/usr/share/keyrings/*",
+	"/etc/pki/rpm-gpg/*",
+}
+
+// OpenPGP packet tags of the packets making up public keys.
+const (
+	pgpPublicKey    = 6
+	pgpUserID       = 13
+	pgpPublicSubkey = 14
+)
+
+var pgpAlgorithms = map[byte]string{
+	1:  "rsa",
+	2:  "rsa",
+	3:  "rsa",
+	16: "elg",
+	17: "dsa",
+	18: "ecdh",
+	19: "ecdsa",
+	22: "eddsa",
+	25: "cv25519",
+	26: "cv448",
+	27: "ed25519",
+	28: "ed448",
+}
+
+// pgpCurves names the curves of ECC keys by the hex of their OID.
+var pgpCurves = map[string]string{
+	"2b06010401da470f01":   "ed25519",
+	"2b060104019755010501": "cv25519",
+	"2a8648ce3d030107":     "nistp256",
+	"2b81040022":           "nistp384",
+	"2b81040023":           "nistp521",
+	"2b2403030208010107":   "brainpoolP256r1",
+	"2b240303020801010b":   "brainpoolP384r1",
+	"2b240303020801010d":   "brainpoolP512r1",
+}
+
+type KeyringAnalyzer struct {
+}
+
+func (a KeyringAnalyzer) Name() string {
+	return "KeyringAnalyzer"
+}
+
+// Diff compares the signing keys apt and rpm trust in two images.
+func (a KeyringAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	keys1, err := getSigningKeys(image1.FSPath)
+	if err != nil {
+		return &util.KeyringDiffResult{}, err
+	}
+	keys2, err := getSigningKeys(image2.FSPath)
+	if err != nil {
+		return &util.KeyringDiffResult{}, err
+	}
+	return &util.KeyringDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Keyrings",
+		Diff:     util.GetKeyringDiff(keys1, keys2),
+	}, nil
+}
+
+func (a KeyringAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	keys, err := getSigningKeys(image.FSPath)
+	if err != nil {
+		return &util.KeyringAnalyzeResult{}, err
+	}
+	return &util.KeyringAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Keyrings",
+		Analysis:    keys,
+	}, nil
+}
+
+// getSigningKeys reads the keys of the keyrings of an image, binary or
+// ASCII armored. A key found in several keyrings is listed once, with all
+// of them. Keyrings linking elsewhere in the image are read there.
+func getSigningKeys(root string) ([]util.SigningKey, error) {
+	keys := []util.SigningKey{}
+	if _, err := os.Stat(root); err != nil {
+		return keys, err
+	}
+	files := []string{}
+	for _, pattern := range keyringPaths {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			files = append(files, path.Clean("/"+filepath.ToSlash(strings.TrimPrefix(match, root))))
+		}
+	}
+	sort.Strings(files)
+
+	byFingerprint := map[string]*util.SigningKey{}
+	order := []string{}
+	for _, file := range files {
+		resolved, ok := resolveInImage(root, file)
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, resolved))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(root, resolved))
+		if err != nil {
+			logrus.Warningf("unable to read keyring %s: %s", file, err)
+			continue
+		}
+		fileKeys, err := readKeyring(data)
+		if err != nil {
+			logrus.Warningf("unable to read keyring %s: %s", file, err)
+		}
+		for _, key := range fileKeys {
+			existing, ok := byFingerprint[key.Fingerprint]
+			if !ok {
+				key := key
+				key.Keyrings = []string{file}
+				byFingerprint[key.Fingerprint] = &key
+				order = append(order, key.Fingerprint)
+				continue
+			}
+			existing.Keyrings = append(existing.Keyrings, file)
+			existing.UserIDs = mergeStrings(existing.UserIDs, key.UserIDs)
+			existing.Subkeys = mergeStrings(existing.Subkeys, key.Subkeys)
+		}
+	}
+	for _, fingerprint := range order {
+		keys = append(keys, *byFingerprint[fingerprint])
+	}
+	util.SortSigningKeys(keys)
+	return keys, nil
+}
+
+func mergeStrings(list, more []string) []string {
+	for _, s := range more {
+		found := false
+		for _, existing := range list {
+			if existing == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// readKeyring reads the public keys of an OpenPGP keyring, made of binary
+// packets or of ASCII armored blocks of them. The keys read before an
+// error are returned along with it.
+func readKeyring(data []byte) ([]util.SigningKey, error) {
+	if bytes.Contains(data, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+		keys := []util.SigningKey{}
+		blocks, err := dearmor(data)
+		for _, block := range blocks {
+			blockKeys, blockErr := readPackets(block)
+			keys = append(keys, blockKeys...)
+			if blockErr != nil && err == nil {
+				err = blockErr
+			}
+		}
+		return keys, err
+	}
+	if len(data) >= 12 && string(data[8:12]) == "KBXf" {
+		// apt can't read the keybox format of gpg either
+		return nil, errors.New("keybox files are not supported by apt")
+	}
+	return readPackets(data)
+}
+
+// dearmor decodes the ASCII armored public key blocks of data.
+func dearmor(data []byte) ([][]byte, error) {
+	blocks := [][]byte{}
+	var body *strings.Builder
+	inHeaders := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "-----BEGIN PGP PUBLIC KEY BLOCK-----":
+			body = &strings.Builder{}
+			inHeaders = true
+		case body == nil:
+		case strings.HasPrefix(line, "-----END PGP"):
+			decoded, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return blocks, fmt.Errorf("decoding armored key: %s", err)
+			}
+			blocks = append(blocks, decoded)
+			body = nil
+		case inHeaders && strings.Contains(line, ": "):
+			// armor headers, such as Comment: or Version:
+		case line == "":
+			inHeaders = false
+		case strings.HasPrefix(line, "=") && len(line) == 5:
+			// the CRC-24 checksum
+		default:
+			inHeaders = false
+			body.WriteString(line)
+		}
+	}
+	return blocks, nil
+}
+
+// readPackets reads the public keys of a sequence of OpenPGP packets, each
+// made of its primary key packet followed by user ID and subkey packets,
+// along with signatures, which are skipped.
+func readPackets(data []byte) ([]util.SigningKey, error) {
+	keys := []util.SigningKey{}
+	var key *util.SigningKey
+	for len(data) > 0 {
+		tag, body, rest, err := readPacket(data)
+		if err != nil {
+			if key != nil {
+				keys = append(keys, *key)
+			}
+			return keys, err
+		}
+		data = rest
+		switch tag {
+		case pgpPublicKey:
+			if key != nil {
+				keys = append(keys, *key)
+			}
+			key = nil
+			if k, ok := parsePublicKey(body); ok {
+				key = &k
+			}
+		case pgpUserID:
+			if key != nil {
+				key.UserIDs = append(key.UserIDs, string(body))
+			}
+		case pgpPublicSubkey:
+			if key == nil {
+				continue
+			}
+			if subkey, ok := parsePublicKey(body); ok {
+				key.Subkeys = append(key.Subkeys, subkey.Fingerprint)
+			}
+		}
+	}
+	if key != nil {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// readPacket splits the first packet off data, in either the old or the
+// new packet format.
+func readPacket(data []byte) (byte, []byte, []byte, error) {
+	if data[0]&0x80 == 0 {
+		return 0, nil, nil, errors.New("not an OpenPGP packet")
+	}
+	var tag byte
+	var length, offset int
+	if data[0]&0x40 != 0 {
+		tag = data[0] & 0x3f
+		if len(data) < 2 {
+			return 0, nil, nil, errors.New("truncated packet")
+		}
+		switch l := int(data[1]); {
+		case l < 192:
+			length, offset = l, 2
+		case l < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, errors.New("truncated packet")
+			}
+			length, offset = (l-192)<<8+int(data[2])+192, 3
+		case l == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, errors.New("truncated packet")
+			}
+			length, offset = int(binary.BigEndian.Uint32(data[2:6])), 6
+		default:
+			// partial lengths are only used by data packets
+			return 0, nil, nil, errors.New("unexpected partial length packet")
+		}
+	} else {
+		tag = (data[0] >> 2) & 0x0f
+		switch data[0] & 0x03 {
+		case 0:
+			if len(data) < 2 {
+				return 0, nil, nil, errors.New("truncated packet")
+			}
+			length, offset = int(data[1]), 2
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, errors.New("truncated packet")
+			}
+			length, offset = int(binary.BigEndian.Uint16(data[1:3])), 3
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, errors.New("truncated packet")
+			}
+			length, offset = int(binary.BigEndian.Uint32(data[1:5])), 5
+		default:
+			length, offset = len(data)-1, 1
+		}
+	}
+	if length < 0 || offset+length > len(data) {
+		return 0, nil, nil, errors.New("truncated packet")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+// parsePublicKey reads the fingerprint, key ID, algorithm and creation
+// date of a public key or subkey packet body. Version 3 keys, long
+// deprecated, are skipped.
+func parsePublicKey(body []byte) (util.SigningKey, bool) {
+	if len(body) < 6 {
+		return util.SigningKey{}, false
+	}
+	var fingerprint []byte
+	var keyID []byte
+	switch version := body[0]; version {
+	case 4:
+		h := sha1.New()
+		h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+		h.Write(body)
+		fingerprint = h.Sum(nil)
+		keyID = fingerprint[len(fingerprint)-8:]
+	case 5, 6:
+		h := sha256.New()
+		prefix := byte(0x9a)
+		if version == 6 {
+			prefix = 0x9b
+		}
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(body)))
+		h.Write(append([]byte{prefix}, length...))
+		h.Write(body)
+		fingerprint = h.Sum(nil)
+		keyID = fingerprint[:8]
+	default:
+		return util.SigningKey{}, false
+	}
+	created := time.Unix(int64(binary.BigEndian.Uint32(body[1:5])), 0).UTC()
+	material := body[6:]
+	if body[0] != 4 && len(material) >= 4 {
+		// later versions prefix the key material with its length
+		material = material[4:]
+	}
+	return util.SigningKey{
+		Fingerprint: strings.ToUpper(hex.EncodeToString(fingerprint)),
+		KeyID:       strings.ToUpper(hex.EncodeToString(keyID)),
+		Algorithm:   pgpAlgorithm(body[5], material),
+		Created:     created.Format("2006-01-02"),
+		UserIDs:     []string{},
+	}, true
+}
+
+// pgpAlgorithm names the algorithm of a key as gpg does: with the bits of
+// its first number for RSA, DSA and Elgamal keys, and by curve for ECC
+// keys, which start with the OID of their curve.
+func pgpAlgorithm(algorithm byte, material []byte) string {
+	name, ok := pgpAlgorithms[algorithm]
+	if !ok {
+		return fmt.Sprintf("unknown%d", algorithm)
+	}
+	switch name {
+	case "rsa", "dsa", "elg":
+		if len(material) >= 2 {
+			return fmt.Sprintf("%s%d", name, binary.BigEndian.Uint16(material[:2]))
+		}
+	case "ecdh", "ecdsa", "eddsa":
+		if len(material) >= 1 && len(material) > int(material[0]) {
+			if curve, ok := pgpCurves[hex.EncodeToString(material[1:1+int(material[0])])]; ok {
+				return curve
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const (
+	exampleKey = "315ACDC11B81ADDDC8B0B5A29472495C3EE3D900"
+	vendorKey  = "88350224527C5A3EBB972964F8AA8BE0B81777C8"
+	thirdKey   = "AEF00B5175B1E49628D5C76D1F558C98AC68F2E4"
+)
+
+func TestGetSigningKeys(t *testing.T) {
+	keys, err := getSigningKeys("testDirs/keyring/image1")
+	if err != nil {
+		t.Fatalf("Error reading keyrings: %s", err)
+	}
+	expected := []util.SigningKey{
+		{
+			Fingerprint: exampleKey,
+			KeyID:       "9472495C3EE3D900",
+			Algorithm:   "ed25519",
+			Created:     "2026-10-14",
+			UserIDs:     []string{"Example Apt Repository <apt@example.com>"},
+			Subkeys:     []string{"1A4A0FFC9F24F75FA6BBF07A0E87D9E0C07DE77C"},
+			Keyrings:    []string{"/usr/share/keyrings/example-archive-keyring.gpg"},
+		},
+		{
+			Fingerprint: vendorKey,
+			KeyID:       "F8AA8BE0B81777C8",
+			Algorithm:   "rsa2048",
+			Created:     "2026-10-14",
+			UserIDs:     []string{"Vendor Packages <rpm@example.com>"},
+			Keyrings:    []string{"/etc/pki/rpm-gpg/RPM-GPG-KEY-vendor"},
+		},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, keys)
+	}
+
+	if _, err := getSigningKeys("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestKeyringDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/keyring/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/keyring/image2"}
+	result, err := KeyringAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.KeyringDiffResult).Diff.(util.KeyringDiff)
+
+	expectedWarnings := []string{
+		"key " + exampleKey + " (Example Apt Repository <apt@example.com>) trusted for every apt repository by /etc/apt/trusted.gpg.d/example.gpg",
+		"key " + thirdKey + " (Third Party Repository <third@example.com>) trusted for every apt repository by /etc/apt/trusted.gpg.d/thirdparty.asc",
+	}
+	if !reflect.DeepEqual(diff.Warnings, expectedWarnings) {
+		t.Errorf("Expected warnings: %v but got: %v", expectedWarnings, diff.Warnings)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Fingerprint != thirdKey {
+		t.Errorf("Expected the third-party key to be added, got: %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Fingerprint != vendorKey {
+		t.Errorf("Expected the vendor key to be removed, got: %+v", diff.Removed)
+	}
+	expectedKeyrings := []string{"/etc/apt/trusted.gpg.d/example.gpg", "/usr/share/keyrings/example-archive-keyring.gpg"}
+	if len(diff.Changed) != 1 || !reflect.DeepEqual(diff.Changed[0].Key2.Keyrings, expectedKeyrings) {
+		t.Errorf("Expected the example key to be linked into trusted.gpg.d, got: %+v", diff.Changed)
+	}
+}
+
+func TestReadKeyringErrors(t *testing.T) {
+	if _, err := readKeyring([]byte("not a keyring")); err == nil {
+		t.Errorf("Expected an error for data which isn't an OpenPGP keyring")
+	}
+	keybox := append([]byte{0, 0, 0, 32, 1, 1, 0, 0}, []byte("KBXf")...)
+	if _, err := readKeyring(keybox); err == nil {
+		t.Errorf("Expected an error for a keybox file")
+	}
+	// a key packet whose length runs past the end of the data
+	if keys, err := readKeyring([]byte{0x99, 0x01, 0x0d, 0x04}); err == nil || len(keys) != 0 {
+		t.Errorf("Expected an error and no keys for a truncated packet, got %v, %v", keys, err)
+	}
+}
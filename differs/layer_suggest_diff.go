@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"errors"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// LayerSuggestAnalyzer finds layers of the second image which were rebuilt
+// with unchanged contents because an earlier layer changed, and suggests
+// reordering the Dockerfile so they can be reused from the build cache.
+type LayerSuggestAnalyzer struct {
+}
+
+func (a LayerSuggestAnalyzer) Name() string {
+	return "LayerSuggestAnalyzer"
+}
+
+func (a LayerSuggestAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := getLayerSuggestions(image1, image2)
+	if err != nil {
+		return &util.LayerSuggestDiffResult{}, err
+	}
+	return &util.LayerSuggestDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "LayerSuggest",
+		Diff:     diff,
+	}, nil
+}
+
+func (a LayerSuggestAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	return nil, errors.New("layer suggestions compare two builds, use container-diff diff")
+}
+
+func getLayerSuggestions(image1, image2 pkgutil.Image) (util.LayerSuggestDiff, error) {
+	diff := util.LayerSuggestDiff{
+		FirstChangedLayer: -1,
+		Suggestions:       []util.LayerSuggestion{},
+	}
+	layers1, layers2 := image1.Layers, image2.Layers
+
+	changed := 0
+	for changed < len(layers1) && changed < len(layers2) && layers1[changed].Digest == layers2[changed].Digest {
+		changed++
+	}
+	if changed == len(layers1) || changed == len(layers2) {
+		// one image extends the other, so no layer was invalidated
+		return diff, nil
+	}
+
+	commands1 := layerCommands(image1)
+	commands2 := layerCommands(image2)
+	diff.FirstChangedLayer = changed
+	diff.ChangedCommand = commands2[changed]
+	diff.RebuiltLayers = len(layers2) - changed
+	for _, layer := range layers2[changed:] {
+		diff.RebuiltBytes += pkgutil.GetSize(layer.FSPath)
+	}
+
+	used := map[int]bool{changed: true}
+	for j := changed + 1; j < len(layers2); j++ {
+		i := matchRebuiltLayer(commands1, commands2, j, changed, len(layers1), used)
+		if i < 0 {
+			continue
+		}
+		used[i] = true
+		same, err := sameLayerContents(layers1[i], layers2[j])
+		if err != nil {
+			logrus.Warningf("Error comparing layer %d of %s with layer %d of %s: %s", i, image1.Source, j, image2.Source, err)
+			continue
+		}
+		if !same {
+			continue
+		}
+		saved := pkgutil.GetSize(layers2[j].FSPath)
+		diff.Suggestions = append(diff.Suggestions, util.LayerSuggestion{
+			Layer:             j,
+			Command:           commands2[j],
+			MoveBefore:        changed,
+			MoveBeforeCommand: diff.ChangedCommand,
+			SavedBytes:        saved,
+		})
+		diff.SavedBytes += saved
+	}
+	return diff, nil
+}
+
+// matchRebuiltLayer finds the layer of the first image built by the same
+// instruction as layer j of the second. Without history the layer at the same
+// index is used. Returns -1 when there is no counterpart.
+func matchRebuiltLayer(commands1, commands2 []string, j, start, count int, used map[int]bool) int {
+	if commands2[j] != "" {
+		for i := start; i < count; i++ {
+			if !used[i] && commands1[i] == commands2[j] {
+				return i
+			}
+		}
+		return -1
+	}
+	if j < count && !used[j] {
+		return j
+	}
+	return -1
+}
+
+func sameLayerContents(layer1, layer2 pkgutil.Layer) (bool, error) {
+	dir1, err := pkgutil.GetDirectory(layer1.FSPath, true)
+	if err != nil {
+		return false, err
+	}
+	dir2, err := pkgutil.GetDirectory(layer2.FSPath, true)
+	if err != nil {
+		return false, err
+	}
+	_, same := util.DiffDirectory(dir1, dir2)
+	return same, nil
+}
+
+// layerCommands returns the instruction that created each layer of image,
+// taken from the history entries which produced a layer. Entries are empty
+// when the history is missing or doesn't line up with the layers.
+func layerCommands(image pkgutil.Image) []string {
+	commands := make([]string, len(image.Layers))
+	if image.Image == nil {
+		return commands
+	}
+	c, err := image.Image.ConfigFile()
+	if err != nil {
+		return commands
+	}
+	history := []string{}
+	for _, h := range c.History {
+		if !h.EmptyLayer {
+			history = append(history, cleanLayerCommand(h.CreatedBy))
+		}
+	}
+	if len(history) != len(commands) {
+		return commands
+	}
+	return history
+}
+
+func cleanLayerCommand(createdBy string) string {
+	command := strings.TrimSpace(createdBy)
+	command = strings.TrimPrefix(command, "/bin/sh -c ")
+	command = strings.TrimPrefix(command, "#(nop) ")
+	return strings.TrimSpace(command)
+}
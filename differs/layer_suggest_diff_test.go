@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func testLayer(t *testing.T, dir, digest string) pkgutil.Layer {
+	h, err := v1.NewHash("sha256:" + digest)
+	if err != nil {
+		t.Fatalf("Error creating hash: %s", err)
+	}
+	return pkgutil.Layer{FSPath: "testDirs/layerSuggest/" + dir, Digest: h}
+}
+
+func TestGetLayerSuggestions(t *testing.T) {
+	const (
+		base = "0000000000000000000000000000000000000000000000000000000000000000"
+		d1   = "1111111111111111111111111111111111111111111111111111111111111111"
+		d2   = "2222222222222222222222222222222222222222222222222222222222222222"
+		d3   = "3333333333333333333333333333333333333333333333333333333333333333"
+		d4   = "4444444444444444444444444444444444444444444444444444444444444444"
+		d5   = "5555555555555555555555555555555555555555555555555555555555555555"
+		d6   = "6666666666666666666666666666666666666666666666666666666666666666"
+	)
+	image1 := pkgutil.Image{Layers: []pkgutil.Layer{
+		testLayer(t, "base", base), testLayer(t, "src1", d1), testLayer(t, "deps1", d2), testLayer(t, "config1", d3),
+	}}
+
+	testCases := []struct {
+		descrip  string
+		image2   pkgutil.Image
+		expected util.LayerSuggestDiff
+	}{
+		{
+			descrip: "identical layers",
+			image2:  image1,
+			expected: util.LayerSuggestDiff{
+				FirstChangedLayer: -1,
+				Suggestions:       []util.LayerSuggestion{},
+			},
+		},
+		{
+			descrip: "unchanged dependency layer rebuilt after source change",
+			image2: pkgutil.Image{Layers: []pkgutil.Layer{
+				testLayer(t, "base", base), testLayer(t, "src2", d4), testLayer(t, "deps2", d5), testLayer(t, "config2", d6),
+			}},
+			expected: util.LayerSuggestDiff{
+				FirstChangedLayer: 1,
+				RebuiltLayers:     3,
+				RebuiltBytes: pkgutil.GetSize("testDirs/layerSuggest/src2") +
+					pkgutil.GetSize("testDirs/layerSuggest/deps2") +
+					pkgutil.GetSize("testDirs/layerSuggest/config2"),
+				Suggestions: []util.LayerSuggestion{
+					{Layer: 2, MoveBefore: 1, SavedBytes: pkgutil.GetSize("testDirs/layerSuggest/deps2")},
+				},
+				SavedBytes: pkgutil.GetSize("testDirs/layerSuggest/deps2"),
+			},
+		},
+	}
+	for _, test := range testCases {
+		diff, err := getLayerSuggestions(image1, test.image2)
+		if err != nil {
+			t.Errorf("%s: got unexpected error: %s", test.descrip, err)
+			continue
+		}
+		if !reflect.DeepEqual(diff, test.expected) {
+			t.Errorf("%s: Expected: %v but got: %v", test.descrip, test.expected, diff)
+		}
+	}
+}
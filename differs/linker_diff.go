@@ -0,0 +1,268 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// Dynamic linker configuration locations
+const (
+	ldSoConfFile  = "/etc/ld.so.conf"
+	ldSoCacheFile = "/etc/ld.so.cache"
+)
+
+// ld.so.cache formats. Caches written before glibc 2.32 start with the old
+// format, followed by the new one.
+const (
+	ldCacheOldMagic      = "ld.so-1.7.0"
+	ldCacheOldHeaderSize = 16
+	ldCacheOldEntrySize  = 12
+	ldCacheMagic         = "glibc-ld.so.cache1.1"
+	ldCacheHeaderSize    = 48
+	ldCacheEntrySize     = 24
+)
+
+// LinkerAnalyzer compares how an image resolves shared libraries and
+// commands when a container starts.
+type LinkerAnalyzer struct {
+}
+
+func (a LinkerAnalyzer) Name() string {
+	return "LinkerAnalyzer"
+}
+
+// Diff compares the dynamic linker configuration and PATH resolution of two
+// images.
+func (a LinkerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	analysis1, err := getLinkerAnalysis(image1)
+	if err != nil {
+		return &util.LinkerDiffResult{}, err
+	}
+	analysis2, err := getLinkerAnalysis(image2)
+	if err != nil {
+		return &util.LinkerDiffResult{}, err
+	}
+	return &util.LinkerDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Linker",
+		Diff:     util.GetLinkerDiff(analysis1, analysis2),
+	}, nil
+}
+
+func (a LinkerAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := getLinkerAnalysis(image)
+	if err != nil {
+		return &util.LinkerAnalyzeResult{}, err
+	}
+	return &util.LinkerAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Linker",
+		Analysis:    analysis,
+	}, nil
+}
+
+func getLinkerAnalysis(image pkgutil.Image) (util.LinkerAnalysis, error) {
+	root := image.FSPath
+	analysis := util.LinkerAnalysis{SearchDirs: []string{}, Libraries: []util.Resolution{}, Commands: []util.Resolution{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return analysis, err
+	}
+	config, err := configFile(image)
+	if err != nil {
+		return analysis, err
+	}
+	searchPath := defaultPath
+	for _, env := range config.Config.Env {
+		if strings.HasPrefix(env, "PATH=") {
+			searchPath = strings.TrimPrefix(env, "PATH=")
+		}
+	}
+
+	readLdSoConf(root, ldSoConfFile, &analysis.SearchDirs, map[string]bool{})
+	if data, err := ioutil.ReadFile(filepath.Join(root, ldSoCacheFile)); err == nil {
+		libraries, err := parseLdSoCache(data)
+		if err != nil {
+			logrus.Warnf("Could not read %s in %s: %s", ldSoCacheFile, image.Source, err)
+		}
+		for _, library := range libraries {
+			library.Target = resolveCommand(root, path.Dir(library.Path), path.Base(library.Path))
+			analysis.Libraries = append(analysis.Libraries, library)
+		}
+	}
+	analysis.Commands = getPathCommands(root, searchPath)
+	util.SortResolutions(analysis.Libraries)
+	util.SortResolutions(analysis.Commands)
+	return analysis, nil
+}
+
+// readLdSoConf appends the library directories configured in an
+// ld.so.conf file to dirs, following its include directives.
+func readLdSoConf(root, conf string, dirs *[]string, seen map[string]bool) {
+	if seen[conf] {
+		return
+	}
+	seen[conf] = true
+	file, err := os.Open(filepath.Join(root, conf))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "hwcap" {
+			continue
+		}
+		if fields[0] == "include" {
+			for _, pattern := range fields[1:] {
+				if !path.IsAbs(pattern) {
+					pattern = path.Join(path.Dir(conf), pattern)
+				}
+				for _, include := range glob(root, pattern) {
+					readLdSoConf(root, include, dirs, seen)
+				}
+			}
+			continue
+		}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return strings.ContainsRune(" \t:,", r) }) {
+			// an obsolete =type suffix may follow the directory
+			dir := path.Clean(strings.SplitN(field, "=", 2)[0])
+			if !containsString(*dirs, dir) {
+				*dirs = append(*dirs, dir)
+			}
+		}
+	}
+}
+
+// parseLdSoCache reads the library names and paths stored in an
+// ld.so.cache. When a library is cached more than once, for several
+// architectures or hardware capabilities, the first entry is kept.
+func parseLdSoCache(data []byte) ([]util.Resolution, error) {
+	offset := 0
+	if bytes.HasPrefix(data, []byte(ldCacheOldMagic)) && len(data) >= ldCacheOldHeaderSize {
+		offset = ldCacheOldHeaderSize + ldCacheOldEntrySize*int(binary.LittleEndian.Uint32(data[12:16]))
+	}
+	if offset < 0 || offset+ldCacheHeaderSize > len(data) || !bytes.HasPrefix(data[offset:], []byte(ldCacheMagic)) {
+		return nil, errors.New("unsupported ld.so.cache format")
+	}
+	// string offsets are relative to the start of the new format
+	cache := data[offset:]
+	count := int(binary.LittleEndian.Uint32(cache[20:24]))
+	libraries := []util.Resolution{}
+	seen := map[string]bool{}
+	for i := 0; i < count; i++ {
+		start := ldCacheHeaderSize + i*ldCacheEntrySize
+		if start+ldCacheEntrySize > len(cache) {
+			return libraries, errors.New("truncated ld.so.cache")
+		}
+		entry := cache[start : start+ldCacheEntrySize]
+		name := cString(cache, binary.LittleEndian.Uint32(entry[4:8]))
+		p := cString(cache, binary.LittleEndian.Uint32(entry[8:12]))
+		if name == "" || p == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		libraries = append(libraries, util.Resolution{Name: name, Path: p})
+	}
+	return libraries, nil
+}
+
+// cString returns the NUL terminated string at offset in data.
+func cString(data []byte, offset uint32) string {
+	if int64(offset) >= int64(len(data)) {
+		return ""
+	}
+	s := data[offset:]
+	if end := bytes.IndexByte(s, 0); end >= 0 {
+		s = s[:end]
+	}
+	return string(s)
+}
+
+// getPathCommands returns the executable each command name on searchPath
+// runs, found in the first directory which has it, as a shell would.
+func getPathCommands(root, searchPath string) []util.Resolution {
+	commands := []util.Resolution{}
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(searchPath) {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		resolved := resolveDir(root, dir)
+		entries, err := ioutil.ReadDir(filepath.Join(root, resolved))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if seen[name] {
+				continue
+			}
+			target := resolveCommand(root, resolved, name)
+			if target == "" {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(root, target)); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			commands = append(commands, util.Resolution{Name: name, Path: path.Join(dir, name), Target: target})
+		}
+	}
+	return commands
+}
+
+// resolveDir follows a symlinked directory, such as /bin on merged /usr
+// systems, inside the image.
+func resolveDir(root, dir string) string {
+	target, err := os.Readlink(filepath.Join(root, dir))
+	if err != nil {
+		return dir
+	}
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(path.Dir(dir), target)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const multiarchLibDir = "/usr/lib/x86_64-linux-gnu"
+
+func TestGetLinkerAnalysis(t *testing.T) {
+	// image2 has a cache in the old and new formats, and /bin linked to /usr/bin
+	analysis, err := getLinkerAnalysis(pkgutil.Image{Source: "image2", FSPath: "testDirs/linker/image2"})
+	if err != nil {
+		t.Fatalf("Error reading linker configuration: %s", err)
+	}
+	expected := util.LinkerAnalysis{
+		SearchDirs: []string{"/opt/vendor/lib", "/usr/local/lib", "/usr/local/lib/x86_64-linux-gnu", "/lib/x86_64-linux-gnu", multiarchLibDir},
+		Libraries: []util.Resolution{
+			{Name: "libssl.so.3", Path: "/opt/vendor/lib/libssl.so.3", Target: "/opt/vendor/lib/libssl.so.3"},
+			{Name: "libvendor.so.2", Path: "/opt/vendor/lib/libvendor.so.2"},
+			{Name: "libz.so.1", Path: multiarchLibDir + "/libz.so.1", Target: multiarchLibDir + "/libz.so.1.2.13"},
+		},
+		Commands: []util.Resolution{
+			{Name: "python", Path: "/usr/bin/python", Target: "/usr/bin/python3.9"},
+			{Name: "python3", Path: "/usr/local/bin/python3", Target: "/usr/local/bin/python3.11"},
+			{Name: "python3.11", Path: "/usr/local/bin/python3.11", Target: "/usr/local/bin/python3.11"},
+			{Name: "python3.9", Path: "/usr/bin/python3.9", Target: "/usr/bin/python3.9"},
+		},
+	}
+	if !reflect.DeepEqual(analysis, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, analysis)
+	}
+
+	if _, err := getLinkerAnalysis(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestParseLdSoCache(t *testing.T) {
+	if _, err := parseLdSoCache([]byte("not a cache")); err == nil {
+		t.Errorf("Expected an error for an unknown cache format")
+	}
+}
+
+func TestLinkerDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/linker/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/linker/image2"}
+	result, err := LinkerAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.LinkerDiffResult).Diff.(util.LinkerDiff)
+	if len(diff.SearchDirs1) != 4 || len(diff.SearchDirs2) != 5 {
+		t.Errorf("Expected the search directories to differ, got: %v and %v", diff.SearchDirs1, diff.SearchDirs2)
+	}
+	expectedLibraries := []util.ResolutionChange{
+		{Name: "libssl.so.3", Path1: multiarchLibDir + "/libssl.so.3", Target1: multiarchLibDir + "/libssl.so.3", Path2: "/opt/vendor/lib/libssl.so.3", Target2: "/opt/vendor/lib/libssl.so.3"},
+	}
+	if !reflect.DeepEqual(diff.Libraries, expectedLibraries) {
+		t.Errorf("Expected libraries: %+v but got: %+v", expectedLibraries, diff.Libraries)
+	}
+	expectedCommands := []util.ResolutionChange{
+		{Name: "python3", Path1: "/usr/bin/python3", Target1: "/usr/bin/python3.9", Path2: "/usr/local/bin/python3", Target2: "/usr/local/bin/python3.11"},
+	}
+	if !reflect.DeepEqual(diff.Commands, expectedCommands) {
+		t.Errorf("Expected commands: %+v but got: %+v", expectedCommands, diff.Commands)
+	}
+}
@@ -17,21 +17,51 @@ limitations under the License.
 package differs
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
 )
 
 type MetadataAnalyzer struct {
 }
 
+// MetadataDiff holds the config lines found in only one of the images, in
+// Adds and Dels, and the structured changes of the config fields.
 type MetadataDiff struct {
-	Adds []string
-	Dels []string
+	Adds    []string
+	Dels    []string
+	Changes []ConfigChange
 }
 
+// ConfigChange is a change of an image config field, or of a manifest
+// annotation. Key is the variable, label, annotation, port or volume for
+// the fields holding several of them, and empty for the others. Op tells
+// whether the key or field was added, removed or changed, so that a key
+// set to an empty value isn't mistaken for an unset one.
+type ConfigChange struct {
+	Field  string
+	Key    string
+	Op     string
+	Value1 string
+	Value2 string
+}
+
+// Operations of config changes.
+const (
+	ConfigAdded   = "added"
+	ConfigRemoved = "removed"
+	ConfigChanged = "changed"
+)
+
+// keyedFields are the config fields whose whole lines are left out of the
+// Adds and Dels of metadata diffs, as their changes are listed by key.
+var keyedFields = []string{"Env", "Labels"}
+
 func (a MetadataAnalyzer) Name() string {
 	return "MetadataAnalyzer"
 }
@@ -68,9 +98,160 @@ func getMetadataDiff(image1, image2 pkgutil.Image) (MetadataDiff, error) {
 		return MetadataDiff{}, err
 	}
 
-	adds := util.GetAdditions(m1, m2)
-	dels := util.GetDeletions(m1, m2)
-	return MetadataDiff{adds, dels}, nil
+	adds := withoutKeyedFields(util.GetAdditions(m1, m2))
+	dels := withoutKeyedFields(util.GetDeletions(m1, m2))
+
+	c1, err := image1.Image.ConfigFile()
+	if err != nil {
+		return MetadataDiff{}, err
+	}
+	c2, err := image2.Image.ConfigFile()
+	if err != nil {
+		return MetadataDiff{}, err
+	}
+	changes := getConfigChanges(c1.Config, c2.Config)
+	changes = append(changes, keyedChanges("Annotations", manifestAnnotations(image1), manifestAnnotations(image2))...)
+	return MetadataDiff{adds, dels, changes}, nil
+}
+
+func withoutKeyedFields(lines []string) []string {
+	kept := []string{}
+	for _, line := range lines {
+		keyed := false
+		for _, field := range keyedFields {
+			if strings.HasPrefix(line, field+": ") {
+				keyed = true
+				break
+			}
+		}
+		if !keyed {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// getConfigChanges compares the fields of two image configs, in the order
+// they matter when running a container. Env and Labels are compared by key,
+// and ExposedPorts and Volumes by entry.
+func getConfigChanges(c1, c2 v1.Config) []ConfigChange {
+	changes := []ConfigChange{}
+	scalar := func(field, value1, value2 string) {
+		if value1 != value2 {
+			changes = append(changes, ConfigChange{Field: field, Op: configOp(value1 != "", value2 != ""), Value1: value1, Value2: value2})
+		}
+	}
+	keyed := func(field string, m1, m2 map[string]string) {
+		changes = append(changes, keyedChanges(field, m1, m2)...)
+	}
+	scalar("Entrypoint", commandString(c1.Entrypoint), commandString(c2.Entrypoint))
+	scalar("Cmd", commandString(c1.Cmd), commandString(c2.Cmd))
+	scalar("User", c1.User, c2.User)
+	scalar("WorkingDir", c1.WorkingDir, c2.WorkingDir)
+	keyed("Env", envMap(c1.Env), envMap(c2.Env))
+	keyed("Labels", c1.Labels, c2.Labels)
+	keyed("ExposedPorts", setMap(c1.ExposedPorts), setMap(c2.ExposedPorts))
+	keyed("Volumes", setMap(c1.Volumes), setMap(c2.Volumes))
+	scalar("Healthcheck", healthcheckString(c1.Healthcheck), healthcheckString(c2.Healthcheck))
+	scalar("StopSignal", c1.StopSignal, c2.StopSignal)
+	scalar("Shell", commandString(c1.Shell), commandString(c2.Shell))
+	scalar("OnBuild", commandString(c1.OnBuild), commandString(c2.OnBuild))
+	return changes
+}
+
+// keyedChanges compares the keys of a config field, or of the manifest
+// annotations, which holds several of them.
+func keyedChanges(field string, m1, m2 map[string]string) []ConfigChange {
+	changes := []ConfigChange{}
+	for _, key := range unionKeys(m1, m2) {
+		value1, ok1 := m1[key]
+		value2, ok2 := m2[key]
+		if ok1 != ok2 || value1 != value2 {
+			changes = append(changes, ConfigChange{Field: field, Key: key, Op: configOp(ok1, ok2), Value1: value1, Value2: value2})
+		}
+	}
+	return changes
+}
+
+func configOp(set1, set2 bool) string {
+	switch {
+	case !set1:
+		return ConfigAdded
+	case !set2:
+		return ConfigRemoved
+	default:
+		return ConfigChanged
+	}
+}
+
+// commandString formats a command in the exec form of a Dockerfile.
+func commandString(command []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(command)
+	if err != nil {
+		return strings.Join(command, " ")
+	}
+	return string(b)
+}
+
+func healthcheckString(h *v1.HealthConfig) string {
+	if h == nil {
+		return ""
+	}
+	s := commandString(h.Test)
+	if h.Interval != 0 {
+		s += fmt.Sprintf(" interval=%s", h.Interval)
+	}
+	if h.Timeout != 0 {
+		s += fmt.Sprintf(" timeout=%s", h.Timeout)
+	}
+	if h.StartPeriod != 0 {
+		s += fmt.Sprintf(" start-period=%s", h.StartPeriod)
+	}
+	if h.Retries != 0 {
+		s += fmt.Sprintf(" retries=%d", h.Retries)
+	}
+	return strings.TrimSpace(s)
+}
+
+// envMap maps environment variables to their values. As when running a
+// container, the last of repeated variables wins.
+func envMap(env []string) map[string]string {
+	m := map[string]string{}
+	for _, e := range env {
+		if i := strings.Index(e, "="); i >= 0 {
+			m[e[:i]] = e[i+1:]
+		} else {
+			m[e] = ""
+		}
+	}
+	return m
+}
+
+// setMap maps the entries of a set to themselves, so that added and
+// removed entries show as values.
+func setMap(set map[string]struct{}) map[string]string {
+	m := map[string]string{}
+	for key := range set {
+		m[key] = key
+	}
+	return m
+}
+
+func unionKeys(m1, m2 map[string]string) []string {
+	keys := []string{}
+	for key := range m1 {
+		keys = append(keys, key)
+	}
+	for key := range m2 {
+		if _, ok := m1[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func getMetadataList(image pkgutil.Image) ([]string, error) {
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestGetConfigChanges(t *testing.T) {
+	c1 := v1.Config{
+		Entrypoint:   []string{"/docker-entrypoint.sh"},
+		Cmd:          []string{"nginx", "-g", "daemon off;"},
+		Env:          []string{"PATH=/usr/bin", "NGINX_VERSION=1.24.0", "DEBUG=1", "PROXY="},
+		Labels:       map[string]string{"maintainer": "dev@example.com"},
+		ExposedPorts: map[string]struct{}{"80/tcp": {}},
+		StopSignal:   "SIGQUIT",
+	}
+	c2 := v1.Config{
+		Entrypoint:   []string{"/docker-entrypoint.sh"},
+		Cmd:          []string{"nginx"},
+		User:         "nginx",
+		WorkingDir:   "/srv",
+		Env:          []string{"PATH=/usr/bin", "NGINX_VERSION=1.24.0", "NGINX_VERSION=1.25.3", "NO_PROXY="},
+		Labels:       map[string]string{"maintainer": "dev@example.com", "tier": "web"},
+		ExposedPorts: map[string]struct{}{"80/tcp": {}, "443/tcp": {}},
+		Volumes:      map[string]struct{}{"/var/cache/nginx": {}},
+		Healthcheck:  &v1.HealthConfig{Test: []string{"CMD", "curl", "-f", "http://localhost/"}, Interval: 30 * time.Second},
+		StopSignal:   "SIGQUIT",
+	}
+	expected := []ConfigChange{
+		{Field: "Cmd", Op: ConfigChanged, Value1: `["nginx","-g","daemon off;"]`, Value2: `["nginx"]`},
+		{Field: "User", Op: ConfigAdded, Value2: "nginx"},
+		{Field: "WorkingDir", Op: ConfigAdded, Value2: "/srv"},
+		{Field: "Env", Key: "DEBUG", Op: ConfigRemoved, Value1: "1"},
+		{Field: "Env", Key: "NGINX_VERSION", Op: ConfigChanged, Value1: "1.24.0", Value2: "1.25.3"},
+		{Field: "Env", Key: "NO_PROXY", Op: ConfigAdded},
+		{Field: "Env", Key: "PROXY", Op: ConfigRemoved},
+		{Field: "Labels", Key: "tier", Op: ConfigAdded, Value2: "web"},
+		{Field: "ExposedPorts", Key: "443/tcp", Op: ConfigAdded, Value2: "443/tcp"},
+		{Field: "Volumes", Key: "/var/cache/nginx", Op: ConfigAdded, Value2: "/var/cache/nginx"},
+		{Field: "Healthcheck", Op: ConfigAdded, Value2: `["CMD","curl","-f","http://localhost/"] interval=30s`},
+	}
+	if changes := getConfigChanges(c1, c2); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, changes)
+	}
+	if changes := getConfigChanges(c2, c2); len(changes) != 0 {
+		t.Errorf("Expected no changes between identical configs but got: %+v", changes)
+	}
+}
+
+// annotatedImage is a test image with an OCI manifest holding
+// annotations.
+type annotatedImage struct {
+	*pkgutil.TestImage
+	annotations map[string]string
+}
+
+func (i annotatedImage) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+func (i annotatedImage) Manifest() (*v1.Manifest, error) {
+	return &v1.Manifest{MediaType: types.OCIManifestSchema1, Annotations: i.annotations}, nil
+}
+
+func TestMetadataDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", Image: annotatedImage{
+		TestImage:   &pkgutil.TestImage{Config: &v1.ConfigFile{Config: v1.Config{User: "root", Env: []string{"A=1"}}}},
+		annotations: map[string]string{"org.opencontainers.image.version": "1.0"},
+	}}
+	image2 := pkgutil.Image{Source: "image2", Image: annotatedImage{
+		TestImage:   &pkgutil.TestImage{Config: &v1.ConfigFile{Config: v1.Config{User: "app", Env: []string{"A=1"}, Labels: map[string]string{"tier": "web"}}}},
+		annotations: map[string]string{"org.opencontainers.image.version": "1.1"},
+	}}
+	result, err := MetadataAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.MetadataDiffResult).Diff.(MetadataDiff)
+	expected := []ConfigChange{
+		{Field: "User", Op: ConfigChanged, Value1: "root", Value2: "app"},
+		{Field: "Labels", Key: "tier", Op: ConfigAdded, Value2: "web"},
+		{Field: "Annotations", Key: "org.opencontainers.image.version", Op: ConfigChanged, Value1: "1.0", Value2: "1.1"},
+	}
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff.Changes)
+	}
+	if !reflect.DeepEqual(diff.Adds, []string{"User: app"}) || !reflect.DeepEqual(diff.Dels, []string{"User: root"}) {
+		t.Errorf("Expected the changed config lines other than Env and Labels in Adds and Dels but got: %+v %+v", diff.Adds, diff.Dels)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"sync"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+const npmEcosystem = "npm"
+
+var advisoryDBs = struct {
+	sync.Mutex
+	dbs map[string]util.AdvisoryDB
+}{dbs: map[string]util.AdvisoryDB{}}
+
+// getAdvisoryDB loads the advisory database at path once per process, so
+// the advisory analyzers share it and its cache of OSV API lookups.
+func getAdvisoryDB(path string) (util.AdvisoryDB, error) {
+	advisoryDBs.Lock()
+	defer advisoryDBs.Unlock()
+	if db, ok := advisoryDBs.dbs[path]; ok {
+		return db, nil
+	}
+	db, err := util.NewAdvisoryDB(path)
+	if err != nil {
+		return nil, err
+	}
+	advisoryDBs.dbs[path] = db
+	return db, nil
+}
+
+// NodeAdvisoryAnalyzer reports the advisories affecting the packages found
+// by the node analyzer.
+type NodeAdvisoryAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a NodeAdvisoryAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a NodeAdvisoryAnalyzer) Name() string {
+	return "NodeAdvisoryAnalyzer"
+}
+
+func (a NodeAdvisoryAnalyzer) Dependencies() []string {
+	return []string{nodeAnalyzer}
+}
+
+// Diff reports the advisories introduced and resolved between two images.
+func (a NodeAdvisoryAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	advisories1, err := getNodeAdvisories(image1, a.Options)
+	if err != nil {
+		return &util.AdvisoryDiffResult{}, err
+	}
+	advisories2, err := getNodeAdvisories(image2, a.Options)
+	if err != nil {
+		return &util.AdvisoryDiffResult{}, err
+	}
+	return &util.AdvisoryDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "NodeAdvisory",
+		Diff:     util.GetAdvisoryDiff(advisories1, advisories2),
+	}, nil
+}
+
+func (a NodeAdvisoryAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	advisories, err := getNodeAdvisories(image, a.Options)
+	if err != nil {
+		return &util.AdvisoryAnalyzeResult{}, err
+	}
+	return &util.AdvisoryAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "NodeAdvisory",
+		Analysis:    advisories,
+	}, nil
+}
+
+// getNodeAdvisories looks up every distinct installed version of each node
+// package in the advisory database.
+func getNodeAdvisories(image pkgutil.Image, opts *AnalyzerOptions) ([]util.Advisory, error) {
+	db, err := getAdvisoryDB(analyzerOptions(opts).AdvisoryDBPath)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := multiVersionPackages(image, NodeAnalyzer{Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	queries := []util.AdvisoryQuery{}
+	versions := map[string]bool{}
+	for name, installs := range packages {
+		for _, info := range installs {
+			key := name + "@" + info.Version
+			if info.Version == "" || versions[key] {
+				continue
+			}
+			versions[key] = true
+			queries = append(queries, util.AdvisoryQuery{Ecosystem: npmEcosystem, Name: name, Version: info.Version})
+		}
+	}
+	if err := util.PrefetchAdvisories(db, queries); err != nil {
+		logrus.Warningf("Error looking up advisories in batches, looking up each package: %s", err)
+	}
+	advisories := []util.Advisory{}
+	for _, q := range queries {
+		found, err := util.GetAdvisories(db, npmEcosystem, q.Name, q.Version)
+		if err != nil {
+			logrus.Warningf("Error looking up advisories for %s@%s: %s", q.Name, q.Version, err)
+			continue
+		}
+		advisories = append(advisories, found...)
+	}
+	util.SortAdvisories(advisories)
+	return advisories, nil
+}
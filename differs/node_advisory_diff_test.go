@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestNodeAdvisoryDiff(t *testing.T) {
+	analyzer := NodeAdvisoryAnalyzer{Options: &AnalyzerOptions{AdvisoryDBPath: "testDirs/advisories"}}
+
+	pac1 := util.Advisory{ID: "GHSA-0001", Package: "pac1", Version: "1.0", Severity: "HIGH", Summary: "Prototype pollution in pac1", Fixed: "1.2.0"}
+	pac2 := util.Advisory{ID: "GHSA-0002", Package: "pac2", Version: "3.0", Severity: "MODERATE", Summary: "ReDoS in pac2"}
+
+	testCases := []struct {
+		descrip  string
+		path1    string
+		path2    string
+		expected util.AdvisoryDiff
+	}{
+		{
+			descrip: "advisories introduced",
+			path1:   "testDirs/noPackages",
+			path2:   "testDirs/packageMulti",
+			expected: util.AdvisoryDiff{
+				Introduced: []util.Advisory{pac1, pac2},
+				Resolved:   []util.Advisory{},
+			},
+		},
+		{
+			descrip: "advisory resolved",
+			path1:   "testDirs/packageMulti",
+			path2:   "testDirs/packageOne",
+			expected: util.AdvisoryDiff{
+				Introduced: []util.Advisory{},
+				Resolved:   []util.Advisory{pac2},
+			},
+		},
+	}
+	for _, test := range testCases {
+		result, err := analyzer.Diff(pkgutil.Image{FSPath: test.path1}, pkgutil.Image{FSPath: test.path2})
+		if err != nil {
+			t.Errorf("%s: got unexpected error: %s", test.descrip, err)
+			continue
+		}
+		diff := result.(*util.AdvisoryDiffResult).Diff
+		if !reflect.DeepEqual(diff, test.expected) {
+			t.Errorf("%s: Expected: %v but got: %v", test.descrip, test.expected, diff)
+		}
+	}
+}
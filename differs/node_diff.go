@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
@@ -29,21 +31,53 @@ import (
 )
 
 type NodeAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a NodeAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a NodeAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
 }
 
 func (a NodeAnalyzer) Name() string {
 	return "NodeAnalyzer"
 }
 
-// NodeDiff compares the packages installed by apt-get.
+// NodeDiff compares the packages installed by npm, project by project.
 func (a NodeAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
-	diff, err := multiVersionDiff(image1, image2, a)
-	return diff, err
+	pack1, err := multiVersionPackages(image1, a)
+	if err != nil {
+		return &util.NodeDiffResult{}, err
+	}
+	pack2, err := multiVersionPackages(image2, a)
+	if err != nil {
+		return &util.NodeDiffResult{}, err
+	}
+	diff := util.GetNodeProjectDiff(pack1, pack2)
+	util.ClassifyMultiVersionChanges(util.VersionSchemeSemver, diff.InfoDiff)
+	return &util.NodeDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Node",
+		Diff:     diff,
+	}, nil
 }
 
 func (a NodeAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
-	analysis, err := multiVersionAnalysis(image, a)
-	return analysis, err
+	pack, err := multiVersionPackages(image, a)
+	if err != nil {
+		return &util.NodeAnalyzeResult{}, err
+	}
+	return &util.NodeAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Node",
+		Analysis:    pack,
+	}, nil
 }
 
 func (a NodeAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
@@ -53,7 +87,7 @@ func (a NodeAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]ut
 		// path provided invalid
 		return packages, err
 	}
-	layerStems, err := buildNodePaths(path)
+	layerStems, err := buildNodePaths(path, a.options())
 	if err != nil {
 		logrus.Warningf("Error building JSON paths at %s: %s\n", path, err)
 		return packages, err
@@ -97,10 +131,61 @@ type nodePackage struct {
 	Version string `json:"version"`
 }
 
-func buildNodePaths(path string) ([]string, error) {
-	globalPaths := filepath.Join(path, "node_modules")
-	localPath := filepath.Join(path, "usr/local/lib/node_modules")
-	return []string{globalPaths, localPath}, nil
+// nodeSkipDirs aren't searched for node_modules directories.
+var nodeSkipDirs = map[string]bool{
+	".git":          true,
+	"__pycache__":   true,
+	"site-packages": true,
+	"dist-packages": true,
+}
+
+// buildNodePaths returns the node_modules directories of the image: the
+// global ones of npm, nvm and yarn, those of every app and package, found
+// anywhere in the image, and the NodeRoots of opts.
+func buildNodePaths(path string, opts AnalyzerOptions) ([]string, error) {
+	roots := []string{"/node_modules", "/usr/local/lib/node_modules"}
+	for _, pattern := range util.NodeGlobalModules {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			roots = append(roots, imagePath(path, match))
+		}
+	}
+	roots = append(roots, findNodeModules(path)...)
+	roots = append(roots, opts.NodeRoots...)
+	paths := []string{}
+	for _, root := range packageRoots(path, roots, opts) {
+		paths = append(paths, filepath.Join(path, root))
+	}
+	return paths, nil
+}
+
+// findNodeModules returns the image paths of the node_modules directories
+// below root, including those nested in packages, and of the scopes in
+// them, e.g. node_modules/@types, which hold packages in turn.
+func findNodeModules(root string) []string {
+	modules := []string{}
+	pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if !info.IsDir() {
+			return nil
+		}
+		if nodeSkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if info.Name() == "node_modules" ||
+			strings.HasPrefix(info.Name(), "@") && filepath.Base(filepath.Dir(file)) == "node_modules" {
+			modules = append(modules, imagePath(root, file))
+		}
+		return nil
+	})
+	sort.Strings(modules)
+	return modules
+}
+
+func imagePath(root, file string) string {
+	return path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(file, root)))
 }
 
 func readPackageJSON(path string) (nodePackage, error) {
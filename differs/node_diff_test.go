@@ -20,8 +20,10 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
 )
 
 func TestGetNodePackages(t *testing.T) {
@@ -75,6 +77,42 @@ func TestGetNodePackages(t *testing.T) {
 		}
 	}
 }
+
+func TestGetNodePackagesByProject(t *testing.T) {
+	image := difftest.NewFS(t).
+		File("usr/local/lib/node_modules/npm/package.json", `{"name": "npm", "version": "10.2.0"}`).
+		File("root/.nvm/versions/node/v20.10.0/lib/node_modules/pm2/package.json", `{"name": "pm2", "version": "5.3.0"}`).
+		File("srv/api/node_modules/express/package.json", `{"name": "express", "version": "4.18.2"}`).
+		File("srv/api/node_modules/express/node_modules/debug/package.json", `{"name": "debug", "version": "2.6.9"}`).
+		File("srv/api/node_modules/@types/node/package.json", `{"name": "@types/node", "version": "20.10.0"}`).
+		File("srv/web/node_modules/debug/package.json", `{"name": "debug", "version": "4.3.4"}`).
+		Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+
+	packages, err := NodeAnalyzer{}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string][]string{
+		"npm":         {"/usr/local/lib/node_modules/npm/"},
+		"pm2":         {"/root/.nvm/versions/node/v20.10.0/lib/node_modules/pm2/"},
+		"express":     {"/srv/api/node_modules/express/"},
+		"debug":       {"/srv/api/node_modules/express/node_modules/debug/", "/srv/web/node_modules/debug/"},
+		"@types/node": {"/srv/api/node_modules/@types/node/"},
+	}
+	if len(packages) != len(expected) {
+		t.Errorf("Expected packages %v but got %v", expected, packages)
+	}
+	for name, paths := range expected {
+		for _, p := range paths {
+			if _, ok := packages[name][p]; !ok {
+				t.Errorf("Expected %s at %s but got %v", name, p, packages[name])
+			}
+		}
+	}
+
+}
+
 func TestReadPackageJSON(t *testing.T) {
 	testCases := []struct {
 		descrip  string
@@ -0,0 +1,185 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultNugetCaches are the global packages folders of the root and other
+// users, and the offline cache older .NET Core SDKs ship, in addition to
+// NUGET_PACKAGES from the image environment.
+var defaultNugetCaches = []string{
+	"/root/.nuget/packages",
+	"/home/*/.nuget/packages",
+	"/usr/share/dotnet/sdk/NuGetFallbackFolder",
+	"/usr/lib/dotnet/sdk/NuGetFallbackFolder",
+}
+
+// nuspecID matches the package id of a .nuspec file.
+var nuspecID = regexp.MustCompile(`<id>\s*([^<\s]+)\s*</id>`)
+
+// NugetAnalyzer compares the NuGet packages .NET applications depend on,
+// from their .deps.json files, and those kept in NuGet package caches.
+type NugetAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a NugetAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a NugetAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a NugetAnalyzer) Name() string {
+	return "NugetAnalyzer"
+}
+
+// Diff compares the NuGet packages in two images.
+func (a NugetAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := multiVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a NugetAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := multiVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// getPackages maps each NuGet package to the .deps.json files and package
+// caches which have it.
+func (a NugetAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
+	root := image.FSPath
+	packages := make(map[string]map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// path provided invalid
+		return packages, err
+	}
+	config, err := configFile(image)
+	if err != nil {
+		return packages, err
+	}
+	patterns := defaultNugetCaches
+	for _, env := range config.Config.Env {
+		if strings.HasPrefix(env, "NUGET_PACKAGES=") {
+			patterns = append([]string{strings.TrimPrefix(env, "NUGET_PACKAGES=")}, patterns...)
+		}
+	}
+
+	// the caches hold packages, whose own .deps.json files aren't the
+	// image's dependencies
+	skip := map[string]bool{}
+	for _, pattern := range patterns {
+		for _, cache := range glob(root, pattern) {
+			cache = path.Clean(cache)
+			if skip[filepath.Join(root, cache)] {
+				continue
+			}
+			skip[filepath.Join(root, cache)] = true
+			readNugetCache(root, cache, packages)
+		}
+	}
+	err = pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if info.IsDir() && skip[file] {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".deps.json") {
+			readDepsJSON(root, file, packages)
+		}
+		return nil
+	})
+	return packages, err
+}
+
+// readNugetCache records the packages of a cache laid out as
+// <id>/<version>, where the id is lowercased, taking the id as published
+// from the package's .nuspec.
+func readNugetCache(root, cache string, packages map[string]map[string]util.PackageInfo) {
+	for _, dir := range glob(root, path.Join(cache, "*/*")) {
+		info, err := os.Stat(filepath.Join(root, dir))
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		name := path.Base(path.Dir(dir))
+		for _, nuspec := range glob(root, path.Join(dir, "*.nuspec")) {
+			if id := readQuoted(root, nuspec, nuspecID); id != "" {
+				name = id
+			}
+			break
+		}
+		addNugetPackage(packages, name, dir, path.Base(dir), pkgutil.GetSize(filepath.Join(root, dir)))
+	}
+}
+
+// depsJSON is the part of a .deps.json file listing the resolved libraries,
+// keyed by name/version.
+type depsJSON struct {
+	Libraries map[string]struct {
+		Type string `json:"type"`
+	} `json:"libraries"`
+}
+
+// readDepsJSON records the NuGet packages an application or shared
+// framework was built against. Its own projects are not packages.
+func readDepsJSON(root, file string, packages map[string]map[string]util.PackageInfo) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		logrus.Warnf("Could not read %s: %s", file, err)
+		return
+	}
+	var deps depsJSON
+	if err := json.Unmarshal(data, &deps); err != nil {
+		logrus.Warnf("Could not parse %s: %s", file, err)
+		return
+	}
+	depsPath := "/" + filepath.ToSlash(strings.TrimPrefix(file, root+string(os.PathSeparator)))
+	versions := map[string][]string{}
+	for library, info := range deps.Libraries {
+		i := strings.LastIndex(library, "/")
+		if info.Type != "package" || i <= 0 {
+			continue
+		}
+		name := library[:i]
+		versions[name] = append(versions[name], library[i+1:])
+	}
+	for name, v := range versions {
+		sort.Strings(v)
+		addNugetPackage(packages, name, depsPath, strings.Join(v, ", "), -1)
+	}
+}
+
+func addNugetPackage(packages map[string]map[string]util.PackageInfo, name, location, version string, size int64) {
+	if _, ok := packages[name]; !ok {
+		packages[name] = make(map[string]util.PackageInfo)
+	}
+	packages[name][location] = util.PackageInfo{Version: version, Size: size}
+}
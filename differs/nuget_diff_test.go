@@ -0,0 +1,66 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const appDepsJSON = `{
+  "runtimeTarget": {"name": ".NETCoreApp,Version=v6.0"},
+  "targets": {},
+  "libraries": {
+    "App/1.0.0": {"type": "project", "serviceable": false, "sha512": ""},
+    "Newtonsoft.Json/13.0.1": {"type": "package", "serviceable": true, "sha512": "sha512-ppPFpBcvxdsfUonNcvITKqLl3bqxWbDCZIzDWHzjpdAHRFfZe0Dw9HmA0+za13IdyrgJwpkDTDA9fHaxOrt20A==", "path": "newtonsoft.json/13.0.1", "hashPath": "newtonsoft.json.13.0.1.nupkg.sha512"},
+    "Serilog/2.10.0": {"type": "package", "serviceable": true},
+    "Serilog/2.12.0": {"type": "package", "serviceable": true}
+  }
+}`
+
+func TestGetNugetPackages(t *testing.T) {
+	fs := difftest.NewFS(t).
+		File("/app/App.deps.json", appDepsJSON).
+		File("/root/.nuget/packages/newtonsoft.json/13.0.1/newtonsoft.json.nuspec", `<?xml version="1.0"?><package><metadata><id>Newtonsoft.Json</id><version>13.0.1</version></metadata></package>`).
+		// packages may ship .deps.json files, which are not the image's
+		File("/root/.nuget/packages/newtonsoft.json/13.0.1/lib/tool.deps.json", `{"libraries": {"Other/1.0.0": {"type": "package"}}}`).
+		File("/root/.nuget/packages/dapper/2.0.123/lib/Dapper.dll", "dll").
+		File("/app/broken.deps.json", "{")
+	packages, err := NugetAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]util.PackageInfo{
+		"Newtonsoft.Json": {
+			"/app/App.deps.json":                           {Version: "13.0.1", Size: -1},
+			"/root/.nuget/packages/newtonsoft.json/13.0.1": {Version: "13.0.1", Size: 161},
+		},
+		"Serilog": {"/app/App.deps.json": {Version: "2.10.0, 2.12.0", Size: -1}},
+		"dapper":  {"/root/.nuget/packages/dapper/2.0.123": {Version: "2.0.123", Size: 3}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, packages)
+	}
+
+	if _, err := (NugetAnalyzer{}).getPackages(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
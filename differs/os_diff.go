@@ -0,0 +1,275 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// osReleasePaths are read in order, as systemd does; /etc/os-release is
+// often a link to the other.
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// baseNameLabel is the OCI annotation, also set as a label by some builders,
+// naming the image an image was built from.
+const baseNameLabel = "org.opencontainers.image.base.name"
+
+// redhatRelease matches release files such as /etc/redhat-release, e.g.
+// "CentOS Linux release 7.9.2009 (Core)".
+var redhatRelease = regexp.MustCompile(`^(.+?) release (\S+)(?: \((.+)\))?`)
+
+// officialRepositories are the Docker Hub repositories of distributions
+// whose os-release ID isn't the repository name.
+var officialRepositories = map[string]string{
+	"amzn":                "amazonlinux",
+	"arch":                "archlinux",
+	"ol":                  "oraclelinux",
+	"rocky":               "rockylinux",
+	"opensuse-leap":       "opensuse/leap",
+	"opensuse-tumbleweed": "opensuse/tumbleweed",
+}
+
+// OSAnalyzer identifies the distribution of an image and the base images it
+// is likely built from.
+type OSAnalyzer struct {
+}
+
+func (a OSAnalyzer) Name() string {
+	return "OSAnalyzer"
+}
+
+// Diff compares the distributions of two images.
+func (a OSAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	r1, err := getOSRelease(image1)
+	if err != nil {
+		return &util.OSDiffResult{}, err
+	}
+	r2, err := getOSRelease(image2)
+	if err != nil {
+		return &util.OSDiffResult{}, err
+	}
+	return &util.OSDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "OS",
+		Diff:     util.GetOSDiff(r1, r2),
+	}, nil
+}
+
+func (a OSAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	release, err := getOSRelease(image)
+	if err != nil {
+		return &util.OSAnalyzeResult{}, err
+	}
+	return &util.OSAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "OS",
+		Analysis:    release,
+	}, nil
+}
+
+// getOSRelease reads the distribution of an image from its os-release file,
+// completed by the release files distributions kept before os-release, and
+// works out its lineage.
+func getOSRelease(image pkgutil.Image) (util.OSRelease, error) {
+	root := image.FSPath
+	release := util.OSRelease{IDLike: []string{}, Source: []string{}, Lineage: []string{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return release, err
+	}
+	fields := map[string]string{}
+	for _, p := range osReleasePaths {
+		if fields = readOSRelease(root, p); fields != nil {
+			release.Source = append(release.Source, p)
+			break
+		}
+	}
+	release.ID = fields["ID"]
+	if fields["ID_LIKE"] != "" {
+		release.IDLike = strings.Fields(fields["ID_LIKE"])
+	}
+	release.Name = fields["NAME"]
+	release.Version = fields["VERSION_ID"]
+	release.Codename = firstValue(fields["VERSION_CODENAME"], fields["UBUNTU_CODENAME"])
+	release.PrettyName = fields["PRETTY_NAME"]
+	readDistributionRelease(root, &release)
+
+	config, err := configFile(image)
+	if err != nil {
+		return release, err
+	}
+	if base := config.Config.Labels[baseNameLabel]; base != "" {
+		release.Lineage = append(release.Lineage, base)
+	}
+	// distroless images describe the distribution they are built from
+	if strings.Contains(fields["HOME_URL"], "distroless") {
+		release.Lineage = append(release.Lineage, "gcr.io/distroless")
+	}
+	if official := officialImage(release); official != "" {
+		release.Lineage = append(release.Lineage, official)
+	}
+	release.Lineage = append(release.Lineage, release.IDLike...)
+	return release, nil
+}
+
+// readOSRelease parses the KEY=value lines of an os-release file, resolving
+// links within the image. It returns nil when the file doesn't exist.
+func readOSRelease(root, p string) map[string]string {
+	file, err := os.Open(filepath.Join(root, resolveLink(root, p)))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.Index(line, "=")
+		if i <= 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields[line[:i]] = unquoteShell(line[i+1:])
+	}
+	return fields
+}
+
+// unquoteShell removes the shell quoting os-release values may have.
+func unquoteShell(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+		var b strings.Builder
+		for i := 0; i < len(value); i++ {
+			if value[i] == '\\' && i+1 < len(value) && strings.IndexByte("\"\\$`", value[i+1]) >= 0 {
+				i++
+			}
+			b.WriteByte(value[i])
+		}
+		return b.String()
+	}
+	return value
+}
+
+// readDistributionRelease fills in what the release files of Debian, Alpine,
+// Red Hat derived and LSB distributions tell beyond os-release: the point
+// release, or the whole release in images without os-release.
+func readDistributionRelease(root string, release *util.OSRelease) {
+	// derivatives such as Ubuntu keep the Debian release they are based on
+	if version := readReleaseLine(root, "/etc/debian_version"); version != "" && (release.ID == "" || release.ID == "debian") {
+		release.Source = append(release.Source, "/etc/debian_version")
+		if release.ID == "" {
+			release.ID, release.Name = "debian", "Debian GNU/Linux"
+		}
+		setPointRelease(release, version)
+	}
+	if version := readReleaseLine(root, "/etc/alpine-release"); version != "" && (release.ID == "" || release.ID == "alpine") {
+		release.Source = append(release.Source, "/etc/alpine-release")
+		if release.ID == "" {
+			release.ID, release.Name, release.Version = "alpine", "Alpine Linux", version
+		}
+		setPointRelease(release, version)
+	}
+	if m := redhatRelease.FindStringSubmatch(readReleaseLine(root, "/etc/redhat-release")); m != nil {
+		release.Source = append(release.Source, "/etc/redhat-release")
+		if release.ID == "" {
+			release.ID = strings.ToLower(strings.Fields(m[1])[0])
+			if strings.HasPrefix(m[1], "Red Hat") {
+				release.ID = "rhel"
+			}
+			release.Name = m[1]
+			release.Version = strings.SplitN(m[2], ".", 2)[0]
+			release.PrettyName = m[0]
+		}
+		setPointRelease(release, m[2])
+	}
+	if release.ID == "" {
+		lsb := readOSRelease(root, "/etc/lsb-release")
+		if lsb["DISTRIB_ID"] != "" {
+			release.Source = append(release.Source, "/etc/lsb-release")
+			release.ID = strings.ToLower(lsb["DISTRIB_ID"])
+			release.Name = lsb["DISTRIB_ID"]
+			release.Version = lsb["DISTRIB_RELEASE"]
+			release.Codename = lsb["DISTRIB_CODENAME"]
+			release.PrettyName = lsb["DISTRIB_DESCRIPTION"]
+		}
+	}
+}
+
+// setPointRelease records a release file's version when it is more precise
+// than the os-release one.
+func setPointRelease(release *util.OSRelease, version string) {
+	if version != release.Version {
+		release.PointRelease = version
+	}
+}
+
+func readReleaseLine(root, p string) string {
+	data, err := ioutil.ReadFile(filepath.Join(root, resolveLink(root, p)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+}
+
+// resolveLink follows symlinks at p within root, so that absolute targets
+// don't escape the image filesystem.
+func resolveLink(root, p string) string {
+	for i := 0; i < 40; i++ {
+		target, err := os.Readlink(filepath.Join(root, p))
+		if err != nil {
+			return p
+		}
+		if path.IsAbs(target) {
+			p = path.Clean(target)
+		} else {
+			p = path.Join(path.Dir(p), target)
+		}
+	}
+	return p
+}
+
+// officialImage names the official image of a distribution release, such
+// as debian:12 or ubuntu:22.04. Releases without a version, such as rolling
+// ones, are named by their codename or not tagged.
+func officialImage(release util.OSRelease) string {
+	if release.ID == "" {
+		return ""
+	}
+	if release.ID == "rhel" && release.Version != "" {
+		return "registry.access.redhat.com/ubi" + strings.SplitN(release.Version, ".", 2)[0]
+	}
+	repository := release.ID
+	if r, ok := officialRepositories[release.ID]; ok {
+		repository = r
+	}
+	if tag := firstValue(release.Version, release.Codename); tag != "" {
+		return repository + ":" + tag
+	}
+	return repository
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+const debianOSRelease = `PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+NAME="Debian GNU/Linux"
+VERSION_ID="12"
+VERSION="12 (bookworm)"
+VERSION_CODENAME=bookworm
+ID=debian
+HOME_URL="https://www.debian.org/"
+`
+
+const ubuntuOSRelease = `PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+VERSION="22.04.3 LTS (Jammy Jellyfish)"
+VERSION_CODENAME=jammy
+ID=ubuntu
+ID_LIKE=debian
+UBUNTU_CODENAME=jammy
+`
+
+func TestGetOSRelease(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		fs       *difftest.FS
+		labels   map[string]string
+		expected util.OSRelease
+	}{
+		{
+			descrip: "debian with point release and base image label",
+			fs: difftest.NewFS(t).
+				File("/usr/lib/os-release", debianOSRelease).
+				Symlink("/etc/os-release", "/usr/lib/os-release").
+				File("/etc/debian_version", "12.4\n"),
+			labels: map[string]string{baseNameLabel: "docker.io/library/debian:bookworm-slim"},
+			expected: util.OSRelease{
+				ID:           "debian",
+				IDLike:       []string{},
+				Name:         "Debian GNU/Linux",
+				Version:      "12",
+				Codename:     "bookworm",
+				PrettyName:   "Debian GNU/Linux 12 (bookworm)",
+				PointRelease: "12.4",
+				Source:       []string{"/etc/os-release", "/etc/debian_version"},
+				Lineage:      []string{"docker.io/library/debian:bookworm-slim", "debian:12"},
+			},
+		},
+		{
+			descrip: "derivative keeping the release of its parent",
+			fs: difftest.NewFS(t).
+				File("/etc/os-release", ubuntuOSRelease).
+				File("/etc/debian_version", "bookworm/sid\n"),
+			expected: util.OSRelease{
+				ID:         "ubuntu",
+				IDLike:     []string{"debian"},
+				Name:       "Ubuntu",
+				Version:    "22.04",
+				Codename:   "jammy",
+				PrettyName: "Ubuntu 22.04.3 LTS",
+				Source:     []string{"/etc/os-release"},
+				Lineage:    []string{"ubuntu:22.04", "debian"},
+			},
+		},
+		{
+			descrip: "distroless",
+			fs: difftest.NewFS(t).
+				File("/etc/os-release", "PRETTY_NAME=\"Distroless\"\nNAME=\"Debian GNU/Linux\"\nID=\"debian\"\nVERSION_ID=\"12\"\nHOME_URL=\"https://github.com/GoogleContainerTools/distroless\"\n"),
+			expected: util.OSRelease{
+				ID:         "debian",
+				IDLike:     []string{},
+				Name:       "Debian GNU/Linux",
+				Version:    "12",
+				PrettyName: "Distroless",
+				Source:     []string{"/etc/os-release"},
+				Lineage:    []string{"gcr.io/distroless", "debian:12"},
+			},
+		},
+		{
+			descrip: "red hat release file only",
+			fs: difftest.NewFS(t).
+				File("/etc/redhat-release", "CentOS Linux release 7.9.2009 (Core)\n"),
+			expected: util.OSRelease{
+				ID:           "centos",
+				IDLike:       []string{},
+				Name:         "CentOS Linux",
+				Version:      "7",
+				PrettyName:   "CentOS Linux release 7.9.2009 (Core)",
+				PointRelease: "7.9.2009",
+				Source:       []string{"/etc/redhat-release"},
+				Lineage:      []string{"centos:7"},
+			},
+		},
+		{
+			descrip: "alpine release file only",
+			fs:      difftest.NewFS(t).File("/etc/alpine-release", "3.18.4\n"),
+			expected: util.OSRelease{
+				ID:      "alpine",
+				IDLike:  []string{},
+				Name:    "Alpine Linux",
+				Version: "3.18.4",
+				Source:  []string{"/etc/alpine-release"},
+				Lineage: []string{"alpine:3.18.4"},
+			},
+		},
+		{
+			descrip:  "scratch",
+			fs:       difftest.NewFS(t).File("/app", "binary"),
+			expected: util.OSRelease{IDLike: []string{}, Source: []string{}, Lineage: []string{}},
+		},
+	}
+	for _, test := range testCases {
+		image := test.fs.Image("image")
+		image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{Config: v1.Config{Labels: test.labels}}}
+		release, err := getOSRelease(image)
+		if err != nil {
+			t.Errorf("%s: Got unexpected error: %s", test.descrip, err)
+			continue
+		}
+		if !reflect.DeepEqual(release, test.expected) {
+			t.Errorf("%s: Expected: %+v but got: %+v", test.descrip, test.expected, release)
+		}
+	}
+
+	if _, err := getOSRelease(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
+
+func TestGetOSDiff(t *testing.T) {
+	debian := difftest.NewFS(t).File("/etc/os-release", debianOSRelease).Image("debian")
+	ubuntu := difftest.NewFS(t).File("/etc/os-release", ubuntuOSRelease).Image("ubuntu")
+	result, err := OSAnalyzer{}.Diff(debian, ubuntu)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.OSDiffResult).Diff.(util.OSDiff)
+	expected := []util.OSChange{
+		{Field: "ID", Value1: "debian", Value2: "ubuntu"},
+		{Field: "Name", Value1: "Debian GNU/Linux", Value2: "Ubuntu"},
+		{Field: "Version", Value1: "12", Value2: "22.04"},
+		{Field: "Codename", Value1: "bookworm", Value2: "jammy"},
+		{Field: "PrettyName", Value1: "Debian GNU/Linux 12 (bookworm)", Value2: "Ubuntu 22.04.3 LTS"},
+		{Field: "Family", Value1: "", Value2: "debian"},
+		{Field: "Lineage", Value1: "debian:12", Value2: "ubuntu:22.04 < debian"},
+	}
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff.Changes)
+	}
+}
@@ -18,6 +18,7 @@ package differs
 
 import (
 	"errors"
+	"sort"
 	"strings"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
@@ -28,52 +29,100 @@ import (
 type MultiVersionPackageAnalyzer interface {
 	getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error)
 	Name() string
+	options() AnalyzerOptions
 }
 
 type SingleVersionPackageAnalyzer interface {
 	getPackages(image pkgutil.Image) (map[string]util.PackageInfo, error)
 	Name() string
+	options() AnalyzerOptions
 }
 
 type SingleVersionPackageLayerAnalyzer interface {
 	getPackages(image pkgutil.Image) ([]map[string]util.PackageInfo, error)
 	Name() string
+	options() AnalyzerOptions
+}
+
+// multiVersionPackages returns the packages found by analyzer in image, reusing
+// the inventory computed earlier in the same request when available. The
+// returned map is a copy and may be modified by the caller. Packages left
+// out by --ignore-package and --only-package are dropped.
+func multiVersionPackages(image pkgutil.Image, analyzer MultiVersionPackageAnalyzer) (map[string]map[string]util.PackageInfo, error) {
+	inv, err := inventories.get(image, analyzer.Name(), func() (interface{}, error) {
+		return analyzer.getPackages(image)
+	})
+	packages, _ := inv.(map[string]map[string]util.PackageInfo)
+	packagesCopy := make(map[string]map[string]util.PackageInfo, len(packages))
+	keep := packageFilter(analyzer.Name(), analyzer.options())
+	for name, versions := range packages {
+		if keep != nil && !keep(name) {
+			continue
+		}
+		versionsCopy := make(map[string]util.PackageInfo, len(versions))
+		for path, info := range versions {
+			versionsCopy[path] = info
+		}
+		packagesCopy[name] = versionsCopy
+	}
+	return packagesCopy, err
+}
+
+// singleVersionPackages returns the packages found by analyzer in image, reusing
+// the inventory computed earlier in the same request when available. The
+// returned map is a copy and may be modified by the caller. Packages left
+// out by --ignore-package and --only-package are dropped.
+func singleVersionPackages(image pkgutil.Image, analyzer SingleVersionPackageAnalyzer) (map[string]util.PackageInfo, error) {
+	inv, err := inventories.get(image, analyzer.Name(), func() (interface{}, error) {
+		return analyzer.getPackages(image)
+	})
+	packages, _ := inv.(map[string]util.PackageInfo)
+	packagesCopy := make(map[string]util.PackageInfo, len(packages))
+	for name, info := range packages {
+		packagesCopy[name] = info
+	}
+	filterPackages(analyzer.Name(), analyzer.options(), packagesCopy)
+	return packagesCopy, err
 }
 
 func multiVersionDiff(image1, image2 pkgutil.Image, differ MultiVersionPackageAnalyzer) (*util.MultiVersionPackageDiffResult, error) {
-	pack1, err := differ.getPackages(image1)
+	pack1, err := multiVersionPackages(image1, differ)
 	if err != nil {
 		return &util.MultiVersionPackageDiffResult{}, err
 	}
-	pack2, err := differ.getPackages(image2)
+	pack2, err := multiVersionPackages(image2, differ)
 	if err != nil {
 		return &util.MultiVersionPackageDiffResult{}, err
 	}
 
+	diffType := strings.TrimSuffix(differ.Name(), "Analyzer")
 	diff := util.GetMultiVersionMapDiff(pack1, pack2)
+	util.ClassifyMultiVersionChanges(util.PackageVersionScheme(diffType), diff.InfoDiff)
 	return &util.MultiVersionPackageDiffResult{
 		Image1:   image1.Source,
 		Image2:   image2.Source,
-		DiffType: strings.TrimSuffix(differ.Name(), "Analyzer"),
+		DiffType: diffType,
 		Diff:     diff,
 	}, nil
 }
 
 func singleVersionDiff(image1, image2 pkgutil.Image, differ SingleVersionPackageAnalyzer) (*util.SingleVersionPackageDiffResult, error) {
-	pack1, err := differ.getPackages(image1)
+	pack1, err := singleVersionPackages(image1, differ)
 	if err != nil {
 		return &util.SingleVersionPackageDiffResult{}, err
 	}
-	pack2, err := differ.getPackages(image2)
+	pack2, err := singleVersionPackages(image2, differ)
 	if err != nil {
 		return &util.SingleVersionPackageDiffResult{}, err
 	}
 
+	diffType := strings.TrimSuffix(differ.Name(), "Analyzer")
 	diff := util.GetMapDiff(pack1, pack2)
+	util.ClassifyVersionChanges(util.PackageVersionScheme(diffType), diff.InfoDiff)
 	return &util.SingleVersionPackageDiffResult{
 		Image1:   image1.Source,
 		Image2:   image2.Source,
-		DiffType: strings.TrimSuffix(differ.Name(), "Analyzer"),
+		DiffType: diffType,
 		Diff:     diff,
 	}, nil
 }
@@ -85,8 +134,32 @@ func singleVersionLayerDiff(image1, image2 pkgutil.Image, differ SingleVersionPa
 	return &util.SingleVersionPackageLayerDiffResult{}, errors.New("Diff for packages on layers is not supported, only analysis is supported")
 }
 
+// PackageAnalyzeTypes lists, sorted, the AnalyzeType of the results of
+// the package analyzers, e.g. Apt or Pip.
+func PackageAnalyzeTypes() []string {
+	types := []string{}
+	for _, analyzer := range Analyzers {
+		switch analyzer.(type) {
+		case SingleVersionPackageAnalyzer, MultiVersionPackageAnalyzer:
+			types = append(types, strings.TrimSuffix(analyzer.Name(), "Analyzer"))
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IsPackageAnalyzer reports whether the analyzer registered as name diffs
+// package versions, as apt or pip do.
+func IsPackageAnalyzer(name string) bool {
+	switch Analyzers[name].(type) {
+	case SingleVersionPackageAnalyzer, MultiVersionPackageAnalyzer:
+		return true
+	}
+	return false
+}
+
 func multiVersionAnalysis(image pkgutil.Image, analyzer MultiVersionPackageAnalyzer) (*util.MultiVersionPackageAnalyzeResult, error) {
-	pack, err := analyzer.getPackages(image)
+	pack, err := multiVersionPackages(image, analyzer)
 	if err != nil {
 		return &util.MultiVersionPackageAnalyzeResult{}, err
 	}
@@ -100,7 +173,7 @@ func multiVersionAnalysis(image pkgutil.Image, analyzer MultiVersionPackageAnaly
 }
 
 func singleVersionAnalysis(image pkgutil.Image, analyzer SingleVersionPackageAnalyzer) (*util.SingleVersionPackageAnalyzeResult, error) {
-	pack, err := analyzer.getPackages(image)
+	pack, err := singleVersionPackages(image, analyzer)
 	if err != nil {
 		return &util.SingleVersionPackageAnalyzeResult{}, err
 	}
@@ -120,6 +193,9 @@ func singleVersionLayerAnalysis(image pkgutil.Image, analyzer SingleVersionPacka
 	if err != nil {
 		return &util.SingleVersionPackageLayerAnalyzeResult{}, err
 	}
+	for _, layerPackages := range pack {
+		filterPackages(analyzer.Name(), analyzer.options(), layerPackages)
+	}
 	var pkgDiffs []util.PackageDiff
 
 	// Each layer with modified packages includes a complete list of packages
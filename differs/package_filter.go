@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// packageGlob is a glob of package names and the analyzer type it is
+// restricted to, if any.
+type packageGlob struct {
+	analyzer string
+	glob     string
+}
+
+// ValidatePackageGlobs checks the globs of IgnorePackages and OnlyPackages.
+func (o AnalyzerOptions) ValidatePackageGlobs() error {
+	for _, glob := range append(parsePackageGlobs(o.IgnorePackages), parsePackageGlobs(o.OnlyPackages)...) {
+		if _, err := path.Match(glob.glob, ""); err != nil {
+			return fmt.Errorf("invalid package glob %q: %s", glob.glob, err)
+		}
+	}
+	return nil
+}
+
+// parsePackageGlobs splits the analyzer type off globs. Prefixes which
+// aren't analyzer types are part of the glob, as in the group:artifact
+// names of jars.
+func parsePackageGlobs(globs []string) []packageGlob {
+	parsed := []packageGlob{}
+	for _, glob := range globs {
+		if i := strings.Index(glob, ":"); i > 0 {
+			if _, ok := Analyzers[glob[:i]]; ok {
+				parsed = append(parsed, packageGlob{analyzer: glob[:i], glob: glob[i+1:]})
+				continue
+			}
+		}
+		parsed = append(parsed, packageGlob{glob: glob})
+	}
+	return parsed
+}
+
+// packageFilter returns which packages the named analyzer reports given
+// opts, or nil if it reports them all.
+func packageFilter(analyzerName string, opts AnalyzerOptions) func(string) bool {
+	analyzerType := ""
+	for t, analyzer := range Analyzers {
+		if analyzer.Name() == analyzerName {
+			analyzerType = t
+		}
+	}
+	ignore := packageGlobsFor(parsePackageGlobs(opts.IgnorePackages), analyzerType)
+	only := packageGlobsFor(parsePackageGlobs(opts.OnlyPackages), analyzerType)
+	if len(ignore) == 0 && len(only) == 0 {
+		return nil
+	}
+	return func(name string) bool {
+		if matchesPackageGlob(ignore, name) {
+			return false
+		}
+		return len(only) == 0 || matchesPackageGlob(only, name)
+	}
+}
+
+func packageGlobsFor(globs []packageGlob, analyzerType string) []string {
+	applying := []string{}
+	for _, glob := range globs {
+		if glob.analyzer == "" || glob.analyzer == analyzerType {
+			applying = append(applying, glob.glob)
+		}
+	}
+	return applying
+}
+
+func matchesPackageGlob(globs []string, name string) bool {
+	for _, glob := range globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPackages removes the packages the named analyzer doesn't report.
+func filterPackages(analyzerName string, opts AnalyzerOptions, packages map[string]util.PackageInfo) {
+	keep := packageFilter(analyzerName, opts)
+	if keep == nil {
+		return
+	}
+	for name := range packages {
+		if !keep(name) {
+			delete(packages, name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// fakePackageAnalyzer lists fixed packages under the name of a real
+// analyzer, so the globs of its type apply.
+type fakePackageAnalyzer struct {
+	name     string
+	packages map[string]util.PackageInfo
+	opts     AnalyzerOptions
+}
+
+func (a fakePackageAnalyzer) Name() string {
+	return a.name
+}
+
+func (a fakePackageAnalyzer) options() AnalyzerOptions {
+	return a.opts
+}
+
+func (a fakePackageAnalyzer) getPackages(image pkgutil.Image) (map[string]util.PackageInfo, error) {
+	return a.packages, nil
+}
+
+func TestPackageFilter(t *testing.T) {
+	packages := map[string]util.PackageInfo{
+		"tzdata":          {Version: "2021a"},
+		"ca-certificates": {Version: "20210119"},
+		"libssl1.1":       {Version: "1.1.1k"},
+		"openssl":         {Version: "1.1.1k"},
+	}
+	apt := fakePackageAnalyzer{name: "AptAnalyzer", packages: packages}
+	pip := fakePackageAnalyzer{name: "PipAnalyzer", packages: packages}
+
+	tests := []struct {
+		name     string
+		ignore   []string
+		only     []string
+		analyzer fakePackageAnalyzer
+		expected []string
+	}{
+		{
+			name:     "no globs",
+			analyzer: apt,
+			expected: []string{"ca-certificates", "libssl1.1", "openssl", "tzdata"},
+		},
+		{
+			name:     "ignored packages",
+			ignore:   []string{"tzdata", "ca-*"},
+			analyzer: apt,
+			expected: []string{"libssl1.1", "openssl"},
+		},
+		{
+			name:     "only packages, less ignored ones",
+			ignore:   []string{"openssl"},
+			only:     []string{"*ssl*"},
+			analyzer: apt,
+			expected: []string{"libssl1.1"},
+		},
+		{
+			name:     "globs of the analyzer type",
+			ignore:   []string{"apt:tzdata", "pip:openssl"},
+			only:     []string{"pip:lib*"},
+			analyzer: apt,
+			expected: []string{"ca-certificates", "libssl1.1", "openssl"},
+		},
+		{
+			name:     "globs of another analyzer type",
+			ignore:   []string{"apt:tzdata", "pip:openssl"},
+			only:     []string{"apt:lib*"},
+			analyzer: pip,
+			expected: []string{"ca-certificates", "libssl1.1", "tzdata"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analyzer := test.analyzer
+			analyzer.opts = AnalyzerOptions{IgnorePackages: test.ignore, OnlyPackages: test.only}
+			if err := analyzer.opts.ValidatePackageGlobs(); err != nil {
+				t.Fatalf("Error validating globs: %s", err)
+			}
+			kept, err := singleVersionPackages(pkgutil.Image{}, analyzer)
+			if err != nil {
+				t.Fatalf("Error getting packages: %s", err)
+			}
+			names := []string{}
+			for _, name := range test.expected {
+				if _, ok := kept[name]; ok {
+					names = append(names, name)
+				}
+			}
+			if len(kept) != len(test.expected) || !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("Expected packages %v but got %v", test.expected, kept)
+			}
+		})
+	}
+
+	if err := (AnalyzerOptions{IgnorePackages: []string{"lib[ssl"}}).ValidatePackageGlobs(); err == nil {
+		t.Errorf("Expected an error for a malformed glob but got none")
+	}
+	// prefixes other than analyzer types are part of the glob
+	if globs := parsePackageGlobs([]string{"com.google.guava:*", "jar:guava"}); !reflect.DeepEqual(globs, []packageGlob{{glob: "com.google.guava:*"}, {analyzer: "jar", glob: "guava"}}) {
+		t.Errorf("Expected only analyzer types to be split off but got %v", globs)
+	}
+}
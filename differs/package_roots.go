@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxSymlinks bounds the symlinks followed resolving a path, as in Linux.
+const maxSymlinks = 40
+
+// packageRoots resolves the image paths of the package roots below root,
+// dropping those missing or reached through a symlink with
+// opts.SkipPackageSymlinks. Roots resolving to the same directory are listed
+// once.
+func packageRoots(root string, roots []string, opts AnalyzerOptions) []string {
+	resolved := []string{}
+	seen := map[string]bool{}
+	for _, r := range roots {
+		p, ok := resolveSymlinks(root, r, !opts.SkipPackageSymlinks)
+		if !ok || seen[p] {
+			continue
+		}
+		if p != path.Clean("/"+r) {
+			logrus.Debugf("package root %s resolved to %s", r, p)
+		}
+		seen[p] = true
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
+
+// resolveInImage resolves the symlinks of the image path p within root, so
+// that absolute targets don't escape the image filesystem. It returns false
+// if p doesn't exist.
+func resolveInImage(root, p string) (string, bool) {
+	return resolveSymlinks(root, p, true)
+}
+
+// resolveSymlinks resolves p as resolveInImage does, but returns false if p
+// goes through a symlink and follow is false.
+func resolveSymlinks(root, p string, follow bool) (string, bool) {
+	resolved := "/"
+	remaining := strings.Split(path.Clean("/"+p), "/")
+	for links := 0; len(remaining) > 0; {
+		part := remaining[0]
+		remaining = remaining[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			return "", false
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if !follow || links == maxSymlinks {
+			return "", false
+		}
+		links++
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", false
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return resolved, true
+}
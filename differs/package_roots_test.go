@@ -0,0 +1,73 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestPackageRoots(t *testing.T) {
+	fs := difftest.NewFS(t).
+		File("opt/python/lib/python3.9/site-packages/requests-2.31.0.dist-info/METADATA", "Name: requests\nVersion: 2.31.0\n").
+		Symlink("usr/lib/python3.9", "/opt/python/lib/python3.9").
+		Symlink("usr/local/lib/python3.9", "../../lib/python3.9").
+		Symlink("loop", "loop")
+	roots := []string{"/usr/lib/python3.9/site-packages", "/usr/local/lib/python3.9/site-packages", "/missing", "/loop/x"}
+
+	if got, expected := packageRoots(fs.Root(), roots, AnalyzerOptions{}), []string{"/opt/python/lib/python3.9/site-packages"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected roots %v but got %v", expected, got)
+	}
+
+	if got := packageRoots(fs.Root(), roots, AnalyzerOptions{SkipPackageSymlinks: true}); len(got) != 0 {
+		t.Errorf("Expected no roots without following symlinks but got %v", got)
+	}
+}
+
+func TestExtraPackageRoots(t *testing.T) {
+	image := difftest.NewFS(t).
+		File("opt/venv/lib/python3.11/site-packages/flask-3.0.0.dist-info/METADATA", "Name: flask\nVersion: 3.0.0\n").
+		File("opt/venv/lib/python3.11/site-packages/flask/__init__.py", "").
+		File("app/node_modules/left-pad/package.json", `{"name": "left-pad", "version": "1.3.0"}`).
+		Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+
+	opts := &AnalyzerOptions{
+		PipRoots:  []string{"/opt/venv/lib/python3.11/site-packages"},
+		NodeRoots: []string{"/app/node_modules"},
+	}
+	packages, err := PipAnalyzer{Options: opts}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if info := packages["flask"]["/opt/venv/lib/python3.11/site-packages"]; info.Version != "3.0.0" {
+		t.Errorf("Expected flask 3.0.0 from --pip-root but got %v", packages)
+	}
+	packages, err = NodeAnalyzer{Options: opts}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]util.PackageInfo{"/app/node_modules/left-pad/": {Version: "1.3.0", Size: packages["left-pad"]["/app/node_modules/left-pad/"].Size}}
+	if !reflect.DeepEqual(packages["left-pad"], expected) {
+		t.Errorf("Expected left-pad 1.3.0 from --node-root but got %v", packages)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"errors"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// PermissionsAnalyzer compares the modes and owners of image files, warning
+// about setuid, setgid and world-writable files introduced by an image.
+type PermissionsAnalyzer struct {
+}
+
+func (a PermissionsAnalyzer) Name() string {
+	return "PermissionsAnalyzer"
+}
+
+// Diff compares the modes and owners of the files in both images.
+func (a PermissionsAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	inv1, err := getFileModes(image1)
+	if err != nil {
+		return &util.PermissionDiffResult{}, err
+	}
+	inv2, err := getFileModes(image2)
+	if err != nil {
+		return &util.PermissionDiffResult{}, err
+	}
+	return &util.PermissionDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Permissions",
+		Diff:     util.GetPermissionDiff(inv1, inv2),
+	}, nil
+}
+
+// Analyze lists the files of an image with risky permissions.
+func (a PermissionsAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	inv, err := getFileModes(image)
+	if err != nil {
+		return &util.PermissionAnalyzeResult{}, err
+	}
+	return &util.PermissionAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Permissions",
+		Analysis:    util.RiskyPermissions(inv),
+	}, nil
+}
+
+// getFileModes returns the modes and owners of the files of an image. They
+// are read from the layers, as extracting drops the ownership of files and,
+// in read-only mode, their write bits.
+func getFileModes(image pkgutil.Image) (pkgutil.FileInventory, error) {
+	if image.Inventory != nil {
+		return image.Inventory, nil
+	}
+	if image.Image == nil {
+		return nil, errors.New("no image to read file modes from")
+	}
+	return pkgutil.StreamFileModes(image.Image)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestPermissionsDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", Inventory: pkgutil.FileInventory{
+		"/usr":          {Name: "/usr", IsDir: true},
+		"/usr/bin":      {Name: "/usr/bin", IsDir: true, Mode: os.ModeDir | 0755},
+		"/usr/bin/ping": {Name: "/usr/bin/ping", Mode: 0755},
+		"/usr/bin/sudo": {Name: "/usr/bin/sudo", Mode: os.ModeSetuid | 0755},
+		"/etc/app.conf": {Name: "/etc/app.conf", Mode: 0644},
+		"/tmp":          {Name: "/tmp", IsDir: true, Mode: os.ModeDir | os.ModeSticky | 0777},
+		"/bin/sh":       {Name: "/bin/sh", Mode: os.ModeSymlink | 0777, Linkname: "dash"},
+	}}
+	image2 := pkgutil.Image{Source: "image2", Inventory: pkgutil.FileInventory{
+		"/usr":          {Name: "/usr", IsDir: true, Mode: os.ModeDir | 0755},
+		"/usr/bin":      {Name: "/usr/bin", IsDir: true, Mode: os.ModeDir | 0755},
+		"/usr/bin/ping": {Name: "/usr/bin/ping", Mode: os.ModeSetuid | 0755},
+		"/usr/bin/sudo": {Name: "/usr/bin/sudo", Mode: os.ModeSetuid | 0755},
+		"/etc/app.conf": {Name: "/etc/app.conf", Mode: 0644, Uid: 1000, Gid: 1000},
+		"/tmp":          {Name: "/tmp", IsDir: true, Mode: os.ModeDir | os.ModeSticky | 0777},
+		"/bin/sh":       {Name: "/bin/sh", Mode: os.ModeSymlink | 0777, Linkname: "bash"},
+		"/srv/uploads":  {Name: "/srv/uploads", IsDir: true, Mode: os.ModeDir | 0777},
+		"/usr/bin/wall": {Name: "/usr/bin/wall", Mode: os.ModeSetgid | 0750, Gid: 5},
+	}}
+
+	result, err := PermissionsAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.PermissionDiffResult).Diff.(util.PermissionDiff)
+	expected := util.PermissionDiff{
+		Changes: []util.PermissionChange{
+			{Name: "/etc/app.conf", Mode1: "-rw-r--r--", Mode2: "-rw-r--r--", Owner1: "0:0", Owner2: "1000:1000", Flags: []string{}},
+			{Name: "/usr/bin/ping", Mode1: "-rwxr-xr-x", Mode2: "-rwsr-xr-x", Owner1: "0:0", Owner2: "0:0", Flags: []string{util.PermSetuid}},
+		},
+		Warnings: []string{
+			"/srv/uploads was added world-writable",
+			"/usr/bin/ping became setuid",
+			"/usr/bin/wall was added setgid",
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+
+	result, err = PermissionsAnalyzer{}.Analyze(image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	analysis := result.(*util.PermissionAnalyzeResult).Analysis.([]util.FilePermission)
+	expectedFiles := []util.FilePermission{
+		{Name: "/srv/uploads", Mode: "drwxrwxrwx", Flags: []string{util.PermWorldWritable}},
+		{Name: "/usr/bin/ping", Mode: "-rwsr-xr-x", Flags: []string{util.PermSetuid}},
+		{Name: "/usr/bin/sudo", Mode: "-rwsr-xr-x", Flags: []string{util.PermSetuid}},
+		{Name: "/usr/bin/wall", Mode: "-rwxr-s---", Gid: 5, Flags: []string{util.PermSetgid}},
+	}
+	if !reflect.DeepEqual(analysis, expectedFiles) {
+		t.Errorf("Expected: %+v but got: %+v", expectedFiles, analysis)
+	}
+}
+
+func TestFormatMode(t *testing.T) {
+	for mode, expected := range map[os.FileMode]string{
+		0644:                                 "-rw-r--r--",
+		os.ModeDir | os.ModeSticky | 0777:    "drwxrwxrwt",
+		os.ModeSetuid | 0644:                 "-rwSr--r--",
+		os.ModeSymlink | 0777:                "lrwxrwxrwx",
+		os.ModeDevice | os.ModeCharDevice:    "c---------",
+		os.ModeSetgid | os.ModeSetuid | 0711: "-rws--s--x",
+	} {
+		if actual := util.FormatMode(mode); actual != expected {
+			t.Errorf("Expected %v to be formatted as %s but got %s", mode, expected, actual)
+		}
+	}
+}
@@ -30,6 +30,17 @@ import (
 )
 
 type PipAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a PipAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a PipAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
 }
 
 func (a PipAnalyzer) Name() string {
@@ -61,20 +72,17 @@ func (a PipAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]uti
 			pythonPaths = append(pythonPaths, p)
 		}
 	}
-	pythonVersions, err := getPythonVersion(path)
-	if err != nil {
-		// Image doesn't have Python installed
-		return packages, nil
+	// pex, shiv and zipapp bundles carry their own dependencies
+	if err := readPythonBundles(path, packages); err != nil {
+		logrus.Warnf("Could not read Python application bundles: %s", err)
 	}
-
-	// default python package installation directories in unix
-	// these are hardcoded in the python source; unfortunately no way to retrieve them from env
-	for _, pythonVersion := range pythonVersions {
-		pythonPaths = append(pythonPaths, filepath.Join(path, "usr/lib", pythonVersion))
-		pythonPaths = append(pythonPaths, filepath.Join(path, "usr/lib", pythonVersion, "dist-packages"))
-		pythonPaths = append(pythonPaths, filepath.Join(path, "usr/lib", pythonVersion, "site-packages"))
-		pythonPaths = append(pythonPaths, filepath.Join(path, "usr/local/lib", pythonVersion, "dist-packages"))
-		pythonPaths = append(pythonPaths, filepath.Join(path, "usr/local/lib", pythonVersion, "site-packages"))
+	// default python package installation directories in unix, and those of
+	// other interpreters and of virtualenvs, each diffed by its path
+	opts := a.options()
+	roots := pythonPackageRoots(path)
+	roots = append(roots, opts.PipRoots...)
+	for _, root := range packageRoots(path, roots, opts) {
+		pythonPaths = append(pythonPaths, filepath.Join(path, root))
 	}
 
 	for _, pythonPath := range pythonPaths {
@@ -83,13 +91,22 @@ func (a PipAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]uti
 			// python version folder doesn't have a site-packages folder
 			continue
 		}
+		mapPath := strings.Replace(pythonPath, path, "", 1)
+		// top-level modules installed by the distributions found
+		claimed := map[string]bool{}
 
 		for i := 0; i < len(contents); i++ {
 			c := contents[i]
 			fileName := c.Name()
 			var metadata *os.File
 			var err error
-			if strings.HasSuffix(fileName, "egg-info") {
+			if strings.HasSuffix(fileName, ".egg") {
+				// egg installed by setup.py install or easy_install
+				if packageName, version, size := readEgg(filepath.Join(pythonPath, fileName), c); packageName != "" {
+					addToMap(packages, packageName, mapPath, util.PackageInfo{Version: version, Size: size})
+				}
+				continue
+			} else if strings.HasSuffix(fileName, "egg-info") {
 				// wheel directory
 				metadata, err = os.Open(filepath.Join(pythonPath, fileName, "PKG-INFO"))
 				if err != nil {
@@ -140,6 +157,10 @@ func (a PipAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]uti
 				}
 			}
 
+			for _, module := range installedModules(filepath.Join(pythonPath, fileName), packageName) {
+				claimed[module] = true
+			}
+
 			// First, try and use the "top_level.txt",
 			// Many egg packages contains a "top_level.txt" file describing the directories containing the
 			// required code. Combining the sizes of each of these directories should give the total size.
@@ -176,9 +197,11 @@ func (a PipAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]uti
 			}
 
 			currPackage := util.PackageInfo{Version: version, Size: size}
-			mapPath := strings.Replace(pythonPath, path, "", 1)
 			addToMap(packages, packageName, mapPath, currPackage)
 		}
+		if isSitePackages(pythonPath) {
+			readUnmanagedModules(pythonPath, mapPath, contents, claimed, packages)
+		}
 	}
 
 	return packages, nil
@@ -197,22 +220,9 @@ func addToMap(packages map[string]map[string]util.PackageInfo, pack string, path
 
 func getPythonVersion(pathToLayer string) ([]string, error) {
 	matches := []string{}
-	pattern := regexp.MustCompile("^python[0-9]+\\.[0-9]+$")
-
 	libPaths := []string{"usr/local/lib", "usr/lib"}
 	for _, lp := range libPaths {
-		libPath := filepath.Join(pathToLayer, lp)
-		libContents, err := ioutil.ReadDir(libPath)
-		if err != nil {
-			logrus.Debugf("Could not find %s to determine Python version", err)
-			continue
-		}
-		for _, file := range libContents {
-			match := pattern.FindString(file.Name())
-			if match != "" {
-				matches = append(matches, match)
-			}
-		}
+		matches = append(matches, pythonLibVersions(filepath.Join(pathToLayer, lp))...)
 	}
 	return matches, nil
 }
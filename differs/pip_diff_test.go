@@ -17,9 +17,12 @@ limitations under the License.
 package differs
 
 import (
+	"archive/zip"
+	"bytes"
 	"reflect"
 	"testing"
 
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
 	"github.com/google/go-containerregistry/pkg/v1"
@@ -172,3 +175,122 @@ func TestGetPythonPackages(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPythonPackagesWithoutPipMetadata(t *testing.T) {
+	var egg bytes.Buffer
+	writer := zip.NewWriter(&egg)
+	for name, contents := range map[string]string{
+		"EGG-INFO/PKG-INFO":  "Metadata-Version: 1.1\nName: zipped\nVersion: 0.3\n",
+		"zipped/__init__.py": "",
+	} {
+		f, _ := writer.Create(name)
+		f.Write([]byte(contents))
+	}
+	writer.Close()
+
+	site := "usr/local/lib/python3.11/site-packages/"
+	dist := "usr/lib/python3/dist-packages/"
+	image := difftest.NewFS(t).
+		Dir("usr/lib/python3.11").
+		// Debian python3-* packages, any python version
+		File(dist+"requests-2.28.1.egg-info/PKG-INFO", "Name: requests\nVersion: 2.28.1\n").
+		File(dist+"requests/__init__.py", "__version__ = '2.28.1'\n").
+		File(dist+"apt_pkg.cpython-311-x86_64-linux-gnu.so", "ELF").
+		// setup.py install
+		File(site+"legacy-1.2-py3.11.egg/EGG-INFO/PKG-INFO", "Name: legacy\nVersion: 1.2\n").
+		File(site+"legacy-1.2-py3.11.egg/legacy/__init__.py", "").
+		File(site+"noinfo-0.9-py3.11.egg/noinfo.py", "").
+		File(site+"zipped-0.3-py3.11.egg", egg.String()).
+		// a wheel installing a module of another name
+		File(site+"PyYAML-6.0.dist-info/METADATA", "Name: PyYAML\nVersion: 6.0\n").
+		File(site+"PyYAML-6.0.dist-info/top_level.txt", "_yaml\nyaml\n").
+		File(site+"yaml/__init__.py", "__version__ = '6.0'\n").
+		// copied in without any metadata
+		File(site+"vendored/__init__.py", "\"\"\"Vendored.\"\"\"\n__version__ = \"1.4.2\"\n").
+		File(site+"helper.py", "").
+		File(site+"_private.py", "").
+		File(site+"data/file.txt", "").
+		File(site+"easy-install.pth", "./legacy-1.2-py3.11.egg\n").
+		Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+
+	packages, err := PipAnalyzer{}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expectedVersions := map[string]string{
+		"requests": "2.28.1",
+		"apt_pkg":  "",
+		"legacy":   "1.2",
+		"noinfo":   "0.9",
+		"zipped":   "0.3",
+		"PyYAML":   "6.0",
+		"vendored": "1.4.2",
+		"helper":   "",
+	}
+	versions := map[string]string{}
+	for name, installations := range packages {
+		if len(installations) != 1 {
+			t.Errorf("Expected one installation of %s, got %v", name, installations)
+		}
+		for _, info := range installations {
+			versions[name] = info.Version
+		}
+	}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("Expected packages %v but got %v", expectedVersions, versions)
+	}
+	if _, ok := packages["requests"]["/usr/lib/python3/dist-packages"]; !ok {
+		t.Errorf("Expected requests in /usr/lib/python3/dist-packages, got %v", packages["requests"])
+	}
+}
+
+func TestGetPythonPackagesPerInterpreter(t *testing.T) {
+	dist := func(site, name, version string) (string, string) {
+		return site + "/" + name + "-" + version + ".dist-info/METADATA", "Name: " + name + "\nVersion: " + version + "\n"
+	}
+	fs := difftest.NewFS(t)
+	for _, p := range [][]string{
+		{"usr/lib/python2.7/dist-packages", "six", "1.10.0"},
+		{"usr/local/lib/python3.11/site-packages", "six", "1.16.0"},
+		{"opt/python/cp312-cp312/lib/python3.12/site-packages", "six", "1.16.0"},
+		{"opt/conda/lib/python3.10/site-packages", "numpy", "1.26.4"},
+		{"opt/conda/envs/ml/lib/python3.9/site-packages", "numpy", "1.21.0"},
+		{"app/.venv/lib/python3.11/site-packages", "django", "4.2"},
+	} {
+		fs.File(dist(p[0], p[1], p[2]))
+		fs.File(p[0]+"/"+p[1]+"/__init__.py", "")
+	}
+	image := fs.File("app/.venv/pyvenv.cfg", "home = /usr/local/bin\n").
+		// not a virtualenv, and not an interpreter prefix
+		File("srv/lib/python3.11/site-packages/flask-3.0.dist-info/METADATA", "Name: flask\nVersion: 3.0\n").
+		Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+
+	packages, err := PipAnalyzer{}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]string{
+		"six": {
+			"/usr/lib/python2.7/dist-packages":                     "1.10.0",
+			"/usr/local/lib/python3.11/site-packages":              "1.16.0",
+			"/opt/python/cp312-cp312/lib/python3.12/site-packages": "1.16.0",
+		},
+		"numpy": {
+			"/opt/conda/lib/python3.10/site-packages":        "1.26.4",
+			"/opt/conda/envs/ml/lib/python3.9/site-packages": "1.21.0",
+		},
+		"django": {"/app/.venv/lib/python3.11/site-packages": "4.2"},
+	}
+	versions := map[string]map[string]string{}
+	for name, installations := range packages {
+		versions[name] = map[string]string{}
+		for p, info := range installations {
+			versions[name][p] = info.Version
+		}
+	}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected packages %v but got %v", expected, versions)
+	}
+}
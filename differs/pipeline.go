@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+// Dependent is implemented by analyzers which build on the intermediate
+// inventories of other analyzers. Dependencies returns the names of those
+// analyzers, as accepted by --type.
+type Dependent interface {
+	Dependencies() []string
+}
+
+// resolveDependencies orders analyzers so that every analyzer runs after the
+// requested analyzers it depends on, which lets the dependent reuse their
+// cached inventories. Dependencies which were not requested are computed on
+// demand by the dependent and are not added to the results.
+func resolveDependencies(analyzers []Analyzer) ([]Analyzer, error) {
+	typeNames := map[string]string{}
+	for typeName, a := range Analyzers {
+		typeNames[a.Name()] = typeName
+	}
+	requested := map[string]Analyzer{}
+	for _, a := range analyzers {
+		if typeName, ok := typeNames[a.Name()]; ok {
+			requested[typeName] = a
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	ordered := []Analyzer{}
+	var visit func(typeName string, path []string) error
+	visit = func(typeName string, path []string) error {
+		switch state[typeName] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("analyzer dependency cycle: %s", strings.Join(append(path, typeName), " -> "))
+		}
+		a, exists := Analyzers[typeName]
+		if !exists {
+			return fmt.Errorf("unknown analyzer %s required by %s", typeName, path[len(path)-1])
+		}
+		state[typeName] = visiting
+		if dependent, ok := a.(Dependent); ok {
+			for _, dep := range dependent.Dependencies() {
+				if err := visit(dep, append(path, typeName)); err != nil {
+					return err
+				}
+			}
+		}
+		state[typeName] = visited
+		if r, ok := requested[typeName]; ok {
+			ordered = append(ordered, r)
+		}
+		return nil
+	}
+
+	for _, a := range analyzers {
+		typeName, ok := typeNames[a.Name()]
+		if !ok {
+			// not a registered analyzer, so nothing can depend on it
+			ordered = append(ordered, a)
+			continue
+		}
+		if err := visit(typeName, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// inventoryCache memoizes intermediate results, such as parsed package
+// databases, per extracted image for as long as a request holds the image.
+// Outside of a request nothing is cached.
+type inventoryCache struct {
+	mu     sync.Mutex
+	images map[string]*imageInventory
+}
+
+type imageInventory struct {
+	refs    int
+	entries map[string]*inventoryEntry
+}
+
+type inventoryEntry struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+var inventories = &inventoryCache{images: map[string]*imageInventory{}}
+
+func (c *inventoryCache) acquire(image pkgutil.Image) {
+	if image.FSPath == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	inv, ok := c.images[image.FSPath]
+	if !ok {
+		inv = &imageInventory{entries: map[string]*inventoryEntry{}}
+		c.images[image.FSPath] = inv
+	}
+	inv.refs++
+}
+
+func (c *inventoryCache) release(image pkgutil.Image) {
+	if image.FSPath == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	inv, ok := c.images[image.FSPath]
+	if !ok {
+		return
+	}
+	inv.refs--
+	if inv.refs <= 0 {
+		delete(c.images, image.FSPath)
+	}
+}
+
+// get returns the inventory stored under key for image, calling compute the
+// first time it is requested. Callers must not modify the returned value.
+func (c *inventoryCache) get(image pkgutil.Image, key string, compute func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	inv, ok := c.images[image.FSPath]
+	if !ok || image.FSPath == "" {
+		c.mu.Unlock()
+		return compute()
+	}
+	entry, ok := inv.entries[key]
+	if !ok {
+		entry = &inventoryEntry{}
+		inv.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = compute()
+	})
+	return entry.value, entry.err
+}
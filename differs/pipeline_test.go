@@ -0,0 +1,179 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+type fakeDependentAnalyzer struct {
+	name string
+	deps []string
+}
+
+func (a fakeDependentAnalyzer) Name() string {
+	return a.name
+}
+
+func (a fakeDependentAnalyzer) Dependencies() []string {
+	return a.deps
+}
+
+func (a fakeDependentAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	return nil, nil
+}
+
+func (a fakeDependentAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	return nil, nil
+}
+
+func TestResolveDependencies(t *testing.T) {
+	first := fakeDependentAnalyzer{name: "FirstAnalyzer", deps: []string{"apt"}}
+	second := fakeDependentAnalyzer{name: "SecondAnalyzer", deps: []string{"first"}}
+	loopA := fakeDependentAnalyzer{name: "LoopAAnalyzer", deps: []string{"loopb"}}
+	loopB := fakeDependentAnalyzer{name: "LoopBAnalyzer", deps: []string{"loopa"}}
+	broken := fakeDependentAnalyzer{name: "BrokenAnalyzer", deps: []string{"notthere"}}
+	for typeName, a := range map[string]Analyzer{"first": first, "second": second, "loopa": loopA, "loopb": loopB, "broken": broken} {
+		Analyzers[typeName] = a
+		defer delete(Analyzers, typeName)
+	}
+
+	tests := []struct {
+		name    string
+		input   []Analyzer
+		want    []Analyzer
+		wantErr bool
+	}{
+		{
+			name:  "dependencies run first",
+			input: []Analyzer{second, first, AptAnalyzer{}},
+			want:  []Analyzer{AptAnalyzer{}, first, second},
+		},
+		{
+			name:  "unrequested dependencies are not added",
+			input: []Analyzer{second},
+			want:  []Analyzer{second},
+		},
+		{
+			name:    "cycles are reported",
+			input:   []Analyzer{loopA},
+			wantErr: true,
+		},
+		{
+			name:    "unknown dependencies are reported",
+			input:   []Analyzer{broken},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDependencies(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveDependencies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInventoryReuse(t *testing.T) {
+	image := pkgutil.Image{FSPath: "testDirs/packageOne"}
+	calls := 0
+	compute := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	inventories.get(image, "test", compute)
+	inventories.get(image, "test", compute)
+	if calls != 2 {
+		t.Errorf("Expected inventory to be recomputed outside of a request, computed %d times", calls)
+	}
+
+	calls = 0
+	inventories.acquire(image)
+	inventories.get(image, "test", compute)
+	inventories.get(image, "test", compute)
+	inventories.release(image)
+	if calls != 1 {
+		t.Errorf("Expected inventory to be computed once per request, computed %d times", calls)
+	}
+}
+
+// blockingAnalyzer waits for the others started with it, recording how many
+// ran at once.
+type blockingAnalyzer struct {
+	name    string
+	fail    bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (a blockingAnalyzer) Name() string {
+	return a.name
+}
+
+func (a blockingAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	return a.Analyze(image1)
+}
+
+func (a blockingAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	a.started <- struct{}{}
+	<-a.release
+	if a.fail {
+		return nil, fmt.Errorf("%s failed", a.name)
+	}
+	return &util.SizeAnalyzeResult{Image: image.Source, AnalyzeType: a.name}, nil
+}
+
+func TestRunAnalyzersConcurrently(t *testing.T) {
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	analyzers := []Analyzer{
+		blockingAnalyzer{name: "AAnalyzer", started: started, release: release},
+		blockingAnalyzer{name: "BAnalyzer", started: started, release: release},
+		blockingAnalyzer{name: "CAnalyzer", fail: true, started: started, release: release},
+	}
+	done := make(chan map[string]util.Result)
+	go func() {
+		results, _ := SingleRequest{Image: pkgutil.Image{Source: "image"}, AnalyzeTypes: analyzers, Concurrency: 2}.GetAnalysis()
+		done <- results
+	}()
+
+	// two analyzers start together, and the third only once one finishes
+	<-started
+	<-started
+	select {
+	case <-started:
+		t.Fatal("Expected at most 2 analyzers to run at once")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-started
+	results := <-done
+	if len(results) != 2 || results["AAnalyzer"] == nil || results["BAnalyzer"] == nil {
+		t.Errorf("Expected the results of the analyzers which succeeded but got %v", results)
+	}
+}
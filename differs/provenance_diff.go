@@ -0,0 +1,130 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"io/ioutil"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/pkg/errors"
+)
+
+// ProvenanceAnalyzer checks the digests of an image and its layers against
+// the subjects and materials of its build attestations.
+type ProvenanceAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a ProvenanceAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a ProvenanceAnalyzer) Name() string {
+	return "ProvenanceAnalyzer"
+}
+
+// Diff annotates the layers of the second image with their provenance.
+func (a ProvenanceAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	analysis1, err := getProvenance(image1, analyzerOptions(a.Options))
+	if err != nil {
+		return &util.ProvenanceDiffResult{}, err
+	}
+	analysis2, err := getProvenance(image2, analyzerOptions(a.Options))
+	if err != nil {
+		return &util.ProvenanceDiffResult{}, err
+	}
+	return &util.ProvenanceDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Provenance",
+		Diff:     util.GetProvenanceDiff(analysis1, analysis2),
+	}, nil
+}
+
+func (a ProvenanceAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := getProvenance(image, analyzerOptions(a.Options))
+	if err != nil {
+		return &util.ProvenanceAnalyzeResult{}, err
+	}
+	return &util.ProvenanceAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Provenance",
+		Analysis:    analysis,
+	}, nil
+}
+
+func getProvenance(image pkgutil.Image, opts AnalyzerOptions) (util.ProvenanceAnalysis, error) {
+	attestation, err := getAttestation(image, opts.ProvenancePaths)
+	if err != nil {
+		return util.ProvenanceAnalysis{}, err
+	}
+	digests, diffIDs := []string{}, []string{}
+	if image.Image != nil {
+		layers, err := image.Image.Layers()
+		if err != nil {
+			return util.ProvenanceAnalysis{}, errors.Wrap(err, "reading image layers")
+		}
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			if err != nil {
+				return util.ProvenanceAnalysis{}, errors.Wrap(err, "reading layer digest")
+			}
+			diffID, err := layer.DiffID()
+			if err != nil {
+				return util.ProvenanceAnalysis{}, errors.Wrap(err, "reading layer diff ID")
+			}
+			digests = append(digests, digest.String())
+			diffIDs = append(diffIDs, diffID.String())
+		}
+	}
+	digest := ""
+	if image.Digest.Hex != "" {
+		digest = image.Digest.String()
+	}
+	return util.GetProvenanceAnalysis(attestation, digest, digests, diffIDs), nil
+}
+
+// getAttestation reads the files in paths, or the attestations the registry
+// holds for the image when none are given.
+func getAttestation(image pkgutil.Image, paths []string) (*util.Attestation, error) {
+	attestation := util.NewAttestation()
+	if len(paths) != 0 {
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, errors.Wrap(err, "reading attestation")
+			}
+			if err := attestation.Add(data); err != nil {
+				return nil, errors.Wrapf(err, "reading attestation %s", path)
+			}
+		}
+		return attestation, nil
+	}
+	attestations, err := pkgutil.GetReferrerAttestations(image.Source, image.Digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching attestations, pass them with --provenance instead")
+	}
+	for _, data := range attestations {
+		if err := attestation.Add(data); err != nil {
+			return nil, errors.Wrapf(err, "reading attestation for %s", image.Source)
+		}
+	}
+	return attestation, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// pythonBundleExtensions are the file extensions of pex, shiv and zipapp
+// bundles. Executable files starting with a python shebang are checked too,
+// as bundles are often installed without an extension.
+var pythonBundleExtensions = []string{".pex", ".pyz", ".pyzw", ".shiv"}
+
+// Kinds of Python application bundles.
+const (
+	bundlePex    = "pex"
+	bundleShiv   = "shiv"
+	bundleZipapp = "zipapp"
+)
+
+// readPythonBundles records the distributions embedded in the pex, shiv
+// and zipapp bundles below root in packages, keyed by the path of the
+// bundle.
+func readPythonBundles(root string, packages map[string]map[string]util.PackageInfo) error {
+	return pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if !info.Mode().IsRegular() || !isPythonBundleCandidate(file, info) {
+			return nil
+		}
+		reader, err := zip.OpenReader(file)
+		if err != nil {
+			// a python script rather than a bundle
+			return nil
+		}
+		defer reader.Close()
+		mapPath := path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(file, root)))
+		kind := pythonBundleKind(&reader.Reader)
+		if kind == "" {
+			return nil
+		}
+		logrus.Debugf("reading %s bundle %s", kind, mapPath)
+		for name, info := range readBundleDistributions(&reader.Reader, mapPath, 1) {
+			addToMap(packages, name, mapPath, info)
+		}
+		return nil
+	})
+}
+
+func isPythonBundleCandidate(file string, info os.FileInfo) bool {
+	for _, ext := range pythonBundleExtensions {
+		if strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	if info.Mode()&0111 == 0 {
+		return false
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(io.LimitReader(f, 128)).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	return strings.HasPrefix(line, "#!") && strings.Contains(line, "python")
+}
+
+// pythonBundleKind tells a pex, in which PEX-INFO describes the bundle, from
+// a shiv, which bootstraps from environment.json, and from a plain zipapp.
+// Archives without a __main__.py aren't runnable, and aren't bundles.
+func pythonBundleKind(reader *zip.Reader) string {
+	entries := map[string]bool{}
+	for _, file := range reader.File {
+		entries[file.Name] = true
+	}
+	switch {
+	case !entries["__main__.py"]:
+		return ""
+	case entries["PEX-INFO"]:
+		return bundlePex
+	case entries["environment.json"]:
+		return bundleShiv
+	}
+	return bundleZipapp
+}
+
+// readBundleDistributions reads the dist-info and egg-info metadata of the
+// distributions in a bundle: in .deps/ for pex, site-packages/ for shiv,
+// and wherever they were vendored for zipapps. Wheels kept zipped, as in
+// the packed layout of pex, are opened too.
+func readBundleDistributions(reader *zip.Reader, name string, depth int) map[string]util.PackageInfo {
+	distributions := map[string]util.PackageInfo{}
+	for _, file := range reader.File {
+		dir, base := path.Split(file.Name)
+		switch {
+		case (base == "METADATA" && strings.HasSuffix(dir, ".dist-info/")) || (base == "PKG-INFO" && strings.HasSuffix(dir, ".egg-info/")):
+			data, err := readZipEntry(file)
+			if err != nil {
+				logrus.Warnf("Could not read %s in %s: %s", file.Name, name, err)
+				continue
+			}
+			pkg, version := parsePythonMetadata(data)
+			if pkg == "" {
+				continue
+			}
+			distributions[pkg] = util.PackageInfo{Version: version, Size: distributionSize(reader, dir)}
+		case depth == 1 && strings.HasSuffix(base, ".whl") && !file.FileInfo().IsDir():
+			data, err := readZipEntry(file)
+			if err != nil {
+				logrus.Warnf("Could not read %s in %s: %s", file.Name, name, err)
+				continue
+			}
+			wheel, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				logrus.Warnf("Could not read wheel %s%s%s: %s", name, util.ArchiveSeparator, file.Name, err)
+				continue
+			}
+			for pkg, info := range readBundleDistributions(wheel, name+util.ArchiveSeparator+file.Name, depth+1) {
+				info.Size = int64(file.UncompressedSize64)
+				distributions[pkg] = info
+			}
+		}
+	}
+	return distributions
+}
+
+// parsePythonMetadata reads the name and version from the headers of a
+// METADATA or PKG-INFO file.
+func parsePythonMetadata(data []byte) (string, string) {
+	var name, version string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// the description follows the headers
+			break
+		}
+		if strings.HasPrefix(line, "Name: ") {
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Name: "))
+		} else if strings.HasPrefix(line, "Version: ") {
+			version = strings.TrimSpace(strings.TrimPrefix(line, "Version: "))
+		}
+	}
+	return name, version
+}
+
+// distributionSize sums the sizes of the files a distribution installed, as
+// listed in the RECORD of its metadata directory. Without one, the size of
+// the metadata directory is all that is known.
+func distributionSize(reader *zip.Reader, metadataDir string) int64 {
+	site := path.Dir(strings.TrimSuffix(metadataDir, "/"))
+	if site == "." {
+		site = ""
+	} else {
+		site += "/"
+	}
+	var size, metadataSize int64
+	recorded := false
+	for _, file := range reader.File {
+		if strings.HasPrefix(file.Name, metadataDir) {
+			metadataSize += int64(file.UncompressedSize64)
+		}
+		if file.Name != metadataDir+"RECORD" {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			continue
+		}
+		recorded = true
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), ",")
+			if len(fields) < 3 {
+				continue
+			}
+			if n, err := strconv.ParseInt(fields[len(fields)-1], 10, 64); err == nil {
+				size += n
+			}
+		}
+	}
+	if !recorded {
+		return metadataSize
+	}
+	return size
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func distMetadata(name, version string) string {
+	return "Metadata-Version: 2.1\nName: " + name + "\nVersion: " + version + "\nSummary: test\n\nName: not a header\n"
+}
+
+func TestGetPythonBundlePackages(t *testing.T) {
+	shebang := "#!/usr/bin/env python3\n"
+	wheel := makeJar(t, map[string]string{
+		"six.py":                        "import sys",
+		"six-1.16.0.dist-info/METADATA": distMetadata("six", "1.16.0"),
+	})
+	pex := makeJar(t, map[string]string{
+		"__main__.py": "import pex",
+		"PEX-INFO":    `{"requirements": ["requests", "six"]}`,
+		".deps/requests-2.31.0-py3-none-any.whl/requests-2.31.0.dist-info/METADATA": distMetadata("requests", "2.31.0"),
+		".deps/requests-2.31.0-py3-none-any.whl/requests-2.31.0.dist-info/RECORD":   "requests/__init__.py,sha256=abc,120\nrequests/api.py,sha256=def,300\nrequests-2.31.0.dist-info/RECORD,,\n",
+		".deps/six-1.16.0-py2.py3-none-any.whl":                                     wheel,
+	})
+	shiv := makeJar(t, map[string]string{
+		"__main__.py":      "import _bootstrap",
+		"environment.json": `{"entry_point": "app:main"}`,
+		"site-packages/flask-3.0.0.dist-info/METADATA": distMetadata("Flask", "3.0.0"),
+	})
+	zipapp := makeJar(t, map[string]string{
+		"__main__.py":                           "import app",
+		"vendor/click-8.1.7.dist-info/METADATA": distMetadata("click", "8.1.7"),
+	})
+	library := makeJar(t, map[string]string{"attrs-23.1.0.dist-info/METADATA": distMetadata("attrs", "23.1.0")})
+
+	fs := difftest.NewFS(t).
+		File("/app/service.pex", shebang+pex).
+		Executable("/usr/local/bin/tool", shebang+shiv).
+		File("/opt/cli.pyz", zipapp).
+		File("/opt/lib.pyz", library).
+		Executable("/usr/local/bin/script", shebang+"print('hello')\n")
+	image := fs.Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+	packages, err := PipAnalyzer{}.getPackages(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]util.PackageInfo{
+		"requests": {"/app/service.pex": {Version: "2.31.0", Size: 420}},
+		"six":      {"/app/service.pex": {Version: "1.16.0", Size: int64(len(wheel))}},
+		"Flask":    {"/usr/local/bin/tool": {Version: "3.0.0", Size: int64(len(distMetadata("Flask", "3.0.0")))}},
+		"click":    {"/opt/cli.pyz": {Version: "8.1.7", Size: int64(len(distMetadata("click", "8.1.7")))}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, packages)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+var pythonVersionDir = regexp.MustCompile("^python[0-9]+\\.[0-9]+$")
+
+// pythonPrefixes are the image paths, as globs, of the prefixes Python
+// interpreters are commonly installed under besides /usr and /usr/local:
+// the builds of manylinux images, conda and its environments, and pyenv.
+var pythonPrefixes = []string{
+	"/opt/*",
+	"/opt/python/*",
+	"/opt/conda/envs/*",
+	"/root/.pyenv/versions/*",
+	"/usr/local/pyenv/versions/*",
+	"/opt/pyenv/versions/*",
+}
+
+// pythonEnvironmentSkipDirs aren't searched for virtualenvs, which aren't
+// created inside them, to keep the search short.
+var pythonEnvironmentSkipDirs = map[string]bool{
+	"site-packages": true,
+	"dist-packages": true,
+	"node_modules":  true,
+	"__pycache__":   true,
+	".git":          true,
+}
+
+// pythonPackageRoots returns the image paths of the directories of every
+// Python interpreter and environment of the image where packages are
+// installed: the site-packages and dist-packages of each Python version
+// in /usr/lib and /usr/local/lib, along with the standard library of the
+// system Python, in the prefixes of pythonPrefixes, and in the
+// virtualenvs found anywhere in the image.
+func pythonPackageRoots(root string) []string {
+	roots := []string{}
+	for _, lib := range []string{"/usr/lib", "/usr/local/lib"} {
+		for _, version := range pythonLibVersions(filepath.Join(root, lib)) {
+			if lib == "/usr/lib" {
+				roots = append(roots, path.Join(lib, version))
+			}
+			roots = append(roots, path.Join(lib, version, "dist-packages"), path.Join(lib, version, "site-packages"))
+		}
+	}
+	// where Debian and Ubuntu python3-* packages install, for all versions
+	roots = append(roots, "/usr/lib/python3/dist-packages")
+
+	prefixes := []string{}
+	for _, pattern := range pythonPrefixes {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			prefixes = append(prefixes, path.Clean("/"+filepath.ToSlash(strings.TrimPrefix(match, root))))
+		}
+	}
+	prefixes = append(prefixes, findVirtualenvs(root)...)
+	for _, prefix := range prefixes {
+		lib := path.Join(prefix, "lib")
+		for _, version := range pythonLibVersions(filepath.Join(root, lib)) {
+			roots = append(roots, path.Join(lib, version, "site-packages"))
+		}
+	}
+	return roots
+}
+
+// pythonLibVersions lists the pythonX.Y directories of the lib directory
+// of a prefix.
+func pythonLibVersions(lib string) []string {
+	versions := []string{}
+	contents, err := ioutil.ReadDir(lib)
+	if err != nil {
+		return versions
+	}
+	for _, file := range contents {
+		if pythonVersionDir.MatchString(file.Name()) {
+			versions = append(versions, file.Name())
+		}
+	}
+	return versions
+}
+
+// findVirtualenvs returns the image paths of the virtualenvs below root,
+// which are the directories holding a pyvenv.cfg.
+func findVirtualenvs(root string) []string {
+	venvs := []string{}
+	pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if info.IsDir() && pythonEnvironmentSkipDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() && info.Name() == "pyvenv.cfg" {
+			venv := path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(filepath.Dir(file), root)))
+			logrus.Debugf("found virtualenv %s", venv)
+			venvs = append(venvs, venv)
+		}
+		return nil
+	})
+	sort.Strings(venvs)
+	return venvs
+}
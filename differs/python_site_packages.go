@@ -0,0 +1,206 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// eggName matches the name and version of eggs installed by
+// `setup.py install` or easy_install, e.g. foo-1.0-py3.11.egg.
+var eggName = regexp.MustCompile(`^([A-Za-z0-9_.]+)-([^-]+)(-py[0-9.]+)?(-.+)?\.egg$`)
+
+// moduleVersion matches the __version__ assignment modules commonly make.
+var moduleVersion = regexp.MustCompile(`(?m)^__version__\s*(?::\s*str\s*)?=\s*['"]([^'"]+)['"]`)
+
+// moduleVersionLimit is how much of a module is searched for __version__.
+const moduleVersionLimit = 64 * 1024
+
+// isSitePackages reports whether dir is a site-packages or dist-packages
+// directory, whose top-level entries are all installed packages. Other
+// directories on the path, such as the standard library or an app's source
+// directory, aren't scanned for packages without metadata.
+func isSitePackages(dir string) bool {
+	base := filepath.Base(dir)
+	return base == "site-packages" || base == "dist-packages"
+}
+
+// readEgg reads the name and version of an egg, a directory or a zip file
+// holding a package along with its EGG-INFO. Without metadata, they are
+// taken from the name of the egg.
+func readEgg(file string, info os.FileInfo) (string, string, int64) {
+	var data []byte
+	var err error
+	if info.IsDir() {
+		data, err = ioutil.ReadFile(filepath.Join(file, "EGG-INFO", "PKG-INFO"))
+	} else {
+		data, err = readZipFile(file, "EGG-INFO/PKG-INFO")
+	}
+	if err == nil {
+		if name, version := parsePythonMetadata(data); name != "" {
+			return name, version, pkgutil.GetSize(file)
+		}
+	}
+	logrus.Debugf("unable to read PKG-INFO of egg %s: inferring package name", file)
+	if match := eggName.FindStringSubmatch(info.Name()); match != nil {
+		return match[1], match[2], pkgutil.GetSize(file)
+	}
+	return "", "", 0
+}
+
+func readZipFile(file, name string) ([]byte, error) {
+	reader, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		if f.Name == name {
+			return readZipEntry(f)
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// installedModules lists the top-level modules the distribution whose
+// dist-info or egg-info is metadataDir installed, from its top_level.txt,
+// the RECORD of wheels and the installed-files.txt of pip-installed
+// sdists, along with its normalized name.
+func installedModules(metadataDir, name string) []string {
+	modules := []string{normalizeModule(name)}
+	readLines := func(file string, module func(line string) string) {
+		f, err := os.Open(filepath.Join(metadataDir, file))
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if m := module(scanner.Text()); m != "" {
+				modules = append(modules, normalizeModule(m))
+			}
+		}
+	}
+	readLines("top_level.txt", strings.TrimSpace)
+	readLines("RECORD", func(line string) string {
+		if i := strings.Index(line, ","); i >= 0 {
+			line = line[:i]
+		}
+		return topLevelEntry(line)
+	})
+	// installed-files.txt paths are relative to the egg-info directory
+	readLines("installed-files.txt", func(line string) string {
+		return topLevelEntry(path.Join(path.Base(metadataDir), strings.TrimSpace(line)))
+	})
+	return modules
+}
+
+// topLevelEntry returns the module the site-packages relative path p
+// belongs to, if it isn't outside site-packages.
+func topLevelEntry(p string) string {
+	p = path.Clean(p)
+	if p == "." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+		return ""
+	}
+	return moduleName(strings.Split(p, "/")[0])
+}
+
+// moduleName returns the module an entry of site-packages provides: a
+// package directory, a .py file or an extension module such as
+// foo.cpython-311-x86_64-linux-gnu.so.
+func moduleName(entry string) string {
+	for _, ext := range []string{".py", ".so", ".pyd"} {
+		if strings.HasSuffix(entry, ext) {
+			return strings.SplitN(entry, ".", 2)[0]
+		}
+	}
+	return entry
+}
+
+// normalizeModule makes distribution and module names comparable, as the
+// Foo-Bar distribution usually installs the foo_bar module.
+func normalizeModule(name string) string {
+	return strings.Replace(strings.ToLower(name), "-", "_", -1)
+}
+
+// readUnmanagedModules records the top-level packages and modules of the
+// site-packages directory dir that no distribution's metadata claims, such
+// as those copied in by hand or installed by tools which don't leave any.
+// Their version is the __version__ they declare, if any.
+func readUnmanagedModules(dir, mapPath string, contents []os.FileInfo, claimed map[string]bool, packages map[string]map[string]util.PackageInfo) {
+	for _, c := range contents {
+		fileName := c.Name()
+		if strings.HasPrefix(fileName, "_") || strings.HasPrefix(fileName, ".") {
+			// private modules, such as _distutils_hack, and __pycache__
+			continue
+		}
+		file := filepath.Join(dir, fileName)
+		var source string
+		switch {
+		case c.IsDir():
+			source = filepath.Join(file, "__init__.py")
+			if _, err := os.Stat(source); err != nil {
+				// not a package, such as the data directories of packages
+				continue
+			}
+		case strings.HasSuffix(fileName, ".py"):
+			source = file
+		case strings.HasSuffix(fileName, ".so") || strings.HasSuffix(fileName, ".pyd"):
+			// extension modules have no source to read a version from
+		default:
+			continue
+		}
+		module := moduleName(fileName)
+		if claimed[normalizeModule(module)] {
+			continue
+		}
+		logrus.Debugf("found %s in %s without package metadata", module, mapPath)
+		info := util.PackageInfo{Size: pkgutil.GetSize(file)}
+		if source != "" {
+			info.Version = readModuleVersion(source)
+		}
+		addToMap(packages, module, mapPath, info)
+	}
+}
+
+func readModuleVersion(source string) string {
+	f, err := os.Open(source)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(f, moduleVersionLimit))
+	if err != nil {
+		return ""
+	}
+	if match := moduleVersion.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
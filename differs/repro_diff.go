@@ -0,0 +1,242 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// ReproAnalyzer reports what keeps an image from being rebuilt bit-for-bit.
+type ReproAnalyzer struct {
+}
+
+func (a ReproAnalyzer) Name() string {
+	return "ReproAnalyzer"
+}
+
+// Diff explains the differences between two builds of the same image.
+func (a ReproAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff := util.ReproDiff{Findings: []util.ReproFinding{}}
+	if image1.Digest.Hex != "" && image1.Digest == image2.Digest {
+		diff.Identical = true
+	} else {
+		findings := util.ReproFindings{}
+		if err := addConfigDiffs(image1, image2, findings); err != nil {
+			return &util.ReproDiffResult{}, err
+		}
+		files, err := diffImageFiles(image1.FSPath, image2.FSPath)
+		if err != nil {
+			return &util.ReproDiffResult{}, err
+		}
+		for _, entry := range files.Adds {
+			addChangedFile(findings, image2.FSPath, entry.Name, entry.Size)
+		}
+		for _, entry := range files.Dels {
+			addChangedFile(findings, image1.FSPath, entry.Name, entry.Size)
+		}
+		for _, entry := range files.Mods {
+			addChangedFile(findings, image2.FSPath, entry.Name, entry.Size2)
+		}
+		diff.Findings = findings.Sorted()
+	}
+	return &util.ReproDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Repro",
+		Diff:     diff,
+	}, nil
+}
+
+// Analyze reports the indicators of non-reproducibility in a single image.
+func (a ReproAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	findings := util.ReproFindings{}
+	if err := addConfigRisks(image, findings); err != nil {
+		return &util.ReproAnalyzeResult{}, err
+	}
+	dir, err := pkgutil.GetDirectory(image.FSPath, true)
+	if err != nil {
+		return &util.ReproAnalyzeResult{}, err
+	}
+	for _, name := range dir.Content {
+		issue, ok := util.ClassifyReproFile(name)
+		if !ok {
+			continue
+		}
+		path := filepath.Join(image.FSPath, name)
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if embedsTimestamp(issue, path) {
+			findings.Add(issue, name, info.Size())
+		}
+	}
+	return &util.ReproAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Repro",
+		Analysis:    findings.Sorted(),
+	}, nil
+}
+
+// addChangedFile records a file which differs between the builds. Directories
+// are skipped, their size changes are explained by the files below them.
+func addChangedFile(findings util.ReproFindings, root, name string, size int64) {
+	if info, err := os.Lstat(filepath.Join(root, name)); err == nil && info.IsDir() {
+		return
+	}
+	issue, ok := util.ClassifyReproFile(name)
+	if !ok {
+		issue = util.ReproOther
+	}
+	findings.Add(issue, name, size)
+}
+
+// embedsTimestamp checks the headers of gzip and Python bytecode files, which
+// only embed a timestamp in some modes. Other classified files always count.
+func embedsTimestamp(issue, path string) bool {
+	if issue != util.ReproGzip && issue != util.ReproPyc {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	if issue == util.ReproGzip {
+		if header[0] != 0x1f || header[1] != 0x8b {
+			return false
+		}
+		// MTIME is stored little endian in bytes 4-7, zero means unset
+		return header[4]|header[5]|header[6]|header[7] != 0
+	}
+	// Python 3.7 (magic 3390) added a flags word whose bit 0 marks hash-based
+	// bytecode; Python 2 magic numbers are far larger and always embed mtimes
+	magic := int(header[0]) | int(header[1])<<8
+	if magic < 3390 || magic >= 20000 {
+		return true
+	}
+	return header[4]&1 == 0
+}
+
+func configFile(image pkgutil.Image) (*v1.ConfigFile, error) {
+	if image.Image == nil {
+		return &v1.ConfigFile{}, nil
+	}
+	return image.Image.ConfigFile()
+}
+
+func isSetTime(t v1.Time) bool {
+	return !t.IsZero() && t.Unix() != 0
+}
+
+func formatTime(t v1.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// buildMetadataLabel matches labels conventionally holding the build time.
+func buildMetadataLabel(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"date", "created", "time", "build"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func addConfigRisks(image pkgutil.Image, findings util.ReproFindings) error {
+	c, err := configFile(image)
+	if err != nil {
+		return err
+	}
+	if isSetTime(c.Created) {
+		findings.Add(util.ReproCreated, "created "+formatTime(c.Created), 0)
+	}
+	for i, h := range c.History {
+		if isSetTime(h.Created) {
+			findings.Add(util.ReproHistory, fmt.Sprintf("history[%d] %s", i, formatTime(h.Created)), 0)
+		}
+	}
+	if c.Config.Hostname != "" {
+		findings.Add(util.ReproBuildHost, "config hostname "+c.Config.Hostname, 0)
+	}
+	if c.ContainerConfig.Hostname != "" {
+		findings.Add(util.ReproBuildHost, "container_config hostname "+c.ContainerConfig.Hostname, 0)
+	}
+	if c.Container != "" {
+		findings.Add(util.ReproBuildHost, "container "+c.Container, 0)
+	}
+	for key, value := range c.Config.Labels {
+		if buildMetadataLabel(key) {
+			findings.Add(util.ReproLabels, fmt.Sprintf("label %s=%s", key, value), 0)
+		}
+	}
+	return nil
+}
+
+func addConfigDiffs(image1, image2 pkgutil.Image, findings util.ReproFindings) error {
+	c1, err := configFile(image1)
+	if err != nil {
+		return err
+	}
+	c2, err := configFile(image2)
+	if err != nil {
+		return err
+	}
+	if !c1.Created.Equal(c2.Created.Time) {
+		findings.Add(util.ReproCreated, fmt.Sprintf("created %s -> %s", formatTime(c1.Created), formatTime(c2.Created)), 0)
+	}
+	for i := 0; i < len(c1.History) && i < len(c2.History); i++ {
+		t1, t2 := c1.History[i].Created, c2.History[i].Created
+		if !t1.Equal(t2.Time) {
+			findings.Add(util.ReproHistory, fmt.Sprintf("history[%d] %s -> %s", i, formatTime(t1), formatTime(t2)), 0)
+		}
+	}
+	changed := func(field, v1, v2 string) {
+		if v1 != v2 {
+			findings.Add(util.ReproBuildHost, fmt.Sprintf("%s %s -> %s", field, v1, v2), 0)
+		}
+	}
+	changed("config hostname", c1.Config.Hostname, c2.Config.Hostname)
+	changed("container_config hostname", c1.ContainerConfig.Hostname, c2.ContainerConfig.Hostname)
+	changed("container", c1.Container, c2.Container)
+
+	for key, v1 := range c1.Config.Labels {
+		if v2 := c2.Config.Labels[key]; v1 != v2 {
+			findings.Add(util.ReproLabels, fmt.Sprintf("label %s: %s -> %s", key, v1, v2), 0)
+		}
+	}
+	for key, v2 := range c2.Config.Labels {
+		if _, ok := c1.Config.Labels[key]; !ok {
+			findings.Add(util.ReproLabels, fmt.Sprintf("label %s: -> %s", key, v2), 0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func findingItems(findings []util.ReproFinding) map[string][]string {
+	items := map[string][]string{}
+	for _, f := range findings {
+		items[f.Issue] = f.Items
+	}
+	return items
+}
+
+func TestReproAnalyze(t *testing.T) {
+	image := pkgutil.Image{Source: "image1", FSPath: "testDirs/repro/image1"}
+	result, err := ReproAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Error analyzing image: %s", err)
+	}
+	findings := result.(*util.ReproAnalyzeResult).Analysis.([]util.ReproFinding)
+	expected := map[string][]string{
+		util.ReproPyc:    {"/usr/lib/app/mod.pyc"},
+		util.ReproGzip:   {"/usr/share/doc/stamped.gz"},
+		util.ReproRandom: {"/etc/machine-id"},
+		util.ReproLogs:   {"/var/log/dpkg.log"},
+	}
+	if actual := findingItems(findings); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+	for i := 1; i < len(findings); i++ {
+		if findings[i-1].Priority > findings[i].Priority {
+			t.Errorf("Findings out of priority order: %v", findings)
+		}
+	}
+}
+
+func TestReproDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/repro/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/repro/image2"}
+	result, err := ReproAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.ReproDiffResult).Diff.(util.ReproDiff)
+	if diff.Identical {
+		t.Errorf("Expected differing builds to not be identical")
+	}
+	expected := map[string][]string{
+		util.ReproPyc:    {"/usr/lib/app/hashed.pyc", "/usr/lib/app/mod.pyc"},
+		util.ReproGzip:   {"/usr/share/doc/clean.gz", "/usr/share/doc/stamped.gz"},
+		util.ReproRandom: {"/etc/machine-id"},
+		util.ReproLogs:   {"/var/log/dpkg.log"},
+	}
+	if actual := findingItems(diff.Findings); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+}
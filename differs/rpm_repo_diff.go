@@ -0,0 +1,262 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// yum and dnf read repositories from these directories, and their global
+// settings from the [main] section of the first config file found.
+var (
+	rpmRepoDirs     = []string{"etc/yum.repos.d", "etc/yum/repos.d", "etc/distro.repos.d"}
+	rpmConfigFiles  = []string{"etc/dnf/dnf.conf", "etc/yum.conf"}
+	dnfModulesDir   = "etc/dnf/modules.d"
+	vendorRepoHosts = []string{"fedoraproject.org", "redhat.com", "centos.org", "rockylinux.org", "almalinux.org", "oracle.com", "amazonlinux.com"}
+)
+
+type RPMRepoAnalyzer struct {
+}
+
+func (a RPMRepoAnalyzer) Name() string {
+	return "RPMRepoAnalyzer"
+}
+
+// Diff compares the yum/dnf repositories and module streams of two images.
+func (a RPMRepoAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	analysis1, err := getRPMRepoAnalysis(image1.FSPath)
+	if err != nil {
+		return &util.RPMRepoDiffResult{}, err
+	}
+	analysis2, err := getRPMRepoAnalysis(image2.FSPath)
+	if err != nil {
+		return &util.RPMRepoDiffResult{}, err
+	}
+	return &util.RPMRepoDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "RPMRepos",
+		Diff:     util.GetRPMRepoDiff(analysis1, analysis2),
+	}, nil
+}
+
+func (a RPMRepoAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := getRPMRepoAnalysis(image.FSPath)
+	if err != nil {
+		return &util.RPMRepoAnalyzeResult{}, err
+	}
+	return &util.RPMRepoAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "RPMRepos",
+		Analysis:    analysis,
+	}, nil
+}
+
+// getRPMRepoAnalysis reads the .repo files and module state files of an
+// image. Repositories are enabled unless they set enabled=0, and check
+// package signatures when gpgcheck is set in the repository or, failing
+// that, in the global config, as dnf does.
+func getRPMRepoAnalysis(root string) (util.RPMRepoAnalysis, error) {
+	analysis := util.RPMRepoAnalysis{Repos: []util.RPMRepo{}, Modules: []util.DnfModule{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return analysis, err
+	}
+
+	defaultGPGCheck := false
+	for _, config := range rpmConfigFiles {
+		sections, err := readINIFile(filepath.Join(root, config))
+		if err != nil {
+			continue
+		}
+		for _, s := range sections {
+			if s.name == "main" {
+				defaultGPGCheck = parseRepoBool(s.values["gpgcheck"], false)
+			}
+		}
+		break
+	}
+
+	seen := map[string]bool{}
+	for _, dir := range rpmRepoDirs {
+		contents, err := ioutil.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			continue
+		}
+		for _, c := range contents {
+			if c.IsDir() || !strings.HasSuffix(c.Name(), ".repo") {
+				continue
+			}
+			sections, err := readINIFile(filepath.Join(root, dir, c.Name()))
+			if err != nil {
+				continue
+			}
+			for _, s := range sections {
+				// the first definition of a repository wins
+				if seen[s.name] {
+					continue
+				}
+				seen[s.name] = true
+				repo := util.RPMRepo{
+					ID:       s.name,
+					Name:     s.values["name"],
+					File:     "/" + dir + "/" + c.Name(),
+					URL:      firstValue(s.values["baseurl"], s.values["mirrorlist"], s.values["metalink"]),
+					Enabled:  parseRepoBool(s.values["enabled"], true),
+					GPGCheck: parseRepoBool(s.values["gpgcheck"], defaultGPGCheck),
+				}
+				repo.ThirdParty = isThirdPartyRepo(repo)
+				analysis.Repos = append(analysis.Repos, repo)
+			}
+		}
+	}
+
+	contents, _ := ioutil.ReadDir(filepath.Join(root, dnfModulesDir))
+	for _, c := range contents {
+		if c.IsDir() || !strings.HasSuffix(c.Name(), ".module") {
+			continue
+		}
+		sections, err := readINIFile(filepath.Join(root, dnfModulesDir, c.Name()))
+		if err != nil {
+			continue
+		}
+		for _, s := range sections {
+			module := util.DnfModule{
+				Name:     firstValue(s.values["name"], s.name),
+				Stream:   s.values["stream"],
+				State:    s.values["state"],
+				Profiles: []string{},
+			}
+			for _, p := range strings.Split(s.values["profiles"], ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					module.Profiles = append(module.Profiles, p)
+				}
+			}
+			sort.Strings(module.Profiles)
+			analysis.Modules = append(analysis.Modules, module)
+		}
+	}
+	return util.SortRPMRepoAnalysis(analysis), nil
+}
+
+// isThirdPartyRepo reports whether a repository comes from EPEL or from a
+// host other than those of the distribution vendors. Repositories without
+// a URL, or with local file URLs, can't be told apart and are not flagged.
+func isThirdPartyRepo(repo util.RPMRepo) bool {
+	if strings.HasPrefix(strings.ToLower(repo.ID), "epel") || strings.Contains(repo.URL, "/epel") || strings.Contains(repo.URL, "repo=epel") {
+		return true
+	}
+	// URLs may list several mirrors; the first is representative
+	fields := strings.Fields(repo.URL)
+	if len(fields) == 0 {
+		return false
+	}
+	u, err := url.Parse(fields[0])
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	// Amazon Linux mirrors are named like amazonlinux.$awsregion.$awsdomain
+	if strings.HasPrefix(host, "amazonlinux.") {
+		return false
+	}
+	for _, vendor := range vendorRepoHosts {
+		if host == vendor || strings.HasSuffix(host, "."+vendor) {
+			return false
+		}
+	}
+	return true
+}
+
+// iniSection is a section of a yum or dnf configuration file, with keys
+// lowercased.
+type iniSection struct {
+	name   string
+	values map[string]string
+}
+
+// readINIFile reads the sections of a yum or dnf configuration file, or of
+// files of the same syntax such as supervisord configurations and systemd
+// units. Lines starting with whitespace continue the previous value, as in
+// baseurl lists.
+func readINIFile(path string) ([]iniSection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sections := []iniSection{}
+	var current *iniSection
+	lastKey := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, iniSection{name: strings.TrimSpace(line[1 : len(line)-1]), values: map[string]string{}})
+			current = &sections[len(sections)-1]
+			lastKey = ""
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if (raw[0] == ' ' || raw[0] == '\t') && lastKey != "" {
+			current.values[lastKey] += " " + line
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		lastKey = strings.ToLower(strings.TrimSpace(parts[0]))
+		current.values[lastKey] = strings.TrimSpace(parts[1])
+	}
+	return sections, scanner.Err()
+}
+
+func parseRepoBool(value string, defaultValue bool) bool {
+	switch strings.ToLower(value) {
+	case "1", "yes", "true", "on":
+		return true
+	case "0", "no", "false", "off":
+		return false
+	}
+	return defaultValue
+}
+
+func firstValue(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
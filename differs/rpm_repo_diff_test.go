@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const rockyMirrorList = "https://mirrors.rockylinux.org/mirrorlist?arch=$basearch&repo="
+
+func TestGetRPMRepoAnalysis(t *testing.T) {
+	analysis, err := getRPMRepoAnalysis("testDirs/rpmrepo/image1")
+	if err != nil {
+		t.Fatalf("Error reading repositories: %s", err)
+	}
+	expected := util.RPMRepoAnalysis{
+		Repos: []util.RPMRepo{
+			{ID: "appstream", Name: "Rocky Linux $releasever - AppStream", File: "/etc/yum.repos.d/rocky.repo", URL: rockyMirrorList + "AppStream-$releasever", Enabled: true, GPGCheck: true},
+			{ID: "baseos", Name: "Rocky Linux $releasever - BaseOS", File: "/etc/yum.repos.d/rocky.repo", URL: rockyMirrorList + "BaseOS-$releasever", Enabled: true, GPGCheck: true},
+			{ID: "vendor", Name: "Vendor packages", File: "/etc/yum.repos.d/vendor.repo", URL: "https://packages.example.com/el8", GPGCheck: true, ThirdParty: true},
+		},
+		Modules: []util.DnfModule{{Name: "nodejs", Stream: "14", State: "enabled", Profiles: []string{}}},
+	}
+	if !reflect.DeepEqual(analysis, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, analysis)
+	}
+
+	if _, err := getRPMRepoAnalysis("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestRPMRepoDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/rpmrepo/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/rpmrepo/image2"}
+	result, err := RPMRepoAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.RPMRepoDiffResult).Diff.(util.RPMRepoDiff)
+
+	expectedWarnings := []string{
+		"gpgcheck disabled for repository appstream",
+		"third-party repository epel added (https://mirrors.fedoraproject.org/metalink?repo=epel-$releasever&arch=$basearch)",
+	}
+	if !reflect.DeepEqual(diff.Warnings, expectedWarnings) {
+		t.Errorf("Expected warnings: %v but got: %v", expectedWarnings, diff.Warnings)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].ID != "epel" || !diff.Added[0].ThirdParty {
+		t.Errorf("Expected epel to be added as a third-party repository, got: %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "vendor" {
+		t.Errorf("Expected vendor to be removed, got: %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].ID != "appstream" {
+		t.Errorf("Expected appstream to change, got: %+v", diff.Changed)
+	}
+	expectedModules := []util.DnfModuleChange{
+		{Name: "nodejs", Stream1: "14", Stream2: "18", State1: "enabled", State2: "enabled"},
+		{Name: "php", State2: "disabled"},
+	}
+	if !reflect.DeepEqual(diff.Modules, expectedModules) {
+		t.Errorf("Expected modules: %+v but got: %+v", expectedModules, diff.Modules)
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// defaultPath is searched for runtime commands when the image sets no PATH
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// RuntimesAnalyzer detects the language runtimes installed in an image from
+// the version files they ship, as the binaries themselves can't be run.
+type RuntimesAnalyzer struct {
+}
+
+func (a RuntimesAnalyzer) Name() string {
+	return "RuntimesAnalyzer"
+}
+
+func (a RuntimesAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	runtimes1, err := getLanguageRuntimes(image1)
+	if err != nil {
+		return &util.RuntimesDiffResult{}, err
+	}
+	runtimes2, err := getLanguageRuntimes(image2)
+	if err != nil {
+		return &util.RuntimesDiffResult{}, err
+	}
+	return &util.RuntimesDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Runtimes",
+		Diff:     util.GetRuntimeMatrixDiff(runtimes1, runtimes2),
+	}, nil
+}
+
+func (a RuntimesAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	runtimes, err := getLanguageRuntimes(image)
+	if err != nil {
+		return &util.RuntimesAnalyzeResult{}, err
+	}
+	return &util.RuntimesAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Runtimes",
+		Analysis:    runtimes,
+	}, nil
+}
+
+// runtimeInstall is a detected installation. It is the default when the
+// runtime's command resolves to binary, or to a file below home.
+type runtimeInstall struct {
+	version string
+	path    string
+	binary  string
+	home    string
+}
+
+// runtimeDetector finds the installations of a runtime below root, and
+// names the commands whose resolution decides the default one.
+type runtimeDetector struct {
+	runtime  string
+	commands []string
+	detect   func(root string) []runtimeInstall
+}
+
+var runtimeDetectors = []runtimeDetector{
+	{"python", []string{"python3", "python", "python2"}, detectPython},
+	{"node", []string{"node", "nodejs"}, detectNode},
+	{"ruby", []string{"ruby"}, detectRuby},
+	{"java", []string{"java"}, detectJava},
+	{"go", []string{"go"}, detectGo},
+	{"dotnet", []string{"dotnet"}, detectDotnet},
+}
+
+func getLanguageRuntimes(image pkgutil.Image) ([]util.LanguageRuntime, error) {
+	root := image.FSPath
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return nil, err
+	}
+	config, err := configFile(image)
+	if err != nil {
+		return nil, err
+	}
+	searchPath := defaultPath
+	for _, env := range config.Config.Env {
+		if strings.HasPrefix(env, "PATH=") {
+			searchPath = strings.TrimPrefix(env, "PATH=")
+		}
+	}
+
+	runtimes := []util.LanguageRuntime{}
+	for _, d := range runtimeDetectors {
+		installs := d.detect(root)
+		resolved := ""
+		for _, command := range d.commands {
+			if resolved = resolveCommand(root, searchPath, command); resolved != "" {
+				break
+			}
+		}
+		defaultFound := false
+		for _, install := range installs {
+			isDefault := !defaultFound && resolved != "" &&
+				(resolved == install.binary || (install.home != "" && strings.HasPrefix(resolved, install.home+"/")))
+			defaultFound = defaultFound || isDefault
+			runtimes = append(runtimes, util.LanguageRuntime{
+				Runtime: d.runtime,
+				Version: install.version,
+				Path:    install.path,
+				Default: isDefault,
+			})
+		}
+	}
+	util.SortLanguageRuntimes(runtimes)
+	return runtimes, nil
+}
+
+// resolveCommand finds command on searchPath and follows its symlinks inside
+// the image, returning the absolute path of the file it runs.
+func resolveCommand(root, searchPath, command string) string {
+	for _, dir := range filepath.SplitList(searchPath) {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		p := path.Join(dir, command)
+		for i := 0; i < 40; i++ {
+			info, err := os.Lstat(filepath.Join(root, p))
+			if err != nil {
+				break
+			}
+			if info.Mode()&os.ModeSymlink == 0 {
+				if info.Mode().IsRegular() {
+					return p
+				}
+				break
+			}
+			target, err := os.Readlink(filepath.Join(root, p))
+			if err != nil {
+				break
+			}
+			if path.IsAbs(target) {
+				p = path.Clean(target)
+			} else {
+				p = path.Join(path.Dir(p), target)
+			}
+		}
+	}
+	return ""
+}
+
+// glob matches pattern below root, returning image paths.
+func glob(root, pattern string) []string {
+	matches, _ := filepath.Glob(filepath.Join(root, pattern))
+	paths := []string{}
+	for _, m := range matches {
+		if rel, err := filepath.Rel(root, m); err == nil {
+			paths = append(paths, "/"+filepath.ToSlash(rel))
+		}
+	}
+	return paths
+}
+
+func exists(root, p string) bool {
+	_, err := os.Lstat(filepath.Join(root, p))
+	return err == nil
+}
+
+// readQuoted returns the first quoted value captured by re in a file.
+func readQuoted(root, p string, re *regexp.Regexp) string {
+	file, err := os.Open(filepath.Join(root, p))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if m := re.FindStringSubmatch(scanner.Text()); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+var (
+	pythonLibDir     = regexp.MustCompile(`^python(\d+\.\d+)$`)
+	pythonPatchlevel = regexp.MustCompile(`#define\s+PY_VERSION\s+"([^"]+)"`)
+	nodeVersionPart  = regexp.MustCompile(`#define\s+NODE_(MAJOR|MINOR|PATCH)_VERSION\s+(\d+)`)
+	rubyVersion      = regexp.MustCompile(`CONFIG\["RUBY_PROGRAM_VERSION"\]\s*=\s*"([^"]+)"`)
+	javaVersion      = regexp.MustCompile(`^JAVA_VERSION="([^"]+)"`)
+)
+
+var runtimePrefixes = []string{"/usr/local", "/usr"}
+
+// detectPython finds pythonX.Y interpreters next to their standard library,
+// taking the exact version from the installed headers when present.
+func detectPython(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	for _, prefix := range runtimePrefixes {
+		for _, lib := range glob(root, prefix+"/lib/python*") {
+			m := pythonLibDir.FindStringSubmatch(path.Base(lib))
+			if m == nil {
+				continue
+			}
+			binary := prefix + "/bin/python" + m[1]
+			if !exists(root, binary) {
+				continue
+			}
+			version := m[1]
+			for _, header := range glob(root, prefix+"/include/python"+m[1]+"*/patchlevel.h") {
+				if v := readQuoted(root, header, pythonPatchlevel); v != "" {
+					version = v
+					break
+				}
+			}
+			installs = append(installs, runtimeInstall{version: version, path: lib, binary: binary})
+		}
+	}
+	return installs
+}
+
+// detectNode reads the version of node binaries from node_version.h.
+func detectNode(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	for _, prefix := range runtimePrefixes {
+		binary := prefix + "/bin/node"
+		if !exists(root, binary) {
+			continue
+		}
+		version := "unknown"
+		if data, err := ioutil.ReadFile(filepath.Join(root, prefix, "include/node/node_version.h")); err == nil {
+			parts := map[string]string{}
+			for _, m := range nodeVersionPart.FindAllStringSubmatch(string(data), -1) {
+				if _, ok := parts[m[1]]; !ok {
+					parts[m[1]] = m[2]
+				}
+			}
+			if len(parts) == 3 {
+				version = parts["MAJOR"] + "." + parts["MINOR"] + "." + parts["PATCH"]
+			}
+		}
+		installs = append(installs, runtimeInstall{version: version, path: binary, binary: binary})
+	}
+	return installs
+}
+
+// detectRuby reads rbconfig.rb, which records the exact ruby version.
+func detectRuby(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	for _, prefix := range runtimePrefixes {
+		configs := glob(root, prefix+"/lib/ruby/*/*/rbconfig.rb")
+		// Debian keeps rbconfig.rb in the multiarch library directory
+		configs = append(configs, glob(root, prefix+"/lib/*/ruby/*/rbconfig.rb")...)
+		for _, config := range configs {
+			version := readQuoted(root, config, rubyVersion)
+			if version == "" {
+				continue
+			}
+			installs = append(installs, runtimeInstall{version: version, path: config, home: prefix + "/bin"})
+		}
+	}
+	return installs
+}
+
+// detectJava reads the release file at the root of each JDK or JRE.
+func detectJava(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	homes := []string{}
+	for _, pattern := range []string{"/usr/lib/jvm/*/release", "/opt/java/*/release", "/usr/local/openjdk*/release", "/opt/jdk*/release"} {
+		homes = append(homes, glob(root, pattern)...)
+	}
+	for _, release := range homes {
+		version := readQuoted(root, release, javaVersion)
+		if version == "" {
+			continue
+		}
+		home := path.Dir(release)
+		installs = append(installs, runtimeInstall{version: version, path: home, home: home})
+	}
+	return installs
+}
+
+// detectGo reads the VERSION file of each Go toolchain.
+func detectGo(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	homes := append(glob(root, "/usr/local/go"), glob(root, "/usr/lib/go-*")...)
+	homes = append(homes, glob(root, "/usr/lib/go")...)
+	for _, home := range homes {
+		data, err := ioutil.ReadFile(filepath.Join(root, home, "VERSION"))
+		if err != nil {
+			continue
+		}
+		version := strings.TrimPrefix(strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]), "go")
+		installs = append(installs, runtimeInstall{version: version, path: home, home: home})
+	}
+	return installs
+}
+
+// detectDotnet lists the shared .NET runtimes. The dotnet host runs the
+// newest of them, so only that one can be the default.
+func detectDotnet(root string) []runtimeInstall {
+	installs := []runtimeInstall{}
+	for _, home := range []string{"/usr/share/dotnet", "/usr/lib/dotnet", "/opt/dotnet"} {
+		versions := glob(root, home+"/shared/Microsoft.NETCore.App/*")
+		newest := ""
+		for _, dir := range versions {
+			if v := path.Base(dir); newest == "" || util.CompareVersions(v, newest) > 0 {
+				newest = v
+			}
+		}
+		for _, dir := range versions {
+			install := runtimeInstall{version: path.Base(dir), path: dir}
+			if install.version == newest {
+				install.home = home
+			}
+			installs = append(installs, install)
+		}
+	}
+	return installs
+}
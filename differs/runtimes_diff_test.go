@@ -0,0 +1,68 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetLanguageRuntimes(t *testing.T) {
+	image := pkgutil.Image{Source: "image2", FSPath: "testDirs/runtimes/image2"}
+	runtimes, err := getLanguageRuntimes(image)
+	if err != nil {
+		t.Fatalf("Error detecting runtimes: %s", err)
+	}
+	expected := []util.LanguageRuntime{
+		{Runtime: "dotnet", Version: "3.1.20", Path: "/usr/share/dotnet/shared/Microsoft.NETCore.App/3.1.20"},
+		{Runtime: "dotnet", Version: "6.0.0", Path: "/usr/share/dotnet/shared/Microsoft.NETCore.App/6.0.0", Default: true},
+		{Runtime: "go", Version: "1.17.1", Path: "/usr/local/go"},
+		{Runtime: "java", Version: "11.0.12", Path: "/usr/lib/jvm/java-11-openjdk-amd64"},
+		{Runtime: "java", Version: "17.0.1", Path: "/usr/lib/jvm/java-17-openjdk-amd64", Default: true},
+		{Runtime: "node", Version: "16.13.0", Path: "/usr/local/bin/node", Default: true},
+		{Runtime: "python", Version: "3.7.3", Path: "/usr/lib/python3.7"},
+		{Runtime: "python", Version: "3.9.7", Path: "/usr/local/lib/python3.9", Default: true},
+		{Runtime: "ruby", Version: "2.7.4", Path: "/usr/lib/x86_64-linux-gnu/ruby/2.7.0/rbconfig.rb", Default: true},
+	}
+	if !reflect.DeepEqual(runtimes, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, runtimes)
+	}
+}
+
+func TestRuntimesDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/runtimes/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/runtimes/image2"}
+	result, err := RuntimesAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.RuntimesDiffResult).Diff.([]util.RuntimeVersions)
+	expected := []util.RuntimeVersions{
+		{Runtime: "dotnet", Versions1: []string{}, Versions2: []string{"3.1.20", "6.0.0"}, Default2: "6.0.0"},
+		{Runtime: "go", Versions1: []string{"1.16.5"}, Versions2: []string{"1.17.1"}},
+		{Runtime: "java", Versions1: []string{"11.0.12"}, Versions2: []string{"11.0.12", "17.0.1"}, Default1: "11.0.12", Default2: "17.0.1"},
+		{Runtime: "node", Versions1: []string{}, Versions2: []string{"16.13.0"}, Default2: "16.13.0"},
+		{Runtime: "python", Versions1: []string{"3.7.3"}, Versions2: []string{"3.7.3", "3.9.7"}, Default1: "3.7.3", Default2: "3.9.7"},
+		{Runtime: "ruby", Versions1: []string{}, Versions2: []string{"2.7.4"}, Default2: "2.7.4"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// ScoreAnalyzer condenses an image into a small scorecard, for dashboards
+// which can't render full reports.
+type ScoreAnalyzer struct {
+}
+
+func (a ScoreAnalyzer) Name() string {
+	return "ScoreAnalyzer"
+}
+
+func (a ScoreAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	card1, err := getScorecard(image1)
+	if err != nil {
+		return &util.ScoreDiffResult{}, err
+	}
+	card2, err := getScorecard(image2)
+	if err != nil {
+		return &util.ScoreDiffResult{}, err
+	}
+	return &util.ScoreDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Score",
+		Diff:     util.GetScorecardDiff(card1, card2),
+	}, nil
+}
+
+func (a ScoreAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	card, err := getScorecard(image)
+	if err != nil {
+		return &util.ScoreAnalyzeResult{}, err
+	}
+	return &util.ScoreAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Score",
+		Analysis:    card,
+	}, nil
+}
+
+func getScorecard(image pkgutil.Image) (util.Scorecard, error) {
+	categories := []util.ScoreCategory{
+		util.SizeScore(imageSize(image)),
+		util.LayerScore(len(image.Layers)),
+	}
+	wasted, total, err := wastedSpace(image.Layers)
+	if err != nil {
+		return util.Scorecard{}, err
+	}
+	categories = append(categories, util.WastedScore(wasted, total))
+
+	// package databases don't record install times and extraction doesn't
+	// keep mtimes, so the build time stands in for the last package update
+	c, err := configFile(image)
+	if err != nil {
+		return util.Scorecard{}, err
+	}
+	if isSetTime(c.Created) {
+		days := int(time.Since(c.Created.Time).Hours() / 24)
+		if days < 0 {
+			days = 0
+		}
+		categories = append(categories, util.FreshnessScore(days))
+	}
+
+	risky, err := riskyPermissions(image.FSPath)
+	if err != nil {
+		return util.Scorecard{}, err
+	}
+	categories = append(categories, util.PermissionScore(risky))
+	return util.NewScorecard(categories), nil
+}
+
+// wastedSpace sums the size of the files in each layer which are overwritten
+// or deleted by a later layer, along with the size of all layer files.
+func wastedSpace(layers []pkgutil.Layer) (int64, int64, error) {
+	var wasted, total int64
+	replaced := map[string]bool{}
+	removed := map[string]bool{}
+	for i := len(layers) - 1; i >= 0; i-- {
+		dir, err := pkgutil.GetDirectory(layers[i].FSPath, true)
+		if err != nil {
+			return 0, 0, err
+		}
+		files := []string{}
+		for _, name := range dir.Content {
+			base := path.Base(name)
+			if strings.HasPrefix(base, whiteoutPrefix) {
+				continue
+			}
+			info, err := os.Lstat(filepath.Join(layers[i].FSPath, name))
+			if err != nil {
+				return 0, 0, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			total += info.Size()
+			if replaced[name] || removedBelow(removed, name) {
+				wasted += info.Size()
+			}
+			files = append(files, name)
+		}
+		// record this layer only after checking it, it hides lower layers
+		for _, file := range files {
+			replaced[file] = true
+		}
+		for _, name := range dir.Content {
+			base := path.Base(name)
+			if base == opaqueWhiteout {
+				removed[path.Dir(name)] = true
+			} else if strings.HasPrefix(base, whiteoutPrefix) {
+				removed[path.Join(path.Dir(name), strings.TrimPrefix(base, whiteoutPrefix))] = true
+			}
+		}
+	}
+	return wasted, total, nil
+}
+
+// removedBelow reports whether name or one of its parents was whited out.
+func removedBelow(removed map[string]bool, name string) bool {
+	for p := name; p != "/" && p != "."; p = path.Dir(p) {
+		if removed[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// riskyPermissions counts setuid and setgid files, and world-writable files
+// and directories other than sticky ones such as /tmp.
+func riskyPermissions(root string) (int, error) {
+	dir, err := pkgutil.GetDirectory(root, true)
+	if err != nil {
+		return 0, err
+	}
+	risky := 0
+	for _, name := range dir.Content {
+		info, err := os.Lstat(filepath.Join(root, name))
+		if err != nil {
+			return 0, err
+		}
+		mode := info.Mode()
+		if mode&os.ModeSymlink != 0 {
+			continue
+		}
+		if mode&(os.ModeSetuid|os.ModeSetgid) != 0 && mode.IsRegular() {
+			risky++
+		} else if mode.Perm()&0002 != 0 && !(mode.IsDir() && mode&os.ModeSticky != 0) {
+			risky++
+		}
+	}
+	return risky, nil
+}
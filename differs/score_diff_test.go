@@ -0,0 +1,110 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		target := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatalf("Error creating directory: %s", err)
+		}
+		if err := ioutil.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing file: %s", err)
+		}
+	}
+}
+
+func TestWastedSpace(t *testing.T) {
+	root, err := ioutil.TempDir("", "score")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	layerFiles := []map[string]string{
+		{"etc/config": "original", "var/cache/apt/pkgcache.bin": "0123456789", "opt/app/old": "12345", "bin/tool": "tool"},
+		{"etc/config": "replaced", "var/cache/apt/.wh.pkgcache.bin": "", "opt/.wh..wh..opq": "", "opt/new": "1"},
+		{"etc/.wh.missing": ""},
+	}
+	layers := []pkgutil.Layer{}
+	for i, files := range layerFiles {
+		dir := filepath.Join(root, strconv.Itoa(i))
+		writeTestFiles(t, dir, files)
+		layers = append(layers, pkgutil.Layer{FSPath: dir})
+	}
+
+	wasted, total, err := wastedSpace(layers)
+	if err != nil {
+		t.Fatalf("Error computing wasted space: %s", err)
+	}
+	// the original config, the deleted package cache and everything below
+	// the opaque /opt directory
+	if expected := int64(len("original") + len("0123456789") + len("12345")); wasted != expected {
+		t.Errorf("Expected %d wasted bytes but got %d", expected, wasted)
+	}
+	if expected := int64(len("original") + len("0123456789") + len("12345") + len("tool") + len("replaced") + len("1")); total != expected {
+		t.Errorf("Expected %d total bytes but got %d", expected, total)
+	}
+}
+
+func TestRiskyPermissions(t *testing.T) {
+	root, err := ioutil.TempDir("", "score")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTestFiles(t, root, map[string]string{
+		"bin/su":       "su",
+		"bin/ls":       "ls",
+		"etc/shadow":   "shadow",
+		"srv/data/log": "log",
+	})
+	if err := os.Mkdir(filepath.Join(root, "tmp"), 0755); err != nil {
+		t.Fatalf("Error creating directory: %s", err)
+	}
+	modes := map[string]os.FileMode{
+		"bin/su":     0755 | os.ModeSetuid,
+		"bin/ls":     0755,
+		"etc/shadow": 0666,
+		"srv/data":   0777,
+		"tmp":        0777 | os.ModeSticky,
+	}
+	for name, mode := range modes {
+		if err := os.Chmod(filepath.Join(root, name), mode); err != nil {
+			t.Fatalf("Error changing mode: %s", err)
+		}
+	}
+
+	risky, err := riskyPermissions(root)
+	if err != nil {
+		t.Fatalf("Error counting risky permissions: %s", err)
+	}
+	if risky != 3 {
+		t.Errorf("Expected 3 risky files but got %d", risky)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// secretsMaxFileSize is the size above which files aren't scanned for
+// secrets: credentials live in small text files, large ones are data.
+const secretsMaxFileSize = 1 << 20
+
+// secretsMinEntropy is the Shannon entropy, in bits per character, above
+// which the value of a credential-like assignment is reported. English
+// words and placeholders score below 3.5, random tokens above 4.
+const secretsMinEntropy = 4.0
+
+// Kinds of secrets.
+const (
+	secretPrivateKey   = "private-key"
+	secretAWSAccessKey = "aws-access-key"
+	secretAWSSecretKey = "aws-secret-key"
+	secretNpmToken     = "npm-token"
+	secretDockerAuth   = "docker-auth"
+	secretHighEntropy  = "high-entropy"
+)
+
+// secretRule finds a kind of secret in a line. The last submatch of pattern
+// is the credential. Rules with a file only apply to files of that name.
+type secretRule struct {
+	kind    string
+	file    string
+	pattern *regexp.Regexp
+	// entropy, when set, is the entropy the credential must reach
+	entropy float64
+}
+
+var secretRules = []secretRule{
+	{kind: secretPrivateKey, pattern: regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`)},
+	{kind: secretAWSAccessKey, pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{kind: secretAWSSecretKey, pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{kind: secretNpmToken, file: ".npmrc", pattern: regexp.MustCompile(`(_authToken|_auth|_password)\s*=\s*["']?([^\s"']+)`)},
+	{kind: secretDockerAuth, file: "config.json", pattern: regexp.MustCompile(`"(auth|identitytoken)"\s*:\s*"([^"]+)"`)},
+	{kind: secretHighEntropy, pattern: regexp.MustCompile(`(?i)(secret|token|passw(?:or)?d|api_?key|credentials?)[\w.-]*["']?\s*[=:]\s*["']?([A-Za-z0-9+/=_\-]{20,})`), entropy: secretsMinEntropy},
+}
+
+// SecretsAnalyzer scans image files for likely credentials: private keys,
+// AWS keys, npm and registry auth, and high-entropy values assigned to
+// secret-sounding names.
+type SecretsAnalyzer struct {
+}
+
+func (a SecretsAnalyzer) Name() string {
+	return "SecretsAnalyzer"
+}
+
+// Diff reports the secrets found only in one of the images, those of the
+// second image first, as leaked by its build.
+func (a SecretsAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	secrets1, err := getSecrets(image1.FSPath)
+	if err != nil {
+		return &util.SecretDiffResult{}, err
+	}
+	secrets2, err := getSecrets(image2.FSPath)
+	if err != nil {
+		return &util.SecretDiffResult{}, err
+	}
+	return &util.SecretDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Secrets",
+		Diff:     util.GetSecretDiff(secrets1, secrets2),
+	}, nil
+}
+
+// Analyze lists the likely secrets of an image.
+func (a SecretsAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	secrets, err := getSecrets(image.FSPath)
+	if err != nil {
+		return &util.SecretAnalyzeResult{}, err
+	}
+	return &util.SecretAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Secrets",
+		Analysis:    secrets,
+	}, nil
+}
+
+// getSecrets scans the regular text files of an image filesystem for
+// secrets.
+func getSecrets(root string) ([]util.Secret, error) {
+	secrets := []util.Secret{}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return secrets, err
+	}
+	err := pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		if !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > secretsMaxFileSize {
+			return nil
+		}
+		name := path.Clean("/" + filepath.ToSlash(strings.TrimPrefix(file, root)))
+		f, err := os.Open(file)
+		if err != nil {
+			logrus.Warnf("Could not scan %s for secrets: %s", name, err)
+			return nil
+		}
+		defer f.Close()
+		secrets = append(secrets, scanSecrets(name, f)...)
+		return nil
+	})
+	util.SortSecrets(secrets)
+	return secrets, err
+}
+
+// scanSecrets finds the secrets in the file name read from reader. Binary
+// files, recognized by a NUL byte in their first block, are skipped.
+func scanSecrets(name string, reader io.Reader) []util.Secret {
+	secrets := []util.Secret{}
+	buffered := bufio.NewReader(reader)
+	if head, _ := buffered.Peek(8192); bytes.IndexByte(head, 0) >= 0 {
+		return secrets
+	}
+	base := path.Base(name)
+	scanner := bufio.NewScanner(buffered)
+	scanner.Buffer(make([]byte, 64*1024), secretsMaxFileSize)
+	privateKey := ""
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if privateKey != "" {
+			// the first line of the key body tells keys apart, the header doesn't
+			secrets = append(secrets, newSecret(name, line-1, secretPrivateKey, privateKey, privateKey+"\n"+text))
+			privateKey = ""
+		}
+		found := len(secrets)
+		for _, rule := range secretRules {
+			if rule.file != "" && !secretRuleApplies(rule, name, base) {
+				continue
+			}
+			if rule.kind == secretHighEntropy && len(secrets) > found {
+				// the line's credential was already recognized
+				continue
+			}
+			for _, match := range rule.pattern.FindAllStringSubmatch(text, -1) {
+				credential := match[len(match)-1]
+				if rule.kind == secretPrivateKey {
+					privateKey = match[0]
+					continue
+				}
+				if rule.entropy > 0 && shannonEntropy(credential) < rule.entropy {
+					continue
+				}
+				secrets = append(secrets, newSecret(name, line, rule.kind, credential, credential))
+			}
+		}
+	}
+	if privateKey != "" {
+		secrets = append(secrets, newSecret(name, line, secretPrivateKey, privateKey, privateKey))
+	}
+	return secrets
+}
+
+// secretRuleApplies tells whether a rule restricted to a file name applies
+// to the file name, whose base name is base. Registry auth is only looked
+// for in docker config files.
+func secretRuleApplies(rule secretRule, name, base string) bool {
+	if base != rule.file {
+		return false
+	}
+	return rule.kind != secretDockerAuth || path.Base(path.Dir(name)) == ".docker"
+}
+
+// newSecret records a secret, redacting it. Private keys are reported by
+// their header, which isn't secret.
+func newSecret(name string, line int, kind, credential, fingerprinted string) util.Secret {
+	match := util.RedactSecret(credential)
+	if kind == secretPrivateKey {
+		match = credential
+	}
+	digest := sha256.Sum256([]byte(fingerprinted))
+	return util.Secret{
+		Path:        name,
+		Line:        line,
+		Kind:        kind,
+		Match:       match,
+		Fingerprint: hex.EncodeToString(digest[:8]),
+	}
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	length := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
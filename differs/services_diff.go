@@ -0,0 +1,316 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+var (
+	initScriptDirs = []string{"etc/init.d", "etc/rc.d/init.d"}
+	// runlevels 0 and 6 halt and reboot, and start no services
+	sysVRunlevelDirs = []string{"etc/rc[1-5S].d", "etc/rc.d/rc[1-5].d"}
+	openRCRunlevels  = "etc/runlevels"
+	// supervisord reads the first of these, and the files it includes,
+	// which are usually those of the conf.d directories below
+	supervisordConfigs  = []string{"etc/supervisord.conf", "etc/supervisor/supervisord.conf"}
+	supervisordIncludes = []string{"etc/supervisor/conf.d/*.conf", "etc/supervisord.d/*.ini", "etc/supervisord.d/*.conf"}
+)
+
+// serviceUnitSuffixes are the types of systemd units which start a
+// service: directly, or on connections, timers or path changes.
+var serviceUnitSuffixes = []string{".service", ".socket", ".timer", ".path"}
+
+// sysVStartLink matches the links of runlevel directories starting
+// scripts, e.g. S01cron, and initScriptCommand the program an init script
+// starts, as set by Debian scripts with DAEMON= and by OpenRC ones with
+// command=.
+var (
+	sysVStartLink     = regexp.MustCompile(`^S[0-9]*(.+)$`)
+	initScriptCommand = regexp.MustCompile(`^\s*(?:DAEMON|command)=["']?([^"'\s]+)`)
+)
+
+type ServicesAnalyzer struct {
+}
+
+func (a ServicesAnalyzer) Name() string {
+	return "ServicesAnalyzer"
+}
+
+// Diff compares the background services two images start.
+func (a ServicesAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	services1, err := getServices(image1.FSPath)
+	if err != nil {
+		return &util.ServiceDiffResult{}, err
+	}
+	services2, err := getServices(image2.FSPath)
+	if err != nil {
+		return &util.ServiceDiffResult{}, err
+	}
+	return &util.ServiceDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Services",
+		Diff:     util.GetServiceDiff(services1, services2),
+	}, nil
+}
+
+func (a ServicesAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	services, err := getServices(image.FSPath)
+	if err != nil {
+		return &util.ServiceAnalyzeResult{}, err
+	}
+	return &util.ServiceAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Services",
+		Analysis:    services,
+	}, nil
+}
+
+// getServices lists the services an image starts, whichever of systemd,
+// SysV init, OpenRC and supervisord manages them. Services shipped but not
+// enabled are left out.
+func getServices(root string) ([]util.Service, error) {
+	units, err := getSystemdUnits(root)
+	if err != nil {
+		return []util.Service{}, err
+	}
+	services := []util.Service{}
+	for name, unit := range units {
+		if unit.State != util.UnitEnabled || !isServiceUnit(name) {
+			continue
+		}
+		service := util.Service{Name: name, Manager: util.ManagerSystemd, StartedBy: unit.WantedBy}
+		if unitPath, ok := findUnitFile(root, name); ok {
+			service.Source = imagePath(root, unitPath)
+			service.Command = unitCommand(unitPath)
+		}
+		services = append(services, service)
+	}
+	services = append(services, sysVServices(root)...)
+	services = append(services, openRCServices(root)...)
+	services = append(services, supervisordServices(root)...)
+	util.SortServices(services)
+	return services, nil
+}
+
+func isServiceUnit(name string) bool {
+	for _, suffix := range serviceUnitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findUnitFile returns the file of a unit, following the precedence of
+// the unit directories, with symlinks resolved inside the image. Instances
+// of template units, such as getty@tty1.service, are read from their
+// template.
+func findUnitFile(root, name string) (string, bool) {
+	names := []string{name}
+	if i := strings.Index(name, "@"); i >= 0 {
+		names = append(names, name[:i+1]+path.Ext(name))
+	}
+	for _, n := range names {
+		for _, dir := range systemdUnitDirs {
+			if resolved, ok := resolveInImage(root, path.Join("/", dir, n)); ok {
+				return filepath.Join(root, resolved), true
+			}
+		}
+	}
+	return "", false
+}
+
+// unitCommand returns the first ExecStart of a service unit, without the
+// prefixes changing how it runs, such as - to ignore failures.
+func unitCommand(unitPath string) string {
+	sections, err := readINIFile(unitPath)
+	if err != nil {
+		return ""
+	}
+	for _, s := range sections {
+		if s.name == "Service" && s.values["execstart"] != "" {
+			return strings.TrimLeft(s.values["execstart"], "-@:+!")
+		}
+	}
+	return ""
+}
+
+// sysVServices lists the init scripts the rc directories start, with the
+// runlevels starting them.
+func sysVServices(root string) []util.Service {
+	runlevels := map[string][]string{}
+	names := []string{}
+	for _, pattern := range sysVRunlevelDirs {
+		dirs, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, dir := range dirs {
+			runlevel := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(dir), "rc"), ".d")
+			links, err := ioutil.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, link := range links {
+				match := sysVStartLink.FindStringSubmatch(link.Name())
+				if match == nil {
+					continue
+				}
+				name := match[1]
+				if target, err := os.Readlink(filepath.Join(dir, link.Name())); err == nil {
+					name = path.Base(target)
+				}
+				if runlevels[name] == nil {
+					names = append(names, name)
+				}
+				runlevels[name] = append(runlevels[name], "runlevel "+runlevel)
+			}
+		}
+	}
+	services := []util.Service{}
+	for _, name := range names {
+		services = append(services, initScriptService(root, name, util.ManagerSysVInit, runlevels[name]))
+	}
+	return services
+}
+
+// openRCServices lists the init scripts the OpenRC runlevels start, other
+// than at shutdown.
+func openRCServices(root string) []util.Service {
+	runlevels := map[string][]string{}
+	names := []string{}
+	levels, err := ioutil.ReadDir(filepath.Join(root, openRCRunlevels))
+	if err != nil {
+		return []util.Service{}
+	}
+	for _, level := range levels {
+		if !level.IsDir() || level.Name() == "shutdown" {
+			continue
+		}
+		links, err := ioutil.ReadDir(filepath.Join(root, openRCRunlevels, level.Name()))
+		if err != nil {
+			continue
+		}
+		for _, link := range links {
+			if runlevels[link.Name()] == nil {
+				names = append(names, link.Name())
+			}
+			runlevels[link.Name()] = append(runlevels[link.Name()], "runlevel "+level.Name())
+		}
+	}
+	services := []util.Service{}
+	for _, name := range names {
+		services = append(services, initScriptService(root, name, util.ManagerOpenRC, runlevels[name]))
+	}
+	return services
+}
+
+func initScriptService(root, name, manager string, runlevels []string) util.Service {
+	service := util.Service{Name: name, Manager: manager, StartedBy: dedupe(runlevels)}
+	for _, dir := range initScriptDirs {
+		script := filepath.Join(root, dir, name)
+		if _, err := os.Stat(script); err == nil {
+			service.Source = imagePath(root, script)
+			service.Command = initScriptProgram(script)
+			break
+		}
+	}
+	return service
+}
+
+func initScriptProgram(script string) string {
+	file, err := os.Open(script)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if match := initScriptCommand.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// supervisordServices lists the programs and event listeners supervisord
+// starts automatically, from its configuration and the files it includes.
+func supervisordServices(root string) []util.Service {
+	queue := []string{}
+	for _, config := range supervisordConfigs {
+		if _, err := os.Stat(filepath.Join(root, config)); err == nil {
+			queue = append(queue, filepath.Join(root, config))
+			break
+		}
+	}
+	for _, pattern := range supervisordIncludes {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		queue = append(queue, matches...)
+	}
+
+	services := []util.Service{}
+	read := map[string]bool{}
+	for len(queue) > 0 {
+		config := queue[0]
+		queue = queue[1:]
+		if read[config] {
+			continue
+		}
+		read[config] = true
+		sections, err := readINIFile(config)
+		if err != nil {
+			continue
+		}
+		for _, s := range sections {
+			if s.name == "include" {
+				for _, pattern := range strings.Fields(s.values["files"]) {
+					if path.IsAbs(pattern) {
+						pattern = filepath.Join(root, pattern)
+					} else {
+						pattern = filepath.Join(filepath.Dir(config), pattern)
+					}
+					matches, _ := filepath.Glob(pattern)
+					queue = append(queue, matches...)
+				}
+				continue
+			}
+			parts := strings.SplitN(s.name, ":", 2)
+			if len(parts) != 2 || (parts[0] != "program" && parts[0] != "fcgi-program" && parts[0] != "eventlistener") {
+				continue
+			}
+			if !parseRepoBool(s.values["autostart"], true) {
+				continue
+			}
+			services = append(services, util.Service{
+				Name:      parts[1],
+				Manager:   util.ManagerSupervisord,
+				Command:   s.values["command"],
+				StartedBy: []string{},
+				Source:    imagePath(root, config),
+			})
+		}
+	}
+	return services
+}
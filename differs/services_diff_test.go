@@ -0,0 +1,80 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetServices(t *testing.T) {
+	root := difftest.NewFS(t).
+		File("lib/systemd/system/nginx.service", "[Unit]\nDescription=nginx\n\n[Service]\nExecStart=-/usr/sbin/nginx -g 'daemon on;'\n\n[Install]\nWantedBy=multi-user.target\n").
+		Symlink("etc/systemd/system/multi-user.target.wants/nginx.service", "/lib/systemd/system/nginx.service").
+		File("lib/systemd/system/getty@.service", "[Service]\nExecStart=/sbin/agetty %I\n\n[Install]\nWantedBy=getty.target\n").
+		Symlink("etc/systemd/system/getty.target.wants/getty@tty1.service", "/lib/systemd/system/getty@.service").
+		File("lib/systemd/system/apt-daily.timer", "[Timer]\nOnCalendar=daily\n\n[Install]\nWantedBy=timers.target\n").
+		File("etc/init.d/cron", "#!/bin/sh\nDAEMON=/usr/sbin/cron\n").
+		Symlink("etc/rc2.d/S01cron", "../init.d/cron").
+		Symlink("etc/rc3.d/S01cron", "../init.d/cron").
+		Symlink("etc/rc0.d/K01cron", "../init.d/cron").
+		File("etc/init.d/sshd", "#!/sbin/openrc-run\ncommand=\"/usr/sbin/sshd\"\n").
+		Symlink("etc/runlevels/default/sshd", "/etc/init.d/sshd").
+		File("etc/supervisord.conf", "[supervisord]\nnodaemon=true\n\n[include]\nfiles = apps/*.ini\n").
+		File("etc/apps/web.ini", "[program:web]\ncommand=/app/bin/web --port 8080\n\n[program:migrate]\ncommand=/app/bin/migrate\nautostart=false\n").
+		Root()
+
+	services, err := getServices(root)
+	if err != nil {
+		t.Fatalf("Error reading services: %s", err)
+	}
+	expected := []util.Service{
+		{Name: "sshd", Manager: util.ManagerOpenRC, Command: "/usr/sbin/sshd", StartedBy: []string{"runlevel default"}, Source: "/etc/init.d/sshd"},
+		{Name: "web", Manager: util.ManagerSupervisord, Command: "/app/bin/web --port 8080", StartedBy: []string{}, Source: "/etc/apps/web.ini"},
+		{Name: "getty@tty1.service", Manager: util.ManagerSystemd, Command: "/sbin/agetty %I", StartedBy: []string{"getty.target"}, Source: "/lib/systemd/system/getty@.service"},
+		{Name: "nginx.service", Manager: util.ManagerSystemd, Command: "/usr/sbin/nginx -g 'daemon on;'", StartedBy: []string{"multi-user.target"}, Source: "/lib/systemd/system/nginx.service"},
+		{Name: "cron", Manager: util.ManagerSysVInit, Command: "/usr/sbin/cron", StartedBy: []string{"runlevel 2", "runlevel 3"}, Source: "/etc/init.d/cron"},
+	}
+	if !reflect.DeepEqual(services, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, services)
+	}
+
+	if _, err := getServices("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestGetServiceDiff(t *testing.T) {
+	cron := util.Service{Name: "cron", Manager: util.ManagerSysVInit, Command: "/usr/sbin/cron", StartedBy: []string{"runlevel 2"}, Source: "/etc/init.d/cron"}
+	web1 := util.Service{Name: "web", Manager: util.ManagerSupervisord, Command: "/app/bin/web", StartedBy: []string{}, Source: "/etc/supervisord.conf"}
+	web2 := web1
+	web2.Command = "/app/bin/web --workers 4"
+	sshd := util.Service{Name: "ssh.service", Manager: util.ManagerSystemd, StartedBy: []string{"multi-user.target"}}
+
+	diff := util.GetServiceDiff([]util.Service{cron, web1}, []util.Service{web2, sshd})
+	expected := util.ServiceDiff{
+		Started: []util.Service{sshd},
+		Stopped: []util.Service{cron},
+		Changed: []util.ServiceChange{{Name: "web", Manager: util.ManagerSupervisord, Service1: web1, Service2: web2}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"errors"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// SharedLayersAnalyzer reports which layers two images share, such as the
+// layers of a common base, and which are unique to each.
+type SharedLayersAnalyzer struct {
+}
+
+func (a SharedLayersAnalyzer) Name() string {
+	return "SharedLayersAnalyzer"
+}
+
+// Diff matches the layers of both images by digest, from their manifests.
+func (a SharedLayersAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	layers1, err := manifestLayers(image1)
+	if err != nil {
+		return &util.SharedLayerDiffResult{}, err
+	}
+	layers2, err := manifestLayers(image2)
+	if err != nil {
+		return &util.SharedLayerDiffResult{}, err
+	}
+	return &util.SharedLayerDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "SharedLayers",
+		Diff:     util.GetSharedLayerDiff(layers1, layers2),
+	}, nil
+}
+
+func (a SharedLayersAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	return nil, errors.New("shared layers compare two images, use container-diff diff")
+}
+
+// manifestLayers lists the layers of an image with their compressed sizes.
+func manifestLayers(image pkgutil.Image) ([]util.LayerRef, error) {
+	layers := []util.LayerRef{}
+	if image.Image == nil {
+		return layers, nil
+	}
+	manifest, err := image.Image.Manifest()
+	if err != nil || manifest == nil {
+		return layers, err
+	}
+	for i, layer := range manifest.Layers {
+		layers = append(layers, util.LayerRef{Index: i, Digest: layer.Digest, Size: layer.Size})
+	}
+	return layers, nil
+}
+
+// sameLayer tells whether two layers have the same digest, and so the same
+// contents, without reading them.
+func sameLayer(layer1, layer2 pkgutil.Layer) bool {
+	return layer1.Digest != (v1.Hash{}) && layer1.Digest == layer2.Digest
+}
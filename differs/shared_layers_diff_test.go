@@ -0,0 +1,96 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+// appendRandomLayer returns base with a new random layer on top.
+func appendRandomLayer(t *testing.T, base v1.Image) v1.Image {
+	layered, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	layers, err := layered.Layers()
+	if err != nil {
+		t.Fatalf("Error reading layers: %s", err)
+	}
+	img, err := mutate.AppendLayers(base, layers...)
+	if err != nil {
+		t.Fatalf("Error appending layer: %s", err)
+	}
+	return img
+}
+
+func TestSharedLayersDiff(t *testing.T) {
+	base, err := random.Image(512, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	image1 := pkgutil.Image{Source: "image1", Image: appendRandomLayer(t, base)}
+	image2 := pkgutil.Image{Source: "image2", Image: appendRandomLayer(t, appendRandomLayer(t, base))}
+	layers1, _ := manifestLayers(image1)
+	layers2, _ := manifestLayers(image2)
+
+	result, err := SharedLayersAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.SharedLayerDiffResult).Diff.(util.SharedLayerDiff)
+	expected := util.SharedLayerDiff{
+		Shared: []util.SharedLayer{
+			{Digest: layers1[0].Digest, Size: layers1[0].Size, Index1: 0, Index2: 0},
+			{Digest: layers1[1].Digest, Size: layers1[1].Size, Index1: 1, Index2: 1},
+		},
+		Unique1:    []util.LayerRef{layers1[2]},
+		Unique2:    []util.LayerRef{layers2[2], layers2[3]},
+		SharedSize: layers1[0].Size + layers1[1].Size,
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
+
+func TestSharedLayersAreNotRead(t *testing.T) {
+	digest := v1.Hash{Algorithm: "sha256", Hex: "ab"}
+	// the shared layer's directory doesn't exist, so reading it would fail
+	image1 := pkgutil.Image{Source: "image1", Layers: []pkgutil.Layer{{FSPath: "/nonexistent", Digest: digest}}}
+	image2 := pkgutil.Image{Source: "image2", Layers: []pkgutil.Layer{{FSPath: "/nonexistent", Digest: digest}}}
+
+	result, err := FileLayerAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if diff := result.(*util.MultipleDirDiffResult).Diff.(util.MultipleDirDiff); !reflect.DeepEqual(diff.DirDiffs, []util.DirDiff{{}}) {
+		t.Errorf("Expected an empty diff of the shared layer but got: %+v", diff.DirDiffs)
+	}
+	result, err = SizeLayerAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if diff := result.(*util.SizeLayerDiffResult).Diff.([]util.SizeDiff); len(diff) != 0 {
+		t.Errorf("Expected no size difference for the shared layer but got: %+v", diff)
+	}
+}
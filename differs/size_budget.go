@@ -0,0 +1,271 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/bytefmt"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// SizeCulpritsPerKind is the number of files, packages and layers listed
+// when an image exceeds its size budget.
+const SizeCulpritsPerKind = 5
+
+// SizeBudgetError is returned when the second image of a diff grew by more
+// than its budget. Explanation ranks what grew it.
+type SizeBudgetError struct {
+	Image1      string
+	Image2      string
+	Increase    int64
+	Budget      int64
+	Explanation string
+}
+
+func (e *SizeBudgetError) Error() string {
+	return fmt.Sprintf("%s grew by %s from %s, over the size budget of %s", e.Image2, bytefmt.ByteSize(uint64(e.Increase)), e.Image1, bytefmt.ByteSize(uint64(e.Budget)))
+}
+
+// CheckSizeBudget fails with a SizeBudgetError when image2 is more than
+// budget bytes larger than image1. Package upgrades are ranked from the
+// package analyzer results, so only those of the analyzers that ran are
+// listed.
+func CheckSizeBudget(image1, image2 pkgutil.Image, results map[string]util.Result, budget int64) error {
+	size1, size2 := imageSize(image1), imageSize(image2)
+	if size2-size1 <= budget {
+		return nil
+	}
+	culprits, err := fileCulprits(image1, image2)
+	if err != nil {
+		return errors.Wrap(err, "ranking grown files")
+	}
+	culprits = append(culprits, packageCulprits(results)...)
+	layers, err := layerCulprits(image1, image2)
+	if err != nil {
+		logrus.Warnf("Could not rank the new layers of %s: %s", image2.Source, err)
+	}
+	culprits = util.RankSizeCulprits(append(culprits, layers...), SizeCulpritsPerKind)
+	return &SizeBudgetError{
+		Image1:      image1.Source,
+		Image2:      image2.Source,
+		Increase:    size2 - size1,
+		Budget:      budget,
+		Explanation: util.ExplainSizeBudget(image1.Source, image2.Source, size1, size2, budget, culprits),
+	}
+}
+
+// fileCulprits lists the files added to image2 or grown in it.
+func fileCulprits(image1, image2 pkgutil.Image) ([]util.SizeCulprit, error) {
+	files1, err := fileSizes(image1)
+	if err != nil {
+		return nil, err
+	}
+	files2, err := fileSizes(image2)
+	if err != nil {
+		return nil, err
+	}
+	culprits := []util.SizeCulprit{}
+	for name, size2 := range files2 {
+		size1, ok := files1[name]
+		switch {
+		case !ok:
+			culprits = append(culprits, util.SizeCulprit{Kind: util.CulpritFile, Name: name, Detail: "new", Size: size2})
+		case size2 > size1:
+			culprits = append(culprits, util.SizeCulprit{Kind: util.CulpritFile, Name: name, Detail: "grown from " + bytefmt.ByteSize(uint64(size1)), Size: size2 - size1})
+		}
+	}
+	return culprits, nil
+}
+
+// fileSizes maps the files of an image, other than directories, to their
+// sizes.
+func fileSizes(image pkgutil.Image) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	if image.Inventory != nil {
+		for name, entry := range image.Inventory {
+			if !entry.IsDir {
+				sizes[name] = entry.Size
+			}
+		}
+		return sizes, nil
+	}
+	err := filepath.Walk(image.FSPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(image.FSPath, path)
+			if err != nil {
+				return err
+			}
+			sizes["/"+filepath.ToSlash(rel)] = info.Size()
+		}
+		return nil
+	})
+	return sizes, err
+}
+
+// packageCulprits lists the packages added or upgraded, as found by the
+// package analyzers among the results.
+func packageCulprits(results map[string]util.Result) []util.SizeCulprit {
+	culprits := []util.SizeCulprit{}
+	for _, result := range results {
+		switch r := result.(type) {
+		case *util.SingleVersionPackageDiffResult:
+			diff, ok := r.Diff.(util.PackageDiff)
+			if !ok {
+				continue
+			}
+			for name, info := range diff.Packages2 {
+				culprits = append(culprits, util.SizeCulprit{Kind: util.CulpritPackage, Name: r.DiffType + " " + name, Detail: "new, " + info.Version, Size: info.Size})
+			}
+			for _, info := range diff.InfoDiff {
+				culprits = append(culprits, util.SizeCulprit{
+					Kind:   util.CulpritPackage,
+					Name:   r.DiffType + " " + info.Package,
+					Detail: info.Info1.Version + " -> " + info.Info2.Version,
+					Size:   info.Info2.Size - info.Info1.Size,
+				})
+			}
+		case *util.MultiVersionPackageDiffResult:
+			culprits = append(culprits, multiVersionCulprits(r.DiffType, r.Diff)...)
+		case *util.NodeDiffResult:
+			culprits = append(culprits, multiVersionCulprits(r.DiffType, r.Diff)...)
+		}
+	}
+	return culprits
+}
+
+func multiVersionCulprits(diffType string, result interface{}) []util.SizeCulprit {
+	culprits := []util.SizeCulprit{}
+	diff, ok := result.(util.MultiVersionPackageDiff)
+	if !ok {
+		return culprits
+	}
+	for name, versions := range diff.Packages2 {
+		culprits = append(culprits, util.SizeCulprit{Kind: util.CulpritPackage, Name: diffType + " " + name, Detail: "new", Size: sumPackageSizes(versions)})
+	}
+	for _, info := range diff.InfoDiff {
+		name := diffType + " " + info.Package
+		if info.Project != "" {
+			name += " (" + info.Project + ")"
+		}
+		culprits = append(culprits, util.SizeCulprit{
+			Kind:   util.CulpritPackage,
+			Name:   name,
+			Detail: packageVersions(info.Info1) + " -> " + packageVersions(info.Info2),
+			Size:   sumPackageInfoSizes(info.Info2) - sumPackageInfoSizes(info.Info1),
+		})
+	}
+	return culprits
+}
+
+func sumPackageSizes(versions map[string]util.PackageInfo) int64 {
+	var size int64
+	for _, info := range versions {
+		size += info.Size
+	}
+	return size
+}
+
+func sumPackageInfoSizes(infos []util.PackageInfo) int64 {
+	var size int64
+	for _, info := range infos {
+		size += info.Size
+	}
+	return size
+}
+
+func packageVersions(infos []util.PackageInfo) string {
+	versions := []string{}
+	for _, info := range infos {
+		versions = append(versions, info.Version)
+	}
+	if len(versions) == 0 {
+		return "none"
+	}
+	return strings.Join(versions, ", ")
+}
+
+// layerCulprits lists the layers of image2 that image1 lacks, with their
+// compressed sizes and the history entries that created them.
+func layerCulprits(image1, image2 pkgutil.Image) ([]util.SizeCulprit, error) {
+	if image1.Image == nil || image2.Image == nil {
+		return nil, nil
+	}
+	layers1, err := image1.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	diffIDs := map[string]bool{}
+	for _, layer := range layers1 {
+		if diffID, err := layer.DiffID(); err == nil {
+			diffIDs[diffID.String()] = true
+		}
+	}
+	layers2, err := image2.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	commands := []string{}
+	if config, err := image2.Image.ConfigFile(); err == nil && config != nil {
+		for _, h := range config.History {
+			if !h.EmptyLayer {
+				commands = append(commands, h.CreatedBy)
+			}
+		}
+	}
+	culprits := []util.SizeCulprit{}
+	for i, layer := range layers2 {
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		if diffIDs[diffID.String()] {
+			continue
+		}
+		size, err := layer.Size()
+		if err != nil {
+			return nil, err
+		}
+		culprit := util.SizeCulprit{Kind: util.CulpritLayer, Name: fmt.Sprintf("layer %d", i), Detail: "compressed", Size: size}
+		if len(commands) == len(layers2) {
+			culprit.Detail += ", " + shortCommand(commands[i])
+		}
+		culprits = append(culprits, culprit)
+	}
+	return culprits, nil
+}
+
+// shortCommand trims a history entry to fit on a line.
+func shortCommand(command string) string {
+	command = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), "/bin/sh -c"))
+	if i := strings.Index(command, "#(nop)"); i >= 0 {
+		command = strings.TrimSpace(command[i+len("#(nop)"):])
+	}
+	if len(command) > 60 {
+		command = command[:57] + "..."
+	}
+	return command
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestCheckSizeBudget(t *testing.T) {
+	image1 := difftest.NewFS(t).
+		File("/usr/lib/libssl.so", strings.Repeat("a", 100)).
+		File("/etc/app.conf", "port=80").
+		Image("image1")
+	image2 := difftest.NewFS(t).
+		File("/usr/lib/libssl.so", strings.Repeat("a", 400)).
+		File("/etc/app.conf", "port=8").
+		File("/app/bundle.js", strings.Repeat("b", 1500)).
+		File("/app/README", "docs").
+		Image("image2")
+	results := map[string]util.Result{
+		"apt": &util.SingleVersionPackageDiffResult{
+			DiffType: "Apt",
+			Diff: util.PackageDiff{
+				Packages1: map[string]util.PackageInfo{},
+				Packages2: map[string]util.PackageInfo{"nodejs": {Version: "18.19.0", Size: 1504}},
+				InfoDiff: []util.Info{
+					{Package: "libssl3", Info1: util.PackageInfo{Version: "3.0.2", Size: 100}, Info2: util.PackageInfo{Version: "3.0.11", Size: 400}},
+					{Package: "tzdata", Info1: util.PackageInfo{Version: "2023c", Size: 50}, Info2: util.PackageInfo{Version: "2024a", Size: 40}},
+				},
+			},
+		},
+	}
+
+	if err := CheckSizeBudget(image1, image2, results, 2000); err != nil {
+		t.Errorf("Expected a growth under budget to pass but got: %s", err)
+	}
+
+	err := CheckSizeBudget(image1, image2, results, 1000)
+	budgetErr, ok := err.(*SizeBudgetError)
+	if !ok {
+		t.Fatalf("Expected a SizeBudgetError but got: %v", err)
+	}
+	if budgetErr.Increase != 1803 {
+		t.Errorf("Expected an increase of 1803 bytes but got %d", budgetErr.Increase)
+	}
+	expected := `image2 is 1.8K larger than image1 (1.9K, was 107B), over the size budget of 1000B.
+
+Largest new or grown files:
+1. /app/bundle.js +1.5K (new)
+2. /usr/lib/libssl.so +300B (grown from 100B)
+3. /app/README +4B (new)
+
+Biggest package upgrades:
+1. Apt nodejs +1.5K (new, 18.19.0)
+2. Apt libssl3 +300B (3.0.2 -> 3.0.11)
+`
+	if budgetErr.Explanation != expected {
+		t.Errorf("Expected explanation:\n%s\nbut got:\n%s", expected, budgetErr.Explanation)
+	}
+}
+
+func TestRankSizeCulprits(t *testing.T) {
+	culprits := []util.SizeCulprit{}
+	for _, name := range []string{"a", "b", "c"} {
+		culprits = append(culprits, util.SizeCulprit{Kind: util.CulpritLayer, Name: name, Size: int64(len(culprits) + 1)})
+	}
+	ranked := util.RankSizeCulprits(culprits, 2)
+	if len(ranked) != 2 || ranked[0].Name != "c" || ranked[1].Name != "b" {
+		t.Errorf("Expected the two largest culprits, largest first, but got: %+v", ranked)
+	}
+}
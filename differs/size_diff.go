@@ -21,6 +21,7 @@ import (
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
 )
 
 type SizeAnalyzer struct {
@@ -33,13 +34,17 @@ func (a SizeAnalyzer) Name() string {
 // SizeDiff diffs two images and compares their size
 func (a SizeAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
 	diff := []util.SizeDiff{}
-	size1 := pkgutil.GetSize(image1.FSPath)
-	size2 := pkgutil.GetSize(image2.FSPath)
+	size1 := imageSize(image1)
+	size2 := imageSize(image2)
+	compressed1 := sumSizes(compressedLayerSizes(image1))
+	compressed2 := sumSizes(compressedLayerSizes(image2))
 
-	if size1 != size2 {
+	if size1 != size2 || compressed1 != compressed2 {
 		diff = append(diff, util.SizeDiff{
-			Size1: size1,
-			Size2: size2,
+			Size1:           size1,
+			Size2:           size2,
+			CompressedSize1: compressed1,
+			CompressedSize2: compressed2,
 		})
 	}
 
@@ -54,9 +59,10 @@ func (a SizeAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
 func (a SizeAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
 	entries := []util.SizeEntry{
 		{
-			Name:   image.Source,
-			Digest: image.Digest,
-			Size:   pkgutil.GetSize(image.FSPath),
+			Name:           image.Source,
+			Digest:         image.Digest,
+			Size:           imageSize(image),
+			CompressedSize: sumSizes(compressedLayerSizes(image)),
 		},
 	}
 
@@ -67,6 +73,62 @@ func (a SizeAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
 	}, nil
 }
 
+// imageSize returns the size of the image filesystem, from the streamed
+// inventory when the image wasn't extracted.
+func imageSize(image pkgutil.Image) int64 {
+	if image.Inventory == nil {
+		return pkgutil.GetSize(image.FSPath)
+	}
+	var size int64
+	for _, entry := range image.Inventory {
+		if !entry.IsDir {
+			size += entry.Size
+		}
+	}
+	return size
+}
+
+// compressedLayerSizes returns the compressed size of each layer, as listed
+// in the image manifest, or nil when there is no manifest to read.
+func compressedLayerSizes(image pkgutil.Image) []int64 {
+	if image.Image == nil {
+		return nil
+	}
+	manifest, err := image.Image.Manifest()
+	if err != nil {
+		logrus.Warnf("Could not read the manifest of %s for compressed sizes: %s", image.Source, err)
+		return nil
+	}
+	if manifest == nil {
+		return nil
+	}
+	sizes := []int64{}
+	for _, layer := range manifest.Layers {
+		sizes = append(sizes, layer.Size)
+	}
+	return sizes
+}
+
+func sumSizes(sizes []int64) int64 {
+	var sum int64
+	for _, size := range sizes {
+		sum += size
+	}
+	return sum
+}
+
+// layerSize returns the size at index of sizes, 0 if unknown and -1 if the
+// image has fewer layers.
+func layerSize(sizes []int64, index, layers int) int64 {
+	switch {
+	case index >= layers:
+		return -1
+	case index >= len(sizes):
+		return 0
+	}
+	return sizes[index]
+}
+
 type SizeLayerAnalyzer struct {
 }
 
@@ -74,9 +136,12 @@ func (a SizeLayerAnalyzer) Name() string {
 	return "SizeLayerAnalyzer"
 }
 
-// SizeLayerDiff diffs the layers of two images and compares their size
+// SizeLayerDiff diffs the layers of two images and compares their size,
+// extracted and compressed
 func (a SizeLayerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
 	var layerDiffs []util.SizeDiff
+	compressed1 := compressedLayerSizes(image1)
+	compressed2 := compressedLayerSizes(image2)
 
 	maxLayer := len(image1.Layers)
 	if len(image2.Layers) > maxLayer {
@@ -84,6 +149,9 @@ func (a SizeLayerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, erro
 	}
 
 	for index := 0; index < maxLayer; index++ {
+		if index < len(image1.Layers) && index < len(image2.Layers) && sameLayer(image1.Layers[index], image2.Layers[index]) {
+			continue
+		}
 		var size1, size2 int64 = -1, -1
 		if index < len(image1.Layers) {
 			size1 = pkgutil.GetSize(image1.Layers[index].FSPath)
@@ -92,11 +160,16 @@ func (a SizeLayerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, erro
 			size2 = pkgutil.GetSize(image2.Layers[index].FSPath)
 		}
 
-		if size1 != size2 {
+		compressedSize1 := layerSize(compressed1, index, len(image1.Layers))
+		compressedSize2 := layerSize(compressed2, index, len(image2.Layers))
+
+		if size1 != size2 || compressedSize1 != compressedSize2 {
 			diff := util.SizeDiff{
-				Name:  strconv.Itoa(index),
-				Size1: size1,
-				Size2: size2,
+				Name:            strconv.Itoa(index),
+				Size1:           size1,
+				Size2:           size2,
+				CompressedSize1: compressedSize1,
+				CompressedSize2: compressedSize2,
 			}
 			layerDiffs = append(layerDiffs, diff)
 		}
@@ -112,11 +185,13 @@ func (a SizeLayerAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, erro
 
 func (a SizeLayerAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
 	var entries []util.SizeEntry
+	compressed := compressedLayerSizes(image)
 	for index, layer := range image.Layers {
 		entry := util.SizeEntry{
-			Name:   strconv.Itoa(index),
-			Digest: layer.Digest,
-			Size:   pkgutil.GetSize(layer.FSPath),
+			Name:           strconv.Itoa(index),
+			Digest:         layer.Digest,
+			Size:           pkgutil.GetSize(layer.FSPath),
+			CompressedSize: layerSize(compressed, index, len(image.Layers)),
 		}
 		entries = append(entries, entry)
 	}
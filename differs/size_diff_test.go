@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+// layeredImage returns an image with a layer for each of the contents, and
+// a random manifest of as many layers of layerSize bytes.
+func layeredImage(t *testing.T, layerSize int64, contents ...string) pkgutil.Image {
+	img, err := random.Image(layerSize, int64(len(contents)))
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	image := pkgutil.Image{Source: "image", Image: img}
+	for _, content := range contents {
+		fs := difftest.NewFS(t).File("data", content)
+		image.Layers = append(image.Layers, pkgutil.Layer{FSPath: fs.Root()})
+	}
+	return image
+}
+
+func TestSizeLayerAnalyze(t *testing.T) {
+	image := layeredImage(t, 512, "aa", "bbbb")
+	result, err := SizeLayerAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	analysis := result.(*util.SizeLayerAnalyzeResult).Analysis.([]util.SizeEntry)
+	manifest, _ := image.Image.Manifest()
+	for i, entry := range analysis {
+		if entry.CompressedSize != manifest.Layers[i].Size {
+			t.Errorf("Expected layer %d compressed size %d but got %d", i, manifest.Layers[i].Size, entry.CompressedSize)
+		}
+	}
+	if analysis[0].Size != 2 || analysis[1].Size != 4 {
+		t.Errorf("Expected layer sizes 2 and 4 but got %+v", analysis)
+	}
+}
+
+func TestSizeLayerDiff(t *testing.T) {
+	image1 := layeredImage(t, 512, "aa", "bbbb")
+	image2 := layeredImage(t, 512, "aa", "bb", "cccc")
+	// the manifest of image1 lists two layers, leaving the compressed size
+	// of the third unknown
+	image2.Image = image1.Image
+	manifest, _ := image1.Image.Manifest()
+
+	result, err := SizeLayerAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.SizeLayerDiffResult).Diff.([]util.SizeDiff)
+	expected := []util.SizeDiff{
+		{Name: "1", Size1: 4, Size2: 2, CompressedSize1: manifest.Layers[1].Size, CompressedSize2: manifest.Layers[1].Size},
+		{Name: "2", Size1: -1, Size2: 4, CompressedSize1: -1, CompressedSize2: 0},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+
+	var text strings.Builder
+	if err := result.OutputText(&text, "sizelayer", util.OutputOptions{}); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	for _, change := range []string{"-2B", "added", "unknown"} {
+		if !strings.Contains(text.String(), change) {
+			t.Errorf("Expected %q in output:\n%s", change, text.String())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// snapd state and snap locations
+const (
+	snapStateFile = "var/lib/snapd/state.json"
+	snapBlobDir   = "var/lib/snapd/snaps"
+	snapMountDir  = "snap"
+)
+
+type SnapAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a SnapAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a SnapAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a SnapAnalyzer) Name() string {
+	return "SnapAnalyzer"
+}
+
+// Diff compares the snaps installed by snapd.
+func (a SnapAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := singleVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a SnapAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := singleVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// snapSideInfo holds the fields of a snap revision recorded by snapd.
+type snapSideInfo struct {
+	Revision json.RawMessage `json:"revision"`
+	Version  string          `json:"version"`
+}
+
+// snapSequenceEntry is a revision of a snap. Newer snapd versions nest its
+// side info under "side-info".
+type snapSequenceEntry struct {
+	snapSideInfo
+	SideInfo *snapSideInfo `json:"side-info"`
+}
+
+type snapState struct {
+	Data struct {
+		Snaps map[string]struct {
+			Sequence []snapSequenceEntry `json:"sequence"`
+			Current  json.RawMessage     `json:"current"`
+		} `json:"snaps"`
+	} `json:"data"`
+}
+
+func (a SnapAnalyzer) getPackages(image pkgutil.Image) (map[string]util.PackageInfo, error) {
+	return getSnapPackages(image.FSPath)
+}
+
+// getSnapPackages reads the current revision of each snap from the snapd
+// state. Snaps are squashfs images mounted at runtime, so their version is
+// only known when snapd recorded it or the snap is unpacked under /snap.
+func getSnapPackages(root string) (map[string]util.PackageInfo, error) {
+	packages := make(map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return packages, err
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(root, snapStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// snapd is not installed
+			return packages, nil
+		}
+		return packages, err
+	}
+	var state snapState
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return packages, err
+	}
+	for name, snap := range state.Data.Snaps {
+		current := snapRevision(snap.Current)
+		if current == "" {
+			continue
+		}
+		for _, entry := range snap.Sequence {
+			info := entry.snapSideInfo
+			if entry.SideInfo != nil {
+				info = *entry.SideInfo
+			}
+			if snapRevision(info.Revision) != current {
+				continue
+			}
+			version := info.Version
+			if version == "" {
+				version = readSnapYAMLVersion(filepath.Join(root, snapMountDir, name, current, "meta", "snap.yaml"))
+			}
+			packages[name] = util.PackageInfo{
+				Version: snapVersion(version, current),
+				Size:    snapSize(filepath.Join(root, snapBlobDir, name+"_"+current+".snap")),
+			}
+		}
+	}
+	return packages, nil
+}
+
+// snapRevision returns a revision, which snapd stores either as a number or
+// as a string such as "x1" for locally installed snaps.
+func snapRevision(raw json.RawMessage) string {
+	return strings.Trim(string(raw), `"`)
+}
+
+// snapVersion formats a snap version the way `snap list` does, with the
+// revision in parentheses.
+func snapVersion(version, revision string) string {
+	if version == "" {
+		return "(" + revision + ")"
+	}
+	return version + " (" + revision + ")"
+}
+
+// readSnapYAMLVersion reads the top-level version key of a snap.yaml.
+func readSnapYAMLVersion(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), "version:"); value != scanner.Text() {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// snapSize returns the size of a snap file, or -1 if it isn't in the image.
+func snapSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
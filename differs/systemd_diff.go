@@ -0,0 +1,272 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// systemd unit directories, in order of precedence. Units are enabled by
+// linking them from the .wants and .requires directories of the admin
+// directory.
+var (
+	systemdAdminDir   = "etc/systemd/system"
+	systemdUnitDirs   = []string{systemdAdminDir, "run/systemd/system", "lib/systemd/system", "usr/lib/systemd/system"}
+	systemdPresetDirs = []string{"etc/systemd/system-preset", "run/systemd/system-preset", "lib/systemd/system-preset", "usr/lib/systemd/system-preset"}
+)
+
+var unitSuffixes = []string{".service", ".socket", ".timer", ".target", ".path", ".mount", ".automount", ".swap", ".slice", ".device"}
+
+type SystemdAnalyzer struct {
+}
+
+func (a SystemdAnalyzer) Name() string {
+	return "SystemdAnalyzer"
+}
+
+// Diff compares which systemd units are enabled in two images.
+func (a SystemdAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	units1, err := getSystemdUnits(image1.FSPath)
+	if err != nil {
+		return &util.SystemdDiffResult{}, err
+	}
+	units2, err := getSystemdUnits(image2.FSPath)
+	if err != nil {
+		return &util.SystemdDiffResult{}, err
+	}
+	return &util.SystemdDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Systemd",
+		Diff:     util.GetSystemdDiff(units1, units2),
+	}, nil
+}
+
+func (a SystemdAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	units, err := getSystemdUnits(image.FSPath)
+	if err != nil {
+		return &util.SystemdAnalyzeResult{}, err
+	}
+	return &util.SystemdAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Systemd",
+		Analysis:    util.SortedSystemdUnits(units),
+	}, nil
+}
+
+// getSystemdUnits reads the unit files and enablement links of an image. A
+// unit is masked when the admin directory links it to /dev/null, enabled
+// when an admin .wants or .requires directory links to it, static when its
+// unit file has no [Install] section or only vendor directories pull it in,
+// and disabled otherwise.
+func getSystemdUnits(root string) (map[string]util.SystemdUnit, error) {
+	units := make(map[string]util.SystemdUnit)
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return units, err
+	}
+
+	unitFiles := map[string]string{}
+	masked := map[string]bool{}
+	enabled := map[string]bool{}
+	wantedBy := map[string][]string{}
+	for _, dir := range systemdUnitDirs {
+		contents, err := ioutil.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			continue
+		}
+		for _, c := range contents {
+			name := c.Name()
+			if c.IsDir() {
+				dependent := strings.TrimSuffix(strings.TrimSuffix(name, ".wants"), ".requires")
+				if dependent == name {
+					continue
+				}
+				links, err := ioutil.ReadDir(filepath.Join(root, dir, name))
+				if err != nil {
+					continue
+				}
+				for _, link := range links {
+					if !isUnitName(link.Name()) {
+						continue
+					}
+					wantedBy[link.Name()] = append(wantedBy[link.Name()], dependent)
+					if dir == systemdAdminDir {
+						enabled[link.Name()] = true
+					}
+				}
+				continue
+			}
+			if !isUnitName(name) {
+				continue
+			}
+			unitPath := filepath.Join(root, dir, name)
+			if c.Mode()&os.ModeSymlink != 0 {
+				if target, err := os.Readlink(unitPath); err == nil && target == "/dev/null" {
+					masked[name] = true
+					continue
+				}
+			}
+			// directories earlier in the list override later ones
+			if _, ok := unitFiles[name]; !ok {
+				unitFiles[name] = unitPath
+			}
+		}
+	}
+
+	names := map[string]bool{}
+	for name := range unitFiles {
+		names[name] = true
+	}
+	for name := range wantedBy {
+		names[name] = true
+	}
+	for name := range masked {
+		names[name] = true
+	}
+	presets := readPresets(root)
+	for name := range names {
+		unit := util.SystemdUnit{Unit: name, WantedBy: dedupe(wantedBy[name]), Preset: presetFor(presets, name)}
+		switch {
+		case masked[name]:
+			unit.State = util.UnitMasked
+		case enabled[name]:
+			unit.State = util.UnitEnabled
+		case len(wantedBy[name]) != 0:
+			unit.State = util.UnitStatic
+		case unitFiles[name] != "" && !hasInstallSection(root, unitFiles[name]):
+			unit.State = util.UnitStatic
+		default:
+			unit.State = util.UnitDisabled
+		}
+		units[name] = unit
+	}
+	return units, nil
+}
+
+func isUnitName(name string) bool {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasInstallSection reports whether a unit file can be enabled. Absolute
+// symlinks are resolved inside the image; unreadable files are assumed to
+// be installable.
+func hasInstallSection(root, unitPath string) bool {
+	if target, err := os.Readlink(unitPath); err == nil {
+		if filepath.IsAbs(target) {
+			unitPath = filepath.Join(root, target)
+		} else {
+			unitPath = filepath.Join(filepath.Dir(unitPath), target)
+		}
+	}
+	file, err := os.Open(unitPath)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "[Install]" {
+			return true
+		}
+	}
+	return false
+}
+
+// presetRule is a line of a systemd preset file, e.g. "enable foo.service".
+type presetRule struct {
+	action  string
+	pattern string
+}
+
+// readPresets reads the preset rules of an image in the order systemd
+// applies them: files sorted by name across all preset directories, with a
+// file shadowing same-named files in lower precedence directories.
+func readPresets(root string) []presetRule {
+	files := map[string]string{}
+	for _, dir := range systemdPresetDirs {
+		contents, err := ioutil.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			continue
+		}
+		for _, c := range contents {
+			if _, ok := files[c.Name()]; !ok && strings.HasSuffix(c.Name(), ".preset") {
+				files[c.Name()] = filepath.Join(root, dir, c.Name())
+			}
+		}
+	}
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := []presetRule{}
+	for _, name := range names {
+		file, err := os.Open(files[name])
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || (fields[0] != "enable" && fields[0] != "disable") {
+				continue
+			}
+			rules = append(rules, presetRule{action: fields[0], pattern: fields[1]})
+		}
+		file.Close()
+	}
+	return rules
+}
+
+// presetFor returns the action of the first preset rule matching unit.
+func presetFor(rules []presetRule, unit string) string {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.pattern, unit); ok {
+			return r.action
+		}
+	}
+	return ""
+}
+
+func dedupe(list []string) []string {
+	seen := map[string]bool{}
+	deduped := []string{}
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			deduped = append(deduped, s)
+		}
+	}
+	sort.Strings(deduped)
+	return deduped
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetSystemdUnits(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		path     string
+		expected map[string]util.SystemdUnit
+		err      bool
+	}{
+		{
+			descrip:  "no directory",
+			path:     "testDirs/notThere",
+			expected: map[string]util.SystemdUnit{},
+			err:      true,
+		},
+		{
+			descrip:  "no units",
+			path:     "testDirs/noPackages",
+			expected: map[string]util.SystemdUnit{},
+		},
+		{
+			descrip: "enabled, static and masked units with presets",
+			path:    "testDirs/systemd/image2",
+			expected: map[string]util.SystemdUnit{
+				"cron.service":             {Unit: "cron.service", State: util.UnitEnabled, WantedBy: []string{"multi-user.target"}, Preset: "enable"},
+				"ssh.service":              {Unit: "ssh.service", State: util.UnitEnabled, WantedBy: []string{"multi-user.target"}, Preset: "enable"},
+				"systemd-journald.service": {Unit: "systemd-journald.service", State: util.UnitStatic, WantedBy: []string{"sysinit.target"}, Preset: "disable"},
+				"telnet.socket":            {Unit: "telnet.socket", State: util.UnitMasked, WantedBy: []string{}, Preset: "disable"},
+			},
+		},
+	}
+	for _, test := range testCases {
+		units, err := getSystemdUnits(test.path)
+		if err != nil && !test.err {
+			t.Errorf("%s: Got unexpected error: %s", test.descrip, err)
+		}
+		if err == nil && test.err {
+			t.Errorf("%s: Expected error but got none", test.descrip)
+		}
+		if !reflect.DeepEqual(units, test.expected) {
+			t.Errorf("%s: Expected: %v but got: %v", test.descrip, test.expected, units)
+		}
+	}
+}
+
+func TestSystemdDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", FSPath: "testDirs/systemd/image1"}
+	image2 := pkgutil.Image{Source: "image2", FSPath: "testDirs/systemd/image2"}
+	result, err := SystemdAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Error diffing images: %s", err)
+	}
+	diff := result.(*util.SystemdDiffResult).Diff.(util.SystemdDiff)
+	expected := util.SystemdDiff{
+		Enabled:  []string{"ssh.service"},
+		Disabled: []string{},
+		Changes: []util.SystemdUnitChange{
+			{
+				Unit:      "ssh.service",
+				State1:    util.UnitDisabled,
+				State2:    util.UnitEnabled,
+				WantedBy1: []string{},
+				WantedBy2: []string{"multi-user.target"},
+				Preset1:   "disable",
+				Preset2:   "enable",
+			},
+			{
+				Unit:      "telnet.socket",
+				State1:    util.UnitDisabled,
+				State2:    util.UnitMasked,
+				WantedBy1: []string{},
+				WantedBy2: []string{},
+				Preset1:   "disable",
+				Preset2:   "disable",
+			},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
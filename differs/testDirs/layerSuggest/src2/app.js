@@ -0,0 +1 @@
+v2
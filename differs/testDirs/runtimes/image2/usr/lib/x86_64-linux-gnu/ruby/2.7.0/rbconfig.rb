@@ -0,0 +1,3 @@
+module RbConfig
+  CONFIG["RUBY_PROGRAM_VERSION"] = "2.7.4"
+end
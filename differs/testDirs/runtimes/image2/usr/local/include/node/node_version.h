@@ -0,0 +1,3 @@
+#define NODE_MAJOR_VERSION 16
+#define NODE_MINOR_VERSION 13
+#define NODE_PATCH_VERSION 0
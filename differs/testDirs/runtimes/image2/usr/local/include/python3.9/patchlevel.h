@@ -0,0 +1 @@
+#define PY_VERSION "3.9.7"
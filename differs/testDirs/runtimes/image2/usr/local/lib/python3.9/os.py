@@ -0,0 +1 @@
+# os
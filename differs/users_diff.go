@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	passwdFile = "etc/passwd"
+	groupFile  = "etc/group"
+	shadowFile = "etc/shadow"
+)
+
+type UsersAnalyzer struct {
+}
+
+func (a UsersAnalyzer) Name() string {
+	return "UsersAnalyzer"
+}
+
+// Diff compares the user and group accounts of two images.
+func (a UsersAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	accounts1, err := getUserAccounts(image1.FSPath)
+	if err != nil {
+		return &util.UserDiffResult{}, err
+	}
+	accounts2, err := getUserAccounts(image2.FSPath)
+	if err != nil {
+		return &util.UserDiffResult{}, err
+	}
+	return &util.UserDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Users",
+		Diff:     util.GetAccountDiff(accounts1, accounts2),
+	}, nil
+}
+
+func (a UsersAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	accounts, err := getUserAccounts(image.FSPath)
+	if err != nil {
+		return &util.UserAnalyzeResult{}, err
+	}
+	return &util.UserAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Users",
+		Analysis:    accounts,
+	}, nil
+}
+
+// getUserAccounts reads the users of /etc/passwd and the groups of
+// /etc/group. Only the names of the /etc/shadow entries are read. Images
+// without these files, such as scratch images, have no accounts.
+func getUserAccounts(root string) (util.UserAccounts, error) {
+	accounts := util.UserAccounts{Users: []util.UserAccount{}, Groups: []util.GroupAccount{}}
+	if _, err := os.Stat(root); err != nil {
+		// invalid image directory path
+		return accounts, err
+	}
+
+	groupNames := map[int]string{}
+	memberships := map[string][]string{}
+	for _, fields := range readColonFile(filepath.Join(root, groupFile), 4) {
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			logrus.Debugf("skipping group %s with invalid GID %s", fields[0], fields[2])
+			continue
+		}
+		group := util.GroupAccount{Name: fields[0], GID: gid, Members: []string{}}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				group.Members = append(group.Members, member)
+				memberships[member] = append(memberships[member], group.Name)
+			}
+		}
+		if _, ok := groupNames[gid]; !ok {
+			groupNames[gid] = group.Name
+		}
+		accounts.Groups = append(accounts.Groups, group)
+	}
+
+	shadowed := map[string]bool{}
+	for _, fields := range readColonFile(filepath.Join(root, shadowFile), 2) {
+		shadowed[fields[0]] = true
+	}
+
+	for _, fields := range readColonFile(filepath.Join(root, passwdFile), 7) {
+		uid, errUID := strconv.Atoi(fields[2])
+		gid, errGID := strconv.Atoi(fields[3])
+		if errUID != nil || errGID != nil {
+			logrus.Debugf("skipping user %s with invalid UID or GID", fields[0])
+			continue
+		}
+		user := util.UserAccount{
+			Name:   fields[0],
+			UID:    uid,
+			GID:    gid,
+			Groups: []string{},
+			Home:   fields[5],
+			Shell:  fields[6],
+			Shadow: shadowed[fields[0]],
+		}
+		if name, ok := groupNames[gid]; ok {
+			user.Groups = append(user.Groups, name)
+		}
+		for _, group := range memberships[user.Name] {
+			if len(user.Groups) == 0 || group != user.Groups[0] {
+				user.Groups = append(user.Groups, group)
+			}
+		}
+		accounts.Users = append(accounts.Users, user)
+	}
+	util.SortUserAccounts(accounts)
+	return accounts, nil
+}
+
+// readColonFile reads the entries of a colon separated account database
+// with at least n fields, skipping comments and the +/- lines of NIS
+// compat mode.
+func readColonFile(path string, n int) [][]string {
+	entries := [][]string{}
+	file, err := os.Open(path)
+	if err != nil {
+		return entries
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < n || fields[0] == "" {
+			continue
+		}
+		entries = append(entries, fields)
+	}
+	return entries
+}
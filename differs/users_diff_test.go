@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestGetUserAccounts(t *testing.T) {
+	root := difftest.NewFS(t).
+		File("etc/passwd", "root:x:0:0:root:/root:/bin/bash\n# comment\napp:x:1000:1000:App:/home/app:/bin/sh\nnobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin\n+@netgroup::::::\nbroken:x:abc:0::/:/bin/sh\n").
+		File("etc/group", "root:x:0:\nsudo:x:27:app\napp:x:1000:\nnogroup:x:65534:\n").
+		File("etc/shadow", "root:$6$secret:19000:0:99999:7:::\napp:!:19000:0:99999:7:::\n").
+		Root()
+
+	accounts, err := getUserAccounts(root)
+	if err != nil {
+		t.Fatalf("Error reading user accounts: %s", err)
+	}
+	expected := util.UserAccounts{
+		Users: []util.UserAccount{
+			{Name: "root", UID: 0, GID: 0, Groups: []string{"root"}, Home: "/root", Shell: "/bin/bash", Shadow: true},
+			{Name: "app", UID: 1000, GID: 1000, Groups: []string{"app", "sudo"}, Home: "/home/app", Shell: "/bin/sh", Shadow: true},
+			{Name: "nobody", UID: 65534, GID: 65534, Groups: []string{"nogroup"}, Home: "/nonexistent", Shell: "/usr/sbin/nologin"},
+		},
+		Groups: []util.GroupAccount{
+			{Name: "root", GID: 0, Members: []string{}},
+			{Name: "sudo", GID: 27, Members: []string{"app"}},
+			{Name: "app", GID: 1000, Members: []string{}},
+			{Name: "nogroup", GID: 65534, Members: []string{}},
+		},
+	}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, accounts)
+	}
+
+	empty, err := getUserAccounts(difftest.NewFS(t).Root())
+	if err != nil || len(empty.Users) != 0 || len(empty.Groups) != 0 {
+		t.Errorf("Expected no accounts in an image without /etc/passwd, got: %+v, %v", empty, err)
+	}
+	if _, err := getUserAccounts("testDirs/notThere"); err == nil {
+		t.Errorf("Expected an error for a missing image directory")
+	}
+}
+
+func TestGetAccountDiff(t *testing.T) {
+	root := util.UserAccount{Name: "root", UID: 0, GID: 0, Groups: []string{"root"}, Home: "/root", Shell: "/bin/bash"}
+	app1 := util.UserAccount{Name: "app", UID: 1000, GID: 1000, Groups: []string{"app"}, Home: "/home/app", Shell: "/bin/sh"}
+	app2 := app1
+	app2.Groups = []string{"app", "docker"}
+	toor := util.UserAccount{Name: "toor", UID: 0, GID: 0, Groups: []string{"root"}, Home: "/root", Shell: "/bin/sh"}
+	games := util.UserAccount{Name: "games", UID: 5, GID: 60, Groups: []string{}, Home: "/usr/games", Shell: "/usr/sbin/nologin"}
+	docker1 := util.GroupAccount{Name: "docker", GID: 999, Members: []string{}}
+	docker2 := util.GroupAccount{Name: "docker", GID: 999, Members: []string{"app"}}
+
+	diff := util.GetAccountDiff(
+		util.UserAccounts{Users: []util.UserAccount{root, games, app1}, Groups: []util.GroupAccount{docker1}},
+		util.UserAccounts{Users: []util.UserAccount{root, toor, app2}, Groups: []util.GroupAccount{docker2}},
+	)
+	expected := util.AccountDiff{
+		Warnings: []string{
+			"user toor has UID 0, making it root-equivalent",
+			"user toor is a member of the privileged group root",
+			"user app is a member of the privileged group docker",
+		},
+		UsersAdded:    []util.UserAccount{toor},
+		UsersRemoved:  []util.UserAccount{games},
+		UsersChanged:  []util.UserAccountChange{{Name: "app", User1: app1, User2: app2}},
+		GroupsAdded:   []util.GroupAccount{},
+		GroupsRemoved: []util.GroupAccount{},
+		GroupsChanged: []util.GroupAccountChange{{Name: "docker", Group1: docker1, Group2: docker2}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// VersionChangeError is returned when packages changed version in a way the
+// diff was told to fail on, such as a downgrade. Changes lists them, e.g.
+// "Apt libc6: 2.36-9 -> 2.31-13 (downgrade)".
+type VersionChangeError struct {
+	Changes []string
+}
+
+func (e *VersionChangeError) Error() string {
+	return fmt.Sprintf("%d disallowed package version change(s):\n%s", len(e.Changes), strings.Join(e.Changes, "\n"))
+}
+
+// CheckVersionChanges fails with a VersionChangeError when a package found
+// by the package analyzers among the results changed version by one of the
+// kinds given, e.g. util.ChangeDowngrade.
+func CheckVersionChanges(results map[string]util.Result, kinds []string) error {
+	disallowed := map[string]bool{}
+	for _, kind := range kinds {
+		disallowed[kind] = true
+	}
+	changes := []string{}
+	for _, result := range results {
+		switch r := result.(type) {
+		case *util.SingleVersionPackageDiffResult:
+			diff, ok := r.Diff.(util.PackageDiff)
+			if !ok {
+				continue
+			}
+			for _, info := range diff.InfoDiff {
+				if disallowed[info.Change] {
+					changes = append(changes, fmt.Sprintf("%s %s: %s -> %s (%s)", r.DiffType, info.Package, info.Info1.Version, info.Info2.Version, info.Change))
+				}
+			}
+		case *util.MultiVersionPackageDiffResult:
+			changes = append(changes, multiVersionChanges(r.DiffType, r.Diff, disallowed)...)
+		case *util.NodeDiffResult:
+			changes = append(changes, multiVersionChanges(r.DiffType, r.Diff, disallowed)...)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.Strings(changes)
+	return &VersionChangeError{Changes: changes}
+}
+
+func multiVersionChanges(diffType string, result interface{}, disallowed map[string]bool) []string {
+	changes := []string{}
+	diff, ok := result.(util.MultiVersionPackageDiff)
+	if !ok {
+		return changes
+	}
+	for _, info := range diff.InfoDiff {
+		if !disallowed[info.Change] {
+			continue
+		}
+		name := diffType + " " + info.Package
+		if info.Project != "" {
+			name += " (" + info.Project + ")"
+		}
+		changes = append(changes, fmt.Sprintf("%s: %s -> %s (%s)", name, packageVersions(info.Info1), packageVersions(info.Info2), info.Change))
+	}
+	return changes
+}
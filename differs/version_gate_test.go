@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+func TestCheckVersionChanges(t *testing.T) {
+	results := map[string]util.Result{
+		"apt": &util.SingleVersionPackageDiffResult{
+			DiffType: "Apt",
+			Diff: util.PackageDiff{
+				InfoDiff: []util.Info{
+					{Package: "libc6", Info1: util.PackageInfo{Version: "2.36-9"}, Info2: util.PackageInfo{Version: "2.31-13"}, Change: util.ChangeDowngrade},
+					{Package: "tzdata", Info1: util.PackageInfo{Version: "2023c-5"}, Info2: util.PackageInfo{Version: "2024a-0+deb12u1"}, Change: util.ChangeMajor},
+				},
+			},
+		},
+		"node": &util.NodeDiffResult{
+			DiffType: "Node",
+			Diff: util.MultiVersionPackageDiff{
+				InfoDiff: []util.MultiVersionInfo{
+					{Package: "express", Project: "/srv/api", Info1: []util.PackageInfo{{Version: "4.18.2"}}, Info2: []util.PackageInfo{{Version: "5.0.0"}}, Change: util.ChangeMajor},
+					{Package: "debug", Project: "/srv/api", Info1: []util.PackageInfo{{Version: "2.6.9"}}, Info2: []util.PackageInfo{{Version: "2.6.10"}}, Change: util.ChangePatch},
+				},
+			},
+		},
+	}
+
+	if err := CheckVersionChanges(results, []string{}); err != nil {
+		t.Errorf("Expected no error without disallowed changes but got: %s", err)
+	}
+
+	err := CheckVersionChanges(results, []string{util.ChangeDowngrade, util.ChangeMajor})
+	changeErr, ok := err.(*VersionChangeError)
+	if !ok {
+		t.Fatalf("Expected a VersionChangeError but got: %v", err)
+	}
+	expected := []string{
+		"Apt libc6: 2.36-9 -> 2.31-13 (downgrade)",
+		"Apt tzdata: 2023c-5 -> 2024a-0+deb12u1 (major)",
+		"Node express (/srv/api): 4.18.2 -> 5.0.0 (major)",
+	}
+	if !reflect.DeepEqual(changeErr.Changes, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, changeErr.Changes)
+	}
+}
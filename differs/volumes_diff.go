@@ -0,0 +1,117 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+// VolumesAnalyzer reports the files images ship below the paths their
+// config declares as VOLUMEs, which a volume mounted there hides.
+type VolumesAnalyzer struct {
+}
+
+func (a VolumesAnalyzer) Name() string {
+	return "VolumesAnalyzer"
+}
+
+// Diff compares the volumes of both images and the files below them.
+func (a VolumesAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	volumes1, err := getVolumeContents(image1)
+	if err != nil {
+		return &util.VolumeDiffResult{}, err
+	}
+	volumes2, err := getVolumeContents(image2)
+	if err != nil {
+		return &util.VolumeDiffResult{}, err
+	}
+	return &util.VolumeDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Volumes",
+		Diff:     util.GetVolumeDiff(volumes1, volumes2),
+	}, nil
+}
+
+// Analyze lists the volumes of an image and the files below them.
+func (a VolumesAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	volumes, err := getVolumeContents(image)
+	if err != nil {
+		return &util.VolumeAnalyzeResult{}, err
+	}
+	return &util.VolumeAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Volumes",
+		Analysis:    volumes,
+	}, nil
+}
+
+// getVolumeContents returns the files below each volume declared by the
+// image config, from the streamed inventory or the extracted filesystem.
+func getVolumeContents(image pkgutil.Image) ([]util.VolumeContent, error) {
+	config, err := configFile(image)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []string{}
+	for volume := range config.Config.Volumes {
+		volumes = append(volumes, path.Clean("/"+volume))
+	}
+	sort.Strings(volumes)
+
+	contents := []util.VolumeContent{}
+	for _, volume := range volumes {
+		content := util.VolumeContent{Volume: volume, Files: []pkgutil.DirectoryEntry{}}
+		if image.Inventory != nil {
+			for name, entry := range image.Inventory {
+				if entry.IsDir || !util.UnderVolume(volume, name) {
+					continue
+				}
+				content.Files = append(content.Files, pkgutil.DirectoryEntry{
+					Name:     name,
+					Size:     entry.Size,
+					Linkname: entry.Linkname,
+					Digest:   entry.Digest,
+				})
+			}
+		} else if image.FSPath != "" {
+			names := []string{}
+			root := filepath.Join(image.FSPath, volume)
+			pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+				if info.IsDir() {
+					return nil
+				}
+				names = append(names, path.Clean("/"+filepath.ToSlash(strings.TrimPrefix(file, image.FSPath))))
+				return nil
+			})
+			content.Files = append(content.Files, pkgutil.CreateDirectoryEntries(image.FSPath, names)...)
+		}
+		sort.Slice(content.Files, func(i, j int) bool { return content.Files[i].Name < content.Files[j].Name })
+		for _, f := range content.Files {
+			content.Size += f.Size
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func volumesConfig(volumes ...string) *pkgutil.TestImage {
+	config := &v1.ConfigFile{Config: v1.Config{Volumes: map[string]struct{}{}}}
+	for _, volume := range volumes {
+		config.Config.Volumes[volume] = struct{}{}
+	}
+	return &pkgutil.TestImage{Config: config}
+}
+
+func TestVolumesAnalyze(t *testing.T) {
+	image := difftest.NewFS(t).
+		File("var/lib/mysql/ibdata1", "data").
+		File("var/lib/mysql/mysql/user.frm", "users").
+		Symlink("data/current", "/var/lib/mysql").
+		File("etc/my.cnf", "[mysqld]").
+		Image("image")
+	image.Image = volumesConfig("/var/lib/mysql/", "/data", "/logs")
+
+	result, err := VolumesAnalyzer{}.Analyze(image)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	analysis := result.(*util.VolumeAnalyzeResult).Analysis.([]util.VolumeContent)
+	volumes := []string{}
+	files := []string{}
+	for _, v := range analysis {
+		volumes = append(volumes, v.Volume)
+		for _, f := range v.Files {
+			files = append(files, f.Name)
+		}
+	}
+	if expected := []string{"/data", "/logs", "/var/lib/mysql"}; !reflect.DeepEqual(volumes, expected) {
+		t.Errorf("Expected volumes %v but got %v", expected, volumes)
+	}
+	if expected := []string{"/data/current", "/var/lib/mysql/ibdata1", "/var/lib/mysql/mysql/user.frm"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected files %v but got %v", expected, files)
+	}
+	if analysis[0].Files[0].Linkname != "/var/lib/mysql" {
+		t.Errorf("Expected /data/current to link to /var/lib/mysql, got %q", analysis[0].Files[0].Linkname)
+	}
+	if analysis[2].Size != 9 {
+		t.Errorf("Expected /var/lib/mysql to hold 9 bytes, got %d", analysis[2].Size)
+	}
+}
+
+func TestVolumesDiff(t *testing.T) {
+	image1 := pkgutil.Image{Source: "image1", Image: volumesConfig("/data", "/cache"), Inventory: pkgutil.FileInventory{
+		"/data":          {Name: "/data", IsDir: true},
+		"/data/seed.db":  {Name: "/data/seed.db", Size: 10, Digest: "sha256:aa"},
+		"/data/old.db":   {Name: "/data/old.db", Size: 3, Digest: "sha256:bb"},
+		"/cache/x":       {Name: "/cache/x", Size: 1, Digest: "sha256:cc"},
+		"/etc/app.conf":  {Name: "/etc/app.conf", Size: 5, Digest: "sha256:dd"},
+		"/dataset/train": {Name: "/dataset/train", Size: 7, Digest: "sha256:ee"},
+	}}
+	image2 := pkgutil.Image{Source: "image2", Image: volumesConfig("/data", "/uploads"), Inventory: pkgutil.FileInventory{
+		"/data":           {Name: "/data", IsDir: true},
+		"/data/seed.db":   {Name: "/data/seed.db", Size: 10, Digest: "sha256:ab"},
+		"/data/new.db":    {Name: "/data/new.db", Size: 4, Digest: "sha256:ff"},
+		"/uploads/.keep":  {Name: "/uploads/.keep", Digest: "sha256:00"},
+		"/etc/app.conf":   {Name: "/etc/app.conf", Size: 6, Digest: "sha256:de"},
+		"/dataset/train":  {Name: "/dataset/train", Size: 8, Digest: "sha256:ef"},
+		"/dataset/labels": {Name: "/dataset/labels", Size: 2, Digest: "sha256:12"},
+	}}
+
+	result, err := VolumesAnalyzer{}.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.VolumeDiffResult).Diff.(util.VolumeDiff)
+	expected := util.VolumeDiff{
+		AddedVolumes:   []string{"/uploads"},
+		RemovedVolumes: []string{"/cache"},
+		Adds: []pkgutil.DirectoryEntry{
+			{Name: "/data/new.db", Size: 4, Digest: "sha256:ff"},
+			{Name: "/uploads/.keep", Digest: "sha256:00"},
+		},
+		Dels: []pkgutil.DirectoryEntry{
+			{Name: "/cache/x", Size: 1, Digest: "sha256:cc"},
+			{Name: "/data/old.db", Size: 3, Digest: "sha256:bb"},
+		},
+		Mods: []util.EntryDiff{
+			{Name: "/data/seed.db", Size1: 10, Size2: 10, Digest1: "sha256:aa", Digest2: "sha256:ab"},
+		},
+		Warnings: []string{
+			"1 file(s) were added below VOLUME /data, which a volume mounted there hides",
+			"1 file(s) were added below VOLUME /uploads, which a volume mounted there hides",
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+type languageEcosystem struct {
+	ecosystem string
+	analyzer  MultiVersionPackageAnalyzer
+}
+
+// languageEcosystems returns the OSV ecosystems of the packages found by the
+// language package analyzers, configured by opts.
+func languageEcosystems(opts *AnalyzerOptions) []languageEcosystem {
+	return []languageEcosystem{
+		{"PyPI", PipAnalyzer{Options: opts}},
+		{npmEcosystem, NodeAnalyzer{Options: opts}},
+		{"crates.io", CargoAnalyzer{Options: opts}},
+		{"NuGet", NugetAnalyzer{Options: opts}},
+		{"Maven", JarAnalyzer{Options: opts}},
+	}
+}
+
+// VulnAnalyzer reports the known vulnerabilities of the OS and language
+// packages of an image, as listed by the advisory database of
+// --advisory-db or the OSV API.
+type VulnAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a VulnAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a VulnAnalyzer) Name() string {
+	return "VulnAnalyzer"
+}
+
+func (a VulnAnalyzer) Dependencies() []string {
+	return []string{aptAnalyzer, rpmAnalyzer, pipAnalyzer, nodeAnalyzer, cargoAnalyzer, nugetAnalyzer, jarAnalyzer}
+}
+
+// Diff reports the vulnerabilities introduced and fixed between two images.
+func (a VulnAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	vulns1, err := getVulnerabilities(image1, a.Options)
+	if err != nil {
+		return &util.AdvisoryDiffResult{}, err
+	}
+	vulns2, err := getVulnerabilities(image2, a.Options)
+	if err != nil {
+		return &util.AdvisoryDiffResult{}, err
+	}
+	return &util.AdvisoryDiffResult{
+		Image1:   image1.Source,
+		Image2:   image2.Source,
+		DiffType: "Vuln",
+		Diff:     util.GetAdvisoryDiff(vulns1, vulns2),
+	}, nil
+}
+
+func (a VulnAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	vulns, err := getVulnerabilities(image, a.Options)
+	if err != nil {
+		return &util.AdvisoryAnalyzeResult{}, err
+	}
+	return &util.AdvisoryAnalyzeResult{
+		Image:       image.Source,
+		AnalyzeType: "Vuln",
+		Analysis:    vulns,
+	}, nil
+}
+
+// getVulnerabilities looks up every distinct installed version of the OS
+// and language packages of an image in the advisory database.
+func getVulnerabilities(image pkgutil.Image, opts *AnalyzerOptions) ([]util.Advisory, error) {
+	db, err := getAdvisoryDB(analyzerOptions(opts).AdvisoryDBPath)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]map[string]map[string]bool{}
+	add := func(ecosystem, name, version string) {
+		if version == "" {
+			return
+		}
+		if versions[ecosystem] == nil {
+			versions[ecosystem] = map[string]map[string]bool{}
+		}
+		if versions[ecosystem][name] == nil {
+			versions[ecosystem][name] = map[string]bool{}
+		}
+		versions[ecosystem][name][version] = true
+	}
+
+	ecosystem, osPackages, err := getOSPackages(image, opts)
+	if err != nil {
+		return nil, err
+	}
+	for name, info := range osPackages {
+		// the apt analyzer shows the first + of versions as a space, and
+		// Debian versions have no spaces
+		add(ecosystem, name, strings.Replace(info.Version, " ", "+", 1))
+	}
+	for _, language := range languageEcosystems(opts) {
+		packages, err := multiVersionPackages(image, language.analyzer)
+		if err != nil {
+			return nil, err
+		}
+		for name, installs := range packages {
+			for _, info := range installs {
+				add(language.ecosystem, name, info.Version)
+			}
+		}
+	}
+
+	queries := []util.AdvisoryQuery{}
+	for ecosystem, packages := range versions {
+		for name, installed := range packages {
+			for version := range installed {
+				queries = append(queries, util.AdvisoryQuery{Ecosystem: ecosystem, Name: name, Version: version})
+			}
+		}
+	}
+	if err := util.PrefetchAdvisories(db, queries); err != nil {
+		logrus.Warningf("Error looking up advisories in batches, looking up each package: %s", err)
+	}
+
+	vulns := []util.Advisory{}
+	for ecosystem, packages := range versions {
+		for name, installed := range packages {
+			for version := range installed {
+				found, err := util.GetAdvisories(db, ecosystem, name, version)
+				if err != nil {
+					logrus.Warningf("Error looking up advisories for %s %s@%s: %s", ecosystem, name, version, err)
+					continue
+				}
+				for _, advisory := range found {
+					advisory.Ecosystem = ecosystem
+					vulns = append(vulns, advisory)
+				}
+			}
+		}
+	}
+	util.SortAdvisories(vulns)
+	return vulns, nil
+}
+
+// getOSPackages returns the distribution packages of an image along with
+// their OSV ecosystem, e.g. Debian:12. Distributions without an OSV
+// ecosystem have no packages looked up.
+func getOSPackages(image pkgutil.Image, opts *AnalyzerOptions) (string, map[string]util.PackageInfo, error) {
+	release, err := getOSRelease(image)
+	if err != nil {
+		return "", nil, err
+	}
+	major := strings.SplitN(release.Version, ".", 2)[0]
+	qualify := func(ecosystem, version string) string {
+		if version == "" {
+			return ecosystem
+		}
+		return ecosystem + ":" + version
+	}
+	var ecosystem string
+	var analyzer SingleVersionPackageAnalyzer
+	switch release.ID {
+	case "debian":
+		ecosystem, analyzer = qualify("Debian", major), AptAnalyzer{Options: opts}
+	case "ubuntu":
+		ecosystem, analyzer = qualify("Ubuntu", release.Version), AptAnalyzer{Options: opts}
+	case "almalinux":
+		ecosystem, analyzer = qualify("AlmaLinux", major), RPMAnalyzer{Options: opts}
+	case "rocky":
+		ecosystem, analyzer = qualify("Rocky Linux", major), RPMAnalyzer{Options: opts}
+	default:
+		logrus.Infof("no vulnerability ecosystem for the OS packages of %s (%s), only looking up language packages", image.Source, release.ID)
+		return "", map[string]util.PackageInfo{}, nil
+	}
+	packages, err := singleVersionPackages(image, analyzer)
+	return ecosystem, packages, err
+}
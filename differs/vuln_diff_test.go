@@ -0,0 +1,229 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+const vulnAdvisories = `[
+  {
+    "id": "DSA-0001",
+    "summary": "Buffer overflow in openssl",
+    "affected": [{
+      "package": {"ecosystem": "Debian:12", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.11-1~deb12u2"}]}]
+    }]
+  },
+  {
+    "id": "DSA-0002",
+    "summary": "Use after free in curl",
+    "affected": [{
+      "package": {"ecosystem": "Debian:12", "name": "curl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "7.88.1-10+deb12u4"}, {"fixed": "7.88.1-10+deb12u6"}]}]
+    }]
+  },
+  {
+    "id": "PYSEC-0003",
+    "summary": "Header injection in requests",
+    "affected": [{
+      "package": {"ecosystem": "PyPI", "name": "requests"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.31.0"}]}]
+    }],
+    "database_specific": {"severity": "MODERATE"}
+  }
+]`
+
+func vulnImage(t *testing.T, openssl, curl, requests string) pkgutil.Image {
+	image := difftest.NewFS(t).
+		File("etc/os-release", "ID=debian\nVERSION_ID=\"12\"\n").
+		File("var/lib/dpkg/status", "Package: openssl\nVersion: "+openssl+"\n\nPackage: curl\nVersion: "+curl+"\n").
+		File("usr/lib/python3.11/site-packages/requests-"+requests+".dist-info/METADATA", "Name: requests\nVersion: "+requests+"\n").
+		File("usr/lib/python3.11/site-packages/requests/__init__.py", "").
+		Image("image " + requests)
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+	return image
+}
+
+func TestVulnDiff(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "advisories.json")
+	if err := ioutil.WriteFile(db, []byte(vulnAdvisories), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer := VulnAnalyzer{Options: &AnalyzerOptions{AdvisoryDBPath: db}}
+
+	image1 := vulnImage(t, "3.0.11-1~deb12u1", "7.88.1-10+deb12u1", "2.28.1")
+	image2 := vulnImage(t, "3.0.11-1~deb12u2", "7.88.1-10+deb12u5", "2.28.1")
+	result, err := analyzer.Diff(image1, image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	diff := result.(*util.AdvisoryDiffResult).Diff.(util.AdvisoryDiff)
+	expected := util.AdvisoryDiff{
+		Introduced: []util.Advisory{
+			{ID: "DSA-0002", Ecosystem: "Debian:12", Package: "curl", Version: "7.88.1-10+deb12u5", Summary: "Use after free in curl", Fixed: "7.88.1-10+deb12u6"},
+		},
+		Resolved: []util.Advisory{
+			{ID: "DSA-0001", Ecosystem: "Debian:12", Package: "openssl", Version: "3.0.11-1~deb12u1", Summary: "Buffer overflow in openssl", Fixed: "3.0.11-1~deb12u2"},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff)
+	}
+
+	result, err = analyzer.Analyze(image2)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	ids := []string{}
+	for _, advisory := range result.(*util.AdvisoryAnalyzeResult).Analysis.([]util.Advisory) {
+		ids = append(ids, advisory.Ecosystem+"/"+advisory.ID)
+	}
+	if expected := []string{"Debian:12/DSA-0002", "PyPI/PYSEC-0003"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected vulnerabilities %v but got %v", expected, ids)
+	}
+}
+
+// serveOSV answers OSV API batch queries with the IDs of the matching
+// entries of advisories, and serves the entries themselves. Single package
+// queries are rejected.
+func serveOSV(t *testing.T, advisories string) *httptest.Server {
+	var entries []util.OSVEntry
+	if err := json.Unmarshal([]byte(advisories), &entries); err != nil {
+		t.Fatal(err)
+	}
+	type vulnID struct {
+		ID string `json:"id"`
+	}
+	matching := func(ecosystem, name string) []vulnID {
+		ids := []vulnID{}
+		for _, entry := range entries {
+			for _, affected := range entry.Affected {
+				if affected.Package.Ecosystem == ecosystem && affected.Package.Name == name {
+					ids = append(ids, vulnID{entry.ID})
+					break
+				}
+			}
+		}
+		return ids
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/vulns/") {
+			for _, entry := range entries {
+				if entry.ID == strings.TrimPrefix(r.URL.Path, "/v1/vulns/") {
+					json.NewEncoder(w).Encode(entry)
+					return
+				}
+			}
+			http.NotFound(w, r)
+			return
+		}
+		var query struct {
+			Queries []struct {
+				Package struct {
+					Ecosystem string `json:"ecosystem"`
+					Name      string `json:"name"`
+				} `json:"package"`
+			} `json:"queries"`
+		}
+		if r.URL.Path != "/v1/querybatch" || json.NewDecoder(r.Body).Decode(&query) != nil {
+			t.Errorf("Unexpected OSV request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		results := []map[string][]vulnID{}
+		for _, q := range query.Queries {
+			results = append(results, map[string][]vulnID{"vulns": matching(q.Package.Ecosystem, q.Package.Name)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+}
+
+// osvTransport sends the requests for the OSV API to a test server.
+type osvTransport struct {
+	server    *url.URL
+	transport http.RoundTripper
+}
+
+func (t osvTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.server.Scheme, t.server.Host
+	return t.transport.RoundTrip(req)
+}
+
+func TestAdvisoryAnalyzersConcurrently(t *testing.T) {
+	npm, err := ioutil.ReadFile("testDirs/advisories/npm.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vulns := vulnAdvisories[:len(vulnAdvisories)-1] + "," + string(npm[1:])
+	server := serveOSV(t, vulns)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	defer func(orig http.RoundTripper) { http.DefaultTransport = orig }(http.DefaultTransport)
+	http.DefaultTransport = osvTransport{serverURL, http.DefaultTransport}
+	advisoryDBs.Lock()
+	advisoryDBs.dbs = map[string]util.AdvisoryDB{}
+	advisoryDBs.Unlock()
+
+	fs := difftest.NewFS(t).
+		File("etc/os-release", "ID=debian\nVERSION_ID=\"12\"\n").
+		File("var/lib/dpkg/status", "Package: openssl\nVersion: 3.0.11-1~deb12u1\n").
+		File("usr/local/lib/node_modules/pac1/package.json", `{"name": "pac1", "version": "1.0"}`)
+	for i := 0; i < 20; i++ {
+		fs.File("usr/local/lib/node_modules/dep"+string(rune('a'+i))+"/package.json", `{"name": "dep`+string(rune('a'+i))+`", "version": "1.0.0"}`)
+	}
+	image := fs.Image("image")
+	image.Image = &pkgutil.TestImage{Config: &v1.ConfigFile{}}
+
+	analyzers := []Analyzer{NodeAdvisoryAnalyzer{}, VulnAnalyzer{}, NodeAdvisoryAnalyzer{}, VulnAnalyzer{}}
+	ids := make([][]string, len(analyzers))
+	var wg sync.WaitGroup
+	for i, analyzer := range analyzers {
+		wg.Add(1)
+		go func(i int, analyzer Analyzer) {
+			defer wg.Done()
+			result, err := analyzer.Analyze(image)
+			if err != nil {
+				t.Errorf("%s: got unexpected error: %s", analyzer.Name(), err)
+				return
+			}
+			for _, advisory := range result.(*util.AdvisoryAnalyzeResult).Analysis.([]util.Advisory) {
+				ids[i] = append(ids[i], advisory.ID)
+			}
+		}(i, analyzer)
+	}
+	wg.Wait()
+	expected := [][]string{{"GHSA-0001"}, {"DSA-0001", "GHSA-0001"}, {"GHSA-0001"}, {"DSA-0001", "GHSA-0001"}}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected advisories %v but got %v", expected, ids)
+	}
+}
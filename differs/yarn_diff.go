@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+	"github.com/sirupsen/logrus"
+)
+
+// YarnAnalyzer compares the packages of yarn projects: those locked by each
+// yarn.lock, the project's workspaces, and those installed in the project's
+// node_modules, which the node analyzer doesn't search.
+type YarnAnalyzer struct {
+	// Options configure the analyzer, the zero AnalyzerOptions if nil.
+	Options *AnalyzerOptions
+}
+
+func (a YarnAnalyzer) options() AnalyzerOptions {
+	return analyzerOptions(a.Options)
+}
+
+func (a YarnAnalyzer) withOptions(opts *AnalyzerOptions) Analyzer {
+	a.Options = opts
+	return a
+}
+
+func (a YarnAnalyzer) Name() string {
+	return "YarnAnalyzer"
+}
+
+// Diff compares the yarn packages in two images.
+func (a YarnAnalyzer) Diff(image1, image2 pkgutil.Image) (util.Result, error) {
+	diff, err := multiVersionDiff(image1, image2, a)
+	return diff, err
+}
+
+func (a YarnAnalyzer) Analyze(image pkgutil.Image) (util.Result, error) {
+	analysis, err := multiVersionAnalysis(image, a)
+	return analysis, err
+}
+
+// getPackages maps each package of the yarn projects in an image to the lock
+// files, workspaces and node_modules directories which have it.
+func (a YarnAnalyzer) getPackages(image pkgutil.Image) (map[string]map[string]util.PackageInfo, error) {
+	root := image.FSPath
+	packages := make(map[string]map[string]util.PackageInfo)
+	if _, err := os.Stat(root); err != nil {
+		// path provided invalid
+		return packages, err
+	}
+	err := pkgutil.WalkReadable(root, func(file string, info os.FileInfo) error {
+		// published packages may ship lock files of their own
+		if info.IsDir() && (info.Name() == "node_modules" || info.Name() == ".yarn") {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() || info.Name() != "yarn.lock" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(file))
+		if err != nil {
+			return nil
+		}
+		project := path.Clean("/" + filepath.ToSlash(rel))
+		readYarnLock(root, path.Join(project, "yarn.lock"), packages)
+		readYarnWorkspaces(root, project, packages)
+		readNodeModules(root, path.Join(project, "node_modules"), packages)
+		return nil
+	})
+	return packages, err
+}
+
+// readYarnLock records the packages locked by a yarn.lock, in the format of
+// yarn 1 or the YAML of later versions. Entries are keyed by the descriptors
+// resolving to them, e.g. "lodash@^4.17.0, lodash@^4.17.21", followed by
+// an indented version field. A package locked at several versions is listed
+// with all of them.
+func readYarnLock(root, file string, packages map[string]map[string]util.PackageInfo) {
+	f, err := os.Open(filepath.Join(root, file))
+	if err != nil {
+		logrus.Warnf("Could not read %s: %s", file, err)
+		return
+	}
+	defer f.Close()
+	locked := map[string][]string{}
+	name := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			name = yarnEntryName(strings.TrimSuffix(trimmed, ":"))
+			continue
+		}
+		if name == "" || strings.HasPrefix(line, "   ") {
+			continue
+		}
+		var version string
+		switch {
+		case strings.HasPrefix(trimmed, "version "):
+			version = strings.TrimSpace(strings.TrimPrefix(trimmed, "version "))
+		case strings.HasPrefix(trimmed, "version:"):
+			version = strings.TrimSpace(strings.TrimPrefix(trimmed, "version:"))
+		default:
+			continue
+		}
+		if unquoted, err := strconv.Unquote(version); err == nil {
+			version = unquoted
+		}
+		if !containsString(locked[name], version) {
+			locked[name] = append(locked[name], version)
+		}
+	}
+	for pkg, versions := range locked {
+		sort.Strings(versions)
+		addYarnPackage(packages, pkg, file, strings.Join(versions, ", "), -1)
+	}
+}
+
+// yarnEntryName returns the package name of a lock file entry, from its
+// first descriptor. Metadata and the project's own workspaces, which later
+// yarn versions lock as name@workspace:path, are no packages.
+func yarnEntryName(key string) string {
+	descriptor := strings.Trim(strings.TrimSpace(strings.Split(key, ",")[0]), `"`)
+	if descriptor == "" || descriptor == "__metadata" || strings.Contains(descriptor, "@workspace:") {
+		return ""
+	}
+	// scoped names start with @, so the version range follows the next one
+	i := strings.Index(descriptor[1:], "@")
+	if i < 0 {
+		return descriptor
+	}
+	return descriptor[:i+1]
+}
+
+// yarnManifest is the part of a project's package.json naming its
+// workspaces, either as a list of globs or as {"packages": [...]}.
+type yarnManifest struct {
+	Workspaces json.RawMessage `json:"workspaces"`
+}
+
+// readYarnWorkspaces records the workspaces of a project with their
+// package.json versions.
+func readYarnWorkspaces(root, project string, packages map[string]map[string]util.PackageInfo) {
+	data, err := ioutil.ReadFile(filepath.Join(root, project, "package.json"))
+	if err != nil {
+		return
+	}
+	var manifest yarnManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || len(manifest.Workspaces) == 0 {
+		return
+	}
+	var globs []string
+	if err := json.Unmarshal(manifest.Workspaces, &globs); err != nil {
+		var nested struct {
+			Packages []string `json:"packages"`
+		}
+		if err := json.Unmarshal(manifest.Workspaces, &nested); err != nil {
+			logrus.Warnf("Could not parse the workspaces of %s: %s", path.Join(project, "package.json"), err)
+			return
+		}
+		globs = nested.Packages
+	}
+	for _, pattern := range globs {
+		for _, dir := range glob(root, path.Join(project, pattern)) {
+			workspace, err := readPackageJSON(filepath.Join(root, dir, "package.json"))
+			if err != nil || workspace.Name == "" {
+				continue
+			}
+			addYarnPackage(packages, workspace.Name, dir, workspace.Version, -1)
+		}
+	}
+}
+
+// readNodeModules records the packages installed in a node_modules
+// directory, including scoped and nested ones. Symlinks, such as those yarn
+// makes to workspaces, are not followed.
+func readNodeModules(root, dir string, packages map[string]map[string]util.PackageInfo) {
+	entries, err := ioutil.ReadDir(filepath.Join(root, dir))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), "@") {
+			readNodeModules(root, path.Join(dir, entry.Name()), packages)
+			continue
+		}
+		pkgDir := path.Join(dir, entry.Name())
+		pkg, err := readPackageJSON(filepath.Join(root, pkgDir, "package.json"))
+		if err != nil || pkg.Name == "" {
+			continue
+		}
+		addYarnPackage(packages, pkg.Name, pkgDir, pkg.Version, pkgutil.GetSize(filepath.Join(root, pkgDir)))
+		readNodeModules(root, path.Join(pkgDir, "node_modules"), packages)
+	}
+}
+
+func addYarnPackage(packages map[string]map[string]util.PackageInfo, name, location, version string, size int64) {
+	if _, ok := packages[name]; !ok {
+		packages[name] = make(map[string]util.PackageInfo)
+	}
+	packages[name][location] = util.PackageInfo{Version: version, Size: size}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package differs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/GoogleContainerTools/container-diff/util"
+)
+
+const classicYarnLock = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+  version "7.12.13"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.12.13.tgz#dcfc826beef65e75c50e21d3837d7d95798dd658"
+  dependencies:
+    "@babel/highlight" "^7.10.4"
+
+lodash@^3.0.0:
+  version "3.10.1"
+
+lodash@^4.17.21:
+  version "4.17.21"
+`
+
+const berryYarnLock = `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"left-pad@npm:^1.3.0":
+  version: 1.3.0
+  resolution: "left-pad@npm:1.3.0"
+  checksum: 13fa96e17b70a54836490de22d4bab706e2ed508338bbabecfac72ecce445a74139c5b009a8112252cab8fc4ab7ac4ebd870e5b35bd236b443b12be1f7f8d1
+  languageName: node
+  linkType: hard
+
+"resolve@patch:resolve@^1.20.0#~builtin<compat/resolve>":
+  version: 1.22.1
+  resolution: "resolve@patch:resolve@npm%3A1.22.1#~builtin<compat/resolve>::version=1.22.1&hash=07638b"
+
+"web@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "web@workspace:."
+`
+
+func TestGetYarnPackages(t *testing.T) {
+	fs := difftest.NewFS(t).
+		File("/app/yarn.lock", classicYarnLock).
+		File("/app/package.json", `{"name": "app", "private": true, "workspaces": ["packages/*"]}`).
+		File("/app/packages/ui/package.json", `{"name": "@app/ui", "version": "0.3.0"}`).
+		File("/app/node_modules/lodash/package.json", `{"name": "lodash", "version": "4.17.21"}`).
+		File("/app/node_modules/@babel/code-frame/package.json", `{"name": "@babel/code-frame", "version": "7.12.13"}`).
+		File("/app/node_modules/@babel/code-frame/node_modules/lodash/package.json", `{"name": "lodash", "version": "3.10.1"}`).
+		// lock files of installed packages are not the project's
+		File("/app/node_modules/lodash/yarn.lock", "left-pad@^1.0.0:\n  version \"1.0.0\"\n").
+		File("/web/yarn.lock", berryYarnLock).
+		File("/web/package.json", `{"name": "web", "workspaces": {"packages": ["libs/*"]}}`).
+		File("/web/libs/core/package.json", `{"name": "core", "version": "2.0.0"}`)
+	packages, err := YarnAnalyzer{}.getPackages(fs.Image("image"))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	expected := map[string]map[string]util.PackageInfo{
+		"@babel/code-frame": {
+			"/app/yarn.lock":                      {Version: "7.12.13", Size: -1},
+			"/app/node_modules/@babel/code-frame": {Version: "7.12.13", Size: 90},
+		},
+		"lodash": {
+			"/app/yarn.lock":           {Version: "3.10.1, 4.17.21", Size: -1},
+			"/app/node_modules/lodash": {Version: "4.17.21", Size: 75},
+			"/app/node_modules/@babel/code-frame/node_modules/lodash": {Version: "3.10.1", Size: 39},
+		},
+		"@app/ui":  {"/app/packages/ui": {Version: "0.3.0", Size: -1}},
+		"left-pad": {"/web/yarn.lock": {Version: "1.3.0", Size: -1}},
+		"resolve":  {"/web/yarn.lock": {Version: "1.22.1", Size: -1}},
+		"core":     {"/web/libs/core": {Version: "2.0.0", Size: -1}},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, packages)
+	}
+
+	if _, err := (YarnAnalyzer{}).getPackages(pkgutil.Image{FSPath: "testDirs/notThere"}); err == nil {
+		t.Errorf("Expected error for a missing directory but got none.")
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var requestConfiguration = struct {
+	userAgent string
+	headers   map[string]string
+	auditLog  *auditLog
+}{
+	headers: make(map[string]string),
+}
+
+// ConfigureRequests sets the User-Agent and extra headers sent with every
+// registry request, and the writer each request is recorded to, if any.
+func ConfigureRequests(userAgent string, headers map[string]string, audit io.Writer) {
+	requestConfiguration.userAgent = userAgent
+	requestConfiguration.headers = make(map[string]string)
+	for key, value := range headers {
+		requestConfiguration.headers[key] = value
+	}
+	requestConfiguration.auditLog = nil
+	if audit != nil {
+		requestConfiguration.auditLog = &auditLog{writer: audit}
+	}
+}
+
+// wrapTransport adds the configured headers and auditing to tr.
+func wrapTransport(tr http.RoundTripper) http.RoundTripper {
+	if requestConfiguration.auditLog != nil {
+		tr = &auditTransport{inner: tr, log: requestConfiguration.auditLog}
+	}
+	if requestConfiguration.userAgent != "" || len(requestConfiguration.headers) != 0 {
+		tr = &headerTransport{inner: tr, userAgent: requestConfiguration.userAgent, headers: requestConfiguration.headers}
+	}
+	return tr
+}
+
+// headerTransport sets headers on each request. It sits below the
+// registry auth transports, so its User-Agent replaces theirs.
+type headerTransport struct {
+	inner     http.RoundTripper
+	userAgent string
+	headers   map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.inner.RoundTrip(req)
+}
+
+// AuditEntry records a registry request. Bytes counts the response body
+// bytes read, which is less than the body size if it wasn't read in full.
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Status int       `json:"status,omitempty"`
+	Bytes  int64     `json:"bytes"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// auditLog writes entries as JSON lines; images are pulled concurrently.
+type auditLog struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (l *auditLog) record(entry AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := json.NewEncoder(l.writer).Encode(entry); err != nil {
+		logrus.Warnf("Failed to write audit log entry: %s", err)
+	}
+}
+
+// auditTransport records each request once its response body is closed or
+// read to the end, so that the bytes transferred are known.
+type auditTransport struct {
+	inner http.RoundTripper
+	log   *auditLog
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := AuditEntry{Time: time.Now().UTC(), Method: req.Method, URL: redactURL(req.URL)}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		t.log.record(entry)
+		return resp, err
+	}
+	entry.Status = resp.StatusCode
+	resp.Body = &auditBody{ReadCloser: resp.Body, entry: entry, log: t.log}
+	return resp, nil
+}
+
+type auditBody struct {
+	io.ReadCloser
+	entry AuditEntry
+	log   *auditLog
+	once  sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.entry.Bytes += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.log.record(b.entry) })
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	b.once.Do(func() { b.log.record(b.entry) })
+	return b.ReadCloser.Close()
+}
+
+// redactURL hides query parameters which may carry credentials, such as
+// the signatures of blob storage redirects.
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	redacted := *u
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "sig") || strings.Contains(lower, "token") || strings.Contains(lower, "credential") || strings.Contains(lower, "key") {
+			query[key] = []string{"REDACTED"}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
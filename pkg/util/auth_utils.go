@@ -0,0 +1,161 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// registryCredentials are the credentials given on the command line. They
+// are sent to the registries listed, or to every registry if none are.
+var registryCredentials struct {
+	username   string
+	password   string
+	token      string
+	registries map[string]struct{}
+}
+
+// cloudCredentialHelpers are the credential helpers of the cloud registries,
+// tried when the Docker config has no credentials for a registry, in order,
+// so that images can be read right after gcloud, aws or az logins without
+// running `docker login` or configuring the helper. The gcloud helper ships
+// with the Google Cloud SDK, and gcr is the standalone one.
+var cloudCredentialHelpers = []struct {
+	registry *regexp.Regexp
+	helpers  []string
+}{
+	{regexp.MustCompile(`^([a-z0-9-]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`), []string{"gcloud", "gcr"}},
+	{regexp.MustCompile(`^[0-9]+\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`), []string{"ecr-login"}},
+	{regexp.MustCompile(`^[a-z0-9]+\.azurecr\.(io|cn|us)$`), []string{"acr-env"}},
+}
+
+// credentialHelperNotFound is what Docker credential helpers print for
+// registries they have no credentials for.
+const credentialHelperNotFound = "credentials not found in native keychain"
+
+// ConfigureRegistryAuth sets credentials overriding those of the Docker
+// config: a username and password, or a token sent to the token service of
+// the registry as a bearer token, such as the access tokens of
+// `gcloud auth print-access-token`. registries restricts them to those
+// registries, given as host[:port].
+func ConfigureRegistryAuth(username, password, token string, registries []string) error {
+	if token != "" && (username != "" || password != "") {
+		return errors.New("a registry token can't be combined with a username or password")
+	}
+	if password != "" && username == "" {
+		return errors.New("a registry password needs a username")
+	}
+	if len(registries) > 0 && username == "" && token == "" {
+		return errors.New("registries to authenticate to need a username or token")
+	}
+	registryCredentials.username = username
+	registryCredentials.password = password
+	registryCredentials.token = token
+	registryCredentials.registries = make(map[string]struct{})
+	for _, registry := range registries {
+		registryCredentials.registries[registryHost(registry)] = struct{}{}
+	}
+	return nil
+}
+
+// resolveAuth returns the credentials for a registry: those given with
+// ConfigureRegistryAuth, those of the Docker config and its credential
+// helpers, or those of the credential helper of a cloud registry, in that
+// order. Registries none of them has credentials for are read anonymously.
+func resolveAuth(registry name.Registry) (authn.Authenticator, error) {
+	if auth, ok := explicitAuth(registry); ok {
+		return auth, nil
+	}
+	auth, err := authn.DefaultKeychain.Resolve(registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving auth")
+	}
+	if auth != authn.Anonymous {
+		return auth, nil
+	}
+	for _, cloud := range cloudCredentialHelpers {
+		if !cloud.registry.MatchString(registry.RegistryStr()) {
+			continue
+		}
+		for _, helper := range cloud.helpers {
+			if _, err := exec.LookPath("docker-credential-" + helper); err == nil {
+				logrus.Infof("using credential helper docker-credential-%s for %s", helper, registry.RegistryStr())
+				return &credentialHelper{name: helper, registry: registry}, nil
+			}
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+func explicitAuth(registry name.Registry) (authn.Authenticator, bool) {
+	if registryCredentials.username == "" && registryCredentials.token == "" {
+		return nil, false
+	}
+	if len(registryCredentials.registries) > 0 {
+		if _, ok := registryCredentials.registries[registry.RegistryStr()]; !ok {
+			return nil, false
+		}
+	}
+	if registryCredentials.token != "" {
+		return &authn.Bearer{Token: registryCredentials.token}, true
+	}
+	return &authn.Basic{Username: registryCredentials.username, Password: registryCredentials.password}, true
+}
+
+// credentialHelper gets the credentials for a registry from a Docker
+// credential helper, docker-credential-<name>, as Docker does for the
+// helpers of its config.
+type credentialHelper struct {
+	name     string
+	registry name.Registry
+}
+
+func (h *credentialHelper) Authorization() (string, error) {
+	command := "docker-credential-" + h.name
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, "get")
+	cmd.Stdin = strings.NewReader("https://" + h.registry.RegistryStr())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	output := strings.TrimSpace(stdout.String())
+	if output == credentialHelperNotFound {
+		return authn.Anonymous.Authorization()
+	}
+	if err != nil {
+		// the output of a failed helper holds no secret
+		return "", fmt.Errorf("invoking %s: %v: %s", command, err, strings.TrimSpace(output+" "+stderr.String()))
+	}
+	var credentials struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal([]byte(output), &credentials); err != nil {
+		return "", errors.Wrapf(err, "parsing the output of %s", command)
+	}
+	return (&authn.Basic{Username: credentials.Username, Password: credentials.Secret}).Authorization()
+}
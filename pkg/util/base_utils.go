@@ -0,0 +1,63 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// VerifyBase checks that the lowest layers of img are exactly the layers of
+// the base image. Layers are compared by their uncompressed digests, so the
+// result doesn't depend on where either image was retrieved from.
+func VerifyBase(img v1.Image, base string) error {
+	baseImg, _, cleanup, err := getImageReference(base)
+	if err != nil {
+		return errors.Wrapf(err, "retrieving expected base %s", base)
+	}
+	defer cleanup()
+	baseIDs, err := diffIDs(baseImg)
+	if err != nil {
+		return errors.Wrapf(err, "reading layers of expected base %s", base)
+	}
+	ids, err := diffIDs(img)
+	if err != nil {
+		return errors.Wrap(err, "reading image layers")
+	}
+	if len(ids) < len(baseIDs) {
+		return fmt.Errorf("image has %d layers, fewer than the %d layers of the expected base", len(ids), len(baseIDs))
+	}
+	for i, baseID := range baseIDs {
+		if ids[i] != baseID {
+			return fmt.Errorf("layer %d is %s, but the expected base has %s", i, ids[i], baseID)
+		}
+	}
+	return nil
+}
+
+func diffIDs(img v1.Image) ([]v1.Hash, error) {
+	if img == nil {
+		return nil, errors.New("no image reference")
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	return config.RootFS.DiffIDs, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// cacheRecordSuffix names the file, next to a cache directory, recording
+// the digest of the image or layer fully extracted to it. Directories
+// without a record were interrupted midway, or hold another image since
+// their tag moved, and are extracted again.
+const cacheRecordSuffix = ".digest"
+
+// cachedDigest returns the digest of the image or layer fully extracted to
+// the cache directory dir, or "" if none was.
+func cachedDigest(dir string) string {
+	b, err := ioutil.ReadFile(dir + cacheRecordSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// HasCachedFilesystem reports whether an earlier run fully extracted a
+// filesystem to the cache directory dir.
+func HasCachedFilesystem(dir string) bool {
+	return dir != "" && cachedDigest(dir) != ""
+}
+
+// recordCache records that the image or layer of digest was fully
+// extracted to dir.
+func recordCache(dir, digest string) error {
+	return ioutil.WriteFile(dir+cacheRecordSuffix, []byte(digest+"\n"), 0600)
+}
+
+// clearCache removes the cache directory dir of an interrupted or stale
+// extraction, along with its record.
+func clearCache(dir string) error {
+	if err := os.Remove(dir + cacheRecordSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	makeWritable(dir)
+	return os.RemoveAll(dir)
+}
+
+// layerCacheDir is the cache directory of a layer of the image cached in
+// cacheDir.
+func layerCacheDir(cacheDir string, digest v1.Hash) string {
+	return filepath.Join(cacheDir+".layers", digest.Hex)
+}
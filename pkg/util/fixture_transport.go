@@ -0,0 +1,301 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// A recording is a directory holding the registry responses of a run, one
+// JSON line per response in requests.jsonl, with the bodies stored under
+// bodies/ by their SHA-256.
+const (
+	fixtureIndex  = "requests.jsonl"
+	fixtureBodies = "bodies"
+)
+
+// fixtureTokenFields are the fields of token service responses holding
+// credentials, which recordings leave out so they can be shared.
+var fixtureTokenFields = []string{"token", "access_token", "refresh_token"}
+
+var fixtureConfiguration struct {
+	recorder *fixtureRecorder
+	replayer *fixtureReplayer
+}
+
+// FixtureEntry is a registry response of a recording: the request it
+// answers, and its status, headers and body. Requests which failed record
+// their error instead.
+type FixtureEntry struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// ConfigureFixtures records the registry responses of this run to the
+// directory record, replacing any earlier recording there, or replays the
+// recording of the directory replay instead of reaching any registry.
+func ConfigureFixtures(record, replay string) error {
+	fixtureConfiguration.recorder = nil
+	fixtureConfiguration.replayer = nil
+	if record != "" && replay != "" {
+		return errors.New("registry requests can't be recorded and replayed at once")
+	}
+	if record != "" {
+		recorder, err := newFixtureRecorder(record)
+		if err != nil {
+			return err
+		}
+		fixtureConfiguration.recorder = recorder
+	}
+	if replay != "" {
+		replayer, err := newFixtureReplayer(replay)
+		if err != nil {
+			return err
+		}
+		fixtureConfiguration.replayer = replayer
+	}
+	return nil
+}
+
+// fixtureTransport records the responses of tr, or replaces it with the
+// recording replayed.
+func fixtureTransport(tr http.RoundTripper) http.RoundTripper {
+	if fixtureConfiguration.replayer != nil {
+		return fixtureConfiguration.replayer
+	}
+	if fixtureConfiguration.recorder != nil {
+		return &recordTransport{inner: tr, recorder: fixtureConfiguration.recorder}
+	}
+	return tr
+}
+
+// fixtureRecorder writes entries as JSON lines; images are pulled
+// concurrently.
+type fixtureRecorder struct {
+	mu    sync.Mutex
+	dir   string
+	index *os.File
+}
+
+func newFixtureRecorder(dir string) (*fixtureRecorder, error) {
+	if err := os.MkdirAll(filepath.Join(dir, fixtureBodies), 0755); err != nil {
+		return nil, errors.Wrap(err, "creating recording directory")
+	}
+	index, err := os.OpenFile(filepath.Join(dir, fixtureIndex), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating recording")
+	}
+	return &fixtureRecorder{dir: dir, index: index}, nil
+}
+
+func (r *fixtureRecorder) record(entry FixtureEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := json.NewEncoder(r.index).Encode(entry); err != nil {
+		logrus.Warnf("Failed to record registry response: %s", err)
+	}
+}
+
+// storeBody copies the body of resp to the recording, and returns its name
+// there along with the body to hand on. Tokens are redacted from the
+// recorded copy only, which the returned bool tells.
+func (r *fixtureRecorder) storeBody(resp *http.Response) (string, bool, io.ReadCloser, error) {
+	defer resp.Body.Close()
+	bodies := filepath.Join(r.dir, fixtureBodies)
+	tmp, err := ioutil.TempFile(bodies, ".body")
+	if err != nil {
+		return "", false, nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", false, nil, err
+	}
+	name := hex.EncodeToString(hash.Sum(nil))
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") && size <= 1<<20 {
+		body, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			return "", false, nil, err
+		}
+		if redacted, ok := redactTokens(body); ok {
+			os.Remove(tmp.Name())
+			sum := sha256.Sum256(redacted)
+			name = hex.EncodeToString(sum[:])
+			if err := ioutil.WriteFile(filepath.Join(bodies, name), redacted, 0644); err != nil {
+				return "", false, nil, err
+			}
+			return name, true, ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(bodies, name)); err != nil {
+		return "", false, nil, err
+	}
+	file, err := os.Open(filepath.Join(bodies, name))
+	if err != nil {
+		return "", false, nil, err
+	}
+	return name, false, file, nil
+}
+
+// redactTokens replaces the tokens of a token service response.
+func redactTokens(body []byte) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+	redacted := false
+	for _, field := range fixtureTokenFields {
+		if _, ok := fields[field]; ok {
+			fields[field] = json.RawMessage(`"REDACTED"`)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return nil, false
+	}
+	out, err := json.Marshal(fields)
+	return out, err == nil
+}
+
+// recordTransport saves each response in full to the recording before
+// handing it on, with cookies left out.
+type recordTransport struct {
+	inner    http.RoundTripper
+	recorder *fixtureRecorder
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := FixtureEntry{Method: req.Method, URL: req.URL.String()}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		t.recorder.record(entry)
+		return nil, err
+	}
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	name, redacted, body, err := t.recorder.storeBody(resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "recording the response to %s %s", req.Method, redactURL(req.URL))
+	}
+	if redacted {
+		header.Del("Content-Length")
+	}
+	entry.Status = resp.StatusCode
+	entry.Header = header
+	entry.Body = name
+	t.recorder.record(entry)
+	resp.Body = body
+	return resp, nil
+}
+
+// fixtureReplayer answers requests with the recorded responses to the
+// same method and URL, in the order they were recorded, repeating the last
+// one once all were served. Requests it has no response to fail.
+type fixtureReplayer struct {
+	mu        sync.Mutex
+	dir       string
+	responses map[string][]FixtureEntry
+	served    map[string]int
+}
+
+func newFixtureReplayer(dir string) (*fixtureReplayer, error) {
+	index, err := os.Open(filepath.Join(dir, fixtureIndex))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening recording")
+	}
+	defer index.Close()
+	replayer := &fixtureReplayer{dir: dir, responses: map[string][]FixtureEntry{}, served: map[string]int{}}
+	decoder := json.NewDecoder(index)
+	for {
+		var entry FixtureEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "reading recording %s", index.Name())
+		}
+		key := entry.Method + " " + entry.URL
+		replayer.responses[key] = append(replayer.responses[key], entry)
+	}
+	return replayer, nil
+}
+
+func (r *fixtureReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := req.Method + " " + req.URL.String()
+	r.mu.Lock()
+	entries := r.responses[key]
+	i := r.served[key]
+	r.served[key]++
+	r.mu.Unlock()
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no recorded response to %s %s in %s", req.Method, redactURL(req.URL), r.dir)
+	}
+	if i >= len(entries) {
+		i = len(entries) - 1
+	}
+	entry := entries[i]
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	var body io.ReadCloser = ioutil.NopCloser(bytes.NewReader(nil))
+	if entry.Body != "" {
+		file, err := os.Open(filepath.Join(r.dir, fixtureBodies, entry.Body))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading recorded response")
+		}
+		body = file
+	}
+	contentLength := int64(-1)
+	if length, err := strconv.ParseInt(entry.Header.Get("Content-Length"), 10, 64); err == nil {
+		contentLength = length
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          body,
+		ContentLength: contentLength,
+		Request:       req,
+	}, nil
+}
@@ -18,6 +18,8 @@ package util
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"os"
@@ -33,9 +35,14 @@ type Directory struct {
 	Content []string
 }
 
+// DirectoryEntry is a file in a directory. Linkname is the target of a
+// symlink, as changing it leaves the size of the link looking unchanged.
+// Digest is the sha256 digest of the contents of a regular file.
 type DirectoryEntry struct {
-	Name string
-	Size int64
+	Name     string
+	Size     int64
+	Linkname string `json:",omitempty"`
+	Digest   string `json:",omitempty"`
 }
 
 func GetSize(path string) int64 {
@@ -54,7 +61,7 @@ func GetSize(path string) int64 {
 	return stat.Size()
 }
 
-//GetFileContents returns the contents of a file at the specified path
+// GetFileContents returns the contents of a file at the specified path
 func GetFileContents(path string) (*string, error) {
 	if _, err := os.Lstat(path); os.IsNotExist(err) {
 		return nil, err
@@ -113,6 +120,19 @@ func GetDirectory(path string, deep bool) (Directory, error) {
 	return directory, err
 }
 
+// WalkReadable walks the file tree of root as filepath.Walk does, calling fn
+// for each file and directory which can be read. Image filesystems extracted
+// without root privileges hold directories which can't, and analyzers look
+// past them rather than failing.
+func WalkReadable(root string, fn func(path string, info os.FileInfo) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		return fn(path, info)
+	})
+}
+
 func GetDirectoryEntries(d Directory) []DirectoryEntry {
 	return CreateDirectoryEntries(d.Root, d.Content)
 }
@@ -123,14 +143,53 @@ func CreateDirectoryEntries(root string, entryNames []string) (entries []Directo
 		size := GetSize(entryPath)
 
 		entry := DirectoryEntry{
-			Name: name,
-			Size: size,
+			Name:     name,
+			Size:     size,
+			Linkname: GetLinkname(entryPath),
+			Digest:   GetDigest(entryPath),
 		}
 		entries = append(entries, entry)
 	}
 	return entries
 }
 
+// GetLinkname returns the target of the symlink at path, or an empty string
+// if it isn't one.
+func GetLinkname(path string) string {
+	stat, err := os.Lstat(path)
+	if err != nil || stat.Mode()&os.ModeSymlink == 0 {
+		return ""
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		logrus.Errorf("Could not read symlink %s: %s", path, err)
+		return ""
+	}
+	return link
+}
+
+// GetDigest returns the sha256 digest of the regular file at path, e.g.
+// sha256:9f86d0..., or an empty string for directories, links and other
+// entries without contents of their own.
+func GetDigest(path string) string {
+	stat, err := os.Lstat(path)
+	if err != nil || !stat.Mode().IsRegular() {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.Errorf("Could not open %s to compute its digest: %s", path, err)
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		logrus.Errorf("Could not read %s to compute its digest: %s", path, err)
+		return ""
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
 func CheckSameSymlink(f1name, f2name string) (bool, error) {
 	link1, err := os.Readlink(f1name)
 	if err != nil {
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+// IsRegistryImage reports whether imageName is only looked up in a
+// registry, so its manifest can be fetched without pulling or exporting the
+// image. Images without a prefix are looked up in the local runtimes first.
+func IsRegistryImage(imageName string) bool {
+	return strings.HasPrefix(imageName, remotePrefix)
+}
+
+// CheckIdentical fetches the manifests of two registry images, and reports
+// whether they are the same image. Images are identical when they share a
+// manifest digest, which is read from a HEAD request, or, if compareLayers is
+// set, when their configs and all layer digests match, as for the same build
+// pushed with different manifest annotations. No config or layer is fetched.
+// The reason explains the match.
+func CheckIdentical(image1, image2 string, compareLayers bool) (bool, string, error) {
+	for _, image := range []string{image1, image2} {
+		if !IsRegistryImage(image) {
+			return false, "", fmt.Errorf("%s is not a registry image, prefix it with %s to compare it before pulling", image, remotePrefix)
+		}
+	}
+	digest1, err := manifestDigest(image1)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "getting digest of %s", image1)
+	}
+	digest2, err := manifestDigest(image2)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "getting digest of %s", image2)
+	}
+	if digest1 == digest2 {
+		return true, fmt.Sprintf("both images resolve to manifest %s", digest1), nil
+	}
+	if !compareLayers {
+		return false, "", nil
+	}
+
+	manifest1, err := platformManifest(image1)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "getting manifest of %s", image1)
+	}
+	manifest2, err := platformManifest(image2)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "getting manifest of %s", image2)
+	}
+	if manifest1.Config.Digest != manifest2.Config.Digest || !sameLayers(manifest1.Layers, manifest2.Layers) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("config %s and all %d layer digests match", manifest1.Config.Digest, len(manifest1.Layers)), nil
+}
+
+// manifestDigest returns the digest of the manifest or manifest list a
+// registry image resolves to. Registries report it in the headers of a HEAD
+// request; the manifest is only fetched and hashed when they don't.
+func manifestDigest(imageName string) (v1.Hash, error) {
+	client, ref, base, err := newRegistryClient(imageName)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	if digest, ok := ref.(name.Digest); ok {
+		return v1.NewHash(digest.DigestStr())
+	}
+	req, err := http.NewRequest(http.MethodHead, base+"/manifests/"+ref.Identifier(), nil)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return v1.Hash{}, manifestError(ref, err)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return v1.NewHash(digest)
+	}
+	manifest, err := getRegistryManifest(client, base+"/manifests/"+ref.Identifier())
+	if err != nil {
+		return v1.Hash{}, manifestError(ref, err)
+	}
+	digest, _, err := v1.SHA256(bytes.NewReader(manifest))
+	return digest, err
+}
+
+// platformManifest fetches the manifest of a registry image, resolving a
+// manifest list to the image of the --platform.
+func platformManifest(imageName string) (*v1.Manifest, error) {
+	client, ref, base, err := newRegistryClient(imageName)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := getRegistryManifest(client, base+"/manifests/"+ref.Identifier())
+	if err != nil {
+		return nil, manifestError(ref, err)
+	}
+	var index v1.IndexManifest
+	if err := json.Unmarshal(raw, &index); err == nil && len(index.Manifests) != 0 {
+		platform := DefaultPlatform()
+		image, ok := matchPlatform(indexPlatformImages(index, ref.Context().String()), platform)
+		if !ok {
+			return nil, fmt.Errorf("%s has no %s image", imageName, platform)
+		}
+		if raw, err = getRegistryManifest(client, base+"/manifests/"+image.Digest); err != nil {
+			return nil, err
+		}
+	}
+	return v1.ParseManifest(bytes.NewReader(raw))
+}
+
+func sameLayers(layers1, layers2 []v1.Descriptor) bool {
+	if len(layers1) != len(layers2) {
+		return false
+	}
+	for i := range layers1 {
+		if layers1[i].Digest != layers2[i].Digest {
+			return false
+		}
+	}
+	return true
+}
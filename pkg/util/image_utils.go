@@ -28,13 +28,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/daemon"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 
 	"github.com/docker/docker/pkg/system"
 	"github.com/pkg/errors"
@@ -42,8 +41,12 @@ import (
 )
 
 const (
-	daemonPrefix = "daemon://"
-	remotePrefix = "remote://"
+	daemonPrefix     = "daemon://"
+	remotePrefix     = "remote://"
+	containerdPrefix = "containerd://"
+	podmanPrefix     = "podman://"
+	// the transport name skopeo and buildah use for the same local storage
+	containersStoragePrefix = "containers-storage:"
 
 	tagRegexStr = ".*:([^/]+$)"
 )
@@ -51,6 +54,8 @@ const (
 type Layer struct {
 	FSPath string
 	Digest v1.Hash
+	// Cached tells layers reused from the cache of an earlier run.
+	Cached bool
 }
 
 type Image struct {
@@ -59,6 +64,18 @@ type Image struct {
 	FSPath string
 	Digest v1.Hash
 	Layers []Layer
+	// SkippedLayers lists the foreign and encrypted layers which were left
+	// out of the extracted filesystem.
+	SkippedLayers []SkippedLayer
+	// Inventory is set instead of extracting the full filesystem when the
+	// image is streamed with GetImageInventory.
+	Inventory FileInventory
+	// Origin tells daemon images the daemon already had, OriginLocal, from
+	// those pulled for this run, OriginPulled. It is empty for other images.
+	Origin string
+	// Cached tells whether the filesystem was reused from the cache of an
+	// earlier run rather than extracted.
+	Cached bool
 }
 
 type ImageHistoryItem struct {
@@ -75,14 +92,127 @@ func GetImageForName(imageName string) (Image, error) {
 // Once a reference is obtained, it attempts to unpack the v1.Image's reader's contents
 // into a temp directory on the local filesystem.
 func GetImage(imageName string, includeLayers bool, cacheDir string) (Image, error) {
+	img, imageName, cleanup, err := getImageReference(imageName)
+	if err != nil {
+		return Image{}, err
+	}
+	defer cleanup()
+
+	imgLayers, err := img.Layers()
+	if err != nil {
+		return Image{}, errors.Wrap(err, "getting image layers")
+	}
+	resolvedLayers, skipped, err := resolveLayers(img)
+	if err != nil {
+		return Image{}, err
+	}
+	for _, s := range skipped {
+		logrus.Infof("skipping layer %d (%s) of %s: %s", s.Index, s.Digest, imageName, s.Reason)
+	}
+
+	// create tempdir and extract fs into it
+	var layers []Layer
+	if includeLayers {
+		start := time.Now()
+		for i, layer := range imgLayers {
+			layerStart := time.Now()
+			digest, err := layer.Digest()
+			if err != nil {
+				return Image{
+					Layers: layers,
+				}, errors.Wrap(err, "getting layer digest")
+			}
+			path, cached, err := extractLayer(digest, resolvedLayers[i], cacheDir)
+			if err != nil {
+				return Image{
+					Layers: layers,
+				}, err
+			}
+			layers = append(layers, Layer{
+				FSPath: path,
+				Digest: digest,
+				Cached: cached,
+			})
+			elapsed := time.Now().Sub(layerStart)
+			logrus.Infof("time elapsed retrieving layer: %fs", elapsed.Seconds())
+		}
+		elapsed := time.Now().Sub(start)
+		logrus.Infof("time elapsed retrieving image layers: %fs", elapsed.Seconds())
+	}
+
+	imageDigest, err := getImageDigest(img)
+	if err != nil {
+		return Image{}, err
+	}
+	extractImg, err := extractableImage(img, resolvedLayers)
+	if err != nil {
+		return Image{}, errors.Wrap(err, "filtering image layers")
+	}
+	// extract fs into the cache dir, or a fresh one
+	path, cached, err := extractFileSystem(RemoveTag(imageName)+"@"+imageDigest.String(), imageDigest.String(), cacheDir, extractedSize(resolvedLayers), func(path string) error {
+		return GetFileSystemForImage(extractImg, path, nil)
+	})
+	if err != nil {
+		return Image{
+			FSPath: path,
+			Layers: layers,
+		}, errors.Wrap(err, "getting filesystem for image")
+	}
+	return Image{
+		Image:         img,
+		Source:        imageName,
+		FSPath:        path,
+		Digest:        imageDigest,
+		Layers:        layers,
+		SkippedLayers: skipped,
+		Origin:        daemonImageOrigin(imageName),
+		Cached:        cached,
+	}, nil
+}
+
+// getImageReference infers the source of an image and retrieves a v1.Image
+// reference to it, along with the image name stripped of any source prefix.
+// The returned function releases any local copy the reference reads from, and
+// must be called once the image has been extracted.
+func getImageReference(imageName string) (v1.Image, string, func(), error) {
 	logrus.Infof("retrieving image: %s", imageName)
 	var img v1.Image
 	var err error
-	if IsTar(imageName) {
+	cleanup := func() {}
+	if prefix, source, ok := registeredImageSource(imageName); ok {
+		img, cleanup, err = getRegisteredImage(prefix, source, imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if strings.HasPrefix(imageName, sshPrefix) {
+		img, cleanup, err = getSSHImage(imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		imageName = strings.TrimPrefix(imageName, sshPrefix)
+	} else if strings.HasPrefix(imageName, containerdPrefix) {
+		imageName = strings.TrimPrefix(imageName, containerdPrefix)
+		img, cleanup, err = getContainerdImage(imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if strings.HasPrefix(imageName, podmanPrefix) || strings.HasPrefix(imageName, containersStoragePrefix) {
+		var spec string
+		spec, imageName = splitContainersStorageSource(imageName)
+		img, cleanup, err = getContainersStorageImage(spec, imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if strings.HasPrefix(imageName, ociLayoutPrefix) {
+		img, err = getOCILayoutImage(imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if isTarballSource(imageName) {
 		start := time.Now()
-		img, err = tarball.ImageFromPath(imageName, nil)
+		img, err = imageTarball(splitTarballSource(imageName))
 		if err != nil {
-			return Image{}, errors.Wrap(err, "retrieving tar from path")
+			return nil, "", nil, errors.Wrap(err, "retrieving tar from path")
 		}
 		elapsed := time.Now().Sub(start)
 		logrus.Infof("retrieving image ref from tar took %f seconds", elapsed.Seconds())
@@ -92,115 +222,162 @@ func GetImage(imageName string, includeLayers bool, cacheDir string) (Image, err
 
 		ref, err := name.ParseReference(imageName, name.WeakValidation)
 		if err != nil {
-			return Image{}, errors.Wrap(err, "parsing image reference")
+			return nil, "", nil, errors.Wrap(err, "parsing image reference")
+		}
+
+		// the daemon package only reads DOCKER_HOST
+		if host, ok := dockerSocketHost(); ok && os.Getenv("DOCKER_HOST") == "" {
+			os.Setenv("DOCKER_HOST", host)
+		}
+		if err := prepareDaemonImage(imageName, ref); err != nil {
+			return nil, "", nil, err
 		}
 
 		start := time.Now()
 		// TODO(nkubala): specify gzip.NoCompression here when functional options are supported
 		img, err = daemon.Image(ref, daemon.WithBufferedOpener())
 		if err != nil {
-			return Image{}, errors.Wrap(err, "retrieving image from daemon")
+			return nil, "", nil, errors.Wrap(err, "retrieving image from daemon")
 		}
 		elapsed := time.Now().Sub(start)
 		logrus.Infof("retrieving local image ref took %f seconds", elapsed.Seconds())
 	} else {
-		// either has remote prefix or has no prefix, in which case we force remote
+		// images without a prefix are looked up in the local runtimes first
+		if !strings.HasPrefix(imageName, remotePrefix) {
+			if img, cleanup, err = getLocalImage(imageName); err == nil {
+				warnForeignPlatform(img, imageName)
+				return img, imageName, cleanup, nil
+			}
+			logrus.Infof("%s, falling back to the registry", err)
+			cleanup = func() {}
+		}
 		imageName = strings.Replace(imageName, remotePrefix, "", -1)
-		ref, err := name.ParseReference(imageName, name.WeakValidation)
+		pullName, err := resolvePlatformReference(imageName)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		ref, err := parseRemoteReference(pullName)
 		if err != nil {
-			return Image{}, errors.Wrap(err, "parsing image reference")
+			return nil, "", nil, errors.Wrap(err, "parsing image reference")
 		}
-		auth, err := authn.DefaultKeychain.Resolve(ref.Context().Registry)
+		auth, err := resolveAuth(ref.Context().Registry)
 		if err != nil {
-			return Image{}, errors.Wrap(err, "resolving auth")
+			return nil, "", nil, err
 		}
 		start := time.Now()
 		img, err = remote.Image(ref, remote.WithAuth(auth), remote.WithTransport(BuildTransport(ref.Context().Registry)))
 		if err != nil {
-			return Image{}, errors.Wrap(err, "retrieving remote image")
+			return nil, "", nil, errors.Wrap(err, "retrieving remote image")
+		}
+		// fetch the manifest now, so a registry refusing to resolve the
+		// reference is reported as such rather than as a later digest error
+		if _, err := img.RawManifest(); err != nil {
+			return nil, "", nil, manifestError(ref, err)
 		}
 		elapsed := time.Now().Sub(start)
 		logrus.Infof("retrieving remote image ref took %f seconds", elapsed.Seconds())
 	}
+	warnForeignPlatform(img, imageName)
+	return img, imageName, cleanup, nil
+}
 
-	// create tempdir and extract fs into it
-	var layers []Layer
-	if includeLayers {
-		start := time.Now()
-		imgLayers, err := img.Layers()
-		if err != nil {
-			return Image{}, errors.Wrap(err, "getting image layers")
-		}
-		for _, layer := range imgLayers {
-			layerStart := time.Now()
-			digest, err := layer.Digest()
-			path, err := getExtractPathForName(digest.String(), cacheDir)
-			if err != nil {
-				return Image{
-					Layers: layers,
-				}, errors.Wrap(err, "getting extract path for layer")
-			}
-			if err := GetFileSystemForLayer(layer, path, nil); err != nil {
-				return Image{
-					Layers: layers,
-				}, errors.Wrap(err, "getting filesystem for layer")
-			}
-			layers = append(layers, Layer{
-				FSPath: path,
-				Digest: digest,
-			})
-			elapsed := time.Now().Sub(layerStart)
-			logrus.Infof("time elapsed retrieving layer: %fs", elapsed.Seconds())
+// parseRemoteReference parses a registry reference. References carrying both
+// a tag and a digest are pulled by digest alone, so the tag is never resolved.
+func parseRemoteReference(imageName string) (name.Reference, error) {
+	if i := strings.Index(imageName, "@"); i >= 0 {
+		repo, digest := imageName[:i], imageName[i:]
+		if HasTag(repo) {
+			logrus.Infof("pulling %s by digest, ignoring its tag", imageName)
+			repo = RemoveTag(repo)
 		}
-		elapsed := time.Now().Sub(start)
-		logrus.Infof("time elapsed retrieving image layers: %fs", elapsed.Seconds())
+		return name.NewDigest(repo+digest, name.WeakValidation)
 	}
+	return name.ParseReference(imageName, name.WeakValidation)
+}
 
-	imageDigest, err := getImageDigest(img)
-	if err != nil {
-		return Image{}, err
+// manifestError explains a failure to fetch the manifest for ref. Registries
+// which only allow pulls by digest refuse to resolve tags with a variety of
+// errors. Some of them are only sent for such a refusal, while others, such
+// as DENIED, are as likely to mean missing credentials or a missing tag and
+// are reported with both explanations.
+func manifestError(ref name.Reference, err error) error {
+	tag, ok := ref.(name.Tag)
+	if !ok {
+		return errors.Wrapf(err, "retrieving manifest for %s by digest", ref)
 	}
-	path, err := getExtractPathForName(RemoveTag(imageName)+"@"+imageDigest.String(), cacheDir)
-	if err != nil {
-		return Image{}, err
+	switch {
+	case tagResolutionRefused(err):
+		return errors.Errorf("registry %s refused to resolve tag %q (%s); if it only allows pulls by digest, specify the image as %s@sha256:<digest>",
+			tag.RegistryStr(), tag.TagStr(), err, tag.Context())
+	case tagResolutionDenied(err):
+		return errors.Errorf("registry %s could not resolve tag %q (%s); check that the tag exists and that the credentials allow pulling it, or if the registry only allows pulls by digest, specify the image as %s@sha256:<digest>",
+			tag.RegistryStr(), tag.TagStr(), err, tag.Context())
 	}
-	// extract fs into provided dir
-	if err := GetFileSystemForImage(img, path, nil); err != nil {
-		return Image{
-			FSPath: path,
-			Layers: layers,
-		}, errors.Wrap(err, "getting filesystem for image")
+	return errors.Wrapf(err, "retrieving manifest for tag %q", tag.TagStr())
+}
+
+// tagResolutionRefused reports whether err is one registries only send when
+// they don't resolve tags at all.
+func tagResolutionRefused(err error) bool {
+	if terr, ok := err.(*transport.Error); ok {
+		for _, d := range terr.Errors {
+			switch d.Code {
+			case transport.UnsupportedErrorCode, transport.TagInvalidErrorCode:
+				return true
+			}
+		}
+		return false
 	}
-	return Image{
-		Image:  img,
-		Source: imageName,
-		FSPath: path,
-		Digest: imageDigest,
-		Layers: layers,
-	}, nil
+	return strings.HasPrefix(err.Error(), "unsupported status code 405")
 }
 
-func getExtractPathForName(name string, cacheDir string) (string, error) {
-	path := cacheDir
-	var err error
-	if cacheDir != "" {
-		// if cachedir doesn't exist, create it
-		if _, err := os.Stat(cacheDir); err != nil && os.IsNotExist(err) {
-			err = os.MkdirAll(cacheDir, 0700)
-			if err != nil {
-				return "", err
+// tagResolutionDenied reports whether err may be a policy refusing to
+// resolve tags, as well as a missing tag or missing credentials.
+func tagResolutionDenied(err error) bool {
+	if terr, ok := err.(*transport.Error); ok {
+		for _, d := range terr.Errors {
+			switch d.Code {
+			case transport.DeniedErrorCode, transport.ManifestUnknownErrorCode:
+				return true
 			}
-			logrus.Infof("caching filesystem at %s", cacheDir)
 		}
-	} else {
-		// otherwise, create tempdir
+		return false
+	}
+	return strings.HasPrefix(err.Error(), "unsupported status code 403")
+}
+
+// extractFileSystem runs extract on the directory name is extracted to,
+// of about size bytes: cacheDir, or else a directory of the extraction
+// backend. It reports whether the filesystem of digest was already fully
+// extracted to cacheDir by an earlier run, which is then reused as is.
+func extractFileSystem(name, digest, cacheDir string, size int64, extract func(path string) error) (string, bool, error) {
+	if cacheDir == "" {
 		logrus.Infof("skipping caching")
-		path, err = ioutil.TempDir("", strings.Replace(name, "/", "", -1))
-		if err != nil {
-			return "", err
+		path, err := extractToWorkdir(name, size, extract)
+		return path, false, err
+	}
+	switch cached := cachedDigest(cacheDir); {
+	case cached == digest:
+		logrus.Infof("reusing cached filesystem of %s in %s", name, cacheDir)
+		return cacheDir, true, nil
+	case cached != "":
+		logrus.Infof("cached filesystem in %s is of %s, extracting %s again", cacheDir, cached, name)
+	default:
+		if empty, err := DirIsEmpty(cacheDir); err == nil && !empty {
+			logrus.Infof("cached filesystem in %s is incomplete, extracting %s again", cacheDir, name)
 		}
 	}
-	return path, nil
+	if err := clearCache(cacheDir); err != nil {
+		return "", false, err
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return "", false, err
+	}
+	logrus.Infof("caching filesystem at %s", cacheDir)
+	if err := extract(cacheDir); err != nil {
+		return cacheDir, false, err
+	}
+	return cacheDir, false, recordCache(cacheDir, digest)
 }
 
 func getImageDigest(image v1.Image) (digest v1.Hash, err error) {
@@ -217,12 +394,18 @@ func getImageDigest(image v1.Image) (digest v1.Hash, err error) {
 func CleanupImage(image Image) {
 	if image.FSPath != "" {
 		logrus.Infof("Removing image filesystem directory %s from system", image.FSPath)
+		makeWritable(image.FSPath)
 		if err := os.RemoveAll(image.FSPath); err != nil {
 			logrus.Warn(err.Error())
 		}
 	}
 	if image.Layers != nil {
 		for _, layer := range image.Layers {
+			if !releaseLayer(layer.Digest) {
+				// still used by another image
+				continue
+			}
+			makeWritable(layer.FSPath)
 			if err := os.RemoveAll(layer.FSPath); err != nil {
 				logrus.Warn(err.Error())
 			}
@@ -239,7 +422,7 @@ func SortMap(m map[string]string) string {
 	return strings.Join(pairs, " ")
 }
 
-// GetFileSystemForLayer unpacks a layer to local disk
+// GetFileSystemForLayer unpacks a layer to local disk, read-only
 func GetFileSystemForLayer(layer v1.Layer, root string, whitelist []string) error {
 	empty, err := DirIsEmpty(root)
 	if err != nil {
@@ -253,10 +436,13 @@ func GetFileSystemForLayer(layer v1.Layer, root string, whitelist []string) erro
 	if err != nil {
 		return err
 	}
-	return unpackTar(tar.NewReader(contents), root, whitelist)
+	if err := unpackTar(tar.NewReader(contents), root, whitelist); err != nil {
+		return err
+	}
+	return makeReadOnly(root)
 }
 
-// unpack image filesystem to local disk
+// unpack image filesystem to local disk, read-only
 // if provided directory is not empty, do nothing
 func GetFileSystemForImage(image v1.Image, root string, whitelist []string) error {
 	empty, err := DirIsEmpty(root)
@@ -270,7 +456,7 @@ func GetFileSystemForImage(image v1.Image, root string, whitelist []string) erro
 	if err := unpackTar(tar.NewReader(mutate.Extract(image)), root, whitelist); err != nil {
 		return err
 	}
-	return nil
+	return makeReadOnly(root)
 }
 
 func GetImageLayers(pathToImage string) []string {
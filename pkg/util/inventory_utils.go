@@ -0,0 +1,284 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// FileInventory describes the flattened filesystem of an image, keyed by
+// absolute path in the same form as Directory.Content.
+type FileInventory map[string]InventoryEntry
+
+// InventoryEntry records what is needed to compare a file without keeping
+// its contents around.
+type InventoryEntry struct {
+	Name     string
+	Size     int64
+	IsDir    bool
+	Linkname string `json:",omitempty"`
+	Digest   string `json:",omitempty"`
+	// Mode, Uid and Gid come from the tar header, as extracting drops the
+	// ownership. Directories only implied by the paths below them have no
+	// mode.
+	Mode os.FileMode `json:",omitempty"`
+	Uid  int         `json:",omitempty"`
+	Gid  int         `json:",omitempty"`
+}
+
+// Directory converts the inventory into a Directory rooted at root, so it
+// can be used wherever a walked directory is expected.
+func (inv FileInventory) Directory(root string) Directory {
+	d := Directory{Root: root}
+	for name := range inv {
+		d.Content = append(d.Content, name)
+	}
+	return d
+}
+
+// GetImageInventory retrieves an image reference and streams its flattened
+// filesystem into a FileInventory instead of extracting it. Only the files in
+// materialize are written to disk, under the returned image's FSPath.
+func GetImageInventory(imageName string, materialize []string) (Image, error) {
+	img, imageName, cleanup, err := getImageReference(imageName)
+	if err != nil {
+		return Image{}, err
+	}
+	defer cleanup()
+	resolvedLayers, skipped, err := resolveLayers(img)
+	if err != nil {
+		return Image{}, err
+	}
+	for _, s := range skipped {
+		logrus.Infof("skipping layer %d (%s) of %s: %s", s.Index, s.Digest, imageName, s.Reason)
+	}
+	extractImg, err := extractableImage(img, resolvedLayers)
+	if err != nil {
+		return Image{}, errors.Wrap(err, "filtering image layers")
+	}
+	imageDigest, err := getImageDigest(img)
+	if err != nil {
+		return Image{}, err
+	}
+	start := time.Now()
+	var inventory FileInventory
+	// only the materialized files are written out
+	path, _, err := extractFileSystem(RemoveTag(imageName)+"@"+imageDigest.String(), imageDigest.String(), "", 0, func(path string) error {
+		inventory, err = StreamFileInventory(extractImg, path, materialize)
+		return err
+	})
+	if err != nil {
+		return Image{FSPath: path}, errors.Wrap(err, "streaming image filesystem")
+	}
+	elapsed := time.Now().Sub(start)
+	logrus.Infof("time elapsed streaming image inventory: %fs", elapsed.Seconds())
+	return Image{
+		Image:         img,
+		Source:        imageName,
+		FSPath:        path,
+		Digest:        imageDigest,
+		Inventory:     inventory,
+		SkippedLayers: skipped,
+		Origin:        daemonImageOrigin(imageName),
+	}, nil
+}
+
+// StreamFileInventory reads the flattened filesystem of image, hashing file
+// contents as they stream past. Files listed in materialize, or matching one
+// of its globs as MatchPathGlob does, are also written below root so their
+// contents can be diffed.
+func StreamFileInventory(image v1.Image, root string, materialize []string) (FileInventory, error) {
+	return streamInventory(image, root, materialize, true)
+}
+
+// StreamFileModes reads the names, modes and owners of the files in the
+// flattened filesystem of image, without digesting their contents. Layers
+// are skipped as they are when extracting.
+func StreamFileModes(image v1.Image) (FileInventory, error) {
+	resolvedLayers, _, err := resolveLayers(image)
+	if err != nil {
+		return nil, err
+	}
+	extractImg, err := extractableImage(image, resolvedLayers)
+	if err != nil {
+		return nil, errors.Wrap(err, "filtering image layers")
+	}
+	return streamInventory(extractImg, "", nil, false)
+}
+
+func streamInventory(image v1.Image, root string, materialize []string, hash bool) (FileInventory, error) {
+	wanted := map[string]bool{}
+	globs := []string{}
+	for _, name := range materialize {
+		if strings.ContainsAny(name, "*?[") {
+			globs = append(globs, name)
+		} else {
+			wanted[inventoryName(name)] = true
+		}
+	}
+	materialized := func(name string) bool {
+		if wanted[name] {
+			return true
+		}
+		for _, glob := range globs {
+			if MatchPathGlob(glob, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	inv := FileInventory{}
+	hardlinks := map[string]string{}
+	tr := tar.NewReader(mutate.Extract(image))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Error getting next tar header")
+		}
+		name := inventoryName(header.Name)
+		if name == "/" {
+			continue
+		}
+		addParentDirs(inv, name)
+		entry := InventoryEntry{Name: name, Mode: header.FileInfo().Mode(), Uid: header.Uid, Gid: header.Gid}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.IsDir = true
+		case tar.TypeReg:
+			if !hash {
+				entry.Size = header.Size
+				break
+			}
+			size, digest, err := hashEntry(tr, root, name, materialized(name))
+			if err != nil {
+				return nil, err
+			}
+			entry.Size = size
+			entry.Digest = digest
+		case tar.TypeSymlink:
+			entry.Size = int64(len(header.Linkname))
+			entry.Linkname = header.Linkname
+		case tar.TypeLink:
+			hardlinks[name] = inventoryName(header.Linkname)
+		default:
+			entry.Size = header.Size
+		}
+		inv[name] = entry
+	}
+	for name, target := range hardlinks {
+		if linked, ok := inv[target]; ok {
+			linked.Name = name
+			inv[name] = linked
+		}
+	}
+	return inv, nil
+}
+
+// Sizes returns the size of every entry, with directories summing the
+// entries below them to match GetSize on an extracted directory.
+func (inv FileInventory) Sizes() map[string]int64 {
+	sizes := make(map[string]int64, len(inv))
+	for name, entry := range inv {
+		if entry.IsDir {
+			continue
+		}
+		sizes[name] += entry.Size
+		for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+			sizes[dir] += entry.Size
+		}
+	}
+	for name, entry := range inv {
+		if _, ok := sizes[name]; !ok && entry.IsDir {
+			sizes[name] = 0
+		}
+	}
+	return sizes
+}
+
+// hashEntry digests the current tar entry, copying it below root as well
+// when it needs to be materialized.
+func hashEntry(r io.Reader, root, name string, materialize bool) (int64, string, error) {
+	h := sha256.New()
+	w := io.Writer(h)
+	if materialize {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return 0, "", err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return 0, "", err
+		}
+		defer f.Close()
+		w = io.MultiWriter(h, f)
+	}
+	size, err := io.Copy(w, r)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MatchPathGlob reports whether the image path name matches a glob. Globs
+// without a slash match the base name, and a trailing /** matches everything
+// below a directory.
+func MatchPathGlob(glob, name string) bool {
+	name = "/" + strings.TrimPrefix(name, "/")
+	if strings.HasSuffix(glob, "/**") {
+		dir := "/" + strings.Trim(strings.TrimSuffix(glob, "/**"), "/")
+		return strings.HasPrefix(name, dir+"/")
+	}
+	if !strings.Contains(glob, "/") {
+		name = path.Base(name)
+	} else {
+		glob = "/" + strings.TrimPrefix(glob, "/")
+	}
+	matched, _ := path.Match(glob, name)
+	return matched
+}
+
+func inventoryName(name string) string {
+	return path.Clean("/" + name)
+}
+
+// addParentDirs records the parent directories of name, which are not always
+// present as their own tar entries.
+func addParentDirs(inv FileInventory, name string) {
+	for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+		if _, ok := inv[dir]; ok {
+			return
+		}
+		inv[dir] = InventoryEntry{Name: dir, IsDir: true}
+	}
+}
@@ -0,0 +1,498 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/third_party/zstd"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	encryptedMediaTypeSuffix = "+encrypted"
+
+	jweKeysAnnotation  = "org.opencontainers.image.enc.keys.jwe"
+	pubOptsAnnotation  = "org.opencontainers.image.enc.pubopts"
+	aesCTRHMACSHA256   = "AES_256_CTR_HMAC_SHA256"
+	nondistributableMT = ".nondistributable."
+
+	// zstd compressed layers are typed tar+zstd by OCI, and tar.zstd by
+	// some Docker tooling
+	zstdMediaTypeSuffix       = "+zstd"
+	dockerZstdMediaTypeSuffix = ".zstd"
+	// estargzTOCAnnotation marks eStargz layers, whose gzip stream is
+	// split into chunks indexed by a table of contents
+	estargzTOCAnnotation = "containerd.io/snapshot/stargz/toc.digest"
+)
+
+// estargzMetadata are the entries eStargz layers add at the root of their
+// tar stream: the table of contents, and the landmark ending the files to
+// prefetch.
+var estargzMetadata = map[string]bool{
+	"stargz.index.json":     true,
+	".prefetch.landmark":    true,
+	".no.prefetch.landmark": true,
+}
+
+// SkippedLayer describes an image layer which was not extracted, along with
+// the manifest metadata needed to track it down.
+type SkippedLayer struct {
+	Index     int
+	Digest    v1.Hash
+	MediaType string
+	Size      int64
+	URLs      []string `json:",omitempty"`
+	Reason    string
+}
+
+var decryptionKeys []*rsa.PrivateKey
+
+// ConfigureDecryption loads the PEM encoded RSA private keys used to unwrap
+// encrypted layers. Keys which cannot be loaded are skipped with a warning.
+func ConfigureDecryption(keyPaths []string) {
+	decryptionKeys = nil
+	for _, path := range keyPaths {
+		key, err := loadRSAPrivateKey(path)
+		if err != nil {
+			logrus.Warnf("Failed to load decryption key %s: %s", path, err)
+			continue
+		}
+		decryptionKeys = append(decryptionKeys, key)
+	}
+}
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("only RSA private keys are supported")
+	}
+	return key, nil
+}
+
+// resolveLayers returns the layers of img that can be extracted, decrypting
+// encrypted layers where a matching key was configured. Layers which cannot be
+// extracted are nil in the returned slice and described in the skipped list.
+func resolveLayers(img v1.Image) ([]v1.Layer, []SkippedLayer, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "getting image layers")
+	}
+	manifest, err := img.Manifest()
+	if err != nil || manifest == nil || len(manifest.Layers) != len(layers) {
+		// without a manifest there is nothing to tell the layers apart by
+		return layers, nil, nil
+	}
+
+	resolved := make([]v1.Layer, len(layers))
+	var skipped []SkippedLayer
+	for i, layer := range layers {
+		desc := manifest.Layers[i]
+		mediaType := string(desc.MediaType)
+		skip := SkippedLayer{
+			Index:     i,
+			Digest:    desc.Digest,
+			MediaType: mediaType,
+			Size:      desc.Size,
+			URLs:      desc.URLs,
+		}
+		switch {
+		case desc.MediaType == types.DockerForeignLayer || (strings.Contains(mediaType, nondistributableMT) && len(desc.URLs) > 0):
+			skip.Reason = "foreign layer is not distributed by the registry"
+			skipped = append(skipped, skip)
+		case strings.HasSuffix(mediaType, encryptedMediaTypeSuffix):
+			if len(decryptionKeys) == 0 {
+				skip.Reason = "encrypted layer, no decryption key provided"
+				skipped = append(skipped, skip)
+				continue
+			}
+			decrypted, err := decryptLayer(layer, desc)
+			if err != nil {
+				skip.Reason = fmt.Sprintf("encrypted layer could not be decrypted: %s", err)
+				skipped = append(skipped, skip)
+				continue
+			}
+			// the decrypted layer reads the blob as it was before encryption
+			resolved[i] = decompressedLayer(decrypted, decrypted.Uncompressed, desc)
+		default:
+			resolved[i] = decompressedLayer(layer, layer.Compressed, desc)
+		}
+	}
+	return resolved, skipped, nil
+}
+
+// decompressedLayer returns layer reading its tar stream as described by
+// desc: decompressed from the zstd blob of compressed, and without the
+// metadata of eStargz. The registry library only knows gzip.
+func decompressedLayer(layer v1.Layer, compressed func() (io.ReadCloser, error), desc v1.Descriptor) v1.Layer {
+	mediaType := strings.TrimSuffix(string(desc.MediaType), encryptedMediaTypeSuffix)
+	if strings.HasSuffix(mediaType, zstdMediaTypeSuffix) || strings.HasSuffix(mediaType, dockerZstdMediaTypeSuffix) {
+		layer = &zstdLayer{Layer: layer, compressed: compressed}
+	}
+	if _, ok := desc.Annotations[estargzTOCAnnotation]; ok {
+		layer = &estargzLayer{Layer: layer}
+	}
+	return layer
+}
+
+// zstdLayer decompresses a zstd compressed layer.
+type zstdLayer struct {
+	v1.Layer
+	compressed func() (io.ReadCloser, error)
+}
+
+func (l *zstdLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.compressed()
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{zstd.NewReader(rc), rc}, nil
+}
+
+// estargzLayer leaves the eStargz metadata out of the tar stream of a
+// layer, so it isn't taken for files of the image.
+type estargzLayer struct {
+	v1.Layer
+}
+
+func (l *estargzLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer rc.Close()
+		pw.CloseWithError(copyTarWithout(rc, pw, estargzMetadata))
+	}()
+	return pr, nil
+}
+
+// copyTarWithout copies the tar stream of r to w, leaving out the entries
+// at the root named in skip.
+func copyTarWithout(r io.Reader, w io.Writer, skip map[string]bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if skip[path.Clean(strings.TrimPrefix(hdr.Name, "/"))] {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractableImage returns an image containing only the layers which can be
+// extracted, for use when flattening the filesystem.
+func extractableImage(img v1.Image, resolved []v1.Layer) (v1.Image, error) {
+	original, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	changed := len(original) != len(resolved)
+	var layers []v1.Layer
+	for i, layer := range resolved {
+		if layer == nil {
+			changed = true
+			continue
+		}
+		if i < len(original) && layer != original[i] {
+			changed = true
+		}
+		layers = append(layers, layer)
+	}
+	if !changed {
+		return img, nil
+	}
+	return mutate.AppendLayers(empty.Image, layers...)
+}
+
+// layerPrivateOptions is the JWE payload wrapped for each recipient of an
+// encrypted layer.
+type layerPrivateOptions struct {
+	SymmetricKey  []byte            `json:"symkey"`
+	Digest        string            `json:"digest"`
+	CipherOptions map[string][]byte `json:"cipheroptions"`
+}
+
+type layerPublicOptions struct {
+	Cipher string `json:"cipher"`
+	HMAC   []byte `json:"hmac"`
+}
+
+// decryptLayer unwraps the layer key with one of the configured RSA keys and
+// returns a layer which decrypts the blob on the fly.
+func decryptLayer(layer v1.Layer, desc v1.Descriptor) (v1.Layer, error) {
+	var pubOpts layerPublicOptions
+	if raw, ok := desc.Annotations[pubOptsAnnotation]; ok {
+		data, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding public options")
+		}
+		if err := json.Unmarshal(data, &pubOpts); err != nil {
+			return nil, errors.Wrap(err, "parsing public options")
+		}
+	}
+	if pubOpts.Cipher != "" && pubOpts.Cipher != aesCTRHMACSHA256 {
+		return nil, fmt.Errorf("unsupported layer cipher %s", pubOpts.Cipher)
+	}
+
+	keys, ok := desc.Annotations[jweKeysAnnotation]
+	if !ok {
+		return nil, errors.New("only JWE wrapped keys are supported")
+	}
+	var privOpts *layerPrivateOptions
+	for _, b64 := range strings.Split(keys, ",") {
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			continue
+		}
+		payload, err := decryptJWE(data, decryptionKeys)
+		if err != nil {
+			logrus.Debugf("Unable to unwrap layer key: %s", err)
+			continue
+		}
+		var opts layerPrivateOptions
+		if err := json.Unmarshal(payload, &opts); err != nil {
+			return nil, errors.Wrap(err, "parsing private options")
+		}
+		privOpts = &opts
+		break
+	}
+	if privOpts == nil {
+		return nil, errors.New("no provided key matches the layer recipients")
+	}
+
+	opener := func() (io.ReadCloser, error) {
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		return newLayerDecrypter(rc, privOpts.SymmetricKey, privOpts.CipherOptions["nonce"], pubOpts.HMAC)
+	}
+	decrypted, err := tarball.LayerFromOpener(opener)
+	if err != nil {
+		return nil, err
+	}
+	if privOpts.Digest != "" {
+		digest, err := decrypted.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if digest.String() != privOpts.Digest {
+			return nil, fmt.Errorf("decrypted digest %s does not match expected %s", digest, privOpts.Digest)
+		}
+	}
+	return decrypted, nil
+}
+
+// layerDecrypter decrypts an AES-256-CTR stream and verifies its HMAC once the
+// whole ciphertext has been read.
+type layerDecrypter struct {
+	rc       io.ReadCloser
+	stream   cipher.Stream
+	mac      hash.Hash
+	expected []byte
+}
+
+func newLayerDecrypter(rc io.ReadCloser, key, nonce, expectedMAC []byte) (io.ReadCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	if len(nonce) != block.BlockSize() {
+		rc.Close()
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	return &layerDecrypter{
+		rc:       rc,
+		stream:   cipher.NewCTR(block, nonce),
+		mac:      hmac.New(sha256.New, key),
+		expected: expectedMAC,
+	}, nil
+}
+
+func (d *layerDecrypter) Read(p []byte) (int, error) {
+	n, err := d.rc.Read(p)
+	if n > 0 {
+		d.mac.Write(p[:n])
+		d.stream.XORKeyStream(p[:n], p[:n])
+	}
+	if err == io.EOF && d.expected != nil && !hmac.Equal(d.mac.Sum(nil), d.expected) {
+		return n, errors.New("layer HMAC verification failed")
+	}
+	return n, err
+}
+
+func (d *layerDecrypter) Close() error {
+	return d.rc.Close()
+}
+
+type jweRecipient struct {
+	Header       map[string]interface{} `json:"header"`
+	EncryptedKey string                 `json:"encrypted_key"`
+}
+
+type jweJSON struct {
+	Protected    string                 `json:"protected"`
+	Unprotected  map[string]interface{} `json:"unprotected"`
+	Recipients   []jweRecipient         `json:"recipients"`
+	Header       map[string]interface{} `json:"header"`
+	EncryptedKey string                 `json:"encrypted_key"`
+	AAD          string                 `json:"aad"`
+	IV           string                 `json:"iv"`
+	Ciphertext   string                 `json:"ciphertext"`
+	Tag          string                 `json:"tag"`
+}
+
+// decryptJWE decrypts a JWE in JSON serialization whose content key was
+// wrapped with RSA-OAEP for one of the given keys.
+func decryptJWE(data []byte, keys []*rsa.PrivateKey) ([]byte, error) {
+	var jwe jweJSON
+	if err := json.Unmarshal(data, &jwe); err != nil {
+		return nil, err
+	}
+	recipients := jwe.Recipients
+	if len(recipients) == 0 {
+		recipients = []jweRecipient{{Header: jwe.Header, EncryptedKey: jwe.EncryptedKey}}
+	}
+	protected := map[string]interface{}{}
+	if jwe.Protected != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(jwe.Protected)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &protected); err != nil {
+			return nil, err
+		}
+	}
+	headerValue := func(r jweRecipient, name string) string {
+		for _, h := range []map[string]interface{}{r.Header, jwe.Unprotected, protected} {
+			if v, ok := h[name].(string); ok {
+				return v
+			}
+		}
+		return ""
+	}
+
+	for _, r := range recipients {
+		var h hash.Hash
+		switch headerValue(r, "alg") {
+		case "RSA-OAEP":
+			h = sha1.New()
+		case "RSA-OAEP-256":
+			h = sha256.New()
+		default:
+			continue
+		}
+		encryptedKey, err := base64.RawURLEncoding.DecodeString(r.EncryptedKey)
+		if err != nil {
+			continue
+		}
+		for _, key := range keys {
+			cek, err := rsa.DecryptOAEP(h, nil, key, encryptedKey, nil)
+			if err != nil {
+				continue
+			}
+			return decryptJWEContent(jwe, headerValue(r, "enc"), cek)
+		}
+	}
+	return nil, errors.New("no matching recipient")
+}
+
+func decryptJWEContent(jwe jweJSON, enc string, cek []byte) ([]byte, error) {
+	switch enc {
+	case "A128GCM", "A192GCM", "A256GCM":
+	default:
+		return nil, fmt.Errorf("unsupported content encryption %s", enc)
+	}
+	iv, err := base64.RawURLEncoding.DecodeString(jwe.IV)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(jwe.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := base64.RawURLEncoding.DecodeString(jwe.Tag)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	aad := jwe.Protected
+	if jwe.AAD != "" {
+		aad = aad + "." + jwe.AAD
+	}
+	return gcm.Open(nil, iv, append(ciphertext, tag...), []byte(aad))
+}
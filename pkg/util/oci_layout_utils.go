@@ -0,0 +1,377 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/container-diff/third_party/zstd"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ociLayoutPrefix marks images read from an OCI image layout directory, as
+// exported by buildah, skopeo or BuildKit's --output type=oci. Results
+// pushed with --push-result use the same scheme for registries, as they
+// are pushed as OCI artifacts.
+const ociLayoutPrefix = "oci://"
+
+// Annotations naming the images of an OCI layout index: the tag skopeo,
+// buildah and BuildKit record, and the full name containerd and BuildKit
+// also record.
+const (
+	ociRefNameAnnotation          = "org.opencontainers.image.ref.name"
+	containerdImageNameAnnotation = "io.containerd.image.name"
+)
+
+// getOCILayoutImage reads an image of an OCI image layout, the one tagged
+// tag when the source ends with :tag, the manifest of digest when it ends
+// with @digest, or else the only image of the layout. Indexes of images for
+// several platforms are resolved to the image for the default platform.
+func getOCILayoutImage(source string) (v1.Image, error) {
+	layout, desc, err := resolveOCILayoutSource(source)
+	if err != nil {
+		return nil, err
+	}
+	// follow nested indexes down to the image of the default platform
+	for desc.MediaType == types.OCIImageIndex || desc.MediaType == types.DockerManifestList {
+		var nested v1.IndexManifest
+		if err := readOCILayoutJSON(ociBlobPath(layout, desc.Digest), &nested); err != nil {
+			return nil, errors.Wrapf(err, "reading index %s", desc.Digest)
+		}
+		if desc, err = selectOCILayoutPlatform(nested); err != nil {
+			return nil, errors.Wrapf(err, "selecting image of %s", layout)
+		}
+	}
+
+	img := &ociLayoutImage{layout: layout, mediaType: desc.MediaType}
+	if img.rawManifest, err = ioutil.ReadFile(ociBlobPath(layout, desc.Digest)); err != nil {
+		return nil, errors.Wrap(err, "reading image manifest")
+	}
+	if img.manifest, err = v1.ParseManifest(bytes.NewReader(img.rawManifest)); err != nil {
+		return nil, errors.Wrap(err, "parsing image manifest")
+	}
+	if img.rawConfig, err = ioutil.ReadFile(ociBlobPath(layout, img.manifest.Config.Digest)); err != nil {
+		return nil, errors.Wrap(err, "reading image config")
+	}
+	if img.config, err = v1.ParseConfigFile(bytes.NewReader(img.rawConfig)); err != nil {
+		return nil, errors.Wrap(err, "parsing image config")
+	}
+	return img, nil
+}
+
+// resolveOCILayoutSource returns the layout of an OCI layout source and the
+// descriptor of the manifest it selects, by tag or by digest.
+func resolveOCILayoutSource(source string) (string, v1.Descriptor, error) {
+	source = strings.TrimPrefix(source, ociLayoutPrefix)
+	var tag string
+	layout, digest, byDigest := splitOCILayoutDigest(source)
+	if !byDigest {
+		layout, tag = splitOCILayoutSource(source)
+	}
+	if _, err := os.Stat(filepath.Join(layout, "oci-layout")); err != nil {
+		return "", v1.Descriptor{}, errors.Wrapf(err, "%s is not an OCI image layout", layout)
+	}
+	if !byDigest {
+		var index v1.IndexManifest
+		if err := readOCILayoutJSON(filepath.Join(layout, "index.json"), &index); err != nil {
+			return "", v1.Descriptor{}, errors.Wrap(err, "reading OCI layout index")
+		}
+		desc, err := selectOCILayoutManifest(index, tag)
+		if err != nil {
+			return "", v1.Descriptor{}, errors.Wrapf(err, "selecting image of %s", layout)
+		}
+		return layout, desc, nil
+	}
+	// manifests name their media type, which is optional for indexes
+	var manifest struct {
+		MediaType types.MediaType   `json:"mediaType"`
+		Manifests []json.RawMessage `json:"manifests"`
+	}
+	if err := readOCILayoutJSON(ociBlobPath(layout, digest), &manifest); err != nil {
+		return "", v1.Descriptor{}, errors.Wrapf(err, "reading manifest %s of %s", digest, layout)
+	}
+	desc := v1.Descriptor{Digest: digest, MediaType: manifest.MediaType}
+	if desc.MediaType == "" {
+		desc.MediaType = types.OCIManifestSchema1
+		if manifest.Manifests != nil {
+			desc.MediaType = types.OCIImageIndex
+		}
+	}
+	return layout, desc, nil
+}
+
+// splitOCILayoutDigest splits the digest from the path of an OCI layout, as
+// in path/to/layout@sha256:<hex>, which --all-platforms reads the image of
+// each platform by.
+func splitOCILayoutDigest(source string) (string, v1.Hash, bool) {
+	i := strings.LastIndex(source, "@")
+	if i < 0 || i < strings.LastIndex(source, "/") {
+		return source, v1.Hash{}, false
+	}
+	digest, err := v1.NewHash(source[i+1:])
+	if err != nil {
+		return source, v1.Hash{}, false
+	}
+	return source[:i], digest, true
+}
+
+// getOCILayoutPlatformImages reads the index of images for several
+// platforms of an OCI layout source, each referenced by its digest in the
+// layout.
+func getOCILayoutPlatformImages(source string) (map[string]PlatformImage, error) {
+	layout, desc, err := resolveOCILayoutSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if desc.MediaType != types.OCIImageIndex && desc.MediaType != types.DockerManifestList {
+		return nil, fmt.Errorf("%s is a single image, not a multi-platform index", source)
+	}
+	var index v1.IndexManifest
+	if err := readOCILayoutJSON(ociBlobPath(layout, desc.Digest), &index); err != nil {
+		return nil, errors.Wrapf(err, "reading index %s", desc.Digest)
+	}
+	return indexPlatformImages(index, ociLayoutPrefix+layout), nil
+}
+
+// splitOCILayoutSource splits the tag from the path of an OCI layout, as
+// in path/to/layout:tag. A path which exists as is has no tag, even when
+// it contains a colon.
+func splitOCILayoutSource(source string) (string, string) {
+	if _, err := os.Stat(source); err == nil {
+		return source, ""
+	}
+	if i := strings.LastIndex(source, ":"); i > strings.LastIndex(source, "/") {
+		return source[:i], source[i+1:]
+	}
+	return source, ""
+}
+
+func selectOCILayoutManifest(index v1.IndexManifest, tag string) (v1.Descriptor, error) {
+	if tag == "" {
+		if len(index.Manifests) == 1 {
+			return index.Manifests[0], nil
+		}
+		return v1.Descriptor{}, fmt.Errorf("the layout holds %d images, specify one of its tags: %s", len(index.Manifests), strings.Join(ociLayoutTags(index), ", "))
+	}
+	for _, desc := range index.Manifests {
+		name := desc.Annotations[containerdImageNameAnnotation]
+		if desc.Annotations[ociRefNameAnnotation] == tag || name == tag || strings.HasSuffix(name, ":"+tag) {
+			return desc, nil
+		}
+	}
+	return v1.Descriptor{}, fmt.Errorf("no image is tagged %s, the layout has: %s", tag, strings.Join(ociLayoutTags(index), ", "))
+}
+
+func ociLayoutTags(index v1.IndexManifest) []string {
+	tags := []string{}
+	for _, desc := range index.Manifests {
+		if tag := desc.Annotations[ociRefNameAnnotation]; tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func selectOCILayoutPlatform(index v1.IndexManifest) (v1.Descriptor, error) {
+	images := indexPlatformImages(index, "")
+	descs := map[string]v1.Descriptor{}
+	for _, desc := range index.Manifests {
+		descs[desc.Digest.String()] = desc
+	}
+	platform := DefaultPlatform()
+	image, ok := matchPlatform(images, platform)
+	if !ok {
+		available := []string{}
+		for key := range images {
+			available = append(available, key)
+		}
+		sort.Strings(available)
+		return v1.Descriptor{}, fmt.Errorf("no %s image, set --platform to one of: %s", platform, strings.Join(available, ", "))
+	}
+	logrus.Infof("using the %s image of the OCI layout: %s", image.Platform, image.Digest)
+	return descs[image.Digest], nil
+}
+
+func readOCILayoutJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func ociBlobPath(layout string, digest v1.Hash) string {
+	return filepath.Join(layout, "blobs", digest.Algorithm, digest.Hex)
+}
+
+// ociLayoutImage is an image of an OCI image layout, whose manifest and
+// config are read up front and whose layers are read from the blobs of
+// the layout.
+type ociLayoutImage struct {
+	layout      string
+	mediaType   types.MediaType
+	rawManifest []byte
+	manifest    *v1.Manifest
+	rawConfig   []byte
+	config      *v1.ConfigFile
+}
+
+var _ v1.Image = (*ociLayoutImage)(nil)
+
+func (i *ociLayoutImage) Layers() ([]v1.Layer, error) {
+	layers := []v1.Layer{}
+	for n := range i.manifest.Layers {
+		layers = append(layers, i.layer(n))
+	}
+	return layers, nil
+}
+
+func (i *ociLayoutImage) BlobSet() (map[v1.Hash]struct{}, error) {
+	return partial.BlobSet(i)
+}
+
+func (i *ociLayoutImage) MediaType() (types.MediaType, error) {
+	return i.mediaType, nil
+}
+
+func (i *ociLayoutImage) ConfigName() (v1.Hash, error) {
+	return i.manifest.Config.Digest, nil
+}
+
+func (i *ociLayoutImage) ConfigFile() (*v1.ConfigFile, error) {
+	return i.config, nil
+}
+
+func (i *ociLayoutImage) RawConfigFile() ([]byte, error) {
+	return i.rawConfig, nil
+}
+
+func (i *ociLayoutImage) Digest() (v1.Hash, error) {
+	return partial.Digest(i)
+}
+
+func (i *ociLayoutImage) Manifest() (*v1.Manifest, error) {
+	return i.manifest, nil
+}
+
+func (i *ociLayoutImage) RawManifest() ([]byte, error) {
+	return i.rawManifest, nil
+}
+
+func (i *ociLayoutImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	for n, desc := range i.manifest.Layers {
+		if desc.Digest == h {
+			return i.layer(n), nil
+		}
+	}
+	return nil, fmt.Errorf("no layer with digest %s", h)
+}
+
+func (i *ociLayoutImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	for n, diffID := range i.config.RootFS.DiffIDs {
+		if diffID == h && n < len(i.manifest.Layers) {
+			return i.layer(n), nil
+		}
+	}
+	return nil, fmt.Errorf("no layer with diff ID %s", h)
+}
+
+func (i *ociLayoutImage) layer(n int) *ociLayoutLayer {
+	layer := &ociLayoutLayer{path: ociBlobPath(i.layout, i.manifest.Layers[n].Digest), desc: i.manifest.Layers[n]}
+	if n < len(i.config.RootFS.DiffIDs) {
+		layer.diffID = i.config.RootFS.DiffIDs[n]
+	}
+	return layer
+}
+
+// zstdMagic starts zstd frames.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// ociLayoutLayer is a layer blob of an OCI image layout, compressed or not.
+type ociLayoutLayer struct {
+	path   string
+	desc   v1.Descriptor
+	diffID v1.Hash
+}
+
+func (l *ociLayoutLayer) Digest() (v1.Hash, error) {
+	return l.desc.Digest, nil
+}
+
+func (l *ociLayoutLayer) DiffID() (v1.Hash, error) {
+	if l.diffID != (v1.Hash{}) {
+		return l.diffID, nil
+	}
+	r, err := l.Uncompressed()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	defer r.Close()
+	h, _, err := v1.SHA256(r)
+	return h, err
+}
+
+func (l *ociLayoutLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Uncompressed reads the layer, gunzipping it when it is gzipped and
+// decompressing it when it is zstd compressed, as layouts may hold
+// uncompressed tar layers.
+func (l *ociLayoutLayer) Uncompressed() (io.ReadCloser, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	if magic, err := r.Peek(4); err == nil && bytes.Equal(magic, zstdMagic) {
+		return readCloser{zstd.NewReader(r), f}, nil
+	}
+	if magic, err := r.Peek(2); err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return readCloser{r, f}, nil
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{gz, f}, nil
+}
+
+func (l *ociLayoutLayer) Size() (int64, error) {
+	return l.desc.Size, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// requestedPlatform is the platform picked from manifest lists, if set. It
+// defaults to the platform of the local Docker daemon, so that Apple Silicon
+// hosts analyze the arm64 images they run natively.
+var requestedPlatform string
+
+// daemonPlatform caches the platform of the local Docker daemon; it is empty
+// if no daemon answers.
+var daemonPlatform struct {
+	once     sync.Once
+	platform string
+}
+
+// PlatformImage is one image of a multi-platform manifest list, with the
+// reference it can be pulled by.
+type PlatformImage struct {
+	Platform  string
+	Digest    string
+	Reference string
+}
+
+var manifestAccept = strings.Join([]string{
+	string(types.DockerManifestList),
+	string(types.OCIImageIndex),
+	string(types.DockerManifestSchema2),
+	string(types.OCIManifestSchema1),
+}, ",")
+
+// GetPlatformImages reads the manifest list or OCI index imageName refers to,
+// in a registry or an OCI image layout, keying its images by PlatformKey. It
+// fails if imageName is a single image.
+func GetPlatformImages(imageName string) (map[string]PlatformImage, error) {
+	if strings.HasPrefix(imageName, ociLayoutPrefix) {
+		return getOCILayoutPlatformImages(imageName)
+	}
+	client, ref, base, err := newRegistryClient(imageName)
+	if err != nil {
+		return nil, err
+	}
+	var index v1.IndexManifest
+	if err := getRegistryJSON(client, base+"/manifests/"+ref.Identifier(), manifestAccept, &index); err != nil {
+		return nil, errors.Wrapf(err, "fetching manifest of %s", imageName)
+	}
+	if len(index.Manifests) == 0 {
+		return nil, fmt.Errorf("%s is a single image, not a manifest list", imageName)
+	}
+	return indexPlatformImages(index, ref.Context().String()), nil
+}
+
+// indexPlatformImages keys the images of an index by PlatformKey, each
+// referenced by its digest in repository.
+func indexPlatformImages(index v1.IndexManifest, repository string) map[string]PlatformImage {
+	images := map[string]PlatformImage{}
+	for _, desc := range index.Manifests {
+		if desc.Platform == nil {
+			continue
+		}
+		key := PlatformKey(*desc.Platform)
+		// attestation manifests are listed with an unknown platform
+		if key == "unknown/unknown" {
+			continue
+		}
+		images[key] = PlatformImage{
+			Platform:  key,
+			Digest:    desc.Digest.String(),
+			Reference: repository + "@" + desc.Digest.String(),
+		}
+	}
+	return images
+}
+
+// PlatformKey names a platform as os/architecture[/variant]. Windows images
+// only run on hosts of the same build, so the build, e.g. 10.0.17763, is
+// part of their key while the patch revision is not.
+func PlatformKey(p v1.Platform) string {
+	key := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		key += "/" + p.Variant
+	}
+	if p.OS == "windows" && p.OSVersion != "" {
+		parts := strings.Split(p.OSVersion, ".")
+		if len(parts) > 3 {
+			parts = parts[:3]
+		}
+		key += ":" + strings.Join(parts, ".")
+	}
+	return key
+}
+
+// ConfigurePlatform sets the os/architecture[/variant] to pick from
+// manifest lists, e.g. linux/arm64. The local Docker daemon is asked for its
+// platform again afterwards.
+func ConfigurePlatform(platform string) error {
+	if platform != "" && len(strings.Split(platform, "/")) < 2 {
+		return fmt.Errorf("invalid platform %s, expected os/architecture[/variant], e.g. linux/arm64", platform)
+	}
+	requestedPlatform = platform
+	daemonPlatform.once, daemonPlatform.platform = sync.Once{}, ""
+	return nil
+}
+
+// DefaultPlatform is the platform picked from manifest lists: the requested
+// one, else that of the local Docker daemon, else linux on the host's
+// architecture.
+func DefaultPlatform() string {
+	if requestedPlatform != "" {
+		return requestedPlatform
+	}
+	if platform := getDaemonPlatform(); platform != "" {
+		return platform
+	}
+	return "linux/" + runtime.GOARCH
+}
+
+// getDaemonPlatform asks the local Docker daemon for its platform. Docker
+// Desktop reports the architecture of its Linux VM, e.g. aarch64 on M-series
+// Macs.
+func getDaemonPlatform() string {
+	daemonPlatform.once.Do(func() {
+		opt := client.FromEnv
+		if os.Getenv("DOCKER_HOST") == "" {
+			host, ok := dockerSocketHost()
+			if !ok {
+				return
+			}
+			opt = client.WithHost(host)
+		}
+		cli, err := client.NewClientWithOpts(opt)
+		if err != nil {
+			return
+		}
+		defer cli.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		cli.NegotiateAPIVersion(ctx)
+		info, err := cli.Info(ctx)
+		if err != nil || info.OSType == "" {
+			logrus.Debugf("could not read the Docker daemon platform: %v", err)
+			return
+		}
+		daemonPlatform.platform = info.OSType + "/" + normalizeArch(info.Architecture)
+	})
+	return daemonPlatform.platform
+}
+
+// normalizeArch maps machine names, as reported by uname, to the
+// architectures images are published for.
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	case "armv7l", "armhf":
+		return "arm/v7"
+	case "armv6l":
+		return "arm/v6"
+	case "i386", "i686":
+		return "386"
+	}
+	return arch
+}
+
+// matchPlatform picks the image of a manifest list for platform. A platform
+// without a variant matches any variant, and Windows images match any build.
+func matchPlatform(images map[string]PlatformImage, platform string) (PlatformImage, bool) {
+	if image, ok := images[platform]; ok {
+		return image, true
+	}
+	keys := []string{}
+	for key := range images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		base := strings.SplitN(key, ":", 2)[0]
+		if base == platform || strings.HasPrefix(base, platform+"/") {
+			return images[key], true
+		}
+	}
+	return PlatformImage{}, false
+}
+
+// resolvePlatformReference returns the reference of the image for the
+// default platform when imageName is a manifest list, or imageName itself
+// when it is a single image.
+func resolvePlatformReference(imageName string) (string, error) {
+	images, err := GetPlatformImages(imageName)
+	if err != nil {
+		logrus.Debugf("not resolving a platform for %s: %s", imageName, err)
+		return imageName, nil
+	}
+	platform := DefaultPlatform()
+	image, ok := matchPlatform(images, platform)
+	if !ok {
+		available := []string{}
+		for key := range images {
+			available = append(available, key)
+		}
+		sort.Strings(available)
+		return "", fmt.Errorf("%s has no %s image, set --platform to one of: %s", imageName, platform, strings.Join(available, ", "))
+	}
+	logrus.Infof("using the %s image of %s: %s", image.Platform, imageName, image.Digest)
+	return image.Reference, nil
+}
+
+// warnForeignPlatform warns when an image is built for another architecture
+// than the local daemon's, as happens with amd64 images on Apple Silicon.
+// Containers of such images run under emulation, which the analysis doesn't
+// account for.
+func warnForeignPlatform(img v1.Image, imageName string) {
+	daemon := getDaemonPlatform()
+	if daemon == "" {
+		return
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil || cfg.Architecture == "" {
+		return
+	}
+	platform := cfg.OS + "/" + cfg.Architecture
+	if requestedPlatform != "" && strings.HasPrefix(requestedPlatform, platform) {
+		return
+	}
+	if !strings.HasPrefix(daemon+"/", platform+"/") {
+		logrus.Warnf("%s is a %s image, but the local Docker daemon runs %s; its containers run under emulation there", imageName, platform, daemon)
+	}
+}
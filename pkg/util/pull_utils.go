@@ -0,0 +1,219 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Pull policies of daemon images, as selected with ConfigurePullPolicy.
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+// Origins of daemon images: already in the daemon, or pulled by this run.
+const (
+	OriginLocal  = "local"
+	OriginPulled = "pulled"
+)
+
+var pullPolicy = PullNever
+
+// daemonPulls records the origin of each daemon image, so that an image
+// named several times in a run, e.g. to check it is identical to the other
+// one before diffing, is pulled once.
+var daemonPulls = struct {
+	sync.Mutex
+	pulls map[string]*daemonPull
+}{pulls: map[string]*daemonPull{}}
+
+type daemonPull struct {
+	once   sync.Once
+	origin string
+	err    error
+}
+
+// ConfigurePullPolicy selects whether daemon:// images are pulled from
+// their registry before being read from the daemon: always, only when the
+// daemon doesn't have them (if-not-present), or never.
+func ConfigurePullPolicy(policy string) error {
+	switch policy {
+	case "":
+		pullPolicy = PullNever
+	case PullAlways, PullIfNotPresent, PullNever:
+		pullPolicy = policy
+	default:
+		return fmt.Errorf("invalid pull policy %s, expected %s, %s or %s", policy, PullAlways, PullIfNotPresent, PullNever)
+	}
+	return nil
+}
+
+// daemonImageOrigin returns the origin of the daemon image imageName, if it
+// was read from the daemon.
+func daemonImageOrigin(imageName string) string {
+	daemonPulls.Lock()
+	defer daemonPulls.Unlock()
+	if pull, ok := daemonPulls.pulls[imageName]; ok {
+		return pull.origin
+	}
+	return ""
+}
+
+// prepareDaemonImage pulls the daemon image imageName as the pull policy
+// requires, unless it already was, and records its origin.
+func prepareDaemonImage(imageName string, ref name.Reference) error {
+	daemonPulls.Lock()
+	pull, ok := daemonPulls.pulls[imageName]
+	if !ok {
+		pull = &daemonPull{}
+		daemonPulls.pulls[imageName] = pull
+	}
+	daemonPulls.Unlock()
+	pull.once.Do(func() {
+		pull.origin, pull.err = pullForPolicy(imageName, ref)
+		if pull.err == nil {
+			logrus.Infof("reading %s image %s from the daemon", pull.origin, imageName)
+		}
+	})
+	return pull.err
+}
+
+// pullForPolicy pulls ref to the daemon if the pull policy requires it, and
+// returns the origin of the image the daemon then has.
+func pullForPolicy(imageName string, ref name.Reference) (string, error) {
+	if pullPolicy == PullNever {
+		return OriginLocal, nil
+	}
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return "", errors.Wrap(err, "connecting to the Docker daemon")
+	}
+	defer cli.Close()
+	ctx := context.Background()
+	cli.NegotiateAPIVersion(ctx)
+	if pullPolicy == PullIfNotPresent {
+		_, _, err := cli.ImageInspectWithRaw(ctx, ref.Name())
+		if err == nil {
+			return OriginLocal, nil
+		}
+		if !client.IsErrNotFound(err) {
+			return "", errors.Wrapf(err, "inspecting %s in the daemon", imageName)
+		}
+	}
+	if err := pullToDaemon(ctx, cli, ref); err != nil {
+		return "", errors.Wrapf(err, "pulling %s", imageName)
+	}
+	return OriginPulled, nil
+}
+
+// pullToDaemon has the daemon pull ref, with the credentials of the
+// default keychain, for the requested platform if any.
+func pullToDaemon(ctx context.Context, cli *client.Client, ref name.Reference) error {
+	options := types.ImagePullOptions{Platform: requestedPlatform}
+	auth, err := registryAuth(ref)
+	if err != nil {
+		return err
+	}
+	options.RegistryAuth = auth
+	logrus.Infof("pulling %s to the daemon", ref.Name())
+	rc, err := cli.ImagePull(ctx, ref.Name(), options)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// the pull only completes once its progress is read, and failures are
+	// reported in it
+	decoder := json.NewDecoder(rc)
+	for {
+		var message struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+		logrus.Debugf("pull %s: %s", ref.Name(), message.Status)
+	}
+}
+
+// identityTokenUsername is the username Docker credential helpers return
+// along with an identity token, which is exchanged for registry tokens,
+// rather than a password.
+const identityTokenUsername = "<token>"
+
+// registryAuth encodes the credentials the default keychain has for the
+// registry of ref as the daemon expects them: basic credentials, identity
+// tokens or registry tokens. Anonymous pulls send none, and credentials
+// the daemon can't be given fail the pull rather than pulling anonymously.
+func registryAuth(ref name.Reference) (string, error) {
+	keychainAuth, err := resolveAuth(ref.Context().Registry)
+	if err != nil {
+		return "", err
+	}
+	header, err := keychainAuth.Authorization()
+	if err != nil {
+		return "", errors.Wrapf(err, "getting the credentials for %s", ref.Context().RegistryStr())
+	}
+	config := types.AuthConfig{ServerAddress: ref.Context().RegistryStr()}
+	switch {
+	case header == "":
+		return "", nil
+	case strings.HasPrefix(header, "Bearer "):
+		config.RegistryToken = strings.TrimPrefix(header, "Bearer ")
+	case strings.HasPrefix(header, "Basic "):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err != nil {
+			return "", errors.Wrapf(err, "decoding the credentials for %s", config.ServerAddress)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return "", fmt.Errorf("the credentials for %s have no password", config.ServerAddress)
+		}
+		if parts[0] == identityTokenUsername {
+			config.IdentityToken = parts[1]
+		} else {
+			config.Username, config.Password = parts[0], parts[1]
+		}
+	default:
+		scheme := strings.SplitN(header, " ", 2)[0]
+		return "", fmt.Errorf("the %s credentials for %s can't be passed to the daemon", scheme, config.ServerAddress)
+	}
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
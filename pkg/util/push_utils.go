@@ -0,0 +1,287 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ociPrefix            = "oci://"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+
+	// ResultArtifactType is the artifact type results are pushed under.
+	ResultArtifactType = "application/vnd.container-diff.result.v1+json"
+)
+
+// manifestMediaTypes are accepted when looking up the image results refer to
+var manifestMediaTypes = []string{
+	ociManifestMediaType,
+	ociIndexMediaType,
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// ArtifactFile is a file pushed as a layer of an artifact.
+type ArtifactFile struct {
+	Name      string
+	MediaType string
+	Data      []byte
+}
+
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        *ociDescriptor    `json:"config,omitempty"`
+	Layers        []ociDescriptor   `json:"layers,omitempty"`
+	Manifests     []ociDescriptor   `json:"manifests,omitempty"`
+	Subject       *ociDescriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// PushResultArtifact pushes files, such as analysis results, as an OCI
+// artifact to target, given as oci://registry/repository[:tag]. Without a tag
+// the artifact is pushed by digest only. When subject is a registry image
+// found in the target's registry, the artifact refers to it, so that the OCI
+// referrers API lists it with the image. The digest of the artifact manifest
+// is returned.
+func PushResultArtifact(target string, subject Image, files []ArtifactFile, annotations map[string]string) (string, error) {
+	if !strings.HasPrefix(target, ociPrefix) {
+		return "", fmt.Errorf("%s is not an OCI artifact reference, expected oci://registry/repository[:tag]", target)
+	}
+	reference := strings.TrimPrefix(target, ociPrefix)
+	if strings.Contains(reference, "@") {
+		return "", fmt.Errorf("%s can't be pushed to by digest, give a tag or no version", target)
+	}
+	tag := ""
+	if HasTag(reference) {
+		tag = reference[strings.LastIndex(reference, ":")+1:]
+		reference = RemoveTag(reference)
+	}
+	repo, err := name.NewRepository(reference, name.WeakValidation)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing artifact reference")
+	}
+	client, base, err := newRepositoryClient(repo, transport.PushScope)
+	if err != nil {
+		return "", err
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  ResultArtifactType,
+		Annotations:   map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)},
+	}
+	for key, value := range annotations {
+		manifest.Annotations[key] = value
+	}
+	config := ArtifactFile{MediaType: ociEmptyMediaType, Data: []byte("{}")}
+	manifest.Config = descriptorFor(config)
+	if err := pushBlob(client, base, config.Data); err != nil {
+		return "", errors.Wrap(err, "pushing artifact config")
+	}
+	for _, file := range files {
+		if err := pushBlob(client, base, file.Data); err != nil {
+			return "", errors.Wrapf(err, "pushing %s", file.Name)
+		}
+		manifest.Layers = append(manifest.Layers, *descriptorFor(file))
+	}
+	manifest.Subject = subjectDescriptor(subject, repo)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256Digest(data)
+	version := digest
+	if tag != "" {
+		version = tag
+	}
+	resp, err := putRegistryDocument(client, base+"/manifests/"+version, ociManifestMediaType, data)
+	if err != nil {
+		return "", errors.Wrap(err, "pushing artifact manifest")
+	}
+	// registries without the referrers API need the referrers tag updated
+	if manifest.Subject != nil && resp.Header.Get("OCI-Subject") == "" {
+		artifact := ociDescriptor{
+			MediaType:    ociManifestMediaType,
+			ArtifactType: ResultArtifactType,
+			Digest:       digest,
+			Size:         int64(len(data)),
+			Annotations:  manifest.Annotations,
+		}
+		if err := addToReferrersTag(client, base, manifest.Subject.Digest, artifact); err != nil {
+			return digest, errors.Wrap(err, "updating referrers tag")
+		}
+	}
+	return digest, nil
+}
+
+// subjectDescriptor looks the manifest of an analyzed image up in the
+// registry, as images read from local runtimes may have been rewritten. It
+// returns nil when the image has no manifest there.
+func subjectDescriptor(image Image, repo name.Repository) *ociDescriptor {
+	if image.Image == nil {
+		logrus.Warnf("not attaching results to %s: image is unknown", image.Source)
+		return nil
+	}
+	client, ref, base, err := newRegistryClient(image.Source)
+	if err != nil {
+		logrus.Warnf("not attaching results to %s: %s", image.Source, err)
+		return nil
+	}
+	if ref.Context().String() != repo.String() {
+		logrus.Warnf("results pushed to %s, rather than the repository of %s, are not listed with its referrers", repo, image.Source)
+	}
+	digest, err := image.Image.Digest()
+	if err != nil {
+		logrus.Warnf("not attaching results to %s: %s", image.Source, err)
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodHead, base+"/manifests/"+digest.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ","))
+	resp, err := client.Do(req)
+	if err != nil {
+		logrus.Warnf("not attaching results to %s: %s", image.Source, err)
+		return nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength <= 0 {
+		logrus.Warnf("not attaching results to %s: manifest %s is not in the registry", image.Source, digest)
+		return nil
+	}
+	return &ociDescriptor{MediaType: resp.Header.Get("Content-Type"), Digest: digest.String(), Size: resp.ContentLength}
+}
+
+// addToReferrersTag adds an artifact to the index tagged after its subject,
+// as the OCI distribution spec's referrers tag schema does for registries
+// without the referrers API.
+func addToReferrersTag(client *http.Client, base, subject string, artifact ociDescriptor) error {
+	tag := strings.Replace(subject, ":", "-", 1)
+	index := ociManifest{SchemaVersion: 2, MediaType: ociIndexMediaType}
+	if err := getRegistryJSON(client, base+"/manifests/"+tag, ociIndexMediaType, &index); err != nil {
+		if terr, ok := err.(*transport.Error); !ok || !isNotFound(terr) {
+			return err
+		}
+	}
+	for _, m := range index.Manifests {
+		if m.Digest == artifact.Digest {
+			return nil
+		}
+	}
+	index.Manifests = append(index.Manifests, artifact)
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	_, err = putRegistryDocument(client, base+"/manifests/"+tag, ociIndexMediaType, data)
+	return err
+}
+
+func isNotFound(err *transport.Error) bool {
+	for _, e := range err.Errors {
+		if e.Code == transport.ManifestUnknownErrorCode || e.Code == transport.NameUnknownErrorCode {
+			return true
+		}
+	}
+	return false
+}
+
+// pushBlob uploads a blob in a single request, unless the repository
+// already has it.
+func pushBlob(client *http.Client, base string, data []byte) error {
+	digest := sha256Digest(data)
+	if resp, err := client.Head(base + "/blobs/" + digest); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+	}
+	resp, err := client.Post(base+"/blobs/uploads/", "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusAccepted); err != nil {
+		return err
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return errors.Wrap(err, "parsing upload location")
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	_, err = putRegistryDocument(client, location.String(), "application/octet-stream", data)
+	return err
+}
+
+func putRegistryDocument(client *http.Client, url, contentType string, data []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, transport.CheckError(resp, http.StatusCreated)
+}
+
+func descriptorFor(file ArtifactFile) *ociDescriptor {
+	d := &ociDescriptor{MediaType: file.MediaType, Digest: sha256Digest(file.Data), Size: int64(len(file.Data))}
+	if file.Name != "" {
+		d.Annotations = map[string]string{"org.opencontainers.image.title": file.Name}
+	}
+	return d
+}
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
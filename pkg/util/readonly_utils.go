@@ -0,0 +1,66 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ownerWrite is the only permission bit changed on extracted filesystems.
+// The others are kept as in the image, as analyzers check them, e.g. for
+// world-writable files.
+const ownerWrite = 0200
+
+// makeReadOnly clears the owner write bit of every file and directory below
+// root, as a best-effort guard against analyzers, which may run concurrently
+// on the same extracted filesystem, changing it by mistake. It doesn't
+// apply to processes running as root, whom file modes don't restrict.
+// Symlinks are skipped, as changing their mode would change their targets,
+// possibly outside root.
+func makeReadOnly(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 || info.Mode()&ownerWrite == 0 {
+			return nil
+		}
+		return os.Chmod(path, chmodMode(info.Mode())&^ownerWrite)
+	})
+}
+
+// makeWritable sets the owner write bit of the directories below root
+// again, which removing them requires.
+func makeWritable(root string) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || info.Mode()&ownerWrite != 0 {
+			return nil
+		}
+		if err := os.Chmod(path, chmodMode(info.Mode())|ownerWrite); err != nil {
+			logrus.Debugf("could not make %s writable: %s", path, err)
+		}
+		return nil
+	})
+}
+
+// chmodMode keeps the bits of a mode os.Chmod accepts.
+func chmodMode(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type referrerDescriptor struct {
+	MediaType    string `json:"mediaType"`
+	Digest       string `json:"digest"`
+	ArtifactType string `json:"artifactType"`
+}
+
+type referrerManifest struct {
+	Layers    []referrerDescriptor `json:"layers"`
+	Manifests []referrerDescriptor `json:"manifests"`
+}
+
+// isAttestationType matches the artifact types in-toto attestations are
+// stored under, bare or in DSSE envelopes.
+func isAttestationType(artifactType string) bool {
+	return strings.Contains(artifactType, "in-toto") || strings.Contains(artifactType, "dsse")
+}
+
+// GetReferrerAttestations fetches the attestations attached to a registry
+// image with the OCI referrers API. Images read from tarballs, the Docker
+// daemon or over SSH have no registry to ask.
+func GetReferrerAttestations(imageName string, digest v1.Hash) ([][]byte, error) {
+	client, _, base, err := newRegistryClient(imageName)
+	if err != nil {
+		return nil, err
+	}
+	var index referrerManifest
+	if err := getRegistryJSON(client, base+"/referrers/"+digest.String(), "application/vnd.oci.image.index.v1+json", &index); err != nil {
+		return nil, errors.Wrap(err, "listing referrers")
+	}
+	attestations := [][]byte{}
+	for _, referrer := range index.Manifests {
+		if !isAttestationType(referrer.ArtifactType) {
+			continue
+		}
+		var manifest referrerManifest
+		if err := getRegistryJSON(client, base+"/manifests/"+referrer.Digest, referrer.MediaType, &manifest); err != nil {
+			return nil, errors.Wrapf(err, "fetching referrer %s", referrer.Digest)
+		}
+		for _, layer := range manifest.Layers {
+			data, err := getRegistryBlob(client, base+"/blobs/"+layer.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fetching attestation %s", layer.Digest)
+			}
+			attestations = append(attestations, data)
+		}
+	}
+	logrus.Infof("found %d attestation(s) referring to %s", len(attestations), digest)
+	return attestations, nil
+}
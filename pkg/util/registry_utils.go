@@ -0,0 +1,116 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+// maxRegistryDocumentSize bounds the manifests and blobs read into memory
+const maxRegistryDocumentSize = 16 << 20
+
+// newRegistryClient authenticates to the registry of imageName for pulls,
+// for registry API calls the remote package doesn't cover. It returns the
+// parsed reference and the base URL of the repository's API.
+func newRegistryClient(imageName string) (*http.Client, name.Reference, string, error) {
+	if _, _, ok := registeredImageSource(imageName); ok {
+		return nil, nil, "", fmt.Errorf("%s is not a registry image", imageName)
+	}
+	if isTarballSource(imageName) || strings.HasPrefix(imageName, daemonPrefix) || strings.HasPrefix(imageName, sshPrefix) || strings.HasPrefix(imageName, ociLayoutPrefix) || strings.HasPrefix(imageName, containerdPrefix) || strings.HasPrefix(imageName, podmanPrefix) || strings.HasPrefix(imageName, containersStoragePrefix) {
+		return nil, nil, "", fmt.Errorf("%s is not a registry image", imageName)
+	}
+	ref, err := parseRemoteReference(strings.TrimPrefix(imageName, remotePrefix))
+	if err != nil {
+		return nil, nil, "", errors.Wrap(err, "parsing image reference")
+	}
+	client, base, err := newRepositoryClient(ref.Context(), transport.PullScope)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return client, ref, base, nil
+}
+
+// newRepositoryClient authenticates to a repository with the given scope, and
+// returns the base URL of its API.
+func newRepositoryClient(repo name.Repository, scope string) (*http.Client, string, error) {
+	auth, err := resolveAuth(repo.Registry)
+	if err != nil {
+		return nil, "", err
+	}
+	tr, err := transport.New(repo.Registry, auth, BuildTransport(repo.Registry), []string{repo.Scope(scope)})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "creating registry transport")
+	}
+	base := fmt.Sprintf("%s://%s/v2/%s", repo.Registry.Scheme(), repo.RegistryStr(), repo.RepositoryStr())
+	return &http.Client{Transport: tr}, base, nil
+}
+
+func getRegistryJSON(client *http.Client, url, accept string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return err
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxRegistryDocumentSize)).Decode(v)
+}
+
+func getRegistryBlob(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxRegistryDocumentSize))
+}
+
+// getRegistryManifest fetches a manifest or manifest list as it is stored.
+func getRegistryManifest(client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxRegistryDocumentSize))
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Local container runtimes which can provide images given without a prefix.
+const (
+	DockerRuntime     = "docker"
+	PodmanRuntime     = "podman"
+	ContainerdRuntime = "containerd"
+)
+
+var defaultRuntimes = []string{DockerRuntime, PodmanRuntime, ContainerdRuntime}
+
+// runtimes is the order in which local runtimes are probed before falling
+// back to the registry.
+var runtimes = defaultRuntimes
+
+const (
+	dockerSocket     = "/var/run/docker.sock"
+	podmanSocket     = "/run/podman/podman.sock"
+	containerdSocket = "/run/containerd/containerd.sock"
+	// Docker Desktop for Mac serves its API here when it isn't allowed to
+	// link /var/run/docker.sock
+	dockerDesktopSocket = ".docker/run/docker.sock"
+)
+
+// containerdNamespace is the containerd namespace images are exported from.
+// When empty, ctr reads CONTAINERD_NAMESPACE, or uses its default namespace.
+var containerdNamespace string
+
+// ConfigureContainerdNamespace sets the containerd namespace images are
+// looked up in, e.g. k8s.io for the images pulled by the kubelet.
+func ConfigureContainerdNamespace(namespace string) {
+	containerdNamespace = namespace
+}
+
+// ConfigureRuntimes sets the order in which local runtimes are probed for
+// images given without a prefix. The preferred runtimes are probed first, in
+// the order given, followed by the others in their default order.
+func ConfigureRuntimes(preferred []string) error {
+	order := []string{}
+	seen := map[string]bool{}
+	for _, r := range preferred {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if !isRuntime(r) {
+			return fmt.Errorf("unknown container runtime %q, supported runtimes: %s", r, strings.Join(defaultRuntimes, ", "))
+		}
+		if !seen[r] {
+			seen[r] = true
+			order = append(order, r)
+		}
+	}
+	for _, r := range defaultRuntimes {
+		if !seen[r] {
+			order = append(order, r)
+		}
+	}
+	runtimes = order
+	return nil
+}
+
+func isRuntime(r string) bool {
+	for _, known := range defaultRuntimes {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// getLocalImage looks for an image in the available local runtimes, in the
+// configured order. Like getSSHImage, it returns a function removing the
+// local copy the image is read from. An error means no runtime had the image.
+func getLocalImage(imageName string) (v1.Image, func(), error) {
+	ref, err := name.ParseReference(imageName, name.WeakValidation)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing image reference")
+	}
+	for _, r := range runtimes {
+		export, ok := runtimeExporter(r)
+		if !ok {
+			logrus.Debugf("container runtime %s not available", r)
+			continue
+		}
+		start := time.Now()
+		img, cleanup, err := exportImage(export, imageName, ref)
+		if err != nil {
+			logrus.Infof("image %s not found in %s: %s", imageName, r, err)
+			continue
+		}
+		elapsed := time.Now().Sub(start)
+		logrus.Infof("retrieving image ref from %s took %f seconds", r, elapsed.Seconds())
+		return img, cleanup, nil
+	}
+	return nil, nil, fmt.Errorf("image %s not found in local runtimes", imageName)
+}
+
+// exporter writes a `docker save` archive of an image to a file. Runtimes
+// serving the Docker API resolve imageName themselves, so short names and
+// image IDs work as they do with their own CLI.
+type exporter func(imageName string, ref name.Reference, path string) error
+
+// runtimeExporter returns the exporter of a runtime if it is reachable.
+func runtimeExporter(runtime string) (exporter, bool) {
+	switch runtime {
+	case DockerRuntime:
+		if os.Getenv("DOCKER_HOST") != "" {
+			return dockerAPIExporter(client.FromEnv), true
+		}
+		if host, ok := dockerSocketHost(); ok {
+			return dockerAPIExporter(client.WithHost(host)), true
+		}
+	case PodmanRuntime:
+		// podman serves the Docker API on its socket
+		if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+			return dockerAPIExporter(client.WithHost(host)), true
+		}
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && socketExists(filepath.Join(dir, "podman", "podman.sock")) {
+			return dockerAPIExporter(client.WithHost("unix://" + filepath.Join(dir, "podman", "podman.sock"))), true
+		}
+		if socketExists(podmanSocket) {
+			return dockerAPIExporter(client.WithHost("unix://" + podmanSocket)), true
+		}
+	case ContainerdRuntime:
+		address := containerdAddress()
+		if !socketExists(address) {
+			return nil, false
+		}
+		if _, err := exec.LookPath("ctr"); err != nil {
+			logrus.Infof("skipping containerd at %s: %s", address, errCtrMissing)
+			return nil, false
+		}
+		return ctrExporter(address), true
+	}
+	return nil, false
+}
+
+func containerdAddress() string {
+	if address := os.Getenv("CONTAINERD_ADDRESS"); address != "" {
+		return address
+	}
+	return containerdSocket
+}
+
+// getContainerdImage exports a containerd:// image with ctr. Unlike images
+// given without a prefix, it is never looked up in other runtimes or pulled
+// from the registry.
+func getContainerdImage(imageName string) (v1.Image, func(), error) {
+	ref, err := name.ParseReference(imageName, name.WeakValidation)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing image reference")
+	}
+	export, ok := runtimeExporter(ContainerdRuntime)
+	if !ok {
+		if socketExists(containerdAddress()) {
+			return nil, nil, fmt.Errorf("containerd is listening on %s, but %s", containerdAddress(), errCtrMissing)
+		}
+		return nil, nil, fmt.Errorf("containerd is not available: ctr must be on the PATH and containerd listening on %s", containerdAddress())
+	}
+	start := time.Now()
+	img, cleanup, err := exportImage(export, imageName, ref)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "exporting %s from containerd", containerdName(ref))
+	}
+	elapsed := time.Now().Sub(start)
+	logrus.Infof("retrieving image ref from containerd took %f seconds", elapsed.Seconds())
+	return img, cleanup, nil
+}
+
+// dockerSocketHost returns the address of the local Docker socket, at its
+// usual path or where Docker Desktop puts it.
+func dockerSocketHost() (string, bool) {
+	if socketExists(dockerSocket) {
+		return "unix://" + dockerSocket, true
+	}
+	if home, err := os.UserHomeDir(); err == nil && socketExists(filepath.Join(home, dockerDesktopSocket)) {
+		return "unix://" + filepath.Join(home, dockerDesktopSocket), true
+	}
+	return "", false
+}
+
+func socketExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// splitContainersStorageSource splits a podman:// or containers-storage:
+// source into its storage spec and image name. As in skopeo, the spec is an
+// optional [driver@graphroot+runroot:options] before the name, selecting
+// another store than the default one of the user.
+func splitContainersStorageSource(source string) (string, string) {
+	source = strings.TrimPrefix(strings.TrimPrefix(source, podmanPrefix), containersStoragePrefix)
+	if i := strings.Index(source, "]"); strings.HasPrefix(source, "[") && i > 0 {
+		return source[1:i], source[i+1:]
+	}
+	return "", source
+}
+
+// containersStorageFlags are the global podman and buildah flags selecting
+// the store a spec names.
+func containersStorageFlags(spec string) []string {
+	flags := []string{}
+	if spec == "" {
+		return flags
+	}
+	if i := strings.Index(spec, ":"); i >= 0 {
+		for _, opt := range strings.Split(spec[i+1:], ",") {
+			flags = append(flags, "--storage-opt", opt)
+		}
+		spec = spec[:i]
+	}
+	if i := strings.Index(spec, "@"); i >= 0 {
+		flags = append(flags, "--storage-driver", spec[:i])
+		spec = spec[i+1:]
+	}
+	if i := strings.Index(spec, "+"); i >= 0 {
+		flags = append(flags, "--runroot", spec[i+1:])
+		spec = spec[:i]
+	}
+	if spec != "" {
+		flags = append(flags, "--root", spec)
+	}
+	return flags
+}
+
+// getContainersStorageImage exports an image from the containers-storage
+// store podman and buildah share, without the podman API service rootless
+// users rarely run. podman is used if installed, else buildah. Either one
+// resolves the name, so locally built images are found as myapp:latest
+// rather than localhost/myapp:latest.
+func getContainersStorageImage(spec, imageName string) (v1.Image, func(), error) {
+	if imageName == "" {
+		return nil, nil, errors.New("missing image name in containers-storage source")
+	}
+	flags := containersStorageFlags(spec)
+	var export exporter
+	if _, err := exec.LookPath("podman"); err == nil {
+		export = cliExporter("podman", func(path string) []string {
+			return append(flags, "image", "save", "--format", "docker-archive", "--output", path, imageName)
+		})
+	} else if _, err := exec.LookPath("buildah"); err == nil {
+		export = cliExporter("buildah", func(path string) []string {
+			return append(flags, "push", imageName, "docker-archive:"+path)
+		})
+	} else {
+		return nil, nil, errors.New("reading containers-storage images needs podman or buildah on the PATH")
+	}
+	start := time.Now()
+	img, cleanup, err := exportImage(export, imageName, nil)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "exporting %s from containers-storage", imageName)
+	}
+	elapsed := time.Now().Sub(start)
+	logrus.Infof("retrieving image ref from containers-storage took %f seconds", elapsed.Seconds())
+	return img, cleanup, nil
+}
+
+// cliExporter exports images by running a CLI with the arguments args
+// returns for the path to write to.
+func cliExporter(command string, args func(path string) []string) exporter {
+	return func(_ string, _ name.Reference, path string) error {
+		var stderr bytes.Buffer
+		cmd := exec.Command(command, args(path)...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+}
+
+// exportImage exports an image to a temp file and reads it back.
+func exportImage(export exporter, imageName string, ref name.Reference) (v1.Image, func(), error) {
+	f, err := ioutil.TempFile("", "container-diff-runtime-*.tar")
+	if err != nil {
+		return nil, nil, err
+	}
+	f.Close()
+	cleanup := func() {
+		if err := os.Remove(f.Name()); err != nil {
+			logrus.Warn(err.Error())
+		}
+	}
+	if err := export(imageName, ref, f.Name()); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	img, err := tarball.ImageFromPath(f.Name(), nil)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return img, cleanup, nil
+}
+
+// dockerAPIExporter saves images through the Docker API of the daemon the
+// client options point at.
+func dockerAPIExporter(ops ...func(*client.Client) error) exporter {
+	return func(imageName string, _ name.Reference, path string) error {
+		cli, err := client.NewClientWithOpts(ops...)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+		ctx := context.Background()
+		cli.NegotiateAPIVersion(ctx)
+		rc, err := cli.ImageSave(ctx, []string{imageName})
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(f, rc)
+		return err
+	}
+}
+
+// errCtrMissing explains why containerd can't be read from without ctr:
+// the containerd Go client isn't a dependency, so images are exported with
+// the containerd CLI.
+var errCtrMissing = errors.New("ctr, which images are exported with, is not on the PATH")
+
+// ctrExporter exports images with the containerd CLI, in the configured
+// namespace or the one set by CONTAINERD_NAMESPACE. Only the requested
+// platform is exported from multi-platform images, by default that of the
+// host.
+func ctrExporter(address string) exporter {
+	return func(_ string, ref name.Reference, path string) error {
+		args := []string{"--address", address}
+		if containerdNamespace != "" {
+			args = append(args, "--namespace", containerdNamespace)
+		}
+		args = append(args, "images", "export")
+		if requestedPlatform != "" {
+			args = append(args, "--platform", requestedPlatform)
+		}
+		var stderr bytes.Buffer
+		cmd := exec.Command("ctr", append(args, path, containerdName(ref))...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+}
+
+// containerdName spells out a reference the way containerd stores it, fully
+// qualified and with Docker Hub as docker.io.
+func containerdName(ref name.Reference) string {
+	registry := ref.Context().RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = "docker.io"
+	}
+	repo := registry + "/" + ref.Context().RepositoryStr()
+	if digest, ok := ref.(name.Digest); ok {
+		return repo + "@" + digest.DigestStr()
+	}
+	return repo + ":" + ref.Identifier()
+}
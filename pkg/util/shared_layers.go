@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// extractedLayer is a layer extracted to a temp dir, shared by the images
+// retrieved in this process which have it.
+type extractedLayer struct {
+	once sync.Once
+	path string
+	err  error
+	refs int
+}
+
+// extractedLayers tracks the layers extracted without a cache by digest, so
+// that a layer two images share, such as their base, is only downloaded and
+// extracted once.
+var extractedLayers = struct {
+	sync.Mutex
+	layers map[v1.Hash]*extractedLayer
+}{layers: map[v1.Hash]*extractedLayer{}}
+
+// extractLayer returns the directory layer is extracted to, and whether it
+// was reused from the cache. A nil layer, which can't be extracted, gets an
+// empty directory so that layer indexes still line up. Layers of an image
+// cached in cacheDir are cached in their own directories next to it.
+func extractLayer(digest v1.Hash, layer v1.Layer, cacheDir string) (string, bool, error) {
+	size := extractedSize([]v1.Layer{layer})
+	extract := func(path string) error {
+		if layer == nil {
+			return nil
+		}
+		return GetFileSystemForLayer(layer, path, nil)
+	}
+	if cacheDir != "" {
+		path, cached, err := extractFileSystem(digest.String(), digest.String(), layerCacheDir(cacheDir, digest), size, extract)
+		if err != nil {
+			return "", false, errors.Wrap(err, "getting filesystem for layer")
+		}
+		return path, cached, nil
+	}
+
+	extractedLayers.Lock()
+	extracted, ok := extractedLayers.layers[digest]
+	if !ok {
+		extracted = &extractedLayer{}
+		extractedLayers.layers[digest] = extracted
+	}
+	extracted.refs++
+	extractedLayers.Unlock()
+	if ok {
+		logrus.Infof("layer %s is shared, reusing its filesystem", digest)
+	}
+
+	extracted.once.Do(func() {
+		extracted.path, _, extracted.err = extractFileSystem(digest.String(), digest.String(), "", size, extract)
+		if extracted.err != nil {
+			extracted.err = errors.Wrap(extracted.err, "getting filesystem for layer")
+		}
+	})
+	if extracted.err != nil {
+		releaseLayer(digest)
+		return "", false, extracted.err
+	}
+	return extracted.path, false, nil
+}
+
+// releaseLayer drops a reference to a shared layer, returning whether its
+// directory is no longer used and can be removed. Layers which aren't
+// shared, such as cached ones, are always removable.
+func releaseLayer(digest v1.Hash) bool {
+	extractedLayers.Lock()
+	defer extractedLayers.Unlock()
+	extracted, ok := extractedLayers.layers[digest]
+	if !ok {
+		return true
+	}
+	extracted.refs--
+	if extracted.refs > 0 {
+		return false
+	}
+	delete(extractedLayers.layers, digest)
+	return true
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// ImageSource retrieves an image of a source registered with
+// RegisterImageSource, given its name without the source prefix. The
+// returned function releases any local copy the image reads from, such as
+// a downloaded tarball, once the image has been extracted; it may be nil.
+type ImageSource func(name string) (v1.Image, func(), error)
+
+var imageSources = struct {
+	sync.RWMutex
+	byPrefix map[string]ImageSource
+}{byPrefix: map[string]ImageSource{}}
+
+// RegisterImageSource reads images named with prefix, e.g. "artifacts://",
+// from source, so that programs embedding container-diff can diff images
+// of stores it doesn't support. Registered prefixes take precedence over
+// the built-in ones, the longest matching prefix winning, and registering a
+// prefix again replaces its source. It panics if prefix is empty or source
+// is nil.
+func RegisterImageSource(prefix string, source ImageSource) {
+	if prefix == "" {
+		panic("container-diff: RegisterImageSource with an empty prefix")
+	}
+	if source == nil {
+		panic("container-diff: RegisterImageSource of " + prefix + " with a nil source")
+	}
+	imageSources.Lock()
+	defer imageSources.Unlock()
+	imageSources.byPrefix[prefix] = source
+}
+
+// registeredImageSource returns the registered source of imageName and its
+// prefix, if any.
+func registeredImageSource(imageName string) (string, ImageSource, bool) {
+	imageSources.RLock()
+	defer imageSources.RUnlock()
+	var match string
+	for prefix := range imageSources.byPrefix {
+		if strings.HasPrefix(imageName, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return "", nil, false
+	}
+	return match, imageSources.byPrefix[match], true
+}
+
+// getRegisteredImage retrieves imageName from its registered source.
+func getRegisteredImage(prefix string, source ImageSource, imageName string) (v1.Image, func(), error) {
+	img, cleanup, err := source(strings.TrimPrefix(imageName, prefix))
+	if err != nil {
+		if cleanup != nil {
+			cleanup()
+		}
+		return nil, nil, errors.Wrapf(err, "retrieving %s", imageName)
+	}
+	if cleanup == nil {
+		cleanup = func() {}
+	}
+	return img, cleanup, nil
+}
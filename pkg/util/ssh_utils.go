@@ -0,0 +1,121 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const sshPrefix = "ssh://"
+
+// SSHCommand is the ssh client used to reach ssh:// sources. It is run with
+// the user's ssh configuration, so keys and agents work as usual.
+var SSHCommand = "ssh"
+
+// getSSHImage copies an ssh:// source to a local file and returns an image
+// reference to it, together with a function removing the local copy. Paths
+// with a tar extension are read as `docker save` archives, with the image of
+// archives holding several selected by the URL fragment; anything else is
+// treated as a root filesystem and becomes a single layer image.
+func getSSHImage(source string) (v1.Image, func(), error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parsing ssh source")
+	}
+	if u.Hostname() == "" || u.Path == "" {
+		return nil, nil, errors.Errorf("ssh source %s must be of the form ssh://[user@]host[:port]/path", source)
+	}
+	// ssh would take a user or host starting with a dash for an option, such
+	// as -oProxyCommand running a local command
+	if strings.HasPrefix(u.Hostname(), "-") || (u.User != nil && strings.HasPrefix(u.User.Username(), "-")) {
+		return nil, nil, errors.Errorf("ssh source %s has an invalid user or host", source)
+	}
+	args := []string{}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	host := u.Hostname()
+	if u.User != nil {
+		host = u.User.Username() + "@" + host
+	}
+	remote := "tar -C " + shellQuote(u.Path) + " -cf - ."
+	if IsTar(u.Path) {
+		remote = "cat " + shellQuote(u.Path)
+	}
+	args = append(args, "--", host, remote)
+
+	f, err := ioutil.TempFile("", "container-diff-ssh-*.tar")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		if err := os.Remove(f.Name()); err != nil {
+			logrus.Warn(err.Error())
+		}
+	}
+	start := time.Now()
+	var stderr bytes.Buffer
+	cmd := exec.Command(SSHCommand, args...)
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	f.Close()
+	if err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "copying %s over ssh: %s", source, strings.TrimSpace(stderr.String()))
+	}
+	elapsed := time.Now().Sub(start)
+	logrus.Infof("copying %s over ssh took %f seconds", source, elapsed.Seconds())
+
+	var img v1.Image
+	if IsTar(u.Path) {
+		img, err = imageTarball(f.Name(), u.Fragment)
+	} else {
+		img, err = rootFSImage(f.Name())
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "reading %s", source)
+	}
+	return img, cleanup, nil
+}
+
+// rootFSImage wraps a tarball of a root filesystem in a single layer image.
+func rootFSImage(path string) (v1.Image, error) {
+	layer, err := tarball.LayerFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return mutate.AppendLayers(empty.Image, layer)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RegistryTag is a tag of a repository. Digest and Time are only set when
+// known: Time is when the tag's manifest was uploaded, as GCR and Artifact
+// Registry report, or else when its image was created, as ResolveTagTimes
+// reads it.
+type RegistryTag struct {
+	Tag    string
+	Digest string
+	Time   time.Time
+}
+
+// tagList is the response of the tags/list API. GCR and Artifact Registry
+// also list the manifests of the repository, with their tags and upload
+// times.
+type tagList struct {
+	Tags     []string `json:"tags"`
+	Manifest map[string]struct {
+		Tag            []string `json:"tag"`
+		TimeUploadedMs string   `json:"timeUploadedMs"`
+	} `json:"manifest"`
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// ListTags lists the tags of the repository of the registry image
+// imageName, following the pages of the listing.
+func ListTags(imageName string) ([]RegistryTag, error) {
+	client, ref, base, err := newRegistryClient(imageName)
+	if err != nil {
+		return nil, err
+	}
+	tags := []RegistryTag{}
+	seen := map[string]int{}
+	next := base + "/tags/list"
+	for next != "" {
+		list, link, err := getTagList(client, next)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing tags of %s", ref.Context())
+		}
+		for _, tag := range list.Tags {
+			if _, ok := seen[tag]; !ok {
+				seen[tag] = len(tags)
+				tags = append(tags, RegistryTag{Tag: tag})
+			}
+		}
+		for digest, manifest := range list.Manifest {
+			ms, _ := strconv.ParseInt(manifest.TimeUploadedMs, 10, 64)
+			for _, tag := range manifest.Tag {
+				if i, ok := seen[tag]; ok {
+					tags[i].Digest = digest
+					if ms > 0 {
+						tags[i].Time = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+					}
+				}
+			}
+		}
+		next, err = resolveNextLink(next, link)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func getTagList(client *http.Client, url string) (tagList, string, error) {
+	list := tagList{}
+	resp, err := client.Get(url)
+	if err != nil {
+		return list, "", err
+	}
+	defer resp.Body.Close()
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return list, "", err
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegistryDocumentSize)).Decode(&list); err != nil {
+		return list, "", err
+	}
+	return list, resp.Header.Get("Link"), nil
+}
+
+// resolveNextLink returns the URL of the next page of a listing from its
+// Link header, relative to the URL of the current page, or "" on the last
+// page.
+func resolveNextLink(current, link string) (string, error) {
+	match := nextLinkPattern.FindStringSubmatch(link)
+	if match == nil {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := url.Parse(match[1])
+	if err != nil {
+		return "", errors.Wrap(err, "parsing the next page of the tag listing")
+	}
+	return base.ResolveReference(next).String(), nil
+}
+
+// tagTimeConcurrency bounds the tags whose creation times are read at once.
+const tagTimeConcurrency = 8
+
+// ResolveTagTimes fetches the digests and creation times of the tags whose
+// listing didn't include them, reading the manifest and image config of
+// each, up to tagTimeConcurrency tags at a time. The creation time is when
+// the image was built, which isn't when its tag was pushed for images
+// pushed again or built reproducibly.
+func ResolveTagTimes(imageName string, tags []RegistryTag) error {
+	ref, err := parseRemoteReference(strings.TrimPrefix(imageName, remotePrefix))
+	if err != nil {
+		return errors.Wrap(err, "parsing image reference")
+	}
+	repo := ref.Context()
+	auth, err := resolveAuth(repo.Registry)
+	if err != nil {
+		return err
+	}
+	transport := BuildTransport(repo.Registry)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	slots := make(chan struct{}, tagTimeConcurrency)
+	for i := range tags {
+		if !tags[i].Time.IsZero() {
+			continue
+		}
+		tag, err := name.NewTag(fmt.Sprintf("%s:%s", repo, tags[i].Tag), name.WeakValidation)
+		if err != nil {
+			return err
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(tag *RegistryTag, ref name.Tag) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			mu.Lock()
+			failed := firstErr != nil
+			mu.Unlock()
+			if failed {
+				return
+			}
+			digest, created, err := tagCreationTime(ref, auth, transport)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			tag.Digest, tag.Time = digest, created
+		}(&tags[i], tag)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func tagCreationTime(tag name.Tag, auth authn.Authenticator, transport http.RoundTripper) (string, time.Time, error) {
+	logrus.Infof("reading the creation time of %s", tag)
+	img, err := remote.Image(tag, remote.WithAuth(auth), remote.WithTransport(transport))
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "retrieving %s", tag)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "retrieving the digest of %s", tag)
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "retrieving the config of %s", tag)
+	}
+	return digest.String(), config.Created.Time, nil
+}
+
+// ImageTag returns the tag of the registry image imageName, latest when it
+// has none.
+func ImageTag(imageName string) (string, error) {
+	ref, err := parseRemoteReference(strings.TrimPrefix(imageName, remotePrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "parsing image reference")
+	}
+	tag, ok := ref.(name.Tag)
+	if !ok {
+		return "", fmt.Errorf("%s is referenced by digest rather than tag", imageName)
+	}
+	return tag.TagStr(), nil
+}
+
+// WithTag returns imageName with its tag, if any, replaced by tag, keeping
+// its prefix.
+func WithTag(imageName, tag string) (string, error) {
+	prefix := ""
+	if strings.HasPrefix(imageName, remotePrefix) {
+		prefix = remotePrefix
+	}
+	ref, err := parseRemoteReference(strings.TrimPrefix(imageName, remotePrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "parsing image reference")
+	}
+	return fmt.Sprintf("%s%s:%s", prefix, ref.Context(), tag), nil
+}
@@ -18,8 +18,10 @@ package util
 
 import (
 	"archive/tar"
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,6 +43,13 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 	var hardlinks sync.Map
 
 	originalPerms := make([]OriginalPerm, 0)
+	// paths differing only in case overwrite each other on the default
+	// APFS and NTFS volumes, so collisions are reported there
+	var folded map[string]string
+	collisions := []string{}
+	if isCaseInsensitive(path) {
+		folded = map[string]string{}
+	}
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -55,6 +64,13 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 		if checkWhitelist(target, whitelist) {
 			continue
 		}
+		if folded != nil && header.Typeflag != tar.TypeDir {
+			key := strings.ToLower(target)
+			if other, ok := folded[key]; ok && other != target {
+				collisions = append(collisions, strings.TrimPrefix(target, path))
+			}
+			folded[key] = target
+		}
 		mode := header.FileInfo().Mode()
 		switch header.Typeflag {
 
@@ -101,6 +117,12 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 				}
 			}
 
+			// macOS tar stores extended attributes as AppleDouble ._ files
+			contents, appleDouble := readAppleDouble(target, tr)
+			if appleDouble {
+				logrus.Debugf("Not extracting AppleDouble file %s", target)
+				continue
+			}
 			logrus.Debugf("Creating file %s with permissions %v", target, mode)
 			currFile, err := os.Create(target)
 			if err != nil {
@@ -112,7 +134,7 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 				logrus.Errorf("Error updating file permissions on %s", target)
 				return err
 			}
-			_, err = io.Copy(currFile, tr)
+			_, err = io.Copy(currFile, contents)
 			if err != nil {
 				return err
 			}
@@ -159,6 +181,10 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 		return resolveError.Load().(error)
 	}
 
+	if len(collisions) != 0 {
+		logrus.Warnf("%s is case-insensitive, so %d path(s) differing only in case from an earlier one were overwritten, e.g. %s. Set --cache-dir or TMPDIR to a case-sensitive volume for exact results", path, len(collisions), collisions[0])
+	}
+
 	// reset all original file
 	for _, perm := range originalPerms {
 		if err := os.Chmod(perm.path, perm.perm); err != nil {
@@ -168,6 +194,40 @@ func unpackTar(tr *tar.Reader, path string, whitelist []string) error {
 	return nil
 }
 
+// appleDoubleMagic starts the AppleDouble files macOS writes for the
+// extended attributes and resource forks of a file named like them without
+// the ._ prefix.
+var appleDoubleMagic = []byte{0x00, 0x05, 0x16, 0x07}
+
+// readAppleDouble reports whether the entry for target is an AppleDouble
+// file. Otherwise it returns a reader for the whole entry.
+func readAppleDouble(target string, r io.Reader) (io.Reader, bool) {
+	if !strings.HasPrefix(filepath.Base(target), "._") {
+		return r, false
+	}
+	magic := make([]byte, len(appleDoubleMagic))
+	n, _ := io.ReadFull(r, magic)
+	if n == len(magic) && bytes.Equal(magic, appleDoubleMagic) {
+		return nil, true
+	}
+	return io.MultiReader(bytes.NewReader(magic[:n]), r), false
+}
+
+// isCaseInsensitive reports whether the filesystem of dir folds case.
+func isCaseInsensitive(dir string) bool {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false
+	}
+	probe, err := ioutil.TempFile(dir, ".case-probe")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	defer os.Remove(probe.Name())
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(probe.Name()))))
+	return err == nil
+}
+
 func resolveHardlink(linkname, target string) error {
 	if err := os.Link(linkname, target); err != nil {
 		return err
@@ -187,9 +247,9 @@ func checkWhitelist(target string, whitelist []string) bool {
 }
 
 func IsTar(path string) bool {
-	return filepath.Ext(path) == ".tar" ||
-		filepath.Ext(path) == ".tar.gz" ||
-		filepath.Ext(path) == ".tgz"
+	return strings.HasSuffix(path, ".tar") ||
+		strings.HasSuffix(path, ".tar.gz") ||
+		strings.HasSuffix(path, ".tgz")
 }
 
 func CheckTar(image string) bool {
@@ -0,0 +1,255 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+)
+
+// tarballManifest is the file of a docker save tarball listing its images.
+const tarballManifest = "manifest.json"
+
+// imageIDSelector matches the image IDs, full or shortened, selecting an
+// image of a tarball.
+var imageIDSelector = regexp.MustCompile(`^(sha256:)?[0-9a-f]{4,64}$`)
+
+// tarballEntry is an image of a docker save tarball: its config file,
+// named after the image ID, and its tags.
+type tarballEntry struct {
+	Config   string
+	RepoTags []string
+}
+
+// id returns the image ID of an entry, from the name of its config file:
+// <id>.json, blobs/sha256/<id> in the OCI layout of newer Dockers, or
+// sha256:<id> as go-containerregistry writes it.
+func (e tarballEntry) id() string {
+	return strings.TrimPrefix(strings.TrimSuffix(path.Base(e.Config), ".json"), "sha256:")
+}
+
+// name returns how an entry is selected: by its first tag, or its
+// shortened ID if it has none.
+func (e tarballEntry) name() string {
+	if len(e.RepoTags) > 0 {
+		return e.RepoTags[0]
+	}
+	id := e.id()
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// splitTarballSource splits a tarball source into the path of the tarball
+// and the image selected from it, if any, as in images.tar#nginx:1.25.
+func splitTarballSource(source string) (string, string) {
+	if i := strings.LastIndex(source, "#"); i >= 0 && IsTar(source[:i]) {
+		return source[:i], source[i+1:]
+	}
+	return source, ""
+}
+
+// isTarballSource tells whether source names an image tarball.
+func isTarballSource(source string) bool {
+	tarballPath, _ := splitTarballSource(source)
+	return IsTar(tarballPath)
+}
+
+// imageTarball reads the image tarball at tarballPath, as written by docker save,
+// without a daemon. Tarballs compressed with gzip are decompressed as they
+// are read. Tarballs holding several images need one selected, by tag or
+// image ID.
+func imageTarball(tarballPath, selector string) (v1.Image, error) {
+	open := func() (io.ReadCloser, error) {
+		f, err := os.Open(tarballPath)
+		if err != nil {
+			return nil, err
+		}
+		r := bufio.NewReader(f)
+		if magic, err := r.Peek(2); err != nil || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+			return struct {
+				io.Reader
+				io.Closer
+			}{r, f}, nil
+		}
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{gz, f}, nil
+	}
+	open, err := selectTarballImage(open, selector)
+	if err != nil {
+		return nil, err
+	}
+	return tarball.Image(open, nil)
+}
+
+// selectTarballImage returns an opener of the tarball of open showing only
+// the selected image in its manifest, so the tarball package reads that
+// one. Tarballs of a single image are read as they are, unless another
+// image is selected.
+func selectTarballImage(open tarball.Opener, selector string) (tarball.Opener, error) {
+	raw, err := readTarballManifest(open)
+	if err != nil {
+		return nil, err
+	}
+	var rawEntries []json.RawMessage
+	var entries []tarballEntry
+	if err := json.Unmarshal(raw, &rawEntries); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", tarballManifest)
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", tarballManifest)
+	}
+	if selector == "" && len(entries) == 1 {
+		return open, nil
+	}
+	i, err := findTarballEntry(entries, selector)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := json.Marshal(rawEntries[i : i+1])
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		rc, err := open()
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer rc.Close()
+			pw.CloseWithError(replaceTarballManifest(rc, pw, manifest))
+		}()
+		return pr, nil
+	}, nil
+}
+
+// findTarballEntry returns the index of the entry selected by a tag, as
+// the tarball package resolves tags, or by a unique prefix of its image ID.
+func findTarballEntry(entries []tarballEntry, selector string) (int, error) {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.name())
+	}
+	if selector == "" {
+		return 0, fmt.Errorf("tarball holds %d images, select one with #<repo:tag> or #<image ID>: %s", len(entries), strings.Join(names, ", "))
+	}
+	if tag, err := name.NewTag(selector, name.WeakValidation); err == nil {
+		for i, e := range entries {
+			for _, repoTag := range e.RepoTags {
+				if t, err := name.NewTag(repoTag, name.WeakValidation); err == nil && t.Name() == tag.Name() {
+					return i, nil
+				}
+			}
+		}
+	}
+	if imageIDSelector.MatchString(selector) {
+		matches := []int{}
+		for i, e := range entries {
+			if strings.HasPrefix(e.id(), strings.TrimPrefix(selector, "sha256:")) {
+				matches = append(matches, i)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+		if len(matches) > 1 {
+			return 0, fmt.Errorf("image ID %s is ambiguous in tarball", selector)
+		}
+	}
+	return 0, fmt.Errorf("no image %s in tarball, which holds: %s", selector, strings.Join(names, ", "))
+}
+
+// readTarballManifest returns the manifest of the tarball of open.
+func readTarballManifest(open tarball.Opener) ([]byte, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no %s in tarball", tarballManifest)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading tarball")
+		}
+		if path.Clean(hdr.Name) == tarballManifest {
+			var manifest bytes.Buffer
+			if _, err := io.Copy(&manifest, tr); err != nil {
+				return nil, errors.Wrapf(err, "reading %s", tarballManifest)
+			}
+			return manifest.Bytes(), nil
+		}
+	}
+}
+
+// replaceTarballManifest copies the tarball of r to w, with its manifest
+// replaced.
+func replaceTarballManifest(r io.Reader, w io.Writer, manifest []byte) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if path.Clean(hdr.Name) == tarballManifest {
+			hdr.Size = int64(len(manifest))
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -19,49 +19,178 @@ package util
 import (
 	"crypto/tls"
 	"crypto/x509"
-	. "github.com/google/go-containerregistry/pkg/name"
-	"github.com/sirupsen/logrus"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+
+	. "github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 var tlsConfiguration = struct {
 	certifiedRegistries     map[string]string
 	skipTLSVerifyRegistries map[string]struct{}
+	insecureRegistries      map[string]struct{}
+	// caCertificates are the PEM bundles of the CAs trusted for every
+	// registry, along with those of the system
+	caCertificates [][]byte
 }{
 	certifiedRegistries:     make(map[string]string),
 	skipTLSVerifyRegistries: make(map[string]struct{}),
+	insecureRegistries:      make(map[string]struct{}),
 }
 
+// plainHTTPRegistries remembers the insecure registries which fell back to
+// plain HTTP, so later requests go there directly.
+var plainHTTPRegistries sync.Map
+
+// ConfigureTLS sets the registries whose certificates aren't verified, and
+// the certificates to verify other registries with. Registries are given as
+// host[:port], e.g. registry.local:5000 or [fd00::10]:5000.
 func ConfigureTLS(skipTsVerifyRegistries []string, registriesToCertificates map[string]string) {
 	tlsConfiguration.skipTLSVerifyRegistries = make(map[string]struct{})
 	for _, registry := range skipTsVerifyRegistries {
-		tlsConfiguration.skipTLSVerifyRegistries[registry] = struct{}{}
+		tlsConfiguration.skipTLSVerifyRegistries[registryHost(registry)] = struct{}{}
 	}
 	tlsConfiguration.certifiedRegistries = make(map[string]string)
 	for registry := range registriesToCertificates {
-		tlsConfiguration.certifiedRegistries[registry] = registriesToCertificates[registry]
+		tlsConfiguration.certifiedRegistries[registryHost(registry)] = registriesToCertificates[registry]
+	}
+}
+
+// ConfigureRegistryCAs sets the CA certificates, as PEM files, which every
+// registry's certificate may be signed by, e.g. the CA of a company's
+// internal registries.
+func ConfigureRegistryCAs(paths []string) error {
+	tlsConfiguration.caCertificates = nil
+	for _, path := range paths {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "reading registry CA certificate")
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates in %s", path)
+		}
+		tlsConfiguration.caCertificates = append(tlsConfiguration.caCertificates, pem)
+	}
+	return nil
+}
+
+// ConfigureInsecureRegistries sets the registries which may be reached
+// without TLS. Their certificates aren't verified, and if HTTPS fails they
+// are retried over plain HTTP.
+func ConfigureInsecureRegistries(registries []string) {
+	tlsConfiguration.insecureRegistries = make(map[string]struct{})
+	for _, registry := range registries {
+		tlsConfiguration.insecureRegistries[registryHost(registry)] = struct{}{}
 	}
 }
 
+// registryHost strips the scheme and trailing slash a registry may be given
+// with.
+func registryHost(registry string) string {
+	if i := strings.Index(registry, "://"); i >= 0 {
+		registry = registry[i+len("://"):]
+	}
+	return strings.TrimSuffix(registry, "/")
+}
+
 func BuildTransport(registry Registry) http.RoundTripper {
 	var tr http.RoundTripper = http.DefaultTransport.(*http.Transport).Clone()
 
-	if _, present := tlsConfiguration.skipTLSVerifyRegistries[registry.RegistryStr()]; present {
+	_, skipVerify := tlsConfiguration.skipTLSVerifyRegistries[registry.RegistryStr()]
+	_, insecure := tlsConfiguration.insecureRegistries[registry.RegistryStr()]
+	if skipVerify || insecure {
 		tr.(*http.Transport).TLSClientConfig = &tls.Config{
 			InsecureSkipVerify: true,
 		}
-	} else if certificatePath := tlsConfiguration.certifiedRegistries[registry.RegistryStr()]; certificatePath != "" {
+	} else if certificatePath := tlsConfiguration.certifiedRegistries[registry.RegistryStr()]; certificatePath != "" || len(tlsConfiguration.caCertificates) > 0 {
 		systemCertPool := defaultX509Handler()
-		if err := appendCertificate(systemCertPool, certificatePath); err != nil {
-			logrus.WithError(err).Warnf("Failed to load certificate %s for %s\n", certificatePath, registry.RegistryStr())
-		} else {
-			tr.(*http.Transport).TLSClientConfig = &tls.Config{
-				RootCAs: systemCertPool,
+		for _, pem := range tlsConfiguration.caCertificates {
+			systemCertPool.AppendCertsFromPEM(pem)
+		}
+		if certificatePath != "" {
+			if err := appendCertificate(systemCertPool, certificatePath); err != nil {
+				logrus.WithError(err).Warnf("Failed to load certificate %s for %s\n", certificatePath, registry.RegistryStr())
+			}
+		}
+		tr.(*http.Transport).TLSClientConfig = &tls.Config{
+			RootCAs: systemCertPool,
+		}
+	}
+	tr = fixtureTransport(tr)
+	tr = &schemeTransport{inner: tr, host: registry.RegistryStr(), insecure: insecure}
+	return wrapTransport(tr)
+}
+
+// schemeTransport picks the scheme of the requests to a registry. The
+// registry library uses plain HTTP for any IPv6 host containing "::1",
+// public or not, and HTTPS for the other private ones, so the scheme of IPv6
+// hosts is chosen here by their range. Requests to insecure registries are
+// retried over HTTP if HTTPS fails.
+type schemeTransport struct {
+	inner    http.RoundTripper
+	host     string
+	insecure bool
+}
+
+func (t *schemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		// e.g. token servers and blob storage redirects
+		return t.inner.RoundTrip(req)
+	}
+	if _, ok := plainHTTPRegistries.Load(t.host); ok {
+		return t.inner.RoundTrip(withScheme(req, "http"))
+	}
+	if scheme, ok := ipv6Scheme(t.host); ok && req.URL.Scheme != scheme {
+		req = withScheme(req, scheme)
+	}
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil && t.insecure && req.URL.Scheme == "https" && (req.Body == nil || req.GetBody != nil) {
+		logrus.Warnf("HTTPS request to insecure registry %s failed, retrying over HTTP: %s", t.host, err)
+		retry := withScheme(req, "http")
+		if req.Body != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
 			}
 		}
+		if resp, err = t.inner.RoundTrip(retry); err == nil {
+			plainHTTPRegistries.Store(t.host, struct{}{})
+		}
+	}
+	return resp, err
+}
+
+func withScheme(req *http.Request, scheme string) *http.Request {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = scheme
+	return req
+}
+
+// ipv6Scheme returns the scheme of host if it is a bracketed IPv6 address,
+// with or without a port: plain HTTP in the loopback, link-local and private
+// ranges, as for IPv4 addresses, and HTTPS elsewhere.
+func ipv6Scheme(host string) (string, bool) {
+	if !strings.HasPrefix(host, "[") {
+		return "", false
+	}
+	end := strings.Index(host, "]")
+	if end < 0 {
+		return "", false
+	}
+	ip := net.ParseIP(host[1:end])
+	if ip == nil || ip.To4() != nil {
+		return "", false
+	}
+	_, private, _ := net.ParseCIDR("fc00::/7")
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || private.Contains(ip) {
+		return "http", true
 	}
-	return tr
+	return "https", true
 }
 
 func appendCertificate(pool *x509.CertPool, path string) error {
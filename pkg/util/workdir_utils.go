@@ -0,0 +1,202 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Extraction backends, as selected with ConfigureWorkdir.
+const (
+	WorkdirDisk   = "disk"
+	WorkdirMemory = "memory"
+	WorkdirAuto   = "auto"
+)
+
+// layerExpansion is how many times larger than its compressed size an
+// extracted layer is assumed to be, the usual gzip ratio of image layers.
+const layerExpansion = 3
+
+// ExtractionBackend provides the directories image filesystems and layers
+// are extracted to when they aren't cached.
+type ExtractionBackend interface {
+	Name() string
+	// Extract creates a directory named after prefix for an extraction of
+	// about size bytes, and runs extract on it. The directory is returned
+	// even if extract fails, for it to be cleaned up.
+	Extract(prefix string, size int64, extract func(path string) error) (string, error)
+}
+
+// diskBackend extracts to the temp dir, $TMPDIR or /tmp.
+type diskBackend struct{}
+
+func (b diskBackend) Name() string {
+	return WorkdirDisk
+}
+
+func (b diskBackend) Extract(prefix string, size int64, extract func(path string) error) (string, error) {
+	return extractTo("", prefix, extract)
+}
+
+// memoryBackend extracts to a tmpfs, so that extraction and analysis never
+// touch the disk.
+type memoryBackend struct {
+	dir string
+}
+
+func (b memoryBackend) Name() string {
+	return WorkdirMemory
+}
+
+func (b memoryBackend) Extract(prefix string, size int64, extract func(path string) error) (string, error) {
+	return extractTo(b.dir, prefix, extract)
+}
+
+// autoBackend extracts to a tmpfs when it has twice the room an extraction
+// is estimated to need, leaving memory for the other image and the
+// analyzers, and to disk otherwise. Extractions the estimate got wrong spill
+// to disk when the tmpfs fills up.
+type autoBackend struct {
+	memory memoryBackend
+}
+
+func (b autoBackend) Name() string {
+	return WorkdirAuto
+}
+
+func (b autoBackend) Extract(prefix string, size int64, extract func(path string) error) (string, error) {
+	if b.memory.dir == "" {
+		return diskBackend{}.Extract(prefix, size, extract)
+	}
+	if free, ok := freeSpace(b.memory.dir); !ok || size*2 > free {
+		logrus.Infof("%s has no room for the %d bytes of %s, extracting to disk", b.memory.dir, size, prefix)
+		return diskBackend{}.Extract(prefix, size, extract)
+	}
+	path, err := b.memory.Extract(prefix, size, extract)
+	if err == nil || !isNoSpace(err) {
+		return path, err
+	}
+	logrus.Warnf("%s filled up extracting %s, extracting it to disk instead", b.memory.dir, prefix)
+	makeWritable(path)
+	if err := os.RemoveAll(path); err != nil {
+		logrus.Warn(err.Error())
+	}
+	return diskBackend{}.Extract(prefix, size, extract)
+}
+
+func extractTo(dir, prefix string, extract func(path string) error) (string, error) {
+	path, err := ioutil.TempDir(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+	return path, extract(path)
+}
+
+var extractionBackend ExtractionBackend = diskBackend{}
+
+// ConfigureWorkdir selects where uncached image filesystems are extracted:
+// to disk, to memory through a tmpfs such as /dev/shm, or to memory when
+// they fit (auto).
+func ConfigureWorkdir(backend string) error {
+	memory := memoryBackend{dir: memoryDir()}
+	switch backend {
+	case "", WorkdirDisk:
+		extractionBackend = diskBackend{}
+		return nil
+	case WorkdirMemory:
+		if memory.dir == "" {
+			return errors.New("no tmpfs, such as /dev/shm, found to extract images to memory")
+		}
+		extractionBackend = memory
+	case WorkdirAuto:
+		extractionBackend = autoBackend{memory: memory}
+	default:
+		return fmt.Errorf("invalid workdir backend %s, expected %s, %s or %s", backend, WorkdirMemory, WorkdirDisk, WorkdirAuto)
+	}
+	if memory.dir != "" {
+		logrus.Infof("extracting images to the tmpfs at %s", memory.dir)
+	}
+	return nil
+}
+
+// memoryDir returns a writable tmpfs to extract to: /dev/shm, or the temp
+// dir when it is itself a tmpfs.
+func memoryDir() string {
+	for _, dir := range []string{"/dev/shm", os.TempDir()} {
+		if !isTmpfs(dir) {
+			continue
+		}
+		if probe, err := ioutil.TempDir(dir, ".container-diff"); err == nil {
+			os.Remove(probe)
+			return dir
+		}
+	}
+	return ""
+}
+
+// extractedSize estimates the size of the extracted filesystem of layers
+// from their compressed size.
+func extractedSize(layers []v1.Layer) int64 {
+	size := int64(0)
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		if s, err := layer.Size(); err == nil {
+			size += s * layerExpansion
+		}
+	}
+	return size
+}
+
+// extractToWorkdir extracts name, of about size bytes, with the configured
+// extraction backend.
+func extractToWorkdir(name string, size int64, extract func(path string) error) (string, error) {
+	return extractionBackend.Extract(strings.Replace(name, "/", "", -1), size, extract)
+}
+
+// isNoSpace reports whether err, possibly wrapped, is ENOSPC.
+func isNoSpace(err error) bool {
+	for err != nil {
+		if errno, ok := err.(syscall.Errno); ok {
+			return errno == syscall.ENOSPC
+		}
+		switch e := err.(type) {
+		case *os.PathError:
+			err = e.Err
+		case *os.LinkError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
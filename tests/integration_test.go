@@ -23,13 +23,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/GoogleContainerTools/container-diff/differs/difftest"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 )
@@ -240,15 +240,7 @@ func TestDiffAndAnalysis(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Error running command: %s. Stderr: %s", err, stderr)
 			}
-			e, err := ioutil.ReadFile(test.expectedFile)
-			if err != nil {
-				t.Fatalf("Error reading expected file output file: %s", err)
-			}
-			actual = strings.TrimSpace(actual)
-			expected := strings.TrimSpace(string(e))
-			if actual != expected {
-				t.Errorf("Error actual output does not match expected.  \n\nExpected: %s\n\n Actual: %s\n\n, Stderr: %s", expected, actual, stderr)
-			}
+			difftest.AssertGolden(t, test.expectedFile, actual)
 		})
 	}
 }
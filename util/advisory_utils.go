@@ -0,0 +1,548 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	osvAPIURL = "https://api.osv.dev/v1"
+	// osvBatchSize is the most queries the OSV API accepts in one batch.
+	osvBatchSize = 1000
+)
+
+// Advisory is a single security advisory affecting an installed package version.
+type Advisory struct {
+	ID       string
+	Package  string
+	Version  string
+	Severity string
+	Summary  string
+	Fixed    string
+	// Ecosystem is the OSV ecosystem of the package, when advisories of
+	// several ecosystems are reported together.
+	Ecosystem string `json:",omitempty"`
+}
+
+// AdvisoryDiff stores the advisories introduced and resolved between two images.
+type AdvisoryDiff struct {
+	Introduced []Advisory
+	Resolved   []Advisory
+}
+
+// AdvisoryDB looks up the OSV entries affecting a package version.
+type AdvisoryDB interface {
+	Lookup(ecosystem, name, version string) ([]OSVEntry, error)
+}
+
+// OSVEntry is the subset of the OSV schema used to match advisories.
+// See https://ossf.github.io/osv-schema/.
+type OSVEntry struct {
+	ID               string        `json:"id"`
+	Summary          string        `json:"summary"`
+	Affected         []OSVAffected `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []OSVRange `json:"ranges"`
+	Versions []string   `json:"versions"`
+}
+
+type OSVRange struct {
+	Type   string     `json:"type"`
+	Events []OSVEvent `json:"events"`
+}
+
+type OSVEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// AdvisoryQuery is a package version to look up in an AdvisoryDB.
+type AdvisoryQuery struct {
+	Ecosystem string
+	Name      string
+	Version   string
+}
+
+// NewAdvisoryDB returns an advisory database read from path, which may be a
+// single OSV JSON file, a JSON array of entries, or a directory of either.
+// With an empty path advisories are queried from the OSV API.
+func NewAdvisoryDB(path string) (AdvisoryDB, error) {
+	if path == "" {
+		return &osvAPI{
+			url:    osvAPIURL,
+			client: &http.Client{Timeout: 30 * time.Second},
+			cache:  map[string][]OSVEntry{},
+			vulns:  map[string]OSVEntry{},
+		}, nil
+	}
+	db := &offlineAdvisoryDB{entries: map[string][]OSVEntry{}}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening advisory database")
+	}
+	if !info.IsDir() {
+		return db, db.load(path)
+	}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != ".json" {
+			return nil
+		}
+		return db.load(p)
+	})
+	return db, err
+}
+
+type offlineAdvisoryDB struct {
+	// entries are keyed by ecosystem and package name
+	entries map[string][]OSVEntry
+}
+
+func advisoryKey(ecosystem, name string) string {
+	return strings.ToLower(ecosystem) + "/" + name
+}
+
+func (db *offlineAdvisoryDB) load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []OSVEntry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		var entry OSVEntry
+		err = json.Unmarshal(data, &entry)
+		entries = []OSVEntry{entry}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "parsing advisories in %s", path)
+	}
+	for _, entry := range entries {
+		seen := map[string]bool{}
+		for _, affected := range entry.Affected {
+			key := advisoryKey(affected.Package.Ecosystem, affected.Package.Name)
+			if !seen[key] {
+				db.entries[key] = append(db.entries[key], entry)
+				seen[key] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (db *offlineAdvisoryDB) Lookup(ecosystem, name, version string) ([]OSVEntry, error) {
+	return db.entries[advisoryKey(ecosystem, name)], nil
+}
+
+// osvAPI queries the OSV API, caching the entries of each package version.
+// It is shared by the analyzers of a run, which may look up packages
+// concurrently.
+type osvAPI struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string][]OSVEntry
+	// vulns are the full entries fetched for the IDs returned by batch
+	// queries, which are shared by many package versions
+	vulns map[string]OSVEntry
+}
+
+func osvCacheKey(ecosystem, name, version string) string {
+	return advisoryKey(ecosystem, name) + "@" + version
+}
+
+func osvQuery(ecosystem, name, version string) map[string]interface{} {
+	return map[string]interface{}{
+		"version": version,
+		"package": map[string]string{"ecosystem": ecosystem, "name": name},
+	}
+}
+
+func (api *osvAPI) cached(key string) ([]OSVEntry, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	entries, ok := api.cache[key]
+	return entries, ok
+}
+
+func (api *osvAPI) post(path string, query interface{}, result interface{}) error {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	resp, err := api.client.Post(api.url+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "querying OSV")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("querying OSV: %s", resp.Status)
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(result), "parsing OSV response")
+}
+
+func (api *osvAPI) Lookup(ecosystem, name, version string) ([]OSVEntry, error) {
+	key := osvCacheKey(ecosystem, name, version)
+	if entries, ok := api.cached(key); ok {
+		return entries, nil
+	}
+	var result struct {
+		Vulns []OSVEntry `json:"vulns"`
+	}
+	if err := api.post("/query", osvQuery(ecosystem, name, version), &result); err != nil {
+		return nil, err
+	}
+	api.mu.Lock()
+	api.cache[key] = result.Vulns
+	api.mu.Unlock()
+	return result.Vulns, nil
+}
+
+// prefetch looks up the package versions not yet cached with batch
+// queries, then fetches each vulnerability they return once. Versions with
+// more vulnerabilities than fit in one page of results are left to Lookup.
+func (api *osvAPI) prefetch(queries []AdvisoryQuery) error {
+	pending := []AdvisoryQuery{}
+	seen := map[string]bool{}
+	for _, q := range queries {
+		key := osvCacheKey(q.Ecosystem, q.Name, q.Version)
+		if _, ok := api.cached(key); ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		pending = append(pending, q)
+	}
+	for len(pending) > 0 {
+		batch := pending
+		if len(batch) > osvBatchSize {
+			batch = batch[:osvBatchSize]
+		}
+		pending = pending[len(batch):]
+		if err := api.queryBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (api *osvAPI) queryBatch(batch []AdvisoryQuery) error {
+	query := struct {
+		Queries []map[string]interface{} `json:"queries"`
+	}{}
+	for _, q := range batch {
+		query.Queries = append(query.Queries, osvQuery(q.Ecosystem, q.Name, q.Version))
+	}
+	var result struct {
+		Results []struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+			NextPageToken string `json:"next_page_token"`
+		} `json:"results"`
+	}
+	if err := api.post("/querybatch", query, &result); err != nil {
+		return err
+	}
+	if len(result.Results) != len(batch) {
+		return fmt.Errorf("querying OSV: got %d results for %d queries", len(result.Results), len(batch))
+	}
+	for i, r := range result.Results {
+		if r.NextPageToken != "" {
+			continue
+		}
+		entries := []OSVEntry{}
+		for _, vuln := range r.Vulns {
+			entry, err := api.vuln(vuln.ID)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		api.mu.Lock()
+		api.cache[osvCacheKey(batch[i].Ecosystem, batch[i].Name, batch[i].Version)] = entries
+		api.mu.Unlock()
+	}
+	return nil
+}
+
+// vuln returns the full entry of a vulnerability, as batch queries only
+// return IDs.
+func (api *osvAPI) vuln(id string) (OSVEntry, error) {
+	api.mu.Lock()
+	entry, ok := api.vulns[id]
+	api.mu.Unlock()
+	if ok {
+		return entry, nil
+	}
+	resp, err := api.client.Get(api.url + "/vulns/" + url.PathEscape(id))
+	if err != nil {
+		return entry, errors.Wrap(err, "querying OSV")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return entry, fmt.Errorf("fetching OSV entry %s: %s", id, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return entry, errors.Wrapf(err, "parsing OSV entry %s", id)
+	}
+	api.mu.Lock()
+	api.vulns[id] = entry
+	api.mu.Unlock()
+	return entry, nil
+}
+
+// PrefetchAdvisories looks up the given package versions ahead of
+// GetAdvisories, so that databases backed by the OSV API send them in
+// batches rather than one request per package. Versions that fail to
+// prefetch are looked up one at a time by GetAdvisories.
+func PrefetchAdvisories(db AdvisoryDB, queries []AdvisoryQuery) error {
+	if api, ok := db.(*osvAPI); ok {
+		return api.prefetch(queries)
+	}
+	return nil
+}
+
+// GetAdvisories returns the advisories in db affecting the given version of a
+// package, ordered by ID.
+func GetAdvisories(db AdvisoryDB, ecosystem, name, version string) ([]Advisory, error) {
+	entries, err := db.Lookup(ecosystem, name, version)
+	if err != nil {
+		return nil, err
+	}
+	advisories := []Advisory{}
+	for _, entry := range entries {
+		for _, affected := range entry.Affected {
+			if !strings.EqualFold(affected.Package.Ecosystem, ecosystem) || affected.Package.Name != name {
+				continue
+			}
+			fixed, ok := affectsVersion(affected, version)
+			if !ok {
+				continue
+			}
+			advisories = append(advisories, Advisory{
+				ID:       entry.ID,
+				Package:  name,
+				Version:  version,
+				Severity: entry.DatabaseSpecific.Severity,
+				Summary:  entry.Summary,
+				Fixed:    fixed,
+			})
+			break
+		}
+	}
+	sort.Slice(advisories, func(i, j int) bool { return advisories[i].ID < advisories[j].ID })
+	return advisories, nil
+}
+
+// affectsVersion reports whether version is affected, along with the version
+// fixing the affected range if one is known.
+func affectsVersion(affected OSVAffected, version string) (string, bool) {
+	for _, v := range affected.Versions {
+		if v == version {
+			return "", true
+		}
+	}
+	for _, r := range affected.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+		compare := compareSemver
+		if r.Type == "ECOSYSTEM" {
+			compare = ecosystemComparer(affected.Package.Ecosystem)
+		}
+		inRange := false
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				if event.Introduced == "0" || compare(version, event.Introduced) >= 0 {
+					inRange = true
+				}
+			case event.Fixed != "":
+				if inRange && compare(version, event.Fixed) < 0 {
+					return event.Fixed, true
+				}
+				inRange = false
+			case event.LastAffected != "":
+				if inRange && compare(version, event.LastAffected) <= 0 {
+					return "", true
+				}
+				inRange = false
+			}
+		}
+		if inRange {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// compareSemver compares two semantic versions, returning -1, 0 or 1.
+// Build metadata is ignored, and a pre-release sorts before its release.
+func compareSemver(a, b string) int {
+	a, aPre := splitSemver(a)
+	b, bPre := splitSemver(b)
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < 3; i++ {
+		if c := compareNumeric(semverPart(aParts, i), semverPart(bParts, i)); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aIDs := strings.Split(aPre, ".")
+	bIDs := strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := comparePrerelease(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(aIDs), len(bIDs))
+}
+
+func splitSemver(v string) (string, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.Index(v, "-"); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+func semverPart(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return "0"
+}
+
+func compareNumeric(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return compareInt(x, y)
+}
+
+func comparePrerelease(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(x, y)
+	case errA == nil:
+		// numeric identifiers have lower precedence
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// GetAdvisoryDiff compares the advisories affecting two images. An advisory
+// is introduced if it affects a package in the second image but did not
+// affect that package in the first, and resolved in the opposite case.
+func GetAdvisoryDiff(advisories1, advisories2 []Advisory) AdvisoryDiff {
+	key := func(a Advisory) string {
+		return a.ID + " " + a.Ecosystem + " " + a.Package
+	}
+	seen1 := map[string]bool{}
+	for _, a := range advisories1 {
+		seen1[key(a)] = true
+	}
+	seen2 := map[string]bool{}
+	for _, a := range advisories2 {
+		seen2[key(a)] = true
+	}
+	diff := AdvisoryDiff{
+		Introduced: []Advisory{},
+		Resolved:   []Advisory{},
+	}
+	for _, a := range advisories2 {
+		if !seen1[key(a)] {
+			diff.Introduced = append(diff.Introduced, a)
+		}
+	}
+	for _, a := range advisories1 {
+		if !seen2[key(a)] {
+			diff.Resolved = append(diff.Resolved, a)
+		}
+	}
+	return diff
+}
+
+// SortAdvisories orders advisories by package, version and ID.
+func SortAdvisories(advisories []Advisory) {
+	sort.Slice(advisories, func(i, j int) bool {
+		a, b := advisories[i], advisories[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompareSemver(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.0.0", b: "1.0.0", expected: 0},
+		{a: "1.0", b: "1.0.0", expected: 0},
+		{a: "v1.2.3", b: "1.2.3", expected: 0},
+		{a: "1.2.3", b: "1.10.0", expected: -1},
+		{a: "2.0.0", b: "1.99.99", expected: 1},
+		{a: "1.0.0-alpha", b: "1.0.0", expected: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", expected: -1},
+		{a: "1.0.0-rc.10", b: "1.0.0-rc.2", expected: 1},
+		{a: "1.0.0+build.5", b: "1.0.0", expected: 0},
+	}
+	for _, test := range testCases {
+		if actual := compareSemver(test.a, test.b); actual != test.expected {
+			t.Errorf("compareSemver(%s, %s): Expected: %d but got: %d", test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestAffectsVersion(t *testing.T) {
+	affected := OSVAffected{
+		Ranges: []OSVRange{{
+			Type: "SEMVER",
+			Events: []OSVEvent{
+				{Introduced: "1.0.0"}, {Fixed: "1.4.2"},
+				{Introduced: "2.0.0"}, {LastAffected: "2.1.0"},
+			},
+		}},
+		Versions: []string{"0.9.0"},
+	}
+	testCases := []struct {
+		version  string
+		affected bool
+		fixed    string
+	}{
+		{version: "0.8.0", affected: false},
+		{version: "0.9.0", affected: true},
+		{version: "1.0.0", affected: true, fixed: "1.4.2"},
+		{version: "1.4.2", affected: false},
+		{version: "2.1.0", affected: true},
+		{version: "2.1.1", affected: false},
+	}
+	for _, test := range testCases {
+		fixed, ok := affectsVersion(affected, test.version)
+		if ok != test.affected || fixed != test.fixed {
+			t.Errorf("%s: Expected: %t %q but got: %t %q", test.version, test.affected, test.fixed, ok, fixed)
+		}
+	}
+}
+
+func TestEcosystemVersions(t *testing.T) {
+	testCases := []struct {
+		ecosystem string
+		a, b      string
+		expected  int
+	}{
+		{ecosystem: "Debian:12", a: "3.0.11-1~deb12u2", b: "3.0.11-1", expected: -1},
+		{ecosystem: "Debian:12", a: "1:1.0-1", b: "2.0-1", expected: 1},
+		{ecosystem: "Debian:12", a: "1.2.10-1", b: "1.2.9-1", expected: 1},
+		{ecosystem: "Debian:12", a: "1.0a-1", b: "1.0+b1-1", expected: -1},
+		{ecosystem: "Ubuntu:22.04", a: "2.35-0ubuntu3.4", b: "2.35-0ubuntu3.10", expected: -1},
+		{ecosystem: "Ubuntu:22.04", a: "1.0-1", b: "1.0-1", expected: 0},
+		{ecosystem: "AlmaLinux:9", a: "3.0.7-24.el9", b: "3.0.7-25.el9", expected: -1},
+		{ecosystem: "AlmaLinux:9", a: "1.0~rc1-1", b: "1.0-1", expected: -1},
+		{ecosystem: "Rocky Linux:9", a: "2.0a", b: "2.0", expected: 1},
+		{ecosystem: "Rocky Linux:9", a: "1.10", b: "1.9", expected: 1},
+		{ecosystem: "Rocky Linux:9", a: "1.0", b: "1.0-3.el9", expected: 0},
+		{ecosystem: "PyPI", a: "2.31.0", b: "2.9.0", expected: 1},
+	}
+	for _, test := range testCases {
+		if actual := ecosystemComparer(test.ecosystem)(test.a, test.b); actual != test.expected {
+			t.Errorf("%s: comparing %s and %s: Expected: %d but got: %d", test.ecosystem, test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestOSVBatchQueries(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		type query struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+		}
+		switch {
+		case r.URL.Path == "/v1/querybatch":
+			var batch struct {
+				Queries []query `json:"queries"`
+			}
+			json.NewDecoder(r.Body).Decode(&batch)
+			results := []map[string]interface{}{}
+			for _, q := range batch.Queries {
+				switch {
+				case q.Package.Name == "paged":
+					results = append(results, map[string]interface{}{"vulns": []map[string]string{{"id": "GHSA-1"}}, "next_page_token": "next"})
+				case strings.HasPrefix(q.Package.Name, "vulnerable"):
+					results = append(results, map[string]interface{}{"vulns": []map[string]string{{"id": "GHSA-1"}}})
+				default:
+					results = append(results, map[string]interface{}{})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+		case r.URL.Path == "/v1/query":
+			var q query
+			json.NewDecoder(r.Body).Decode(&q)
+			fmt.Fprintf(w, `{"vulns": [{"id": "GHSA-2", "affected": [{"package": {"ecosystem": "npm", "name": %q}, "versions": ["1.0.0"]}]}]}`, q.Package.Name)
+		case strings.HasPrefix(r.URL.Path, "/v1/vulns/"):
+			fmt.Fprintf(w, `{"id": %q, "affected": [{"package": {"ecosystem": "npm", "name": "vulnerable"}, "versions": ["1.0.0"]}]}`, strings.TrimPrefix(r.URL.Path, "/v1/vulns/"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	db := &osvAPI{
+		url:    server.URL + "/v1",
+		client: &http.Client{Timeout: 5 * time.Second},
+		cache:  map[string][]OSVEntry{},
+		vulns:  map[string]OSVEntry{},
+	}
+
+	queries := []AdvisoryQuery{
+		{Ecosystem: "npm", Name: "vulnerable", Version: "1.0.0"},
+		{Ecosystem: "npm", Name: "vulnerable-too", Version: "1.0.0"},
+		{Ecosystem: "npm", Name: "paged", Version: "1.0.0"},
+	}
+	for i := 0; i < osvBatchSize; i++ {
+		queries = append(queries, AdvisoryQuery{Ecosystem: "npm", Name: fmt.Sprintf("pac%d", i), Version: "1.0.0"})
+	}
+	if err := PrefetchAdvisories(db, queries); err != nil {
+		t.Fatalf("Error prefetching advisories: %s", err)
+	}
+	for _, q := range queries {
+		if _, err := GetAdvisories(db, q.Ecosystem, q.Name, q.Version); err != nil {
+			t.Fatalf("Error looking up %s: %s", q.Name, err)
+		}
+	}
+	advisories, _ := GetAdvisories(db, "npm", "vulnerable", "1.0.0")
+	if len(advisories) != 1 || advisories[0].ID != "GHSA-1" {
+		t.Errorf("Expected GHSA-1 from the batch query but got %v", advisories)
+	}
+	advisories, _ = GetAdvisories(db, "npm", "paged", "1.0.0")
+	if len(advisories) != 1 || advisories[0].ID != "GHSA-2" {
+		t.Errorf("Expected GHSA-2 from the single query but got %v", advisories)
+	}
+	// the paged result is looked up on its own, and GHSA-1 fetched once
+	expected := map[string]int{"/v1/querybatch": 2, "/v1/vulns/GHSA-1": 1, "/v1/query": 1}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests %v but got %v", expected, requests)
+	}
+
+	if err := PrefetchAdvisories(db, queries); err != nil {
+		t.Fatalf("Error prefetching advisories: %s", err)
+	}
+	if requests["/v1/querybatch"] != 2 {
+		t.Errorf("Expected cached package versions not queried again but got %v", requests)
+	}
+}
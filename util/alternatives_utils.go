@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "sort"
+
+// Alternative stores the state of a single update-alternatives link group.
+type Alternative struct {
+	Name      string
+	Link      string
+	Mode      string
+	Selected  string
+	Providers []string
+}
+
+// AlternativeDiff stores the difference in alternatives between two images.
+// Adds and Dels hold alternatives only present in the second and first image
+// respectively, Mods the alternatives whose selection changed.
+type AlternativeDiff struct {
+	Adds []Alternative
+	Dels []Alternative
+	Mods []AlternativeChange
+}
+
+// AlternativeChange stores the selection of one alternative in two images.
+type AlternativeChange struct {
+	Name      string
+	Mode1     string
+	Mode2     string
+	Selected1 string
+	Selected2 string
+}
+
+// GetAlternativesDiff compares two sets of alternatives keyed by name.
+func GetAlternativesDiff(alts1, alts2 map[string]Alternative) AlternativeDiff {
+	diff := AlternativeDiff{
+		Adds: []Alternative{},
+		Dels: []Alternative{},
+		Mods: []AlternativeChange{},
+	}
+	for name, alt1 := range alts1 {
+		alt2, ok := alts2[name]
+		if !ok {
+			diff.Dels = append(diff.Dels, alt1)
+			continue
+		}
+		if alt1.Selected != alt2.Selected || alt1.Mode != alt2.Mode {
+			diff.Mods = append(diff.Mods, AlternativeChange{
+				Name:      name,
+				Mode1:     alt1.Mode,
+				Mode2:     alt2.Mode,
+				Selected1: alt1.Selected,
+				Selected2: alt2.Selected,
+			})
+		}
+	}
+	for name, alt2 := range alts2 {
+		if _, ok := alts1[name]; !ok {
+			diff.Adds = append(diff.Adds, alt2)
+		}
+	}
+	sort.Slice(diff.Adds, func(i, j int) bool { return diff.Adds[i].Name < diff.Adds[j].Name })
+	sort.Slice(diff.Dels, func(i, j int) bool { return diff.Dels[i].Name < diff.Dels[j].Name })
+	sort.Slice(diff.Mods, func(i, j int) bool { return diff.Mods[i].Name < diff.Mods[j].Name })
+	return diff
+}
+
+// SortedAlternatives returns the alternatives in alts ordered by name.
+func SortedAlternatives(alts map[string]Alternative) []Alternative {
+	sorted := []Alternative{}
+	for _, alt := range alts {
+		sorted = append(sorted, alt)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
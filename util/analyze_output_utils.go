@@ -20,14 +20,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/GoogleContainerTools/container-diff/pkg/util"
 	"github.com/sirupsen/logrus"
 )
 
 type Result interface {
-	OutputStruct() interface{}
-	OutputText(writer io.Writer, resultType string, format string) error
+	OutputStruct(opts OutputOptions) interface{}
+	OutputText(writer io.Writer, resultType string, opts OutputOptions) error
 }
 
 type AnalyzeResult struct {
@@ -38,30 +39,30 @@ type AnalyzeResult struct {
 
 type ListAnalyzeResult AnalyzeResult
 
-func (r ListAnalyzeResult) OutputStruct() interface{} {
+func (r ListAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	return r
 }
 
-func (r ListAnalyzeResult) OutputText(writer io.Writer, resultType string, format string) error {
+func (r ListAnalyzeResult) OutputText(writer io.Writer, resultType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.([]string)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []string")
 		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
 	}
 	r.Analysis = analysis
-	return TemplateOutputFromFormat(writer, r, "ListAnalyze", format)
+	return TemplateOutputFromFormat(writer, r, "ListAnalyze", opts)
 
 }
 
 type MultiVersionPackageAnalyzeResult AnalyzeResult
 
-func (r MultiVersionPackageAnalyzeResult) OutputStruct() interface{} {
+func (r MultiVersionPackageAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.(map[string]map[string]PackageInfo)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]map[string]PackageInfo")
 		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
 	}
-	analysisOutput := getMultiVersionPackageOutput(analysis)
+	analysisOutput := getMultiVersionPackageOutput(analysis, opts)
 	output := struct {
 		Image       string
 		AnalyzeType string
@@ -74,13 +75,13 @@ func (r MultiVersionPackageAnalyzeResult) OutputStruct() interface{} {
 	return output
 }
 
-func (r MultiVersionPackageAnalyzeResult) OutputText(writer io.Writer, resultType string, format string) error {
+func (r MultiVersionPackageAnalyzeResult) OutputText(writer io.Writer, resultType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.(map[string]map[string]PackageInfo)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]map[string]PackageInfo")
 		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
 	}
-	analysisOutput := getMultiVersionPackageOutput(analysis)
+	analysisOutput := getMultiVersionPackageOutput(analysis, opts)
 
 	strAnalysis := stringifyPackages(analysisOutput)
 	strResult := struct {
@@ -92,18 +93,56 @@ func (r MultiVersionPackageAnalyzeResult) OutputText(writer io.Writer, resultTyp
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strAnalysis,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "MultiVersionPackageAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "MultiVersionPackageAnalyze", opts)
+}
+
+type NodeAnalyzeResult AnalyzeResult
+
+func (r NodeAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(map[string]map[string]PackageInfo)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]map[string]PackageInfo")
+		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
+	}
+	output := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    []PackageOutput
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+		Analysis:    getNodePackageOutput(analysis, opts),
+	}
+	return output
+}
+
+func (r NodeAnalyzeResult) OutputText(writer io.Writer, resultType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.(map[string]map[string]PackageInfo)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]map[string]PackageInfo")
+		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    []StrNodeProject
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+		Analysis:    groupNodeProjects(getNodePackageOutput(analysis, opts)),
+	}
+	return TemplateOutputFromFormat(writer, strResult, "NodeAnalyze", opts)
 }
 
 type SingleVersionPackageAnalyzeResult AnalyzeResult
 
-func (r SingleVersionPackageAnalyzeResult) OutputStruct() interface{} {
+func (r SingleVersionPackageAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.(map[string]PackageInfo)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]PackageInfo")
 		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
 	}
-	analysisOutput := getSingleVersionPackageOutput(analysis)
+	analysisOutput := getSingleVersionPackageOutput(analysis, opts)
 	output := struct {
 		Image       string
 		AnalyzeType string
@@ -116,13 +155,13 @@ func (r SingleVersionPackageAnalyzeResult) OutputStruct() interface{} {
 	return output
 }
 
-func (r SingleVersionPackageAnalyzeResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SingleVersionPackageAnalyzeResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.(map[string]PackageInfo)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type map[string]PackageInfo")
 		return fmt.Errorf("Could not output %s analysis result", r.AnalyzeType)
 	}
-	analysisOutput := getSingleVersionPackageOutput(analysis)
+	analysisOutput := getSingleVersionPackageOutput(analysis, opts)
 
 	strAnalysis := stringifyPackages(analysisOutput)
 	strResult := struct {
@@ -134,12 +173,12 @@ func (r SingleVersionPackageAnalyzeResult) OutputText(writer io.Writer, diffType
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strAnalysis,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageAnalyze", opts)
 }
 
 type SingleVersionPackageLayerAnalyzeResult AnalyzeResult
 
-func (r SingleVersionPackageLayerAnalyzeResult) OutputStruct() interface{} {
+func (r SingleVersionPackageLayerAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.(PackageLayerDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type PackageLayerDiff")
@@ -155,9 +194,9 @@ func (r SingleVersionPackageLayerAnalyzeResult) OutputStruct() interface{} {
 	var analysisOutput []PkgDiff
 	for _, d := range analysis.PackageDiffs {
 		diffOutput := PkgDiff{
-			Packages1: getSingleVersionPackageOutput(d.Packages1),
-			Packages2: getSingleVersionPackageOutput(d.Packages2),
-			InfoDiff:  getSingleVersionInfoDiffOutput(d.InfoDiff),
+			Packages1: getSingleVersionPackageOutput(d.Packages1, opts),
+			Packages2: getSingleVersionPackageOutput(d.Packages2, opts),
+			InfoDiff:  getSingleVersionInfoDiffOutput(d.InfoDiff, opts),
 		}
 		analysisOutput = append(analysisOutput, diffOutput)
 	}
@@ -174,7 +213,7 @@ func (r SingleVersionPackageLayerAnalyzeResult) OutputStruct() interface{} {
 	return output
 }
 
-func (r SingleVersionPackageLayerAnalyzeResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SingleVersionPackageLayerAnalyzeResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.(PackageLayerDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type PackageLayerDiff")
@@ -190,9 +229,9 @@ func (r SingleVersionPackageLayerAnalyzeResult) OutputText(writer io.Writer, dif
 	var analysisOutput []StrDiff
 	for _, d := range analysis.PackageDiffs {
 		diffOutput := StrDiff{
-			Packages1: stringifyPackages(getSingleVersionPackageOutput(d.Packages1)),
-			Packages2: stringifyPackages(getSingleVersionPackageOutput(d.Packages2)),
-			InfoDiff:  stringifyPackageDiff(getSingleVersionInfoDiffOutput(d.InfoDiff)),
+			Packages1: stringifyPackages(getSingleVersionPackageOutput(d.Packages1, opts)),
+			Packages2: stringifyPackages(getSingleVersionPackageOutput(d.Packages2, opts)),
+			InfoDiff:  stringifyPackageDiff(getSingleVersionInfoDiffOutput(d.InfoDiff, opts)),
 		}
 		analysisOutput = append(analysisOutput, diffOutput)
 	}
@@ -206,7 +245,7 @@ func (r SingleVersionPackageLayerAnalyzeResult) OutputText(writer io.Writer, dif
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    analysisOutput,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageLayerAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageLayerAnalyze", opts)
 }
 
 type PackageOutput struct {
@@ -214,15 +253,17 @@ type PackageOutput struct {
 	Path    string `json:",omitempty"`
 	Version string
 	Size    int64
+	Project string `json:",omitempty"`
+	Global  bool   `json:",omitempty"`
 }
 
-func getSingleVersionPackageOutput(packageMap map[string]PackageInfo) []PackageOutput {
+func getSingleVersionPackageOutput(packageMap map[string]PackageInfo, opts OutputOptions) []PackageOutput {
 	packages := []PackageOutput{}
 	for name, info := range packageMap {
 		packages = append(packages, PackageOutput{Name: name, Version: info.Version, Size: info.Size})
 	}
 
-	if SortSize {
+	if opts.SortSize {
 		packageBy(packageSizeSort).Sort(packages)
 	} else {
 		packageBy(packageNameSort).Sort(packages)
@@ -230,7 +271,7 @@ func getSingleVersionPackageOutput(packageMap map[string]PackageInfo) []PackageO
 	return packages
 }
 
-func getMultiVersionPackageOutput(packageMap map[string]map[string]PackageInfo) []PackageOutput {
+func getMultiVersionPackageOutput(packageMap map[string]map[string]PackageInfo, opts OutputOptions) []PackageOutput {
 	packages := []PackageOutput{}
 	for name, versionMap := range packageMap {
 		for path, info := range versionMap {
@@ -238,7 +279,7 @@ func getMultiVersionPackageOutput(packageMap map[string]map[string]PackageInfo)
 		}
 	}
 
-	if SortSize {
+	if opts.SortSize {
 		packageBy(packageSizeSort).Sort(packages)
 	} else {
 		packageBy(packageNameSort).Sort(packages)
@@ -248,34 +289,26 @@ func getMultiVersionPackageOutput(packageMap map[string]map[string]PackageInfo)
 
 type FileAnalyzeResult AnalyzeResult
 
-func (r FileAnalyzeResult) OutputStruct() interface{} {
+func (r FileAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.([]util.DirectoryEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []DirectoryEntry")
 		return errors.New("Could not output FileAnalyzer analysis result")
 	}
 
-	if SortSize {
-		directoryBy(directorySizeSort).Sort(analysis)
-	} else {
-		directoryBy(directoryNameSort).Sort(analysis)
-	}
+	sortDirectoryEntries(analysis, opts)
 	r.Analysis = analysis
 	return r
 }
 
-func (r FileAnalyzeResult) OutputText(writer io.Writer, analyzeType string, format string) error {
+func (r FileAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.([]util.DirectoryEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []DirectoryEntry")
 		return errors.New("Could not output FileAnalyzer analysis result")
 	}
 
-	if SortSize {
-		directoryBy(directorySizeSort).Sort(analysis)
-	} else {
-		directoryBy(directoryNameSort).Sort(analysis)
-	}
+	sortDirectoryEntries(analysis, opts)
 	strAnalysis := stringifyDirectoryEntries(analysis)
 
 	strResult := struct {
@@ -287,12 +320,12 @@ func (r FileAnalyzeResult) OutputText(writer io.Writer, analyzeType string, form
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strAnalysis,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "FileAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "FileAnalyze", opts)
 }
 
 type FileLayerAnalyzeResult AnalyzeResult
 
-func (r FileLayerAnalyzeResult) OutputStruct() interface{} {
+func (r FileLayerAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.([][]util.DirectoryEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []DirectoryEntry")
@@ -300,18 +333,14 @@ func (r FileLayerAnalyzeResult) OutputStruct() interface{} {
 	}
 
 	for _, a := range analysis {
-		if SortSize {
-			directoryBy(directorySizeSort).Sort(a)
-		} else {
-			directoryBy(directoryNameSort).Sort(a)
-		}
+		sortDirectoryEntries(a, opts)
 	}
 
 	r.Analysis = analysis
 	return r
 }
 
-func (r FileLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, format string) error {
+func (r FileLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.([][]util.DirectoryEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []DirectoryEntry")
@@ -321,11 +350,7 @@ func (r FileLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string,
 	var strDirectoryEntries [][]StrDirectoryEntry
 
 	for _, a := range analysis {
-		if SortSize {
-			directoryBy(directorySizeSort).Sort(a)
-		} else {
-			directoryBy(directoryNameSort).Sort(a)
-		}
+		sortDirectoryEntries(a, opts)
 		strAnalysis := stringifyDirectoryEntries(a)
 		strDirectoryEntries = append(strDirectoryEntries, strAnalysis)
 	}
@@ -339,12 +364,12 @@ func (r FileLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string,
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strDirectoryEntries,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "FileLayerAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "FileLayerAnalyze", opts)
 }
 
 type SizeAnalyzeResult AnalyzeResult
 
-func (r SizeAnalyzeResult) OutputStruct() interface{} {
+func (r SizeAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.([]SizeEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []SizeEntry")
@@ -354,7 +379,7 @@ func (r SizeAnalyzeResult) OutputStruct() interface{} {
 	return r
 }
 
-func (r SizeAnalyzeResult) OutputText(writer io.Writer, analyzeType string, format string) error {
+func (r SizeAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.([]SizeEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []SizeEntry")
@@ -372,12 +397,12 @@ func (r SizeAnalyzeResult) OutputText(writer io.Writer, analyzeType string, form
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strAnalysis,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SizeAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "SizeAnalyze", opts)
 }
 
 type SizeLayerAnalyzeResult AnalyzeResult
 
-func (r SizeLayerAnalyzeResult) OutputStruct() interface{} {
+func (r SizeLayerAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
 	analysis, valid := r.Analysis.([]SizeEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []SizeEntry")
@@ -387,7 +412,7 @@ func (r SizeLayerAnalyzeResult) OutputStruct() interface{} {
 	return r
 }
 
-func (r SizeLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, format string) error {
+func (r SizeLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
 	analysis, valid := r.Analysis.([]SizeEntry)
 	if !valid {
 		logrus.Error("Unexpected structure of Analysis.  Should be of type []SizeEntry")
@@ -405,5 +430,621 @@ func (r SizeLayerAnalyzeResult) OutputText(writer io.Writer, analyzeType string,
 		AnalyzeType: r.AnalyzeType,
 		Analysis:    strAnalysis,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SizeLayerAnalyze", format)
+	return TemplateOutputFromFormat(writer, strResult, "SizeLayerAnalyze", opts)
+}
+
+type AlternativesAnalyzeResult AnalyzeResult
+
+func (r AlternativesAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Alternative)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Alternative")
+		return errors.New("Could not output AlternativesAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r AlternativesAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]Alternative); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Alternative")
+		return errors.New("Could not output AlternativesAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AlternativesAnalyze", opts)
+}
+
+type AdvisoryAnalyzeResult AnalyzeResult
+
+func (r AdvisoryAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Advisory)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Advisory")
+		return errors.New("Could not output advisory analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r AdvisoryAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]Advisory); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Advisory")
+		return errors.New("Could not output advisory analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AdvisoryAnalyze", opts)
+}
+
+type ReproAnalyzeResult AnalyzeResult
+
+func (r ReproAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]ReproFinding)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []ReproFinding")
+		return errors.New("Could not output ReproAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r ReproAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.([]ReproFinding)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []ReproFinding")
+		return errors.New("Could not output ReproAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    []StrReproFinding
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+		Analysis:    stringifyReproFindings(analysis),
+	}
+	return TemplateOutputFromFormat(writer, strResult, "ReproAnalyze", opts)
+}
+
+type InitAnalyzeResult AnalyzeResult
+
+func (r InitAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(InitAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the InitAnalysis struct")
+		return errors.New("Could not output InitAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r InitAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(InitAnalysis); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the InitAnalysis struct")
+		return errors.New("Could not output InitAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "InitAnalyze", opts)
+}
+
+type ScoreAnalyzeResult AnalyzeResult
+
+func (r ScoreAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(Scorecard)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the Scorecard struct")
+		return errors.New("Could not output ScoreAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r ScoreAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.(Scorecard)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the Scorecard struct")
+		return errors.New("Could not output ScoreAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    struct {
+			Score      int
+			Categories []StrScoreCategory
+		}
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+	}
+	strResult.Analysis.Score = analysis.Score
+	strResult.Analysis.Categories = stringifyScoreCategories(analysis.Categories)
+	return TemplateOutputFromFormat(writer, strResult, "ScoreAnalyze", opts)
+}
+
+type AptDepsAnalyzeResult AnalyzeResult
+
+func (r AptDepsAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(DependencyAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the DependencyAnalysis struct")
+		return errors.New("Could not output AptDepsAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r AptDepsAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(DependencyAnalysis); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the DependencyAnalysis struct")
+		return errors.New("Could not output AptDepsAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AptDepsAnalyze", opts)
+}
+
+type SystemdAnalyzeResult AnalyzeResult
+
+func (r SystemdAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]SystemdUnit)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []SystemdUnit")
+		return errors.New("Could not output SystemdAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r SystemdAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]SystemdUnit); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []SystemdUnit")
+		return errors.New("Could not output SystemdAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "SystemdAnalyze", opts)
+}
+
+type RuntimesAnalyzeResult AnalyzeResult
+
+func (r RuntimesAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]LanguageRuntime)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []LanguageRuntime")
+		return errors.New("Could not output RuntimesAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r RuntimesAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]LanguageRuntime); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []LanguageRuntime")
+		return errors.New("Could not output RuntimesAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "RuntimesAnalyze", opts)
+}
+
+type ProvenanceAnalyzeResult AnalyzeResult
+
+func (r ProvenanceAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(ProvenanceAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the ProvenanceAnalysis struct")
+		return errors.New("Could not output ProvenanceAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r ProvenanceAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.(ProvenanceAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the ProvenanceAnalysis struct")
+		return errors.New("Could not output ProvenanceAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    struct {
+			Digest        string
+			Builders      []string
+			ImageVerified bool
+			Layers        []StrLayerProvenance
+		}
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+	}
+	strResult.Analysis.Digest = analysis.Digest
+	strResult.Analysis.Builders = analysis.Builders
+	strResult.Analysis.ImageVerified = analysis.ImageVerified
+	strResult.Analysis.Layers = stringifyLayerProvenance(analysis.Layers)
+	return TemplateOutputFromFormat(writer, strResult, "ProvenanceAnalyze", opts)
+}
+
+type RPMRepoAnalyzeResult AnalyzeResult
+
+func (r RPMRepoAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(RPMRepoAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the RPMRepoAnalysis struct")
+		return errors.New("Could not output RPMRepoAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r RPMRepoAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(RPMRepoAnalysis); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the RPMRepoAnalysis struct")
+		return errors.New("Could not output RPMRepoAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "RPMRepoAnalyze", opts)
+}
+
+type EntropyAnalyzeResult AnalyzeResult
+
+func (r EntropyAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(EntropyAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the EntropyAnalysis struct")
+		return errors.New("Could not output EntropyAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r EntropyAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.(EntropyAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the EntropyAnalysis struct")
+		return errors.New("Could not output EntropyAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    struct {
+			Size       string
+			Compressed string
+			Ratio      string
+			Duplicate  string
+			Dirs       []StrDirEntropy
+			Duplicates []StrDuplicateContent
+		}
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+	}
+	strResult.Analysis.Size = stringifySize(analysis.Size)
+	strResult.Analysis.Compressed = stringifySize(analysis.CompressedSize)
+	strResult.Analysis.Ratio = compressionRatio(analysis.Size, analysis.CompressedSize)
+	strResult.Analysis.Duplicate = stringifySize(analysis.DuplicateSize)
+	strResult.Analysis.Dirs = stringifyDirEntropies(analysis.Dirs)
+	strResult.Analysis.Duplicates = stringifyDuplicateContents(analysis.Duplicates)
+	return TemplateOutputFromFormat(writer, strResult, "EntropyAnalyze", opts)
+}
+
+type LinkerAnalyzeResult AnalyzeResult
+
+func (r LinkerAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(LinkerAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the LinkerAnalysis struct")
+		return errors.New("Could not output LinkerAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r LinkerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(LinkerAnalysis); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the LinkerAnalysis struct")
+		return errors.New("Could not output LinkerAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "LinkerAnalyze", opts)
+}
+
+type ConffileAnalyzeResult AnalyzeResult
+
+func (r ConffileAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(ConffileAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the ConffileAnalysis struct")
+		return errors.New("Could not output ConffilesAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r ConffileAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.(ConffileAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the ConffileAnalysis struct")
+		return errors.New("Could not output ConffilesAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    struct {
+			Packages []ConfigPackage
+			Files    []StrLeftoverFile
+			Size     string
+		}
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+	}
+	strResult.Analysis.Packages = analysis.Packages
+	strResult.Analysis.Files = stringifyLeftoverFiles(analysis.Files)
+	strResult.Analysis.Size = stringifySize(analysis.Size)
+	return TemplateOutputFromFormat(writer, strResult, "ConffileAnalyze", opts)
+}
+
+type OSAnalyzeResult AnalyzeResult
+
+func (r OSAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(OSRelease)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the OSRelease struct")
+		return errors.New("Could not output OSAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r OSAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(OSRelease); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the OSRelease struct")
+		return errors.New("Could not output OSAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "OSAnalyze", opts)
+}
+
+type BuildInfoAnalyzeResult AnalyzeResult
+
+func (r BuildInfoAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(BuildInfo)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the BuildInfo struct")
+		return errors.New("Could not output BuildInfoAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r BuildInfoAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(BuildInfo); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the BuildInfo struct")
+		return errors.New("Could not output BuildInfoAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "BuildInfoAnalyze", opts)
+}
+
+type PermissionAnalyzeResult AnalyzeResult
+
+func (r PermissionAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]FilePermission)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []FilePermission")
+		return errors.New("Could not output PermissionsAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r PermissionAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]FilePermission); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []FilePermission")
+		return errors.New("Could not output PermissionsAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "PermissionAnalyze", opts)
+}
+
+type VolumeAnalyzeResult AnalyzeResult
+
+func (r VolumeAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]VolumeContent)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []VolumeContent")
+		return errors.New("Could not output VolumesAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r VolumeAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.([]VolumeContent)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []VolumeContent")
+		return errors.New("Could not output VolumesAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    struct {
+			Volumes []StrVolumeContent
+			Files   []StrDirectoryEntry
+		}
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+	}
+	strResult.Analysis.Volumes, strResult.Analysis.Files = stringifyVolumeContents(analysis)
+	return TemplateOutputFromFormat(writer, strResult, "VolumeAnalyze", opts)
+}
+
+type SecretAnalyzeResult AnalyzeResult
+
+func (r SecretAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Secret)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Secret")
+		return errors.New("Could not output SecretsAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r SecretAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	analysis, valid := r.Analysis.([]Secret)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Secret")
+		return errors.New("Could not output SecretsAnalyzer analysis result")
+	}
+	strResult := struct {
+		Image       string
+		AnalyzeType string
+		Analysis    []StrSecret
+	}{
+		Image:       r.Image,
+		AnalyzeType: r.AnalyzeType,
+		Analysis:    stringifySecrets(analysis),
+	}
+	return TemplateOutputFromFormat(writer, strResult, "SecretAnalyze", opts)
+}
+
+type BinaryAnalyzeResult AnalyzeResult
+
+func (r BinaryAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]ELFObject)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []ELFObject")
+		return errors.New("Could not output BinaryAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r BinaryAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]ELFObject); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []ELFObject")
+		return errors.New("Could not output BinaryAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "BinaryAnalyze", opts)
+}
+
+type KeyringAnalyzeResult AnalyzeResult
+
+func (r KeyringAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]SigningKey)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []SigningKey")
+		return errors.New("Could not output KeyringAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r KeyringAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]SigningKey); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []SigningKey")
+		return errors.New("Could not output KeyringAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "KeyringAnalyze", opts)
+}
+
+type ServiceAnalyzeResult AnalyzeResult
+
+func (r ServiceAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Service)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Service")
+		return errors.New("Could not output ServicesAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r ServiceAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]Service); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Service")
+		return errors.New("Could not output ServicesAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "ServiceAnalyze", opts)
+}
+
+type CronAnalyzeResult AnalyzeResult
+
+func (r CronAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]CronJob)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []CronJob")
+		return errors.New("Could not output CronAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r CronAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]CronJob); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []CronJob")
+		return errors.New("Could not output CronAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "CronAnalyze", opts)
+}
+
+type UserAnalyzeResult AnalyzeResult
+
+func (r UserAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.(UserAccounts)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type UserAccounts")
+		return errors.New("Could not output UsersAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r UserAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.(UserAccounts); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type UserAccounts")
+		return errors.New("Could not output UsersAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "UserAnalyze", opts)
+}
+
+type CertificateAnalyzeResult AnalyzeResult
+
+func (r CertificateAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Certificate)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Certificate")
+		return errors.New("Could not output CertsAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r CertificateAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	if _, valid := r.Analysis.([]Certificate); !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Certificate")
+		return errors.New("Could not output CertsAnalyzer analysis result")
+	}
+	return TemplateOutputFromFormat(writer, r, "CertificateAnalyze", opts)
+}
+
+type BannerAnalyzeResult AnalyzeResult
+
+func (r BannerAnalyzeResult) OutputStruct(opts OutputOptions) interface{} {
+	analysis, valid := r.Analysis.([]Banner)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Banner")
+		return errors.New("Could not output BannersAnalyzer analysis result")
+	}
+	r.Analysis = analysis
+	return r
+}
+
+func (r BannerAnalyzeResult) OutputText(writer io.Writer, analyzeType string, opts OutputOptions) error {
+	banners, valid := r.Analysis.([]Banner)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []Banner")
+		return errors.New("Could not output BannersAnalyzer analysis result")
+	}
+	if err := TemplateOutputFromFormat(writer, r, "BannerAnalyze", opts); err != nil || opts.Format != "" {
+		return err
+	}
+	// written as is, as aligning columns would rewrite the tabs of the banners
+	for _, b := range banners {
+		if containsKind(b, BannerMotdScript) {
+			continue
+		}
+		if _, err := fmt.Fprintf(writer, "Contents of %s:\n%s\n", b.Path, strings.TrimSuffix(b.Content, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
 }
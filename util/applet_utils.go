@@ -0,0 +1,130 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path"
+	"sort"
+)
+
+// AppletThreshold is the number of changed symlinks pointing at the same
+// binary above which they are reported as a single applet group.
+const AppletThreshold = 10
+
+// AppletLinks summarizes the changes to a farm of symlinks pointing at one
+// multi-call binary such as busybox. Updated is set when the binary itself
+// was modified.
+type AppletLinks struct {
+	Target    string
+	Updated   bool
+	Added     []string
+	Removed   []string
+	Repointed []string
+}
+
+// LinkTarget resolves the symlink at name to an absolute path within the
+// image, returning false if name is not a symlink.
+type LinkTarget func(name string) (string, bool)
+
+// ResolveLinkTarget makes a symlink target absolute relative to the link.
+func ResolveLinkTarget(name, target string) string {
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(path.Dir(name), target)
+}
+
+// GroupAppletLinks moves symlink changes sharing a target out of the
+// individual adds, dels and mods of diff and into applet groups, once at
+// least AppletThreshold links to the target changed.
+func GroupAppletLinks(diff DirDiff, target1, target2 LinkTarget) DirDiff {
+	groups := map[string]*AppletLinks{}
+	group := func(target string) *AppletLinks {
+		g, ok := groups[target]
+		if !ok {
+			g = &AppletLinks{Target: target, Added: []string{}, Removed: []string{}, Repointed: []string{}}
+			groups[target] = g
+		}
+		return g
+	}
+	for _, entry := range diff.Adds {
+		if target, ok := target2(entry.Name); ok {
+			g := group(target)
+			g.Added = append(g.Added, entry.Name)
+		}
+	}
+	for _, entry := range diff.Dels {
+		if target, ok := target1(entry.Name); ok {
+			g := group(target)
+			g.Removed = append(g.Removed, entry.Name)
+		}
+	}
+	for _, entry := range diff.Mods {
+		if _, ok := target1(entry.Name); !ok {
+			continue
+		}
+		if target, ok := target2(entry.Name); ok {
+			g := group(target)
+			g.Repointed = append(g.Repointed, entry.Name)
+		}
+	}
+
+	grouped := map[string]bool{}
+	for target, g := range groups {
+		if len(g.Added)+len(g.Removed)+len(g.Repointed) < AppletThreshold {
+			delete(groups, target)
+			continue
+		}
+		for _, names := range [][]string{g.Added, g.Removed, g.Repointed} {
+			for _, name := range names {
+				grouped[name] = true
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return diff
+	}
+
+	result := DirDiff{}
+	for _, entry := range diff.Adds {
+		if !grouped[entry.Name] {
+			result.Adds = append(result.Adds, entry)
+		}
+	}
+	for _, entry := range diff.Dels {
+		if !grouped[entry.Name] {
+			result.Dels = append(result.Dels, entry)
+		}
+	}
+	for _, entry := range diff.Mods {
+		if grouped[entry.Name] {
+			continue
+		}
+		if g, ok := groups[entry.Name]; ok {
+			g.Updated = true
+		}
+		result.Mods = append(result.Mods, entry)
+	}
+	for _, g := range groups {
+		sort.Strings(g.Added)
+		sort.Strings(g.Removed)
+		sort.Strings(g.Repointed)
+		result.Applets = append(result.Applets, *g)
+	}
+	sort.Slice(result.Applets, func(i, j int) bool { return result.Applets[i].Target < result.Applets[j].Target })
+	return result
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func TestGroupAppletLinks(t *testing.T) {
+	links1 := map[string]string{}
+	links2 := map[string]string{}
+	diff := DirDiff{
+		Adds: []pkgutil.DirectoryEntry{{Name: "/bin/newapplet", Size: 7}, {Name: "/etc/new.conf", Size: 3}},
+		Dels: []pkgutil.DirectoryEntry{{Name: "/bin/oldapplet", Size: 12}},
+		Mods: []EntryDiff{{Name: "/bin/busybox", Size1: 100, Size2: 120}, {Name: "/usr/bin/python", Size1: 9, Size2: 9}},
+	}
+	links2["/bin/newapplet"] = "/bin/busybox"
+	links1["/bin/oldapplet"] = "/bin/busybox"
+	links1["/usr/bin/python"] = "python2"
+	links2["/usr/bin/python"] = "python3"
+	repointed := []string{}
+	for i := 0; i < AppletThreshold; i++ {
+		name := fmt.Sprintf("/bin/applet%d", i)
+		links1[name] = "/bin/busybox.old"
+		links2[name] = "busybox"
+		diff.Mods = append(diff.Mods, EntryDiff{Name: name, Size1: 16, Size2: 7})
+		repointed = append(repointed, name)
+	}
+	target := func(links map[string]string) LinkTarget {
+		return func(name string) (string, bool) {
+			l, ok := links[name]
+			if !ok {
+				return "", false
+			}
+			return ResolveLinkTarget(name, l), true
+		}
+	}
+
+	expected := DirDiff{
+		Adds: []pkgutil.DirectoryEntry{{Name: "/etc/new.conf", Size: 3}},
+		Mods: []EntryDiff{{Name: "/bin/busybox", Size1: 100, Size2: 120}, {Name: "/usr/bin/python", Size1: 9, Size2: 9}},
+		Applets: []AppletLinks{{
+			Target:    "/bin/busybox",
+			Updated:   true,
+			Added:     []string{"/bin/newapplet"},
+			Removed:   []string{"/bin/oldapplet"},
+			Repointed: repointed,
+		}},
+	}
+	actual := GroupAppletLinks(diff, target(links1), target(links2))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+
+	small := DirDiff{Mods: []EntryDiff{{Name: "/usr/bin/python", Size1: 9, Size2: 9}}}
+	if actual := GroupAppletLinks(small, target(links1), target(links2)); !reflect.DeepEqual(actual, small) {
+		t.Errorf("Expected: %v but got: %v", small, actual)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+)
+
+// DependencyNode is an installed package of a dependency graph. Depends only
+// lists installed packages, with alternatives and virtual packages resolved.
+// Auto is set for packages apt installed to satisfy a dependency, Protected
+// for essential and required packages which apt never removes on its own.
+type DependencyNode struct {
+	Depends   []string
+	Auto      bool
+	Protected bool
+}
+
+// DependencyGraph maps installed package names to their dependencies.
+type DependencyGraph map[string]DependencyNode
+
+// PackageDependencies describes a package in a dependency analysis.
+type PackageDependencies struct {
+	Name       string
+	Depends    []string
+	RequiredBy []string
+}
+
+// DependencyAnalysis holds the dependency structure of an image. Roots are
+// the packages no other package depends on, and Removable the automatically
+// installed packages which nothing installed by hand still needs, as listed
+// by apt-get autoremove.
+type DependencyAnalysis struct {
+	Roots     []string
+	Removable []string
+	Packages  []PackageDependencies
+}
+
+// PackageReason explains why a package was added: the packages requiring it.
+// An empty RequiredBy means the package is a new root.
+type PackageReason struct {
+	Name       string
+	RequiredBy []string
+}
+
+// ReverseDependencyChange lists the packages which started or stopped
+// depending on a package present in both images.
+type ReverseDependencyChange struct {
+	Name    string
+	Added   []string
+	Removed []string
+}
+
+// DependencyDiff reports the structural changes between two dependency
+// graphs. Removable lists the packages of the second image apt-get
+// autoremove would remove.
+type DependencyDiff struct {
+	NewRoots           []string
+	Added              []PackageReason
+	ReverseDepsChanged []ReverseDependencyChange
+	Removable          []string
+}
+
+// ReverseDependencies maps each package to the sorted list of packages
+// depending on it.
+func (g DependencyGraph) ReverseDependencies() map[string][]string {
+	reverse := map[string][]string{}
+	for name := range g {
+		reverse[name] = []string{}
+	}
+	for name, node := range g {
+		for _, dep := range node.Depends {
+			if _, ok := g[dep]; ok && dep != name {
+				reverse[dep] = append(reverse[dep], name)
+			}
+		}
+	}
+	for name := range reverse {
+		sort.Strings(reverse[name])
+	}
+	return reverse
+}
+
+// Roots lists the packages no other installed package depends on.
+func (g DependencyGraph) Roots() []string {
+	roots := []string{}
+	for name, requiredBy := range g.ReverseDependencies() {
+		if len(requiredBy) == 0 {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Removable lists the automatically installed packages which can't be
+// reached from a manually installed or protected package.
+func (g DependencyGraph) Removable() []string {
+	needed := map[string]bool{}
+	var visit func(string)
+	visit = func(name string) {
+		if needed[name] {
+			return
+		}
+		needed[name] = true
+		for _, dep := range g[name].Depends {
+			if _, ok := g[dep]; ok {
+				visit(dep)
+			}
+		}
+	}
+	for name, node := range g {
+		if !node.Auto || node.Protected {
+			visit(name)
+		}
+	}
+	removable := []string{}
+	for name := range g {
+		if !needed[name] {
+			removable = append(removable, name)
+		}
+	}
+	sort.Strings(removable)
+	return removable
+}
+
+// GetDependencyAnalysis summarizes the dependency structure of a graph.
+func GetDependencyAnalysis(g DependencyGraph) DependencyAnalysis {
+	reverse := g.ReverseDependencies()
+	packages := []PackageDependencies{}
+	for name, node := range g {
+		depends := []string{}
+		for _, dep := range node.Depends {
+			if _, ok := g[dep]; ok && dep != name {
+				depends = append(depends, dep)
+			}
+		}
+		sort.Strings(depends)
+		packages = append(packages, PackageDependencies{Name: name, Depends: depends, RequiredBy: reverse[name]})
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return DependencyAnalysis{
+		Roots:     g.Roots(),
+		Removable: g.Removable(),
+		Packages:  packages,
+	}
+}
+
+// GetDependencyDiff compares the dependency graphs of two images.
+func GetDependencyDiff(g1, g2 DependencyGraph) DependencyDiff {
+	diff := DependencyDiff{
+		NewRoots:           []string{},
+		Added:              []PackageReason{},
+		ReverseDepsChanged: []ReverseDependencyChange{},
+		Removable:          g2.Removable(),
+	}
+	roots1 := map[string]bool{}
+	for _, root := range g1.Roots() {
+		roots1[root] = true
+	}
+	for _, root := range g2.Roots() {
+		if !roots1[root] {
+			diff.NewRoots = append(diff.NewRoots, root)
+		}
+	}
+
+	reverse1, reverse2 := g1.ReverseDependencies(), g2.ReverseDependencies()
+	for name, requiredBy2 := range reverse2 {
+		requiredBy1, ok := reverse1[name]
+		if !ok {
+			diff.Added = append(diff.Added, PackageReason{Name: name, RequiredBy: requiredBy2})
+			continue
+		}
+		added, removed := stringSetDiff(requiredBy1, requiredBy2)
+		if len(added) != 0 || len(removed) != 0 {
+			diff.ReverseDepsChanged = append(diff.ReverseDepsChanged, ReverseDependencyChange{Name: name, Added: added, Removed: removed})
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Name < diff.Added[j].Name })
+	sort.Slice(diff.ReverseDepsChanged, func(i, j int) bool {
+		return diff.ReverseDepsChanged[i].Name < diff.ReverseDepsChanged[j].Name
+	})
+	return diff
+}
+
+// stringSetDiff returns the sorted strings only in list2, then those only
+// in list1.
+func stringSetDiff(list1, list2 []string) ([]string, []string) {
+	set1, set2 := map[string]bool{}, map[string]bool{}
+	for _, s := range list1 {
+		set1[s] = true
+	}
+	for _, s := range list2 {
+		set2[s] = true
+	}
+	added, removed := []string{}, []string{}
+	for s := range set2 {
+		if !set1[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range set1 {
+		if !set2[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
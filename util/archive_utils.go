@@ -0,0 +1,244 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ArchiveSeparator joins the path of an archive to the entries inside it.
+const ArchiveSeparator = "!/"
+
+// ArchiveDiff lists the entries which changed inside an archive present in
+// both images. The entries of nested archives are reported in their own
+// ArchiveDiff, named by joining the archive paths with ArchiveSeparator.
+type ArchiveDiff struct {
+	Archive string
+	Adds    []pkgutil.DirectoryEntry
+	Dels    []pkgutil.DirectoryEntry
+	Mods    []EntryDiff
+}
+
+// ArchiveOptions selects the archives to descend into. Depth is the number
+// of archive levels to open; zero disables archive inspection.
+type ArchiveOptions struct {
+	Extensions []string
+	Depth      int
+}
+
+// IsArchive reports whether name has one of the selected extensions.
+func (o ArchiveOptions) IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range o.Extensions {
+		if strings.HasSuffix(name, strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+type archiveEntry struct {
+	size int64
+	sum  string
+	// data is only kept for nested archives which will be opened in turn
+	data []byte
+}
+
+// DiffArchives diffs the entries of two versions of the archive called name,
+// read from path1 and path2, descending into nested archives as allowed by
+// opts.
+func DiffArchives(name, path1, path2 string, opts ArchiveOptions) ([]ArchiveDiff, error) {
+	if opts.Depth <= 0 || !opts.IsArchive(name) {
+		return nil, nil
+	}
+	data1, err := ioutil.ReadFile(path1)
+	if err != nil {
+		return nil, err
+	}
+	data2, err := ioutil.ReadFile(path2)
+	if err != nil {
+		return nil, err
+	}
+	return diffArchiveData(name, data1, data2, opts, opts.Depth)
+}
+
+func diffArchiveData(name string, data1, data2 []byte, opts ArchiveOptions, depth int) ([]ArchiveDiff, error) {
+	keep := func(entry string) bool { return depth > 1 && opts.IsArchive(entry) }
+	entries1, err := readArchive(name, data1, keep)
+	if err != nil {
+		return nil, err
+	}
+	entries2, err := readArchive(name, data2, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := ArchiveDiff{Archive: name}
+	nested := []ArchiveDiff{}
+	for entry, e1 := range entries1 {
+		e2, ok := entries2[entry]
+		if !ok {
+			diff.Dels = append(diff.Dels, pkgutil.DirectoryEntry{Name: entry, Size: e1.size})
+			continue
+		}
+		if e1.size == e2.size && e1.sum == e2.sum {
+			continue
+		}
+		diff.Mods = append(diff.Mods, EntryDiff{Name: entry, Size1: e1.size, Size2: e2.size})
+		if keep(entry) {
+			inner, err := diffArchiveData(name+ArchiveSeparator+entry, e1.data, e2.data, opts, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			nested = append(nested, inner...)
+		}
+	}
+	for entry, e2 := range entries2 {
+		if _, ok := entries1[entry]; !ok {
+			diff.Adds = append(diff.Adds, pkgutil.DirectoryEntry{Name: entry, Size: e2.size})
+		}
+	}
+	if len(diff.Adds) == 0 && len(diff.Dels) == 0 && len(diff.Mods) == 0 {
+		return nested, nil
+	}
+	sort.Slice(diff.Adds, func(i, j int) bool { return diff.Adds[i].Name < diff.Adds[j].Name })
+	sort.Slice(diff.Dels, func(i, j int) bool { return diff.Dels[i].Name < diff.Dels[j].Name })
+	sort.Slice(diff.Mods, func(i, j int) bool { return diff.Mods[i].Name < diff.Mods[j].Name })
+	return append([]ArchiveDiff{diff}, nested...), nil
+}
+
+// readArchive lists the regular files of a zip or tar archive, which may be
+// gzip compressed. The contents of entries selected by keep are retained.
+func readArchive(name string, data []byte, keep func(string) bool) (map[string]archiveEntry, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")) {
+		return readZip(name, data, keep)
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", name)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readTar(name, r, keep)
+}
+
+func readZip(name string, data []byte, keep func(string) bool) (map[string]archiveEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", name)
+	}
+	entries := map[string]archiveEntry{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		// the CRC stored in the zip directory is enough to compare entries
+		entry := archiveEntry{size: int64(f.UncompressedSize64), sum: fmt.Sprintf("%08x", f.CRC32)}
+		if keep(f.Name) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading %s in %s", f.Name, name)
+			}
+			entry.data, err = ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading %s in %s", f.Name, name)
+			}
+		}
+		entries[f.Name] = entry
+	}
+	return entries, nil
+}
+
+func readTar(name string, r io.Reader, keep func(string) bool) (map[string]archiveEntry, error) {
+	tr := tar.NewReader(r)
+	entries := map[string]archiveEntry{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", name)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		entryName := strings.TrimPrefix(header.Name, "./")
+		h := sha256.New()
+		w := io.Writer(h)
+		var buf bytes.Buffer
+		if keep(entryName) {
+			w = io.MultiWriter(h, &buf)
+		}
+		size, err := io.Copy(w, tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s in %s", entryName, name)
+		}
+		entry := archiveEntry{size: size, sum: hex.EncodeToString(h.Sum(nil))}
+		if keep(entryName) {
+			entry.data = buf.Bytes()
+		}
+		entries[entryName] = entry
+	}
+	return entries, nil
+}
+
+// DiffModifiedArchives descends into the archives among the modified
+// entries of diff, whose files are found below root1 and root2.
+// Archives which can't be read are skipped with a warning.
+func DiffModifiedArchives(diff DirDiff, root1, root2 string, opts ArchiveOptions) []ArchiveDiff {
+	var archives []ArchiveDiff
+	for _, mod := range diff.Mods {
+		if !opts.IsArchive(mod.Name) {
+			continue
+		}
+		path1, path2 := filepath.Join(root1, mod.Name), filepath.Join(root2, mod.Name)
+		if info, err := os.Lstat(path1); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if info, err := os.Lstat(path2); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		changes, err := DiffArchives(mod.Name, path1, path2, opts)
+		if err != nil {
+			logrus.Warningf("Error diffing archive %s: %s", mod.Name, err)
+			continue
+		}
+		archives = append(archives, changes...)
+	}
+	return archives
+}
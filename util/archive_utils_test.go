@@ -0,0 +1,118 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+type testEntry struct {
+	name    string
+	content []byte
+}
+
+func makeZip(t *testing.T, entries ...testEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatalf("Error creating zip entry: %s", err)
+		}
+		w.Write(e.content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Error writing zip: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func makeTgz(t *testing.T, entries ...testEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		tw.WriteHeader(&tar.Header{Name: "./" + e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg})
+		tw.Write(e.content)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestDiffArchiveData(t *testing.T) {
+	lib1 := makeZip(t, testEntry{"com/example/Util.class", []byte("v1")}, testEntry{"META-INF/MANIFEST.MF", []byte("m")})
+	lib2 := makeZip(t, testEntry{"com/example/Util.class", []byte("v2")}, testEntry{"META-INF/MANIFEST.MF", []byte("m")})
+	app1 := makeZip(t,
+		testEntry{"BOOT-INF/lib/util.jar", lib1},
+		testEntry{"BOOT-INF/classes/App.class", []byte("app")},
+		testEntry{"BOOT-INF/classes/Old.class", []byte("old")},
+	)
+	app2 := makeZip(t,
+		testEntry{"BOOT-INF/lib/util.jar", lib2},
+		testEntry{"BOOT-INF/classes/App.class", []byte("app")},
+		testEntry{"BOOT-INF/classes/New.class", []byte("new!")},
+	)
+	opts := ArchiveOptions{Extensions: []string{".jar", ".tgz"}, Depth: 2}
+
+	expected := []ArchiveDiff{
+		{
+			Archive: "/app/app.jar",
+			Adds:    []pkgutil.DirectoryEntry{{Name: "BOOT-INF/classes/New.class", Size: 4}},
+			Dels:    []pkgutil.DirectoryEntry{{Name: "BOOT-INF/classes/Old.class", Size: 3}},
+			Mods:    []EntryDiff{{Name: "BOOT-INF/lib/util.jar", Size1: int64(len(lib1)), Size2: int64(len(lib2))}},
+		},
+		{
+			Archive: "/app/app.jar!/BOOT-INF/lib/util.jar",
+			Mods:    []EntryDiff{{Name: "com/example/Util.class", Size1: 2, Size2: 2}},
+		},
+	}
+	actual, err := diffArchiveData("/app/app.jar", app1, app2, opts, opts.Depth)
+	if err != nil {
+		t.Fatalf("Error diffing archives: %s", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+
+	// a depth of one only lists the outer archive
+	actual, err = diffArchiveData("/app/app.jar", app1, app2, opts, 1)
+	if err != nil {
+		t.Fatalf("Error diffing archives: %s", err)
+	}
+	if !reflect.DeepEqual(actual, expected[:1]) {
+		t.Errorf("Expected: %v but got: %v", expected[:1], actual)
+	}
+
+	tgz1 := makeTgz(t, testEntry{"package/index.js", []byte("module.exports = 1")})
+	tgz2 := makeTgz(t, testEntry{"package/index.js", []byte("module.exports = 2")})
+	actual, err = diffArchiveData("/vendor/pkg.tgz", tgz1, tgz2, opts, 1)
+	if err != nil {
+		t.Fatalf("Error diffing archives: %s", err)
+	}
+	expected = []ArchiveDiff{{Archive: "/vendor/pkg.tgz", Mods: []EntryDiff{{Name: "package/index.js", Size1: 18, Size2: 18}}}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestRegistryRequests(t *testing.T) {
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	manifest, _ := img.RawManifest()
+	blobs := map[string][]byte{}
+	config, _ := img.RawConfigFile()
+	configDigest, _ := img.ConfigName()
+	blobs[configDigest.String()] = config
+	layers, _ := img.Layers()
+	for _, layer := range layers {
+		digest, _ := layer.Digest()
+		reader, _ := layer.Compressed()
+		blobs[digest.String()], _ = ioutil.ReadAll(reader)
+	}
+	registry := serveRegistry(map[string][]byte{"v1": manifest}, blobs)
+	defer registry.Close()
+	var mu sync.Mutex
+	var headers []http.Header
+	// blobs are redirected to a signed URL, as storage backends do
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+		if strings.Contains(r.URL.Path, "/blobs/") && r.URL.RawQuery == "" {
+			http.Redirect(w, r, r.URL.Path+"?X-Amz-Signature=secret-signature&expires=60", http.StatusTemporaryRedirect)
+			return
+		}
+		registry.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	auditLogPath := filepath.Join(dir, "audit.jsonl")
+	audit, err := os.Create(auditLogPath)
+	if err != nil {
+		t.Fatalf("Error creating audit log: %s", err)
+	}
+	defer audit.Close()
+	defer pkgutil.ConfigureRequests("", nil, nil)
+	userAgent := "container-diff-test/1.0"
+	pkgutil.ConfigureRequests(userAgent, map[string]string{"X-Request-Source": "ci"}, audit)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	image, err := pkgutil.GetImage("remote://"+host+"/app:v1", true, "")
+	if err != nil {
+		t.Fatalf("Error retrieving image: %s", err)
+	}
+	pkgutil.CleanupImage(image)
+
+	if len(headers) == 0 {
+		t.Fatalf("Expected requests to the registry")
+	}
+	for _, header := range headers {
+		if header.Get("User-Agent") != userAgent || header.Get("X-Request-Source") != "ci" {
+			t.Errorf("Expected User-Agent %s and X-Request-Source ci but got %v", userAgent, header)
+		}
+	}
+
+	log, err := ioutil.ReadFile(auditLogPath)
+	if err != nil {
+		t.Fatalf("Error reading audit log: %s", err)
+	}
+	if bytes.Contains(log, []byte("secret-signature")) {
+		t.Errorf("Expected the blob signatures redacted from the audit log:\n%s", log)
+	}
+	var manifestEntry, blobEntry *pkgutil.AuditEntry
+	for _, line := range bytes.Split(bytes.TrimSpace(log), []byte("\n")) {
+		var entry pkgutil.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("Error parsing audit log line %s: %s", line, err)
+		}
+		if entry.Method == "" || entry.Time.IsZero() || entry.URL == "" {
+			t.Errorf("Expected the time, method and URL of a request but got %s", line)
+		}
+		switch {
+		case strings.HasSuffix(entry.URL, "/v2/app/manifests/v1") && entry.Status == http.StatusOK:
+			manifestEntry = &entry
+		case strings.Contains(entry.URL, "X-Amz-Signature=REDACTED") && entry.Status == http.StatusOK:
+			blobEntry = &entry
+		}
+	}
+	if manifestEntry == nil || manifestEntry.Bytes != int64(len(manifest)) {
+		t.Errorf("Expected the manifest request logged with %d bytes but got:\n%s", len(manifest), log)
+	}
+	if blobEntry == nil || blobEntry.Bytes == 0 {
+		t.Errorf("Expected the redirected blob request logged but got:\n%s", log)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func TestRegistryAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if (user != "ci" || password != "secret") && r.Header.Get("Authorization") != "Bearer token" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/app/tags/list":
+			w.Write([]byte(`{"name": "app", "tags": ["v1.0.0", "v1.1.0"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	// no credentials from the Docker config of the user running the tests
+	config, err := ioutil.TempDir("", "docker-config")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(config)
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", config)
+	defer pkgutil.ConfigureRegistryAuth("", "", "", nil)
+
+	testCases := []struct {
+		descrip     string
+		username    string
+		password    string
+		token       string
+		registries  []string
+		shouldError bool
+	}{
+		{descrip: "anonymous", shouldError: true},
+		{descrip: "username and password", username: "ci", password: "secret"},
+		{descrip: "token", token: "token"},
+		{descrip: "credentials for the registry", username: "ci", password: "secret", registries: []string{host}},
+		{descrip: "credentials for another registry", username: "ci", password: "secret", registries: []string{"registry.example.com"}, shouldError: true},
+	}
+	for _, test := range testCases {
+		if err := pkgutil.ConfigureRegistryAuth(test.username, test.password, test.token, test.registries); err != nil {
+			t.Errorf("%s: unexpected error configuring auth: %s", test.descrip, err)
+			continue
+		}
+		tags, err := pkgutil.ListTags("remote://" + host + "/app:v1.1.0")
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", test.descrip, tags)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.descrip, err)
+		} else if expected := []pkgutil.RegistryTag{{Tag: "v1.0.0"}, {Tag: "v1.1.0"}}; !reflect.DeepEqual(tags, expected) {
+			t.Errorf("%s: expected tags %v but got %v", test.descrip, expected, tags)
+		}
+	}
+
+	if err := pkgutil.ConfigureRegistryAuth("ci", "", "token", nil); err == nil {
+		t.Errorf("Expected an error combining a username and a token")
+	}
+	if err := pkgutil.ConfigureRegistryAuth("", "secret", "", nil); err == nil {
+		t.Errorf("Expected an error for a password without a username")
+	}
+}
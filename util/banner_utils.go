@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of banner files: shown after login, shown before a console or
+// telnet login, generating the message of the day, and sent by sshd before
+// authentication.
+const (
+	BannerMotd       = "motd"
+	BannerIssue      = "issue"
+	BannerIssueNet   = "issue.net"
+	BannerMotdScript = "update-motd"
+	BannerSSH        = "sshd"
+)
+
+// Banner stores a message an image shows to its users. Kinds lists how the
+// file is shown, since sshd commonly sends /etc/issue.net as its banner.
+// Package is the dpkg package shipping the file as a conffile, and Default
+// whether the file still holds the content that package ships.
+type Banner struct {
+	Path    string
+	Kinds   []string
+	Size    int64
+	Package string `json:",omitempty"`
+	Default bool
+	Content string
+}
+
+// BannerDiff stores the banners added to and removed from the second image,
+// and a unified diff of those whose content changed. Warnings flag banners
+// the second image removed, emptied or reset to the default of their
+// package, and sshd no longer sending a banner.
+type BannerDiff struct {
+	Warnings []string
+	Added    []Banner
+	Removed  []Banner
+	Changed  []ContentDiff
+}
+
+// GetBannerDiff compares the banners of two images by path.
+func GetBannerDiff(banners1, banners2 []Banner) (BannerDiff, error) {
+	diff := BannerDiff{
+		Warnings: []string{},
+		Added:    []Banner{},
+		Removed:  []Banner{},
+		Changed:  []ContentDiff{},
+	}
+	byPath1 := map[string]Banner{}
+	for _, b := range banners1 {
+		byPath1[b.Path] = b
+	}
+	byPath2 := map[string]Banner{}
+	for _, b := range banners2 {
+		byPath2[b.Path] = b
+	}
+	for _, b1 := range banners1 {
+		if _, ok := byPath2[b1.Path]; !ok {
+			diff.Removed = append(diff.Removed, b1)
+			if !containsKind(b1, BannerMotdScript) {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s banner %s removed", strings.Join(b1.Kinds, ", "), b1.Path))
+			}
+		}
+	}
+	for _, b2 := range banners2 {
+		b1, ok := byPath1[b2.Path]
+		if !ok {
+			diff.Added = append(diff.Added, b2)
+			continue
+		}
+		if b1.Content == b2.Content {
+			continue
+		}
+		text, err := unifiedPatch(b2.Path, patchLines(b1.Content), patchLines(b2.Content))
+		if err != nil {
+			return diff, errors.Wrapf(err, "diffing %s", b2.Path)
+		}
+		diff.Changed = append(diff.Changed, ContentDiff{Name: b2.Path, Diff: text})
+		switch {
+		case strings.TrimSpace(b2.Content) == "":
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s banner %s emptied", strings.Join(b2.Kinds, ", "), b2.Path))
+		case b2.Default && !b1.Default:
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s banner %s reset to the default of package %s", strings.Join(b2.Kinds, ", "), b2.Path, b2.Package))
+		}
+	}
+	if sshBanner(banners1) && !sshBanner(banners2) {
+		diff.Warnings = append(diff.Warnings, "sshd no longer sends a banner")
+	}
+	return diff, nil
+}
+
+func containsKind(b Banner, kind string) bool {
+	for _, k := range b.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func sshBanner(banners []Banner) bool {
+	for _, b := range banners {
+		if containsKind(b, BannerSSH) {
+			return true
+		}
+	}
+	return false
+}
+
+// SortBanners orders banners by path.
+func SortBanners(banners []Banner) {
+	sort.Slice(banners, func(i, j int) bool { return banners[i].Path < banners[j].Path })
+}
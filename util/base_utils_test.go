@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestVerifyBase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "base")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	base, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	other, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	baseLayers, _ := base.Layers()
+	otherLayers, _ := other.Layers()
+	built, _ := mutate.AppendLayers(base, otherLayers[0])
+	// same first layer as the base, another second one
+	sibling, _ := mutate.AppendLayers(empty.Image, baseLayers[0], otherLayers[1], otherLayers[0])
+	tag, _ := name.NewTag("example.com/base:latest", name.WeakValidation)
+	baseTar, builtTar := filepath.Join(dir, "base.tar"), filepath.Join(dir, "built.tar")
+	for path, img := range map[string]v1.Image{baseTar: base, builtTar: built} {
+		if err := tarball.WriteToFile(path, tag, img); err != nil {
+			t.Fatalf("Error writing image: %s", err)
+		}
+	}
+
+	tests := []struct {
+		descrip  string
+		image    v1.Image
+		base     string
+		expected string
+	}{
+		{descrip: "built on the base", image: built, base: baseTar},
+		{descrip: "the base itself", image: base, base: baseTar},
+		{descrip: "other layers", image: other, base: baseTar, expected: "layer 0 is"},
+		{descrip: "other upper layer", image: sibling, base: baseTar, expected: "layer 1 is"},
+		{descrip: "fewer layers", image: base, base: builtTar, expected: "image has 2 layers, fewer than the 3 layers of the expected base"},
+	}
+	for _, test := range tests {
+		err := pkgutil.VerifyBase(test.image, test.base)
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("%s: expected the base to match, got %s", test.descrip, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.descrip, test.expected, err)
+		}
+	}
+}
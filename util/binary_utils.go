@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// ELFObject is a dynamically linked ELF executable or shared library: the
+// SONAME it provides, for libraries, and the DT_NEEDED libraries it was
+// linked against.
+type ELFObject struct {
+	Path   string
+	Soname string `json:",omitempty"`
+	Needed []string
+}
+
+// ELFChange is an ELF file of both images linked against different
+// libraries.
+type ELFChange struct {
+	Path          string
+	AddedNeeded   []string
+	RemovedNeeded []string
+}
+
+// SonameBump is a library whose SONAMEs changed between two images, e.g.
+// libssl.so.1.1 to libssl.so.3. Dependents are the files of the second
+// image still linked against a SONAME it dropped.
+type SonameBump struct {
+	Library    string
+	Sonames1   []string
+	Sonames2   []string
+	Dependents []string
+}
+
+// BinaryDiff holds the SONAME bumps between two images, and the ELF files
+// added, removed or relinked by the second.
+type BinaryDiff struct {
+	Bumps   []SonameBump
+	Added   []ELFObject
+	Removed []ELFObject
+	Changed []ELFChange
+}
+
+// SortELFObjects sorts ELF files by path.
+func SortELFObjects(objects []ELFObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Path < objects[j].Path
+	})
+}
+
+// SonameLibrary returns the library a SONAME is a version of, e.g. libssl
+// for libssl.so.3.
+func SonameLibrary(soname string) string {
+	if i := strings.Index(soname, ".so."); i > 0 {
+		return soname[:i]
+	}
+	return strings.TrimSuffix(soname, ".so")
+}
+
+// elfKey identifies an ELF file across images. Libraries are known by the
+// SONAME they provide in their directory, so that libfoo.so.1.2.3 updated to
+// libfoo.so.1.2.4 is the same libfoo.so.1.
+func elfKey(object ELFObject) string {
+	if object.Soname == "" {
+		return object.Path
+	}
+	return path.Join(path.Dir(object.Path), object.Soname)
+}
+
+// GetBinaryDiff compares the ELF files of two images.
+func GetBinaryDiff(objects1, objects2 []ELFObject) BinaryDiff {
+	diff := BinaryDiff{Bumps: []SonameBump{}, Added: []ELFObject{}, Removed: []ELFObject{}, Changed: []ELFChange{}}
+	byKey1 := map[string]ELFObject{}
+	for _, object := range objects1 {
+		byKey1[elfKey(object)] = object
+	}
+	byKey2 := map[string]ELFObject{}
+	for _, object := range objects2 {
+		key := elfKey(object)
+		byKey2[key] = object
+		object1, ok := byKey1[key]
+		if !ok {
+			diff.Added = append(diff.Added, object)
+			continue
+		}
+		added, removed := stringSetDiff(object1.Needed, object.Needed)
+		if len(added) != 0 || len(removed) != 0 {
+			diff.Changed = append(diff.Changed, ELFChange{Path: object.Path, AddedNeeded: added, RemovedNeeded: removed})
+		}
+	}
+	for _, object := range objects1 {
+		if _, ok := byKey2[elfKey(object)]; !ok {
+			diff.Removed = append(diff.Removed, object)
+		}
+	}
+	diff.Bumps = getSonameBumps(objects1, objects2)
+	SortELFObjects(diff.Added)
+	SortELFObjects(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Path < diff.Changed[j].Path
+	})
+	return diff
+}
+
+// getSonameBumps finds the libraries of both images which dropped some
+// SONAMEs for others.
+func getSonameBumps(objects1, objects2 []ELFObject) []SonameBump {
+	sonames1, sonames2 := librarySonames(objects1), librarySonames(objects2)
+	bumps := []SonameBump{}
+	for library, provided1 := range sonames1 {
+		provided2, ok := sonames2[library]
+		if !ok {
+			continue
+		}
+		added, removed := stringSetDiff(provided1, provided2)
+		if len(added) == 0 || len(removed) == 0 {
+			continue
+		}
+		dropped := map[string]bool{}
+		for _, soname := range removed {
+			dropped[soname] = true
+		}
+		dependents := []string{}
+		for _, object := range objects2 {
+			for _, needed := range object.Needed {
+				if dropped[needed] {
+					dependents = append(dependents, object.Path)
+					break
+				}
+			}
+		}
+		sort.Strings(dependents)
+		bumps = append(bumps, SonameBump{Library: library, Sonames1: removed, Sonames2: added, Dependents: dependents})
+	}
+	sort.Slice(bumps, func(i, j int) bool {
+		return bumps[i].Library < bumps[j].Library
+	})
+	return bumps
+}
+
+// librarySonames maps each library to the SONAMEs provided for it.
+func librarySonames(objects []ELFObject) map[string][]string {
+	sonames := map[string][]string{}
+	seen := map[string]bool{}
+	for _, object := range objects {
+		if object.Soname == "" || seen[object.Soname] {
+			continue
+		}
+		seen[object.Soname] = true
+		library := SonameLibrary(object.Soname)
+		sonames[library] = append(sonames[library], object.Soname)
+	}
+	return sonames
+}
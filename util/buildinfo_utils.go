@@ -0,0 +1,121 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "sort"
+
+// Where build information was recorded.
+const (
+	SourceManifest   = "manifest"
+	SourceLabel      = "label"
+	SourceHistory    = "history"
+	SourceBuildInfo  = "buildinfo"
+	SourceProvenance = "provenance"
+)
+
+// BuildValue is an annotation or build arg of an image, along with where it
+// was recorded. Build args declared without a default and never set have no
+// value.
+type BuildValue struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// BuildInfo stores how an image was built. Revision, SourceURL, Version and
+// BuildURL are picked from the annotations and provenance, as the values
+// most worth checking when an image changes.
+type BuildInfo struct {
+	Revision    string
+	SourceURL   string
+	Version     string
+	BuildURL    string
+	Annotations []BuildValue
+	BuildArgs   []BuildValue
+}
+
+// BuildChange is a build value which differs between images. Empty values
+// are unset.
+type BuildChange struct {
+	Name   string
+	Value1 string
+	Value2 string
+}
+
+// BuildInfoDiff lists the changes in build information between two images.
+// KeyChanges holds the changes of the revision, source URL, version and
+// build URL, so they stand out from the other annotations.
+type BuildInfoDiff struct {
+	KeyChanges  []BuildChange
+	Annotations []BuildChange
+	BuildArgs   []BuildChange
+}
+
+// KeyFields returns the revision, source URL, version and build URL in a
+// fixed order.
+func (b BuildInfo) KeyFields() []BuildValue {
+	return []BuildValue{
+		{Name: "Revision", Value: b.Revision},
+		{Name: "SourceURL", Value: b.SourceURL},
+		{Name: "Version", Value: b.Version},
+		{Name: "BuildURL", Value: b.BuildURL},
+	}
+}
+
+// SortBuildValues orders build values by name.
+func SortBuildValues(values []BuildValue) {
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+}
+
+// GetBuildInfoDiff compares the build information of two images.
+// Annotations and build args are matched by name; an unset build arg is the
+// same as a missing one.
+func GetBuildInfoDiff(b1, b2 BuildInfo) BuildInfoDiff {
+	diff := BuildInfoDiff{KeyChanges: []BuildChange{}}
+	fields1, fields2 := b1.KeyFields(), b2.KeyFields()
+	for i, f1 := range fields1 {
+		if f2 := fields2[i]; f1.Value != f2.Value {
+			diff.KeyChanges = append(diff.KeyChanges, BuildChange{Name: f1.Name, Value1: f1.Value, Value2: f2.Value})
+		}
+	}
+	diff.Annotations = buildValueChanges(b1.Annotations, b2.Annotations)
+	diff.BuildArgs = buildValueChanges(b1.BuildArgs, b2.BuildArgs)
+	return diff
+}
+
+func buildValueChanges(values1, values2 []BuildValue) []BuildChange {
+	changes := []BuildChange{}
+	byName1, byName2 := map[string]BuildValue{}, map[string]BuildValue{}
+	for _, v := range values1 {
+		byName1[v.Name] = v
+	}
+	for _, v := range values2 {
+		byName2[v.Name] = v
+	}
+	for _, v1 := range values1 {
+		if v2 := byName2[v1.Name]; v1.Value != v2.Value {
+			changes = append(changes, BuildChange{Name: v1.Name, Value1: v1.Value, Value2: v2.Value})
+		}
+	}
+	for _, v2 := range values2 {
+		if _, ok := byName1[v2.Name]; !ok && v2.Value != "" {
+			changes = append(changes, BuildChange{Name: v2.Name, Value2: v2.Value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestCachedFilesystemReuse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(16, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+	tar := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(tar, tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+
+	cache := filepath.Join(dir, "cache", "image")
+	getImage := func() pkgutil.Image {
+		image, err := pkgutil.GetImage(tar, true, cache)
+		if err != nil {
+			t.Fatalf("Error retrieving image: %s", err)
+		}
+		return image
+	}
+	cachedLayers := func(image pkgutil.Image) int {
+		cached := 0
+		for _, layer := range image.Layers {
+			if layer.Cached {
+				cached++
+			}
+		}
+		return cached
+	}
+
+	image := getImage()
+	if image.Cached || cachedLayers(image) != 0 {
+		t.Errorf("Expected nothing reused from an empty cache")
+	}
+	if !pkgutil.HasCachedFilesystem(image.FSPath) {
+		t.Errorf("Expected %s recorded as fully extracted", image.FSPath)
+	}
+
+	image = getImage()
+	if !image.Cached || cachedLayers(image) != 2 {
+		t.Errorf("Expected the filesystem and both layers reused, got %t and %d layers", image.Cached, cachedLayers(image))
+	}
+
+	// an interrupted run leaves a filesystem without a record, and a moved
+	// tag one recorded with another digest
+	if err := os.Remove(image.FSPath + ".digest"); err != nil {
+		t.Fatalf("Error removing cache record: %s", err)
+	}
+	if err := ioutil.WriteFile(image.Layers[1].FSPath+".digest", []byte("sha256:0000\n"), 0600); err != nil {
+		t.Fatalf("Error writing cache record: %s", err)
+	}
+	image = getImage()
+	if image.Cached || cachedLayers(image) != 1 || image.Layers[1].Cached {
+		t.Errorf("Expected only the first layer reused, got %t and %d layers", image.Cached, cachedLayers(image))
+	}
+	if !pkgutil.HasCachedFilesystem(image.FSPath) {
+		t.Errorf("Expected %s recorded as fully extracted again", image.FSPath)
+	}
+}
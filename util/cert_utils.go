@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CertificateDateFormat is the format of the validity dates of
+// certificates.
+const CertificateDateFormat = "2006-01-02"
+
+// Certificate stores an X.509 certificate an image trusts, identified by
+// the SHA-256 fingerprint of its DER encoding. Subject and Issuer are
+// their common names, or the full distinguished names of those without
+// one. NotBefore and NotAfter bound its validity, and Sources lists the
+// image paths of the files and Java keystores holding it.
+type Certificate struct {
+	Fingerprint string
+	Subject     string
+	Issuer      string
+	NotBefore   string
+	NotAfter    string
+	Sources     []string
+}
+
+// CertificateChange stores a certificate trusted by both images from
+// different files or keystores.
+type CertificateChange struct {
+	Fingerprint string
+	Cert1       Certificate
+	Cert2       Certificate
+}
+
+// CertificateDiff stores the certificates added, removed and changed
+// between two images. Warnings flag the certificates the second image
+// adds which have already expired.
+type CertificateDiff struct {
+	Warnings []string
+	Added    []Certificate
+	Removed  []Certificate
+	Changed  []CertificateChange
+}
+
+// GetCertificateDiff compares the certificates of two images by
+// fingerprint, checking the expiration of those added at now.
+func GetCertificateDiff(certs1, certs2 []Certificate, now time.Time) CertificateDiff {
+	diff := CertificateDiff{
+		Warnings: []string{},
+		Added:    []Certificate{},
+		Removed:  []Certificate{},
+		Changed:  []CertificateChange{},
+	}
+	today := now.UTC().Format(CertificateDateFormat)
+	byFingerprint1 := map[string]Certificate{}
+	for _, c := range certs1 {
+		byFingerprint1[c.Fingerprint] = c
+	}
+	byFingerprint2 := map[string]Certificate{}
+	for _, c := range certs2 {
+		byFingerprint2[c.Fingerprint] = c
+		c1, ok := byFingerprint1[c.Fingerprint]
+		if !ok {
+			diff.Added = append(diff.Added, c)
+			if c.NotAfter < today {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("certificate %s (%s) expired on %s", c.Fingerprint, c.Subject, c.NotAfter))
+			}
+		} else if !reflect.DeepEqual(c1, c) {
+			diff.Changed = append(diff.Changed, CertificateChange{Fingerprint: c.Fingerprint, Cert1: c1, Cert2: c})
+		}
+	}
+	for _, c := range certs1 {
+		if _, ok := byFingerprint2[c.Fingerprint]; !ok {
+			diff.Removed = append(diff.Removed, c)
+		}
+	}
+	return diff
+}
+
+// SortCertificates orders certificates by subject, then fingerprint.
+func SortCertificates(certs []Certificate) {
+	sort.Slice(certs, func(i, j int) bool {
+		si, sj := strings.ToLower(certs[i].Subject), strings.ToLower(certs[j].Subject)
+		if si != sj {
+			return si < sj
+		}
+		return certs[i].Fingerprint < certs[j].Fingerprint
+	})
+}
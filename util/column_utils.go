@@ -0,0 +1,117 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// headerField matches a table header such as VERSION or IMAGE1 (<image>).
+var headerField = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)( \(.*\))?$`)
+
+// formatColumns selects the columns of the tables in the text output of a
+// template, and renames their headers, as OutputOptions.Columns and
+// ColumnNames do. A table is a line of tab separated headers followed by
+// the tab separated rows up to the next line without a tab.
+func formatColumns(output string, columns []string, columnNames map[string]string) string {
+	if len(columns) == 0 && len(columnNames) == 0 {
+		return output
+	}
+	lines := strings.Split(output, "\n")
+	for start := 0; start < len(lines); start++ {
+		headers := strings.Split(lines[start], "\t")
+		if len(headers) < 2 || (start > 0 && strings.Contains(lines[start-1], "\t")) {
+			continue
+		}
+		names := make([]string, len(headers))
+		for i, header := range headers {
+			match := headerField.FindStringSubmatch(header)
+			if match == nil {
+				names = nil
+				break
+			}
+			names[i] = match[1]
+		}
+		if names == nil {
+			continue
+		}
+		end := start + 1
+		for end < len(lines) && strings.Contains(lines[end], "\t") {
+			end++
+		}
+		formatTable(lines[start:end], names, selectColumns(names, columns), columnNames)
+		start = end - 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+// selectColumns returns the indices of the columns to print.
+func selectColumns(names []string, columns []string) []int {
+	selected := []int{}
+	for _, column := range columns {
+		for i, name := range names {
+			if strings.EqualFold(column, name) {
+				selected = append(selected, i)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		for i := range names {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// formatTable rewrites the header and rows of a table in place.
+func formatTable(table []string, names []string, selected []int, columnNames map[string]string) {
+	headers := strings.Split(table[0], "\t")
+	for i, name := range names {
+		if rename, ok := lookupColumnName(columnNames, name); ok {
+			headers[i] = rename + strings.TrimPrefix(headers[i], name)
+		}
+	}
+	table[0] = strings.Join(headers, "\t")
+	for row, line := range table {
+		fields := strings.Split(line, "\t")
+		// rows are marked with a dash, which stays on the first column
+		marked := row > 0 && strings.HasPrefix(fields[0], "-")
+		if marked {
+			fields[0] = strings.TrimPrefix(fields[0], "-")
+		}
+		kept := make([]string, len(selected))
+		for i, index := range selected {
+			if index < len(fields) {
+				kept[i] = fields[index]
+			}
+		}
+		if marked {
+			kept[0] = "-" + kept[0]
+		}
+		table[row] = strings.Join(kept, "\t")
+	}
+}
+
+func lookupColumnName(columnNames map[string]string, name string) (string, bool) {
+	for header, rename := range columnNames {
+		if strings.EqualFold(header, name) {
+			return rename, true
+		}
+	}
+	return "", false
+}
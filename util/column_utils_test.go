@@ -0,0 +1,71 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+)
+
+func TestFormatColumns(t *testing.T) {
+	output := "Packages found in image:\n" +
+		"NAME\tVERSION\tSIZE\n" +
+		"-curl\t7.88.1\t500K\n" +
+		"...and 2 more (see JSON for full list)\n\n" +
+		"Version differences:\n" +
+		"PACKAGE\tIMAGE1 (a)\tIMAGE2 (b)\n" +
+		"-zlib\t1.2, 1K\t1.3, 1K\n"
+	testCases := []struct {
+		descrip  string
+		columns  []string
+		names    map[string]string
+		expected string
+	}{
+		{
+			descrip:  "no columns or names",
+			names:    map[string]string{},
+			expected: output,
+		},
+		{
+			descrip: "selected columns",
+			columns: []string{"size", "name"},
+			names:   map[string]string{},
+			expected: "Packages found in image:\n" +
+				"SIZE\tNAME\n" +
+				"-500K\tcurl\n" +
+				"...and 2 more (see JSON for full list)\n\n" +
+				"Version differences:\n" +
+				"PACKAGE\tIMAGE1 (a)\tIMAGE2 (b)\n" +
+				"-zlib\t1.2, 1K\t1.3, 1K\n",
+		},
+		{
+			descrip: "renamed columns",
+			names:   map[string]string{"size": "Taille", "IMAGE1": "Avant"},
+			expected: "Packages found in image:\n" +
+				"NAME\tVERSION\tTaille\n" +
+				"-curl\t7.88.1\t500K\n" +
+				"...and 2 more (see JSON for full list)\n\n" +
+				"Version differences:\n" +
+				"PACKAGE\tAvant (a)\tIMAGE2 (b)\n" +
+				"-zlib\t1.2, 1K\t1.3, 1K\n",
+		},
+	}
+	for _, test := range testCases {
+		if actual := formatColumns(output, test.columns, test.names); actual != test.expected {
+			t.Errorf("%s: expected:\n%s\nbut got:\n%s", test.descrip, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "sort"
+
+// Reasons a configuration file is left over in an image
+const (
+	LeftoverRemoved  = "removed package"
+	LeftoverObsolete = "obsolete conffile"
+	LeftoverBackup   = "backup copy"
+)
+
+// ConfigPackage is a package which was removed but whose configuration files
+// were kept, in the dpkg "rc" state. Conffiles lists the ones still on disk.
+type ConfigPackage struct {
+	Name      string
+	Version   string
+	Conffiles []string
+}
+
+// LeftoverFile is a configuration file on disk which no installed package
+// uses: a conffile of a removed package, an obsolete conffile, or a copy
+// saved by the package manager such as a .dpkg-old or .rpmsave file. Package
+// is the package owning the file, or the saved original, when known.
+type LeftoverFile struct {
+	Path    string
+	Package string
+	Reason  string
+	Size    int64
+}
+
+// ConffileAnalysis stores the removed packages and leftover configuration
+// files of an image, and the total size of those files.
+type ConffileAnalysis struct {
+	Packages []ConfigPackage
+	Files    []LeftoverFile
+	Size     int64
+}
+
+// ConffileDiff stores the removed packages and leftover files only found in
+// the first or the second image.
+type ConffileDiff struct {
+	Packages1 []ConfigPackage
+	Packages2 []ConfigPackage
+	Files1    []LeftoverFile
+	Files2    []LeftoverFile
+}
+
+// SortConffileAnalysis orders packages by name and files by path, and totals
+// the size of the files.
+func SortConffileAnalysis(analysis *ConffileAnalysis) {
+	sort.Slice(analysis.Packages, func(i, j int) bool { return analysis.Packages[i].Name < analysis.Packages[j].Name })
+	sort.Slice(analysis.Files, func(i, j int) bool { return analysis.Files[i].Path < analysis.Files[j].Path })
+	analysis.Size = 0
+	for _, file := range analysis.Files {
+		analysis.Size += file.Size
+	}
+}
+
+// GetConffileDiff compares the leftovers of two images. Packages are matched
+// by name and files by path.
+func GetConffileDiff(analysis1, analysis2 ConffileAnalysis) ConffileDiff {
+	return ConffileDiff{
+		Packages1: configPackagesOnlyIn(analysis1.Packages, analysis2.Packages),
+		Packages2: configPackagesOnlyIn(analysis2.Packages, analysis1.Packages),
+		Files1:    leftoverFilesOnlyIn(analysis1.Files, analysis2.Files),
+		Files2:    leftoverFilesOnlyIn(analysis2.Files, analysis1.Files),
+	}
+}
+
+func configPackagesOnlyIn(packages, others []ConfigPackage) []ConfigPackage {
+	names := map[string]bool{}
+	for _, p := range others {
+		names[p.Name] = true
+	}
+	only := []ConfigPackage{}
+	for _, p := range packages {
+		if !names[p.Name] {
+			only = append(only, p)
+		}
+	}
+	return only
+}
+
+func leftoverFilesOnlyIn(files, others []LeftoverFile) []LeftoverFile {
+	paths := map[string]bool{}
+	for _, f := range others {
+		paths[f.Path] = true
+	}
+	only := []LeftoverFile{}
+	for _, f := range files {
+		if !paths[f.Path] {
+			only = append(only, f)
+		}
+	}
+	return only
+}
+
+type StrLeftoverFile struct {
+	Path    string
+	Package string
+	Reason  string
+	Size    string
+}
+
+func stringifyLeftoverFiles(files []LeftoverFile) []StrLeftoverFile {
+	strFiles := []StrLeftoverFile{}
+	for _, f := range files {
+		strFiles = append(strFiles, StrLeftoverFile{Path: f.Path, Package: f.Package, Reason: f.Reason, Size: stringifySize(f.Size)})
+	}
+	return strFiles
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "sort"
+
+// CronJob stores a job scheduled in an image: its schedule, in crontab
+// syntax such as "*/5 * * * *" or "@daily", the user it runs as, its
+// command and the image path of the crontab or script defining it.
+type CronJob struct {
+	Schedule string
+	User     string
+	Command  string
+	Source   string
+}
+
+// CronJobChange stores a job defined in both images with a different
+// schedule.
+type CronJobChange struct {
+	User      string
+	Command   string
+	Source    string
+	Schedule1 string
+	Schedule2 string
+}
+
+// CronDiff stores the jobs scheduled only in either image, and those whose
+// schedule changed. Jobs are identified by their source, user and command.
+type CronDiff struct {
+	Added   []CronJob
+	Removed []CronJob
+	Changed []CronJobChange
+}
+
+// GetCronDiff compares the scheduled jobs of two images.
+func GetCronDiff(jobs1, jobs2 []CronJob) CronDiff {
+	diff := CronDiff{
+		Added:   []CronJob{},
+		Removed: []CronJob{},
+		Changed: []CronJobChange{},
+	}
+	key := func(j CronJob) string { return j.Source + "\x00" + j.User + "\x00" + j.Command }
+	byKey1 := map[string]CronJob{}
+	for _, j := range jobs1 {
+		byKey1[key(j)] = j
+	}
+	byKey2 := map[string]CronJob{}
+	for _, j := range jobs2 {
+		byKey2[key(j)] = j
+		j1, ok := byKey1[key(j)]
+		if !ok {
+			diff.Added = append(diff.Added, j)
+		} else if j1.Schedule != j.Schedule {
+			diff.Changed = append(diff.Changed, CronJobChange{User: j.User, Command: j.Command, Source: j.Source, Schedule1: j1.Schedule, Schedule2: j.Schedule})
+		}
+	}
+	for _, j := range jobs1 {
+		if _, ok := byKey2[key(j)]; !ok {
+			diff.Removed = append(diff.Removed, j)
+		}
+	}
+	return diff
+}
+
+// SortCronJobs orders jobs by source, then as listed in it.
+func SortCronJobs(jobs []CronJob) {
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].Source < jobs[j].Source })
+}
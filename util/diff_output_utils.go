@@ -33,7 +33,7 @@ type DiffResult struct {
 
 type MultiVersionPackageDiffResult DiffResult
 
-func (r MultiVersionPackageDiffResult) OutputStruct() interface{} {
+func (r MultiVersionPackageDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.(MultiVersionPackageDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the MultiVersionPackageDiff struct")
@@ -45,24 +45,24 @@ func (r MultiVersionPackageDiffResult) OutputStruct() interface{} {
 		Packages2 []PackageOutput
 		InfoDiff  []MultiVersionInfo
 	}{
-		Packages1: getMultiVersionPackageOutput(diff.Packages1),
-		Packages2: getMultiVersionPackageOutput(diff.Packages2),
-		InfoDiff:  getMultiVersionInfoDiffOutput(diff.InfoDiff),
+		Packages1: getMultiVersionPackageOutput(diff.Packages1, opts),
+		Packages2: getMultiVersionPackageOutput(diff.Packages2, opts),
+		InfoDiff:  getMultiVersionInfoDiffOutput(diff.InfoDiff, opts),
 	}
 	r.Diff = diffOutput
 	return r
 }
 
-func (r MultiVersionPackageDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r MultiVersionPackageDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.(MultiVersionPackageDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the MultiVersionPackageDiff struct")
 		return fmt.Errorf("Could not output %s diff result", r.DiffType)
 	}
 
-	strPackages1 := stringifyPackages(getMultiVersionPackageOutput(diff.Packages1))
-	strPackages2 := stringifyPackages(getMultiVersionPackageOutput(diff.Packages2))
-	strInfoDiff := stringifyMultiVersionPackageDiff(getMultiVersionInfoDiffOutput(diff.InfoDiff))
+	strPackages1 := stringifyPackages(getMultiVersionPackageOutput(diff.Packages1, opts))
+	strPackages2 := stringifyPackages(getMultiVersionPackageOutput(diff.Packages2, opts))
+	strInfoDiff := stringifyMultiVersionPackageDiff(getMultiVersionInfoDiffOutput(diff.InfoDiff, opts))
 
 	type StrDiff struct {
 		Packages1 []StrPackageOutput
@@ -85,11 +85,55 @@ func (r MultiVersionPackageDiffResult) OutputText(writer io.Writer, diffType str
 			InfoDiff:  strInfoDiff,
 		},
 	}
-	return TemplateOutputFromFormat(writer, strResult, "MultiVersionPackageDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "MultiVersionPackageDiff", opts)
 }
 
-func getMultiVersionInfoDiffOutput(infoDiff []MultiVersionInfo) []MultiVersionInfo {
-	if SortSize {
+type NodeDiffResult DiffResult
+
+func (r NodeDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(MultiVersionPackageDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the MultiVersionPackageDiff struct")
+		return fmt.Errorf("Could not output %s diff result", r.DiffType)
+	}
+
+	diffOutput := struct {
+		Packages1 []PackageOutput
+		Packages2 []PackageOutput
+		InfoDiff  []MultiVersionInfo
+	}{
+		Packages1: getNodePackageOutput(diff.Packages1, opts),
+		Packages2: getNodePackageOutput(diff.Packages2, opts),
+		InfoDiff:  getMultiVersionInfoDiffOutput(diff.InfoDiff, opts),
+	}
+	r.Diff = diffOutput
+	return r
+}
+
+func (r NodeDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(MultiVersionPackageDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the MultiVersionPackageDiff struct")
+		return fmt.Errorf("Could not output %s diff result", r.DiffType)
+	}
+
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     []StrNodeProjectDiff
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+		Diff: groupNodeProjectDiffs(getNodePackageOutput(diff.Packages1, opts), getNodePackageOutput(diff.Packages2, opts),
+			getMultiVersionInfoDiffOutput(diff.InfoDiff, opts)),
+	}
+	return TemplateOutputFromFormat(writer, strResult, "NodeDiff", opts)
+}
+
+func getMultiVersionInfoDiffOutput(infoDiff []MultiVersionInfo, opts OutputOptions) []MultiVersionInfo {
+	if opts.SortSize {
 		multiInfoBy(multiInfoSizeSort).Sort(infoDiff)
 	} else {
 		multiInfoBy(multiInfoNameSort).Sort(infoDiff)
@@ -99,7 +143,7 @@ func getMultiVersionInfoDiffOutput(infoDiff []MultiVersionInfo) []MultiVersionIn
 
 type SingleVersionPackageDiffResult DiffResult
 
-func (r SingleVersionPackageDiffResult) OutputStruct() interface{} {
+func (r SingleVersionPackageDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.(PackageDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the PackageDiff struct")
@@ -111,24 +155,24 @@ func (r SingleVersionPackageDiffResult) OutputStruct() interface{} {
 		Packages2 []PackageOutput
 		InfoDiff  []Info
 	}{
-		Packages1: getSingleVersionPackageOutput(diff.Packages1),
-		Packages2: getSingleVersionPackageOutput(diff.Packages2),
-		InfoDiff:  getSingleVersionInfoDiffOutput(diff.InfoDiff),
+		Packages1: getSingleVersionPackageOutput(diff.Packages1, opts),
+		Packages2: getSingleVersionPackageOutput(diff.Packages2, opts),
+		InfoDiff:  getSingleVersionInfoDiffOutput(diff.InfoDiff, opts),
 	}
 	r.Diff = diffOutput
 	return r
 }
 
-func (r SingleVersionPackageDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SingleVersionPackageDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.(PackageDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the PackageDiff struct")
 		return fmt.Errorf("Could not output %s diff result", r.DiffType)
 	}
 
-	strPackages1 := stringifyPackages(getSingleVersionPackageOutput(diff.Packages1))
-	strPackages2 := stringifyPackages(getSingleVersionPackageOutput(diff.Packages2))
-	strInfoDiff := stringifyPackageDiff(getSingleVersionInfoDiffOutput(diff.InfoDiff))
+	strPackages1 := stringifyPackages(getSingleVersionPackageOutput(diff.Packages1, opts))
+	strPackages2 := stringifyPackages(getSingleVersionPackageOutput(diff.Packages2, opts))
+	strInfoDiff := stringifyPackageDiff(getSingleVersionInfoDiffOutput(diff.InfoDiff, opts))
 
 	type StrDiff struct {
 		Packages1 []StrPackageOutput
@@ -151,11 +195,11 @@ func (r SingleVersionPackageDiffResult) OutputText(writer io.Writer, diffType st
 			InfoDiff:  strInfoDiff,
 		},
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageDiff", opts)
 }
 
-func getSingleVersionInfoDiffOutput(infoDiff []Info) []Info {
-	if SortSize {
+func getSingleVersionInfoDiffOutput(infoDiff []Info, opts OutputOptions) []Info {
+	if opts.SortSize {
 		singleInfoBy(singleInfoSizeSort).Sort(infoDiff)
 	} else {
 		singleInfoBy(singleInfoNameSort).Sort(infoDiff)
@@ -165,7 +209,7 @@ func getSingleVersionInfoDiffOutput(infoDiff []Info) []Info {
 
 type SingleVersionPackageLayerDiffResult DiffResult
 
-func (r SingleVersionPackageLayerDiffResult) OutputStruct() interface{} {
+func (r SingleVersionPackageLayerDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.(PackageLayerDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the PackageLayerDiff struct")
@@ -181,9 +225,9 @@ func (r SingleVersionPackageLayerDiffResult) OutputStruct() interface{} {
 	var diffOutputs []PkgDiff
 	for _, d := range diff.PackageDiffs {
 		diffOutput := PkgDiff{
-			Packages1: getSingleVersionPackageOutput(d.Packages1),
-			Packages2: getSingleVersionPackageOutput(d.Packages2),
-			InfoDiff:  getSingleVersionInfoDiffOutput(d.InfoDiff),
+			Packages1: getSingleVersionPackageOutput(d.Packages1, opts),
+			Packages2: getSingleVersionPackageOutput(d.Packages2, opts),
+			InfoDiff:  getSingleVersionInfoDiffOutput(d.InfoDiff, opts),
 		}
 		diffOutputs = append(diffOutputs, diffOutput)
 	}
@@ -192,7 +236,7 @@ func (r SingleVersionPackageLayerDiffResult) OutputStruct() interface{} {
 	return r
 }
 
-func (r SingleVersionPackageLayerDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SingleVersionPackageLayerDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.(PackageLayerDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the PackageLayerDiff struct")
@@ -208,9 +252,9 @@ func (r SingleVersionPackageLayerDiffResult) OutputText(writer io.Writer, diffTy
 	var diffOutputs []StrDiff
 	for _, d := range diff.PackageDiffs {
 		diffOutput := StrDiff{
-			Packages1: stringifyPackages(getSingleVersionPackageOutput(d.Packages1)),
-			Packages2: stringifyPackages(getSingleVersionPackageOutput(d.Packages2)),
-			InfoDiff:  stringifyPackageDiff(getSingleVersionInfoDiffOutput(d.InfoDiff)),
+			Packages1: stringifyPackages(getSingleVersionPackageOutput(d.Packages1, opts)),
+			Packages2: stringifyPackages(getSingleVersionPackageOutput(d.Packages2, opts)),
+			InfoDiff:  stringifyPackageDiff(getSingleVersionInfoDiffOutput(d.InfoDiff, opts)),
 		}
 		diffOutputs = append(diffOutputs, diffOutput)
 	}
@@ -226,58 +270,62 @@ func (r SingleVersionPackageLayerDiffResult) OutputText(writer io.Writer, diffTy
 		DiffType: r.DiffType,
 		Diff:     diffOutputs,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageLayerDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "SingleVersionPackageLayerDiff", opts)
 }
 
 type HistDiffResult DiffResult
 
-func (r HistDiffResult) OutputStruct() interface{} {
+func (r HistDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	return r
 }
 
-func (r HistDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
-	return TemplateOutputFromFormat(writer, r, "HistDiff", format)
+func (r HistDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	return TemplateOutputFromFormat(writer, r, "HistDiff", opts)
 }
 
 type MetadataDiffResult DiffResult
 
-func (r MetadataDiffResult) OutputStruct() interface{} {
+func (r MetadataDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	return r
 }
 
-func (r MetadataDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
-	return TemplateOutputFromFormat(writer, r, "MetadataDiff", format)
+func (r MetadataDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	return TemplateOutputFromFormat(writer, r, "MetadataDiff", opts)
 }
 
 type DirDiffResult DiffResult
 
-func (r DirDiffResult) OutputStruct() interface{} {
+func (r DirDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.(DirDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the DirDiff struct")
 		return errors.New("Could not output FileAnalyzer diff result")
 	}
 
-	r.Diff = sortDirDiff(diff)
+	r.Diff = sortDirDiff(diff, opts)
 	return r
 }
 
-func (r DirDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r DirDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.(DirDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the DirDiff struct")
 		return errors.New("Could not output FileAnalyzer diff result")
 	}
-	diff = sortDirDiff(diff)
+	diff = sortDirDiff(diff, opts)
 
 	strAdds := stringifyDirectoryEntries(diff.Adds)
 	strDels := stringifyDirectoryEntries(diff.Dels)
 	strMods := stringifyEntryDiffs(diff.Mods)
 
 	type StrDiff struct {
-		Adds []StrDirectoryEntry
-		Dels []StrDirectoryEntry
-		Mods []StrEntryDiff
+		Adds     []StrDirectoryEntry
+		Dels     []StrDirectoryEntry
+		Mods     []StrEntryDiff
+		Applets  []AppletLinks
+		Archives []StrArchiveDiff
+		Contents []ContentDiff
+		Hotspots []StrDirHotspot
 	}
 
 	strResult := struct {
@@ -290,17 +338,30 @@ func (r DirDiffResult) OutputText(writer io.Writer, diffType string, format stri
 		Image2:   r.Image2,
 		DiffType: r.DiffType,
 		Diff: StrDiff{
-			Adds: strAdds,
-			Dels: strDels,
-			Mods: strMods,
+			Adds:     strAdds,
+			Dels:     strDels,
+			Mods:     strMods,
+			Applets:  diff.Applets,
+			Archives: stringifyArchiveDiffs(diff.Archives),
+			Contents: diff.Contents,
+			Hotspots: stringifyHotspots(diff.Hotspots),
 		},
 	}
-	return TemplateOutputFromFormat(writer, strResult, "DirDiff", format)
+	if err := TemplateOutputFromFormat(writer, strResult, "DirDiff", opts); err != nil || opts.Format != "" {
+		return err
+	}
+	// written as is, as aligning columns would rewrite the tabs of the files
+	for _, content := range diff.Contents {
+		if _, err := fmt.Fprintf(writer, "Contents of %s changed between %s and %s:\n%s\n", content.Name, r.Image1, r.Image2, content.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type SizeDiffResult DiffResult
 
-func (r SizeDiffResult) OutputStruct() interface{} {
+func (r SizeDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.([]SizeDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should be of type []SizeDiff")
@@ -311,7 +372,7 @@ func (r SizeDiffResult) OutputStruct() interface{} {
 	return r
 }
 
-func (r SizeDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SizeDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.([]SizeDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should be of type []SizeDiff")
@@ -331,12 +392,12 @@ func (r SizeDiffResult) OutputText(writer io.Writer, diffType string, format str
 		DiffType: r.DiffType,
 		Diff:     strDiff,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SizeDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "SizeDiff", opts)
 }
 
 type SizeLayerDiffResult DiffResult
 
-func (r SizeLayerDiffResult) OutputStruct() interface{} {
+func (r SizeLayerDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.([]SizeDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should be of type []SizeDiff")
@@ -347,7 +408,7 @@ func (r SizeLayerDiffResult) OutputStruct() interface{} {
 	return r
 }
 
-func (r SizeLayerDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r SizeLayerDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.([]SizeDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should be of type []SizeDiff")
@@ -367,32 +428,32 @@ func (r SizeLayerDiffResult) OutputText(writer io.Writer, diffType string, forma
 		DiffType: r.DiffType,
 		Diff:     strDiff,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "SizeLayerDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "SizeLayerDiff", opts)
 }
 
 type MultipleDirDiffResult DiffResult
 
-func (r MultipleDirDiffResult) OutputStruct() interface{} {
+func (r MultipleDirDiffResult) OutputStruct(opts OutputOptions) interface{} {
 	diff, valid := r.Diff.(MultipleDirDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the MultipleDirDiff struct")
 		return errors.New("Could not output FileLayerAnalyzer diff result")
 	}
 	for i, d := range diff.DirDiffs {
-		diff.DirDiffs[i] = sortDirDiff(d)
+		diff.DirDiffs[i] = sortDirDiff(d, opts)
 	}
 	r.Diff = diff
 	return r
 }
 
-func (r MultipleDirDiffResult) OutputText(writer io.Writer, diffType string, format string) error {
+func (r MultipleDirDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
 	diff, valid := r.Diff.(MultipleDirDiff)
 	if !valid {
 		logrus.Error("Unexpected structure of Diff.  Should follow the MultipleDirDiff struct")
 		return errors.New("Could not output FileLayerAnalyzer diff result")
 	}
 	for i, d := range diff.DirDiffs {
-		diff.DirDiffs[i] = sortDirDiff(d)
+		diff.DirDiffs[i] = sortDirDiff(d, opts)
 	}
 
 	type StrDiff struct {
@@ -429,5 +490,830 @@ func (r MultipleDirDiffResult) OutputText(writer io.Writer, diffType string, for
 		DiffType: r.DiffType,
 		Diff:     strDiffs,
 	}
-	return TemplateOutputFromFormat(writer, strResult, "MultipleDirDiff", format)
+	return TemplateOutputFromFormat(writer, strResult, "MultipleDirDiff", opts)
+}
+
+type AlternativesDiffResult DiffResult
+
+func (r AlternativesDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(AlternativeDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AlternativeDiff struct")
+		return errors.New("Could not output AlternativesAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r AlternativesDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(AlternativeDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AlternativeDiff struct")
+		return errors.New("Could not output AlternativesAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AlternativesDiff", opts)
+}
+
+type AdvisoryDiffResult DiffResult
+
+func (r AdvisoryDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(AdvisoryDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AdvisoryDiff struct")
+		return errors.New("Could not output advisory diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r AdvisoryDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(AdvisoryDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AdvisoryDiff struct")
+		return errors.New("Could not output advisory diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AdvisoryDiff", opts)
+}
+
+type LayerSuggestDiffResult DiffResult
+
+func (r LayerSuggestDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(LayerSuggestDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the LayerSuggestDiff struct")
+		return errors.New("Could not output LayerSuggestAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r LayerSuggestDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(LayerSuggestDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the LayerSuggestDiff struct")
+		return errors.New("Could not output LayerSuggestAnalyzer diff result")
+	}
+
+	strDiff := struct {
+		FirstChangedLayer int
+		ChangedCommand    string
+		RebuiltLayers     int
+		RebuiltBytes      string
+		Suggestions       []StrLayerSuggestion
+		SavedBytes        string
+	}{
+		FirstChangedLayer: diff.FirstChangedLayer,
+		ChangedCommand:    diff.ChangedCommand,
+		RebuiltLayers:     diff.RebuiltLayers,
+		RebuiltBytes:      stringifySize(diff.RebuiltBytes),
+		Suggestions:       stringifyLayerSuggestions(diff.Suggestions),
+		SavedBytes:        stringifySize(diff.SavedBytes),
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     interface{}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+		Diff:     strDiff,
+	}
+	return TemplateOutputFromFormat(writer, strResult, "LayerSuggestDiff", opts)
+}
+
+type ReproDiffResult DiffResult
+
+func (r ReproDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(ReproDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ReproDiff struct")
+		return errors.New("Could not output ReproAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r ReproDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(ReproDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ReproDiff struct")
+		return errors.New("Could not output ReproAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Identical bool
+			Findings  []StrReproFinding
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Identical = diff.Identical
+	strResult.Diff.Findings = stringifyReproFindings(diff.Findings)
+	return TemplateOutputFromFormat(writer, strResult, "ReproDiff", opts)
+}
+
+type InitDiffResult DiffResult
+
+func (r InitDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(InitDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the InitDiff struct")
+		return errors.New("Could not output InitAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r InitDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(InitDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the InitDiff struct")
+		return errors.New("Could not output InitAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "InitDiff", opts)
+}
+
+type ScoreDiffResult DiffResult
+
+func (r ScoreDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(ScorecardDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ScorecardDiff struct")
+		return errors.New("Could not output ScoreAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r ScoreDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(ScorecardDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ScorecardDiff struct")
+		return errors.New("Could not output ScoreAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Score1     int
+			Score2     int
+			Delta      string
+			Categories []StrScoreDelta
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Score1 = diff.Score1
+	strResult.Diff.Score2 = diff.Score2
+	strResult.Diff.Delta = fmt.Sprintf("%+d", diff.Delta)
+	strResult.Diff.Categories = stringifyScoreDeltas(diff.Categories)
+	return TemplateOutputFromFormat(writer, strResult, "ScoreDiff", opts)
+}
+
+type AptDepsDiffResult DiffResult
+
+func (r AptDepsDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(DependencyDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the DependencyDiff struct")
+		return errors.New("Could not output AptDepsAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r AptDepsDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(DependencyDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the DependencyDiff struct")
+		return errors.New("Could not output AptDepsAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "AptDepsDiff", opts)
+}
+
+type SystemdDiffResult DiffResult
+
+func (r SystemdDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(SystemdDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SystemdDiff struct")
+		return errors.New("Could not output SystemdAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r SystemdDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(SystemdDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SystemdDiff struct")
+		return errors.New("Could not output SystemdAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "SystemdDiff", opts)
+}
+
+type RuntimesDiffResult DiffResult
+
+func (r RuntimesDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.([]RuntimeVersions)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should be of type []RuntimeVersions")
+		return errors.New("Could not output RuntimesAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r RuntimesDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.([]RuntimeVersions)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should be of type []RuntimeVersions")
+		return errors.New("Could not output RuntimesAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     []StrRuntimeVersions
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+		Diff:     stringifyRuntimeVersions(diff),
+	}
+	return TemplateOutputFromFormat(writer, strResult, "RuntimesDiff", opts)
+}
+
+type ProvenanceDiffResult DiffResult
+
+func (r ProvenanceDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(ProvenanceDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ProvenanceDiff struct")
+		return errors.New("Could not output ProvenanceAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r ProvenanceDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(ProvenanceDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ProvenanceDiff struct")
+		return errors.New("Could not output ProvenanceAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			ImageVerified1 bool
+			ImageVerified2 bool
+			Layers         []StrLayerProvenance
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.ImageVerified1 = diff.ImageVerified1
+	strResult.Diff.ImageVerified2 = diff.ImageVerified2
+	strResult.Diff.Layers = stringifyLayerProvenanceChanges(diff.Layers)
+	return TemplateOutputFromFormat(writer, strResult, "ProvenanceDiff", opts)
+}
+
+// IdenticalDiff is reported instead of analyzer results when the images
+// were found to be identical before being extracted.
+type IdenticalDiff struct {
+	Identical bool
+	Reason    string
+}
+
+type IdenticalDiffResult DiffResult
+
+func (r IdenticalDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(IdenticalDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the IdenticalDiff struct")
+		return errors.New("Could not output identical images result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r IdenticalDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(IdenticalDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the IdenticalDiff struct")
+		return errors.New("Could not output identical images result")
+	}
+	return TemplateOutputFromFormat(writer, r, "IdenticalDiff", opts)
+}
+
+// PartialDiff is reported with the analysis of one image when the other
+// couldn't be retrieved.
+type PartialDiff struct {
+	AnalyzedImage string
+	FailedImage   string
+	Error         string
+}
+
+type PartialDiffResult DiffResult
+
+func (r PartialDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(PartialDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the PartialDiff struct")
+		return errors.New("Could not output partial diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r PartialDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(PartialDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the PartialDiff struct")
+		return errors.New("Could not output partial diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "PartialDiff", opts)
+}
+
+type PlatformDiffResult DiffResult
+
+func (r PlatformDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.([]PlatformSummary)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should be of type []PlatformSummary")
+		return errors.New("Could not output platform summary")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r PlatformDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.([]PlatformSummary); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should be of type []PlatformSummary")
+		return errors.New("Could not output platform summary")
+	}
+	return TemplateOutputFromFormat(writer, r, "PlatformDiff", opts)
+}
+
+type RPMRepoDiffResult DiffResult
+
+func (r RPMRepoDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(RPMRepoDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the RPMRepoDiff struct")
+		return errors.New("Could not output RPMRepoAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r RPMRepoDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(RPMRepoDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the RPMRepoDiff struct")
+		return errors.New("Could not output RPMRepoAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "RPMRepoDiff", opts)
+}
+
+type EntropyDiffResult DiffResult
+
+func (r EntropyDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(EntropyDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the EntropyDiff struct")
+		return errors.New("Could not output EntropyAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r EntropyDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(EntropyDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the EntropyDiff struct")
+		return errors.New("Could not output EntropyAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Size1      string
+			Size2      string
+			Ratio1     string
+			Ratio2     string
+			Duplicate1 string
+			Duplicate2 string
+			Dirs       []StrDirEntropyDelta
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Size1 = stringifySize(diff.Size1)
+	strResult.Diff.Size2 = stringifySize(diff.Size2)
+	strResult.Diff.Ratio1 = compressionRatio(diff.Size1, diff.CompressedSize1)
+	strResult.Diff.Ratio2 = compressionRatio(diff.Size2, diff.CompressedSize2)
+	strResult.Diff.Duplicate1 = stringifySize(diff.DuplicateSize1)
+	strResult.Diff.Duplicate2 = stringifySize(diff.DuplicateSize2)
+	strResult.Diff.Dirs = stringifyDirEntropyDeltas(diff.Dirs)
+	return TemplateOutputFromFormat(writer, strResult, "EntropyDiff", opts)
+}
+
+type LinkerDiffResult DiffResult
+
+func (r LinkerDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(LinkerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the LinkerDiff struct")
+		return errors.New("Could not output LinkerAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r LinkerDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(LinkerDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the LinkerDiff struct")
+		return errors.New("Could not output LinkerAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "LinkerDiff", opts)
+}
+
+type ConffileDiffResult DiffResult
+
+func (r ConffileDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(ConffileDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ConffileDiff struct")
+		return errors.New("Could not output ConffilesAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r ConffileDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(ConffileDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ConffileDiff struct")
+		return errors.New("Could not output ConffilesAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Packages1 []ConfigPackage
+			Packages2 []ConfigPackage
+			Files1    []StrLeftoverFile
+			Files2    []StrLeftoverFile
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Packages1 = diff.Packages1
+	strResult.Diff.Packages2 = diff.Packages2
+	strResult.Diff.Files1 = stringifyLeftoverFiles(diff.Files1)
+	strResult.Diff.Files2 = stringifyLeftoverFiles(diff.Files2)
+	return TemplateOutputFromFormat(writer, strResult, "ConffileDiff", opts)
+}
+
+type OSDiffResult DiffResult
+
+func (r OSDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(OSDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the OSDiff struct")
+		return errors.New("Could not output OSAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r OSDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(OSDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the OSDiff struct")
+		return errors.New("Could not output OSAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "OSDiff", opts)
+}
+
+type BuildInfoDiffResult DiffResult
+
+func (r BuildInfoDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(BuildInfoDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BuildInfoDiff struct")
+		return errors.New("Could not output BuildInfoAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r BuildInfoDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(BuildInfoDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BuildInfoDiff struct")
+		return errors.New("Could not output BuildInfoAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "BuildInfoDiff", opts)
+}
+
+type PermissionDiffResult DiffResult
+
+func (r PermissionDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(PermissionDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the PermissionDiff struct")
+		return errors.New("Could not output PermissionsAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r PermissionDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(PermissionDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the PermissionDiff struct")
+		return errors.New("Could not output PermissionsAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "PermissionDiff", opts)
+}
+
+type VolumeDiffResult DiffResult
+
+func (r VolumeDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(VolumeDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the VolumeDiff struct")
+		return errors.New("Could not output VolumesAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r VolumeDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(VolumeDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the VolumeDiff struct")
+		return errors.New("Could not output VolumesAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			AddedVolumes   []string
+			RemovedVolumes []string
+			Adds           []StrDirectoryEntry
+			Dels           []StrDirectoryEntry
+			Mods           []StrEntryDiff
+			Warnings       []string
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.AddedVolumes = diff.AddedVolumes
+	strResult.Diff.RemovedVolumes = diff.RemovedVolumes
+	strResult.Diff.Adds = stringifyDirectoryEntries(diff.Adds)
+	strResult.Diff.Dels = stringifyDirectoryEntries(diff.Dels)
+	strResult.Diff.Mods = stringifyEntryDiffs(diff.Mods)
+	strResult.Diff.Warnings = diff.Warnings
+	return TemplateOutputFromFormat(writer, strResult, "VolumeDiff", opts)
+}
+
+type SharedLayerDiffResult DiffResult
+
+func (r SharedLayerDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(SharedLayerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SharedLayerDiff struct")
+		return errors.New("Could not output SharedLayersAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r SharedLayerDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(SharedLayerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SharedLayerDiff struct")
+		return errors.New("Could not output SharedLayersAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Shared     []StrSharedLayer
+			Unique1    []StrLayerRef
+			Unique2    []StrLayerRef
+			SharedSize string
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Shared = stringifySharedLayers(diff.Shared)
+	strResult.Diff.Unique1 = stringifyLayerRefs(diff.Unique1)
+	strResult.Diff.Unique2 = stringifyLayerRefs(diff.Unique2)
+	strResult.Diff.SharedSize = stringifySize(diff.SharedSize)
+	return TemplateOutputFromFormat(writer, strResult, "SharedLayerDiff", opts)
+}
+
+type SecretDiffResult DiffResult
+
+func (r SecretDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(SecretDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SecretDiff struct")
+		return errors.New("Could not output SecretsAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r SecretDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(SecretDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SecretDiff struct")
+		return errors.New("Could not output SecretsAnalyzer diff result")
+	}
+	strResult := struct {
+		Image1   string
+		Image2   string
+		DiffType string
+		Diff     struct {
+			Added   []StrSecret
+			Removed []StrSecret
+		}
+	}{
+		Image1:   r.Image1,
+		Image2:   r.Image2,
+		DiffType: r.DiffType,
+	}
+	strResult.Diff.Added = stringifySecrets(diff.Added)
+	strResult.Diff.Removed = stringifySecrets(diff.Removed)
+	return TemplateOutputFromFormat(writer, strResult, "SecretDiff", opts)
+}
+
+type BinaryDiffResult DiffResult
+
+func (r BinaryDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(BinaryDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BinaryDiff struct")
+		return errors.New("Could not output BinaryAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r BinaryDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(BinaryDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BinaryDiff struct")
+		return errors.New("Could not output BinaryAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "BinaryDiff", opts)
+}
+
+type KeyringDiffResult DiffResult
+
+func (r KeyringDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(KeyringDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the KeyringDiff struct")
+		return errors.New("Could not output KeyringAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r KeyringDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(KeyringDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the KeyringDiff struct")
+		return errors.New("Could not output KeyringAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "KeyringDiff", opts)
+}
+
+type ServiceDiffResult DiffResult
+
+func (r ServiceDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(ServiceDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ServiceDiff struct")
+		return errors.New("Could not output ServicesAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r ServiceDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(ServiceDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the ServiceDiff struct")
+		return errors.New("Could not output ServicesAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "ServiceDiff", opts)
+}
+
+type CronDiffResult DiffResult
+
+func (r CronDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(CronDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the CronDiff struct")
+		return errors.New("Could not output CronAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r CronDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(CronDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the CronDiff struct")
+		return errors.New("Could not output CronAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "CronDiff", opts)
+}
+
+type UserDiffResult DiffResult
+
+func (r UserDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(AccountDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AccountDiff struct")
+		return errors.New("Could not output UsersAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r UserDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(AccountDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the AccountDiff struct")
+		return errors.New("Could not output UsersAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "UserDiff", opts)
+}
+
+type CertificateDiffResult DiffResult
+
+func (r CertificateDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(CertificateDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the CertificateDiff struct")
+		return errors.New("Could not output CertsAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r CertificateDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	if _, valid := r.Diff.(CertificateDiff); !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the CertificateDiff struct")
+		return errors.New("Could not output CertsAnalyzer diff result")
+	}
+	return TemplateOutputFromFormat(writer, r, "CertificateDiff", opts)
+}
+
+type BannerDiffResult DiffResult
+
+func (r BannerDiffResult) OutputStruct(opts OutputOptions) interface{} {
+	diff, valid := r.Diff.(BannerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BannerDiff struct")
+		return errors.New("Could not output BannersAnalyzer diff result")
+	}
+	r.Diff = diff
+	return r
+}
+
+func (r BannerDiffResult) OutputText(writer io.Writer, diffType string, opts OutputOptions) error {
+	diff, valid := r.Diff.(BannerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the BannerDiff struct")
+		return errors.New("Could not output BannersAnalyzer diff result")
+	}
+	if err := TemplateOutputFromFormat(writer, r, "BannerDiff", opts); err != nil || opts.Format != "" {
+		return err
+	}
+	// written as is, as aligning columns would rewrite the tabs of the banners
+	for _, content := range diff.Changed {
+		if _, err := fmt.Fprintf(writer, "Contents of %s changed between %s and %s:\n%s\n", content.Name, r.Image1, r.Image2, content.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -29,9 +29,13 @@ import (
 )
 
 type DirDiff struct {
-	Adds []pkgutil.DirectoryEntry
-	Dels []pkgutil.DirectoryEntry
-	Mods []EntryDiff
+	Adds     []pkgutil.DirectoryEntry
+	Dels     []pkgutil.DirectoryEntry
+	Mods     []EntryDiff
+	Applets  []AppletLinks `json:",omitempty"`
+	Archives []ArchiveDiff `json:",omitempty"`
+	Contents []ContentDiff `json:",omitempty"`
+	Hotspots []DirHotspot  `json:",omitempty"`
 }
 
 type MultipleDirDiff struct {
@@ -44,10 +48,18 @@ type FileNameDiff struct {
 	Diff        string
 }
 
+// EntryDiff is a file modified between two directories. Link1 and Link2
+// are the targets of the entry in each, when it is a symlink there, and
+// Digest1 and Digest2 the digests of its contents, when it is a regular
+// file.
 type EntryDiff struct {
-	Name  string
-	Size1 int64
-	Size2 int64
+	Name    string
+	Size1   int64
+	Size2   int64
+	Link1   string `json:",omitempty"`
+	Link2   string `json:",omitempty"`
+	Digest1 string `json:",omitempty"`
+	Digest2 string `json:",omitempty"`
 }
 
 // Modification of difflib's unified differ
@@ -125,7 +137,80 @@ func DiffDirectory(d1, d2 pkgutil.Directory) (DirDiff, bool) {
 		same = false
 	}
 
-	return DirDiff{addedEntries, deletedEntries, modifiedEntries}, same
+	return DirDiff{Adds: addedEntries, Dels: deletedEntries, Mods: modifiedEntries}, same
+}
+
+// DiffInventories takes the diff of two streamed image inventories, comparing
+// files by digest instead of reading them back from disk
+func DiffInventories(inv1, inv2 pkgutil.FileInventory) (DirDiff, bool) {
+	sizes1 := inv1.Sizes()
+	sizes2 := inv2.Sizes()
+
+	adds := []string{}
+	mods := []string{}
+	for name, entry2 := range inv2 {
+		entry1, ok := inv1[name]
+		if !ok {
+			adds = append(adds, name)
+			continue
+		}
+		if entry1.IsDir && entry2.IsDir {
+			continue
+		}
+		if pkgutil.IsTar(name) {
+			// mirror GetModifiedEntries, which only compares tar sizes
+			if entry1.Size != entry2.Size {
+				mods = append(mods, name)
+			}
+			continue
+		}
+		if entry1.IsDir != entry2.IsDir || entry1.Linkname != entry2.Linkname || entry1.Digest != entry2.Digest {
+			mods = append(mods, name)
+		}
+	}
+	dels := []string{}
+	for name := range inv1 {
+		if _, ok := inv2[name]; !ok {
+			dels = append(dels, name)
+		}
+	}
+	sort.Strings(adds)
+	sort.Strings(dels)
+	sort.Strings(mods)
+
+	diff := DirDiff{
+		Adds: inventoryEntries(adds, inv2, sizes2),
+		Dels: inventoryEntries(dels, inv1, sizes1),
+	}
+	for _, name := range mods {
+		diff.Mods = append(diff.Mods, EntryDiff{
+			Name:    name,
+			Size1:   sizes1[name],
+			Size2:   sizes2[name],
+			Link1:   inv1[name].Linkname,
+			Link2:   inv2[name].Linkname,
+			Digest1: inv1[name].Digest,
+			Digest2: inv2[name].Digest,
+		})
+	}
+	return diff, len(adds) == 0 && len(dels) == 0 && len(mods) == 0
+}
+
+// InventoryEntries lists every entry of a streamed inventory in name order.
+func InventoryEntries(inv pkgutil.FileInventory) []pkgutil.DirectoryEntry {
+	names := []string{}
+	for name := range inv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return inventoryEntries(names, inv, inv.Sizes())
+}
+
+func inventoryEntries(names []string, inv pkgutil.FileInventory, sizes map[string]int64) (entries []pkgutil.DirectoryEntry) {
+	for _, name := range names {
+		entries = append(entries, pkgutil.DirectoryEntry{Name: name, Size: sizes[name], Linkname: inv[name].Linkname, Digest: inv[name].Digest})
+	}
+	return entries
 }
 
 func DiffFile(image1, image2 *pkgutil.Image, filename string) (*FileNameDiff, error) {
@@ -221,6 +306,12 @@ func GetModifiedEntries(d1, d2 pkgutil.Directory) []string {
 			}
 			continue
 		}
+		// A symlink replacing a file, or the other way round, is a change even
+		// if the link resolves to the same contents
+		if (f1stat.Mode()^f2stat.Mode())&os.ModeSymlink != 0 {
+			modified = append(modified, f)
+			continue
+		}
 
 		// If the directory entry in question is a tar, verify that the two have the same size
 		if pkgutil.IsTar(f1path) {
@@ -263,9 +354,13 @@ func createEntryDiffs(root1, root2 string, entryNames []string) (entries []Entry
 		size2 := pkgutil.GetSize(entryPath2)
 
 		entry := EntryDiff{
-			Name:  name,
-			Size1: size1,
-			Size2: size2,
+			Name:    name,
+			Size1:   size1,
+			Size2:   size2,
+			Link1:   pkgutil.GetLinkname(entryPath1),
+			Link2:   pkgutil.GetLinkname(entryPath2),
+			Digest1: pkgutil.GetDigest(entryPath1),
+			Digest2: pkgutil.GetDigest(entryPath2),
 		}
 		entries = append(entries, entry)
 	}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+type testTarEntry struct {
+	name     string
+	content  string
+	linkname string
+	typeflag byte
+}
+
+func testInventoryImage(t *testing.T, entries []testTarEntry) v1.Image {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0644}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if e.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Error writing tar header: %s", err)
+		}
+		if e.typeflag != tar.TypeReg {
+			continue
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatalf("Error writing tar content: %s", err)
+		}
+	}
+	tw.Close()
+	data := buf.Bytes()
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	if err != nil {
+		t.Fatalf("Error creating layer: %s", err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	return img
+}
+
+func TestDiffInventories(t *testing.T) {
+	img1 := testInventoryImage(t, []testTarEntry{
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/os-release", content: "alpine 3.7", typeflag: tar.TypeReg},
+		{name: "bin/busybox", content: "busybox 1.27", typeflag: tar.TypeReg},
+		{name: "bin/sh", linkname: "/bin/busybox", typeflag: tar.TypeSymlink},
+		{name: "old/file", content: "removed", typeflag: tar.TypeReg},
+		{name: "same", content: "unchanged", typeflag: tar.TypeReg},
+		{name: "usr/bin/python3.8", content: "python 3.8", typeflag: tar.TypeReg},
+		{name: "usr/bin/python3.9", content: "python 3.9", typeflag: tar.TypeReg},
+		{name: "usr/bin/python", linkname: "python3.8", typeflag: tar.TypeSymlink},
+		{name: "usr/lib/libz.so", content: "zlib", typeflag: tar.TypeReg},
+		{name: "etc/hostname", content: "build-a", typeflag: tar.TypeReg},
+	})
+	img2 := testInventoryImage(t, []testTarEntry{
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/os-release", content: "alpine 3.8", typeflag: tar.TypeReg},
+		{name: "bin/busybox", content: "busybox 1.28.4", typeflag: tar.TypeReg},
+		{name: "bin/sh", linkname: "busybox", typeflag: tar.TypeSymlink},
+		{name: "bin/ash", linkname: "bin/busybox", typeflag: tar.TypeLink},
+		{name: "same", content: "unchanged", typeflag: tar.TypeReg},
+		{name: "usr/bin/python3.8", content: "python 3.8", typeflag: tar.TypeReg},
+		{name: "usr/bin/python3.9", content: "python 3.9", typeflag: tar.TypeReg},
+		{name: "usr/bin/python", linkname: "python3.9", typeflag: tar.TypeSymlink},
+		{name: "usr/lib/libz.so.1", content: "zlib", typeflag: tar.TypeReg},
+		{name: "usr/lib/libz.so", linkname: "libz.so.1", typeflag: tar.TypeSymlink},
+		{name: "etc/hostname", content: "build-b", typeflag: tar.TypeReg},
+	})
+
+	root, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	// the extracted filesystems are read-only
+	defer pkgutil.CleanupImage(pkgutil.Image{FSPath: root})
+	dir1, dir2 := filepath.Join(root, "1"), filepath.Join(root, "2")
+	for dir, img := range map[string]v1.Image{dir1: img1, dir2: img2} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Error creating dir: %s", err)
+		}
+		if err := pkgutil.GetFileSystemForImage(img, dir, nil); err != nil {
+			t.Fatalf("Error extracting image: %s", err)
+		}
+	}
+	extracted1, _ := pkgutil.GetDirectory(dir1, true)
+	extracted2, _ := pkgutil.GetDirectory(dir2, true)
+	expected, expectedSame := DiffDirectory(extracted1, extracted2)
+
+	inv1, err := pkgutil.StreamFileInventory(img1, root, nil)
+	if err != nil {
+		t.Fatalf("Error streaming inventory: %s", err)
+	}
+	inv2, err := pkgutil.StreamFileInventory(img2, root, []string{"/etc/os-release", "/usr/lib/**"})
+	if err != nil {
+		t.Fatalf("Error streaming inventory: %s", err)
+	}
+	actual, same := DiffInventories(inv1, inv2)
+	if same != expectedSame {
+		t.Errorf("Expected same: %t but got: %t", expectedSame, same)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+	// the retargeted link keeps its size, and the contents it resolves to
+	// are the same for the file replaced by a link
+	links := map[string]EntryDiff{}
+	for _, mod := range actual.Mods {
+		links[mod.Name] = mod
+	}
+	expectedLinks := map[string]string{
+		"/usr/bin/python":  "/usr/bin/python -> python3.9 (was python3.8)",
+		"/usr/lib/libz.so": "/usr/lib/libz.so -> libz.so.1 (was not a symlink)",
+	}
+	// contents changed in place are told apart by digest
+	if hostname := links["/etc/hostname"]; hostname.Size1 != hostname.Size2 || hostname.Digest1 == hostname.Digest2 || !strings.HasPrefix(hostname.Digest1, "sha256:") {
+		t.Errorf("Expected /etc/hostname to be modified with the same size and different digests but got: %+v", hostname)
+	}
+	for name, text := range expectedLinks {
+		mod, ok := links[name]
+		if !ok {
+			t.Errorf("Expected %s to be modified but got: %v", name, actual.Mods)
+			continue
+		}
+		if actual := stringifyEntryDiffs([]EntryDiff{mod})[0].Name; actual != text {
+			t.Errorf("Expected %s to be shown as %q but got %q", name, text, actual)
+		}
+	}
+
+	materialized, err := ioutil.ReadFile(filepath.Join(root, "etc", "os-release"))
+	if err != nil || string(materialized) != "alpine 3.8" {
+		t.Errorf("Expected materialized /etc/os-release but got: %q, %v", materialized, err)
+	}
+	if materialized, err := ioutil.ReadFile(filepath.Join(root, "usr", "lib", "libz.so.1")); err != nil || string(materialized) != "zlib" {
+		t.Errorf("Expected /usr/lib/libz.so.1 materialized by its glob but got: %q, %v", materialized, err)
+	}
+	for _, name := range []string{"same", "etc/hostname"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected /%s not to be materialized", name)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DotFormat is the --format drawing results as Graphviz graphs instead of
+// text, e.g. for `dot -Tsvg`.
+const DotFormat = "dot"
+
+// DotResult is implemented by the results which can be drawn as a Graphviz
+// graph, one digraph per result.
+type DotResult interface {
+	OutputDot(writer io.Writer) error
+}
+
+// Colors of the nodes and edges of graphs.
+const (
+	dotAdded   = "darkgreen"
+	dotRemoved = "red"
+	dotShared  = "gray"
+)
+
+// dotQuote quotes s as a Graphviz ID. Newlines become line breaks in labels.
+func dotQuote(s string) string {
+	return strconv.Quote(s)
+}
+
+// dotGraph writes a digraph, calling body to write its statements.
+func dotGraph(writer io.Writer, name string, body func(io.Writer)) error {
+	if _, err := fmt.Fprintf(writer, "digraph %s {\n\trankdir=BT;\n\tnode [shape=box];\n", dotQuote(name)); err != nil {
+		return err
+	}
+	body(writer)
+	_, err := fmt.Fprintln(writer, "}")
+	return err
+}
+
+func layerLabel(index string, size, compressed int64) string {
+	return fmt.Sprintf("layer %s\n%s (%s compressed)", index, stringifySize(size), stringifyCompressedSize(compressed))
+}
+
+// OutputDot draws the layers of the image, bottom up.
+func (r SizeLayerAnalyzeResult) OutputDot(writer io.Writer) error {
+	analysis, valid := r.Analysis.([]SizeEntry)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should be of type []SizeEntry")
+		return errors.New("Could not output SizeLayerAnalyzer analysis result")
+	}
+	return dotGraph(writer, r.Image, func(w io.Writer) {
+		for i, entry := range analysis {
+			fmt.Fprintf(w, "\t%s [label=%s];\n", dotQuote(entry.Name), dotQuote(layerLabel(entry.Name, entry.Size, entry.CompressedSize)))
+			if i > 0 {
+				fmt.Fprintf(w, "\t%s -> %s;\n", dotQuote(entry.Name), dotQuote(analysis[i-1].Name))
+			}
+		}
+	})
+}
+
+// OutputDot draws the layers whose size changed, green where they grew and
+// red where they shrank.
+func (r SizeLayerDiffResult) OutputDot(writer io.Writer) error {
+	diff, valid := r.Diff.([]SizeDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should be of type []SizeDiff")
+		return errors.New("Could not output SizeLayerAnalyzer diff result")
+	}
+	return dotGraph(writer, r.Image1+" -> "+r.Image2, func(w io.Writer) {
+		for _, entry := range diff {
+			color := dotAdded
+			if entry.Size2 < entry.Size1 {
+				color = dotRemoved
+			}
+			label := fmt.Sprintf("layer %s\n%s -> %s (%s)", entry.Name, stringifySize(entry.Size1), stringifySize(entry.Size2), stringifySizeChange(entry.Size1, entry.Size2))
+			fmt.Fprintf(w, "\t%s [label=%s, color=%s];\n", dotQuote(entry.Name), dotQuote(label), color)
+		}
+	})
+}
+
+// OutputDot draws the layers of both images, the ones they share once.
+func (r SharedLayerDiffResult) OutputDot(writer io.Writer) error {
+	diff, valid := r.Diff.(SharedLayerDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the SharedLayerDiff struct")
+		return errors.New("Could not output SharedLayersAnalyzer diff result")
+	}
+	return dotGraph(writer, r.Image1+" -> "+r.Image2, func(w io.Writer) {
+		nodes1, nodes2 := map[int]string{}, map[int]string{}
+		for _, layer := range diff.Shared {
+			node := "shared " + layer.Digest.String()
+			nodes1[layer.Index1], nodes2[layer.Index2] = node, node
+			fmt.Fprintf(w, "\t%s [label=%s, color=%s];\n", dotQuote(node), dotQuote(fmt.Sprintf("%s\n%s", shortDigest(layer.Digest.String()), stringifySize(layer.Size))), dotShared)
+		}
+		writeImageLayers(w, r.Image1, diff.Unique1, nodes1, dotRemoved)
+		writeImageLayers(w, r.Image2, diff.Unique2, nodes2, dotAdded)
+	})
+}
+
+// writeImageLayers draws the unique layers of an image and chains all its
+// layers, stacked on the image node.
+func writeImageLayers(w io.Writer, image string, unique []LayerRef, nodes map[int]string, color string) {
+	for _, layer := range unique {
+		node := image + " " + strconv.Itoa(layer.Index)
+		nodes[layer.Index] = node
+		fmt.Fprintf(w, "\t%s [label=%s, color=%s];\n", dotQuote(node), dotQuote(fmt.Sprintf("%s\n%s", shortDigest(layer.Digest.String()), stringifySize(layer.Size))), color)
+	}
+	indexes := []int{}
+	for index := range nodes {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	fmt.Fprintf(w, "\t%s [shape=ellipse];\n", dotQuote(image))
+	previous := ""
+	for _, index := range indexes {
+		if previous != "" {
+			fmt.Fprintf(w, "\t%s -> %s [color=%s];\n", dotQuote(nodes[index]), dotQuote(previous), color)
+		}
+		previous = nodes[index]
+	}
+	if previous != "" {
+		fmt.Fprintf(w, "\t%s -> %s [color=%s];\n", dotQuote(image), dotQuote(previous), color)
+	}
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
+
+// OutputDot draws the dependencies of the packages, with roots in bold and
+// the packages apt-get autoremove would remove dashed.
+func (r AptDepsAnalyzeResult) OutputDot(writer io.Writer) error {
+	analysis, valid := r.Analysis.(DependencyAnalysis)
+	if !valid {
+		logrus.Error("Unexpected structure of Analysis.  Should follow the DependencyAnalysis struct")
+		return errors.New("Could not output AptDepsAnalyzer analysis result")
+	}
+	return dotGraph(writer, r.Image, func(w io.Writer) {
+		fmt.Fprintln(w, "\trankdir=LR;")
+		for _, name := range analysis.Roots {
+			fmt.Fprintf(w, "\t%s [style=bold];\n", dotQuote(name))
+		}
+		for _, name := range analysis.Removable {
+			fmt.Fprintf(w, "\t%s [style=dashed];\n", dotQuote(name))
+		}
+		for _, pkg := range analysis.Packages {
+			fmt.Fprintf(w, "\t%s;\n", dotQuote(pkg.Name))
+			for _, dep := range pkg.Depends {
+				fmt.Fprintf(w, "\t%s -> %s;\n", dotQuote(pkg.Name), dotQuote(dep))
+			}
+		}
+	})
+}
+
+// OutputDot draws the dependency deltas: added packages and dependencies in
+// green, removed dependencies in red, and new roots in bold.
+func (r AptDepsDiffResult) OutputDot(writer io.Writer) error {
+	diff, valid := r.Diff.(DependencyDiff)
+	if !valid {
+		logrus.Error("Unexpected structure of Diff.  Should follow the DependencyDiff struct")
+		return errors.New("Could not output AptDepsAnalyzer diff result")
+	}
+	return dotGraph(writer, r.Image1+" -> "+r.Image2, func(w io.Writer) {
+		fmt.Fprintln(w, "\trankdir=LR;")
+		for _, name := range diff.NewRoots {
+			fmt.Fprintf(w, "\t%s [style=bold];\n", dotQuote(name))
+		}
+		for _, pkg := range diff.Added {
+			fmt.Fprintf(w, "\t%s [color=%s];\n", dotQuote(pkg.Name), dotAdded)
+			for _, by := range pkg.RequiredBy {
+				fmt.Fprintf(w, "\t%s -> %s [color=%s];\n", dotQuote(by), dotQuote(pkg.Name), dotAdded)
+			}
+		}
+		for _, change := range diff.ReverseDepsChanged {
+			for _, by := range change.Added {
+				fmt.Fprintf(w, "\t%s -> %s [color=%s];\n", dotQuote(by), dotQuote(change.Name), dotAdded)
+			}
+			for _, by := range change.Removed {
+				fmt.Fprintf(w, "\t%s -> %s [color=%s, style=dashed];\n", dotQuote(by), dotQuote(change.Name), dotRemoved)
+			}
+		}
+	})
+}
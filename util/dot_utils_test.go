@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestOutputDot(t *testing.T) {
+	base := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+	top1 := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("b", 64)}
+	top2 := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("c", 64)}
+	testCases := []struct {
+		descrip  string
+		result   DotResult
+		expected []string
+	}{
+		{
+			descrip: "shared layers",
+			result: SharedLayerDiffResult{
+				Image1:   "image1",
+				Image2:   "image2",
+				DiffType: "SharedLayers",
+				Diff: SharedLayerDiff{
+					Shared:  []SharedLayer{{Digest: base, Size: 10}},
+					Unique1: []LayerRef{{Index: 1, Digest: top1, Size: 20}},
+					Unique2: []LayerRef{{Index: 1, Digest: top2, Size: 30}},
+				},
+			},
+			expected: []string{
+				`digraph "image1 -> image2" {`,
+				`"shared ` + base.String() + `" [label="sha256:aaaaaaaaaaaa\n10B", color=gray];`,
+				`"image1 1" -> "shared ` + base.String() + `" [color=red];`,
+				`"image2 1" -> "shared ` + base.String() + `" [color=darkgreen];`,
+				`"image2" -> "image2 1" [color=darkgreen];`,
+			},
+		},
+		{
+			descrip: "apt dependencies",
+			result: AptDepsDiffResult{
+				Image1:   "image1",
+				Image2:   "image2",
+				DiffType: "AptDeps",
+				Diff: DependencyDiff{
+					NewRoots: []string{"curl"},
+					Added:    []PackageReason{{Name: "curl"}, {Name: "libcurl4", RequiredBy: []string{"curl"}}},
+					ReverseDepsChanged: []ReverseDependencyChange{
+						{Name: "libc6", Removed: []string{"wget"}},
+					},
+				},
+			},
+			expected: []string{
+				`"curl" [style=bold];`,
+				`"libcurl4" [color=darkgreen];`,
+				`"curl" -> "libcurl4" [color=darkgreen];`,
+				`"wget" -> "libc6" [color=red, style=dashed];`,
+			},
+		},
+	}
+	for _, test := range testCases {
+		var buf bytes.Buffer
+		if err := test.result.OutputDot(&buf); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.descrip, err)
+			continue
+		}
+		out := buf.String()
+		if !strings.HasSuffix(out, "}\n") {
+			t.Errorf("%s: graph isn't closed:\n%s", test.descrip, out)
+		}
+		for _, line := range test.expected {
+			if !strings.Contains(out, line) {
+				t.Errorf("%s: expected %s in:\n%s", test.descrip, line, out)
+			}
+		}
+	}
+}
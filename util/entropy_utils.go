@@ -0,0 +1,208 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DirEntropy stores how well the files below a directory compress. Sizes
+// are in bytes; CompressedSize is the sum of the files gzipped one by one,
+// and DuplicateSize the bytes of files whose contents already appeared
+// earlier in the image.
+type DirEntropy struct {
+	Path           string
+	Files          int
+	Size           int64
+	CompressedSize int64
+	DuplicateSize  int64
+}
+
+// DuplicateContent lists the paths storing the same bytes.
+type DuplicateContent struct {
+	Digest string
+	Size   int64
+	Paths  []string
+}
+
+// EntropyAnalysis stores the compressibility and duplicate content of an
+// image filesystem, overall and per directory.
+type EntropyAnalysis struct {
+	Size           int64
+	CompressedSize int64
+	DuplicateSize  int64
+	Dirs           []DirEntropy
+	Duplicates     []DuplicateContent
+}
+
+// DirEntropyDelta stores the entropy figures of a directory in two images.
+type DirEntropyDelta struct {
+	Path            string
+	Size1           int64
+	Size2           int64
+	CompressedSize1 int64
+	CompressedSize2 int64
+	DuplicateSize1  int64
+	DuplicateSize2  int64
+}
+
+// EntropyDiff stores the entropy figures of two images, and the
+// directories whose figures changed, largest compressed size change first.
+type EntropyDiff struct {
+	Size1           int64
+	Size2           int64
+	CompressedSize1 int64
+	CompressedSize2 int64
+	DuplicateSize1  int64
+	DuplicateSize2  int64
+	Dirs            []DirEntropyDelta
+}
+
+// SortEntropyAnalysis orders directories by path and duplicates by the
+// bytes they waste, most first.
+func SortEntropyAnalysis(analysis EntropyAnalysis) EntropyAnalysis {
+	sort.Slice(analysis.Dirs, func(i, j int) bool { return analysis.Dirs[i].Path < analysis.Dirs[j].Path })
+	sort.Slice(analysis.Duplicates, func(i, j int) bool {
+		wasted := func(d DuplicateContent) int64 { return d.Size * int64(len(d.Paths)-1) }
+		if wasted(analysis.Duplicates[i]) != wasted(analysis.Duplicates[j]) {
+			return wasted(analysis.Duplicates[i]) > wasted(analysis.Duplicates[j])
+		}
+		return analysis.Duplicates[i].Paths[0] < analysis.Duplicates[j].Paths[0]
+	})
+	return analysis
+}
+
+// GetEntropyDiff compares the entropy analyses of two images.
+func GetEntropyDiff(analysis1, analysis2 EntropyAnalysis) EntropyDiff {
+	diff := EntropyDiff{
+		Size1:           analysis1.Size,
+		Size2:           analysis2.Size,
+		CompressedSize1: analysis1.CompressedSize,
+		CompressedSize2: analysis2.CompressedSize,
+		DuplicateSize1:  analysis1.DuplicateSize,
+		DuplicateSize2:  analysis2.DuplicateSize,
+		Dirs:            []DirEntropyDelta{},
+	}
+	dirs := map[string]*DirEntropyDelta{}
+	get := func(path string) *DirEntropyDelta {
+		if _, ok := dirs[path]; !ok {
+			dirs[path] = &DirEntropyDelta{Path: path}
+		}
+		return dirs[path]
+	}
+	for _, d := range analysis1.Dirs {
+		delta := get(d.Path)
+		delta.Size1, delta.CompressedSize1, delta.DuplicateSize1 = d.Size, d.CompressedSize, d.DuplicateSize
+	}
+	for _, d := range analysis2.Dirs {
+		delta := get(d.Path)
+		delta.Size2, delta.CompressedSize2, delta.DuplicateSize2 = d.Size, d.CompressedSize, d.DuplicateSize
+	}
+	for _, delta := range dirs {
+		if delta.Size1 != delta.Size2 || delta.CompressedSize1 != delta.CompressedSize2 || delta.DuplicateSize1 != delta.DuplicateSize2 {
+			diff.Dirs = append(diff.Dirs, *delta)
+		}
+	}
+	sort.Slice(diff.Dirs, func(i, j int) bool {
+		ci := abs(diff.Dirs[i].CompressedSize2 - diff.Dirs[i].CompressedSize1)
+		cj := abs(diff.Dirs[j].CompressedSize2 - diff.Dirs[j].CompressedSize1)
+		if ci != cj {
+			return ci > cj
+		}
+		return diff.Dirs[i].Path < diff.Dirs[j].Path
+	})
+	return diff
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// compressionRatio formats compressed size as a percentage of size.
+func compressionRatio(size, compressed int64) string {
+	if size == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(compressed)*100/float64(size))
+}
+
+type StrDirEntropy struct {
+	Path       string
+	Files      int
+	Size       string
+	Compressed string
+	Ratio      string
+	Duplicate  string
+}
+
+type StrDuplicateContent struct {
+	Size  string
+	Paths []string
+}
+
+type StrDirEntropyDelta struct {
+	Path       string
+	Size1      string
+	Size2      string
+	Ratio1     string
+	Ratio2     string
+	Duplicate1 string
+	Duplicate2 string
+}
+
+func stringifyDirEntropies(dirs []DirEntropy) []StrDirEntropy {
+	rows := []StrDirEntropy{}
+	for _, d := range dirs {
+		rows = append(rows, StrDirEntropy{
+			Path:       d.Path,
+			Files:      d.Files,
+			Size:       stringifySize(d.Size),
+			Compressed: stringifySize(d.CompressedSize),
+			Ratio:      compressionRatio(d.Size, d.CompressedSize),
+			Duplicate:  stringifySize(d.DuplicateSize),
+		})
+	}
+	return rows
+}
+
+func stringifyDuplicateContents(duplicates []DuplicateContent) []StrDuplicateContent {
+	rows := []StrDuplicateContent{}
+	for _, d := range duplicates {
+		rows = append(rows, StrDuplicateContent{Size: stringifySize(d.Size), Paths: d.Paths})
+	}
+	return rows
+}
+
+func stringifyDirEntropyDeltas(dirs []DirEntropyDelta) []StrDirEntropyDelta {
+	rows := []StrDirEntropyDelta{}
+	for _, d := range dirs {
+		rows = append(rows, StrDirEntropyDelta{
+			Path:       d.Path,
+			Size1:      stringifySize(d.Size1),
+			Size2:      stringifySize(d.Size2),
+			Ratio1:     compressionRatio(d.Size1, d.CompressedSize1),
+			Ratio2:     compressionRatio(d.Size2, d.CompressedSize2),
+			Duplicate1: stringifySize(d.DuplicateSize1),
+			Duplicate2: stringifySize(d.DuplicateSize2),
+		})
+	}
+	return rows
+}
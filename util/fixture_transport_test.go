@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// serveImage answers pulls of img as the repository app, with a token from
+// the token service at /token.
+func serveImage(img v1.Image) *httptest.Server {
+	manifest, _ := img.RawManifest()
+	config, _ := img.RawConfigFile()
+	configDigest, _ := img.ConfigName()
+	blobs := map[string][]byte{configDigest.String(): config}
+	layers, _ := img.Layers()
+	for _, layer := range layers {
+		digest, _ := layer.Digest()
+		reader, _ := layer.Compressed()
+		blob, _ := ioutil.ReadAll(reader)
+		blobs[digest.String()] = blob
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token": "secret-token"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/v2/app/manifests/v1":
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/app/blobs/") && blobs[path.Base(r.URL.Path)] != nil:
+			w.Write(blobs[path.Base(r.URL.Path)])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestRecordReplay(t *testing.T) {
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	digest, _ := img.Digest()
+	dir, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer pkgutil.ConfigureFixtures("", "")
+	server := serveImage(img)
+	source := "remote://" + strings.TrimPrefix(server.URL, "http://") + "/app:v1"
+
+	if err := pkgutil.ConfigureFixtures(dir, ""); err != nil {
+		t.Fatalf("Error configuring the recording: %s", err)
+	}
+	image, err := pkgutil.GetImage(source, false, "")
+	if err != nil {
+		t.Fatalf("Error recording %s: %s", source, err)
+	}
+	pkgutil.CleanupImage(image)
+	server.Close()
+	recording, err := ioutil.ReadFile(filepath.Join(dir, "requests.jsonl"))
+	if err != nil {
+		t.Fatalf("Error reading the recording: %s", err)
+	}
+	bodies, _ := filepath.Glob(filepath.Join(dir, "bodies", "*"))
+	for _, body := range append(bodies, filepath.Join(dir, "requests.jsonl")) {
+		if contents, _ := ioutil.ReadFile(body); bytes.Contains(contents, []byte("secret-token")) {
+			t.Errorf("Expected the token redacted from %s", body)
+		}
+	}
+	if !bytes.Contains(recording, []byte("/v2/app/manifests/v1")) {
+		t.Errorf("Expected the manifest request recorded, got %s", recording)
+	}
+
+	if err := pkgutil.ConfigureFixtures("", dir); err != nil {
+		t.Fatalf("Error configuring the replay: %s", err)
+	}
+	image, err = pkgutil.GetImage(source, false, "")
+	if err != nil {
+		t.Fatalf("Error replaying %s: %s", source, err)
+	}
+	defer pkgutil.CleanupImage(image)
+	if image.Digest != digest {
+		t.Errorf("Expected image %s replayed but got %s", digest, image.Digest)
+	}
+	if _, err := pkgutil.GetImage(strings.Replace(source, ":v1", ":v2", 1), false, ""); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Expected an error for a request missing from the recording, got %v", err)
+	}
+
+	if err := pkgutil.ConfigureFixtures(dir, dir); err == nil {
+		t.Errorf("Expected an error recording and replaying at once")
+	}
+}
@@ -18,9 +18,13 @@ package util
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"text/template"
@@ -31,6 +35,7 @@ import (
 var templates = map[string]string{
 	"SingleVersionPackageDiff":         SingleVersionDiffOutput,
 	"MultiVersionPackageDiff":          MultiVersionDiffOutput,
+	"NodeDiff":                         NodeDiffOutput,
 	"HistDiff":                         HistoryDiffOutput,
 	"MetadataDiff":                     MetadataDiffOutput,
 	"DirDiff":                          FSDiffOutput,
@@ -44,8 +49,131 @@ var templates = map[string]string{
 	"SizeDiff":                         SizeDiffOutput,
 	"SizeLayerDiff":                    SizeLayerDiffOutput,
 	"MultiVersionPackageAnalyze":       MultiVersionPackageOutput,
+	"NodeAnalyze":                      NodePackageOutput,
 	"SingleVersionPackageAnalyze":      SingleVersionPackageOutput,
 	"SingleVersionPackageLayerAnalyze": SingleVersionPackageLayerOutput,
+	"AlternativesAnalyze":              AlternativesAnalysisOutput,
+	"AlternativesDiff":                 AlternativesDiffOutput,
+	"AdvisoryAnalyze":                  AdvisoryAnalysisOutput,
+	"AdvisoryDiff":                     AdvisoryDiffOutput,
+	"LayerSuggestDiff":                 LayerSuggestDiffOutput,
+	"ReproAnalyze":                     ReproAnalysisOutput,
+	"ReproDiff":                        ReproDiffOutput,
+	"InitAnalyze":                      InitAnalysisOutput,
+	"InitDiff":                         InitDiffOutput,
+	"ScoreAnalyze":                     ScoreAnalysisOutput,
+	"ScoreDiff":                        ScoreDiffOutput,
+	"AptDepsAnalyze":                   AptDepsAnalysisOutput,
+	"AptDepsDiff":                      AptDepsDiffOutput,
+	"SystemdAnalyze":                   SystemdAnalysisOutput,
+	"SystemdDiff":                      SystemdDiffOutput,
+	"ServiceAnalyze":                   ServiceAnalysisOutput,
+	"ServiceDiff":                      ServiceDiffOutput,
+	"CronAnalyze":                      CronAnalysisOutput,
+	"CronDiff":                         CronDiffOutput,
+	"UserAnalyze":                      UserAnalysisOutput,
+	"UserDiff":                         UserDiffOutput,
+	"CertificateAnalyze":               CertificateAnalysisOutput,
+	"CertificateDiff":                  CertificateDiffOutput,
+	"BannerAnalyze":                    BannerAnalysisOutput,
+	"BannerDiff":                       BannerDiffOutput,
+	"RuntimesAnalyze":                  RuntimesAnalysisOutput,
+	"RuntimesDiff":                     RuntimesDiffOutput,
+	"ProvenanceAnalyze":                ProvenanceAnalysisOutput,
+	"ProvenanceDiff":                   ProvenanceDiffOutput,
+	"IdenticalDiff":                    IdenticalDiffOutput,
+	"PartialDiff":                      PartialDiffOutput,
+	"PlatformDiff":                     PlatformDiffOutput,
+	"RPMRepoAnalyze":                   RPMRepoAnalysisOutput,
+	"RPMRepoDiff":                      RPMRepoDiffOutput,
+	"KeyringAnalyze":                   KeyringAnalysisOutput,
+	"KeyringDiff":                      KeyringDiffOutput,
+	"EntropyAnalyze":                   EntropyAnalysisOutput,
+	"EntropyDiff":                      EntropyDiffOutput,
+	"LinkerAnalyze":                    LinkerAnalysisOutput,
+	"LinkerDiff":                       LinkerDiffOutput,
+	"ConffileAnalyze":                  ConffileAnalysisOutput,
+	"ConffileDiff":                     ConffileDiffOutput,
+	"OSAnalyze":                        OSAnalysisOutput,
+	"OSDiff":                           OSDiffOutput,
+	"BuildInfoAnalyze":                 BuildInfoAnalysisOutput,
+	"BuildInfoDiff":                    BuildInfoDiffOutput,
+	"PermissionAnalyze":                PermissionAnalysisOutput,
+	"PermissionDiff":                   PermissionDiffOutput,
+	"VolumeAnalyze":                    VolumeAnalysisOutput,
+	"VolumeDiff":                       VolumeDiffOutput,
+	"SharedLayerDiff":                  SharedLayerDiffOutput,
+	"SecretAnalyze":                    SecretAnalysisOutput,
+	"SecretDiff":                       SecretDiffOutput,
+	"BinaryAnalyze":                    BinaryAnalysisOutput,
+	"BinaryDiff":                       BinaryDiffOutput,
+	"Rollup":                           RollupOutput,
+	"RollupImages":                     RollupImagesOutput,
+}
+
+// OutputOptions are the options of one output of results, passed along
+// with it rather than set globally so that results can be output
+// concurrently with different options.
+type OutputOptions struct {
+	// Format is a Go template to output text with, or dot to draw graphs.
+	// The built-in templates are used when it is empty.
+	Format string
+	// SortSize sorts file and package results by descending size rather
+	// than by name.
+	SortSize bool
+	// MaxResults caps the number of entries printed for each list in text
+	// output, with the rest summarized by a count. JSON output is never
+	// truncated. Zero means no limit.
+	MaxResults int
+	// Columns selects, in order, the columns printed in text tables, by
+	// header name, e.g. name and size. Tables with none of them are printed
+	// in full.
+	Columns []string
+	// ColumnNames renames table headers, e.g. SIZE to Bytes.
+	ColumnNames map[string]string
+}
+
+// templateFuncs are available to the built-in templates as well as to user
+// supplied formats.
+func templateFuncs(opts OutputOptions) template.FuncMap {
+	return template.FuncMap{
+		"join": strings.Join,
+		"limit": func(list interface{}) interface{} {
+			return limitResults(list, opts.MaxResults)
+		},
+		"more": func(list interface{}) string {
+			return moreResults(list, opts.MaxResults)
+		},
+	}
+}
+
+// limitResults returns at most max entries of list. Values which are not
+// slices are returned unchanged.
+func limitResults(list interface{}, max int) interface{} {
+	v := reflect.ValueOf(list)
+	if max <= 0 || v.Kind() != reflect.Slice || v.Len() <= max {
+		return list
+	}
+	return v.Slice(0, max).Interface()
+}
+
+// moreResults describes the entries of list left out by limitResults, or
+// returns an empty string if none were.
+func moreResults(list interface{}, max int) string {
+	v := reflect.ValueOf(list)
+	if max <= 0 || v.Kind() != reflect.Slice || v.Len() <= max {
+		return ""
+	}
+	return fmt.Sprintf("...and %s more (see JSON for full list)", formatCount(v.Len()-max))
+}
+
+// formatCount formats n with thousands separators.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
 }
 
 func JSONify(writer io.Writer, diff interface{}) error {
@@ -66,37 +194,38 @@ func getTemplate(templateType string) (string, error) {
 	return "", errors.New("No available template")
 }
 
-func TemplateOutput(writer io.Writer, diff interface{}, templateType string) error {
+func TemplateOutput(writer io.Writer, diff interface{}, templateType string, opts OutputOptions) error {
 	outputTmpl, err := getTemplate(templateType)
 	if err != nil {
 		logrus.Error(err)
 	}
-	funcs := template.FuncMap{"join": strings.Join}
-	tmpl, err := template.New("tmpl").Funcs(funcs).Parse(outputTmpl)
+	tmpl, err := template.New("tmpl").Funcs(templateFuncs(opts)).Parse(outputTmpl)
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
-	w := tabwriter.NewWriter(writer, 8, 8, 8, ' ', 0)
-	err = tmpl.Execute(w, diff)
+	var output bytes.Buffer
+	err = tmpl.Execute(&output, diff)
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
+	w := tabwriter.NewWriter(writer, 8, 8, 8, ' ', 0)
+	io.WriteString(w, formatColumns(output.String(), opts.Columns, opts.ColumnNames))
 	w.Flush()
 	return nil
 }
 
-func TemplateOutputFromFormat(writer io.Writer, diff interface{}, templateType string, format string) error {
-	if format == "" {
-		return TemplateOutput(writer, diff, templateType)
+func TemplateOutputFromFormat(writer io.Writer, diff interface{}, templateType string, opts OutputOptions) error {
+	// results drawn as graphs are printed as text wherever graphs don't fit
+	if opts.Format == "" || opts.Format == DotFormat {
+		return TemplateOutput(writer, diff, templateType, opts)
 	}
-	funcs := template.FuncMap{"join": strings.Join}
-	tmpl, err := template.New("tmpl").Funcs(funcs).Parse(format)
+	tmpl, err := template.New("tmpl").Funcs(templateFuncs(opts)).Parse(opts.Format)
 	if err != nil {
 		logrus.Warningf("User specified format resulted in error, printing default output.")
 		logrus.Error(err)
-		return TemplateOutput(writer, diff, templateType)
+		return TemplateOutput(writer, diff, templateType, opts)
 	}
 	w := tabwriter.NewWriter(writer, 8, 8, 8, ' ', 0)
 	err = tmpl.Execute(w, diff)
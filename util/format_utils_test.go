@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplatesParse(t *testing.T) {
+	for name, tmpl := range templates {
+		if _, err := template.New(name).Funcs(templateFuncs(OutputOptions{})).Parse(tmpl); err != nil {
+			t.Errorf("Error parsing template %s: %s", name, err)
+		}
+	}
+}
+
+func TestMaxResults(t *testing.T) {
+	// the history template lists lines of Adds and Dels, and the metadata
+	// template the rows of Changes
+	lines := HistDiffResult{
+		Image1:   "image1",
+		Image2:   "image2",
+		DiffType: "History",
+		Diff: struct {
+			Adds []string
+			Dels []string
+		}{
+			Adds: []string{"a", "b", "c", "d"},
+			Dels: []string{"e"},
+		},
+	}
+	changes := MetadataDiffResult{
+		Image1:   "image1",
+		Image2:   "image2",
+		DiffType: "Metadata",
+		Diff: struct {
+			Changes []struct{ Field, Key, Op, Value1, Value2 string }
+		}{
+			Changes: []struct{ Field, Key, Op, Value1, Value2 string }{
+				{Field: "a"}, {Field: "b"}, {Field: "c"}, {Field: "d"},
+			},
+		},
+	}
+	testCases := []struct {
+		result   Result
+		max      int
+		expected []string
+		missing  []string
+	}{
+		{result: lines, max: 0, expected: []string{"-a", "-d", "-e"}, missing: []string{"more"}},
+		{result: lines, max: 2, expected: []string{"-a", "-b", "...and 2 more (see JSON for full list)", "-e"}, missing: []string{"-c", "-d"}},
+		{result: lines, max: 4, expected: []string{"-a", "-d", "-e"}, missing: []string{"more"}},
+		{result: changes, max: 0, expected: []string{"-a", "-d"}, missing: []string{"more"}},
+		{result: changes, max: 2, expected: []string{"-a", "-b", "...and 2 more (see JSON for full list)"}, missing: []string{"-c", "-d"}},
+		{result: changes, max: 4, expected: []string{"-a", "-d"}, missing: []string{"more"}},
+	}
+	for _, test := range testCases {
+		var buf bytes.Buffer
+		if err := test.result.OutputText(&buf, "", OutputOptions{MaxResults: test.max}); err != nil {
+			t.Fatalf("Error writing output: %s", err)
+		}
+		out := buf.String()
+		for _, s := range test.expected {
+			if !strings.Contains(out, s) {
+				t.Errorf("max %d: expected %q in output:\n%s", test.max, s, out)
+			}
+		}
+		for _, s := range test.missing {
+			if strings.Contains(out, s) {
+				t.Errorf("max %d: unexpected %q in output:\n%s", test.max, s, out)
+			}
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, expected := range map[int]string{0: "0", 999: "999", 1000: "1,000", 4312: "4,312", 1234567: "1,234,567"} {
+		if actual := formatCount(n); actual != expected {
+			t.Errorf("Expected %s but got %s", expected, actual)
+		}
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package util
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -179,3 +182,47 @@ func TestHasFilepathPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestWalkReadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walk")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a/b.txt", "locked/c.txt", "skipped/d.txt"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	}
+	locked := filepath.Join(dir, "locked")
+	os.Chmod(locked, 0)
+	defer os.Chmod(locked, 0755)
+
+	walked := []string{}
+	err = pkgutil.WalkReadable(dir, func(path string, info os.FileInfo) error {
+		if info.IsDir() && info.Name() == "skipped" {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(dir, path)
+		walked = append(walked, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error walking %s: %s", dir, err)
+	}
+	expected := []string{".", "a", "a/b.txt", "locked"}
+	if _, err := ioutil.ReadDir(locked); err == nil {
+		// root reads the directory regardless
+		expected = append(expected, "locked/c.txt")
+	}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Expected the readable files %v, got %v", expected, walked)
+	}
+
+	walked = []string{}
+	if err := pkgutil.WalkReadable(filepath.Join(dir, "missing"), func(path string, info os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	}); err != nil || len(walked) != 0 {
+		t.Errorf("Expected a missing root to be skipped, got %v: %v", walked, err)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// DirHotspot is the net size change of the files below a directory between
+// two images, with how many of them were added, deleted or modified.
+type DirHotspot struct {
+	Dir      string
+	Size1    int64
+	Size2    int64
+	Change   int64
+	Added    int
+	Deleted  int
+	Modified int
+}
+
+// GetHotspots aggregates the size changes of diff by directory, cut at depth
+// path components, e.g. /usr/lib at depth 2, and returns the top directories
+// that grew or shrank the most, all of them if top is 0. Directories whose
+// entries are listed too are left out of the sums, as their sizes already
+// include those of their entries.
+func GetHotspots(diff DirDiff, depth, top int) []DirHotspot {
+	ancestors := map[string]bool{}
+	addAncestors := func(name string) {
+		for dir := path.Dir(name); dir != "/" && dir != "." && !ancestors[dir]; dir = path.Dir(dir) {
+			ancestors[dir] = true
+		}
+	}
+	for _, entry := range diff.Adds {
+		addAncestors(entry.Name)
+	}
+	for _, entry := range diff.Dels {
+		addAncestors(entry.Name)
+	}
+	for _, entry := range diff.Mods {
+		addAncestors(entry.Name)
+	}
+
+	hotspots := map[string]*DirHotspot{}
+	hotspot := func(name string) *DirHotspot {
+		dir := hotspotDir(name, depth)
+		h, ok := hotspots[dir]
+		if !ok {
+			h = &DirHotspot{Dir: dir}
+			hotspots[dir] = h
+		}
+		return h
+	}
+	for _, entry := range diff.Adds {
+		if !ancestors[entry.Name] {
+			h := hotspot(entry.Name)
+			h.Size2 += knownSize(entry.Size)
+			h.Added++
+		}
+	}
+	for _, entry := range diff.Dels {
+		if !ancestors[entry.Name] {
+			h := hotspot(entry.Name)
+			h.Size1 += knownSize(entry.Size)
+			h.Deleted++
+		}
+	}
+	for _, entry := range diff.Mods {
+		if !ancestors[entry.Name] {
+			h := hotspot(entry.Name)
+			h.Size1 += knownSize(entry.Size1)
+			h.Size2 += knownSize(entry.Size2)
+			h.Modified++
+		}
+	}
+
+	result := []DirHotspot{}
+	for _, h := range hotspots {
+		h.Change = h.Size2 - h.Size1
+		result = append(result, *h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := absSize(result[i].Change), absSize(result[j].Change)
+		if ci != cj {
+			return ci > cj
+		}
+		return result[i].Dir < result[j].Dir
+	})
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
+
+// hotspotDir returns the directory of name cut at depth path components.
+func hotspotDir(name string, depth int) string {
+	dir := path.Dir(path.Clean("/" + name))
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if dir == "/" {
+		parts = nil
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// knownSize counts sizes which couldn't be read, which are -1, as empty.
+func knownSize(size int64) int64 {
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+func absSize(size int64) int64 {
+	if size < 0 {
+		return -size
+	}
+	return size
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func TestGetHotspots(t *testing.T) {
+	diff := DirDiff{
+		Adds: []pkgutil.DirectoryEntry{
+			// the directory's size is that of its files, counted once
+			{Name: "/usr/lib/python3", Size: 700},
+			{Name: "/usr/lib/python3/a.py", Size: 300},
+			{Name: "/usr/lib/python3/b.py", Size: 400},
+			{Name: "/usr/share/doc/README", Size: 50},
+			{Name: "/app", Size: 10},
+		},
+		Dels: []pkgutil.DirectoryEntry{
+			{Name: "/usr/share/man/man1/ls.1", Size: 500},
+			{Name: "/var/cache/apt/pkgcache.bin", Size: -1},
+		},
+		Mods: []EntryDiff{
+			{Name: "/usr/lib/libc.so.6", Size1: 1000, Size2: 1200},
+			{Name: "/usr/share/doc/LICENSE", Size1: 100, Size2: 60},
+		},
+	}
+	expected := []DirHotspot{
+		{Dir: "/usr/lib", Size1: 1000, Size2: 1900, Change: 900, Added: 2, Modified: 1},
+		{Dir: "/usr/share", Size1: 600, Size2: 110, Change: -490, Added: 1, Deleted: 1, Modified: 1},
+		{Dir: "/", Size2: 10, Change: 10, Added: 1},
+	}
+	if hotspots := GetHotspots(diff, 2, 3); !reflect.DeepEqual(hotspots, expected) {
+		t.Errorf("Expected hotspots %+v but got %+v", expected, hotspots)
+	}
+
+	deeper := GetHotspots(diff, 3, 0)
+	dirs := []string{}
+	for _, h := range deeper {
+		dirs = append(dirs, h.Dir)
+	}
+	expectedDirs := []string{"/usr/lib/python3", "/usr/share/man", "/usr/lib", "/", "/usr/share/doc", "/var/cache/apt"}
+	if !reflect.DeepEqual(dirs, expectedDirs) {
+		t.Errorf("Expected directories %v but got %v", expectedDirs, dirs)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestCheckIdentical(t *testing.T) {
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	other, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	manifests, blobs := map[string][]byte{}, map[string][]byte{}
+	for _, image := range []v1.Image{img, other} {
+		config, _ := image.RawConfigFile()
+		configDigest, _ := image.ConfigName()
+		blobs[configDigest.String()] = config
+		layers, _ := image.Layers()
+		for _, layer := range layers {
+			digest, _ := layer.Digest()
+			reader, _ := layer.Compressed()
+			blobs[digest.String()], _ = ioutil.ReadAll(reader)
+		}
+	}
+	manifests["v1"], _ = img.RawManifest()
+	manifests["v2"] = manifests["v1"]
+	manifests["other"], _ = other.RawManifest()
+	// a rebuild with the same config and layers, whose manifest differs
+	manifest, _ := img.Manifest()
+	manifest.Annotations = map[string]string{"org.opencontainers.image.created": "2018-06-01T00:00:00Z"}
+	manifests["rebuilt"], _ = json.Marshal(manifest)
+	// a manifest list holding the first image
+	digest, _ := img.Digest()
+	manifests[digest.String()] = manifests["v1"]
+	manifests["list"], _ = json.Marshal(v1.IndexManifest{SchemaVersion: 2, MediaType: types.OCIImageIndex, Manifests: []v1.Descriptor{
+		{MediaType: types.DockerManifestSchema2, Size: int64(len(manifests["v1"])), Digest: digest, Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+	}})
+	defer pkgutil.ConfigurePlatform("")
+	pkgutil.ConfigurePlatform("linux/amd64")
+	registry := serveRegistry(manifests, blobs)
+	defer registry.Close()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		rec := httptest.NewRecorder()
+		registry.Config.Handler.ServeHTTP(rec, r)
+		for key, values := range rec.Header() {
+			// registries which don't send the digest get the manifest hashed
+			if key == "Docker-Content-Digest" && strings.HasSuffix(r.URL.Path, "/rebuilt") {
+				continue
+			}
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer server.Close()
+	repo := "remote://" + strings.TrimPrefix(server.URL, "http://") + "/app:"
+
+	tests := []struct {
+		descrip       string
+		image2        string
+		compareLayers bool
+		identical     bool
+	}{
+		{descrip: "same digest", image2: "v2", identical: true},
+		{descrip: "same digest with layers", image2: "v2", compareLayers: true, identical: true},
+		{descrip: "same layers", image2: "rebuilt"},
+		{descrip: "same layers with layers", image2: "rebuilt", compareLayers: true, identical: true},
+		{descrip: "different layers", image2: "other"},
+		{descrip: "different layers with layers", image2: "other", compareLayers: true},
+		{descrip: "manifest list", image2: "list"},
+		{descrip: "manifest list with layers", image2: "list", compareLayers: true, identical: true},
+	}
+	for _, test := range tests {
+		requests = nil
+		identical, reason, err := pkgutil.CheckIdentical(repo+"v1", repo+test.image2, test.compareLayers)
+		if err != nil {
+			t.Fatalf("%s: error checking images: %s", test.descrip, err)
+		}
+		if identical != test.identical {
+			t.Errorf("%s: expected identical %t but got %t", test.descrip, test.identical, identical)
+		}
+		if identical == (reason == "") {
+			t.Errorf("%s: expected a reason only for identical images, got %q", test.descrip, reason)
+		}
+		// only manifests are fetched, and with a HEAD request when their
+		// digest is all that's needed
+		for _, request := range requests {
+			if strings.Contains(request, "/blobs/") || !test.compareLayers && strings.HasPrefix(request, "GET /v2/app/manifests/") && !strings.HasSuffix(request, "/rebuilt") {
+				t.Errorf("%s: expected only the manifest descriptors fetched, got %v", test.descrip, requests)
+				break
+			}
+		}
+	}
+	for _, local := range []string{"daemon://app:v1", "app:v1", "oci:///tmp/layout:v1"} {
+		if _, _, err := pkgutil.CheckIdentical(repo+"v1", local, false); err == nil || !strings.Contains(err.Error(), "not a registry image") {
+			t.Errorf("Expected %s refused as not a registry image, got %v", local, err)
+		}
+	}
+	if _, _, err := pkgutil.CheckIdentical(repo+"v1", repo+"missing", false); err == nil {
+		t.Errorf("Expected an error checking an image which doesn't resolve")
+	}
+}
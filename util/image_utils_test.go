@@ -17,6 +17,11 @@ limitations under the License.
 package util
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
@@ -43,6 +48,22 @@ func TestImageTags(t *testing.T) {
 			image:  "remote://gcr.io/test_image_foo",
 			hasTag: false,
 		},
+		{
+			image:  "registry.local:5000/test_image",
+			hasTag: false,
+		},
+		{
+			image:  "registry.local:5000/test_image:1.0",
+			hasTag: true,
+		},
+		{
+			image:  "[fd00::10]:5000/team/test_image",
+			hasTag: false,
+		},
+		{
+			image:  "remote://[fd00::10]:5000/team/test_image:1.0",
+			hasTag: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -51,3 +72,87 @@ func TestImageTags(t *testing.T) {
 		}
 	}
 }
+
+func TestTagResolution(t *testing.T) {
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"invalid":   {http.StatusBadRequest, `{"errors": [{"code": "TAG_INVALID", "message": "tags are not resolved"}]}`},
+		"disabled":  {http.StatusMethodNotAllowed, "pull by digest"},
+		"denied":    {http.StatusForbidden, `{"errors": [{"code": "DENIED", "message": "requested access to the resource is denied"}]}`},
+		"forbidden": {http.StatusForbidden, "forbidden"},
+		"unknown":   {http.StatusNotFound, `{"errors": [{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}]}`},
+		"broken":    {http.StatusTeapot, "short and stout"},
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		requested = append(requested, r.URL.Path)
+		repo := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")[0]
+		if response, ok := responses[repo]; ok {
+			w.WriteHeader(response.status)
+			w.Write([]byte(response.body))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	config, err := ioutil.TempDir("", "docker-config")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(config)
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", config)
+
+	refused := "refused to resolve tag \"v1\""
+	denied := "check that the tag exists and that the credentials allow pulling it"
+	testCases := []struct {
+		repo     string
+		expected string
+		hint     bool
+	}{
+		{repo: "invalid", expected: refused, hint: true},
+		{repo: "disabled", expected: refused, hint: true},
+		{repo: "denied", expected: denied, hint: true},
+		{repo: "forbidden", expected: denied, hint: true},
+		{repo: "unknown", expected: denied, hint: true},
+		{repo: "broken", expected: "retrieving manifest for tag \"v1\""},
+	}
+	for _, test := range testCases {
+		_, err := pkgutil.GetImage("remote://"+host+"/"+test.repo+":v1", false, "")
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.repo, test.expected, err)
+			continue
+		}
+		hint := host + "/" + test.repo + "@sha256:<digest>"
+		if strings.Contains(err.Error(), hint) != test.hint {
+			t.Errorf("%s: expected the pull by digest hint %t, got %s", test.repo, test.hint, err)
+		}
+		if test.expected == refused && strings.Contains(err.Error(), "credentials") {
+			t.Errorf("%s: expected no mention of credentials, got %s", test.repo, err)
+		}
+	}
+
+	// references with a tag and a digest are pulled by digest alone
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	requested = nil
+	_, err = pkgutil.GetImage("remote://"+host+"/invalid:v1@"+digest, false, "")
+	if err == nil || !strings.Contains(err.Error(), "by digest") || strings.Contains(err.Error(), "refused to resolve tag") {
+		t.Errorf("Expected an error retrieving the manifest by digest, got %v", err)
+	}
+	for _, path := range requested {
+		if path != "/v2/invalid/manifests/"+digest {
+			t.Errorf("Expected only the manifest to be requested by digest, got %v", requested)
+			break
+		}
+	}
+	if len(requested) == 0 {
+		t.Errorf("Expected the manifest to be requested by digest")
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Categories of container init configuration.
+const (
+	InitEntrypoint  = "Entrypoint"
+	InitCmd         = "Cmd"
+	InitStopSignal  = "StopSignal"
+	InitHealthcheck = "Healthcheck"
+)
+
+// Forms an ENTRYPOINT or CMD instruction can take.
+const (
+	ExecForm  = "exec"
+	ShellForm = "shell"
+)
+
+// Docker's defaults for signals and healthchecks left unset in the config.
+const (
+	defaultStopSignal    = "SIGTERM"
+	defaultCheckInterval = 30 * time.Second
+	defaultCheckTimeout  = 30 * time.Second
+)
+
+var defaultShell = []string{"/bin/sh", "-c"}
+
+// InitConfig is the part of an image config deciding how its container
+// starts, stops and reports its health.
+type InitConfig struct {
+	Entrypoint     []string
+	EntrypointForm string
+	Cmd            []string
+	CmdForm        string
+	StopSignal     string
+	Healthcheck    *v1.HealthConfig
+}
+
+// InitSetting is a single init configuration value of an image.
+type InitSetting struct {
+	Category string
+	Field    string
+	Value    string
+}
+
+// InitAnalysis lists the init configuration of an image along with any
+// risky combinations found in it.
+type InitAnalysis struct {
+	Settings []InitSetting
+	Warnings []string
+}
+
+// InitChange is an init configuration value which differs between images.
+// Empty values are unset.
+type InitChange struct {
+	Category string
+	Field    string
+	Value1   string
+	Value2   string
+}
+
+// InitDiff lists the init configuration changes between two images, and the
+// risks introduced by the second.
+type InitDiff struct {
+	Changes  []InitChange
+	Warnings []string
+}
+
+// NewInitConfig extracts the init configuration from an image config.
+func NewInitConfig(c v1.Config) InitConfig {
+	return InitConfig{
+		Entrypoint:     c.Entrypoint,
+		EntrypointForm: CommandForm(c.Entrypoint, c.Shell),
+		Cmd:            c.Cmd,
+		CmdForm:        CommandForm(c.Cmd, c.Shell),
+		StopSignal:     c.StopSignal,
+		Healthcheck:    c.Healthcheck,
+	}
+}
+
+// CommandForm tells whether command was written in shell form, which the
+// builder stores prefixed with the image's SHELL, or in exec form.
+func CommandForm(command, shell []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+	if len(shell) == 0 {
+		shell = defaultShell
+	}
+	if len(command) != len(shell)+1 {
+		return ExecForm
+	}
+	for i, arg := range shell {
+		if command[i] != arg {
+			return ExecForm
+		}
+	}
+	return ShellForm
+}
+
+// Settings returns the init configuration values in a fixed order.
+func (c InitConfig) Settings() []InitSetting {
+	settings := []InitSetting{
+		{InitEntrypoint, "Command", commandString(c.Entrypoint)},
+		{InitEntrypoint, "Form", c.EntrypointForm},
+		{InitCmd, "Command", commandString(c.Cmd)},
+		{InitCmd, "Form", c.CmdForm},
+		{InitStopSignal, "Signal", c.StopSignal},
+	}
+	h := c.Healthcheck
+	if h == nil {
+		h = &v1.HealthConfig{}
+	}
+	return append(settings,
+		InitSetting{InitHealthcheck, "Test", commandString(h.Test)},
+		InitSetting{InitHealthcheck, "Interval", durationString(h.Interval)},
+		InitSetting{InitHealthcheck, "Timeout", durationString(h.Timeout)},
+		InitSetting{InitHealthcheck, "StartPeriod", durationString(h.StartPeriod)},
+		InitSetting{InitHealthcheck, "Retries", retriesString(h.Retries)},
+	)
+}
+
+// Warnings flags init configurations that are likely to misbehave at run
+// time.
+func (c InitConfig) Warnings() []string {
+	warnings := []string{}
+	signal := c.StopSignal
+	if signal == "" {
+		signal = defaultStopSignal
+	}
+	if c.EntrypointForm == ShellForm {
+		if !execsInShell(c.Entrypoint) {
+			warnings = append(warnings, fmt.Sprintf("shell-form ENTRYPOINT runs as a child of the shell, which will not forward %s", signal))
+		}
+		if len(c.Cmd) != 0 {
+			warnings = append(warnings, "CMD is ignored by the shell-form ENTRYPOINT")
+		}
+	} else if len(c.Entrypoint) == 0 && c.CmdForm == ShellForm && !execsInShell(c.Cmd) {
+		warnings = append(warnings, fmt.Sprintf("shell-form CMD runs as a child of the shell, which will not forward %s", signal))
+	}
+	if c.healthchecked() {
+		interval, timeout := c.Healthcheck.Interval, c.Healthcheck.Timeout
+		if interval == 0 {
+			interval = defaultCheckInterval
+		}
+		if timeout == 0 {
+			timeout = defaultCheckTimeout
+		}
+		if timeout > interval {
+			warnings = append(warnings, fmt.Sprintf("healthcheck timeout %s is longer than its interval %s", timeout, interval))
+		}
+	}
+	return warnings
+}
+
+// healthchecked reports whether the config defines a healthcheck, as opposed
+// to inheriting or disabling one.
+func (c InitConfig) healthchecked() bool {
+	return c.Healthcheck != nil && len(c.Healthcheck.Test) != 0 && c.Healthcheck.Test[0] != "NONE"
+}
+
+// GetInitAnalysis lists the init configuration and warnings of an image.
+func GetInitAnalysis(c InitConfig) InitAnalysis {
+	return InitAnalysis{
+		Settings: c.Settings(),
+		Warnings: c.Warnings(),
+	}
+}
+
+// GetInitDiff compares the init configuration of two images. Warnings only
+// include risks not already present in the first image.
+func GetInitDiff(c1, c2 InitConfig) InitDiff {
+	diff := InitDiff{Changes: []InitChange{}, Warnings: []string{}}
+	settings1, settings2 := c1.Settings(), c2.Settings()
+	for i, s1 := range settings1 {
+		if s2 := settings2[i]; s1.Value != s2.Value {
+			diff.Changes = append(diff.Changes, InitChange{
+				Category: s1.Category,
+				Field:    s1.Field,
+				Value1:   s1.Value,
+				Value2:   s2.Value,
+			})
+		}
+	}
+	existing := map[string]bool{}
+	for _, w := range c1.Warnings() {
+		existing[w] = true
+	}
+	for _, w := range c2.Warnings() {
+		if !existing[w] {
+			diff.Warnings = append(diff.Warnings, w)
+		}
+	}
+	if c1.healthchecked() && !c2.healthchecked() {
+		diff.Warnings = append(diff.Warnings, "healthcheck removed or disabled")
+	}
+	return diff
+}
+
+// execsInShell reports whether a shell-form command replaces the shell with
+// exec, so the process receives signals directly.
+func execsInShell(command []string) bool {
+	return strings.HasPrefix(strings.TrimSpace(command[len(command)-1]), "exec ")
+}
+
+func commandString(command []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Sprint(command)
+	}
+	return string(b)
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func retriesString(retries int) string {
+	if retries == 0 {
+		return ""
+	}
+	return strconv.Itoa(retries)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestCommandForm(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		command  []string
+		shell    []string
+		expected string
+	}{
+		{descrip: "unset", expected: ""},
+		{descrip: "exec form", command: []string{"/app", "--serve"}, expected: ExecForm},
+		{descrip: "shell form", command: []string{"/bin/sh", "-c", "/app --serve"}, expected: ShellForm},
+		{descrip: "exec form running a shell", command: []string{"/bin/sh", "-c", "/app", "--serve"}, expected: ExecForm},
+		{descrip: "custom shell", command: []string{"/bin/bash", "-eo", "pipefail", "-c", "/app"}, shell: []string{"/bin/bash", "-eo", "pipefail", "-c"}, expected: ShellForm},
+		{descrip: "default shell with custom SHELL", command: []string{"/bin/sh", "-c", "/app"}, shell: []string{"/bin/bash", "-c"}, expected: ExecForm},
+	}
+	for _, test := range testCases {
+		if actual := CommandForm(test.command, test.shell); actual != test.expected {
+			t.Errorf("%s: Expected %q but got %q", test.descrip, test.expected, actual)
+		}
+	}
+}
+
+func TestGetInitDiff(t *testing.T) {
+	c1 := NewInitConfig(v1.Config{
+		Entrypoint: []string{"/app"},
+		Cmd:        []string{"--serve"},
+		Healthcheck: &v1.HealthConfig{
+			Test:     []string{"CMD", "/app", "--check"},
+			Interval: 10 * time.Second,
+		},
+	})
+	c2 := NewInitConfig(v1.Config{
+		Entrypoint: []string{"/bin/sh", "-c", "/app"},
+		Cmd:        []string{"--serve"},
+		StopSignal: "SIGQUIT",
+		Healthcheck: &v1.HealthConfig{
+			Test: []string{"NONE"},
+		},
+	})
+
+	expected := InitDiff{
+		Changes: []InitChange{
+			{InitEntrypoint, "Command", `["/app"]`, `["/bin/sh","-c","/app"]`},
+			{InitEntrypoint, "Form", ExecForm, ShellForm},
+			{InitStopSignal, "Signal", "", "SIGQUIT"},
+			{InitHealthcheck, "Test", `["CMD","/app","--check"]`, `["NONE"]`},
+			{InitHealthcheck, "Interval", "10s", ""},
+		},
+		Warnings: []string{
+			"shell-form ENTRYPOINT runs as a child of the shell, which will not forward SIGQUIT",
+			"CMD is ignored by the shell-form ENTRYPOINT",
+			"healthcheck removed or disabled",
+		},
+	}
+	if actual := GetInitDiff(c1, c2); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+
+	if actual := GetInitDiff(c2, c2); len(actual.Changes) != 0 || len(actual.Warnings) != 0 {
+		t.Errorf("Expected no changes or warnings comparing an image to itself, got: %v", actual)
+	}
+}
+
+func TestInitWarnings(t *testing.T) {
+	c := NewInitConfig(v1.Config{
+		Cmd: []string{"/bin/sh", "-c", "/app"},
+		Healthcheck: &v1.HealthConfig{
+			Test:    []string{"CMD-SHELL", "curl -f localhost"},
+			Timeout: time.Minute,
+		},
+	})
+	expected := []string{
+		"shell-form CMD runs as a child of the shell, which will not forward SIGTERM",
+		"healthcheck timeout 1m0s is longer than its interval 30s",
+	}
+	if actual := c.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+
+	c = NewInitConfig(v1.Config{Cmd: []string{"/bin/sh", "-c", "exec /app"}})
+	if actual := c.Warnings(); len(actual) != 0 {
+		t.Errorf("Expected no warnings for a shell-form CMD using exec, got: %v", actual)
+	}
+}
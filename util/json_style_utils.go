@@ -0,0 +1,374 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Key casings of JSON output. Empty keeps the Go field names, e.g.
+// AnalyzeType.
+const (
+	KeysCamel = "camel"
+	KeysSnake = "snake"
+)
+
+// sizeField matches the names of size fields, such as Size, Size1 and
+// LayerSize.
+var sizeField = regexp.MustCompile(`Size\d*$`)
+
+// Where a value is, for dropping size fields: only those of entries listed
+// in an analysis or diff are dropped.
+const (
+	outsideResult = iota
+	inResult
+	inEntry
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// JSONStyle adjusts JSON output to the schema of the systems ingesting it.
+// The zero value leaves the output as is.
+type JSONStyle struct {
+	// Keys is the casing of struct field names, KeysCamel or KeysSnake.
+	// Map keys, such as package names and annotations, are data and are
+	// kept.
+	Keys string
+	// OmitEmpty leaves out results with nothing in their analysis or diff.
+	OmitEmpty bool
+	// OmitSizes leaves out the size fields of listed entries, such as
+	// packages, files and layers. Totals are kept.
+	OmitSizes bool
+}
+
+// Validate checks the key casing.
+func (s JSONStyle) Validate() error {
+	switch s.Keys {
+	case "", KeysCamel, KeysSnake:
+		return nil
+	}
+	return fmt.Errorf("unknown JSON key casing %q, expected %s or %s", s.Keys, KeysCamel, KeysSnake)
+}
+
+// OmitEmptyResults drops the results whose Analysis or Diff holds no
+// entries, when OmitEmpty is set.
+func (s JSONStyle) OmitEmptyResults(results []interface{}) []interface{} {
+	if !s.OmitEmpty {
+		return results
+	}
+	kept := []interface{}{}
+	for _, result := range results {
+		if !emptyResult(result) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// Apply returns v restyled, to be marshalled in its place. Fields keep
+// their order and json tags.
+func (s JSONStyle) Apply(v interface{}) interface{} {
+	if s.Keys == "" && !s.OmitSizes {
+		return v
+	}
+	return s.style(reflect.ValueOf(v), outsideResult)
+}
+
+// emptyResult reports whether a result's Analysis or Diff is empty.
+// Anything else, such as an error, is kept.
+func emptyResult(result interface{}) bool {
+	v := reflect.ValueOf(result)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	for _, name := range []string{"Analysis", "Diff"} {
+		if f := v.FieldByName(name); f.IsValid() {
+			return emptyDeep(f)
+		}
+	}
+	return false
+}
+
+// emptyDeep reports whether a value holds nothing but zero values, empty
+// lists and maps, and structs of those.
+func emptyDeep(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || emptyDeep(v.Elem())
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && !emptyDeep(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.IsZero()
+}
+
+// style converts v into what encoding/json would make of it, with struct
+// fields renamed and size fields dropped. scope tells whether v is in an
+// analysis or diff, and below one of its entries.
+func (s JSONStyle) style(v reflect.Value, scope int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	// values marshalling themselves, such as times and digests, are kept
+	if marshalsItself(v.Type()) {
+		return v.Interface()
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && marshalsItself(reflect.PtrTo(v.Type())) {
+		return v.Addr().Interface()
+	}
+	entryScope := scope
+	if scope != outsideResult {
+		entryScope = inEntry
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.style(v.Elem(), scope)
+	case reflect.Struct:
+		return s.styleStruct(v, scope, jsonObject{})
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = mapKey(key)
+		}
+		sort.Sort(byName{names, keys})
+		obj := make(jsonObject, len(keys))
+		for i, key := range keys {
+			obj[i] = jsonMember{names[i], s.style(v.MapIndex(key), entryScope)}
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = s.style(v.Index(i), entryScope)
+		}
+		return list
+	}
+	return v.Interface()
+}
+
+func marshalsItself(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// styleStruct appends the fields of a struct to obj, flattening embedded
+// structs as encoding/json does.
+func (s JSONStyle) styleStruct(v reflect.Value, scope int, obj jsonObject) jsonObject {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		name, options := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, options = tag[:i], tag[i:]
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if value.Kind() == reflect.Ptr {
+					if value.IsNil() {
+						continue
+					}
+					value = value.Elem()
+				}
+				obj = s.styleStruct(value, scope, obj)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, ",omitempty") && isEmptyJSONValue(value) {
+			continue
+		}
+		if s.OmitSizes && scope == inEntry && sizeField.MatchString(field.Name) {
+			continue
+		}
+		fieldScope := scope
+		if scope == outsideResult && (field.Name == "Analysis" || field.Name == "Diff") {
+			fieldScope = inResult
+		}
+		obj = append(obj, jsonMember{s.key(name), s.style(value, fieldScope)})
+	}
+	return obj
+}
+
+// isEmptyJSONValue is the emptiness the omitempty tag option checks.
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+func mapKey(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if m, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// key converts a field name to the configured casing.
+func (s JSONStyle) key(name string) string {
+	if s.Keys == "" {
+		return name
+	}
+	words := splitWords(name)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if s.Keys == KeysCamel && i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	if s.Keys == KeysSnake {
+		return strings.Join(words, "_")
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits a Go name into words, keeping acronyms and trailing
+// digits together: IDLike is ID Like and Image1 is Image1.
+func splitWords(name string) []string {
+	runes := []rune(name)
+	words := []string{}
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' || r == '-' || r == ' ' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// jsonObject is a JSON object whose members keep their order.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// byName sorts map keys by their JSON names, as encoding/json does.
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (b byName) Len() int           { return len(b.names) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONStyleKeys(t *testing.T) {
+	testCases := []struct {
+		name  string
+		camel string
+		snake string
+	}{
+		{name: "AnalyzeType", camel: "analyzeType", snake: "analyze_type"},
+		{name: "Image1", camel: "image1", snake: "image1"},
+		{name: "IDLike", camel: "idLike", snake: "id_like"},
+		{name: "InfoDiff", camel: "infoDiff", snake: "info_diff"},
+		{name: "HTTPProxyURL", camel: "httpProxyUrl", snake: "http_proxy_url"},
+		{name: "ecosystem", camel: "ecosystem", snake: "ecosystem"},
+	}
+	for _, test := range testCases {
+		if actual := (JSONStyle{Keys: KeysCamel}).key(test.name); actual != test.camel {
+			t.Errorf("Expected camel case of %s to be %s but got %s", test.name, test.camel, actual)
+		}
+		if actual := (JSONStyle{Keys: KeysSnake}).key(test.name); actual != test.snake {
+			t.Errorf("Expected snake case of %s to be %s but got %s", test.name, test.snake, actual)
+		}
+	}
+	if err := (JSONStyle{Keys: "kebab"}).Validate(); err == nil {
+		t.Errorf("Expected an error for an unknown key casing but got none")
+	}
+}
+
+func TestJSONStyleApply(t *testing.T) {
+	results := []interface{}{
+		ConffileAnalyzeResult{
+			Image:       "image",
+			AnalyzeType: "Conffiles",
+			Analysis: ConffileAnalysis{
+				Packages: []ConfigPackage{},
+				Files:    []LeftoverFile{{Path: "/etc/app.conf", Package: "app", Reason: LeftoverRemoved, Size: 12}},
+				Size:     12,
+			},
+		},
+		SingleVersionPackageDiffResult{
+			Image1:   "image1",
+			Image2:   "image2",
+			DiffType: "Pip",
+			Diff: PackageDiff{
+				Packages1: map[string]PackageInfo{"Flask": {Version: "2.0.1", Size: 100}},
+				Packages2: map[string]PackageInfo{},
+				InfoDiff:  []Info{},
+			},
+		},
+	}
+	output := ResultsWithMetadata{Annotations: map[string]string{"BuildID": "1234"}, Results: results}
+	style := JSONStyle{Keys: KeysSnake, OmitSizes: true}
+	actual, err := json.Marshal(style.Apply(output))
+	if err != nil {
+		t.Fatalf("Error marshalling styled output: %s", err)
+	}
+	expected := `{"annotations":{"BuildID":"1234"},"results":[` +
+		`{"image":"image","analyze_type":"Conffiles","analysis":{"packages":[],"files":[{"path":"/etc/app.conf","package":"app","reason":"removed package"}],"size":12}},` +
+		`{"image1":"image1","image2":"image2","diff_type":"Pip","diff":{"packages1":{"Flask":{"version":"2.0.1"}},"packages2":{},"info_diff":[]}}]}`
+	if string(actual) != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+
+	if unstyled := (JSONStyle{OmitEmpty: true}).Apply(output); !reflect.DeepEqual(unstyled, output) {
+		t.Errorf("Expected output to be kept as is without key casing or size omission")
+	}
+}
+
+func TestJSONStyleOmitEmptyResults(t *testing.T) {
+	empty := &InitDiffResult{DiffType: "Init", Diff: InitDiff{Changes: []InitChange{}, Warnings: []string{}}}
+	changed := &InitDiffResult{DiffType: "Init", Diff: InitDiff{Changes: []InitChange{{Category: InitCmd, Field: "Command"}}, Warnings: []string{}}}
+	found := &ListAnalyzeResult{AnalyzeType: "History", Analysis: []string{"RUN make"}}
+	results := []interface{}{empty.OutputStruct(OutputOptions{}), changed.OutputStruct(OutputOptions{}), found.OutputStruct(OutputOptions{})}
+	kept := JSONStyle{OmitEmpty: true}.OmitEmptyResults(results)
+	if !reflect.DeepEqual(kept, results[1:]) {
+		t.Errorf("Expected only the results with entries to be kept but got: %+v", kept)
+	}
+	if all := (JSONStyle{}).OmitEmptyResults(results); len(all) != len(results) {
+		t.Errorf("Expected every result without OmitEmpty but got: %+v", all)
+	}
+}
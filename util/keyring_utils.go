@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SigningKey stores an OpenPGP public key found in the keyrings of an
+// image, identified by the fingerprint of its primary key. Algorithm is
+// named as gpg does, e.g. rsa4096 or ed25519, and Created is the date the
+// key was generated. Keyrings lists the image paths of the files holding
+// the key.
+type SigningKey struct {
+	Fingerprint string
+	KeyID       string
+	Algorithm   string
+	Created     string
+	UserIDs     []string
+	Subkeys     []string `json:",omitempty"`
+	Keyrings    []string
+}
+
+// SigningKeyChange stores a key found in both images with different user
+// IDs, subkeys or keyrings.
+type SigningKeyChange struct {
+	Fingerprint string
+	Key1        SigningKey
+	Key2        SigningKey
+}
+
+// KeyringDiff stores the signing keys added, removed and changed between
+// two images. Warnings flag the keys the second image trusts for every apt
+// repository, in /etc/apt/trusted.gpg or /etc/apt/trusted.gpg.d, while
+// the first didn't.
+type KeyringDiff struct {
+	Warnings []string
+	Added    []SigningKey
+	Removed  []SigningKey
+	Changed  []SigningKeyChange
+}
+
+// aptGlobalKeyrings are the keyrings apt trusts for every repository,
+// unlike those repositories only name with signed-by.
+var aptGlobalKeyrings = []string{"/etc/apt/trusted.gpg", "/etc/apt/trusted.gpg.d/*"}
+
+// IsAptGlobalKeyring reports whether apt trusts the keys of the keyring at
+// the image path file for every repository.
+func IsAptGlobalKeyring(file string) bool {
+	for _, pattern := range aptGlobalKeyrings {
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetKeyringDiff compares the signing keys of two images by fingerprint.
+func GetKeyringDiff(keys1, keys2 []SigningKey) KeyringDiff {
+	diff := KeyringDiff{
+		Warnings: []string{},
+		Added:    []SigningKey{},
+		Removed:  []SigningKey{},
+		Changed:  []SigningKeyChange{},
+	}
+	byFingerprint1 := map[string]SigningKey{}
+	for _, k := range keys1 {
+		byFingerprint1[k.Fingerprint] = k
+	}
+	byFingerprint2 := map[string]SigningKey{}
+	for _, k := range keys2 {
+		byFingerprint2[k.Fingerprint] = k
+		k1, ok := byFingerprint1[k.Fingerprint]
+		if !ok {
+			diff.Added = append(diff.Added, k)
+		} else if !reflect.DeepEqual(k1, k) {
+			diff.Changed = append(diff.Changed, SigningKeyChange{Fingerprint: k.Fingerprint, Key1: k1, Key2: k})
+		}
+		if global := aptGlobalKeyringOf(k); global != "" && (!ok || aptGlobalKeyringOf(k1) == "") {
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("key %s (%s) trusted for every apt repository by %s", k.Fingerprint, primaryUserID(k), global))
+		}
+	}
+	for _, k := range keys1 {
+		if _, ok := byFingerprint2[k.Fingerprint]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	return diff
+}
+
+func aptGlobalKeyringOf(key SigningKey) string {
+	for _, keyring := range key.Keyrings {
+		if IsAptGlobalKeyring(keyring) {
+			return keyring
+		}
+	}
+	return ""
+}
+
+func primaryUserID(key SigningKey) string {
+	if len(key.UserIDs) == 0 {
+		return "no user ID"
+	}
+	return key.UserIDs[0]
+}
+
+// SortSigningKeys orders keys by their first user ID, then fingerprint.
+func SortSigningKeys(keys []SigningKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		ui, uj := strings.ToLower(primaryUserID(keys[i])), strings.ToLower(primaryUserID(keys[j]))
+		if ui != uj {
+			return ui < uj
+		}
+		return keys[i].Fingerprint < keys[j].Fingerprint
+	})
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// LanguageRuntime is an interpreter or toolchain installed in an image.
+// Default is set for the installation the runtime's command runs.
+type LanguageRuntime struct {
+	Runtime string
+	Version string
+	Path    string
+	Default bool
+}
+
+// RuntimeVersions compares the installed versions of a runtime in two
+// images. Default1 and Default2 are the versions the runtime's command runs
+// in each image, if any.
+type RuntimeVersions struct {
+	Runtime   string
+	Versions1 []string
+	Versions2 []string
+	Default1  string
+	Default2  string
+}
+
+// SortLanguageRuntimes orders runtimes by name, then version.
+func SortLanguageRuntimes(runtimes []LanguageRuntime) {
+	sort.Slice(runtimes, func(i, j int) bool {
+		if runtimes[i].Runtime != runtimes[j].Runtime {
+			return runtimes[i].Runtime < runtimes[j].Runtime
+		}
+		return compareSemver(runtimes[i].Version, runtimes[j].Version) < 0
+	})
+}
+
+// GetRuntimeMatrixDiff lists the runtimes whose installed or default
+// versions differ between two images.
+func GetRuntimeMatrixDiff(runtimes1, runtimes2 []LanguageRuntime) []RuntimeVersions {
+	matrix := map[string]*RuntimeVersions{}
+	entry := func(runtime string) *RuntimeVersions {
+		if _, ok := matrix[runtime]; !ok {
+			matrix[runtime] = &RuntimeVersions{Runtime: runtime, Versions1: []string{}, Versions2: []string{}}
+		}
+		return matrix[runtime]
+	}
+	for _, r := range runtimes1 {
+		e := entry(r.Runtime)
+		e.Versions1 = append(e.Versions1, r.Version)
+		if r.Default {
+			e.Default1 = r.Version
+		}
+	}
+	for _, r := range runtimes2 {
+		e := entry(r.Runtime)
+		e.Versions2 = append(e.Versions2, r.Version)
+		if r.Default {
+			e.Default2 = r.Version
+		}
+	}
+	diff := []RuntimeVersions{}
+	for _, e := range matrix {
+		sortVersions(e.Versions1)
+		sortVersions(e.Versions2)
+		if e.Default1 != e.Default2 || strings.Join(e.Versions1, "\n") != strings.Join(e.Versions2, "\n") {
+			diff = append(diff, *e)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Runtime < diff[j].Runtime })
+	return diff
+}
+
+// CompareVersions compares two dotted version strings, returning -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	return compareSemver(a, b)
+}
+
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool { return compareSemver(versions[i], versions[j]) < 0 })
+}
+
+// formatRuntimeVersions lists versions on one line, marking the default
+// with an asterisk.
+func formatRuntimeVersions(versions []string, def string) string {
+	if len(versions) == 0 {
+		return "-"
+	}
+	marked := []string{}
+	for _, v := range versions {
+		if v == def {
+			v += "*"
+		}
+		marked = append(marked, v)
+	}
+	return strings.Join(marked, ", ")
+}
+
+// StrRuntimeVersions is a row of the runtime matrix in text output.
+type StrRuntimeVersions struct {
+	Runtime   string
+	Versions1 string
+	Versions2 string
+}
+
+func stringifyRuntimeVersions(matrix []RuntimeVersions) []StrRuntimeVersions {
+	rows := []StrRuntimeVersions{}
+	for _, r := range matrix {
+		rows = append(rows, StrRuntimeVersions{
+			Runtime:   r.Runtime,
+			Versions1: formatRuntimeVersions(r.Versions1, r.Default1),
+			Versions2: formatRuntimeVersions(r.Versions2, r.Default2),
+		})
+	}
+	return rows
+}
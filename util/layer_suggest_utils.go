@@ -0,0 +1,40 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+// LayerSuggestDiff describes the layers of the second image which could not
+// be reused from the first image's build cache. FirstChangedLayer is -1 when
+// the second image reuses every layer of the first.
+type LayerSuggestDiff struct {
+	FirstChangedLayer int
+	ChangedCommand    string
+	RebuiltLayers     int
+	RebuiltBytes      int64
+	Suggestions       []LayerSuggestion
+	SavedBytes        int64
+}
+
+// LayerSuggestion describes a layer which was rebuilt with unchanged contents
+// only because an earlier layer changed. Moving its instruction before the
+// changed one would let it be reused from cache.
+type LayerSuggestion struct {
+	Layer             int
+	Command           string
+	MoveBefore        int
+	MoveBeforeCommand string
+	SavedBytes        int64
+}
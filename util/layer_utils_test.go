@@ -0,0 +1,336 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// layerTar returns a layer tarball of the given files.
+func layerTar(files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+// serveRegistry answers pulls of the repository app, of manifests by tag
+// or digest and of blobs by digest.
+func serveRegistry(manifests, blobs map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/v2/app/manifests/") && manifests[path.Base(r.URL.Path)] != nil:
+			manifest := manifests[path.Base(r.URL.Path)]
+			var mediaType struct {
+				MediaType string `json:"mediaType"`
+			}
+			json.Unmarshal(manifest, &mediaType)
+			digest, _, _ := v1.SHA256(bytes.NewReader(manifest))
+			w.Header().Set("Content-Type", mediaType.MediaType)
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/app/blobs/") && blobs[path.Base(r.URL.Path)] != nil:
+			w.Write(blobs[path.Base(r.URL.Path)])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestEncryptedLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer pkgutil.ConfigureDecryption(nil)
+	blobs := map[string][]byte{}
+	addBlob := func(data []byte) v1.Hash {
+		h, _, _ := v1.SHA256(bytes.NewReader(data))
+		blobs[h.String()] = data
+		return h
+	}
+	writeKey := func(name string) *rsa.PrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Error generating key: %s", err)
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		ioutil.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600)
+		return key
+	}
+	key := writeKey("key.pem")
+	writeKey("other.pem")
+
+	plainTar := layerTar(map[string]string{"plain.txt": "from a plain layer\n"})
+	secretTar := layerTar(map[string]string{"secret.txt": "from an encrypted layer\n"})
+	gzipped := gzipBytes(secretTar)
+	secretDigest, _, _ := v1.SHA256(bytes.NewReader(gzipped))
+
+	// the layer is encrypted as by ocicrypt: AES-256-CTR with an HMAC, its
+	// key wrapped in a JWE for the recipient
+	symKey, nonce := make([]byte, 32), make([]byte, aes.BlockSize)
+	rand.Read(symKey)
+	rand.Read(nonce)
+	block, _ := aes.NewCipher(symKey)
+	encrypted := make([]byte, len(gzipped))
+	cipher.NewCTR(block, nonce).XORKeyStream(encrypted, gzipped)
+	mac := hmac.New(sha256.New, symKey)
+	mac.Write(encrypted)
+	privOpts, _ := json.Marshal(map[string]interface{}{
+		"symkey":        symKey,
+		"digest":        secretDigest.String(),
+		"cipheroptions": map[string][]byte{"nonce": nonce},
+	})
+	cek, iv := make([]byte, 32), make([]byte, 12)
+	rand.Read(cek)
+	rand.Read(iv)
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, cek, nil)
+	if err != nil {
+		t.Fatalf("Error wrapping key: %s", err)
+	}
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RSA-OAEP-256","enc":"A256GCM"}`))
+	cekBlock, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(cekBlock)
+	sealed := gcm.Seal(nil, iv, privOpts, []byte(protected))
+	jwe, _ := json.Marshal(map[string]interface{}{
+		"protected":  protected,
+		"recipients": []map[string]string{{"encrypted_key": base64.RawURLEncoding.EncodeToString(wrapped)}},
+		"iv":         base64.RawURLEncoding.EncodeToString(iv),
+		"ciphertext": base64.RawURLEncoding.EncodeToString(sealed[:len(sealed)-gcm.Overhead()]),
+		"tag":        base64.RawURLEncoding.EncodeToString(sealed[len(sealed)-gcm.Overhead():]),
+	})
+	pubOpts := func(sum []byte) string {
+		data, _ := json.Marshal(map[string]interface{}{"cipher": "AES_256_CTR_HMAC_SHA256", "hmac": sum})
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	tampered := append([]byte{}, mac.Sum(nil)...)
+	tampered[0] ^= 0xff
+
+	plainDigest, _, _ := v1.SHA256(bytes.NewReader(plainTar))
+	secretDiffID, _, _ := v1.SHA256(bytes.NewReader(secretTar))
+	plainBlob := gzipBytes(plainTar)
+	foreignDigest, _, _ := v1.SHA256(strings.NewReader("foreign"))
+	config, _ := json.Marshal(v1.ConfigFile{OS: "windows", Architecture: "amd64", RootFS: v1.RootFS{Type: "layers", DiffIDs: []v1.Hash{foreignDigest, plainDigest, secretDiffID}}})
+	configDesc := v1.Descriptor{MediaType: types.OCIConfigJSON, Size: int64(len(config)), Digest: addBlob(config)}
+	manifests := map[string][]byte{}
+	for tag, sum := range map[string][]byte{"v1": mac.Sum(nil), "tampered": tampered} {
+		manifests[tag], _ = json.Marshal(v1.Manifest{
+			SchemaVersion: 2,
+			MediaType:     types.OCIManifestSchema1,
+			Config:        configDesc,
+			Layers: []v1.Descriptor{
+				{MediaType: types.DockerForeignLayer, Size: 7, Digest: foreignDigest, URLs: []string{"https://example.com/base.tar.gz"}},
+				{MediaType: types.OCILayer, Size: int64(len(plainBlob)), Digest: addBlob(plainBlob)},
+				{
+					MediaType: "application/vnd.oci.image.layer.v1.tar+gzip+encrypted",
+					Size:      int64(len(encrypted)),
+					Digest:    addBlob(encrypted),
+					Annotations: map[string]string{
+						"org.opencontainers.image.enc.keys.jwe": base64.StdEncoding.EncodeToString(jwe),
+						"org.opencontainers.image.enc.pubopts":  pubOpts(sum),
+					},
+				},
+			},
+		})
+	}
+	// the foreign layer isn't served, so fetching it fails the pull
+	server := serveRegistry(manifests, blobs)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		descrip string
+		keys    []string
+		tag     string
+		secret  bool
+		reason  string
+	}{
+		{descrip: "matching key", keys: []string{"other.pem", "key.pem"}, tag: "v1", secret: true},
+		{descrip: "no key", tag: "v1", reason: "encrypted layer, no decryption key provided"},
+		{descrip: "wrong key", keys: []string{"other.pem"}, tag: "v1", reason: "no provided key matches the layer recipients"},
+		{descrip: "tampered HMAC", keys: []string{"key.pem"}, tag: "tampered", reason: "layer HMAC verification failed"},
+	}
+	for _, test := range tests {
+		var keys []string
+		for _, k := range test.keys {
+			keys = append(keys, filepath.Join(dir, k))
+		}
+		pkgutil.ConfigureDecryption(keys)
+		image, err := pkgutil.GetImage("remote://"+host+"/app:"+test.tag, false, "")
+		if err != nil {
+			t.Fatalf("%s: error retrieving the image: %s", test.descrip, err)
+		}
+		defer pkgutil.CleanupImage(image)
+		if content, err := ioutil.ReadFile(filepath.Join(image.FSPath, "plain.txt")); err != nil || string(content) != "from a plain layer\n" {
+			t.Errorf("%s: expected the plain layer extracted, got %q: %v", test.descrip, content, err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(image.FSPath, "secret.txt"))
+		if test.secret && (err != nil || string(content) != "from an encrypted layer\n") {
+			t.Errorf("%s: expected the encrypted layer decrypted, got %q: %v", test.descrip, content, err)
+		}
+		if !test.secret && err == nil {
+			t.Errorf("%s: expected the encrypted layer skipped, got %q", test.descrip, content)
+		}
+
+		skipped := image.SkippedLayers
+		if len(skipped) == 0 || skipped[0].Index != 0 || skipped[0].Reason != "foreign layer is not distributed by the registry" ||
+			!reflect.DeepEqual(skipped[0].URLs, []string{"https://example.com/base.tar.gz"}) {
+			t.Errorf("%s: expected the foreign layer skipped, got %+v", test.descrip, skipped)
+			continue
+		}
+		if test.secret {
+			if len(skipped) != 1 {
+				t.Errorf("%s: expected only the foreign layer skipped, got %+v", test.descrip, skipped)
+			}
+			continue
+		}
+		if len(skipped) != 2 || skipped[1].Index != 2 || !strings.Contains(skipped[1].Reason, test.reason) {
+			t.Errorf("%s: expected the encrypted layer skipped with %q, got %+v", test.descrip, test.reason, skipped)
+		}
+	}
+}
+
+// zstdFrame stores data in a zstd frame of raw, uncompressed blocks.
+func zstdFrame(data []byte) []byte {
+	// no checksum, and a 128KiB window
+	frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 0x38}
+	for {
+		n := len(data)
+		if n > 1<<17 {
+			n = 1 << 17
+		}
+		header := uint32(n) << 3
+		if n == len(data) {
+			header |= 1
+		}
+		frame = append(frame, byte(header), byte(header>>8), byte(header>>16))
+		frame = append(frame, data[:n]...)
+		if data = data[n:]; len(data) == 0 && header&1 == 1 {
+			return frame
+		}
+	}
+}
+
+func TestCompressedLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	writeBlob := func(data []byte) v1.Hash {
+		h, _, _ := v1.SHA256(bytes.NewReader(data))
+		os.MkdirAll(filepath.Join(dir, "blobs", h.Algorithm), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "blobs", h.Algorithm, h.Hex), data, 0644)
+		return h
+	}
+	zstdTar := layerTar(map[string]string{"zstd.txt": "from a zstd layer\n"})
+	estargzTar := layerTar(map[string]string{
+		"estargz.txt":        "from an eStargz layer\n",
+		".prefetch.landmark": "\x0f",
+		"stargz.index.json":  `{"version": 1, "entries": []}`,
+	})
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(estargzTar)
+	gz.Close()
+
+	diffIDs := []v1.Hash{}
+	for _, layer := range [][]byte{zstdTar, estargzTar} {
+		h, _, _ := v1.SHA256(bytes.NewReader(layer))
+		diffIDs = append(diffIDs, h)
+	}
+	config, _ := json.Marshal(v1.ConfigFile{OS: "linux", Architecture: "amd64", RootFS: v1.RootFS{Type: "layers", DiffIDs: diffIDs}})
+	zstdBlob, estargzBlob := zstdFrame(zstdTar), gzipped.Bytes()
+	manifest, _ := json.Marshal(v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        v1.Descriptor{MediaType: types.OCIConfigJSON, Size: int64(len(config)), Digest: writeBlob(config)},
+		Layers: []v1.Descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+zstd", Size: int64(len(zstdBlob)), Digest: writeBlob(zstdBlob)},
+			{
+				MediaType:   types.OCILayer,
+				Size:        int64(len(estargzBlob)),
+				Digest:      writeBlob(estargzBlob),
+				Annotations: map[string]string{"containerd.io/snapshot/stargz/toc.digest": "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+			},
+		},
+	})
+	index, _ := json.Marshal(v1.IndexManifest{SchemaVersion: 2, Manifests: []v1.Descriptor{{
+		MediaType:   types.OCIManifestSchema1,
+		Size:        int64(len(manifest)),
+		Digest:      writeBlob(manifest),
+		Annotations: map[string]string{"org.opencontainers.image.ref.name": "v1"},
+	}}})
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), index, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0644)
+
+	image, err := pkgutil.GetImage("oci://"+dir+":v1", false, "")
+	if err != nil {
+		t.Fatalf("Error retrieving the image: %s", err)
+	}
+	defer pkgutil.CleanupImage(image)
+	for name, expected := range map[string]string{"zstd.txt": "from a zstd layer\n", "estargz.txt": "from an eStargz layer\n"} {
+		if content, err := ioutil.ReadFile(filepath.Join(image.FSPath, name)); err != nil || string(content) != expected {
+			t.Errorf("Expected %s to hold %q, got %q: %v", name, expected, content, err)
+		}
+	}
+	for _, name := range []string{"stargz.index.json", ".prefetch.landmark"} {
+		if _, err := os.Lstat(filepath.Join(image.FSPath, name)); err == nil {
+			t.Errorf("Expected the eStargz metadata %s left out of the filesystem", name)
+		}
+	}
+}
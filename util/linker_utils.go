@@ -0,0 +1,96 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Resolution is a name, such as a library soname or a command, and the file
+// it resolves to. Path is where the name is found, and Target the file that
+// is run or loaded after following symlinks.
+type Resolution struct {
+	Name   string
+	Path   string
+	Target string
+}
+
+// LinkerAnalysis stores how an image resolves libraries and commands at
+// start-up: the library directories configured in /etc/ld.so.conf, in
+// search order, the libraries in the ld.so.cache, and the command each name
+// on the PATH runs.
+type LinkerAnalysis struct {
+	SearchDirs []string
+	Libraries  []Resolution
+	Commands   []Resolution
+}
+
+// ResolutionChange is a name which resolves differently in two images.
+type ResolutionChange struct {
+	Name    string
+	Path1   string
+	Target1 string
+	Path2   string
+	Target2 string
+}
+
+// LinkerDiff stores the library directories of two images when their
+// order differs, and the libraries and commands found in both images which
+// resolve to different files.
+type LinkerDiff struct {
+	SearchDirs1 []string
+	SearchDirs2 []string
+	Libraries   []ResolutionChange
+	Commands    []ResolutionChange
+}
+
+// SortResolutions orders resolutions by name.
+func SortResolutions(resolutions []Resolution) {
+	sort.Slice(resolutions, func(i, j int) bool { return resolutions[i].Name < resolutions[j].Name })
+}
+
+// GetLinkerDiff compares the library and command resolution of two images.
+func GetLinkerDiff(analysis1, analysis2 LinkerAnalysis) LinkerDiff {
+	diff := LinkerDiff{
+		SearchDirs1: []string{},
+		SearchDirs2: []string{},
+		Libraries:   getResolutionChanges(analysis1.Libraries, analysis2.Libraries),
+		Commands:    getResolutionChanges(analysis1.Commands, analysis2.Commands),
+	}
+	if !reflect.DeepEqual(analysis1.SearchDirs, analysis2.SearchDirs) {
+		diff.SearchDirs1, diff.SearchDirs2 = analysis1.SearchDirs, analysis2.SearchDirs
+	}
+	return diff
+}
+
+func getResolutionChanges(resolutions1, resolutions2 []Resolution) []ResolutionChange {
+	resolved := map[string]Resolution{}
+	for _, r := range resolutions1 {
+		resolved[r.Name] = r
+	}
+	changes := []ResolutionChange{}
+	for _, r2 := range resolutions2 {
+		r1, ok := resolved[r2.Name]
+		if !ok || (r1.Path == r2.Path && r1.Target == r2.Target) {
+			continue
+		}
+		changes = append(changes, ResolutionChange{Name: r2.Name, Path1: r1.Path, Target1: r1.Target, Path2: r2.Path, Target2: r2.Target})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
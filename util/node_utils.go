@@ -0,0 +1,227 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// NodeGlobalModules are the image paths, as globs, of the node_modules
+// directories npm, nvm and yarn install global packages into.
+var NodeGlobalModules = []string{
+	"/usr/lib/node_modules",
+	"/usr/local/lib/node_modules",
+	"/opt/nodejs/lib/node_modules",
+	"/root/.nvm/versions/node/*/lib/node_modules",
+	"/usr/local/nvm/versions/node/*/lib/node_modules",
+	"/home/*/.nvm/versions/node/*/lib/node_modules",
+	"/usr/local/share/.config/yarn/global/node_modules",
+	"/root/.config/yarn/global/node_modules",
+	"/home/*/.config/yarn/global/node_modules",
+}
+
+// NodeProject returns the project the Node package installed at the image
+// path packagePath belongs to, and whether it is installed globally. The
+// project of a package installed in an app's node_modules tree, nested or
+// not, is the directory holding that tree, e.g. /srv/api for
+// /srv/api/node_modules/express/node_modules/debug/. Global packages
+// belong to the global node_modules directory itself.
+func NodeProject(packagePath string) (string, bool) {
+	p := path.Clean("/" + packagePath)
+	i := strings.Index(p+"/", "/node_modules/")
+	if i < 0 {
+		return path.Dir(p), false
+	}
+	modules := path.Join("/", p[:i], "node_modules")
+	if isNodeGlobalModules(modules) {
+		return modules, true
+	}
+	return path.Dir(modules), false
+}
+
+func isNodeGlobalModules(modules string) bool {
+	for _, pattern := range NodeGlobalModules {
+		if ok, _ := path.Match(pattern, modules); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNodeProjectDiff diffs the Node packages of two images project by
+// project, so that a package moving from one app to another, or installed
+// at different versions by unrelated apps, isn't reported as a version
+// change of a single package. Version differences are labeled with their
+// project.
+func GetNodeProjectDiff(map1, map2 map[string]map[string]PackageInfo) MultiVersionPackageDiff {
+	projects1 := splitNodeProjects(map1)
+	projects2 := splitNodeProjects(map2)
+	projects := map[string]bool{}
+	for project := range projects1 {
+		projects[project] = true
+	}
+	for project := range projects2 {
+		projects[project] = true
+	}
+
+	diff := MultiVersionPackageDiff{
+		Packages1: map[string]map[string]PackageInfo{},
+		Packages2: map[string]map[string]PackageInfo{},
+		InfoDiff:  []MultiVersionInfo{},
+	}
+	for project := range projects {
+		packages1, packages2 := projects1[project], projects2[project]
+		if packages1 == nil {
+			packages1 = map[string]map[string]PackageInfo{}
+		}
+		if packages2 == nil {
+			packages2 = map[string]map[string]PackageInfo{}
+		}
+		projectDiff := GetMultiVersionMapDiff(packages1, packages2)
+		mergePackagePaths(diff.Packages1, projectDiff.Packages1)
+		mergePackagePaths(diff.Packages2, projectDiff.Packages2)
+		for _, info := range projectDiff.InfoDiff {
+			info.Project = project
+			info.Global = isNodeGlobalModules(project)
+			diff.InfoDiff = append(diff.InfoDiff, info)
+		}
+	}
+	return diff
+}
+
+// splitNodeProjects splits a map of package names to the paths they are
+// installed at by project.
+func splitNodeProjects(packages map[string]map[string]PackageInfo) map[string]map[string]map[string]PackageInfo {
+	projects := map[string]map[string]map[string]PackageInfo{}
+	for name, paths := range packages {
+		for p, info := range paths {
+			project, _ := NodeProject(p)
+			if projects[project] == nil {
+				projects[project] = map[string]map[string]PackageInfo{}
+			}
+			if projects[project][name] == nil {
+				projects[project][name] = map[string]PackageInfo{}
+			}
+			projects[project][name][p] = info
+		}
+	}
+	return projects
+}
+
+func mergePackagePaths(dst, src map[string]map[string]PackageInfo) {
+	for name, paths := range src {
+		if dst[name] == nil {
+			dst[name] = map[string]PackageInfo{}
+		}
+		for p, info := range paths {
+			dst[name][p] = info
+		}
+	}
+}
+
+// getNodePackageOutput lists the packages of packageMap with their project.
+func getNodePackageOutput(packageMap map[string]map[string]PackageInfo, opts OutputOptions) []PackageOutput {
+	packages := getMultiVersionPackageOutput(packageMap, opts)
+	for i := range packages {
+		packages[i].Project, packages[i].Global = NodeProject(packages[i].Path)
+	}
+	return packages
+}
+
+// StrNodeProject is the text output of the packages of a Node project, or
+// of a global node_modules directory.
+type StrNodeProject struct {
+	Project  string
+	Global   bool
+	Packages []StrPackageOutput
+}
+
+// StrNodeProjectDiff is the text output of the differences between the
+// packages of a Node project in two images.
+type StrNodeProjectDiff struct {
+	Project   string
+	Global    bool
+	Packages1 []StrPackageOutput
+	Packages2 []StrPackageOutput
+	InfoDiff  []StrMultiVersionInfo
+}
+
+// groupNodeProjects groups packages by project, global directories first,
+// keeping the order of the packages within each project.
+func groupNodeProjects(packages []PackageOutput) []StrNodeProject {
+	index := map[string]int{}
+	projects := []StrNodeProject{}
+	for _, pack := range packages {
+		i, ok := index[pack.Project]
+		if !ok {
+			i = len(projects)
+			index[pack.Project] = i
+			projects = append(projects, StrNodeProject{Project: pack.Project, Global: pack.Global, Packages: []StrPackageOutput{}})
+		}
+		projects[i].Packages = append(projects[i].Packages, stringifyPackages([]PackageOutput{pack})...)
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Global != projects[j].Global {
+			return projects[i].Global
+		}
+		return projects[i].Project < projects[j].Project
+	})
+	return projects
+}
+
+// groupNodeProjectDiffs groups the differences of a Node package diff by
+// project, global directories first.
+func groupNodeProjectDiffs(packages1, packages2 []PackageOutput, infoDiff []MultiVersionInfo) []StrNodeProjectDiff {
+	index := map[string]int{}
+	projects := []StrNodeProjectDiff{}
+	project := func(name string, global bool) *StrNodeProjectDiff {
+		i, ok := index[name]
+		if !ok {
+			i = len(projects)
+			index[name] = i
+			projects = append(projects, StrNodeProjectDiff{
+				Project:   name,
+				Global:    global,
+				Packages1: []StrPackageOutput{},
+				Packages2: []StrPackageOutput{},
+				InfoDiff:  []StrMultiVersionInfo{},
+			})
+		}
+		return &projects[i]
+	}
+	for _, pack := range packages1 {
+		p := project(pack.Project, pack.Global)
+		p.Packages1 = append(p.Packages1, stringifyPackages([]PackageOutput{pack})...)
+	}
+	for _, pack := range packages2 {
+		p := project(pack.Project, pack.Global)
+		p.Packages2 = append(p.Packages2, stringifyPackages([]PackageOutput{pack})...)
+	}
+	for _, info := range infoDiff {
+		p := project(info.Project, info.Global)
+		p.InfoDiff = append(p.InfoDiff, stringifyMultiVersionPackageDiff([]MultiVersionInfo{info})...)
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Global != projects[j].Global {
+			return projects[i].Global
+		}
+		return projects[i].Project < projects[j].Project
+	})
+	return projects
+}
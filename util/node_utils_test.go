@@ -0,0 +1,81 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNodeProject(t *testing.T) {
+	testCases := []struct {
+		path    string
+		project string
+		global  bool
+	}{
+		{path: "/node_modules/left-pad/", project: "/"},
+		{path: "/srv/api/node_modules/express/node_modules/debug/", project: "/srv/api"},
+		{path: "/usr/local/lib/node_modules/npm/node_modules/semver/", project: "/usr/local/lib/node_modules", global: true},
+		{path: "/root/.nvm/versions/node/v20.10.0/lib/node_modules/pm2/", project: "/root/.nvm/versions/node/v20.10.0/lib/node_modules", global: true},
+	}
+	for _, test := range testCases {
+		project, global := NodeProject(test.path)
+		if project != test.project || global != test.global {
+			t.Errorf("Expected project %s (global %t) for %s but got %s (global %t)", test.project, test.global, test.path, project, global)
+		}
+	}
+}
+
+func TestGetNodeProjectDiff(t *testing.T) {
+	map1 := map[string]map[string]PackageInfo{
+		"debug":   {"/srv/api/node_modules/debug/": {Version: "2.6.9", Size: 10}},
+		"express": {"/srv/api/node_modules/express/": {Version: "4.18.2", Size: 20}},
+		"npm":     {"/usr/local/lib/node_modules/npm/": {Version: "10.2.0", Size: 30}},
+	}
+	map2 := map[string]map[string]PackageInfo{
+		"debug":   {"/srv/web/node_modules/debug/": {Version: "2.6.9", Size: 10}},
+		"express": {"/srv/api/node_modules/express/": {Version: "4.19.0", Size: 21}},
+		"npm":     {"/usr/local/lib/node_modules/npm/": {Version: "10.2.0", Size: 30}},
+	}
+	expected := MultiVersionPackageDiff{
+		Packages1: map[string]map[string]PackageInfo{"debug": {"/srv/api/node_modules/debug/": {Version: "2.6.9", Size: 10}}},
+		Packages2: map[string]map[string]PackageInfo{"debug": {"/srv/web/node_modules/debug/": {Version: "2.6.9", Size: 10}}},
+		InfoDiff: []MultiVersionInfo{{
+			Package: "express",
+			Info1:   []PackageInfo{{Version: "4.18.2", Size: 20}},
+			Info2:   []PackageInfo{{Version: "4.19.0", Size: 21}},
+			Project: "/srv/api",
+		}},
+	}
+	diff := GetNodeProjectDiff(map1, map2)
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected diff %+v but got %+v", expected, diff)
+	}
+
+	var buf bytes.Buffer
+	result := NodeDiffResult{Image1: "image1", Image2: "image2", DiffType: "Node", Diff: diff}
+	if err := result.OutputText(&buf, "Node", OutputOptions{}); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	output := buf.String()
+	api, web := strings.Index(output, "Project /srv/api:"), strings.Index(output, "Project /srv/web:")
+	if api < 0 || web < api || !strings.Contains(output, "-express") {
+		t.Errorf("Expected the diff grouped by project but got:\n%s", output)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+// writeOCILayout writes img to an OCI image layout in dir, listed in its
+// index once for each of descs, completed with the manifest of img.
+func writeOCILayout(t *testing.T, dir string, img v1.Image, descs ...v1.Descriptor) {
+	writeBlob := func(data []byte) v1.Hash {
+		h, _, _ := v1.SHA256(bytes.NewReader(data))
+		if err := os.MkdirAll(filepath.Join(dir, "blobs", h.Algorithm), 0755); err != nil {
+			t.Fatalf("Error creating blobs dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "blobs", h.Algorithm, h.Hex), data, 0644); err != nil {
+			t.Fatalf("Error writing blob: %s", err)
+		}
+		return h
+	}
+	layers, _ := img.Layers()
+	for _, layer := range layers {
+		r, _ := layer.Compressed()
+		data, _ := ioutil.ReadAll(r)
+		writeBlob(data)
+	}
+	config, _ := img.RawConfigFile()
+	writeBlob(config)
+	manifest, _ := img.RawManifest()
+	mediaType, _ := img.MediaType()
+	index := v1.IndexManifest{SchemaVersion: 2}
+	for _, desc := range descs {
+		desc.MediaType, desc.Size, desc.Digest = mediaType, int64(len(manifest)), writeBlob(manifest)
+		index.Manifests = append(index.Manifests, desc)
+	}
+	data, _ := json.Marshal(index)
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), data, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0644)
+}
+
+func TestOCILayoutImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	digest, _ := img.Digest()
+	writeOCILayout(t, dir, img,
+		v1.Descriptor{Annotations: map[string]string{"org.opencontainers.image.ref.name": "v1"}},
+		v1.Descriptor{Annotations: map[string]string{"io.containerd.image.name": "docker.io/library/app:v2"}},
+	)
+
+	for _, source := range []string{"oci://" + dir + ":v1", "oci://" + dir + ":v2"} {
+		image, err := pkgutil.GetImage(source, false, "")
+		if err != nil {
+			t.Errorf("Error retrieving %s: %s", source, err)
+			continue
+		}
+		if image.Digest != digest || len(image.Layers) != 0 {
+			t.Errorf("Expected image %s from %s but got %s", digest, source, image.Digest)
+		}
+		if empty, err := pkgutil.DirIsEmpty(image.FSPath); err != nil || empty {
+			t.Errorf("Expected the filesystem of %s extracted to %s", source, image.FSPath)
+		}
+		pkgutil.CleanupImage(image)
+	}
+	for _, source := range []string{"oci://" + dir, "oci://" + dir + ":v3", "oci://" + filepath.Join(dir, "blobs")} {
+		if _, err := pkgutil.GetImage(source, false, ""); err == nil {
+			t.Errorf("Expected an error retrieving %s", source)
+		}
+	}
+}
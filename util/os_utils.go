@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "strings"
+
+// OSRelease identifies the distribution of an image, as described by its
+// os-release file or, failing that, the release files of the distribution.
+// PointRelease is the more precise version some distributions keep apart
+// from os-release, such as the 12.4 of /etc/debian_version. Source lists the
+// files the release was read from.
+//
+// Lineage lists the images the image is likely built from, nearest first:
+// the base image named by its labels, the official image of its
+// distribution release, and the distributions that one derives from.
+type OSRelease struct {
+	ID           string
+	IDLike       []string
+	Name         string
+	Version      string
+	Codename     string
+	PrettyName   string
+	PointRelease string
+	Source       []string
+	Lineage      []string
+}
+
+// OSField is a single field of an OSRelease, for text output and diffs.
+type OSField struct {
+	Field string
+	Value string
+}
+
+// OSChange is a distribution field which differs between images. Empty
+// values are unknown.
+type OSChange struct {
+	Field  string
+	Value1 string
+	Value2 string
+}
+
+// OSDiff stores the distributions of two images and the fields in which
+// they differ.
+type OSDiff struct {
+	Release1 OSRelease
+	Release2 OSRelease
+	Changes  []OSChange
+}
+
+// Fields returns the fields of a release in a fixed order.
+func (r OSRelease) Fields() []OSField {
+	return []OSField{
+		{"ID", r.ID},
+		{"Name", r.Name},
+		{"Version", r.Version},
+		{"Codename", r.Codename},
+		{"PointRelease", r.PointRelease},
+		{"PrettyName", r.PrettyName},
+		{"Family", strings.Join(r.IDLike, " ")},
+		{"Lineage", strings.Join(r.Lineage, " < ")},
+	}
+}
+
+// GetOSDiff compares the distributions of two images field by field.
+func GetOSDiff(r1, r2 OSRelease) OSDiff {
+	diff := OSDiff{Release1: r1, Release2: r2, Changes: []OSChange{}}
+	fields1, fields2 := r1.Fields(), r2.Fields()
+	for i, f1 := range fields1 {
+		if f2 := fields2[i]; f1.Value != f2.Value {
+			diff.Changes = append(diff.Changes, OSChange{Field: f1.Field, Value1: f1.Value, Value2: f2.Value})
+		}
+	}
+	return diff
+}
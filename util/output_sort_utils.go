@@ -22,8 +22,6 @@ import (
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 )
 
-var SortSize bool
-
 type packageBy func(p1, p2 *PackageOutput) bool
 
 func (by packageBy) Sort(packages []PackageOutput) {
@@ -226,18 +224,21 @@ var directorySizeSort = func(e1, e2 *pkgutil.DirectoryEntry) bool {
 	return e1.Size > e2.Size
 }
 
-func sortDirDiff(diff DirDiff) DirDiff {
-	adds, dels, mods := diff.Adds, diff.Dels, diff.Mods
-	if SortSize {
-		directoryBy(directorySizeSort).Sort(adds)
-		directoryBy(directorySizeSort).Sort(dels)
-		entryDiffBy(entryDiffSizeSort).Sort(mods)
+// sortDirectoryEntries sorts entries by size or name depending on
+// opts.SortSize.
+func sortDirectoryEntries(entries []pkgutil.DirectoryEntry, opts OutputOptions) {
+	if opts.SortSize {
+		directoryBy(directorySizeSort).Sort(entries)
 	} else {
-		directoryBy(directoryNameSort).Sort(adds)
-		directoryBy(directoryNameSort).Sort(dels)
-		entryDiffBy(entryDiffSizeSort).Sort(mods)
+		directoryBy(directoryNameSort).Sort(entries)
 	}
-	return DirDiff{adds, dels, mods}
+}
+
+func sortDirDiff(diff DirDiff, opts OutputOptions) DirDiff {
+	sortDirectoryEntries(diff.Adds, opts)
+	sortDirectoryEntries(diff.Dels, opts)
+	entryDiffBy(entryDiffSizeSort).Sort(diff.Mods)
+	return diff
 }
 
 type entryDiffBy func(a, b *EntryDiff) bool
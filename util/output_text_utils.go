@@ -17,6 +17,8 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
+
 	"code.cloudfoundry.org/bytefmt"
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
 )
@@ -49,6 +51,7 @@ type StrMultiVersionInfo struct {
 	Package string
 	Info1   []StrPackageInfo
 	Info2   []StrPackageInfo
+	Change  string
 }
 
 type StrPackageInfo struct {
@@ -64,6 +67,7 @@ type StrInfo struct {
 	Package string
 	Info1   StrPackageInfo
 	Info2   StrPackageInfo
+	Change  string
 }
 
 func stringifyPackageDiff(infoDiff []Info) (strInfoDiff []StrInfo) {
@@ -71,7 +75,7 @@ func stringifyPackageDiff(infoDiff []Info) (strInfoDiff []StrInfo) {
 		strInfo1 := stringifyPackageInfo(diff.Info1)
 		strInfo2 := stringifyPackageInfo(diff.Info2)
 
-		strDiff := StrInfo{Package: diff.Package, Info1: strInfo1, Info2: strInfo2}
+		strDiff := StrInfo{Package: diff.Package, Info1: strInfo1, Info2: strInfo2, Change: diff.Change}
 		strInfoDiff = append(strInfoDiff, strDiff)
 	}
 	return
@@ -89,7 +93,7 @@ func stringifyMultiVersionPackageDiff(infoDiff []MultiVersionInfo) (strInfoDiff
 			strInfos2 = append(strInfos2, stringifyPackageInfo(info))
 		}
 
-		strDiff := StrMultiVersionInfo{Package: diff.Package, Info1: strInfos1, Info2: strInfos2}
+		strDiff := StrMultiVersionInfo{Package: diff.Package, Info1: strInfos1, Info2: strInfos2, Change: diff.Change}
 		strInfoDiff = append(strInfoDiff, strDiff)
 	}
 	return
@@ -102,7 +106,11 @@ type StrDirectoryEntry struct {
 
 func stringifyDirectoryEntries(entries []pkgutil.DirectoryEntry) (strEntries []StrDirectoryEntry) {
 	for _, entry := range entries {
-		strEntry := StrDirectoryEntry{Name: entry.Name, Size: stringifySize(entry.Size)}
+		name := entry.Name
+		if entry.Linkname != "" {
+			name = fmt.Sprintf("%s -> %s", name, entry.Linkname)
+		}
+		strEntry := StrDirectoryEntry{Name: name, Size: stringifySize(entry.Size)}
 		strEntries = append(strEntries, strEntry)
 	}
 	return
@@ -116,36 +124,223 @@ type StrEntryDiff struct {
 
 func stringifyEntryDiffs(entries []EntryDiff) (strEntries []StrEntryDiff) {
 	for _, entry := range entries {
-		strEntry := StrEntryDiff{Name: entry.Name, Size1: stringifySize(entry.Size1), Size2: stringifySize(entry.Size2)}
+		strEntry := StrEntryDiff{Name: entryDiffName(entry), Size1: stringifySize(entry.Size1), Size2: stringifySize(entry.Size2)}
 		strEntries = append(strEntries, strEntry)
 	}
 	return
 }
 
+// entryDiffName shows how the target of a modified symlink changed.
+func entryDiffName(entry EntryDiff) string {
+	switch {
+	case entry.Link1 != "" && entry.Link2 != "":
+		return fmt.Sprintf("%s -> %s (was %s)", entry.Name, entry.Link2, entry.Link1)
+	case entry.Link2 != "":
+		return fmt.Sprintf("%s -> %s (was not a symlink)", entry.Name, entry.Link2)
+	case entry.Link1 != "":
+		return fmt.Sprintf("%s (was a symlink to %s)", entry.Name, entry.Link1)
+	}
+	return entry.Name
+}
+
 type StrSizeEntry struct {
-	Name   string
-	Digest string
-	Size   string
+	Name           string
+	Digest         string
+	Size           string
+	CompressedSize string
 }
 
 func stringifySizeEntries(entries []SizeEntry) (strEntries []StrSizeEntry) {
 	for _, entry := range entries {
-		strEntry := StrSizeEntry{Name: entry.Name, Digest: entry.Digest.String(), Size: stringifySize(entry.Size)}
+		strEntry := StrSizeEntry{Name: entry.Name, Digest: entry.Digest.String(), Size: stringifySize(entry.Size), CompressedSize: stringifyCompressedSize(entry.CompressedSize)}
 		strEntries = append(strEntries, strEntry)
 	}
 	return
 }
 
 type StrSizeDiff struct {
-	Name  string
-	Size1 string
-	Size2 string
+	Name            string
+	Size1           string
+	Size2           string
+	CompressedSize1 string
+	CompressedSize2 string
+	Change          string
 }
 
 func stringifySizeDiffs(entries []SizeDiff) (strEntries []StrSizeDiff) {
 	for _, entry := range entries {
-		strEntry := StrSizeDiff{Name: entry.Name, Size1: stringifySize(entry.Size1), Size2: stringifySize(entry.Size2)}
+		strEntry := StrSizeDiff{
+			Name:            entry.Name,
+			Size1:           stringifySize(entry.Size1),
+			Size2:           stringifySize(entry.Size2),
+			CompressedSize1: stringifyCompressedSize(entry.CompressedSize1),
+			CompressedSize2: stringifyCompressedSize(entry.CompressedSize2),
+			Change:          stringifySizeChange(entry.Size1, entry.Size2),
+		}
 		strEntries = append(strEntries, strEntry)
 	}
 	return
 }
+
+// stringifyCompressedSize is stringifySize for compressed sizes, which are
+// 0 when the image manifest wasn't available.
+func stringifyCompressedSize(size int64) string {
+	if size == 0 {
+		return "unknown"
+	}
+	return stringifySize(size)
+}
+
+// stringifySizeChange tells how much a layer or image grew or shrank, e.g.
+// +1.5M, or whether it was added or removed.
+func stringifySizeChange(size1, size2 int64) string {
+	switch {
+	case size1 == -1:
+		return "added"
+	case size2 == -1:
+		return "removed"
+	case size2 >= size1:
+		return "+" + stringifySize(size2-size1)
+	}
+	return "-" + stringifySize(size1-size2)
+}
+
+type StrDirHotspot struct {
+	Dir      string
+	Size1    string
+	Size2    string
+	Change   string
+	Added    int
+	Deleted  int
+	Modified int
+}
+
+func stringifyHotspots(hotspots []DirHotspot) (strHotspots []StrDirHotspot) {
+	for _, h := range hotspots {
+		strHotspots = append(strHotspots, StrDirHotspot{
+			Dir:      h.Dir,
+			Size1:    stringifySize(h.Size1),
+			Size2:    stringifySize(h.Size2),
+			Change:   stringifySizeChange(h.Size1, h.Size2),
+			Added:    h.Added,
+			Deleted:  h.Deleted,
+			Modified: h.Modified,
+		})
+	}
+	return
+}
+
+type StrLayerSuggestion struct {
+	Layer             int
+	Command           string
+	MoveBefore        int
+	MoveBeforeCommand string
+	SavedBytes        string
+}
+
+func stringifyLayerSuggestions(suggestions []LayerSuggestion) (strSuggestions []StrLayerSuggestion) {
+	for _, s := range suggestions {
+		strSuggestion := StrLayerSuggestion{
+			Layer:             s.Layer,
+			Command:           s.Command,
+			MoveBefore:        s.MoveBefore,
+			MoveBeforeCommand: s.MoveBeforeCommand,
+			SavedBytes:        stringifySize(s.SavedBytes),
+		}
+		strSuggestions = append(strSuggestions, strSuggestion)
+	}
+	return
+}
+
+type StrReproFinding struct {
+	Priority int
+	Issue    string
+	Fix      string
+	Items    []string
+	Size     string
+}
+
+func stringifyReproFindings(findings []ReproFinding) (strFindings []StrReproFinding) {
+	for _, f := range findings {
+		strFinding := StrReproFinding{Priority: f.Priority, Issue: f.Issue, Fix: f.Fix, Items: f.Items, Size: stringifySize(f.Size)}
+		strFindings = append(strFindings, strFinding)
+	}
+	return
+}
+
+type StrScoreCategory struct {
+	Category string
+	Score    int
+	Value    string
+}
+
+func stringifyScoreValue(value int64, unit string) string {
+	if unit == UnitBytes {
+		return stringifySize(value)
+	}
+	return fmt.Sprintf("%d %s", value, unit)
+}
+
+func stringifyScoreCategories(categories []ScoreCategory) []StrScoreCategory {
+	strCategories := []StrScoreCategory{}
+	for _, c := range categories {
+		strCategories = append(strCategories, StrScoreCategory{c.Category, c.Score, stringifyScoreValue(c.Value, c.Unit)})
+	}
+	return strCategories
+}
+
+type StrScoreDelta struct {
+	Category string
+	Score1   string
+	Score2   string
+	Delta    string
+	Value1   string
+	Value2   string
+}
+
+func stringifyScoreDeltas(deltas []ScoreDelta) []StrScoreDelta {
+	strDeltas := []StrScoreDelta{}
+	for _, d := range deltas {
+		strDelta := StrScoreDelta{
+			Category: d.Category,
+			Score1:   "n/a",
+			Score2:   "n/a",
+			Delta:    "n/a",
+			Value1:   "n/a",
+			Value2:   "n/a",
+		}
+		if !d.Missing1 {
+			strDelta.Score1 = fmt.Sprint(d.Score1)
+			strDelta.Value1 = stringifyScoreValue(d.Value1, d.Unit)
+		}
+		if !d.Missing2 {
+			strDelta.Score2 = fmt.Sprint(d.Score2)
+			strDelta.Value2 = stringifyScoreValue(d.Value2, d.Unit)
+		}
+		if !d.Missing1 && !d.Missing2 {
+			strDelta.Delta = fmt.Sprintf("%+d", d.Delta)
+		}
+		strDeltas = append(strDeltas, strDelta)
+	}
+	return strDeltas
+}
+
+type StrArchiveDiff struct {
+	Archive string
+	Adds    []StrDirectoryEntry
+	Dels    []StrDirectoryEntry
+	Mods    []StrEntryDiff
+}
+
+func stringifyArchiveDiffs(diffs []ArchiveDiff) []StrArchiveDiff {
+	strDiffs := []StrArchiveDiff{}
+	for _, d := range diffs {
+		strDiffs = append(strDiffs, StrArchiveDiff{
+			Archive: d.Archive,
+			Adds:    stringifyDirectoryEntries(d.Adds),
+			Dels:    stringifyDirectoryEntries(d.Dels),
+			Mods:    stringifyEntryDiffs(d.Mods),
+		})
+	}
+	return strDiffs
+}
@@ -37,6 +37,13 @@ type MultiVersionInfo struct {
 	Package string
 	Info1   []PackageInfo
 	Info2   []PackageInfo
+	// Project and Global are set by analyzers which diff packages by
+	// project, such as the Node analyzer.
+	Project string `json:",omitempty"`
+	Global  bool   `json:",omitempty"`
+	// Change is the kind of version change, e.g. minor or downgrade, from
+	// the highest version installed in each image.
+	Change string `json:",omitempty"`
 }
 
 // PackageDiff stores the difference information between two images.
@@ -57,6 +64,8 @@ type Info struct {
 	Package string
 	Info1   PackageInfo
 	Info2   PackageInfo
+	// Change is the kind of version change, e.g. minor or downgrade.
+	Change string `json:",omitempty"`
 }
 
 // PackageInfo stores the specific metadata about a package.
@@ -90,7 +99,7 @@ func multiVersionDiff(infoDiff []MultiVersionInfo, packageName string, map1, map
 	}
 
 	if len(diff1) > 0 || len(diff2) > 0 {
-		infoDiff = append(infoDiff, MultiVersionInfo{packageName, diff1, diff2})
+		infoDiff = append(infoDiff, MultiVersionInfo{Package: packageName, Info1: diff1, Info2: diff2})
 	}
 	return infoDiff
 }
@@ -168,7 +177,7 @@ func diffMaps(map1, map2 interface{}) interface{} {
 				packageInfo2 := packageEntry2.Interface().(PackageInfo)
 				// If two instances of the same package don't have the same version, then they are considered to be different
 				if packageInfo1.Version != packageInfo2.Version {
-					infoDiff = append(infoDiff, Info{pack.String(), packageInfo1, packageInfo2})
+					infoDiff = append(infoDiff, Info{Package: pack.String(), Info1: packageInfo1, Info2: packageInfo2})
 				}
 			}
 			map2Value.SetMapIndex(pack, reflect.Value{})
@@ -76,7 +76,7 @@ func TestDiffMaps(t *testing.T) {
 				Packages1: map[string]PackageInfo{},
 				Packages2: map[string]PackageInfo{},
 				InfoDiff: []Info{
-					{"pac3", PackageInfo{"3.0", 60}, PackageInfo{"4.0", 60}}},
+					{Package: "pac3", Info1: PackageInfo{"3.0", 60}, Info2: PackageInfo{"4.0", 60}}},
 			},
 		},
 		{
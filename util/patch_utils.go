@@ -0,0 +1,225 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DefaultPatchGlobs select the configuration files written as patches when
+// no globs are given.
+var DefaultPatchGlobs = []string{"/etc/**", "*.conf", "*.cnf", "*.cfg", "*.ini", "*.yaml", "*.yml", "*.toml", "*.properties"}
+
+// DefaultInterestingFiles select the files whose content diffs file diffs
+// always show when they change, as the security and name resolution
+// settings they hold are the changes reviewers most often need to see.
+var DefaultInterestingFiles = []string{
+	"/etc/ssh/sshd_config", "/etc/ssh/sshd_config.d/**",
+	"/etc/sudoers", "/etc/sudoers.d/**",
+	"/etc/crontab", "/etc/cron.d/**", "/var/spool/cron/**",
+	"/etc/pam.d/**",
+	"/etc/nsswitch.conf", "/etc/resolv.conf",
+}
+
+// maxPatchFileSize skips files too large to be reviewed as patches
+const maxPatchFileSize = 1 << 20
+
+// ContentDiff is a unified diff of a text file modified between two images.
+type ContentDiff struct {
+	Name string
+	Diff string
+}
+
+// MatchPatchGlob reports whether the image path name matches a glob. Globs
+// without a slash match the base name, and a trailing /** matches everything
+// below a directory.
+func MatchPatchGlob(glob, name string) bool {
+	return pkgutil.MatchPathGlob(glob, name)
+}
+
+// WritePatches writes a unified patch below dir for each text file matching
+// globs which was added, deleted or modified between two images, mirroring
+// the image layout, e.g. etc/nginx/nginx.conf.patch. Patches use a/ and b/
+// prefixes, so they apply with patch -p1 or git apply. The patch paths are
+// returned.
+func WritePatches(image1, image2 *pkgutil.Image, dir string, globs []string) ([]string, error) {
+	if len(globs) == 0 {
+		globs = DefaultPatchGlobs
+	}
+	dir1, err := pkgutil.GetDirectory(image1.FSPath, true)
+	if err != nil {
+		return nil, err
+	}
+	dir2, err := pkgutil.GetDirectory(image2.FSPath, true)
+	if err != nil {
+		return nil, err
+	}
+	changed := append(GetModifiedEntries(dir1, dir2), GetAddedEntries(dir1, dir2)...)
+	changed = append(changed, GetDeletedEntries(dir1, dir2)...)
+	sort.Strings(changed)
+
+	patches := []string{}
+	for _, name := range changed {
+		if !matchesAny(globs, name) {
+			continue
+		}
+		a, okA := readPatchFile(filepath.Join(image1.FSPath, name), maxPatchFileSize)
+		b, okB := readPatchFile(filepath.Join(image2.FSPath, name), maxPatchFileSize)
+		if !okA || !okB {
+			continue
+		}
+		text, err := unifiedPatch(name, a, b)
+		if err != nil {
+			return patches, errors.Wrapf(err, "diffing %s", name)
+		}
+		if text == "" {
+			continue
+		}
+		patchPath := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, "/"))+".patch")
+		if err := os.MkdirAll(filepath.Dir(patchPath), 0755); err != nil {
+			return patches, err
+		}
+		if err := ioutil.WriteFile(patchPath, []byte(text), 0644); err != nil {
+			return patches, err
+		}
+		patches = append(patches, patchPath)
+	}
+	return patches, nil
+}
+
+// DiffModifiedContents returns a unified diff of each text file modified in
+// diff, reading the files below root1 and root2. Files larger than maxSize
+// in either image are left out, as are binary files and links.
+func DiffModifiedContents(diff DirDiff, root1, root2 string, maxSize int64) ([]ContentDiff, error) {
+	contents := []ContentDiff{}
+	for _, mod := range diff.Mods {
+		a, okA := readPatchFile(filepath.Join(root1, mod.Name), maxSize)
+		b, okB := readPatchFile(filepath.Join(root2, mod.Name), maxSize)
+		if !okA || !okB || a == nil || b == nil {
+			continue
+		}
+		text, err := unifiedPatch(mod.Name, a, b)
+		if err != nil {
+			return contents, errors.Wrapf(err, "diffing %s", mod.Name)
+		}
+		if text != "" {
+			contents = append(contents, ContentDiff{Name: mod.Name, Diff: text})
+		}
+	}
+	return contents, nil
+}
+
+// DiffInterestingContents returns a unified diff of each text file matching
+// globs which was added, deleted or modified in diff, reading the files
+// below root1 and root2. Files larger than maxSize in either image are left
+// out, as are binary files and links.
+func DiffInterestingContents(diff DirDiff, root1, root2 string, maxSize int64, globs []string) ([]ContentDiff, error) {
+	names := []string{}
+	for _, mod := range diff.Mods {
+		names = append(names, mod.Name)
+	}
+	for _, entry := range append(append([]pkgutil.DirectoryEntry{}, diff.Adds...), diff.Dels...) {
+		names = append(names, entry.Name)
+	}
+	sort.Strings(names)
+
+	contents := []ContentDiff{}
+	for _, name := range names {
+		if !matchesAny(globs, name) {
+			continue
+		}
+		a, okA := readPatchFile(filepath.Join(root1, name), maxSize)
+		b, okB := readPatchFile(filepath.Join(root2, name), maxSize)
+		if !okA || !okB || (a == nil && b == nil) {
+			continue
+		}
+		text, err := unifiedPatch(name, a, b)
+		if err != nil {
+			return contents, errors.Wrapf(err, "diffing %s", name)
+		}
+		if text != "" {
+			contents = append(contents, ContentDiff{Name: name, Diff: text})
+		}
+	}
+	return contents, nil
+}
+
+func matchesAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if MatchPatchGlob(glob, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPatchFile returns the lines of a text file, or nil if it doesn't
+// exist. Directories, links, binary files and files larger than maxSize
+// can't be patched.
+func readPatchFile(name string, maxSize int64) ([]string, bool) {
+	info, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil, true
+	}
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxSize {
+		return nil, false
+	}
+	contents, err := ioutil.ReadFile(name)
+	if err != nil || bytes.IndexByte(contents, 0) >= 0 {
+		return nil, false
+	}
+	return patchLines(string(contents)), true
+}
+
+// patchLines splits text into the lines of a patch, each ending in a newline.
+func patchLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		// patch has no way to keep the final line unterminated here
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
+func unifiedPatch(name string, a, b []string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	diff := difflib.UnifiedDiff{
+		A:        a,
+		B:        b,
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	}
+	if a == nil {
+		diff.FromFile = "/dev/null"
+	}
+	if b == nil {
+		diff.ToFile = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(diff)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func TestMatchPatchGlob(t *testing.T) {
+	testCases := []struct {
+		glob     string
+		name     string
+		expected bool
+	}{
+		{glob: "/etc/**", name: "/etc/nginx/nginx.conf", expected: true},
+		{glob: "etc/**", name: "/etc/hosts", expected: true},
+		{glob: "/etc/**", name: "/etcetera/file", expected: false},
+		{glob: "*.yaml", name: "/app/config/settings.yaml", expected: true},
+		{glob: "*.yaml", name: "/app/config/settings.json", expected: false},
+		{glob: "/app/*.json", name: "/app/package.json", expected: true},
+		{glob: "/app/*.json", name: "/app/lib/package.json", expected: false},
+	}
+	for _, test := range testCases {
+		if actual := MatchPatchGlob(test.glob, test.name); actual != test.expected {
+			t.Errorf("Expected %s matching %s to be %v but got %v", test.glob, test.name, test.expected, actual)
+		}
+	}
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error creating directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("Error writing file: %s", err)
+		}
+	}
+}
+
+func TestWritePatches(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "patches")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	root1, root2, patchDir := filepath.Join(tmp, "image1"), filepath.Join(tmp, "image2"), filepath.Join(tmp, "patches")
+	writeTestFiles(t, root1, map[string]string{
+		"etc/app.conf":  "port=80\nhost=localhost\n",
+		"etc/removed":   "gone\n",
+		"etc/same":      "same\n",
+		"bin/tool":      "#!/bin/sh\n",
+		"etc/blob.conf": "a\x00b",
+	})
+	writeTestFiles(t, root2, map[string]string{
+		"etc/app.conf":  "port=8080\nhost=localhost\n",
+		"etc/same":      "same\n",
+		"bin/tool":      "#!/bin/bash\n",
+		"etc/blob.conf": "a\x00c",
+		"srv/new.yaml":  "key: value\n",
+	})
+
+	patches, err := WritePatches(&pkgutil.Image{FSPath: root1}, &pkgutil.Image{FSPath: root2}, patchDir, nil)
+	if err != nil {
+		t.Fatalf("Error writing patches: %s", err)
+	}
+	expected := []string{
+		filepath.Join(patchDir, "etc/app.conf.patch"),
+		filepath.Join(patchDir, "etc/removed.patch"),
+		filepath.Join(patchDir, "srv/new.yaml.patch"),
+	}
+	if !reflect.DeepEqual(patches, expected) {
+		t.Fatalf("Expected patches %v but got %v", expected, patches)
+	}
+
+	expectedPatches := map[string]string{
+		"etc/app.conf.patch": "--- a/etc/app.conf\n+++ b/etc/app.conf\n@@ -1,2 +1,2 @@\n-port=80\n+port=8080\n host=localhost\n",
+		"etc/removed.patch":  "--- a/etc/removed\n+++ /dev/null\n@@ -1 +0,0 @@\n-gone\n",
+		"srv/new.yaml.patch": "--- /dev/null\n+++ b/srv/new.yaml\n@@ -0,0 +1 @@\n+key: value\n",
+	}
+	for name, contents := range expectedPatches {
+		actual, err := ioutil.ReadFile(filepath.Join(patchDir, name))
+		if err != nil {
+			t.Fatalf("Error reading patch: %s", err)
+		}
+		if string(actual) != contents {
+			t.Errorf("Expected %s to be:\n%s\nbut got:\n%s", name, contents, actual)
+		}
+	}
+}
+
+func TestDiffModifiedContents(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "contents")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	root1, root2 := filepath.Join(tmp, "image1"), filepath.Join(tmp, "image2")
+	writeTestFiles(t, root1, map[string]string{
+		"etc/app.conf":  "port=80\n\thost=localhost\n",
+		"etc/blob.conf": "a\x00b",
+		"etc/large":     "small\n",
+	})
+	writeTestFiles(t, root2, map[string]string{
+		"etc/app.conf":  "port=8080\n\thost=localhost\n",
+		"etc/blob.conf": "a\x00c",
+		"etc/large":     "a line too many for the size limit\n",
+	})
+	dir1, _ := pkgutil.GetDirectory(root1, true)
+	dir2, _ := pkgutil.GetDirectory(root2, true)
+	diff, _ := DiffDirectory(dir1, dir2)
+
+	contents, err := DiffModifiedContents(diff, root1, root2, 32)
+	if err != nil {
+		t.Fatalf("Error diffing contents: %s", err)
+	}
+	expected := []ContentDiff{{
+		Name: "/etc/app.conf",
+		Diff: "--- a/etc/app.conf\n+++ b/etc/app.conf\n@@ -1,2 +1,2 @@\n-port=80\n+port=8080\n \thost=localhost\n",
+	}}
+	if !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected content diffs %v but got %v", expected, contents)
+	}
+}
+
+func TestDiffInterestingContents(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "interesting")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	root1, root2 := filepath.Join(tmp, "image1"), filepath.Join(tmp, "image2")
+	writeTestFiles(t, root1, map[string]string{
+		"etc/ssh/sshd_config":  "PermitRootLogin no\n",
+		"etc/cron.d/cleanup":   "0 3 * * * root /usr/local/bin/cleanup\n",
+		"etc/app.conf":         "port=80\n",
+		"etc/resolv.conf":      "nameserver 10.0.0.2\n",
+		"etc/pam.d/common-foo": "a\x00b",
+	})
+	writeTestFiles(t, root2, map[string]string{
+		"etc/ssh/sshd_config":  "PermitRootLogin yes\n",
+		"etc/sudoers.d/app":    "app ALL=(ALL) NOPASSWD: ALL\n",
+		"etc/app.conf":         "port=8080\n",
+		"etc/resolv.conf":      "nameserver 10.0.0.2\n",
+		"etc/pam.d/common-foo": "a\x00c",
+	})
+	dir1, _ := pkgutil.GetDirectory(root1, true)
+	dir2, _ := pkgutil.GetDirectory(root2, true)
+	diff, _ := DiffDirectory(dir1, dir2)
+
+	contents, err := DiffInterestingContents(diff, root1, root2, 1024, DefaultInterestingFiles)
+	if err != nil {
+		t.Fatalf("Error diffing contents: %s", err)
+	}
+	expected := []ContentDiff{
+		{Name: "/etc/cron.d/cleanup", Diff: "--- a/etc/cron.d/cleanup\n+++ /dev/null\n@@ -1 +0,0 @@\n-0 3 * * * root /usr/local/bin/cleanup\n"},
+		{Name: "/etc/ssh/sshd_config", Diff: "--- a/etc/ssh/sshd_config\n+++ b/etc/ssh/sshd_config\n@@ -1 +1 @@\n-PermitRootLogin no\n+PermitRootLogin yes\n"},
+		{Name: "/etc/sudoers.d/app", Diff: "--- /dev/null\n+++ b/etc/sudoers.d/app\n@@ -0,0 +1 @@\n+app ALL=(ALL) NOPASSWD: ALL\n"},
+	}
+	if !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected content diffs %v but got %v", expected, contents)
+	}
+}
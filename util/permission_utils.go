@@ -0,0 +1,198 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+// Permissions worth a warning when an image gains them.
+const (
+	PermSetuid        = "setuid"
+	PermSetgid        = "setgid"
+	PermWorldWritable = "world-writable"
+)
+
+// FilePermission is the mode and owner of a file, along with the risky
+// permissions it has.
+type FilePermission struct {
+	Name  string
+	Mode  string
+	Uid   int
+	Gid   int
+	Flags []string
+}
+
+// PermissionChange is a file present in both images whose mode or owner
+// changed. Flags lists the risky permissions it gained.
+type PermissionChange struct {
+	Name   string
+	Mode1  string
+	Mode2  string
+	Owner1 string
+	Owner2 string
+	Flags  []string
+}
+
+// PermissionDiff holds the mode and owner changes of the files in both
+// images, and warnings for the risky permissions introduced by the second,
+// including by the files it added.
+type PermissionDiff struct {
+	Changes  []PermissionChange
+	Warnings []string
+}
+
+// PermissionFlags returns the risky permissions of a mode: setuid and
+// setgid files, and world-writable files and directories other than sticky
+// ones such as /tmp. Symlinks have none.
+func PermissionFlags(mode os.FileMode) []string {
+	flags := []string{}
+	if mode&os.ModeSymlink != 0 {
+		return flags
+	}
+	if mode.IsRegular() {
+		if mode&os.ModeSetuid != 0 {
+			flags = append(flags, PermSetuid)
+		}
+		if mode&os.ModeSetgid != 0 {
+			flags = append(flags, PermSetgid)
+		}
+	}
+	if mode.Perm()&0002 != 0 && !(mode.IsDir() && mode&os.ModeSticky != 0) {
+		flags = append(flags, PermWorldWritable)
+	}
+	return flags
+}
+
+// RiskyPermissions lists the files of an inventory with risky permissions,
+// sorted by name.
+func RiskyPermissions(inv pkgutil.FileInventory) []FilePermission {
+	files := []FilePermission{}
+	for _, name := range sortedNames(inv) {
+		entry := inv[name]
+		if flags := PermissionFlags(entry.Mode); len(flags) != 0 {
+			files = append(files, FilePermission{Name: name, Mode: FormatMode(entry.Mode), Uid: entry.Uid, Gid: entry.Gid, Flags: flags})
+		}
+	}
+	return files
+}
+
+// GetPermissionDiff compares the modes and owners of the files of two
+// inventories. Symlinks, whose modes are meaningless, and directories only
+// implied by the paths below them are left out.
+func GetPermissionDiff(inv1, inv2 pkgutil.FileInventory) PermissionDiff {
+	diff := PermissionDiff{Changes: []PermissionChange{}, Warnings: []string{}}
+	for _, name := range sortedNames(inv2) {
+		entry2 := inv2[name]
+		if !hasPermissions(entry2) {
+			continue
+		}
+		entry1, ok := inv1[name]
+		if !ok {
+			for _, flag := range PermissionFlags(entry2.Mode) {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s was added %s", name, flag))
+			}
+			continue
+		}
+		if !hasPermissions(entry1) || (entry1.Mode == entry2.Mode && entry1.Uid == entry2.Uid && entry1.Gid == entry2.Gid) {
+			continue
+		}
+		gained := gainedFlags(PermissionFlags(entry1.Mode), PermissionFlags(entry2.Mode))
+		diff.Changes = append(diff.Changes, PermissionChange{
+			Name:   name,
+			Mode1:  FormatMode(entry1.Mode),
+			Mode2:  FormatMode(entry2.Mode),
+			Owner1: fmt.Sprintf("%d:%d", entry1.Uid, entry1.Gid),
+			Owner2: fmt.Sprintf("%d:%d", entry2.Uid, entry2.Gid),
+			Flags:  gained,
+		})
+		for _, flag := range gained {
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("%s became %s", name, flag))
+		}
+	}
+	return diff
+}
+
+// FormatMode formats a mode as ls -l does, e.g. -rwsr-xr-x.
+func FormatMode(mode os.FileMode) string {
+	b := []byte("----------")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		b[0] = 'b'
+	case mode&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		b[0] = 's'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	special := func(i int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if b[i] == 'x' {
+			b[i] = c
+		} else {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's')
+	special(6, mode&os.ModeSetgid != 0, 's')
+	special(9, mode&os.ModeSticky != 0, 't')
+	return string(b)
+}
+
+func hasPermissions(entry pkgutil.InventoryEntry) bool {
+	return entry.Mode&os.ModeSymlink == 0 && !(entry.IsDir && entry.Mode == 0)
+}
+
+func gainedFlags(flags1, flags2 []string) []string {
+	gained := []string{}
+	for _, flag := range flags2 {
+		found := false
+		for _, f := range flags1 {
+			found = found || f == flag
+		}
+		if !found {
+			gained = append(gained, flag)
+		}
+	}
+	return gained
+}
+
+func sortedNames(inv pkgutil.FileInventory) []string {
+	names := make([]string, 0, len(inv))
+	for name := range inv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
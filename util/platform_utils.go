@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+const (
+	PlatformIdentical = "identical"
+	PlatformChanged   = "changed"
+	PlatformRemoved   = "removed"
+	PlatformAdded     = "added"
+)
+
+// PlatformSummary pairs the images of one platform in two manifest lists.
+// Only changed platforms have both references set and need diffing.
+type PlatformSummary struct {
+	Platform   string
+	Status     string
+	Digest1    string
+	Digest2    string
+	Reference1 string `json:"-"`
+	Reference2 string `json:"-"`
+}
+
+// MatchPlatforms pairs the images of two manifest lists by platform.
+func MatchPlatforms(images1, images2 map[string]pkgutil.PlatformImage) []PlatformSummary {
+	platforms := map[string]bool{}
+	for p := range images1 {
+		platforms[p] = true
+	}
+	for p := range images2 {
+		platforms[p] = true
+	}
+	summary := []PlatformSummary{}
+	for p := range platforms {
+		image1, ok1 := images1[p]
+		image2, ok2 := images2[p]
+		s := PlatformSummary{
+			Platform:   p,
+			Digest1:    image1.Digest,
+			Digest2:    image2.Digest,
+			Reference1: image1.Reference,
+			Reference2: image2.Reference,
+		}
+		switch {
+		case !ok2:
+			s.Status = PlatformRemoved
+		case !ok1:
+			s.Status = PlatformAdded
+		case image1.Digest == image2.Digest:
+			s.Status = PlatformIdentical
+		default:
+			s.Status = PlatformChanged
+		}
+		summary = append(summary, s)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Platform < summary[j].Platform })
+	return summary
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestMatchPlatforms(t *testing.T) {
+	images1 := map[string]pkgutil.PlatformImage{
+		"linux/amd64":              {Platform: "linux/amd64", Digest: "sha256:a1", Reference: "app@sha256:a1"},
+		"linux/arm64/v8":           {Platform: "linux/arm64/v8", Digest: "sha256:b", Reference: "app@sha256:b"},
+		"windows/amd64:10.0.17763": {Platform: "windows/amd64:10.0.17763", Digest: "sha256:w", Reference: "app@sha256:w"},
+	}
+	images2 := map[string]pkgutil.PlatformImage{
+		"linux/amd64":    {Platform: "linux/amd64", Digest: "sha256:a2", Reference: "app@sha256:a2"},
+		"linux/arm64/v8": {Platform: "linux/arm64/v8", Digest: "sha256:b", Reference: "app@sha256:b"},
+		"linux/s390x":    {Platform: "linux/s390x", Digest: "sha256:s", Reference: "app@sha256:s"},
+	}
+	expected := []PlatformSummary{
+		{Platform: "linux/amd64", Status: PlatformChanged, Digest1: "sha256:a1", Digest2: "sha256:a2", Reference1: "app@sha256:a1", Reference2: "app@sha256:a2"},
+		{Platform: "linux/arm64/v8", Status: PlatformIdentical, Digest1: "sha256:b", Digest2: "sha256:b", Reference1: "app@sha256:b", Reference2: "app@sha256:b"},
+		{Platform: "linux/s390x", Status: PlatformAdded, Digest2: "sha256:s", Reference2: "app@sha256:s"},
+		{Platform: "windows/amd64:10.0.17763", Status: PlatformRemoved, Digest1: "sha256:w", Reference1: "app@sha256:w"},
+	}
+	if summary := MatchPlatforms(images1, images2); !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, summary)
+	}
+}
+
+// serveDaemonInfo answers the Docker API on a unix socket at path as a
+// daemon running on architecture.
+func serveDaemonInfo(t *testing.T, path, architecture string) *http.Server {
+	socket, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Error listening on socket: %s", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/info") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"OSType": "linux", "Architecture": %q}`, architecture)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(socket)
+	return server
+}
+
+func TestDefaultPlatform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "platform")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	// registered first, so the daemon is asked again once the environment
+	// is restored
+	defer pkgutil.ConfigurePlatform("")
+	for _, key := range []string{"DOCKER_HOST", "HOME"} {
+		defer os.Setenv(key, os.Getenv(key))
+	}
+	daemon := serveDaemonInfo(t, filepath.Join(dir, "docker.sock"), "aarch64")
+	defer daemon.Close()
+
+	images := map[string]v1.Hash{}
+	manifests, blobs := map[string][]byte{}, map[string][]byte{}
+	var index v1.IndexManifest
+	for _, platform := range []v1.Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64", Variant: "v8"}} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("Error creating image: %s", err)
+		}
+		config, _ := img.RawConfigFile()
+		configDigest, _ := img.ConfigName()
+		blobs[configDigest.String()] = config
+		layers, _ := img.Layers()
+		for _, layer := range layers {
+			digest, _ := layer.Digest()
+			reader, _ := layer.Compressed()
+			blobs[digest.String()], _ = ioutil.ReadAll(reader)
+		}
+		manifest, _ := img.RawManifest()
+		digest, _ := img.Digest()
+		manifests[digest.String()] = manifest
+		images[platform.Architecture] = digest
+		p := platform
+		index.Manifests = append(index.Manifests, v1.Descriptor{MediaType: types.DockerManifestSchema2, Size: int64(len(manifest)), Digest: digest, Platform: &p})
+	}
+	index.SchemaVersion, index.MediaType = 2, types.OCIImageIndex
+	manifests["latest"], _ = json.Marshal(index)
+	server := serveRegistry(manifests, blobs)
+	defer server.Close()
+	source := "remote://" + strings.TrimPrefix(server.URL, "http://") + "/app:latest"
+
+	testCases := []struct {
+		descrip    string
+		platform   string
+		dockerHost string
+		expected   string
+	}{
+		{descrip: "the daemon platform", dockerHost: "unix://" + filepath.Join(dir, "docker.sock"), expected: "linux/arm64"},
+		{descrip: "the requested platform", platform: "linux/amd64", dockerHost: "unix://" + filepath.Join(dir, "docker.sock"), expected: "linux/amd64"},
+		{descrip: "no daemon", dockerHost: "unix://" + filepath.Join(dir, "missing.sock"), expected: "linux/" + runtime.GOARCH},
+	}
+	for _, test := range testCases {
+		os.Setenv("DOCKER_HOST", test.dockerHost)
+		if err := pkgutil.ConfigurePlatform(test.platform); err != nil {
+			t.Fatalf("%s: error configuring the platform: %s", test.descrip, err)
+		}
+		if platform := pkgutil.DefaultPlatform(); platform != test.expected {
+			t.Errorf("%s: expected platform %s but got %s", test.descrip, test.expected, platform)
+		}
+		expected, ok := images[strings.Split(test.expected, "/")[1]]
+		if !ok {
+			continue
+		}
+		image, err := pkgutil.GetImage(source, false, "")
+		if err != nil {
+			t.Errorf("%s: error retrieving the image: %s", test.descrip, err)
+			continue
+		}
+		if digest, _ := image.Image.Digest(); digest != expected {
+			t.Errorf("%s: expected the %s image %s but got %s", test.descrip, test.expected, expected, digest)
+		}
+		pkgutil.CleanupImage(image)
+	}
+
+	// Docker Desktop for Mac serves its API from the home directory
+	if _, err := os.Stat("/var/run/docker.sock"); err != nil {
+		os.Unsetenv("DOCKER_HOST")
+		os.Setenv("HOME", dir)
+		os.MkdirAll(filepath.Join(dir, ".docker", "run"), 0755)
+		desktop := serveDaemonInfo(t, filepath.Join(dir, ".docker", "run", "docker.sock"), "riscv64")
+		defer desktop.Close()
+		pkgutil.ConfigurePlatform("")
+		if platform := pkgutil.DefaultPlatform(); platform != "linux/riscv64" {
+			t.Errorf("Expected the platform of the Docker Desktop daemon, linux/riscv64, but got %s", platform)
+		}
+	}
+
+	if err := pkgutil.ConfigurePlatform("arm64"); err == nil {
+		t.Errorf("Expected an error for a platform without an OS")
+	}
+}
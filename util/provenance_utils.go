@@ -0,0 +1,317 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Attestation holds the artifact digests claimed by one or more in-toto
+// statements, such as SLSA provenance. Subjects are the artifacts a build
+// produced and Materials the artifacts it consumed, both keyed by digest.
+// BuildArgs are the build args BuildKit recorded, and Invocations identify
+// the build runs, often by the URL of the CI job.
+type Attestation struct {
+	Builders    []string
+	Subjects    map[string]string
+	Materials   map[string]string
+	BuildArgs   map[string]string
+	Invocations []string
+}
+
+// LayerProvenance records whether an attestation vouches for an image layer.
+// Claim names the subject or material whose digest matched the layer.
+type LayerProvenance struct {
+	Index    int
+	Digest   string
+	DiffID   string
+	Verified bool
+	Claim    string
+}
+
+// ProvenanceAnalysis cross-checks an image against the attestations found
+// for it. ImageVerified is set when the image digest is a claimed subject.
+type ProvenanceAnalysis struct {
+	Digest        string
+	Builders      []string
+	ImageVerified bool
+	Layers        []LayerProvenance
+}
+
+// LayerProvenanceChange is a layer of the second image, marked as New when
+// the first image doesn't share it.
+type LayerProvenanceChange struct {
+	LayerProvenance
+	New bool
+}
+
+type ProvenanceDiff struct {
+	ImageVerified1 bool
+	ImageVerified2 bool
+	Layers         []LayerProvenanceChange
+}
+
+// statement is the subset of an in-toto statement read for provenance. Both
+// SLSA v0.2 materials and v1 resolved dependencies are understood, as are
+// the frontend args BuildKit records in either.
+type statement struct {
+	Type      string `json:"_type"`
+	Subject   []resourceDescriptor
+	Predicate struct {
+		Builder struct {
+			ID string
+		}
+		Materials  []resourceDescriptor
+		Invocation struct {
+			Parameters struct {
+				Args map[string]interface{}
+			}
+		}
+		Metadata struct {
+			BuildInvocationID string
+		}
+		BuildDefinition struct {
+			ExternalParameters struct {
+				Request struct {
+					Args map[string]interface{}
+				}
+			}
+			ResolvedDependencies []resourceDescriptor
+		}
+		RunDetails struct {
+			Builder struct {
+				ID string
+			}
+			Metadata struct {
+				InvocationID string
+			}
+		}
+	}
+}
+
+// buildArgPrefix marks the build args among BuildKit frontend args
+const buildArgPrefix = "build-arg:"
+
+type resourceDescriptor struct {
+	Name   string
+	URI    string
+	Digest map[string]string
+}
+
+// envelope is a DSSE envelope wrapping a base64 encoded statement.
+type envelope struct {
+	PayloadType string
+	Payload     string
+}
+
+func NewAttestation() *Attestation {
+	return &Attestation{
+		Builders:    []string{},
+		Subjects:    map[string]string{},
+		Materials:   map[string]string{},
+		BuildArgs:   map[string]string{},
+		Invocations: []string{},
+	}
+}
+
+// Add parses in-toto statements, bare or in DSSE envelopes, from data.
+// Statements may be concatenated as JSON lines, as in attestation bundles.
+// Signatures are not checked.
+func (a *Attestation) Add(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	found := false
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return errors.Wrap(err, "reading attestation")
+		}
+		var env envelope
+		if err := json.Unmarshal(raw, &env); err == nil && env.Payload != "" {
+			payload, err := base64.StdEncoding.DecodeString(env.Payload)
+			if err != nil {
+				return errors.Wrap(err, "decoding attestation envelope")
+			}
+			raw = payload
+		}
+		var s statement
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return errors.Wrap(err, "parsing attestation statement")
+		}
+		if !strings.HasPrefix(s.Type, "https://in-toto.io/Statement/") {
+			continue
+		}
+		found = true
+		a.addStatement(s)
+	}
+	if !found {
+		return errors.New("no in-toto statements found in attestation")
+	}
+	return nil
+}
+
+func (a *Attestation) addStatement(s statement) {
+	for _, id := range []string{s.Predicate.Builder.ID, s.Predicate.RunDetails.Builder.ID} {
+		if id != "" {
+			a.Builders = append(a.Builders, id)
+		}
+	}
+	for _, subject := range s.Subject {
+		for _, digest := range digestsOf(subject) {
+			a.Subjects[digest] = subject.Name
+		}
+	}
+	materials := append(s.Predicate.Materials, s.Predicate.BuildDefinition.ResolvedDependencies...)
+	for _, material := range materials {
+		name := material.URI
+		if name == "" {
+			name = material.Name
+		}
+		for _, digest := range digestsOf(material) {
+			a.Materials[digest] = name
+		}
+	}
+	for _, args := range []map[string]interface{}{s.Predicate.Invocation.Parameters.Args, s.Predicate.BuildDefinition.ExternalParameters.Request.Args} {
+		for key, value := range args {
+			if v, ok := value.(string); ok && strings.HasPrefix(key, buildArgPrefix) {
+				a.BuildArgs[strings.TrimPrefix(key, buildArgPrefix)] = v
+			}
+		}
+	}
+	for _, id := range []string{s.Predicate.Metadata.BuildInvocationID, s.Predicate.RunDetails.Metadata.InvocationID} {
+		if id != "" {
+			a.Invocations = append(a.Invocations, id)
+		}
+	}
+}
+
+// digestsOf returns the digests of a descriptor in "algorithm:hex" form.
+func digestsOf(r resourceDescriptor) []string {
+	digests := []string{}
+	for algorithm, hex := range r.Digest {
+		digests = append(digests, algorithm+":"+strings.ToLower(hex))
+	}
+	return digests
+}
+
+// claim finds the subject or material with the given digest.
+func (a *Attestation) claim(digest string) (string, bool) {
+	if name, ok := a.Subjects[digest]; ok {
+		return "subject " + name, true
+	}
+	if name, ok := a.Materials[digest]; ok {
+		return "material " + name, true
+	}
+	return "", false
+}
+
+// GetProvenanceAnalysis checks the image digest and each layer, given by its
+// compressed digest and uncompressed diff ID, against the attestation.
+func GetProvenanceAnalysis(a *Attestation, digest string, layerDigests, diffIDs []string) ProvenanceAnalysis {
+	_, imageVerified := a.Subjects[digest]
+	analysis := ProvenanceAnalysis{
+		Digest:        digest,
+		Builders:      dedupeStrings(a.Builders),
+		ImageVerified: imageVerified,
+		Layers:        []LayerProvenance{},
+	}
+	for i, layerDigest := range layerDigests {
+		layer := LayerProvenance{Index: i, Digest: layerDigest}
+		if i < len(diffIDs) {
+			layer.DiffID = diffIDs[i]
+		}
+		for _, d := range []string{layer.Digest, layer.DiffID} {
+			if claim, ok := a.claim(d); ok && d != "" {
+				layer.Verified, layer.Claim = true, claim
+				break
+			}
+		}
+		analysis.Layers = append(analysis.Layers, layer)
+	}
+	return analysis
+}
+
+// GetProvenanceDiff annotates the layers of the second image with their
+// provenance, marking those the first image doesn't have.
+func GetProvenanceDiff(analysis1, analysis2 ProvenanceAnalysis) ProvenanceDiff {
+	shared := map[string]bool{}
+	for _, layer := range analysis1.Layers {
+		shared[layer.Digest] = true
+	}
+	diff := ProvenanceDiff{
+		ImageVerified1: analysis1.ImageVerified,
+		ImageVerified2: analysis2.ImageVerified,
+		Layers:         []LayerProvenanceChange{},
+	}
+	for _, layer := range analysis2.Layers {
+		diff.Layers = append(diff.Layers, LayerProvenanceChange{LayerProvenance: layer, New: !shared[layer.Digest]})
+	}
+	return diff
+}
+
+func dedupeStrings(list []string) []string {
+	seen := map[string]bool{}
+	deduped := []string{}
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			deduped = append(deduped, s)
+		}
+	}
+	sort.Strings(deduped)
+	return deduped
+}
+
+// StrLayerProvenance is a layer row in text output.
+type StrLayerProvenance struct {
+	Index  int
+	Digest string
+	Status string
+	Claim  string
+}
+
+func provenanceStatus(verified bool) string {
+	if verified {
+		return "verified"
+	}
+	return "unverified"
+}
+
+func stringifyLayerProvenance(layers []LayerProvenance) []StrLayerProvenance {
+	rows := []StrLayerProvenance{}
+	for _, l := range layers {
+		rows = append(rows, StrLayerProvenance{Index: l.Index, Digest: l.Digest, Status: provenanceStatus(l.Verified), Claim: l.Claim})
+	}
+	return rows
+}
+
+func stringifyLayerProvenanceChanges(layers []LayerProvenanceChange) []StrLayerProvenance {
+	rows := []StrLayerProvenance{}
+	for _, l := range layers {
+		row := StrLayerProvenance{Index: l.Index, Digest: l.Digest, Status: provenanceStatus(l.Verified), Claim: l.Claim}
+		if l.New {
+			row.Status = "new, " + row.Status
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
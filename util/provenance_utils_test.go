@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+const slsaV02Statement = `{
+  "_type": "https://in-toto.io/Statement/v0.1",
+  "subject": [{"name": "gcr.io/org/app", "digest": {"sha256": "aaaa"}}],
+  "predicateType": "https://slsa.dev/provenance/v0.2",
+  "predicate": {
+    "builder": {"id": "https://cloudbuild.googleapis.com/GoogleHostedWorker"},
+    "materials": [
+      {"uri": "pkg:docker/gcr.io/org/base@sha256:bbbb", "digest": {"sha256": "bbbb"}},
+      {"uri": "git+https://github.com/org/app", "digest": {"sha1": "cccc"}}
+    ],
+    "invocation": {"parameters": {"frontend": "dockerfile.v0", "args": {"build-arg:VERSION": "1.2.3", "label:team": "infra"}}},
+    "metadata": {"buildInvocationID": "https://ci.example.com/builds/42"}
+  }
+}`
+
+const slsaV1Statement = `{
+  "_type": "https://in-toto.io/Statement/v1",
+  "subject": [{"name": "layer", "digest": {"sha256": "DDDD"}}],
+  "predicateType": "https://slsa.dev/provenance/v1",
+  "predicate": {
+    "buildDefinition": {
+      "externalParameters": {"request": {"frontend": "dockerfile.v0", "args": {"build-arg:GO_VERSION": "1.21"}}},
+      "resolvedDependencies": [{"name": "base layer", "digest": {"sha256": "eeee"}}]
+    },
+    "runDetails": {"builder": {"id": "https://github.com/actions/runner"}, "metadata": {"invocationId": "https://github.com/org/app/actions/runs/1/attempts/1"}}
+  }
+}`
+
+func TestAttestationAdd(t *testing.T) {
+	envelope := `{"payloadType": "application/vnd.in-toto+json", "payload": "` +
+		base64.StdEncoding.EncodeToString([]byte(slsaV1Statement)) + `", "signatures": []}`
+	attestation := NewAttestation()
+	if err := attestation.Add([]byte(slsaV02Statement + "\n" + envelope)); err != nil {
+		t.Fatalf("Error reading attestations: %s", err)
+	}
+	expected := &Attestation{
+		Builders: []string{"https://cloudbuild.googleapis.com/GoogleHostedWorker", "https://github.com/actions/runner"},
+		Subjects: map[string]string{"sha256:aaaa": "gcr.io/org/app", "sha256:dddd": "layer"},
+		Materials: map[string]string{
+			"sha256:bbbb": "pkg:docker/gcr.io/org/base@sha256:bbbb",
+			"sha1:cccc":   "git+https://github.com/org/app",
+			"sha256:eeee": "base layer",
+		},
+		BuildArgs:   map[string]string{"VERSION": "1.2.3", "GO_VERSION": "1.21"},
+		Invocations: []string{"https://ci.example.com/builds/42", "https://github.com/org/app/actions/runs/1/attempts/1"},
+	}
+	if !reflect.DeepEqual(attestation, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, attestation)
+	}
+
+	if err := NewAttestation().Add([]byte(`{"spdxVersion": "SPDX-2.3"}`)); err == nil {
+		t.Errorf("Expected an error for a document without statements")
+	}
+}
+
+func TestGetProvenanceDiff(t *testing.T) {
+	attestation := NewAttestation()
+	if err := attestation.Add([]byte(slsaV02Statement + slsaV1Statement)); err != nil {
+		t.Fatalf("Error reading attestations: %s", err)
+	}
+	analysis1 := GetProvenanceAnalysis(attestation, "sha256:ffff", []string{"sha256:1111"}, []string{"sha256:bbbb"})
+	analysis2 := GetProvenanceAnalysis(attestation, "sha256:aaaa",
+		[]string{"sha256:1111", "sha256:dddd", "sha256:2222"}, []string{"sha256:bbbb", "sha256:3333", "sha256:4444"})
+	if analysis1.ImageVerified || !analysis2.ImageVerified {
+		t.Errorf("Expected only the second image to be verified, got %v and %v", analysis1.ImageVerified, analysis2.ImageVerified)
+	}
+
+	diff := GetProvenanceDiff(analysis1, analysis2)
+	expected := []LayerProvenanceChange{
+		{LayerProvenance: LayerProvenance{Index: 0, Digest: "sha256:1111", DiffID: "sha256:bbbb", Verified: true, Claim: "material pkg:docker/gcr.io/org/base@sha256:bbbb"}},
+		{LayerProvenance: LayerProvenance{Index: 1, Digest: "sha256:dddd", DiffID: "sha256:3333", Verified: true, Claim: "subject layer"}, New: true},
+		{LayerProvenance: LayerProvenance{Index: 2, Digest: "sha256:2222", DiffID: "sha256:4444"}, New: true},
+	}
+	if !reflect.DeepEqual(diff.Layers, expected) {
+		t.Errorf("Expected: %+v but got: %+v", expected, diff.Layers)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/docker/docker/api/types"
+)
+
+func TestDaemonPullAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pull")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	socket, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	if err != nil {
+		t.Fatalf("Error listening on socket: %s", err)
+	}
+	// the daemon records the credentials of each pull and fails it, which
+	// ends the lookup before the image is read
+	var auths []string
+	daemon := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/images/create") {
+			auths = append(auths, r.Header.Get("X-Registry-Auth"))
+			w.Write([]byte(`{"error": "pull refused"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})}
+	go daemon.Serve(socket)
+	defer daemon.Close()
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+	os.Setenv("DOCKER_HOST", "unix://"+filepath.Join(dir, "docker.sock"))
+	if err := pkgutil.ConfigurePullPolicy(pkgutil.PullAlways); err != nil {
+		t.Fatalf("Error configuring the pull policy: %s", err)
+	}
+	defer pkgutil.ConfigurePullPolicy("")
+	defer pkgutil.ConfigureRegistryAuth("", "", "", nil)
+
+	testCases := []struct {
+		descrip         string
+		username        string
+		password        string
+		token           string
+		expected        types.AuthConfig
+		expectAnonymous bool
+	}{
+		{
+			descrip:  "basic credentials",
+			username: "user",
+			password: "secret",
+			expected: types.AuthConfig{Username: "user", Password: "secret", ServerAddress: "registry.example.com"},
+		},
+		{
+			descrip:  "identity token",
+			username: "<token>",
+			password: "refresh-token",
+			expected: types.AuthConfig{IdentityToken: "refresh-token", ServerAddress: "registry.example.com"},
+		},
+		{
+			descrip:  "registry token",
+			token:    "access-token",
+			expected: types.AuthConfig{RegistryToken: "access-token", ServerAddress: "registry.example.com"},
+		},
+		{
+			descrip:         "anonymous",
+			expectAnonymous: true,
+		},
+	}
+	for i, test := range testCases {
+		if err := pkgutil.ConfigureRegistryAuth(test.username, test.password, test.token, nil); err != nil {
+			t.Fatalf("%s: Error configuring credentials: %s", test.descrip, err)
+		}
+		// each image is pulled once per process
+		image := "daemon://registry.example.com/app:v" + string(rune('1'+i))
+		if _, err := pkgutil.GetImage(image, false, ""); err == nil || !strings.Contains(err.Error(), "pull refused") {
+			t.Fatalf("%s: Expected the pull to be refused, got: %v", test.descrip, err)
+		}
+		if len(auths) != i+1 {
+			t.Fatalf("%s: Expected one pull per image but got %d", test.descrip, len(auths))
+		}
+		if test.expectAnonymous {
+			if auths[i] != "" {
+				t.Errorf("%s: Expected no credentials, got %s", test.descrip, auths[i])
+			}
+			continue
+		}
+		decoded, err := base64.URLEncoding.DecodeString(auths[i])
+		if err != nil {
+			t.Fatalf("%s: Error decoding %s: %s", test.descrip, auths[i], err)
+		}
+		var config types.AuthConfig
+		if err := json.Unmarshal(decoded, &config); err != nil {
+			t.Fatalf("%s: Error parsing %s: %s", test.descrip, decoded, err)
+		}
+		if config != test.expected {
+			t.Errorf("%s: Expected %+v but got %+v", test.descrip, test.expected, config)
+		}
+	}
+}
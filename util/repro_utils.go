@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Reproducibility issues, listed in the order they should be fixed: issues
+// affecting the image config change the digest on every build, so they come
+// before issues that only affect some files.
+const (
+	ReproCreated   = "Image creation time"
+	ReproHistory   = "History timestamps"
+	ReproBuildHost = "Build hostname or container ID"
+	ReproLabels    = "Build metadata labels"
+	ReproPyc       = "Python bytecode timestamps"
+	ReproGzip      = "Gzip header timestamps"
+	ReproArchive   = "Archive entry timestamps"
+	ReproRandom    = "Machine IDs, random seeds and host keys"
+	ReproLogs      = "Logs and caches"
+	ReproOther     = "Other changed files"
+)
+
+var reproIssues = []struct {
+	issue string
+	fix   string
+}{
+	{ReproCreated, "Set the creation time from SOURCE_DATE_EPOCH or a fixed value"},
+	{ReproHistory, "Set history timestamps from SOURCE_DATE_EPOCH or a fixed value"},
+	{ReproBuildHost, "Clear Hostname and the build container ID from the config"},
+	{ReproLabels, "Derive build date and revision labels from the source, not the build time"},
+	{ReproPyc, "Compile with SOURCE_DATE_EPOCH set or --invalidation-mode=checked-hash"},
+	{ReproGzip, "Compress with gzip -n, or zero the header MTIME"},
+	{ReproArchive, "Normalize entry timestamps, e.g. with strip-nondeterminism"},
+	{ReproRandom, "Don't generate machine IDs, seeds or keys at build time"},
+	{ReproLogs, "Remove logs and caches in the same layer that creates them"},
+	{ReproOther, "Compare the files with --type=file --filename"},
+}
+
+var reproRandomFiles = map[string]bool{
+	"/etc/machine-id":                   true,
+	"/var/lib/dbus/machine-id":          true,
+	"/var/lib/systemd/random-seed":      true,
+	"/var/lib/urandom/random-seed":      true,
+	"/root/.rnd":                        true,
+	"/etc/ssh/ssh_host_rsa_key":         true,
+	"/etc/ssh/ssh_host_rsa_key.pub":     true,
+	"/etc/ssh/ssh_host_ecdsa_key":       true,
+	"/etc/ssh/ssh_host_ecdsa_key.pub":   true,
+	"/etc/ssh/ssh_host_ed25519_key":     true,
+	"/etc/ssh/ssh_host_ed25519_key.pub": true,
+	"/etc/ssh/ssh_host_dsa_key":         true,
+	"/etc/ssh/ssh_host_dsa_key.pub":     true,
+}
+
+// reproCacheFiles are package manager state files rewritten on every run
+var reproCacheFiles = map[string]bool{
+	"/etc/ld.so.cache":                     true,
+	"/var/lib/apt/extended_states":         true,
+	"/var/lib/dpkg/triggers/Lock":          true,
+	"/var/lib/rpm/.rpm.lock":               true,
+	"/var/lib/rpm/.dbenv.lock":             true,
+	"/var/lib/rpm/__db.001":                true,
+	"/var/lib/systemd/catalog/database":    true,
+	"/var/cache/debconf/config.dat-old":    true,
+	"/var/cache/debconf/templates.dat-old": true,
+}
+
+var reproLogDirs = []string{"/var/log", "/var/cache", "/tmp", "/var/tmp", "/root/.cache", "/root/.npm", "/var/lib/apt/lists"}
+
+// ClassifyReproFile returns the reproducibility issue a file is a known
+// source of, based on its path alone.
+func ClassifyReproFile(name string) (string, bool) {
+	if reproRandomFiles[name] {
+		return ReproRandom, true
+	}
+	if reproCacheFiles[name] {
+		return ReproLogs, true
+	}
+	switch path.Ext(name) {
+	case ".pyc", ".pyo":
+		return ReproPyc, true
+	case ".gz", ".tgz":
+		return ReproGzip, true
+	case ".jar", ".war", ".ear", ".zip", ".whl", ".egg":
+		return ReproArchive, true
+	case ".log":
+		return ReproLogs, true
+	}
+	for _, dir := range reproLogDirs {
+		if strings.HasPrefix(name, dir+"/") {
+			return ReproLogs, true
+		}
+	}
+	return "", false
+}
+
+// ReproFinding groups the files or config fields affected by one issue.
+// Priority orders findings, lower values should be fixed first.
+type ReproFinding struct {
+	Priority int
+	Issue    string
+	Fix      string
+	Items    []string
+	Size     int64
+}
+
+// ReproDiff lists what keeps two builds of an image from being identical.
+type ReproDiff struct {
+	Identical bool
+	Findings  []ReproFinding
+}
+
+// ReproFindings collects affected items by issue.
+type ReproFindings map[string]*ReproFinding
+
+// Add records item, of the given size, as affected by issue.
+func (f ReproFindings) Add(issue, item string, size int64) {
+	finding, ok := f[issue]
+	if !ok {
+		finding = &ReproFinding{Issue: issue}
+		for i, known := range reproIssues {
+			if known.issue == issue {
+				finding.Priority = i + 1
+				finding.Fix = known.fix
+			}
+		}
+		f[issue] = finding
+	}
+	finding.Items = append(finding.Items, item)
+	if size > 0 {
+		finding.Size += size
+	}
+}
+
+// Sorted returns the findings in priority order, with their items sorted.
+func (f ReproFindings) Sorted() []ReproFinding {
+	findings := []ReproFinding{}
+	for _, finding := range f {
+		sort.Strings(finding.Items)
+		findings = append(findings, *finding)
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Priority < findings[j].Priority })
+	return findings
+}
@@ -0,0 +1,422 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Rollup holds fleet-level statistics over the JSON results of many
+// analyze and diff runs.
+type Rollup struct {
+	Files    int
+	Images   int
+	Packages []RollupPackage
+	Sizes    RollupSizes
+}
+
+// RollupPackage is a package found in the images of a fleet, with the
+// images having each of its versions.
+type RollupPackage struct {
+	Type     string
+	Name     string
+	Images   int
+	Versions []RollupVersion
+}
+
+// RollupVersion is a version of a package and the images having it.
+type RollupVersion struct {
+	Version string
+	Images  []string
+}
+
+// RollupImage is an image with a version of a package.
+type RollupImage struct {
+	Type    string
+	Package string
+	Version string
+	Image   string
+}
+
+// RollupSizes aggregates the sizes of the analyzed images, and the size
+// changes of the diffed ones.
+type RollupSizes struct {
+	Images        int
+	Total         int64
+	Average       int64
+	Smallest      *RollupImageSize `json:",omitempty"`
+	Largest       *RollupImageSize `json:",omitempty"`
+	Diffs         int
+	Grown         int
+	Shrunk        int
+	TotalChange   int64
+	AverageChange int64
+	LargestGrowth *RollupSizeChange `json:",omitempty"`
+}
+
+// RollupImageSize is the size of an analyzed image.
+type RollupImageSize struct {
+	Image string
+	Size  int64
+}
+
+// RollupSizeChange is the size change between two diffed images.
+type RollupSizeChange struct {
+	Image1 string
+	Image2 string
+	Size1  int64
+	Size2  int64
+	Change int64
+}
+
+// rollupResult reads an analyze or a diff result. Keys are matched without
+// regard to case, so camel-case JSON output reads as well.
+type rollupResult struct {
+	Image       string
+	AnalyzeType string
+	Analysis    json.RawMessage
+	Image1      string
+	Image2      string
+	DiffType    string
+	Diff        json.RawMessage
+}
+
+type rollupPackageKey struct {
+	Type string
+	Name string
+}
+
+// RollupBuilder aggregates result documents into a Rollup.
+type RollupBuilder struct {
+	packageTypes map[string]bool
+	files        int
+	images       map[string]bool
+	packages     map[rollupPackageKey]map[string]map[string]bool
+	sizes        map[string]int64
+	changes      map[[2]string]RollupSizeChange
+}
+
+// NewRollupBuilder returns a builder counting the packages of the analyze
+// results of packageTypes, e.g. Apt or Pip.
+func NewRollupBuilder(packageTypes []string) *RollupBuilder {
+	b := &RollupBuilder{
+		packageTypes: map[string]bool{},
+		images:       map[string]bool{},
+		packages:     map[rollupPackageKey]map[string]map[string]bool{},
+		sizes:        map[string]int64{},
+		changes:      map[[2]string]RollupSizeChange{},
+	}
+	for _, t := range packageTypes {
+		b.packageTypes[t] = true
+	}
+	return b
+}
+
+// ParseResultDocument returns the results of the JSON output of an analyze
+// or diff run: a list of results, the results wrapped with annotations and
+// timings, or the per-platform results of --all-platforms.
+func ParseResultDocument(data []byte) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty document")
+	}
+	results := []json.RawMessage{}
+	if data[0] == '[' {
+		err := json.Unmarshal(data, &results)
+		return results, err
+	}
+	var document struct {
+		Results   []json.RawMessage
+		Platforms []struct {
+			Results []json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Results == nil && document.Platforms == nil {
+		return nil, errors.New("no results in document")
+	}
+	results = append(results, document.Results...)
+	for _, platform := range document.Platforms {
+		results = append(results, platform.Results...)
+	}
+	return results, nil
+}
+
+// AddDocument adds the results of a JSON output document.
+func (b *RollupBuilder) AddDocument(data []byte) error {
+	results, err := ParseResultDocument(data)
+	if err != nil {
+		return err
+	}
+	b.files++
+	for _, raw := range results {
+		var result rollupResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return err
+		}
+		b.addResult(result)
+	}
+	return nil
+}
+
+func (b *RollupBuilder) addResult(result rollupResult) {
+	for _, image := range []string{result.Image, result.Image1, result.Image2} {
+		if image != "" {
+			b.images[image] = true
+		}
+	}
+	switch {
+	case result.AnalyzeType == "Size":
+		var entries []struct{ Size int64 }
+		if json.Unmarshal(result.Analysis, &entries) == nil && len(entries) > 0 && entries[0].Size >= 0 {
+			b.sizes[result.Image] = entries[0].Size
+		}
+	case result.DiffType == "Size":
+		var diffs []struct{ Size1, Size2 int64 }
+		if json.Unmarshal(result.Diff, &diffs) == nil && len(diffs) > 0 && diffs[0].Size1 >= 0 && diffs[0].Size2 >= 0 {
+			b.changes[[2]string{result.Image1, result.Image2}] = RollupSizeChange{
+				Image1: result.Image1,
+				Image2: result.Image2,
+				Size1:  diffs[0].Size1,
+				Size2:  diffs[0].Size2,
+				Change: diffs[0].Size2 - diffs[0].Size1,
+			}
+		}
+	case b.packageTypes[result.AnalyzeType]:
+		var packages []PackageOutput
+		if json.Unmarshal(result.Analysis, &packages) != nil {
+			return
+		}
+		for _, p := range packages {
+			key := rollupPackageKey{Type: result.AnalyzeType, Name: p.Name}
+			if b.packages[key] == nil {
+				b.packages[key] = map[string]map[string]bool{}
+			}
+			if b.packages[key][p.Version] == nil {
+				b.packages[key][p.Version] = map[string]bool{}
+			}
+			b.packages[key][p.Version][result.Image] = true
+		}
+	}
+}
+
+// Rollup returns the statistics of the documents added. Packages are
+// ordered by the number of images having them, most common first, and
+// their versions likewise.
+func (b *RollupBuilder) Rollup() Rollup {
+	rollup := Rollup{Files: b.files, Images: len(b.images), Packages: []RollupPackage{}}
+	for key, versions := range b.packages {
+		p := RollupPackage{Type: key.Type, Name: key.Name, Versions: []RollupVersion{}}
+		images := map[string]bool{}
+		for version, versionImages := range versions {
+			v := RollupVersion{Version: version, Images: []string{}}
+			for image := range versionImages {
+				v.Images = append(v.Images, image)
+				images[image] = true
+			}
+			sort.Strings(v.Images)
+			p.Versions = append(p.Versions, v)
+		}
+		sort.Slice(p.Versions, func(i, j int) bool {
+			if len(p.Versions[i].Images) != len(p.Versions[j].Images) {
+				return len(p.Versions[i].Images) > len(p.Versions[j].Images)
+			}
+			return p.Versions[i].Version < p.Versions[j].Version
+		})
+		p.Images = len(images)
+		rollup.Packages = append(rollup.Packages, p)
+	}
+	sort.Slice(rollup.Packages, func(i, j int) bool {
+		a, b := rollup.Packages[i], rollup.Packages[j]
+		if a.Images != b.Images {
+			return a.Images > b.Images
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	rollup.Sizes = b.rollupSizes()
+	return rollup
+}
+
+func (b *RollupBuilder) rollupSizes() RollupSizes {
+	sizes := RollupSizes{Images: len(b.sizes), Diffs: len(b.changes)}
+	for image, size := range b.sizes {
+		sizes.Total += size
+		entry := &RollupImageSize{Image: image, Size: size}
+		if sizes.Smallest == nil || size < sizes.Smallest.Size || (size == sizes.Smallest.Size && image < sizes.Smallest.Image) {
+			sizes.Smallest = entry
+		}
+		if sizes.Largest == nil || size > sizes.Largest.Size || (size == sizes.Largest.Size && image < sizes.Largest.Image) {
+			sizes.Largest = entry
+		}
+	}
+	if sizes.Images > 0 {
+		sizes.Average = sizes.Total / int64(sizes.Images)
+	}
+	for _, change := range b.changes {
+		change := change
+		switch {
+		case change.Change > 0:
+			sizes.Grown++
+		case change.Change < 0:
+			sizes.Shrunk++
+		}
+		sizes.TotalChange += change.Change
+		if change.Change > 0 && (sizes.LargestGrowth == nil || change.Change > sizes.LargestGrowth.Change ||
+			(change.Change == sizes.LargestGrowth.Change && change.Image2 < sizes.LargestGrowth.Image2)) {
+			sizes.LargestGrowth = &change
+		}
+	}
+	if sizes.Diffs > 0 {
+		sizes.AverageChange = sizes.TotalChange / int64(sizes.Diffs)
+	}
+	return sizes
+}
+
+// ImagesWithPackage lists the images having the package name, of any type,
+// at version, or at any version if version is empty.
+func (r Rollup) ImagesWithPackage(name, version string) []RollupImage {
+	images := []RollupImage{}
+	for _, p := range r.Packages {
+		if p.Name != name {
+			continue
+		}
+		for _, v := range p.Versions {
+			if version != "" && v.Version != version {
+				continue
+			}
+			for _, image := range v.Images {
+				images = append(images, RollupImage{Type: p.Type, Package: p.Name, Version: v.Version, Image: image})
+			}
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if a.Image != b.Image {
+			return a.Image < b.Image
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Version < b.Version
+	})
+	return images
+}
+
+// WritePackagesCSV writes a type,package,version,images row for every
+// version of every package.
+func (r Rollup) WritePackagesCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	w.Write([]string{"type", "package", "version", "images"})
+	for _, p := range r.Packages {
+		for _, v := range p.Versions {
+			w.Write([]string{p.Type, p.Name, v.Version, strconv.Itoa(len(v.Images))})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteImagesCSV writes a type,package,version,image row for every image.
+func WriteImagesCSV(writer io.Writer, images []RollupImage) error {
+	w := csv.NewWriter(writer)
+	w.Write([]string{"type", "package", "version", "image"})
+	for _, image := range images {
+		w.Write([]string{image.Type, image.Package, image.Version, image.Image})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+type StrRollupPackage struct {
+	Type     string
+	Name     string
+	Images   int
+	Versions int
+	Top      string
+}
+
+type StrRollupSizes struct {
+	Images        int
+	Total         string
+	Average       string
+	Smallest      string
+	Largest       string
+	Diffs         int
+	Grown         int
+	Shrunk        int
+	TotalChange   string
+	AverageChange string
+	LargestGrowth string
+}
+
+// OutputText writes the rollup as text, with the top most common packages,
+// or all of them if top is 0.
+func (r Rollup) OutputText(writer io.Writer, top int, opts OutputOptions) error {
+	packages := r.Packages
+	if top > 0 && len(packages) > top {
+		packages = packages[:top]
+	}
+	strPackages := []StrRollupPackage{}
+	for _, p := range packages {
+		strPackage := StrRollupPackage{Type: p.Type, Name: p.Name, Images: p.Images, Versions: len(p.Versions)}
+		if len(p.Versions) > 0 {
+			strPackage.Top = p.Versions[0].Version
+		}
+		strPackages = append(strPackages, strPackage)
+	}
+	sizes := StrRollupSizes{
+		Images:        r.Sizes.Images,
+		Total:         stringifySize(r.Sizes.Total),
+		Average:       stringifySize(r.Sizes.Average),
+		Diffs:         r.Sizes.Diffs,
+		Grown:         r.Sizes.Grown,
+		Shrunk:        r.Sizes.Shrunk,
+		TotalChange:   stringifySizeChange(0, r.Sizes.TotalChange),
+		AverageChange: stringifySizeChange(0, r.Sizes.AverageChange),
+	}
+	if r.Sizes.Smallest != nil {
+		sizes.Smallest = r.Sizes.Smallest.Image + " (" + stringifySize(r.Sizes.Smallest.Size) + ")"
+		sizes.Largest = r.Sizes.Largest.Image + " (" + stringifySize(r.Sizes.Largest.Size) + ")"
+	}
+	if g := r.Sizes.LargestGrowth; g != nil {
+		sizes.LargestGrowth = g.Image1 + " -> " + g.Image2 + " (" + stringifySizeChange(g.Size1, g.Size2) + ")"
+	}
+	strResult := struct {
+		Files    int
+		Images   int
+		Total    int
+		Packages []StrRollupPackage
+		Sizes    StrRollupSizes
+	}{r.Files, r.Images, len(r.Packages), strPackages, sizes}
+	return TemplateOutput(writer, strResult, "Rollup", opts)
+}
+
+// OutputImagesText writes the images having a package as text.
+func OutputImagesText(writer io.Writer, images []RollupImage, opts OutputOptions) error {
+	return TemplateOutput(writer, images, "RollupImages", opts)
+}
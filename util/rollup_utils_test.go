@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRollup(t *testing.T) {
+	documents := []string{
+		`[{"Image": "app:1", "AnalyzeType": "Apt", "Analysis": [{"Name": "openssl", "Version": "3.0.2", "Size": 10}, {"Name": "curl", "Version": "7.81", "Size": 5}]},
+		  {"Image": "app:1", "AnalyzeType": "Size", "Analysis": [{"Name": "app:1", "Digest": "sha256:abc", "Size": 1000}]}]`,
+		// camel-case keys, wrapped with annotations
+		`{"annotations": {"build": "2"}, "results": [
+		  {"image": "app:2", "analyzeType": "Apt", "analysis": [{"name": "openssl", "version": "3.0.13", "size": 10}]},
+		  {"image": "app:2", "analyzeType": "Size", "analysis": [{"name": "app:2", "size": 3000}]}]}`,
+		`{"Summary": {}, "Platforms": [{"Platform": "linux/amd64", "Results": [
+		  {"Image": "worker:1", "AnalyzeType": "Apt", "Analysis": [{"Name": "openssl", "Version": "3.0.13", "Size": 10}]},
+		  {"Image": "worker:1", "AnalyzeType": "History", "Analysis": ["RUN apt-get install openssl"]}]}]}`,
+		`[{"Image1": "app:1", "Image2": "app:2", "DiffType": "Size", "Diff": [{"Name": "Image size", "Size1": 1000, "Size2": 3000}]},
+		  {"Image1": "app:2", "Image2": "app:3", "DiffType": "Size", "Diff": [{"Name": "Image size", "Size1": 3000, "Size2": 2500}]}]`,
+	}
+	builder := NewRollupBuilder([]string{"Apt", "Pip"})
+	for _, document := range documents {
+		if err := builder.AddDocument([]byte(document)); err != nil {
+			t.Fatalf("Got unexpected error adding %s: %s", document, err)
+		}
+	}
+	if err := builder.AddDocument([]byte(`{"name": "package.json"}`)); err == nil {
+		t.Errorf("Expected an error for a document holding no results")
+	}
+	rollup := builder.Rollup()
+
+	if rollup.Files != 4 || rollup.Images != 4 {
+		t.Errorf("Expected 4 images in 4 files, got %d in %d", rollup.Images, rollup.Files)
+	}
+	expectedPackages := []RollupPackage{
+		{Type: "Apt", Name: "openssl", Images: 3, Versions: []RollupVersion{
+			{Version: "3.0.13", Images: []string{"app:2", "worker:1"}},
+			{Version: "3.0.2", Images: []string{"app:1"}},
+		}},
+		{Type: "Apt", Name: "curl", Images: 1, Versions: []RollupVersion{{Version: "7.81", Images: []string{"app:1"}}}},
+	}
+	if !reflect.DeepEqual(rollup.Packages, expectedPackages) {
+		t.Errorf("Expected packages %+v but got %+v", expectedPackages, rollup.Packages)
+	}
+
+	expectedSizes := RollupSizes{
+		Images: 2, Total: 4000, Average: 2000,
+		Smallest: &RollupImageSize{Image: "app:1", Size: 1000},
+		Largest:  &RollupImageSize{Image: "app:2", Size: 3000},
+		Diffs:    2, Grown: 1, Shrunk: 1, TotalChange: 1500, AverageChange: 750,
+		LargestGrowth: &RollupSizeChange{Image1: "app:1", Image2: "app:2", Size1: 1000, Size2: 3000, Change: 2000},
+	}
+	if !reflect.DeepEqual(rollup.Sizes, expectedSizes) {
+		t.Errorf("Expected sizes %+v but got %+v", expectedSizes, rollup.Sizes)
+	}
+
+	expectedImages := []RollupImage{
+		{Type: "Apt", Package: "openssl", Version: "3.0.13", Image: "app:2"},
+		{Type: "Apt", Package: "openssl", Version: "3.0.13", Image: "worker:1"},
+	}
+	if images := rollup.ImagesWithPackage("openssl", "3.0.13"); !reflect.DeepEqual(images, expectedImages) {
+		t.Errorf("Expected images %v but got %v", expectedImages, images)
+	}
+	if images := rollup.ImagesWithPackage("openssl", ""); len(images) != 3 {
+		t.Errorf("Expected 3 images with any openssl, got %v", images)
+	}
+
+	var csv bytes.Buffer
+	if err := rollup.WritePackagesCSV(&csv); err != nil {
+		t.Fatalf("Got unexpected error writing CSV: %s", err)
+	}
+	expectedCSV := "type,package,version,images\nApt,openssl,3.0.13,2\nApt,openssl,3.0.2,1\nApt,curl,7.81,1\n"
+	if csv.String() != expectedCSV {
+		t.Errorf("Expected CSV:\n%s\nbut got:\n%s", expectedCSV, csv.String())
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RPMRepo stores a yum/dnf repository definition. URL is the baseurl,
+// mirrorlist or metalink, whichever is set first. ThirdParty is set for EPEL
+// and for repositories served from outside the distribution vendors' hosts.
+type RPMRepo struct {
+	ID         string
+	Name       string
+	File       string
+	URL        string
+	Enabled    bool
+	GPGCheck   bool
+	ThirdParty bool
+}
+
+// DnfModule stores the state of a dnf module, as recorded in
+// /etc/dnf/modules.d.
+type DnfModule struct {
+	Name     string
+	Stream   string
+	State    string
+	Profiles []string
+}
+
+// RPMRepoAnalysis lists the repositories and module streams of an image.
+type RPMRepoAnalysis struct {
+	Repos   []RPMRepo
+	Modules []DnfModule
+}
+
+// RPMRepoChange stores a repository defined in both images with different
+// settings.
+type RPMRepoChange struct {
+	ID    string
+	Repo1 RPMRepo
+	Repo2 RPMRepo
+}
+
+// DnfModuleChange stores the stream and state of a module in two images.
+// Empty values mean the module has no state in that image.
+type DnfModuleChange struct {
+	Name    string
+	Stream1 string
+	Stream2 string
+	State1  string
+	State2  string
+}
+
+// RPMRepoDiff stores the repository and module differences between two
+// images. Warnings flag the changes worth a closer look: repositories of
+// the second image which gained gpgcheck=0, and third-party repositories
+// it added.
+type RPMRepoDiff struct {
+	Warnings []string
+	Added    []RPMRepo
+	Removed  []RPMRepo
+	Changed  []RPMRepoChange
+	Modules  []DnfModuleChange
+}
+
+// GetRPMRepoDiff compares the repositories and modules of two images.
+func GetRPMRepoDiff(analysis1, analysis2 RPMRepoAnalysis) RPMRepoDiff {
+	diff := RPMRepoDiff{
+		Warnings: []string{},
+		Added:    []RPMRepo{},
+		Removed:  []RPMRepo{},
+		Changed:  []RPMRepoChange{},
+		Modules:  []DnfModuleChange{},
+	}
+	repos1 := map[string]RPMRepo{}
+	for _, r := range analysis1.Repos {
+		repos1[r.ID] = r
+	}
+	repos2 := map[string]RPMRepo{}
+	for _, r := range analysis2.Repos {
+		repos2[r.ID] = r
+		r1, ok := repos1[r.ID]
+		if !ok {
+			diff.Added = append(diff.Added, r)
+			if r.ThirdParty {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("third-party repository %s added (%s)", r.ID, r.URL))
+			}
+			if r.Enabled && !r.GPGCheck {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("repository %s added with gpgcheck disabled", r.ID))
+			}
+			continue
+		}
+		if r1 != r {
+			diff.Changed = append(diff.Changed, RPMRepoChange{ID: r.ID, Repo1: r1, Repo2: r})
+			if r1.GPGCheck && !r.GPGCheck && r.Enabled {
+				diff.Warnings = append(diff.Warnings, fmt.Sprintf("gpgcheck disabled for repository %s", r.ID))
+			}
+		}
+	}
+	for _, r := range analysis1.Repos {
+		if _, ok := repos2[r.ID]; !ok {
+			diff.Removed = append(diff.Removed, r)
+		}
+	}
+
+	modules1 := map[string]DnfModule{}
+	for _, m := range analysis1.Modules {
+		modules1[m.Name] = m
+	}
+	modules2 := map[string]DnfModule{}
+	for _, m := range analysis2.Modules {
+		modules2[m.Name] = m
+	}
+	for _, m := range analysis1.Modules {
+		if _, ok := modules2[m.Name]; !ok {
+			diff.Modules = append(diff.Modules, DnfModuleChange{Name: m.Name, Stream1: m.Stream, State1: m.State})
+		}
+	}
+	for _, m := range analysis2.Modules {
+		m1 := modules1[m.Name]
+		if m1.Stream != m.Stream || m1.State != m.State {
+			diff.Modules = append(diff.Modules, DnfModuleChange{Name: m.Name, Stream1: m1.Stream, Stream2: m.Stream, State1: m1.State, State2: m.State})
+		}
+	}
+	sort.Slice(diff.Modules, func(i, j int) bool { return diff.Modules[i].Name < diff.Modules[j].Name })
+	return diff
+}
+
+// SortRPMRepoAnalysis orders repositories by ID and modules by name.
+func SortRPMRepoAnalysis(analysis RPMRepoAnalysis) RPMRepoAnalysis {
+	sort.Slice(analysis.Repos, func(i, j int) bool { return analysis.Repos[i].ID < analysis.Repos[j].ID })
+	sort.Slice(analysis.Modules, func(i, j int) bool { return analysis.Modules[i].Name < analysis.Modules[j].Name })
+	return analysis
+}
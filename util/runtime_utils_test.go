@@ -0,0 +1,286 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestPreferRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runtimes")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer pkgutil.ConfigureRuntimes(nil)
+	probes := filepath.Join(dir, "probes")
+	probed := func(runtime string) {
+		f, _ := os.OpenFile(probes, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		fmt.Fprintln(f, runtime)
+		f.Close()
+	}
+	images := map[string]v1.Image{}
+	for _, runtime := range []string{"docker", "podman", "containerd"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("Error creating image: %s", err)
+		}
+		images[runtime] = img
+	}
+
+	// docker and podman are stood in for by Docker API servers on their
+	// sockets, holding app:v1 and an image of their own
+	for _, runtime := range []string{"docker", "podman"} {
+		runtime := runtime
+		socket, err := net.Listen("unix", filepath.Join(dir, runtime+".sock"))
+		if err != nil {
+			t.Fatalf("Error listening on socket: %s", err)
+		}
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "/images/get") {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			probed(runtime)
+			image := r.URL.Query().Get("names")
+			if image != "app:v1" && image != runtime+"-only:v1" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"message": "No such image: %s"}`, image)
+				return
+			}
+			tag, _ := name.NewTag(image, name.WeakValidation)
+			tarball.Write(tag, images[runtime], w)
+		})}
+		go server.Serve(socket)
+		defer server.Close()
+	}
+	// ctr is stood in for by a script exporting app:v1
+	tag, _ := name.NewTag("docker.io/library/app:v1", name.WeakValidation)
+	if err := tarball.WriteToFile(filepath.Join(dir, "image.tar"), tag, images["containerd"]); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+	script := `#!/bin/sh
+echo containerd >> "` + probes + `"
+for last; do :; done
+[ "$last" = docker.io/library/app:v1 ] || { echo "ctr: image \"$last\": not found" >&2; exit 1; }
+eval "out=\${$(($# - 1))}"
+cp "` + filepath.Join(dir, "image.tar") + `" "$out"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "ctr"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing ctr: %s", err)
+	}
+	socket, err := net.Listen("unix", filepath.Join(dir, "containerd.sock"))
+	if err != nil {
+		t.Fatalf("Error listening on socket: %s", err)
+	}
+	defer socket.Close()
+	for key, value := range map[string]string{
+		"DOCKER_HOST":        "unix://" + filepath.Join(dir, "docker.sock"),
+		"CONTAINER_HOST":     "unix://" + filepath.Join(dir, "podman.sock"),
+		"CONTAINERD_ADDRESS": filepath.Join(dir, "containerd.sock"),
+		"PATH":               dir + string(os.PathListSeparator) + os.Getenv("PATH"),
+	} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, value)
+	}
+	// images no runtime has fall back to the registry
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer registry.Close()
+	missing := strings.TrimPrefix(registry.URL, "http://") + "/missing:v1"
+
+	testCases := []struct {
+		preferred []string
+		image     string
+		probes    []string
+		expected  string
+	}{
+		{image: "app:v1", probes: []string{"docker"}, expected: "docker"},
+		{preferred: []string{"podman"}, image: "app:v1", probes: []string{"podman"}, expected: "podman"},
+		{preferred: []string{"Containerd", "podman"}, image: "app:v1", probes: []string{"containerd"}, expected: "containerd"},
+		{preferred: []string{"containerd"}, image: "podman-only:v1", probes: []string{"containerd", "docker", "podman"}, expected: "podman"},
+		{preferred: []string{"podman", "podman"}, image: "docker-only:v1", probes: []string{"podman", "docker"}, expected: "docker"},
+		{image: missing, probes: []string{"docker", "podman", "containerd"}},
+	}
+	for _, test := range testCases {
+		if err := pkgutil.ConfigureRuntimes(test.preferred); err != nil {
+			t.Fatalf("Error preferring runtimes %v: %s", test.preferred, err)
+		}
+		os.Remove(probes)
+		image, err := pkgutil.GetImage(test.image, false, "")
+		recorded, _ := ioutil.ReadFile(probes)
+		if got := strings.Fields(string(recorded)); !reflect.DeepEqual(got, test.probes) {
+			t.Errorf("Expected %s to be looked up in %v with %v preferred, got %v", test.image, test.probes, test.preferred, got)
+		}
+		if test.expected == "" {
+			if err == nil || !strings.Contains(err.Error(), "retrieving remote image") && !strings.Contains(err.Error(), "manifest") {
+				t.Errorf("Expected %s to be looked up in the registry, got %v", test.image, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Error retrieving %s with %v preferred: %s", test.image, test.preferred, err)
+			continue
+		}
+		digest, _ := images[test.expected].Digest()
+		if image.Digest != digest {
+			t.Errorf("Expected %s from %s with %v preferred, got %s", test.image, test.expected, test.preferred, image.Digest)
+		}
+		pkgutil.CleanupImage(image)
+	}
+	if err := pkgutil.ConfigureRuntimes([]string{"cri-o"}); err == nil || !strings.Contains(err.Error(), `unknown container runtime "cri-o"`) {
+		t.Errorf("Expected an unknown runtime to be refused, got %v", err)
+	}
+}
+
+func TestContainerdImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	tag, _ := name.NewTag("docker.io/library/app:v1", name.WeakValidation)
+	if err := tarball.WriteToFile(filepath.Join(dir, "image.tar"), tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+	// ctr is stood in for by a script recording its arguments and exporting
+	// the image written above, printing an error like ctr for other images
+	script := `#!/bin/sh
+echo "$@" > "` + filepath.Join(dir, "args") + `"
+for last; do :; done
+[ "$last" = docker.io/library/app:v1 ] || { echo "ctr: image \"$last\": not found" >&2; exit 1; }
+eval "out=\${$(($# - 1))}"
+cp "` + filepath.Join(dir, "image.tar") + `" "$out"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "ctr"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing ctr: %s", err)
+	}
+	socket, err := net.Listen("unix", filepath.Join(dir, "containerd.sock"))
+	if err != nil {
+		t.Fatalf("Error listening on socket: %s", err)
+	}
+	defer socket.Close()
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	defer os.Setenv("CONTAINERD_ADDRESS", os.Getenv("CONTAINERD_ADDRESS"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("CONTAINERD_ADDRESS", filepath.Join(dir, "containerd.sock"))
+	pkgutil.ConfigureContainerdNamespace("k8s.io")
+	defer pkgutil.ConfigureContainerdNamespace("")
+
+	digest, _ := img.Digest()
+	image, err := pkgutil.GetImage("containerd://app:v1", false, "")
+	if err != nil {
+		t.Fatalf("Error retrieving image: %s", err)
+	}
+	defer pkgutil.CleanupImage(image)
+	if image.Digest != digest || image.Source != "app:v1" {
+		t.Errorf("Expected image %s from app:v1 but got %s from %s", digest, image.Digest, image.Source)
+	}
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	if expected := "--namespace k8s.io images export"; !strings.Contains(string(args), expected) {
+		t.Errorf("Expected ctr to be run with %q, got: %s", expected, args)
+	}
+	if _, err := pkgutil.GetImage("containerd://app:v2", false, ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected the error of ctr for a missing image, got: %v", err)
+	}
+
+	// without ctr, a listening containerd is reported as such
+	os.Setenv("PATH", filepath.Join(dir, "empty"))
+	if _, err := pkgutil.GetImage("containerd://app:v1", false, ""); err == nil || !strings.Contains(err.Error(), "listening") || !strings.Contains(err.Error(), "ctr") {
+		t.Errorf("Expected an error for the missing ctr, got: %v", err)
+	}
+}
+
+func TestContainersStorageImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	tag, _ := name.NewTag("localhost/app:latest", name.WeakValidation)
+	if err := tarball.WriteToFile(filepath.Join(dir, "image.tar"), tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+	// podman is stood in for by a script recording its arguments and saving
+	// the image written above to the path following --output
+	script := `#!/bin/sh
+echo "$@" > "` + filepath.Join(dir, "args") + `"
+while [ $# -gt 1 ]; do
+	[ "$1" = --output ] && out=$2
+	shift
+done
+[ "$1" = app ] || { echo "Error: $1: image not known" >&2; exit 125; }
+cp "` + filepath.Join(dir, "image.tar") + `" "$out"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "podman"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing podman: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	digest, _ := img.Digest()
+	tests := []struct {
+		source string
+		args   string
+	}{
+		{source: "podman://app", args: "image save --format docker-archive --output"},
+		{source: "containers-storage:[overlay@/srv/storage+/run/storage:overlay.mount_program=/usr/bin/fuse-overlayfs]app", args: "--storage-opt overlay.mount_program=/usr/bin/fuse-overlayfs --storage-driver overlay --runroot /run/storage --root /srv/storage image save"},
+	}
+	for _, test := range tests {
+		image, err := pkgutil.GetImage(test.source, false, "")
+		if err != nil {
+			t.Errorf("Error retrieving %s: %s", test.source, err)
+			continue
+		}
+		if image.Digest != digest || image.Source != "app" {
+			t.Errorf("Expected image %s from app but got %s from %s", digest, image.Digest, image.Source)
+		}
+		args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+		if !strings.HasPrefix(string(args), test.args) {
+			t.Errorf("Expected podman to be run with %q for %s, got: %s", test.args, test.source, args)
+		}
+		pkgutil.CleanupImage(image)
+	}
+	if _, err := pkgutil.GetImage("podman://other", false, ""); err == nil || !strings.Contains(err.Error(), "image not known") {
+		t.Errorf("Expected the error of podman for a missing image, got: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"math"
+)
+
+// Scorecard categories, in the order they are reported.
+const (
+	ScoreSize        = "Size"
+	ScoreLayers      = "Layer hygiene"
+	ScoreWasted      = "Wasted space"
+	ScoreFreshness   = "Package freshness"
+	ScorePermissions = "Risky permissions"
+)
+
+// Units of the raw value measured for each category.
+const (
+	UnitBytes  = "bytes"
+	UnitLayers = "layers"
+	UnitDays   = "days"
+	UnitFiles  = "files"
+)
+
+// ScoreCategory is one line of a scorecard. Score ranges from 0 to 100,
+// higher is better, and is derived from the raw Value.
+type ScoreCategory struct {
+	Category string
+	Score    int
+	Value    int64
+	Unit     string
+}
+
+// Scorecard condenses the analysis of an image into a handful of numbers.
+// Score is the average of the category scores.
+type Scorecard struct {
+	Score      int
+	Categories []ScoreCategory
+}
+
+// ScoreDelta compares a category between two images. Missing1 or Missing2
+// is set when the category could not be measured for that image.
+type ScoreDelta struct {
+	Category string
+	Unit     string
+	Score1   int
+	Score2   int
+	Delta    int
+	Value1   int64
+	Value2   int64
+	Missing1 bool `json:",omitempty"`
+	Missing2 bool `json:",omitempty"`
+}
+
+// ScorecardDiff compares the scorecards of two images.
+type ScorecardDiff struct {
+	Score1     int
+	Score2     int
+	Delta      int
+	Categories []ScoreDelta
+}
+
+const (
+	mb = 1024 * 1024
+
+	// images at or below goodSize score 100, those at or above badSize 0
+	goodSize = 50 * mb
+	badSize  = 2048 * mb
+	// layers beyond goodLayers each cost layerPenalty points
+	goodLayers   = 10
+	layerPenalty = 5
+	// images built within freshDays score 100, those older than staleDays 0
+	freshDays = 30
+	staleDays = 395
+	// each risky file costs permissionPenalty points
+	permissionPenalty = 5
+)
+
+// SizeScore scores an image size on a logarithmic scale.
+func SizeScore(size int64) ScoreCategory {
+	score := 100.0
+	if size > goodSize {
+		score = 100 * (math.Log(badSize) - math.Log(float64(size))) / (math.Log(badSize) - math.Log(goodSize))
+	}
+	return ScoreCategory{ScoreSize, clampScore(score), size, UnitBytes}
+}
+
+// LayerScore penalizes images with many layers.
+func LayerScore(layers int) ScoreCategory {
+	score := 100.0 - float64(layerPenalty*(layers-goodLayers))
+	return ScoreCategory{ScoreLayers, clampScore(score), int64(layers), UnitLayers}
+}
+
+// WastedScore scores the bytes stored in layers but overwritten or deleted
+// by later ones, relative to the total size of the layers. An image wasting
+// half of its layers scores 0.
+func WastedScore(wasted, total int64) ScoreCategory {
+	score := 100.0
+	if total > 0 {
+		score = 100 - 200*float64(wasted)/float64(total)
+	}
+	return ScoreCategory{ScoreWasted, clampScore(score), wasted, UnitBytes}
+}
+
+// FreshnessScore scores how many days ago the packages of an image were
+// last updated.
+func FreshnessScore(days int) ScoreCategory {
+	score := 100.0
+	if days > freshDays {
+		score = 100 * float64(staleDays-days) / float64(staleDays-freshDays)
+	}
+	return ScoreCategory{ScoreFreshness, clampScore(score), int64(days), UnitDays}
+}
+
+// PermissionScore penalizes setuid, setgid and world-writable files.
+func PermissionScore(files int) ScoreCategory {
+	score := 100.0 - float64(permissionPenalty*files)
+	return ScoreCategory{ScorePermissions, clampScore(score), int64(files), UnitFiles}
+}
+
+// NewScorecard totals the given categories into a scorecard.
+func NewScorecard(categories []ScoreCategory) Scorecard {
+	card := Scorecard{Categories: categories}
+	if len(categories) == 0 {
+		return card
+	}
+	total := 0
+	for _, c := range categories {
+		total += c.Score
+	}
+	card.Score = int(math.Round(float64(total) / float64(len(categories))))
+	return card
+}
+
+// GetScorecardDiff compares two scorecards category by category.
+func GetScorecardDiff(card1, card2 Scorecard) ScorecardDiff {
+	diff := ScorecardDiff{
+		Score1:     card1.Score,
+		Score2:     card2.Score,
+		Delta:      card2.Score - card1.Score,
+		Categories: []ScoreDelta{},
+	}
+	categories2 := map[string]ScoreCategory{}
+	for _, c := range card2.Categories {
+		categories2[c.Category] = c
+	}
+	seen := map[string]bool{}
+	for _, c1 := range card1.Categories {
+		seen[c1.Category] = true
+		delta := ScoreDelta{Category: c1.Category, Unit: c1.Unit, Score1: c1.Score, Value1: c1.Value}
+		if c2, ok := categories2[c1.Category]; ok {
+			delta.Score2 = c2.Score
+			delta.Value2 = c2.Value
+			delta.Delta = c2.Score - c1.Score
+		} else {
+			delta.Missing2 = true
+		}
+		diff.Categories = append(diff.Categories, delta)
+	}
+	for _, c2 := range card2.Categories {
+		if !seen[c2.Category] {
+			diff.Categories = append(diff.Categories, ScoreDelta{
+				Category: c2.Category,
+				Unit:     c2.Unit,
+				Score2:   c2.Score,
+				Value2:   c2.Value,
+				Missing1: true,
+			})
+		}
+	}
+	return diff
+}
+
+func clampScore(score float64) int {
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryScores(t *testing.T) {
+	testCases := []struct {
+		descrip  string
+		category ScoreCategory
+		expected int
+	}{
+		{"small image", SizeScore(10 * mb), 100},
+		{"huge image", SizeScore(4096 * mb), 0},
+		{"midsize image", SizeScore(320 * mb), 50},
+		{"few layers", LayerScore(4), 100},
+		{"many layers", LayerScore(16), 70},
+		{"no waste", WastedScore(0, 100), 100},
+		{"quarter wasted", WastedScore(25, 100), 50},
+		{"empty layers", WastedScore(0, 0), 100},
+		{"fresh", FreshnessScore(7), 100},
+		{"stale", FreshnessScore(1000), 0},
+		{"half stale", FreshnessScore(212), 50},
+		{"setuid binaries", PermissionScore(4), 80},
+	}
+	for _, test := range testCases {
+		if test.category.Score != test.expected {
+			t.Errorf("%s: Expected score %d but got %d", test.descrip, test.expected, test.category.Score)
+		}
+	}
+}
+
+func TestGetScorecardDiff(t *testing.T) {
+	card1 := NewScorecard([]ScoreCategory{SizeScore(10 * mb), LayerScore(16), FreshnessScore(7)})
+	card2 := NewScorecard([]ScoreCategory{SizeScore(10 * mb), LayerScore(12)})
+	if card1.Score != 90 || card2.Score != 95 {
+		t.Fatalf("Expected scores 90 and 95 but got %d and %d", card1.Score, card2.Score)
+	}
+
+	expected := ScorecardDiff{
+		Score1: 90,
+		Score2: 95,
+		Delta:  5,
+		Categories: []ScoreDelta{
+			{Category: ScoreSize, Unit: UnitBytes, Score1: 100, Score2: 100, Value1: 10 * mb, Value2: 10 * mb},
+			{Category: ScoreLayers, Unit: UnitLayers, Score1: 70, Score2: 90, Delta: 20, Value1: 16, Value2: 12},
+			{Category: ScoreFreshness, Unit: UnitDays, Score1: 100, Value1: 7, Missing2: true},
+		},
+	}
+	if actual := GetScorecardDiff(card1, card2); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v but got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Secret is a likely credential found in a file of an image. Match is
+// redacted so that reports don't leak it further; Fingerprint, a digest of
+// the full credential, tells credentials apart across images.
+type Secret struct {
+	Path        string
+	Line        int
+	Kind        string
+	Match       string
+	Fingerprint string
+}
+
+// SecretDiff lists the secrets found only in the second image, which the
+// build introduced, and those found only in the first.
+type SecretDiff struct {
+	Added   []Secret
+	Removed []Secret
+}
+
+// RedactSecret keeps the first characters of a credential, enough to
+// recognize it, and masks the rest.
+func RedactSecret(secret string) string {
+	keep := 4
+	if len(secret) <= 2*keep {
+		keep = len(secret) / 4
+	}
+	return secret[:keep] + strings.Repeat("*", 8)
+}
+
+// SortSecrets orders secrets by path and line.
+func SortSecrets(secrets []Secret) {
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].Path != secrets[j].Path {
+			return secrets[i].Path < secrets[j].Path
+		}
+		if secrets[i].Line != secrets[j].Line {
+			return secrets[i].Line < secrets[j].Line
+		}
+		return secrets[i].Kind < secrets[j].Kind
+	})
+}
+
+// GetSecretDiff compares the secrets of two images. A secret is the same in
+// both when the same credential is found in the same file, whatever line it
+// moved to.
+func GetSecretDiff(secrets1, secrets2 []Secret) SecretDiff {
+	diff := SecretDiff{Added: []Secret{}, Removed: []Secret{}}
+	key := func(s Secret) string { return s.Path + "\x00" + s.Kind + "\x00" + s.Fingerprint }
+	keys1, keys2 := map[string]bool{}, map[string]bool{}
+	for _, s := range secrets1 {
+		keys1[key(s)] = true
+	}
+	for _, s := range secrets2 {
+		keys2[key(s)] = true
+		if !keys1[key(s)] {
+			diff.Added = append(diff.Added, s)
+		}
+	}
+	for _, s := range secrets1 {
+		if !keys2[key(s)] {
+			diff.Removed = append(diff.Removed, s)
+		}
+	}
+	SortSecrets(diff.Added)
+	SortSecrets(diff.Removed)
+	return diff
+}
+
+type StrSecret struct {
+	Path  string
+	Line  string
+	Kind  string
+	Match string
+}
+
+func stringifySecrets(secrets []Secret) []StrSecret {
+	rows := []StrSecret{}
+	for _, s := range secrets {
+		line := "-"
+		if s.Line > 0 {
+			line = strconv.Itoa(s.Line)
+		}
+		rows = append(rows, StrSecret{Path: s.Path, Line: line, Kind: s.Kind, Match: s.Match})
+	}
+	return rows
+}
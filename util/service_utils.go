@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Service managers of the services found in images.
+const (
+	ManagerSystemd     = "systemd"
+	ManagerSysVInit    = "sysvinit"
+	ManagerOpenRC      = "openrc"
+	ManagerSupervisord = "supervisord"
+)
+
+// Service stores a background service an image starts: an enabled systemd
+// unit, an init script linked into a runlevel, or a supervisord program
+// started automatically. StartedBy lists the targets or runlevels which
+// start it, and Source the image path of its unit file, init script or
+// supervisord configuration. Command is the program it runs, when known.
+type Service struct {
+	Name      string
+	Manager   string
+	Command   string `json:",omitempty"`
+	StartedBy []string
+	Source    string
+}
+
+// ServiceChange stores a service started by both images whose command,
+// targets or source differ.
+type ServiceChange struct {
+	Name     string
+	Manager  string
+	Service1 Service
+	Service2 Service
+}
+
+// ServiceDiff stores the services the second image starts and the first
+// doesn't, those it stopped starting, and those started by both which
+// changed.
+type ServiceDiff struct {
+	Started []Service
+	Stopped []Service
+	Changed []ServiceChange
+}
+
+// GetServiceDiff compares the services of two images, identified by
+// manager and name.
+func GetServiceDiff(services1, services2 []Service) ServiceDiff {
+	diff := ServiceDiff{
+		Started: []Service{},
+		Stopped: []Service{},
+		Changed: []ServiceChange{},
+	}
+	key := func(s Service) string { return s.Manager + "/" + s.Name }
+	byKey1 := map[string]Service{}
+	for _, s := range services1 {
+		byKey1[key(s)] = s
+	}
+	byKey2 := map[string]Service{}
+	for _, s := range services2 {
+		byKey2[key(s)] = s
+		s1, ok := byKey1[key(s)]
+		if !ok {
+			diff.Started = append(diff.Started, s)
+		} else if !reflect.DeepEqual(s1, s) {
+			diff.Changed = append(diff.Changed, ServiceChange{Name: s.Name, Manager: s.Manager, Service1: s1, Service2: s})
+		}
+	}
+	for _, s := range services1 {
+		if _, ok := byKey2[key(s)]; !ok {
+			diff.Stopped = append(diff.Stopped, s)
+		}
+	}
+	return diff
+}
+
+// SortServices orders services by manager, then name.
+func SortServices(services []Service) {
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Manager != services[j].Manager {
+			return services[i].Manager < services[j].Manager
+		}
+		return services[i].Name < services[j].Name
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strconv"
+
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+// LayerRef is a layer of an image, by index, with its compressed size.
+type LayerRef struct {
+	Index  int
+	Digest v1.Hash
+	Size   int64
+}
+
+// SharedLayer is a layer found in both images, at Index1 and Index2.
+type SharedLayer struct {
+	Digest v1.Hash
+	Size   int64
+	Index1 int
+	Index2 int
+}
+
+// SharedLayerDiff splits the layers of two images into those they share,
+// by digest, and those unique to each. Sizes are compressed.
+type SharedLayerDiff struct {
+	Shared     []SharedLayer
+	Unique1    []LayerRef
+	Unique2    []LayerRef
+	SharedSize int64
+}
+
+// GetSharedLayerDiff matches the layers of two images by digest. A layer
+// repeated in an image is matched as many times as the other image has it.
+func GetSharedLayerDiff(layers1, layers2 []LayerRef) SharedLayerDiff {
+	diff := SharedLayerDiff{Shared: []SharedLayer{}, Unique1: []LayerRef{}, Unique2: []LayerRef{}}
+	matched := make([]bool, len(layers1))
+	for _, layer2 := range layers2 {
+		shared := false
+		for i, layer1 := range layers1 {
+			if !matched[i] && layer1.Digest == layer2.Digest {
+				matched[i] = true
+				shared = true
+				diff.Shared = append(diff.Shared, SharedLayer{Digest: layer2.Digest, Size: layer2.Size, Index1: layer1.Index, Index2: layer2.Index})
+				diff.SharedSize += layer2.Size
+				break
+			}
+		}
+		if !shared {
+			diff.Unique2 = append(diff.Unique2, layer2)
+		}
+	}
+	for i, layer1 := range layers1 {
+		if !matched[i] {
+			diff.Unique1 = append(diff.Unique1, layer1)
+		}
+	}
+	return diff
+}
+
+type StrSharedLayer struct {
+	Index1 string
+	Index2 string
+	Digest string
+	Size   string
+}
+
+func stringifySharedLayers(shared []SharedLayer) []StrSharedLayer {
+	strLayers := []StrSharedLayer{}
+	for _, layer := range shared {
+		strLayers = append(strLayers, StrSharedLayer{
+			Index1: strconv.Itoa(layer.Index1),
+			Index2: strconv.Itoa(layer.Index2),
+			Digest: layer.Digest.String(),
+			Size:   stringifySize(layer.Size),
+		})
+	}
+	return strLayers
+}
+
+type StrLayerRef struct {
+	Index  string
+	Digest string
+	Size   string
+}
+
+func stringifyLayerRefs(layers []LayerRef) []StrLayerRef {
+	strLayers := []StrLayerRef{}
+	for _, layer := range layers {
+		strLayers = append(strLayers, StrLayerRef{
+			Index:  strconv.Itoa(layer.Index),
+			Digest: layer.Digest.String(),
+			Size:   stringifySize(layer.Size),
+		})
+	}
+	return strLayers
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// What grew an image.
+const (
+	CulpritFile    = "file"
+	CulpritPackage = "package"
+	CulpritLayer   = "layer"
+)
+
+// SizeCulprit is something that grew an image by Size bytes: an added or
+// grown file, a package added or upgraded, or a layer new to the image.
+type SizeCulprit struct {
+	Kind   string
+	Name   string
+	Detail string
+	Size   int64
+}
+
+// RankSizeCulprits orders the culprits of each kind by size, largest first,
+// and keeps the top n of each.
+func RankSizeCulprits(culprits []SizeCulprit, n int) []SizeCulprit {
+	sort.SliceStable(culprits, func(i, j int) bool {
+		if culprits[i].Size != culprits[j].Size {
+			return culprits[i].Size > culprits[j].Size
+		}
+		return culprits[i].Name < culprits[j].Name
+	})
+	ranked := []SizeCulprit{}
+	counts := map[string]int{}
+	for _, c := range culprits {
+		if c.Size <= 0 || counts[c.Kind] >= n {
+			continue
+		}
+		counts[c.Kind]++
+		ranked = append(ranked, c)
+	}
+	return ranked
+}
+
+// ExplainSizeBudget describes how an image grew past its size budget, with
+// the ranked culprits of each kind.
+func ExplainSizeBudget(image1, image2 string, size1, size2, budget int64, culprits []SizeCulprit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is %s larger than %s (%s, was %s), over the size budget of %s.\n",
+		image2, stringifySize(size2-size1), image1, stringifySize(size2), stringifySize(size1), stringifySize(budget))
+	sections := []struct {
+		kind  string
+		title string
+	}{
+		{CulpritFile, "Largest new or grown files"},
+		{CulpritPackage, "Biggest package upgrades"},
+		{CulpritLayer, "Largest new layers"},
+	}
+	for _, section := range sections {
+		rank := 0
+		for _, c := range culprits {
+			if c.Kind != section.kind {
+				continue
+			}
+			if rank == 0 {
+				fmt.Fprintf(&b, "\n%s:\n", section.title)
+			}
+			rank++
+			fmt.Fprintf(&b, "%d. %s +%s", rank, c.Name, stringifySize(c.Size))
+			if c.Detail != "" {
+				fmt.Fprintf(&b, " (%s)", c.Detail)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
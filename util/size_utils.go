@@ -18,14 +18,21 @@ package util
 
 import "github.com/google/go-containerregistry/pkg/v1"
 
+// SizeEntry is the size of an image or a layer once extracted, and
+// compressed as it is pulled when the manifest is known.
 type SizeEntry struct {
-	Name   string
-	Digest v1.Hash
-	Size   int64
+	Name           string
+	Digest         v1.Hash
+	Size           int64
+	CompressedSize int64 `json:",omitempty"`
 }
 
+// SizeDiff compares the sizes of two images or layers. Sizes of layers
+// missing from one of the images are -1.
 type SizeDiff struct {
-	Name  string
-	Size1 int64
-	Size2 int64
+	Name            string
+	Size1           int64
+	Size2           int64
+	CompressedSize1 int64 `json:",omitempty"`
+	CompressedSize2 int64 `json:",omitempty"`
 }
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestRegisteredImageSource(t *testing.T) {
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	digest, _ := img.Digest()
+	released := []string{}
+	pkgutil.RegisterImageSource("store://", func(name string) (v1.Image, func(), error) {
+		if name != "app/v1" {
+			return nil, nil, fmt.Errorf("no artifact %s", name)
+		}
+		return img, func() { released = append(released, name) }, nil
+	})
+
+	image, err := pkgutil.GetImage("store://app/v1", false, "")
+	if err != nil {
+		t.Fatalf("Error retrieving store://app/v1: %s", err)
+	}
+	defer pkgutil.CleanupImage(image)
+	if image.Digest != digest {
+		t.Errorf("Expected image %s but got %s", digest, image.Digest)
+	}
+	if len(released) != 1 {
+		t.Errorf("Expected the source to be released once, got %d", len(released))
+	}
+	if _, err := pkgutil.GetImage("store://app/v2", false, ""); err == nil || !strings.Contains(err.Error(), "no artifact app/v2") {
+		t.Errorf("Expected the error of the source, got %v", err)
+	}
+}
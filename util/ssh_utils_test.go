@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+func TestSSHImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	rootfs := filepath.Join(dir, "it's a rootfs")
+	if err := os.MkdirAll(rootfs, 0755); err != nil {
+		t.Fatalf("Error creating rootfs: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "hello.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Error writing file: %s", err)
+	}
+	// ssh is stood in for by a script recording its arguments and running
+	// the remote command with a shell, as the remote host would
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + filepath.Join(dir, "args") + `"
+for last; do :; done
+exec sh -c "$last"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatalf("Error writing ssh: %s", err)
+	}
+	defer func(command string) { pkgutil.SSHCommand = command }(pkgutil.SSHCommand)
+	pkgutil.SSHCommand = filepath.Join(dir, "ssh")
+
+	source := (&url.URL{Scheme: "ssh", User: url.User("builder"), Host: "buildhost:2222", Path: rootfs}).String()
+	image, err := pkgutil.GetImage(source, false, "")
+	if err != nil {
+		t.Fatalf("Error retrieving %s: %s", source, err)
+	}
+	defer pkgutil.CleanupImage(image)
+	if contents, err := ioutil.ReadFile(filepath.Join(image.FSPath, "hello.txt")); err != nil || string(contents) != "hello" {
+		t.Errorf("Expected hello.txt copied from %s, got %q: %v", rootfs, contents, err)
+	}
+	args, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	expected := []string{"-p", "2222", "--", "builder@buildhost", "tar -C '" + strings.Replace(rootfs, "'", `'\''`, -1) + "' -cf - ."}
+	if actual := strings.Split(strings.TrimSuffix(string(args), "\n"), "\n"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected ssh to be run with %q, got %q", expected, actual)
+	}
+
+	// users and hosts which ssh would parse as options never reach it
+	for _, source := range []string{"ssh://-oProxyCommand=touch%20pwned@host/rootfs", "ssh://-oProxyCommand=true/rootfs"} {
+		os.Remove(filepath.Join(dir, "args"))
+		if _, err := pkgutil.GetImage(source, false, ""); err == nil {
+			t.Errorf("Expected an error retrieving %s", source)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "args")); err == nil {
+			t.Errorf("Expected ssh not to be run for %s", source)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Enablement states of a systemd unit, as reported by systemctl is-enabled.
+const (
+	UnitEnabled  = "enabled"
+	UnitDisabled = "disabled"
+	UnitStatic   = "static"
+	UnitMasked   = "masked"
+)
+
+// SystemdUnit stores the enablement state of a systemd unit. WantedBy lists
+// the units whose .wants or .requires directories link to it, and Preset the
+// action the preset files apply to it, if any.
+type SystemdUnit struct {
+	Unit     string
+	State    string
+	WantedBy []string
+	Preset   string
+}
+
+// SystemdUnitChange stores the state of a unit in two images. Empty states
+// mean the unit is absent from that image.
+type SystemdUnitChange struct {
+	Unit      string
+	State1    string
+	State2    string
+	WantedBy1 []string
+	WantedBy2 []string
+	Preset1   string
+	Preset2   string
+}
+
+// SystemdDiff stores the difference in unit enablement between two images.
+// Enabled and Disabled list the units which started or stopped being enabled
+// in the second image, so newly started services stand out; Changes holds
+// every unit whose state, dependents or preset differ.
+type SystemdDiff struct {
+	Enabled  []string
+	Disabled []string
+	Changes  []SystemdUnitChange
+}
+
+// GetSystemdDiff compares two sets of units keyed by name.
+func GetSystemdDiff(units1, units2 map[string]SystemdUnit) SystemdDiff {
+	diff := SystemdDiff{
+		Enabled:  []string{},
+		Disabled: []string{},
+		Changes:  []SystemdUnitChange{},
+	}
+	names := map[string]bool{}
+	for name := range units1 {
+		names[name] = true
+	}
+	for name := range units2 {
+		names[name] = true
+	}
+	for name := range names {
+		u1, u2 := units1[name], units2[name]
+		if u1.State == u2.State && u1.Preset == u2.Preset && reflect.DeepEqual(u1.WantedBy, u2.WantedBy) {
+			continue
+		}
+		if u2.State == UnitEnabled && u1.State != UnitEnabled {
+			diff.Enabled = append(diff.Enabled, name)
+		} else if u1.State == UnitEnabled && u2.State != UnitEnabled {
+			diff.Disabled = append(diff.Disabled, name)
+		}
+		diff.Changes = append(diff.Changes, SystemdUnitChange{
+			Unit:      name,
+			State1:    u1.State,
+			State2:    u2.State,
+			WantedBy1: nonNil(u1.WantedBy),
+			WantedBy2: nonNil(u2.WantedBy),
+			Preset1:   u1.Preset,
+			Preset2:   u2.Preset,
+		})
+	}
+	sort.Strings(diff.Enabled)
+	sort.Strings(diff.Disabled)
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Unit < diff.Changes[j].Unit })
+	return diff
+}
+
+// SortedSystemdUnits returns the units in units ordered by name.
+func SortedSystemdUnits(units map[string]SystemdUnit) []SystemdUnit {
+	sorted := []SystemdUnit{}
+	for _, u := range units {
+		sorted = append(sorted, u)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Unit < sorted[j].Unit })
+	return sorted
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
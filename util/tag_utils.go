@@ -0,0 +1,117 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+// Orders of the tags of a repository.
+const (
+	TagOrderSemver = "semver"
+	TagOrderTime   = "time"
+)
+
+var versionTagPattern = regexp.MustCompile(`^v?\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// IsVersionTag tells whether tag is a semantic version, such as 1.4,
+// v1.4.2 or 1.5.0-rc.1.
+func IsVersionTag(tag string) bool {
+	return versionTagPattern.MatchString(tag)
+}
+
+// HistoricalTag returns the tag offset tags before current. In semver order
+// only version tags count, and tags of the same version, such as 1.4 and
+// v1.4.0, count once. In time order tags count by their Time, the upload
+// or image creation time, tags of the same digest once; their times must be
+// known.
+func HistoricalTag(tags []pkgutil.RegistryTag, current string, offset int, order string) (string, error) {
+	switch order {
+	case TagOrderSemver:
+		return historicalVersionTag(tags, current, offset)
+	case TagOrderTime:
+		return historicalPushedTag(tags, current, offset)
+	}
+	return "", fmt.Errorf("unknown tag order %q, expected %s or %s", order, TagOrderSemver, TagOrderTime)
+}
+
+func historicalVersionTag(tags []pkgutil.RegistryTag, current string, offset int) (string, error) {
+	if !IsVersionTag(current) {
+		return "", fmt.Errorf("tag %s is not a version, use --tag-order=%s", current, TagOrderTime)
+	}
+	versions := []string{}
+	for _, tag := range tags {
+		if IsVersionTag(tag.Tag) && compareSemver(tag.Tag, current) < 0 {
+			versions = append(versions, tag.Tag)
+		}
+	}
+	// newest first, tags of the same version by name
+	sort.Slice(versions, func(i, j int) bool {
+		if c := compareSemver(versions[i], versions[j]); c != 0 {
+			return c > 0
+		}
+		return versions[i] > versions[j]
+	})
+	count := 0
+	for i, version := range versions {
+		if i > 0 && compareSemver(version, versions[i-1]) == 0 {
+			continue
+		}
+		if count++; count == offset {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("there are only %d version(s) before %s", count, current)
+}
+
+func historicalPushedTag(tags []pkgutil.RegistryTag, current string, offset int) (string, error) {
+	var cur *pkgutil.RegistryTag
+	for i := range tags {
+		if tags[i].Tag == current {
+			cur = &tags[i]
+		}
+	}
+	if cur == nil {
+		return "", fmt.Errorf("tag %s not found", current)
+	}
+	earlier := []pkgutil.RegistryTag{}
+	for _, tag := range tags {
+		if !tag.Time.IsZero() && tag.Time.Before(cur.Time) && tag.Digest != cur.Digest {
+			earlier = append(earlier, tag)
+		}
+	}
+	sort.Slice(earlier, func(i, j int) bool {
+		if !earlier[i].Time.Equal(earlier[j].Time) {
+			return earlier[i].Time.After(earlier[j].Time)
+		}
+		return earlier[i].Tag < earlier[j].Tag
+	})
+	pushes := map[string]bool{}
+	for _, tag := range earlier {
+		if pushes[tag.Digest] {
+			continue
+		}
+		if pushes[tag.Digest] = true; len(pushes) == offset {
+			return tag.Tag, nil
+		}
+	}
+	return "", fmt.Errorf("there are only %d push(es) before %s", len(pushes), current)
+}
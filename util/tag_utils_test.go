@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestHistoricalTag(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	tags := []pkgutil.RegistryTag{
+		{Tag: "latest", Digest: "sha256:d5", Time: day(5)},
+		{Tag: "v1.10.0", Digest: "sha256:d5", Time: day(5)},
+		{Tag: "v1.9.1", Digest: "sha256:d4", Time: day(4)},
+		{Tag: "1.9", Digest: "sha256:d4", Time: day(4)},
+		{Tag: "v1.9.0", Digest: "sha256:d3", Time: day(3)},
+		{Tag: "v1.10.0-rc.1", Digest: "sha256:d3", Time: day(3)},
+		{Tag: "nightly", Digest: "sha256:d2", Time: day(2)},
+		{Tag: "v1.2.0", Digest: "sha256:d1", Time: day(1)},
+	}
+	testCases := []struct {
+		descrip     string
+		current     string
+		offset      int
+		order       string
+		expected    string
+		shouldError bool
+	}{
+		{descrip: "previous version", current: "v1.10.0", offset: 1, order: TagOrderSemver, expected: "v1.10.0-rc.1"},
+		{descrip: "same version counts once", current: "v1.10.0", offset: 2, order: TagOrderSemver, expected: "v1.9.1"},
+		{descrip: "versions compare numerically", current: "v1.10.0", offset: 4, order: TagOrderSemver, expected: "v1.2.0"},
+		{descrip: "too far back", current: "v1.10.0", offset: 5, order: TagOrderSemver, shouldError: true},
+		{descrip: "current is not a version", current: "latest", offset: 1, order: TagOrderSemver, shouldError: true},
+		{descrip: "previous push", current: "latest", offset: 1, order: TagOrderTime, expected: "1.9"},
+		{descrip: "pushes count once", current: "latest", offset: 3, order: TagOrderTime, expected: "nightly"},
+		{descrip: "unknown tag", current: "v0.1", offset: 1, order: TagOrderTime, shouldError: true},
+		{descrip: "unknown order", current: "latest", offset: 1, order: "alphabetical", shouldError: true},
+	}
+	for _, test := range testCases {
+		tag, err := HistoricalTag(tags, test.current, test.offset, test.order)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", test.descrip, tag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.descrip, err)
+		} else if tag != test.expected {
+			t.Errorf("%s: expected %s but got %s", test.descrip, test.expected, tag)
+		}
+	}
+}
+
+func TestResolveTagTimes(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	manifests := map[string][]byte{}
+	blobs := map[string][]byte{}
+	tags := []pkgutil.RegistryTag{{Tag: "listed", Time: day(1)}}
+	for d := 2; d <= 20; d++ {
+		config := []byte(fmt.Sprintf(`{"created": %q, "rootfs": {"type": "layers"}}`, day(d).Format(time.RFC3339)))
+		configDigest, _, _ := v1.SHA256(bytes.NewReader(config))
+		blobs[configDigest.String()] = config
+		tag := fmt.Sprintf("v1.0.%d", d)
+		manifests[tag] = []byte(fmt.Sprintf(`{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.v2+json", "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "size": %d, "digest": %q}, "layers": []}`, len(config), configDigest))
+		tags = append(tags, pkgutil.RegistryTag{Tag: tag})
+	}
+	registry := serveRegistry(manifests, blobs)
+	defer registry.Close()
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/listed") {
+			t.Errorf("Expected the time of a listed tag not to be read")
+		}
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		registry.Config.Handler.ServeHTTP(w, r)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if err := pkgutil.ResolveTagTimes("remote://"+host+"/app:latest", tags); err != nil {
+		t.Fatalf("Error resolving tag times: %s", err)
+	}
+	for i, tag := range tags {
+		if !tag.Time.Equal(day(i + 1)) {
+			t.Errorf("Expected %s created on %s but got %s", tag.Tag, day(i+1), tag.Time)
+		}
+		if i > 0 && !strings.HasPrefix(tag.Digest, "sha256:") {
+			t.Errorf("Expected the digest of %s but got %q", tag.Tag, tag.Digest)
+		}
+	}
+	if maxInFlight < 2 || maxInFlight > 8 {
+		t.Errorf("Expected tags read concurrently, up to 8 requests at once, but got %d", maxInFlight)
+	}
+
+	missing := []pkgutil.RegistryTag{{Tag: "v1.0.2"}, {Tag: "missing"}, {Tag: "v1.0.3"}}
+	if err := pkgutil.ResolveTagTimes("remote://"+host+"/app:latest", missing); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected an error for the missing tag, got: %v", err)
+	}
+}
@@ -17,9 +17,18 @@ limitations under the License.
 package util
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 )
 
 func TestIsTar(t *testing.T) {
@@ -29,6 +38,9 @@ func TestIsTar(t *testing.T) {
 	}{
 		{input: "/testTar/la-croix1.tar", expected: true},
 		{input: "/testTar/la-croix1-actual", expected: false},
+		{input: "/testTar/la-croix1.tar.gz", expected: true},
+		{input: "/testTar/la-croix1.tgz", expected: true},
+		{input: "/testTar/la-croix1.gz", expected: false},
 	}
 	for _, test := range testCases {
 		actual := pkgutil.IsTar(test.input)
@@ -37,3 +49,46 @@ func TestIsTar(t *testing.T) {
 		}
 	}
 }
+
+func TestAppleDoubleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appledouble")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	// tar on macOS stores the extended attributes of notes.txt as ._notes.txt
+	contents := layerTar(map[string]string{
+		"notes.txt":    "notes\n",
+		"._notes.txt":  "\x00\x05\x16\x07\x00\x02\x00\x00Mac OS X",
+		"._config.yml": "not AppleDouble\n",
+	})
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(contents)), nil
+	})
+	if err != nil {
+		t.Fatalf("Error creating layer: %s", err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	source := filepath.Join(dir, "image.tar")
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+	if err := tarball.WriteToFile(source, tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+
+	image, err := pkgutil.GetImage(source, true, "")
+	if err != nil {
+		t.Fatalf("Error retrieving the image: %s", err)
+	}
+	defer pkgutil.CleanupImage(image)
+	for name, expected := range map[string]string{"notes.txt": "notes\n", "._config.yml": "not AppleDouble\n"} {
+		if content, err := ioutil.ReadFile(filepath.Join(image.FSPath, name)); err != nil || string(content) != expected {
+			t.Errorf("Expected %s to hold %q, got %q: %v", name, expected, content, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(image.FSPath, "._notes.txt")); err == nil {
+		t.Errorf("Expected the AppleDouble file ._notes.txt left out of the filesystem")
+	}
+}
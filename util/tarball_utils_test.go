@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestTarballsWithoutDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tarballs")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	// tarballs are read without asking any container runtime
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+	os.Setenv("DOCKER_HOST", "unix://"+filepath.Join(dir, "docker.sock"))
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+
+	digests := map[string]v1.Hash{}
+	for _, source := range []string{filepath.Join(dir, "a.tar"), filepath.Join(dir, "b.tar.gz")} {
+		img, err := random.Image(64, 2)
+		if err != nil {
+			t.Fatalf("Error creating image: %s", err)
+		}
+		digests[source], _ = img.Digest()
+		tar := strings.TrimSuffix(source, ".gz")
+		if err := tarball.WriteToFile(tar, tag, img); err != nil {
+			t.Fatalf("Error writing image: %s", err)
+		}
+		if tar != source {
+			contents, _ := ioutil.ReadFile(tar)
+			var compressed bytes.Buffer
+			gz := gzip.NewWriter(&compressed)
+			gz.Write(contents)
+			gz.Close()
+			ioutil.WriteFile(source, compressed.Bytes(), 0644)
+			os.Remove(tar)
+		}
+	}
+
+	for source, digest := range digests {
+		image, err := pkgutil.GetImage(source, false, "")
+		if err != nil {
+			t.Errorf("Error retrieving %s: %s", source, err)
+			continue
+		}
+		if image.Digest != digest {
+			t.Errorf("Expected image %s from %s but got %s", digest, source, image.Digest)
+		}
+		pkgutil.CleanupImage(image)
+	}
+}
+
+func TestMultiImageTarball(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tarballs")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	images := map[name.Tag]v1.Image{}
+	digests := map[string]v1.Hash{}
+	ids := map[string]string{}
+	for _, ref := range []string{"example.com/app:v1", "example.com/app:v2"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("Error creating image: %s", err)
+		}
+		tag, _ := name.NewTag(ref, name.WeakValidation)
+		images[tag] = img
+		digests[ref], _ = img.Digest()
+		config, _ := img.ConfigName()
+		ids[ref] = config.Hex
+	}
+	tar := filepath.Join(dir, "images.tar")
+	if err := tarball.MultiWriteToFile(tar, images); err != nil {
+		t.Fatalf("Error writing images: %s", err)
+	}
+
+	selected := map[string]string{
+		tar + "#example.com/app:v1":                  "example.com/app:v1",
+		tar + "#example.com/app:v2":                  "example.com/app:v2",
+		tar + "#" + ids["example.com/app:v2"][:12]:   "example.com/app:v2",
+		tar + "#sha256:" + ids["example.com/app:v1"]: "example.com/app:v1",
+	}
+	for source, ref := range selected {
+		image, err := pkgutil.GetImage(source, false, "")
+		if err != nil {
+			t.Errorf("Error retrieving %s: %s", source, err)
+			continue
+		}
+		if image.Digest != digests[ref] {
+			t.Errorf("Expected image %s from %s but got %s", digests[ref], source, image.Digest)
+		}
+		pkgutil.CleanupImage(image)
+	}
+	for _, source := range []string{tar, tar + "#example.com/app:v3"} {
+		if _, err := pkgutil.GetImage(source, false, ""); err == nil || !strings.Contains(err.Error(), "example.com/app:v1") {
+			t.Errorf("Expected an error listing the images of %s, got %v", source, err)
+		}
+	}
+}
@@ -18,15 +18,24 @@ package util
 
 const FSDiffOutput = `
 -----{{.DiffType}}-----
-
+{{if .Diff.Hotspots}}
+These directories changed the most in size between {{.Image1}} and {{.Image2}}:
+DIRECTORY	SIZE1	SIZE2	CHANGE	ADDED	DELETED	MODIFIED{{range .Diff.Hotspots}}{{"\n"}}{{.Dir}}	{{.Size1}}	{{.Size2}}	{{.Change}}	{{.Added}}	{{.Deleted}}	{{.Modified}}{{end}}
+{{end}}
 These entries have been added to {{.Image1}}:{{if not .Diff.Adds}} None{{else}}
-FILE	SIZE{{range .Diff.Adds}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{end}}
+FILE	SIZE{{range limit .Diff.Adds}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Diff.Adds}}{{"\n"}}{{.}}{{end}}{{end}}
 
 These entries have been deleted from {{.Image1}}:{{if not .Diff.Dels}} None{{else}}
-FILE	SIZE{{range .Diff.Dels}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{end}}
+FILE	SIZE{{range limit .Diff.Dels}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Diff.Dels}}{{"\n"}}{{.}}{{end}}{{end}}
 
 These entries have been changed between {{.Image1}} and {{.Image2}}:{{if not .Diff.Mods}} None{{else}}
-FILE	SIZE1	SIZE2{{range .Diff.Mods}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}
+FILE	SIZE1	SIZE2{{range limit .Diff.Mods}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}{{with more .Diff.Mods}}{{"\n"}}{{.}}{{end}}
+{{end}}{{if .Diff.Applets}}
+These applet symlinks have been grouped by target (use --expand-applets to list them):{{range limit .Diff.Applets}}
+{{.Target}}{{if .Updated}} updated,{{end}} {{len .Repointed}} applets re-pointed, {{len .Added}} added, {{len .Removed}} removed{{end}}{{with more .Diff.Applets}}{{"\n"}}{{.}}{{end}}
+{{end}}{{range .Diff.Archives}}
+These entries have changed inside {{.Archive}}:
+CHANGE	ENTRY	SIZE1	SIZE2{{range limit .Adds}}{{"\n"}}{{print "added"}}	{{.Name}}		{{.Size}}{{end}}{{with more .Adds}}{{"\n"}}{{.}}{{end}}{{range limit .Dels}}{{"\n"}}{{print "deleted"}}	{{.Name}}	{{.Size}}	{{end}}{{with more .Dels}}{{"\n"}}{{.}}{{end}}{{range limit .Mods}}{{"\n"}}{{print "modified"}}	{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}{{with more .Mods}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 const FSLayerDiffOutput = `
@@ -36,13 +45,13 @@ const FSLayerDiffOutput = `
 
 Diff for Layer {{$index}}:
 These entries have been added to {{$.Image1}}:{{if not $diff.Adds}} None{{else}}
-FILE	SIZE{{range $diff.Adds}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{end}}
+FILE	SIZE{{range limit $diff.Adds}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more $diff.Adds}}{{"\n"}}{{.}}{{end}}{{end}}
 
 These entries have been deleted from {{$.Image1}}:{{if not $diff.Dels}} None{{else}}
-FILE	SIZE{{range $diff.Dels}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{end}}
+FILE	SIZE{{range limit $diff.Dels}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more $diff.Dels}}{{"\n"}}{{.}}{{end}}{{end}}
 
 These entries have been changed between {{$.Image1}} and {{$.Image2}}:{{if not $diff.Mods}} None{{else}}
-FILE	SIZE1	SIZE2{{range $diff.Mods}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}
+FILE	SIZE1	SIZE2{{range limit $diff.Mods}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}{{with more $diff.Mods}}{{"\n"}}{{.}}{{end}}
 {{end}}
 {{end}}
 `
@@ -51,13 +60,13 @@ const SingleVersionDiffOutput = `
 -----{{.DiffType}}-----
 
 Packages found only in {{.Image1}}:{{if not .Diff.Packages1}} None{{else}}
-NAME	VERSION	SIZE{{range .Diff.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit .Diff.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more .Diff.Packages1}}{{"\n"}}{{.}}{{end}}{{end}}
 
 Packages found only in {{.Image2}}:{{if not .Diff.Packages2}} None{{else}}
-NAME	VERSION	SIZE{{range .Diff.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit .Diff.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more .Diff.Packages2}}{{"\n"}}{{.}}{{end}}{{end}}
 
 Version differences:{{if not .Diff.InfoDiff}} None{{else}}
-PACKAGE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range .Diff.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{.Info1.Version}}, {{.Info1.Size}}	{{.Info2.Version}}, {{.Info2.Size}}{{end}}
+PACKAGE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}})	CHANGE{{range limit .Diff.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{.Info1.Version}}, {{.Info1.Size}}	{{.Info2.Version}}, {{.Info2.Size}}	{{.Change}}{{end}}{{with more .Diff.InfoDiff}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -65,32 +74,46 @@ const MultiVersionDiffOutput = `
 -----{{.DiffType}}-----
 
 Packages found only in {{.Image1}}:{{if not .Diff.Packages1}} None{{else}}
-NAME	VERSION	SIZE{{range .Diff.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit .Diff.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more .Diff.Packages1}}{{"\n"}}{{.}}{{end}}{{end}}
 
 Packages found only in {{.Image2}}:{{if not .Diff.Packages2}} None{{else}}
-NAME	VERSION	SIZE{{range .Diff.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit .Diff.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more .Diff.Packages2}}{{"\n"}}{{.}}{{end}}{{end}}
 
 Version differences:{{if not .Diff.InfoDiff}} None{{else}}
-PACKAGE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range .Diff.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{range .Info1}}{{.Version}}, {{.Size}}{{end}}	{{range .Info2}}{{.Version}}, {{.Size}}{{end}}{{end}}
+PACKAGE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}})	CHANGE{{range limit .Diff.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{range .Info1}}{{.Version}}, {{.Size}}{{end}}	{{range .Info2}}{{.Version}}, {{.Size}}{{end}}	{{.Change}}{{end}}{{with more .Diff.InfoDiff}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
+const NodeDiffOutput = `
+-----{{.DiffType}}-----
+{{if not .Diff}}
+No package differences between {{.Image1}} and {{.Image2}}
+{{end}}{{range .Diff}}
+{{if .Global}}Global packages in {{.Project}}{{else}}Project {{.Project}}{{end}}:
+
+Packages found only in {{$.Image1}}:{{if not .Packages1}} None{{else}}
+NAME	VERSION	SIZE	INSTALLATION{{range limit .Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}	{{.Path}}{{end}}{{with more .Packages1}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Packages found only in {{$.Image2}}:{{if not .Packages2}} None{{else}}
+NAME	VERSION	SIZE	INSTALLATION{{range limit .Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}	{{.Path}}{{end}}{{with more .Packages2}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Version differences:{{if not .InfoDiff}} None{{else}}
+PACKAGE	IMAGE1 ({{$.Image1}})	IMAGE2 ({{$.Image2}})	CHANGE{{range limit .InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{range .Info1}}{{.Version}}, {{.Size}}{{end}}	{{range .Info2}}{{.Version}}, {{.Size}}{{end}}	{{.Change}}{{end}}{{with more .InfoDiff}}{{"\n"}}{{.}}{{end}}{{end}}
+{{end}}`
+
 const HistoryDiffOutput = `
 -----{{.DiffType}}-----
 
-Docker history lines found only in {{.Image1}}:{{if not .Diff.Adds}} None{{else}}{{block "list" .Diff.Dels}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{end}}
+Docker history lines found only in {{.Image1}}:{{if not .Diff.Adds}} None{{else}}{{block "list" limit .Diff.Dels}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{with more .Diff.Dels}}{{.}}{{"\n"}}{{end}}{{end}}
 
-Docker history lines found only in {{.Image2}}:{{if not .Diff.Dels}} None{{else}}{{block "list2" .Diff.Adds}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{end}}
+Docker history lines found only in {{.Image2}}:{{if not .Diff.Dels}} None{{else}}{{block "list2" limit .Diff.Adds}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{with more .Diff.Adds}}{{.}}{{"\n"}}{{end}}{{end}}
 `
 
 const MetadataDiffOutput = `
 -----{{.DiffType}}-----
 
-Image metadata differences between {{.Image1}} and {{.Image2}}:
-
-{{.Image1}}{{if not .Diff.Adds}} None{{else}}{{block "list" .Diff.Adds}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{end}}
-
-{{.Image2}}{{if not .Diff.Dels}} None{{else}}{{block "list2" .Diff.Dels}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{end}}
+Image config differences between {{.Image1}} and {{.Image2}}:{{if not .Diff.Changes}} None{{else}}
+FIELD	KEY	CHANGE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changes}}{{"\n"}}{{print "-"}}{{.Field}}	{{.Key}}	{{.Op}}	{{if eq .Op "added"}}unset{{else}}{{with .Value1}}{{.}}{{else}}""{{end}}{{end}}	{{if eq .Op "removed"}}unset{{else}}{{with .Value2}}{{.}}{{else}}""{{end}}{{end}}{{end}}{{with more .Diff.Changes}}{{"\n"}}{{.}}{{end}}{{end}}
 `
 
 const FilenameDiffOutput = `
@@ -104,7 +127,7 @@ const SizeDiffOutput = `
 -----{{.DiffType}}-----
 
 Image size difference between {{.Image1}} and {{.Image2}}:{{if not .Diff}} None{{else}}
-SIZE1	SIZE2{{range .Diff}}{{"\n"}}{{.Size1}}	{{.Size2}}{{end}}
+SIZE1	SIZE2	COMPRESSED1	COMPRESSED2	CHANGE{{range limit .Diff}}{{"\n"}}{{.Size1}}	{{.Size2}}	{{.CompressedSize1}}	{{.CompressedSize2}}	{{.Change}}{{end}}{{with more .Diff}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -112,21 +135,21 @@ const SizeLayerDiffOutput = `
 -----{{.DiffType}}-----
 
 Layer size differences between {{.Image1}} and {{.Image2}}:{{if not .Diff}} None{{else}}
-LAYER	SIZE1	SIZE2{{range .Diff}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}
+LAYER	SIZE1	SIZE2	COMPRESSED1	COMPRESSED2	CHANGE{{range limit .Diff}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}	{{.CompressedSize1}}	{{.CompressedSize2}}	{{.Change}}{{end}}{{with more .Diff}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
 const ListAnalysisOutput = `
 -----{{.AnalyzeType}}-----
 
-Analysis for {{.Image}}:{{if not .Analysis}} None{{else}}{{block "list" .Analysis}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{end}}
+Analysis for {{.Image}}:{{if not .Analysis}} None{{else}}{{block "list" limit .Analysis}}{{"\n"}}{{range .}}{{print "-" .}}{{"\n"}}{{end}}{{end}}{{with more .Analysis}}{{.}}{{"\n"}}{{end}}{{end}}
 `
 
 const FileAnalysisOutput = `
 -----{{.AnalyzeType}}-----
 
 Analysis for {{.Image}}:{{if not .Analysis}} None{{else}}
-FILE	SIZE{{range .Analysis}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}
+FILE	SIZE{{range limit .Analysis}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -135,7 +158,7 @@ const FileLayerAnalysisOutput = `
 {{range $index, $analysis := .Analysis}}
 
 Analysis for {{$.Image}} Layer {{$index}}:{{if not $analysis}} None{{else}}
-FILE	SIZE{{range $analysis}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}
+FILE	SIZE{{range limit $analysis}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more $analysis}}{{"\n"}}{{.}}{{end}}
 {{end}}
 {{end}}
 `
@@ -144,7 +167,7 @@ const SizeAnalysisOutput = `
 -----{{.AnalyzeType}}-----
 
 Analysis for {{.Image}}:{{if not .Analysis}} None{{else}}
-IMAGE	DIGEST	SIZE{{range .Analysis}}{{"\n"}}{{.Name}}	{{.Digest}}	{{.Size}}{{end}}
+IMAGE	DIGEST	SIZE	COMPRESSED{{range limit .Analysis}}{{"\n"}}{{.Name}}	{{.Digest}}	{{.Size}}	{{.CompressedSize}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -152,7 +175,7 @@ const SizeLayerAnalysisOutput = `
 -----{{.AnalyzeType}}-----
 
 Analysis for {{.Image}}:{{if not .Analysis}} None{{else}}
-LAYER	DIGEST	SIZE{{range .Analysis}}{{"\n"}}{{.Name}}	{{.Digest}}	{{.Size}}{{end}}
+LAYER	DIGEST	SIZE	COMPRESSED{{range limit .Analysis}}{{"\n"}}{{.Name}}	{{.Digest}}	{{.Size}}	{{.CompressedSize}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -160,7 +183,17 @@ const MultiVersionPackageOutput = `
 -----{{.AnalyzeType}}-----
 
 Packages found in {{.Image}}:{{if not .Analysis}} None{{else}}
-NAME	VERSION	SIZE	INSTALLATION{{range .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}	{{.Path}}{{end}}
+NAME	VERSION	SIZE	INSTALLATION{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}	{{.Path}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const NodePackageOutput = `
+-----{{.AnalyzeType}}-----
+
+Packages found in {{.Image}}:{{if not .Analysis}} None{{else}}{{range .Analysis}}
+
+{{if .Global}}Global packages in {{.Project}}{{else}}Project {{.Project}}{{end}}:
+NAME	VERSION	SIZE	INSTALLATION{{range limit .Packages}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}	{{.Path}}{{end}}{{with more .Packages}}{{"\n"}}{{.}}{{end}}{{end}}
 {{end}}
 `
 
@@ -168,7 +201,7 @@ const SingleVersionPackageOutput = `
 -----{{.AnalyzeType}}-----
 
 Packages found in {{.Image}}:{{if not .Analysis}} None{{else}}
-NAME	VERSION	SIZE{{range .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}
+NAME	VERSION	SIZE{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
 {{end}}
 `
 
@@ -177,13 +210,610 @@ const SingleVersionPackageLayerOutput = `
 {{range $index, $analysis := .Analysis}}
 For Layer {{$index}}:{{if not (or (or $analysis.Packages1 $analysis.Packages2) $analysis.InfoDiff)}} No package changes {{else}}
 {{if ne $index 0}}Deleted packages from previous layers:{{if not $analysis.Packages1}} None{{else}}
-NAME	VERSION	SIZE{{range $analysis.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit $analysis.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more $analysis.Packages1}}{{"\n"}}{{.}}{{end}}{{end}}
 
 {{end}}Packages added in this layer:{{if not $analysis.Packages2}} None{{else}}
-NAME	VERSION	SIZE{{range $analysis.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{end}}
+NAME	VERSION	SIZE{{range limit $analysis.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{.Size}}{{end}}{{with more $analysis.Packages2}}{{"\n"}}{{.}}{{end}}{{end}}
 {{if ne $index 0}}
 Version differences:{{if not $analysis.InfoDiff}} None{{else}}
-PACKAGE	PREV_LAYER	CURRENT_LAYER {{range $analysis.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{.Info1.Version}}, {{.Info1.Size}}	{{.Info2.Version}}, {{.Info2.Size}}{{end}}
+PACKAGE	PREV_LAYER	CURRENT_LAYER {{range limit $analysis.InfoDiff}}{{"\n"}}{{print "-"}}{{.Package}}	{{.Info1.Version}}, {{.Info1.Size}}	{{.Info2.Version}}, {{.Info2.Size}}{{end}}{{with more $analysis.InfoDiff}}{{"\n"}}{{.}}{{end}}
 {{end}}{{end}}{{end}}
 {{end}}
 `
+
+const AlternativesAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Alternatives found in {{.Image}}:{{if not .Analysis}} None{{else}}
+NAME	MODE	SELECTED	PROVIDERS{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Mode}}	{{.Selected}}	{{join .Providers ", "}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const AlternativesDiffOutput = `
+-----{{.DiffType}}-----
+
+Alternatives found only in {{.Image1}}:{{if not .Diff.Dels}} None{{else}}
+NAME	MODE	SELECTED{{range limit .Diff.Dels}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Mode}}	{{.Selected}}{{end}}{{with more .Diff.Dels}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Alternatives found only in {{.Image2}}:{{if not .Diff.Adds}} None{{else}}
+NAME	MODE	SELECTED{{range limit .Diff.Adds}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Mode}}	{{.Selected}}{{end}}{{with more .Diff.Adds}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Selection differences:{{if not .Diff.Mods}} None{{else}}
+NAME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Mods}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Selected1}} ({{.Mode1}})	{{.Selected2}} ({{.Mode2}}){{end}}{{with more .Diff.Mods}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const AdvisoryAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Advisories affecting packages in {{.Image}}:{{if not .Analysis}} None{{else}}
+ID	PACKAGE	VERSION	SEVERITY	FIXED	SUMMARY{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.ID}}	{{with .Ecosystem}}{{.}}/{{end}}{{.Package}}	{{.Version}}	{{.Severity}}	{{.Fixed}}	{{.Summary}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const AdvisoryDiffOutput = `
+-----{{.DiffType}}-----
+
+Advisories introduced in {{.Image2}}:{{if not .Diff.Introduced}} None{{else}}
+ID	PACKAGE	VERSION	SEVERITY	FIXED	SUMMARY{{range limit .Diff.Introduced}}{{"\n"}}{{print "-"}}{{.ID}}	{{with .Ecosystem}}{{.}}/{{end}}{{.Package}}	{{.Version}}	{{.Severity}}	{{.Fixed}}	{{.Summary}}{{end}}{{with more .Diff.Introduced}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Advisories resolved from {{.Image1}}:{{if not .Diff.Resolved}} None{{else}}
+ID	PACKAGE	VERSION	SEVERITY	FIXED	SUMMARY{{range limit .Diff.Resolved}}{{"\n"}}{{print "-"}}{{.ID}}	{{with .Ecosystem}}{{.}}/{{end}}{{.Package}}	{{.Version}}	{{.Severity}}	{{.Fixed}}	{{.Summary}}{{end}}{{with more .Diff.Resolved}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const LayerSuggestDiffOutput = `
+-----{{.DiffType}}-----
+{{if lt .Diff.FirstChangedLayer 0}}
+{{.Image2}} reuses every layer of {{.Image1}}.
+{{else}}
+{{.Diff.RebuiltLayers}} layer(s) of {{.Image2}} were rebuilt ({{.Diff.RebuiltBytes}}), starting at layer {{.Diff.FirstChangedLayer}}:{{if .Diff.ChangedCommand}} {{.Diff.ChangedCommand}}{{end}}
+
+Layers rebuilt with unchanged contents:{{if not .Diff.Suggestions}} None{{else}}
+LAYER	COMMAND	SAVED{{range limit .Diff.Suggestions}}{{"\n"}}{{print "-"}}{{.Layer}}	{{.Command}}	{{.SavedBytes}}{{end}}{{with more .Diff.Suggestions}}{{"\n"}}{{.}}{{end}}
+
+Moving these instructions before layer {{.Diff.FirstChangedLayer}} would let them be reused from cache, saving an estimated {{.Diff.SavedBytes}}.{{end}}
+{{end}}`
+
+const ReproAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Reproducibility risks found in {{.Image}}:{{if not .Analysis}} None{{else}}
+PRIORITY	ISSUE	ITEMS	SIZE	FIX{{range limit .Analysis}}{{"\n"}}{{.Priority}}	{{.Issue}}	{{len .Items}}	{{.Size}}	{{.Fix}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const ReproDiffOutput = `
+-----{{.DiffType}}-----
+
+Differences between {{.Image1}} and {{.Image2}}, in the order to fix them:{{if .Diff.Identical}} None, the images are identical{{else if not .Diff.Findings}} None found{{else}}
+PRIORITY	ISSUE	ITEMS	SIZE	FIX{{range limit .Diff.Findings}}{{"\n"}}{{.Priority}}	{{.Issue}}	{{len .Items}}	{{.Size}}	{{.Fix}}{{end}}{{with more .Diff.Findings}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const InitAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Init configuration of {{.Image}}:
+CATEGORY	FIELD	VALUE{{range .Analysis.Settings}}{{"\n"}}{{print "-"}}{{.Category}}	{{.Field}}	{{or .Value "unset"}}{{end}}
+
+Warnings:{{if not .Analysis.Warnings}} None{{else}}{{range limit .Analysis.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Analysis.Warnings}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const InitDiffOutput = `
+-----{{.DiffType}}-----
+
+Init configuration differences between {{.Image1}} and {{.Image2}}:{{if not .Diff.Changes}} None{{else}}
+CATEGORY	FIELD	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changes}}{{"\n"}}{{print "-"}}{{.Category}}	{{.Field}}	{{or .Value1 "unset"}}	{{or .Value2 "unset"}}{{end}}{{with more .Diff.Changes}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Warnings introduced in {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range limit .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Warnings}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const ScoreAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Scorecard for {{.Image}}: {{.Analysis.Score}}/100
+CATEGORY	SCORE	VALUE{{range .Analysis.Categories}}{{"\n"}}{{print "-"}}{{.Category}}	{{.Score}}	{{.Value}}{{end}}
+`
+
+const ScoreDiffOutput = `
+-----{{.DiffType}}-----
+
+Scorecard for {{.Image1}}: {{.Diff.Score1}}/100, {{.Image2}}: {{.Diff.Score2}}/100 ({{.Diff.Delta}})
+CATEGORY	SCORE1	SCORE2	DELTA	VALUE1	VALUE2{{range .Diff.Categories}}{{"\n"}}{{print "-"}}{{.Category}}	{{.Score1}}	{{.Score2}}	{{.Delta}}	{{.Value1}}	{{.Value2}}{{end}}
+`
+
+const AptDepsAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Root packages of {{.Image}}, which no other package depends on:{{if not .Analysis.Roots}} None{{else}}{{range limit .Analysis.Roots}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Analysis.Roots}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Packages apt-get autoremove would remove:{{if not .Analysis.Removable}} None{{else}}{{range limit .Analysis.Removable}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Analysis.Removable}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const AptDepsDiffOutput = `
+-----{{.DiffType}}-----
+
+New root packages in {{.Image2}}:{{if not .Diff.NewRoots}} None{{else}}{{range limit .Diff.NewRoots}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.NewRoots}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Packages added in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+PACKAGE	REQUIRED BY{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Name}}	{{if .RequiredBy}}{{join .RequiredBy ", "}}{{else}}(root){{end}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Packages whose reverse dependencies changed:{{if not .Diff.ReverseDepsChanged}} None{{else}}
+PACKAGE	NOW REQUIRED BY	NO LONGER REQUIRED BY{{range limit .Diff.ReverseDepsChanged}}{{"\n"}}{{print "-"}}{{.Name}}	{{join .Added ", "}}	{{join .Removed ", "}}{{end}}{{with more .Diff.ReverseDepsChanged}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Packages of {{.Image2}} apt-get autoremove would remove:{{if not .Diff.Removable}} None{{else}}{{range limit .Diff.Removable}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Removable}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const SystemdAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Systemd units found in {{.Image}}:{{if not .Analysis}} None{{else}}
+UNIT	STATE	WANTED BY	PRESET{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Unit}}	{{.State}}	{{join .WantedBy ", "}}	{{.Preset}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const SystemdDiffOutput = `
+-----{{.DiffType}}-----
+
+Units newly enabled in {{.Image2}}:{{if not .Diff.Enabled}} None{{else}}{{range limit .Diff.Enabled}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Enabled}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Units no longer enabled in {{.Image2}}:{{if not .Diff.Disabled}} None{{else}}{{range limit .Diff.Disabled}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Disabled}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Unit state differences:{{if not .Diff.Changes}} None{{else}}
+UNIT	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changes}}{{"\n"}}{{print "-"}}{{.Unit}}	{{or .State1 "absent"}}{{with .WantedBy1}} ({{join . ", "}}){{end}}{{with .Preset1}} [preset {{.}}]{{end}}	{{or .State2 "absent"}}{{with .WantedBy2}} ({{join . ", "}}){{end}}{{with .Preset2}} [preset {{.}}]{{end}}{{end}}{{with more .Diff.Changes}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const RuntimesAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Language runtimes found in {{.Image}}:{{if not .Analysis}} None{{else}}
+RUNTIME	VERSION	DEFAULT	PATH{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Runtime}}	{{.Version}}	{{if .Default}}yes{{else}}no{{end}}	{{.Path}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const RuntimesDiffOutput = `
+-----{{.DiffType}}-----
+
+Language runtime differences between {{.Image1}} and {{.Image2}} (* marks the default):{{if not .Diff}} None{{else}}
+RUNTIME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff}}{{"\n"}}{{print "-"}}{{.Runtime}}	{{.Versions1}}	{{.Versions2}}{{end}}{{with more .Diff}}{{"\n"}}{{.}}{{end}}
+{{end}}
+`
+
+const ProvenanceAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Provenance of {{.Image}}:
+Image digest {{.Analysis.Digest}}: {{if .Analysis.ImageVerified}}verified{{else}}not an attested subject{{end}}
+Builders: {{if not .Analysis.Builders}}None{{else}}{{join .Analysis.Builders ", "}}{{end}}
+LAYER	DIGEST	PROVENANCE	CLAIM{{range limit .Analysis.Layers}}{{"\n"}}{{print "-"}}{{.Index}}	{{.Digest}}	{{.Status}}	{{.Claim}}{{end}}{{with more .Analysis.Layers}}{{"\n"}}{{.}}{{end}}
+`
+
+const ProvenanceDiffOutput = `
+-----{{.DiffType}}-----
+
+Provenance of {{.Image1}}: {{if .Diff.ImageVerified1}}verified{{else}}not an attested subject{{end}}
+Provenance of {{.Image2}}: {{if .Diff.ImageVerified2}}verified{{else}}not an attested subject{{end}}
+
+Layers of {{.Image2}}:
+LAYER	DIGEST	PROVENANCE	CLAIM{{range limit .Diff.Layers}}{{"\n"}}{{print "-"}}{{.Index}}	{{.Digest}}	{{.Status}}	{{.Claim}}{{end}}{{with more .Diff.Layers}}{{"\n"}}{{.}}{{end}}
+`
+
+const IdenticalDiffOutput = `
+-----{{.DiffType}}-----
+
+{{.Image1}} and {{.Image2}} are identical: {{.Diff.Reason}}
+`
+
+const PartialDiffOutput = `
+-----{{.DiffType}}-----
+
+Could not diff {{.Image1}} and {{.Image2}}, only {{.Diff.AnalyzedImage}} was analyzed:
+{{.Diff.Error}}
+`
+
+const PlatformDiffOutput = `
+-----{{.DiffType}}-----
+
+Platforms of {{.Image1}} and {{.Image2}}:
+PLATFORM	STATUS	DIGEST1	DIGEST2{{range .Diff}}{{"\n"}}{{print "-"}}{{.Platform}}	{{.Status}}	{{if .Digest1}}{{.Digest1}}{{else}}-{{end}}	{{if .Digest2}}{{.Digest2}}{{else}}-{{end}}{{end}}
+`
+
+const RPMRepoAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Repositories configured in {{.Image}}:{{if not .Analysis.Repos}} None{{else}}
+REPO	ENABLED	GPGCHECK	THIRD-PARTY	URL{{range limit .Analysis.Repos}}{{"\n"}}{{print "-"}}{{.ID}}	{{if .Enabled}}yes{{else}}no{{end}}	{{if .GPGCheck}}yes{{else}}no{{end}}	{{if .ThirdParty}}yes{{else}}no{{end}}	{{.URL}}{{end}}{{with more .Analysis.Repos}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Module streams in {{.Image}}:{{if not .Analysis.Modules}} None{{else}}
+MODULE	STREAM	STATE	PROFILES{{range limit .Analysis.Modules}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Stream}}	{{.State}}	{{join .Profiles ", "}}{{end}}{{with more .Analysis.Modules}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const RPMRepoDiffOutput = `
+-----{{.DiffType}}-----
+
+Warnings for {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Repositories added in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+REPO	ENABLED	GPGCHECK	THIRD-PARTY	URL{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.ID}}	{{if .Enabled}}yes{{else}}no{{end}}	{{if .GPGCheck}}yes{{else}}no{{end}}	{{if .ThirdParty}}yes{{else}}no{{end}}	{{.URL}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Repositories removed from {{.Image2}}:{{if not .Diff.Removed}} None{{else}}{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.ID}}	{{.URL}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Repositories changed:{{if not .Diff.Changed}} None{{else}}
+REPO	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.ID}}	{{with .Repo1}}{{if .Enabled}}enabled{{else}}disabled{{end}}, gpgcheck {{if .GPGCheck}}on{{else}}off{{end}}, {{.URL}}{{end}}	{{with .Repo2}}{{if .Enabled}}enabled{{else}}disabled{{end}}, gpgcheck {{if .GPGCheck}}on{{else}}off{{end}}, {{.URL}}{{end}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Module stream differences:{{if not .Diff.Modules}} None{{else}}
+MODULE	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Modules}}{{"\n"}}{{print "-"}}{{.Name}}	{{if .State1}}{{with .Stream1}}{{.}} {{end}}({{.State1}}){{else}}absent{{end}}	{{if .State2}}{{with .Stream2}}{{.}} {{end}}({{.State2}}){{else}}absent{{end}}{{end}}{{with more .Diff.Modules}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const KeyringAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Signing keys in {{.Image}}:{{if not .Analysis}} None{{else}}
+FINGERPRINT	ALGORITHM	CREATED	USER ID	KEYRINGS{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Fingerprint}}	{{.Algorithm}}	{{.Created}}	{{join .UserIDs ", "}}	{{join .Keyrings ", "}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const KeyringDiffOutput = `
+-----{{.DiffType}}-----
+
+Warnings for {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Keys added in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+FINGERPRINT	ALGORITHM	CREATED	USER ID	KEYRINGS{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Fingerprint}}	{{.Algorithm}}	{{.Created}}	{{join .UserIDs ", "}}	{{join .Keyrings ", "}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Keys removed from {{.Image2}}:{{if not .Diff.Removed}} None{{else}}
+FINGERPRINT	ALGORITHM	CREATED	USER ID	KEYRINGS{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Fingerprint}}	{{.Algorithm}}	{{.Created}}	{{join .UserIDs ", "}}	{{join .Keyrings ", "}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Keys changed:{{if not .Diff.Changed}} None{{else}}
+FINGERPRINT	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Fingerprint}}	{{with .Key1}}{{join .UserIDs ", "}} in {{join .Keyrings ", "}}{{end}}	{{with .Key2}}{{join .UserIDs ", "}} in {{join .Keyrings ", "}}{{end}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const ServiceAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Services started by {{.Image}}:{{if not .Analysis}} None{{else}}
+SERVICE	MANAGER	STARTED BY	COMMAND	SOURCE{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Manager}}	{{join .StartedBy ", "}}	{{.Command}}	{{.Source}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const ServiceDiffOutput = `
+-----{{.DiffType}}-----
+
+Services started only by {{.Image2}}:{{if not .Diff.Started}} None{{else}}
+SERVICE	MANAGER	STARTED BY	COMMAND	SOURCE{{range limit .Diff.Started}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Manager}}	{{join .StartedBy ", "}}	{{.Command}}	{{.Source}}{{end}}{{with more .Diff.Started}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Services started only by {{.Image1}}:{{if not .Diff.Stopped}} None{{else}}
+SERVICE	MANAGER	STARTED BY	COMMAND	SOURCE{{range limit .Diff.Stopped}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Manager}}	{{join .StartedBy ", "}}	{{.Command}}	{{.Source}}{{end}}{{with more .Diff.Stopped}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Services changed:{{if not .Diff.Changed}} None{{else}}
+SERVICE	MANAGER	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Manager}}	{{with .Service1}}{{.Command}} ({{join .StartedBy ", "}}){{end}}	{{with .Service2}}{{.Command}} ({{join .StartedBy ", "}}){{end}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const CronAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Jobs scheduled in {{.Image}}:{{if not .Analysis}} None{{else}}
+SCHEDULE	USER	COMMAND	SOURCE{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Schedule}}	{{.User}}	{{.Command}}	{{.Source}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const CronDiffOutput = `
+-----{{.DiffType}}-----
+
+Jobs scheduled only in {{.Image1}}:{{if not .Diff.Removed}} None{{else}}
+SCHEDULE	USER	COMMAND	SOURCE{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Schedule}}	{{.User}}	{{.Command}}	{{.Source}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Jobs scheduled only in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+SCHEDULE	USER	COMMAND	SOURCE{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Schedule}}	{{.User}}	{{.Command}}	{{.Source}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Schedule changes:{{if not .Diff.Changed}} None{{else}}
+COMMAND	USER	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}})	SOURCE{{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Command}}	{{.User}}	{{.Schedule1}}	{{.Schedule2}}	{{.Source}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const UserAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Users in {{.Image}}:{{if not .Analysis.Users}} None{{else}}
+NAME	UID	GID	GROUPS	HOME	SHELL	SHADOW{{range limit .Analysis.Users}}{{"\n"}}{{print "-"}}{{.Name}}	{{.UID}}	{{.GID}}	{{join .Groups ", "}}	{{.Home}}	{{.Shell}}	{{if .Shadow}}yes{{else}}no{{end}}{{end}}{{with more .Analysis.Users}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Groups in {{.Image}}:{{if not .Analysis.Groups}} None{{else}}
+NAME	GID	MEMBERS{{range limit .Analysis.Groups}}{{"\n"}}{{print "-"}}{{.Name}}	{{.GID}}	{{join .Members ", "}}{{end}}{{with more .Analysis.Groups}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const UserDiffOutput = `
+-----{{.DiffType}}-----
+
+Warnings for {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Users added in {{.Image2}}:{{if not .Diff.UsersAdded}} None{{else}}
+NAME	UID	GID	GROUPS	HOME	SHELL	SHADOW{{range limit .Diff.UsersAdded}}{{"\n"}}{{print "-"}}{{.Name}}	{{.UID}}	{{.GID}}	{{join .Groups ", "}}	{{.Home}}	{{.Shell}}	{{if .Shadow}}yes{{else}}no{{end}}{{end}}{{with more .Diff.UsersAdded}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Users removed from {{.Image2}}:{{if not .Diff.UsersRemoved}} None{{else}}
+NAME	UID	GID	GROUPS	HOME	SHELL	SHADOW{{range limit .Diff.UsersRemoved}}{{"\n"}}{{print "-"}}{{.Name}}	{{.UID}}	{{.GID}}	{{join .Groups ", "}}	{{.Home}}	{{.Shell}}	{{if .Shadow}}yes{{else}}no{{end}}{{end}}{{with more .Diff.UsersRemoved}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Users changed:{{if not .Diff.UsersChanged}} None{{else}}
+NAME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.UsersChanged}}{{"\n"}}{{print "-"}}{{.Name}}	{{with .User1}}UID {{.UID}}, GID {{.GID}}, groups {{join .Groups ","}}, {{.Home}}, {{.Shell}}{{if not .Shadow}}, no shadow entry{{end}}{{end}}	{{with .User2}}UID {{.UID}}, GID {{.GID}}, groups {{join .Groups ","}}, {{.Home}}, {{.Shell}}{{if not .Shadow}}, no shadow entry{{end}}{{end}}{{end}}{{with more .Diff.UsersChanged}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Groups added in {{.Image2}}:{{if not .Diff.GroupsAdded}} None{{else}}
+NAME	GID	MEMBERS{{range limit .Diff.GroupsAdded}}{{"\n"}}{{print "-"}}{{.Name}}	{{.GID}}	{{join .Members ", "}}{{end}}{{with more .Diff.GroupsAdded}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Groups removed from {{.Image2}}:{{if not .Diff.GroupsRemoved}} None{{else}}
+NAME	GID	MEMBERS{{range limit .Diff.GroupsRemoved}}{{"\n"}}{{print "-"}}{{.Name}}	{{.GID}}	{{join .Members ", "}}{{end}}{{with more .Diff.GroupsRemoved}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Groups changed:{{if not .Diff.GroupsChanged}} None{{else}}
+NAME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.GroupsChanged}}{{"\n"}}{{print "-"}}{{.Name}}	{{with .Group1}}GID {{.GID}}, members {{join .Members ","}}{{end}}	{{with .Group2}}GID {{.GID}}, members {{join .Members ","}}{{end}}{{end}}{{with more .Diff.GroupsChanged}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const CertificateAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Certificates trusted by {{.Image}}:{{if not .Analysis}} None{{else}}
+SUBJECT	NOT BEFORE	NOT AFTER	FINGERPRINT	SOURCES{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Subject}}	{{.NotBefore}}	{{.NotAfter}}	{{.Fingerprint}}	{{join .Sources ", "}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const CertificateDiffOutput = `
+-----{{.DiffType}}-----
+
+Warnings for {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Certificates added in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+SUBJECT	NOT BEFORE	NOT AFTER	FINGERPRINT	SOURCES{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Subject}}	{{.NotBefore}}	{{.NotAfter}}	{{.Fingerprint}}	{{join .Sources ", "}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Certificates removed from {{.Image2}}:{{if not .Diff.Removed}} None{{else}}
+SUBJECT	NOT BEFORE	NOT AFTER	FINGERPRINT	SOURCES{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Subject}}	{{.NotBefore}}	{{.NotAfter}}	{{.Fingerprint}}	{{join .Sources ", "}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Certificates changed:{{if not .Diff.Changed}} None{{else}}
+SUBJECT	FINGERPRINT	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Cert2.Subject}}	{{.Fingerprint}}	{{join .Cert1.Sources ", "}}	{{join .Cert2.Sources ", "}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const BannerAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Banners of {{.Image}}:{{if not .Analysis}} None{{else}}
+PATH	KIND	SIZE	PACKAGE	DEFAULT{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Path}}	{{join .Kinds ", "}}	{{.Size}}	{{.Package}}	{{if .Package}}{{.Default}}{{end}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const BannerDiffOutput = `
+-----{{.DiffType}}-----
+
+Warnings for {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Banners added in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+PATH	KIND	SIZE	PACKAGE	DEFAULT{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Path}}	{{join .Kinds ", "}}	{{.Size}}	{{.Package}}	{{if .Package}}{{.Default}}{{end}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Banners removed from {{.Image2}}:{{if not .Diff.Removed}} None{{else}}
+PATH	KIND	SIZE	PACKAGE	DEFAULT{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Path}}	{{join .Kinds ", "}}	{{.Size}}	{{.Package}}	{{if .Package}}{{.Default}}{{end}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Banners changed:{{if not .Diff.Changed}} None{{else}}{{range .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Name}}{{end}}{{end}}
+`
+
+const EntropyAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Compressibility of {{.Image}}: {{.Analysis.Size}} compresses to {{.Analysis.Compressed}} ({{.Analysis.Ratio}}), {{.Analysis.Duplicate}} stored more than once
+DIRECTORY	FILES	SIZE	COMPRESSED	RATIO	DUPLICATE{{range limit .Analysis.Dirs}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Files}}	{{.Size}}	{{.Compressed}}	{{.Ratio}}	{{.Duplicate}}{{end}}{{with more .Analysis.Dirs}}{{"\n"}}{{.}}{{end}}
+
+Duplicate content:{{if not .Analysis.Duplicates}} None{{else}}
+SIZE	PATHS{{range limit .Analysis.Duplicates}}{{"\n"}}{{print "-"}}{{.Size}}	{{join .Paths ", "}}{{end}}{{with more .Analysis.Duplicates}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const EntropyDiffOutput = `
+-----{{.DiffType}}-----
+
+Compressibility: {{.Image1}} {{.Diff.Size1}} ({{.Diff.Ratio1}} compressed, {{.Diff.Duplicate1}} duplicate), {{.Image2}} {{.Diff.Size2}} ({{.Diff.Ratio2}} compressed, {{.Diff.Duplicate2}} duplicate)
+
+Directories whose compressibility changed:{{if not .Diff.Dirs}} None{{else}}
+DIRECTORY	SIZE1	SIZE2	RATIO1	RATIO2	DUPLICATE1	DUPLICATE2{{range limit .Diff.Dirs}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Size1}}	{{.Size2}}	{{.Ratio1}}	{{.Ratio2}}	{{.Duplicate1}}	{{.Duplicate2}}{{end}}{{with more .Diff.Dirs}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const LinkerAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Library search directories in {{.Image}}:{{if not .Analysis.SearchDirs}} None{{else}}{{range .Analysis.SearchDirs}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{end}}
+
+Libraries in the ld.so.cache of {{.Image}}:{{if not .Analysis.Libraries}} None{{else}}
+LIBRARY	PATH{{range limit .Analysis.Libraries}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Path}}{{if not .Target}} (missing){{else if ne .Target .Path}} -> {{.Target}}{{end}}{{end}}{{with more .Analysis.Libraries}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Commands on the PATH of {{.Image}}:{{if not .Analysis.Commands}} None{{else}}
+COMMAND	PATH{{range limit .Analysis.Commands}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Path}}{{if ne .Target .Path}} -> {{.Target}}{{end}}{{end}}{{with more .Analysis.Commands}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const LinkerDiffOutput = `
+-----{{.DiffType}}-----
+
+Library search directories:{{if not (or .Diff.SearchDirs1 .Diff.SearchDirs2)}} None{{else}}
+{{.Image1}}: {{join .Diff.SearchDirs1 ", "}}
+{{.Image2}}: {{join .Diff.SearchDirs2 ", "}}{{end}}
+
+Libraries resolved differently:{{if not .Diff.Libraries}} None{{else}}
+LIBRARY	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Libraries}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Path1}}{{if not .Target1}} (missing){{else if ne .Target1 .Path1}} -> {{.Target1}}{{end}}	{{.Path2}}{{if not .Target2}} (missing){{else if ne .Target2 .Path2}} -> {{.Target2}}{{end}}{{end}}{{with more .Diff.Libraries}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Commands resolved differently:{{if not .Diff.Commands}} None{{else}}
+COMMAND	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Commands}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Path1}}{{if ne .Target1 .Path1}} -> {{.Target1}}{{end}}	{{.Path2}}{{if ne .Target2 .Path2}} -> {{.Target2}}{{end}}{{end}}{{with more .Diff.Commands}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const ConffileAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Packages removed with their configuration left in {{.Image}}:{{if not .Analysis.Packages}} None{{else}}
+NAME	VERSION	CONFFILES{{range limit .Analysis.Packages}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{len .Conffiles}}{{end}}{{with more .Analysis.Packages}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Leftover configuration files in {{.Image}}:{{if not .Analysis.Files}} None{{else}} {{.Analysis.Size}} in total
+FILE	PACKAGE	REASON	SIZE{{range limit .Analysis.Files}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Package}}	{{.Reason}}	{{.Size}}{{end}}{{with more .Analysis.Files}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const ConffileDiffOutput = `
+-----{{.DiffType}}-----
+
+Removed packages with configuration left only in {{.Image1}}:{{if not .Diff.Packages1}} None{{else}}
+NAME	VERSION	CONFFILES{{range limit .Diff.Packages1}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{len .Conffiles}}{{end}}{{with more .Diff.Packages1}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Removed packages with configuration left only in {{.Image2}}:{{if not .Diff.Packages2}} None{{else}}
+NAME	VERSION	CONFFILES{{range limit .Diff.Packages2}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Version}}	{{len .Conffiles}}{{end}}{{with more .Diff.Packages2}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Leftover configuration files only in {{.Image1}}:{{if not .Diff.Files1}} None{{else}}
+FILE	PACKAGE	REASON	SIZE{{range limit .Diff.Files1}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Package}}	{{.Reason}}	{{.Size}}{{end}}{{with more .Diff.Files1}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Leftover configuration files only in {{.Image2}}:{{if not .Diff.Files2}} None{{else}}
+FILE	PACKAGE	REASON	SIZE{{range limit .Diff.Files2}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Package}}	{{.Reason}}	{{.Size}}{{end}}{{with more .Diff.Files2}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const OSAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Distribution of {{.Image}}:{{if not (or .Analysis.Source .Analysis.Lineage)}} Unknown, no release files found{{else}}
+FIELD	VALUE{{range .Analysis.Fields}}{{"\n"}}{{print "-"}}{{.Field}}	{{or .Value "unknown"}}{{end}}{{end}}
+`
+
+const OSDiffOutput = `
+-----{{.DiffType}}-----
+
+Distribution differences between {{.Image1}} and {{.Image2}}:{{if not .Diff.Changes}} None{{else}}
+FIELD	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range .Diff.Changes}}{{"\n"}}{{print "-"}}{{.Field}}	{{or .Value1 "unknown"}}	{{or .Value2 "unknown"}}{{end}}{{end}}
+`
+
+const BuildInfoAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Build information of {{.Image}}:
+FIELD	VALUE{{range .Analysis.KeyFields}}{{"\n"}}{{print "-"}}{{.Name}}	{{or .Value "unknown"}}{{end}}
+
+Annotations:{{if not .Analysis.Annotations}} None{{else}}
+NAME	VALUE	SOURCE{{range limit .Analysis.Annotations}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Value}}	{{.Source}}{{end}}{{with more .Analysis.Annotations}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Build args:{{if not .Analysis.BuildArgs}} None{{else}}
+NAME	VALUE	SOURCE{{range limit .Analysis.BuildArgs}}{{"\n"}}{{print "-"}}{{.Name}}	{{or .Value "unset"}}	{{.Source}}{{end}}{{with more .Analysis.BuildArgs}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const BuildInfoDiffOutput = `
+-----{{.DiffType}}-----
+
+Revision, source, version and build URL changes between {{.Image1}} and {{.Image2}}:{{if not .Diff.KeyChanges}} None{{else}}
+FIELD	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range .Diff.KeyChanges}}{{"\n"}}{{print "!"}}{{.Name}}	{{or .Value1 "unknown"}}	{{or .Value2 "unknown"}}{{end}}{{end}}
+
+Annotations differing between {{.Image1}} and {{.Image2}}:{{if not .Diff.Annotations}} None{{else}}
+NAME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.Annotations}}{{"\n"}}{{print "-"}}{{.Name}}	{{or .Value1 "unset"}}	{{or .Value2 "unset"}}{{end}}{{with more .Diff.Annotations}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Build args differing between {{.Image1}} and {{.Image2}}:{{if not .Diff.BuildArgs}} None{{else}}
+NAME	IMAGE1 ({{.Image1}})	IMAGE2 ({{.Image2}}){{range limit .Diff.BuildArgs}}{{"\n"}}{{print "-"}}{{.Name}}	{{or .Value1 "unset"}}	{{or .Value2 "unset"}}{{end}}{{with more .Diff.BuildArgs}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const PermissionAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Setuid, setgid and world-writable files in {{.Image}}:{{if not .Analysis}} None{{else}}
+NAME	MODE	UID	GID	FLAGS{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Mode}}	{{.Uid}}	{{.Gid}}	{{join .Flags ", "}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const PermissionDiffOutput = `
+-----{{.DiffType}}-----
+
+Permission warnings introduced in {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range limit .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Warnings}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Mode and owner changes between {{.Image1}} and {{.Image2}}:{{if not .Diff.Changes}} None{{else}}
+NAME	MODE1	MODE2	OWNER1	OWNER2{{range limit .Diff.Changes}}{{"\n"}}{{print "-"}}{{.Name}}	{{.Mode1}}	{{.Mode2}}	{{.Owner1}}	{{.Owner2}}{{end}}{{with more .Diff.Changes}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const VolumeAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Content below the VOLUME paths of {{.Image}}:{{if not .Analysis.Volumes}} None{{else}}
+VOLUME	FILES	SIZE{{range limit .Analysis.Volumes}}{{"\n"}}{{print "-"}}{{.Volume}}	{{.Files}}	{{.Size}}{{end}}{{with more .Analysis.Volumes}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Files shipped below volumes, hidden by the volumes mounted there:{{if not .Analysis.Files}} None{{else}}
+FILE	SIZE{{range limit .Analysis.Files}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Analysis.Files}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const VolumeDiffOutput = `
+-----{{.DiffType}}-----
+
+Volume warnings introduced in {{.Image2}}:{{if not .Diff.Warnings}} None{{else}}{{range limit .Diff.Warnings}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.Warnings}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Volumes declared only in {{.Image1}}:{{if not .Diff.RemovedVolumes}} None{{else}}{{range limit .Diff.RemovedVolumes}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.RemovedVolumes}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Volumes declared only in {{.Image2}}:{{if not .Diff.AddedVolumes}} None{{else}}{{range limit .Diff.AddedVolumes}}{{"\n"}}{{print "-"}}{{.}}{{end}}{{with more .Diff.AddedVolumes}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Files below volumes only in {{.Image2}}:{{if not .Diff.Adds}} None{{else}}
+FILE	SIZE{{range limit .Diff.Adds}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Diff.Adds}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Files below volumes only in {{.Image1}}:{{if not .Diff.Dels}} None{{else}}
+FILE	SIZE{{range limit .Diff.Dels}}{{"\n"}}{{.Name}}	{{.Size}}{{end}}{{with more .Diff.Dels}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Files below volumes changed between {{.Image1}} and {{.Image2}}:{{if not .Diff.Mods}} None{{else}}
+FILE	SIZE1	SIZE2{{range limit .Diff.Mods}}{{"\n"}}{{.Name}}	{{.Size1}}	{{.Size2}}{{end}}{{with more .Diff.Mods}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const SharedLayerDiffOutput = `
+-----{{.DiffType}}-----
+
+Layers shared by {{.Image1}} and {{.Image2}} ({{.Diff.SharedSize}} compressed):{{if not .Diff.Shared}} None{{else}}
+LAYER1	LAYER2	DIGEST	SIZE{{range limit .Diff.Shared}}{{"\n"}}{{.Index1}}	{{.Index2}}	{{.Digest}}	{{.Size}}{{end}}{{with more .Diff.Shared}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Layers only in {{.Image1}}:{{if not .Diff.Unique1}} None{{else}}
+LAYER	DIGEST	SIZE{{range limit .Diff.Unique1}}{{"\n"}}{{.Index}}	{{.Digest}}	{{.Size}}{{end}}{{with more .Diff.Unique1}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Layers only in {{.Image2}}:{{if not .Diff.Unique2}} None{{else}}
+LAYER	DIGEST	SIZE{{range limit .Diff.Unique2}}{{"\n"}}{{.Index}}	{{.Digest}}	{{.Size}}{{end}}{{with more .Diff.Unique2}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const SecretAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Likely secrets in {{.Image}}:{{if not .Analysis}} None{{else}}
+PATH	LINE	KIND	MATCH{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Line}}	{{.Kind}}	{{.Match}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const SecretDiffOutput = `
+-----{{.DiffType}}-----
+
+Secrets introduced in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+PATH	LINE	KIND	MATCH{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Line}}	{{.Kind}}	{{.Match}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Secrets only in {{.Image1}}:{{if not .Diff.Removed}} None{{else}}
+PATH	LINE	KIND	MATCH{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Line}}	{{.Kind}}	{{.Match}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const BinaryAnalysisOutput = `
+-----{{.AnalyzeType}}-----
+
+Dynamically linked ELF files in {{.Image}}:{{if not .Analysis}} None{{else}}
+PATH	SONAME	NEEDED{{range limit .Analysis}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Soname}}	{{join .Needed ", "}}{{end}}{{with more .Analysis}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const BinaryDiffOutput = `
+-----{{.DiffType}}-----
+
+SONAME bumps between {{.Image1}} and {{.Image2}}:{{if not .Diff.Bumps}} None{{else}}
+LIBRARY	SONAME1	SONAME2	STILL NEEDED BY{{range limit .Diff.Bumps}}{{"\n"}}{{print "-"}}{{.Library}}	{{join .Sonames1 ", "}}	{{join .Sonames2 ", "}}	{{join .Dependents ", "}}{{end}}{{with more .Diff.Bumps}}{{"\n"}}{{.}}{{end}}{{end}}
+
+ELF files only in {{.Image1}}:{{if not .Diff.Removed}} None{{else}}
+PATH	SONAME	NEEDED{{range limit .Diff.Removed}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Soname}}	{{join .Needed ", "}}{{end}}{{with more .Diff.Removed}}{{"\n"}}{{.}}{{end}}{{end}}
+
+ELF files only in {{.Image2}}:{{if not .Diff.Added}} None{{else}}
+PATH	SONAME	NEEDED{{range limit .Diff.Added}}{{"\n"}}{{print "-"}}{{.Path}}	{{.Soname}}	{{join .Needed ", "}}{{end}}{{with more .Diff.Added}}{{"\n"}}{{.}}{{end}}{{end}}
+
+Linked libraries changed between {{.Image1}} and {{.Image2}}:{{if not .Diff.Changed}} None{{else}}
+PATH	ADDED	REMOVED{{range limit .Diff.Changed}}{{"\n"}}{{print "-"}}{{.Path}}	{{join .AddedNeeded ", "}}	{{join .RemovedNeeded ", "}}{{end}}{{with more .Diff.Changed}}{{"\n"}}{{.}}{{end}}{{end}}
+`
+
+const RollupOutput = `
+-----Rollup-----
+
+{{.Images}} images in {{.Files}} result files
+
+Most common packages{{if lt (len .Packages) .Total}} ({{len .Packages}} of {{.Total}}){{end}}:{{if not .Packages}} None{{else}}
+TYPE	NAME	IMAGES	VERSIONS	TOP VERSION{{range .Packages}}{{"\n"}}{{.Type}}	{{.Name}}	{{.Images}}	{{.Versions}}	{{.Top}}{{end}}{{end}}
+
+Image sizes:{{if not .Sizes.Images}} None{{else}}
+IMAGES	TOTAL	AVERAGE	SMALLEST	LARGEST
+{{.Sizes.Images}}	{{.Sizes.Total}}	{{.Sizes.Average}}	{{.Sizes.Smallest}}	{{.Sizes.Largest}}{{end}}
+
+Size changes across diffs:{{if not .Sizes.Diffs}} None{{else}}
+DIFFS	GROWN	SHRUNK	TOTAL CHANGE	AVERAGE CHANGE	LARGEST GROWTH
+{{.Sizes.Diffs}}	{{.Sizes.Grown}}	{{.Sizes.Shrunk}}	{{.Sizes.TotalChange}}	{{.Sizes.AverageChange}}	{{or .Sizes.LargestGrowth "none"}}{{end}}
+`
+
+const RollupImagesOutput = `
+-----Rollup-----
+
+Images with the package:{{if not .}} None{{else}}
+IMAGE	TYPE	PACKAGE	VERSION{{range .}}{{"\n"}}{{.Image}}	{{.Type}}	{{.Package}}	{{.Version}}{{end}}{{end}}
+`
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timing records when a step of an analysis ran.
+type Timing struct {
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Seconds float64
+}
+
+// Timings records when an analysis or diff ran, when each image was resolved
+// and how long each analyzer took. All methods are no-ops on a nil Timings,
+// so callers need not check whether timings were requested.
+type Timings struct {
+	Start     time.Time
+	End       time.Time
+	Seconds   float64
+	Images    []Timing
+	Analyzers []Timing
+
+	mu sync.Mutex
+}
+
+// ResultsWithMetadata is the JSON output when timings, annotations or the
+// origins of daemon images are included.
+type ResultsWithMetadata struct {
+	Annotations map[string]string `json:",omitempty"`
+	Timings     *Timings          `json:",omitempty"`
+	Origins     []ImageOrigin     `json:",omitempty"`
+	Results     []interface{}
+}
+
+// ImageOrigin records whether a daemon image was already in the daemon or
+// pulled for the run, along with the ID of the image read.
+type ImageOrigin struct {
+	Image  string
+	Origin string
+	ID     string
+}
+
+// NewTimings starts recording timings.
+func NewTimings() *Timings {
+	return &Timings{Start: time.Now().UTC(), Images: []Timing{}, Analyzers: []Timing{}}
+}
+
+// AddImage records the resolution of an image. It may be called from
+// several goroutines.
+func (t *Timings) AddImage(image string, start, end time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Images = append(t.Images, newTiming(image, start, end))
+}
+
+// AddAnalyzer records the run of an analyzer.
+func (t *Timings) AddAnalyzer(analyzer string, start, end time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Analyzers = append(t.Analyzers, newTiming(analyzer, start, end))
+}
+
+// Finish stops recording and orders the images by resolution start, as they
+// may be resolved concurrently. Later calls keep the first end time.
+func (t *Timings) Finish() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.End.IsZero() {
+		return
+	}
+	t.End = time.Now().UTC()
+	t.Seconds = t.End.Sub(t.Start).Seconds()
+	sort.SliceStable(t.Images, func(i, j int) bool { return t.Images[i].Start.Before(t.Images[j].Start) })
+}
+
+func newTiming(name string, start, end time.Time) Timing {
+	return Timing{
+		Name:    name,
+		Start:   start.UTC(),
+		End:     end.UTC(),
+		Seconds: end.Sub(start).Seconds(),
+	}
+}
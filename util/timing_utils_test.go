@@ -0,0 +1,49 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	var none *Timings
+	none.AddImage("image", time.Now(), time.Now())
+	none.AddAnalyzer("analyzer", time.Now(), time.Now())
+	none.Finish()
+
+	timings := NewTimings()
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	timings.AddImage("image2", base.Add(time.Second), base.Add(3*time.Second))
+	timings.AddImage("image1", base, base.Add(2*time.Second))
+	timings.AddAnalyzer("FileAnalyzer", base.Add(3*time.Second), base.Add(3500*time.Millisecond))
+	timings.Finish()
+
+	if timings.End.Before(timings.Start) {
+		t.Errorf("end %s is before start %s", timings.End, timings.Start)
+	}
+	if len(timings.Images) != 2 || timings.Images[0].Name != "image1" || timings.Images[1].Name != "image2" {
+		t.Errorf("images not ordered by start: %+v", timings.Images)
+	}
+	if timings.Images[0].Seconds != 2 {
+		t.Errorf("expected image1 to take 2 seconds, got %f", timings.Images[0].Seconds)
+	}
+	if len(timings.Analyzers) != 1 || timings.Analyzers[0].Seconds != 0.5 {
+		t.Errorf("unexpected analyzer timings: %+v", timings.Analyzers)
+	}
+}
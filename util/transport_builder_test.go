@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func TestRegistryScheme(t *testing.T) {
+	testCases := []struct {
+		registry string
+		insecure bool
+		expected string
+	}{
+		{registry: "registry.example.com", expected: "https"},
+		{registry: "registry.example.com:5000", expected: "https"},
+		{registry: "localhost:5000", expected: "http"},
+		{registry: "registry.local:5000", expected: "http"},
+		{registry: "192.168.1.10:5000", expected: "http"},
+		{registry: "[::1]:5000", expected: "http"},
+		{registry: "[fd00::20]:5000", expected: "http"},
+		{registry: "[fe80::20]", expected: "http"},
+		// the registry library would use plain HTTP for hosts containing ::1
+		{registry: "[2001:db8::1]:5000", expected: "https"},
+		{registry: "[2001:db8::20]", expected: "https"},
+		// insecure registries fall back to plain HTTP
+		{registry: "insecure.example.com:5000", insecure: true, expected: "http"},
+		{registry: "[2001:db8::2]:5000", insecure: true, expected: "http"},
+	}
+	var insecure []string
+	for _, test := range testCases {
+		if test.insecure {
+			insecure = append(insecure, test.registry)
+		}
+	}
+	pkgutil.ConfigureInsecureRegistries(insecure)
+	defer pkgutil.ConfigureInsecureRegistries(nil)
+	// every request fails before leaving the process, so the last scheme
+	// seen is the one the request ended up using
+	var scheme string
+	defer func(orig http.RoundTripper) { http.DefaultTransport = orig }(http.DefaultTransport)
+	http.DefaultTransport = &http.Transport{Proxy: func(req *http.Request) (*url.URL, error) {
+		scheme = req.URL.Scheme
+		return nil, errors.New("no network in tests")
+	}}
+
+	for _, test := range testCases {
+		registry, err := name.NewRegistry(test.registry, name.WeakValidation)
+		if err != nil {
+			t.Fatalf("Error parsing registry %s: %s", test.registry, err)
+		}
+		scheme = ""
+		client := http.Client{Transport: pkgutil.BuildTransport(registry)}
+		if resp, err := client.Get(registry.Scheme() + "://" + test.registry + "/v2/"); err == nil {
+			resp.Body.Close()
+		}
+		if scheme != test.expected {
+			t.Errorf("Expected %s to be reached over %s but got %q", test.registry, test.expected, scheme)
+		}
+	}
+}
+
+func TestRegistryCACertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "ca")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	notPEM := filepath.Join(dir, "ca.der")
+	ioutil.WriteFile(notPEM, server.Certificate().Raw, 0644)
+	defer pkgutil.ConfigureRegistryCAs(nil)
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	registry, err := name.NewRegistry(host, name.WeakValidation)
+	if err != nil {
+		t.Fatalf("Error parsing registry %s: %s", host, err)
+	}
+	get := func() error {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v2/", nil)
+		resp, err := pkgutil.BuildTransport(registry).RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(); err == nil {
+		t.Errorf("Expected the certificate of %s to be rejected without its CA", host)
+	}
+	if err := pkgutil.ConfigureRegistryCAs([]string{ca}); err != nil {
+		t.Fatalf("Error configuring CA %s: %s", ca, err)
+	}
+	if err := get(); err != nil {
+		t.Errorf("Expected the certificate of %s to be trusted with its CA, got %s", host, err)
+	}
+	for _, path := range []string{notPEM, filepath.Join(dir, "missing.pem")} {
+		if err := pkgutil.ConfigureRegistryCAs([]string{path}); err == nil {
+			t.Errorf("Expected an error configuring CA %s", path)
+		}
+	}
+}
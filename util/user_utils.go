@@ -0,0 +1,178 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// privilegedGroups are the groups whose members can become root, or act as
+// root through the daemons they may talk to.
+var privilegedGroups = map[string]bool{"root": true, "sudo": true, "wheel": true, "admin": true, "docker": true}
+
+// UserAccount stores a user of /etc/passwd. Groups lists the groups it
+// belongs to, its primary group first, and Shadow whether /etc/shadow has
+// an entry for it. Password hashes are never read.
+type UserAccount struct {
+	Name   string
+	UID    int
+	GID    int
+	Groups []string
+	Home   string
+	Shell  string
+	Shadow bool
+}
+
+// GroupAccount stores a group of /etc/group with the users it lists as
+// members.
+type GroupAccount struct {
+	Name    string
+	GID     int
+	Members []string
+}
+
+// UserAccounts stores the users and groups of an image.
+type UserAccounts struct {
+	Users  []UserAccount
+	Groups []GroupAccount
+}
+
+// UserAccountChange stores a user of both images with a different UID,
+// groups, home, shell or shadow entry.
+type UserAccountChange struct {
+	Name  string
+	User1 UserAccount
+	User2 UserAccount
+}
+
+// GroupAccountChange stores a group of both images with a different GID or
+// members.
+type GroupAccountChange struct {
+	Name   string
+	Group1 GroupAccount
+	Group2 GroupAccount
+}
+
+// AccountDiff stores the users and groups added, removed and changed
+// between two images. Warnings flag the users of the second image which
+// became root-equivalent, with UID 0, or joined a privileged group such as
+// sudo.
+type AccountDiff struct {
+	Warnings      []string
+	UsersAdded    []UserAccount
+	UsersRemoved  []UserAccount
+	UsersChanged  []UserAccountChange
+	GroupsAdded   []GroupAccount
+	GroupsRemoved []GroupAccount
+	GroupsChanged []GroupAccountChange
+}
+
+// GetAccountDiff compares the users and groups of two images by name.
+func GetAccountDiff(accounts1, accounts2 UserAccounts) AccountDiff {
+	diff := AccountDiff{
+		Warnings:      []string{},
+		UsersAdded:    []UserAccount{},
+		UsersRemoved:  []UserAccount{},
+		UsersChanged:  []UserAccountChange{},
+		GroupsAdded:   []GroupAccount{},
+		GroupsRemoved: []GroupAccount{},
+		GroupsChanged: []GroupAccountChange{},
+	}
+	users1 := map[string]UserAccount{}
+	for _, u := range accounts1.Users {
+		users1[u.Name] = u
+	}
+	users2 := map[string]UserAccount{}
+	for _, u := range accounts2.Users {
+		users2[u.Name] = u
+		u1, ok := users1[u.Name]
+		if !ok {
+			diff.UsersAdded = append(diff.UsersAdded, u)
+		} else if !reflect.DeepEqual(u1, u) {
+			diff.UsersChanged = append(diff.UsersChanged, UserAccountChange{Name: u.Name, User1: u1, User2: u})
+		}
+		diff.Warnings = append(diff.Warnings, privilegeWarnings(u1, u, ok)...)
+	}
+	for _, u := range accounts1.Users {
+		if _, ok := users2[u.Name]; !ok {
+			diff.UsersRemoved = append(diff.UsersRemoved, u)
+		}
+	}
+
+	groups1 := map[string]GroupAccount{}
+	for _, g := range accounts1.Groups {
+		groups1[g.Name] = g
+	}
+	groups2 := map[string]GroupAccount{}
+	for _, g := range accounts2.Groups {
+		groups2[g.Name] = g
+		g1, ok := groups1[g.Name]
+		if !ok {
+			diff.GroupsAdded = append(diff.GroupsAdded, g)
+		} else if !reflect.DeepEqual(g1, g) {
+			diff.GroupsChanged = append(diff.GroupsChanged, GroupAccountChange{Name: g.Name, Group1: g1, Group2: g})
+		}
+	}
+	for _, g := range accounts1.Groups {
+		if _, ok := groups2[g.Name]; !ok {
+			diff.GroupsRemoved = append(diff.GroupsRemoved, g)
+		}
+	}
+	return diff
+}
+
+// privilegeWarnings flags the privileges user2 gained over user1, or has if
+// it is new. root itself is expected to have them.
+func privilegeWarnings(user1, user2 UserAccount, existed bool) []string {
+	warnings := []string{}
+	if user2.Name == "root" {
+		return warnings
+	}
+	if user2.UID == 0 && (!existed || user1.UID != 0) {
+		warnings = append(warnings, fmt.Sprintf("user %s has UID 0, making it root-equivalent", user2.Name))
+	}
+	had := map[string]bool{}
+	if existed {
+		for _, g := range user1.Groups {
+			had[g] = true
+		}
+	}
+	for _, g := range user2.Groups {
+		if privilegedGroups[g] && !had[g] {
+			warnings = append(warnings, fmt.Sprintf("user %s is a member of the privileged group %s", user2.Name, g))
+		}
+	}
+	return warnings
+}
+
+// SortUserAccounts orders users by UID and groups by GID, then by name.
+func SortUserAccounts(accounts UserAccounts) {
+	sort.Slice(accounts.Users, func(i, j int) bool {
+		if accounts.Users[i].UID != accounts.Users[j].UID {
+			return accounts.Users[i].UID < accounts.Users[j].UID
+		}
+		return accounts.Users[i].Name < accounts.Users[j].Name
+	})
+	sort.Slice(accounts.Groups, func(i, j int) bool {
+		if accounts.Groups[i].GID != accounts.Groups[j].GID {
+			return accounts.Groups[i].GID < accounts.Groups[j].GID
+		}
+		return accounts.Groups[i].Name < accounts.Groups[j].Name
+	})
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strings"
+)
+
+// Kinds of version changes of the packages of two images. A revision keeps
+// the major, minor and patch versions, e.g. a new distribution revision or
+// a pre-release promoted to its release.
+const (
+	ChangeMajor     = "major"
+	ChangeMinor     = "minor"
+	ChangePatch     = "patch"
+	ChangeRevision  = "revision"
+	ChangeDowngrade = "downgrade"
+)
+
+// Version schemes of package managers, deciding how versions compare.
+const (
+	VersionSchemeSemver = "semver"
+	VersionSchemeDpkg   = "dpkg"
+	VersionSchemeRPM    = "rpm"
+)
+
+// packageVersionSchemes are the version schemes of the package analyzers,
+// by DiffType, which don't follow semantic versioning.
+var packageVersionSchemes = map[string]string{
+	"Apt": VersionSchemeDpkg,
+	"RPM": VersionSchemeRPM,
+}
+
+// PackageVersionScheme returns the version scheme of the packages of a
+// package analyzer, from its DiffType.
+func PackageVersionScheme(diffType string) string {
+	if scheme, ok := packageVersionSchemes[diffType]; ok {
+		return scheme
+	}
+	return VersionSchemeSemver
+}
+
+func versionSchemeComparer(scheme string) func(a, b string) int {
+	switch scheme {
+	case VersionSchemeDpkg:
+		return compareDpkgVersion
+	case VersionSchemeRPM:
+		return compareRPMVersion
+	}
+	return compareSemver
+}
+
+// ClassifyVersionChange returns the kind of change from version v1 to v2
+// under scheme, or "" if they are the same. The epochs of dpkg and rpm
+// versions reset their ordering, so raising one is a major upgrade.
+func ClassifyVersionChange(scheme, v1, v2 string) string {
+	if v1 == v2 {
+		return ""
+	}
+	if versionSchemeComparer(scheme)(v1, v2) > 0 {
+		return ChangeDowngrade
+	}
+	upstream1, upstream2 := v1, v2
+	if scheme == VersionSchemeSemver {
+		upstream1, _ = splitSemver(v1)
+		upstream2, _ = splitSemver(v2)
+	} else {
+		var epoch1, epoch2 string
+		epoch1, upstream1, _ = splitVersionEpoch(v1)
+		epoch2, upstream2, _ = splitVersionEpoch(v2)
+		if compareNumeric(epoch1, epoch2) != 0 {
+			return ChangeMajor
+		}
+	}
+	parts1 := strings.Split(upstream1, ".")
+	parts2 := strings.Split(upstream2, ".")
+	kinds := []string{ChangeMajor, ChangeMinor, ChangePatch}
+	for i := 0; i < len(parts1) || i < len(parts2); i++ {
+		if compareNumeric(leadingDigits(semverPart(parts1, i)), leadingDigits(semverPart(parts2, i))) == 0 {
+			continue
+		}
+		if i < len(kinds) {
+			return kinds[i]
+		}
+		return ChangePatch
+	}
+	return ChangeRevision
+}
+
+// leadingDigits returns the number a version component starts with, such as
+// 3 of 3+dfsg, or the component itself if it starts with none.
+func leadingDigits(part string) string {
+	end := 0
+	for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return part
+	}
+	return part[:end]
+}
+
+// ClassifyVersionChanges sets the kind of change of each version difference
+// of a single version package diff.
+func ClassifyVersionChanges(scheme string, infoDiff []Info) {
+	for i, info := range infoDiff {
+		infoDiff[i].Change = ClassifyVersionChange(scheme, info.Info1.Version, info.Info2.Version)
+	}
+}
+
+// ClassifyMultiVersionChanges sets the kind of change of each version
+// difference of a multi-version package diff, from the highest version
+// installed in each image.
+func ClassifyMultiVersionChanges(scheme string, infoDiff []MultiVersionInfo) {
+	compare := versionSchemeComparer(scheme)
+	highest := func(infos []PackageInfo) string {
+		version := ""
+		for _, info := range infos {
+			if version == "" || compare(info.Version, version) > 0 {
+				version = info.Version
+			}
+		}
+		return version
+	}
+	for i, info := range infoDiff {
+		v1, v2 := highest(info.Info1), highest(info.Info2)
+		if v1 == "" || v2 == "" {
+			continue
+		}
+		infoDiff[i].Change = ClassifyVersionChange(scheme, v1, v2)
+	}
+}
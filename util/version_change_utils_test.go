@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+)
+
+func TestClassifyVersionChange(t *testing.T) {
+	testCases := []struct {
+		scheme   string
+		v1, v2   string
+		expected string
+	}{
+		{scheme: VersionSchemeSemver, v1: "1.2.3", v2: "1.2.3", expected: ""},
+		{scheme: VersionSchemeSemver, v1: "1.2.3", v2: "2.0.0", expected: ChangeMajor},
+		{scheme: VersionSchemeSemver, v1: "v1.2.3", v2: "v1.3.0", expected: ChangeMinor},
+		{scheme: VersionSchemeSemver, v1: "1.2.3", v2: "1.2.10", expected: ChangePatch},
+		{scheme: VersionSchemeSemver, v1: "1.2", v2: "1.2.1", expected: ChangePatch},
+		{scheme: VersionSchemeSemver, v1: "2.0.0-rc.1", v2: "2.0.0", expected: ChangeRevision},
+		{scheme: VersionSchemeSemver, v1: "2.0.0", v2: "0.8.0", expected: ChangeDowngrade},
+		{scheme: VersionSchemeDpkg, v1: "2.36-9", v2: "2.36-9+deb12u4", expected: ChangeRevision},
+		{scheme: VersionSchemeDpkg, v1: "3.0.11-1~deb12u2", v2: "3.1.5-1", expected: ChangeMinor},
+		{scheme: VersionSchemeDpkg, v1: "9.2p1-2", v2: "1:9.2p1-2", expected: ChangeMajor},
+		{scheme: VersionSchemeDpkg, v1: "1:2.0-1", v2: "3.0-1", expected: ChangeDowngrade},
+		{scheme: VersionSchemeDpkg, v1: "1.2.3+dfsg-1", v2: "1.2.4+dfsg-1", expected: ChangePatch},
+		{scheme: VersionSchemeRPM, v1: "1.1.1k-9.el8", v2: "1.1.1k-12.el8", expected: ChangeRevision},
+		{scheme: VersionSchemeRPM, v1: "1:1.1.1k-9.el8", v2: "1:3.0.7-27.el9", expected: ChangeMajor},
+		{scheme: VersionSchemeRPM, v1: "5.1-4.el9", v2: "5.0-1.el9", expected: ChangeDowngrade},
+	}
+	for _, test := range testCases {
+		if change := ClassifyVersionChange(test.scheme, test.v1, test.v2); change != test.expected {
+			t.Errorf("Expected %s -> %s (%s) to be %q but got %q", test.v1, test.v2, test.scheme, test.expected, change)
+		}
+	}
+}
+
+func TestClassifyMultiVersionChanges(t *testing.T) {
+	infoDiff := []MultiVersionInfo{
+		{Package: "six", Info1: []PackageInfo{{Version: "1.15.0"}, {Version: "1.16.0"}}, Info2: []PackageInfo{{Version: "1.17.0"}}},
+		{Package: "mock", Info1: []PackageInfo{{Version: "2.0.0"}}, Info2: []PackageInfo{{Version: "0.8.0"}}},
+		{Package: "requests", Info1: []PackageInfo{{Version: "2.31.0"}}, Info2: []PackageInfo{}},
+	}
+	ClassifyMultiVersionChanges(VersionSchemeSemver, infoDiff)
+	expected := []string{ChangeMinor, ChangeDowngrade, ""}
+	for i, info := range infoDiff {
+		if info.Change != expected[i] {
+			t.Errorf("Expected %s to be %q but got %q", info.Package, expected[i], info.Change)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"strings"
+)
+
+// ecosystemComparer returns how the versions of an OSV ecosystem compare.
+// Ecosystems of distributions are qualified by release, as in Debian:12.
+func ecosystemComparer(ecosystem string) func(a, b string) int {
+	switch strings.SplitN(ecosystem, ":", 2)[0] {
+	case "Debian", "Ubuntu":
+		return compareDpkgVersion
+	case "AlmaLinux", "Rocky Linux", "Red Hat":
+		return compareRPMVersion
+	}
+	return compareSemver
+}
+
+// splitVersionEpoch splits the epoch off an epoch:version-release version,
+// and the release off what is left after its last dash.
+func splitVersionEpoch(v string) (string, string, string) {
+	epoch := "0"
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, v = v[:i], v[i+1:]
+	}
+	release := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, release = v[:i], v[i+1:]
+	}
+	return epoch, v, release
+}
+
+// compareDpkgVersion compares two Debian package versions as dpkg does,
+// returning -1, 0 or 1.
+func compareDpkgVersion(a, b string) int {
+	aEpoch, aVersion, aRevision := splitVersionEpoch(a)
+	bEpoch, bVersion, bRevision := splitVersionEpoch(b)
+	if c := compareNumeric(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := compareDpkgPart(aVersion, bVersion); c != 0 {
+		return c
+	}
+	return compareDpkgPart(aRevision, bRevision)
+}
+
+// dpkgOrder ranks a character of the non-digit parts of a version: a tilde
+// before the end of the part, letters next and other characters last.
+func dpkgOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case s[0] == '~':
+		return -1
+	case isLetter(s[0]):
+		return int(s[0])
+	}
+	return int(s[0]) + 256
+}
+
+func compareDpkgPart(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			if c := compareInt(dpkgOrder(a), dpkgOrder(b)); c != 0 {
+				return c
+			}
+			a, b = a[1:], b[1:]
+		}
+		var aDigits, bDigits string
+		aDigits, a = splitDigits(a)
+		bDigits, b = splitDigits(b)
+		if c := compareDigits(aDigits, bDigits); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareRPMVersion compares two epoch:version-release package versions as
+// rpm does, returning -1, 0 or 1. The release is only compared when both
+// versions have one.
+func compareRPMVersion(a, b string) int {
+	aEpoch, aVersion, aRelease := splitVersionEpoch(a)
+	bEpoch, bVersion, bRelease := splitVersionEpoch(b)
+	if c := compareNumeric(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := compareRPMPart(aVersion, bVersion); c != 0 || aRelease == "" || bRelease == "" {
+		return c
+	}
+	return compareRPMPart(aRelease, bRelease)
+}
+
+// compareRPMPart compares versions segment by segment, as rpmvercmp: runs of
+// digits numerically, runs of letters alphabetically, a numeric segment
+// being newer than an alphabetic one, and a tilde sorting before anything.
+func compareRPMPart(a, b string) int {
+	for {
+		a = strings.TrimLeftFunc(a, isRPMSeparator)
+		b = strings.TrimLeftFunc(b, isRPMSeparator)
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+		if isDigit(a[0]) {
+			var aDigits, bDigits string
+			aDigits, a = splitDigits(a)
+			if !isDigit(b[0]) {
+				return 1
+			}
+			bDigits, b = splitDigits(b)
+			if c := compareDigits(aDigits, bDigits); c != 0 {
+				return c
+			}
+			continue
+		}
+		if isDigit(b[0]) {
+			return -1
+		}
+		aLetters, bLetters := leadingLetters(a), leadingLetters(b)
+		if c := strings.Compare(aLetters, bLetters); c != 0 {
+			return c
+		}
+		a, b = a[len(aLetters):], b[len(bLetters):]
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func isRPMSeparator(r rune) bool {
+	return r != '~' && (r >= 128 || (!isDigit(byte(r)) && !isLetter(byte(r))))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func leadingLetters(s string) string {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+// compareDigits compares runs of digits of any length numerically.
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if c := compareInt(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
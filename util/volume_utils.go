@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+)
+
+// VolumeContent is what an image ships below a path its config declares as
+// a VOLUME. At runtime a volume mounted there hides these files, or copies
+// them once into a new named volume, which is rarely what was intended.
+type VolumeContent struct {
+	Volume string
+	Files  []pkgutil.DirectoryEntry
+	Size   int64
+}
+
+// VolumeDiff compares the volumes two images declare and the files shipped
+// below them. Warnings point out the volumes files were added below.
+type VolumeDiff struct {
+	AddedVolumes   []string
+	RemovedVolumes []string
+	Adds           []pkgutil.DirectoryEntry
+	Dels           []pkgutil.DirectoryEntry
+	Mods           []EntryDiff
+	Warnings       []string
+}
+
+// UnderVolume reports whether the image path name is below volume.
+func UnderVolume(volume, name string) bool {
+	return volume == "/" || strings.HasPrefix(name, strings.TrimSuffix(volume, "/")+"/")
+}
+
+// GetVolumeDiff compares the volumes of two images. Files are compared by
+// digest, link target and size.
+func GetVolumeDiff(volumes1, volumes2 []VolumeContent) VolumeDiff {
+	diff := VolumeDiff{
+		AddedVolumes:   []string{},
+		RemovedVolumes: []string{},
+		Adds:           []pkgutil.DirectoryEntry{},
+		Dels:           []pkgutil.DirectoryEntry{},
+		Mods:           []EntryDiff{},
+		Warnings:       []string{},
+	}
+	declared1, declared2 := map[string]bool{}, map[string]bool{}
+	files1, files2 := volumeFiles(volumes1, declared1), volumeFiles(volumes2, declared2)
+	for _, v := range volumes2 {
+		if !declared1[v.Volume] {
+			diff.AddedVolumes = append(diff.AddedVolumes, v.Volume)
+		}
+	}
+	for _, v := range volumes1 {
+		if !declared2[v.Volume] {
+			diff.RemovedVolumes = append(diff.RemovedVolumes, v.Volume)
+		}
+	}
+
+	for name, f2 := range files2 {
+		f1, ok := files1[name]
+		if !ok {
+			diff.Adds = append(diff.Adds, f2)
+			continue
+		}
+		if f1.Digest != f2.Digest || f1.Linkname != f2.Linkname || f1.Size != f2.Size {
+			diff.Mods = append(diff.Mods, EntryDiff{
+				Name:    name,
+				Size1:   f1.Size,
+				Size2:   f2.Size,
+				Link1:   f1.Linkname,
+				Link2:   f2.Linkname,
+				Digest1: f1.Digest,
+				Digest2: f2.Digest,
+			})
+		}
+	}
+	for name, f1 := range files1 {
+		if _, ok := files2[name]; !ok {
+			diff.Dels = append(diff.Dels, f1)
+		}
+	}
+	sort.Slice(diff.Adds, func(i, j int) bool { return diff.Adds[i].Name < diff.Adds[j].Name })
+	sort.Slice(diff.Dels, func(i, j int) bool { return diff.Dels[i].Name < diff.Dels[j].Name })
+	sort.Slice(diff.Mods, func(i, j int) bool { return diff.Mods[i].Name < diff.Mods[j].Name })
+
+	for _, v := range volumes2 {
+		added := 0
+		for _, f := range diff.Adds {
+			if UnderVolume(v.Volume, f.Name) {
+				added++
+			}
+		}
+		if added > 0 {
+			diff.Warnings = append(diff.Warnings, fmt.Sprintf("%d file(s) were added below VOLUME %s, which a volume mounted there hides", added, v.Volume))
+		}
+	}
+	return diff
+}
+
+// volumeFiles indexes the files below the volumes by name, recording which
+// volumes are declared.
+func volumeFiles(volumes []VolumeContent, declared map[string]bool) map[string]pkgutil.DirectoryEntry {
+	files := map[string]pkgutil.DirectoryEntry{}
+	for _, v := range volumes {
+		declared[v.Volume] = true
+		for _, f := range v.Files {
+			files[f.Name] = f
+		}
+	}
+	return files
+}
+
+type StrVolumeContent struct {
+	Volume string
+	Files  int
+	Size   string
+}
+
+func stringifyVolumeContents(volumes []VolumeContent) ([]StrVolumeContent, []StrDirectoryEntry) {
+	strVolumes := []StrVolumeContent{}
+	files := []pkgutil.DirectoryEntry{}
+	for _, v := range volumes {
+		strVolumes = append(strVolumes, StrVolumeContent{Volume: v.Volume, Files: len(v.Files), Size: stringifySize(v.Size)})
+		files = append(files, v.Files...)
+	}
+	strFiles := stringifyDirectoryEntries(files)
+	if strFiles == nil {
+		strFiles = []StrDirectoryEntry{}
+	}
+	return strVolumes, strFiles
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Google, Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pkgutil "github.com/GoogleContainerTools/container-diff/pkg/util"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestWorkdirBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workdir")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	// with the temp dir below /dev/shm, extractions to disk and to memory
+	// end up in different directories
+	tmp, err := ioutil.TempDir("/dev/shm", "workdir")
+	if err != nil {
+		t.Skipf("No /dev/shm to extract to memory: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tmp)
+	defer pkgutil.ConfigureWorkdir(pkgutil.WorkdirDisk)
+
+	img, err := random.Image(64, 2)
+	if err != nil {
+		t.Fatalf("Error creating image: %s", err)
+	}
+	tag, _ := name.NewTag("example.com/image:latest", name.WeakValidation)
+	source := filepath.Join(dir, "image.tar")
+	if err := tarball.WriteToFile(source, tag, img); err != nil {
+		t.Fatalf("Error writing image: %s", err)
+	}
+
+	if err := pkgutil.ConfigureWorkdir("ramdisk"); err == nil || !strings.Contains(err.Error(), "invalid workdir backend ramdisk") {
+		t.Errorf("Expected an invalid workdir backend to be refused, got %v", err)
+	}
+	if err := pkgutil.ConfigureWorkdir(pkgutil.WorkdirMemory); err != nil {
+		t.Skipf("No tmpfs to extract to memory: %s", err)
+	}
+	for _, test := range []struct {
+		backend  string
+		expected string
+	}{
+		{backend: "", expected: tmp},
+		{backend: pkgutil.WorkdirDisk, expected: tmp},
+		{backend: pkgutil.WorkdirMemory, expected: "/dev/shm"},
+		// the image fits easily in the tmpfs
+		{backend: pkgutil.WorkdirAuto, expected: "/dev/shm"},
+	} {
+		if err := pkgutil.ConfigureWorkdir(test.backend); err != nil {
+			t.Fatalf("Error selecting workdir backend %q: %s", test.backend, err)
+		}
+		image, err := pkgutil.GetImage(source, true, "")
+		if err != nil {
+			t.Fatalf("Error retrieving the image with workdir backend %q: %s", test.backend, err)
+		}
+		if filepath.Dir(image.FSPath) != test.expected {
+			t.Errorf("Expected workdir backend %q to extract to %s, got %s", test.backend, test.expected, image.FSPath)
+		}
+		pkgutil.CleanupImage(image)
+	}
+}